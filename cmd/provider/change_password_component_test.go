@@ -0,0 +1,42 @@
+// +build component
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestChangePassword(t *testing.T) {
+	email := "passwordChangeTest@leberkleber.io"
+	password := "s3cr3t"
+	newPassword := "t3rc3s"
+
+	createUser(t, email, password)
+	changePassword(t, email, password, newPassword)
+
+	_, authorized := loginUser(t, email, password)
+	if authorized {
+		t.Error("user could login with old password")
+	}
+
+	loginUser(t, email, newPassword)
+}
+
+func changePassword(t *testing.T, email, password, newPassword string) {
+	t.Helper()
+	resp, err := http.Post(
+		"http://simple-jwt-provider/v1/auth/password-change",
+		"application/json",
+		bytes.NewReader([]byte(fmt.Sprintf(`{"email": %q, "password":%q, "new_password": %q}`, email, password, newPassword))),
+	)
+	if err != nil {
+		t.Fatalf("Failed to change password cause: %s", err)
+	}
+
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Invalid response status code. Expected: %d, Given: %d", http.StatusNoContent, resp.StatusCode)
+	}
+}
@@ -0,0 +1,45 @@
+// +build component
+
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCleanupJobs(t *testing.T) {
+	// 1) all cleanup jobs are listed
+	// 2) run job manually
+	// 3) run is part of the job metrics
+	// 4) unknown job can not be run
+
+	type job struct {
+		Name     string `json:"name"`
+		Runs     int    `json:"runs"`
+		Failures int    `json:"failures"`
+	}
+
+	// 1)
+	var jobs []job
+	adminRequest(t, http.MethodGet, "http://simple-jwt-provider/v1/admin/jobs", "", http.StatusOK, &jobs)
+	if len(jobs) != 4 || jobs[0].Name != "purge-tokens" || jobs[1].Name != "purge-login-history" || jobs[2].Name != "purge-unverified-users" || jobs[3].Name != "remind-password-expiry" {
+		t.Fatalf("unexpected jobs: %#v", jobs)
+	}
+	runsBefore := jobs[0].Runs
+
+	// 2)
+	var ran job
+	adminRequest(t, http.MethodPost, "http://simple-jwt-provider/v1/admin/jobs/purge-tokens/run", "", http.StatusOK, &ran)
+	if ran.Name != "purge-tokens" || ran.Failures != 0 {
+		t.Errorf("unexpected job after run: %#v", ran)
+	}
+
+	// 3)
+	adminRequest(t, http.MethodGet, "http://simple-jwt-provider/v1/admin/jobs", "", http.StatusOK, &jobs)
+	if jobs[0].Runs != runsBefore+1 {
+		t.Errorf("unexpected count of runs. Expected: %d, Given: %d", runsBefore+1, jobs[0].Runs)
+	}
+
+	// 4)
+	adminRequest(t, http.MethodPost, "http://simple-jwt-provider/v1/admin/jobs/unknown/run", "", http.StatusNotFound, nil)
+}
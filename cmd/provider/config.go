@@ -5,25 +5,41 @@ import (
 	"fmt"
 	"github.com/ardanlabs/conf"
 	"os"
+	"time"
 )
 
 var confUsage = conf.Usage
 
 type config struct {
 	ServerAddress string `conf:"help:Server-address network-interface to bind on e.g.: '127.0.0.1:8080',default:0.0.0.0:80"`
-	JWT           struct {
+	EMail         struct {
+		LowercaseLocalPart bool `conf:"env:EMAIL_LOWERCASE_LOCAL_PART,help:Lowercase the whole email instead of the domain only on normalization (true / false),default:false"`
+	}
+	JWT struct {
 		PrivateKey string `conf:"env:JWT_PRIVATE_KEY,help:JWT PrivateKey ECDSA512,required,noprint"`
 		Audience   string `conf:"env:JWT_AUDIENCE,help:Audience private claim which will be applied in each JWT"`
 		Issuer     string `conf:"env:JWT_ISSUER,help:Issuer private claim which will be applied in each JWT"`
-		Subject    string `conf:"env:JWT_SUBJECT,help:Subject private claim which will be applied in each JWT"`
 	}
 	DB struct {
-		Host                 string `conf:"help:Database-Host,required"`
-		Port                 int    `conf:"help:Database-Port,default:5432"`
+		Type                 string `conf:"help:Database type (postgres / mysql / sqlite / memory),default:postgres"`
+		Host                 string `conf:"help:Database-Host (required for postgres and for mysql without dsn)"`
+		Port                 int    `conf:"help:Database-Port. Defaults to 5432 for postgres and 3306 for mysql"`
 		Name                 string `conf:"help:Database-name,default:'simple-jwt-provider'"`
 		Username             string `conf:"help:Database-Username"`
 		Password             string `conf:"help:Database-Password,noprint"`
-		MigrationsFolderPath string `conf:"help:Database Migrations Folder Path,default:/db-migrations"`
+		DSN                  string `conf:"env:DB_DSN,help:Data source name e.g.: 'user:password@tcp(db:3306)/simple-jwt-provider' (mysql only). Overrides host / port / name / username / password,noprint"`
+		File                 string `conf:"help:Path to the database file (sqlite only),default:/data/simple-jwt-provider.db"`
+		SnapshotFile         string `conf:"help:Path to the file all data will be loaded from on start and persisted to on shutdown (memory only). Nothing will be persisted when empty"`
+		SeedFile             string `conf:"help:Path to a yaml / json file with users which will be created on start when they do not exist yet"`
+		MigrationsFolderPath string `conf:"help:Database Migrations Folder Path. Migrations of other types than postgres are in the subfolder named after the type,default:/db-migrations"`
+		TLS                  struct {
+			Enable             bool   `conf:"help:Connect to the database with tls (mysql only) (true / false),default:false"`
+			CAFile             string `conf:"env:DB_TLS_CA_FILE,help:Path to the pem encoded ca certificates to verify the database server with. System certificates are used when empty"`
+			CertFile           string `conf:"env:DB_TLS_CERT_FILE,help:Path to the pem encoded client certificate for client authentication"`
+			KeyFile            string `conf:"env:DB_TLS_KEY_FILE,help:Path to the pem encoded client key for client authentication"`
+			InsecureSkipVerify bool   `conf:"help:true if certificates should not be verified,default:false"`
+			ServerName         string `conf:"help:name of the server who expose the certificate"`
+		}
 	}
 	AdminAPI struct {
 		Enable   bool   `conf:"help:Enable admin API to manage stored users (true / false),default:false"`
@@ -32,15 +48,66 @@ type config struct {
 	}
 	Mail struct {
 		TemplatesFolderPath string `conf:"help:Path to mail-templates folder,default:/mail-templates"`
-		SMTPHost            string `conf:"env:MAIL_SMTP_HOST,help:SMTP host to connect to,required"`
+		SMTPHost            string `conf:"env:MAIL_SMTP_HOST,help:SMTP host to connect to. Mails will be logged instead of sent when empty"`
 		SMTPPort            int    `conf:"env:MAIL_SMTP_PORT,help:SMTP port to connect to,default:587"`
-		SMTPUsername        string `conf:"env:MAIL_SMTP_USERNAME,help:SMTP username to authorize with,required"`
-		SMTPPassword        string `conf:"env:MAIL_SMTP_PASSWORD,help:SMTP password to authorize with,required,noprint"`
+		SMTPUsername        string `conf:"env:MAIL_SMTP_USERNAME,help:SMTP username to authorize with"`
+		SMTPPassword        string `conf:"env:MAIL_SMTP_PASSWORD,help:SMTP password to authorize with,noprint"`
 		TLS                 struct {
 			InsecureSkipVerify bool   `conf:"help:true if certificates should not be verified,default:false"`
 			ServerName         string `conf:"help:name of the server who expose the certificate"`
 		}
 	}
+	PasswordBreach struct {
+		DatasetPath   string `conf:"help:Path to a local HIBP k-anonymity dataset folder or banned-password list file. Check is disabled when empty"`
+		DatasetFormat string `conf:"help:Format of the breach dataset (hibp / list),default:hibp"`
+		MinCount      int    `conf:"help:Minimum breach count for a password to be treated as breached (hibp only),default:1"`
+		WarnOnly      bool   `conf:"help:Only log and mark jwts with 'pwd_breached' claim instead of rejecting breached passwords (true / false),default:false"`
+	}
+	PasswordHistory struct {
+		Size int `conf:"help:Count of last passwords per user which can not be reused. 0 disables the password history,default:0"`
+	}
+	PasswordExpiry struct {
+		MaxAgeDays   int `conf:"help:Max age of passwords in days. Can be overwritten per user. 0 disables password expiry,default:0"`
+		ReminderDays int `conf:"help:Days before password expiry a reminder mail will be sent. Requires a cleanup interval > 0. 0 disables reminder mails,default:0"`
+	}
+	MFA struct {
+		TOTPEncryptionKey string        `conf:"env:MFA_TOTP_ENCRYPTION_KEY,help:Hex encoded 32 byte AES key to encrypt totp secrets. TOTP is disabled when empty,noprint"`
+		TOTPIssuer        string        `conf:"env:MFA_TOTP_ISSUER,help:Issuer which will be shown in authenticator apps,default:simple-jwt-provider"`
+		TokenLifetime     time.Duration `conf:"env:MFA_TOKEN_LIFETIME,help:Lifetime of mfa challenge tokens issued by login,default:5m"`
+	}
+	WebAuthn struct {
+		RPID    string   `conf:"env:WEBAUTHN_RP_ID,help:WebAuthn relying party id (effective domain e.g.: 'example.com'). WebAuthn is disabled when empty"`
+		RPName  string   `conf:"env:WEBAUTHN_RP_NAME,help:WebAuthn relying party name which will be shown by authenticators,default:simple-jwt-provider"`
+		Origins []string `conf:"env:WEBAUTHN_ORIGINS,help:';' separated origins the webauthn ceremonies are allowed from e.g.: 'https://login.example.com'"`
+	}
+	MagicLink struct {
+		Lifetime time.Duration `conf:"help:Lifetime of magic login links e.g.: '15m'. Magic link login is disabled when 0,default:0"`
+	}
+	LoginCode struct {
+		Lifetime    time.Duration `conf:"help:Lifetime of login codes sent by mail e.g.: '5m'. Login code login is disabled when 0,default:0"`
+		MaxAttempts int           `conf:"help:Count of attempts per login code,default:5"`
+	}
+	LoginHistory struct {
+		Retention time.Duration `conf:"help:Duration login attempts will be kept in the login history e.g.: '2160h'. 0 keeps them forever,default:2160h"`
+	}
+	PasswordReset struct {
+		TokenLifetime time.Duration `conf:"help:Lifetime of password reset tokens e.g.: '1h',default:1h"`
+	}
+	Invitation struct {
+		Lifetime time.Duration `conf:"help:Lifetime of invitations of users created without password e.g.: '168h'. Invitations are disabled when 0,default:168h"`
+	}
+	Cleanup struct {
+		Interval                time.Duration `conf:"help:Interval in which expired one-time tokens / old login history / unverified users will be purged and password expiry reminders will be sent. 0 disables the cleanup,default:1h"`
+		UnverifiedUserRetention time.Duration `conf:"help:Duration invited users who never accepted their invitation will be kept after their last invitation e.g.: '720h'. 0 keeps them forever,default:0"`
+	}
+	Token struct {
+		HMACKey string `conf:"env:TOKEN_HMAC_KEY,help:Hex encoded key of at least 32 bytes to hash stored one-time tokens with. Derived from the jwt private key when empty,noprint"`
+	}
+	Realms struct {
+		Enable                  bool   `conf:"help:Enable realms selected by url prefix '/v1/realms/{realm}' or host header (true / false),default:false"`
+		MailTemplatesFolderPath string `conf:"help:Path to the folder with one mail-templates folder per realm. Realms without folder use the default mail-templates,default:/mail-templates/realms"`
+		EncryptionKey           string `conf:"help:Hex encoded 32 byte AES key to encrypt the jwt private keys of realms. Derived from the jwt private key when empty,noprint"`
+	}
 }
 
 func newConfig() (config, error) {
@@ -59,5 +126,36 @@ func newConfig() (config, error) {
 		return cfg, errors.New("admin-api-password and admin-api-username must be set if api has been enabled")
 	}
 
+	if cfg.DB.Type != "postgres" && cfg.DB.Type != "mysql" && cfg.DB.Type != "sqlite" && cfg.DB.Type != "memory" {
+		return cfg, errors.New("db-type must be one of 'postgres', 'mysql', 'sqlite' or 'memory'")
+	}
+
+	if cfg.DB.Type == "postgres" && cfg.DB.Host == "" {
+		return cfg, errors.New("db-host must be set if db-type is 'postgres'")
+	}
+
+	if cfg.DB.Type == "mysql" && cfg.DB.Host == "" && cfg.DB.DSN == "" {
+		return cfg, errors.New("db-host or db-dsn must be set if db-type is 'mysql'")
+	}
+
+	if (cfg.DB.TLS.CertFile == "") != (cfg.DB.TLS.KeyFile == "") {
+		return cfg, errors.New("db-tls-cert-file and db-tls-key-file must be set together")
+	}
+
+	if cfg.DB.Port == 0 {
+		cfg.DB.Port = 5432
+		if cfg.DB.Type == "mysql" {
+			cfg.DB.Port = 3306
+		}
+	}
+
+	if cfg.PasswordBreach.DatasetFormat != "hibp" && cfg.PasswordBreach.DatasetFormat != "list" {
+		return cfg, errors.New("password-breach-dataset-format must be one of 'hibp' or 'list'")
+	}
+
+	if cfg.WebAuthn.RPID != "" && len(cfg.WebAuthn.Origins) == 0 {
+		return cfg, errors.New("webauthn-origins must be set if webauthn-rp-id has been set")
+	}
+
 	return cfg, nil
 }
@@ -6,6 +6,7 @@ import (
 	"os"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestNewConfig(t *testing.T) {
@@ -17,8 +18,8 @@ func TestNewConfig(t *testing.T) {
 	setEnv(t, "SJP_JWT_AUDIENCE", jwtAudience)
 	jwtIssuer := "myJWTIssuer"
 	setEnv(t, "SJP_JWT_ISSUER", jwtIssuer)
-	jwtSubject := "myJWTSubject"
-	setEnv(t, "SJP_JWT_SUBJECT", jwtSubject)
+	dbType := "postgres"
+	setEnv(t, "SJP_DB_TYPE", dbType)
 	dbHost := "myDBHost"
 	setEnv(t, "SJP_DB_HOST", dbHost)
 	expectedDBPort := 555
@@ -30,8 +31,30 @@ func TestNewConfig(t *testing.T) {
 	setEnv(t, "SJP_DB_USERNAME", dbUsername)
 	dbPassword := "myDBPassword"
 	setEnv(t, "SJP_DB_PASSWORD", dbPassword)
+	dbFile := "myDBFile"
+	setEnv(t, "SJP_DB_FILE", dbFile)
+	dbSnapshotFile := "myDBSnapshotFile"
+	setEnv(t, "SJP_DB_SNAPSHOT_FILE", dbSnapshotFile)
+	dbSeedFile := "myDBSeedFile"
+	setEnv(t, "SJP_DB_SEED_FILE", dbSeedFile)
 	dbMigrationsFolderPath := "myDBMigrationsFolderPath"
 	setEnv(t, "SJP_DB_MIGRATIONS_FOLDER_PATH", dbMigrationsFolderPath)
+	dbDSN := "myDBDSN"
+	setEnv(t, "SJP_DB_DSN", dbDSN)
+	expectedDBTLSEnable := true
+	dbTLSEnable := "true"
+	setEnv(t, "SJP_DB_TLS_ENABLE", dbTLSEnable)
+	dbTLSCAFile := "myDBTLSCAFile"
+	setEnv(t, "SJP_DB_TLS_CA_FILE", dbTLSCAFile)
+	dbTLSCertFile := "myDBTLSCertFile"
+	setEnv(t, "SJP_DB_TLS_CERT_FILE", dbTLSCertFile)
+	dbTLSKeyFile := "myDBTLSKeyFile"
+	setEnv(t, "SJP_DB_TLS_KEY_FILE", dbTLSKeyFile)
+	expectedDBTLSInsecureSkipVerify := true
+	dbTLSInsecureSkipVerify := "true"
+	setEnv(t, "SJP_DB_TLS_INSECURE_SKIP_VERIFY", dbTLSInsecureSkipVerify)
+	dbTLSServerName := "myDBTLSServerName"
+	setEnv(t, "SJP_DB_TLS_SERVER_NAME", dbTLSServerName)
 	expectedAdminAPIEnable := true
 	adminAPIEnable := "true"
 	setEnv(t, "SJP_ADMIN_API_ENABLE", adminAPIEnable)
@@ -55,6 +78,73 @@ func TestNewConfig(t *testing.T) {
 	setEnv(t, "SJP_MAIL_TLS_INSECURE_SKIP_VERIFY", mailTLSInsecureSkipVerify)
 	mailTLSServerName := "myMailTLSServerName"
 	setEnv(t, "SJP_MAIL_TLS_SERVER_NAME", mailTLSServerName)
+	passwordBreachDatasetPath := "myPasswordBreachDatasetPath"
+	setEnv(t, "SJP_PASSWORD_BREACH_DATASET_PATH", passwordBreachDatasetPath)
+	passwordBreachDatasetFormat := "list"
+	setEnv(t, "SJP_PASSWORD_BREACH_DATASET_FORMAT", passwordBreachDatasetFormat)
+	expectedPasswordBreachMinCount := 10
+	passwordBreachMinCount := "10"
+	setEnv(t, "SJP_PASSWORD_BREACH_MIN_COUNT", passwordBreachMinCount)
+	expectedPasswordBreachWarnOnly := true
+	passwordBreachWarnOnly := "true"
+	setEnv(t, "SJP_PASSWORD_BREACH_WARN_ONLY", passwordBreachWarnOnly)
+	expectedPasswordHistorySize := 5
+	passwordHistorySize := "5"
+	setEnv(t, "SJP_PASSWORD_HISTORY_SIZE", passwordHistorySize)
+	expectedPasswordExpiryMaxAgeDays := 90
+	passwordExpiryMaxAgeDays := "90"
+	setEnv(t, "SJP_PASSWORD_EXPIRY_MAX_AGE_DAYS", passwordExpiryMaxAgeDays)
+	expectedPasswordExpiryReminderDays := 7
+	passwordExpiryReminderDays := "7"
+	setEnv(t, "SJP_PASSWORD_EXPIRY_REMINDER_DAYS", passwordExpiryReminderDays)
+	mfaTOTPEncryptionKey := "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+	setEnv(t, "SJP_MFA_TOTP_ENCRYPTION_KEY", mfaTOTPEncryptionKey)
+	mfaTOTPIssuer := "myIssuer"
+	setEnv(t, "SJP_MFA_TOTP_ISSUER", mfaTOTPIssuer)
+	expectedMFATokenLifetime := 2 * time.Minute
+	mfaTokenLifetime := "2m"
+	setEnv(t, "SJP_MFA_TOKEN_LIFETIME", mfaTokenLifetime)
+	webAuthnRPID := "example.com"
+	setEnv(t, "SJP_WEBAUTHN_RP_ID", webAuthnRPID)
+	webAuthnRPName := "myRPName"
+	setEnv(t, "SJP_WEBAUTHN_RP_NAME", webAuthnRPName)
+	expectedWebAuthnOrigins := []string{"https://example.com", "https://login.example.com"}
+	webAuthnOrigins := "https://example.com;https://login.example.com"
+	setEnv(t, "SJP_WEBAUTHN_ORIGINS", webAuthnOrigins)
+	expectedMagicLinkLifetime := 15 * time.Minute
+	magicLinkLifetime := "15m"
+	setEnv(t, "SJP_MAGIC_LINK_LIFETIME", magicLinkLifetime)
+	expectedLoginCodeLifetime := 5 * time.Minute
+	loginCodeLifetime := "5m"
+	setEnv(t, "SJP_LOGIN_CODE_LIFETIME", loginCodeLifetime)
+	expectedLoginCodeMaxAttempts := 3
+	loginCodeMaxAttempts := "3"
+	setEnv(t, "SJP_LOGIN_CODE_MAX_ATTEMPTS", loginCodeMaxAttempts)
+	expectedLoginHistoryRetention := 720 * time.Hour
+	loginHistoryRetention := "720h"
+	setEnv(t, "SJP_LOGIN_HISTORY_RETENTION", loginHistoryRetention)
+	expectedPasswordResetTokenLifetime := 30 * time.Minute
+	passwordResetTokenLifetime := "30m"
+	setEnv(t, "SJP_PASSWORD_RESET_TOKEN_LIFETIME", passwordResetTokenLifetime)
+	expectedInvitationLifetime := 72 * time.Hour
+	invitationLifetime := "72h"
+	setEnv(t, "SJP_INVITATION_LIFETIME", invitationLifetime)
+	expectedCleanupInterval := 15 * time.Minute
+	cleanupInterval := "15m"
+	setEnv(t, "SJP_CLEANUP_INTERVAL", cleanupInterval)
+	expectedCleanupUnverifiedUserRetention := 720 * time.Hour
+	cleanupUnverifiedUserRetention := "720h"
+	setEnv(t, "SJP_CLEANUP_UNVERIFIED_USER_RETENTION", cleanupUnverifiedUserRetention)
+	tokenHMACKey := "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+	setEnv(t, "SJP_TOKEN_HMAC_KEY", tokenHMACKey)
+	expectedEMailLowercaseLocalPart := true
+	emailLowercaseLocalPart := "true"
+	setEnv(t, "SJP_EMAIL_LOWERCASE_LOCAL_PART", emailLowercaseLocalPart)
+	expectedRealmsEnable := true
+	realmsEnable := "true"
+	setEnv(t, "SJP_REALMS_ENABLE", realmsEnable)
+	realmsMailTemplatesFolderPath := "myRealmsMailTemplatesFolderPath"
+	setEnv(t, "SJP_REALMS_MAIL_TEMPLATES_FOLDER_PATH", realmsMailTemplatesFolderPath)
 
 	cfg, err := newConfig()
 	if err != nil {
@@ -65,13 +155,25 @@ func TestNewConfig(t *testing.T) {
 	fieldEqual(t, "jwt>privateKey", cfg.JWT.PrivateKey, jwtPrivateKey)
 	fieldEqual(t, "jwt>audience", cfg.JWT.Audience, jwtAudience)
 	fieldEqual(t, "jwt>issuer", cfg.JWT.Issuer, jwtIssuer)
-	fieldEqual(t, "jwt>subject", cfg.JWT.Subject, jwtSubject)
+	fieldEqual(t, "db>type", cfg.DB.Type, dbType)
 	fieldEqual(t, "db>host", cfg.DB.Host, dbHost)
 	fieldEqual(t, "db>port", cfg.DB.Port, expectedDBPort)
 	fieldEqual(t, "db>name", cfg.DB.Name, dbName)
 	fieldEqual(t, "db>username", cfg.DB.Username, dbUsername)
 	fieldEqual(t, "db>password", cfg.DB.Password, dbPassword)
+	fieldEqual(t, "db>file", cfg.DB.File, dbFile)
+	fieldEqual(t, "db>snapshotFile", cfg.DB.SnapshotFile, dbSnapshotFile)
+	fieldEqual(t, "db>seedFile", cfg.DB.SeedFile, dbSeedFile)
 	fieldEqual(t, "db>migrationsFolderPath", cfg.DB.MigrationsFolderPath, dbMigrationsFolderPath)
+	fieldEqual(t, "db>dsn", cfg.DB.DSN, dbDSN)
+	//noinspection GoBoolExpressions
+	fieldEqual(t, "db>tls>enable", cfg.DB.TLS.Enable, expectedDBTLSEnable)
+	fieldEqual(t, "db>tls>caFile", cfg.DB.TLS.CAFile, dbTLSCAFile)
+	fieldEqual(t, "db>tls>certFile", cfg.DB.TLS.CertFile, dbTLSCertFile)
+	fieldEqual(t, "db>tls>keyFile", cfg.DB.TLS.KeyFile, dbTLSKeyFile)
+	//noinspection GoBoolExpressions
+	fieldEqual(t, "db>tls>insecureSkipVerify", cfg.DB.TLS.InsecureSkipVerify, expectedDBTLSInsecureSkipVerify)
+	fieldEqual(t, "db>tls>serverName", cfg.DB.TLS.ServerName, dbTLSServerName)
 	//noinspection GoBoolExpressions
 	fieldEqual(t, "adminAPI>enable", cfg.AdminAPI.Enable, expectedAdminAPIEnable)
 	fieldEqual(t, "adminAPI>username", cfg.AdminAPI.Username, adminAPIUsername)
@@ -84,6 +186,33 @@ func TestNewConfig(t *testing.T) {
 	//noinspection GoBoolExpressions
 	fieldEqual(t, "mail>tls>insecureSkipVerify", cfg.Mail.TLS.InsecureSkipVerify, expectedMailTLSServerName)
 	fieldEqual(t, "mail>tls>serverName", cfg.Mail.TLS.ServerName, mailTLSServerName)
+	fieldEqual(t, "passwordBreach>datasetPath", cfg.PasswordBreach.DatasetPath, passwordBreachDatasetPath)
+	fieldEqual(t, "passwordBreach>datasetFormat", cfg.PasswordBreach.DatasetFormat, passwordBreachDatasetFormat)
+	fieldEqual(t, "passwordBreach>minCount", cfg.PasswordBreach.MinCount, expectedPasswordBreachMinCount)
+	//noinspection GoBoolExpressions
+	fieldEqual(t, "passwordBreach>warnOnly", cfg.PasswordBreach.WarnOnly, expectedPasswordBreachWarnOnly)
+	fieldEqual(t, "passwordHistory>size", cfg.PasswordHistory.Size, expectedPasswordHistorySize)
+	fieldEqual(t, "passwordExpiry>maxAgeDays", cfg.PasswordExpiry.MaxAgeDays, expectedPasswordExpiryMaxAgeDays)
+	fieldEqual(t, "passwordExpiry>reminderDays", cfg.PasswordExpiry.ReminderDays, expectedPasswordExpiryReminderDays)
+	fieldEqual(t, "mfa>totpEncryptionKey", cfg.MFA.TOTPEncryptionKey, mfaTOTPEncryptionKey)
+	fieldEqual(t, "mfa>totpIssuer", cfg.MFA.TOTPIssuer, mfaTOTPIssuer)
+	fieldEqual(t, "mfa>tokenLifetime", cfg.MFA.TokenLifetime, expectedMFATokenLifetime)
+	fieldEqual(t, "webAuthn>rpID", cfg.WebAuthn.RPID, webAuthnRPID)
+	fieldEqual(t, "webAuthn>rpName", cfg.WebAuthn.RPName, webAuthnRPName)
+	fieldEqual(t, "webAuthn>origins", cfg.WebAuthn.Origins, expectedWebAuthnOrigins)
+	fieldEqual(t, "magicLink>lifetime", cfg.MagicLink.Lifetime, expectedMagicLinkLifetime)
+	fieldEqual(t, "loginCode>lifetime", cfg.LoginCode.Lifetime, expectedLoginCodeLifetime)
+	fieldEqual(t, "loginCode>maxAttempts", cfg.LoginCode.MaxAttempts, expectedLoginCodeMaxAttempts)
+	fieldEqual(t, "loginHistory>retention", cfg.LoginHistory.Retention, expectedLoginHistoryRetention)
+	fieldEqual(t, "passwordReset>tokenLifetime", cfg.PasswordReset.TokenLifetime, expectedPasswordResetTokenLifetime)
+	fieldEqual(t, "invitation>lifetime", cfg.Invitation.Lifetime, expectedInvitationLifetime)
+	fieldEqual(t, "cleanup>interval", cfg.Cleanup.Interval, expectedCleanupInterval)
+	fieldEqual(t, "cleanup>unverifiedUserRetention", cfg.Cleanup.UnverifiedUserRetention, expectedCleanupUnverifiedUserRetention)
+	fieldEqual(t, "token>hmacKey", cfg.Token.HMACKey, tokenHMACKey)
+	fieldEqual(t, "email>lowercaseLocalPart", cfg.EMail.LowercaseLocalPart, expectedEMailLowercaseLocalPart)
+	//noinspection GoBoolExpressions
+	fieldEqual(t, "realms>enable", cfg.Realms.Enable, expectedRealmsEnable)
+	fieldEqual(t, "realms>mailTemplatesFolderPath", cfg.Realms.MailTemplatesFolderPath, realmsMailTemplatesFolderPath)
 }
 
 func TestNewConfigWithAdminAPIConstraint(t *testing.T) {
@@ -93,7 +222,6 @@ func TestNewConfigWithAdminAPIConstraint(t *testing.T) {
 	setEnv(t, "SJP_JWT_PRIVATE_KEY", "myJWTKey")
 	setEnv(t, "SJP_JWT_AUDIENCE", "myJWTAudience")
 	setEnv(t, "SJP_JWT_ISSUER", "myJWTIssuer")
-	setEnv(t, "SJP_JWT_SUBJECT", "myJWTSubject")
 	setEnv(t, "SJP_DB_HOST", "myDBHost")
 	setEnv(t, "SJP_DB_PORT", "555")
 	setEnv(t, "SJP_DB_NAME", "myDBName")
@@ -133,6 +261,143 @@ func TestNewConfigWithAdminAPIConstraint(t *testing.T) {
 	cleanupEnvs(t)
 }
 
+func TestNewConfigWithDBConstraints(t *testing.T) {
+	tests := []struct {
+		name           string
+		dbType         string
+		dbHost         string
+		dbDSN          string
+		dbTLSCertFile  string
+		dbTLSKeyFile   string
+		expectedDBPort int
+		expectedError  error
+	}{
+		{
+			name:           "postgres",
+			dbType:         "postgres",
+			dbHost:         "myDBHost",
+			expectedDBPort: 5432,
+		},
+		{
+			name:          "postgres without host",
+			dbType:        "postgres",
+			expectedError: errors.New("db-host must be set if db-type is 'postgres'"),
+		},
+		{
+			name:           "mysql",
+			dbType:         "mysql",
+			dbHost:         "myDBHost",
+			expectedDBPort: 3306,
+		},
+		{
+			name:           "mysql with dsn",
+			dbType:         "mysql",
+			dbDSN:          "myDBDSN",
+			expectedDBPort: 3306,
+		},
+		{
+			name:          "mysql without host and dsn",
+			dbType:        "mysql",
+			expectedError: errors.New("db-host or db-dsn must be set if db-type is 'mysql'"),
+		},
+		{
+			name:           "mysql with tls client certificate",
+			dbType:         "mysql",
+			dbHost:         "myDBHost",
+			dbTLSCertFile:  "myDBTLSCertFile",
+			dbTLSKeyFile:   "myDBTLSKeyFile",
+			expectedDBPort: 3306,
+		},
+		{
+			name:          "mysql with tls client certificate without key",
+			dbType:        "mysql",
+			dbHost:        "myDBHost",
+			dbTLSCertFile: "myDBTLSCertFile",
+			expectedError: errors.New("db-tls-cert-file and db-tls-key-file must be set together"),
+		},
+		{
+			name:           "sqlite without host",
+			dbType:         "sqlite",
+			expectedDBPort: 5432,
+		},
+		{
+			name:           "memory without host",
+			dbType:         "memory",
+			expectedDBPort: 5432,
+		},
+		{
+			name:          "unknown type",
+			dbType:        "oracle",
+			dbHost:        "myDBHost",
+			expectedError: errors.New("db-type must be one of 'postgres', 'mysql', 'sqlite' or 'memory'"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cleanupEnvs(t)
+			defer cleanupEnvs(t)
+
+			setEnv(t, "SJP_JWT_PRIVATE_KEY", "myJWTKey")
+			setEnv(t, "SJP_MAIL_SMTP_HOST", "myMailSMTPHost")
+			setEnv(t, "SJP_MAIL_SMTP_USERNAME", "myMailSMTPUsername")
+			setEnv(t, "SJP_MAIL_SMTP_PASSWORD", "myMailSMTPPassword")
+			setEnv(t, "SJP_DB_TYPE", tt.dbType)
+			setEnv(t, "SJP_DB_HOST", tt.dbHost)
+			setEnv(t, "SJP_DB_DSN", tt.dbDSN)
+			setEnv(t, "SJP_DB_TLS_CERT_FILE", tt.dbTLSCertFile)
+			setEnv(t, "SJP_DB_TLS_KEY_FILE", tt.dbTLSKeyFile)
+
+			cfg, err := newConfig()
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("returned error is not as expected. Expected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			if err == nil {
+				fieldEqual(t, "db>port", cfg.DB.Port, tt.expectedDBPort)
+			}
+		})
+	}
+}
+
+func TestNewConfigWithInvalidPasswordBreachDatasetFormat(t *testing.T) {
+	cleanupEnvs(t)
+
+	setEnv(t, "SJP_JWT_PRIVATE_KEY", "myJWTKey")
+	setEnv(t, "SJP_DB_HOST", "myDBHost")
+	setEnv(t, "SJP_MAIL_SMTP_HOST", "myMailSMTPHost")
+	setEnv(t, "SJP_MAIL_SMTP_USERNAME", "myMailSMTPUsername")
+	setEnv(t, "SJP_MAIL_SMTP_PASSWORD", "myMailSMTPPassword")
+	setEnv(t, "SJP_PASSWORD_BREACH_DATASET_FORMAT", "csv")
+
+	_, err := newConfig()
+	expectedError := errors.New("password-breach-dataset-format must be one of 'hibp' or 'list'")
+	if fmt.Sprint(err) != fmt.Sprint(expectedError) {
+		t.Fatalf("returned error is not as expected. Expected:\n%s\nGiven:\n%s", expectedError, err)
+	}
+
+	cleanupEnvs(t)
+}
+
+func TestNewConfigWithWebAuthnOriginsConstraint(t *testing.T) {
+	cleanupEnvs(t)
+
+	setEnv(t, "SJP_JWT_PRIVATE_KEY", "myJWTKey")
+	setEnv(t, "SJP_DB_HOST", "myDBHost")
+	setEnv(t, "SJP_MAIL_SMTP_HOST", "myMailSMTPHost")
+	setEnv(t, "SJP_MAIL_SMTP_USERNAME", "myMailSMTPUsername")
+	setEnv(t, "SJP_MAIL_SMTP_PASSWORD", "myMailSMTPPassword")
+	setEnv(t, "SJP_WEBAUTHN_RP_ID", "example.com")
+
+	_, err := newConfig()
+	expectedError := errors.New("webauthn-origins must be set if webauthn-rp-id has been set")
+	if fmt.Sprint(err) != fmt.Sprint(expectedError) {
+		t.Fatalf("returned error is not as expected. Expected:\n%s\nGiven:\n%s", expectedError, err)
+	}
+
+	cleanupEnvs(t)
+}
+
 func TestNewConfigCfgLibErrorHandling(t *testing.T) {
 	cleanupEnvs(t)
 
@@ -192,12 +457,22 @@ func cleanupEnvs(t *testing.T) {
 	unsetEnv(t, "SJP_JWT_PRIVATE_KEY")
 	unsetEnv(t, "SJP_JWT_AUDIENCE")
 	unsetEnv(t, "SJP_JWT_ISSUER")
-	unsetEnv(t, "SJP_JWT_SUBJECT")
+	unsetEnv(t, "SJP_DB_TYPE")
 	unsetEnv(t, "SJP_DB_HOST")
 	unsetEnv(t, "SJP_DB_PORT")
 	unsetEnv(t, "SJP_DB_NAME")
 	unsetEnv(t, "SJP_DB_USERNAME")
 	unsetEnv(t, "SJP_DB_PASSWORD")
+	unsetEnv(t, "SJP_DB_FILE")
+	unsetEnv(t, "SJP_DB_SNAPSHOT_FILE")
+	unsetEnv(t, "SJP_DB_SEED_FILE")
+	unsetEnv(t, "SJP_DB_DSN")
+	unsetEnv(t, "SJP_DB_TLS_ENABLE")
+	unsetEnv(t, "SJP_DB_TLS_CA_FILE")
+	unsetEnv(t, "SJP_DB_TLS_CERT_FILE")
+	unsetEnv(t, "SJP_DB_TLS_KEY_FILE")
+	unsetEnv(t, "SJP_DB_TLS_INSECURE_SKIP_VERIFY")
+	unsetEnv(t, "SJP_DB_TLS_SERVER_NAME")
 	unsetEnv(t, "SJP_DB_MIGRATIONS_FOLDER_PATH")
 	unsetEnv(t, "SJP_MAIL_TEMPLATES_FOLDER_PATH")
 	unsetEnv(t, "SJP_MAIL_SMTP_HOST")
@@ -209,4 +484,29 @@ func cleanupEnvs(t *testing.T) {
 	unsetEnv(t, "SJP_ADMIN_API_ENABLE")
 	unsetEnv(t, "SJP_ADMIN_API_USERNAME")
 	unsetEnv(t, "SJP_ADMIN_API_PASSWORD")
+	unsetEnv(t, "SJP_PASSWORD_BREACH_DATASET_PATH")
+	unsetEnv(t, "SJP_PASSWORD_BREACH_DATASET_FORMAT")
+	unsetEnv(t, "SJP_PASSWORD_BREACH_MIN_COUNT")
+	unsetEnv(t, "SJP_PASSWORD_BREACH_WARN_ONLY")
+	unsetEnv(t, "SJP_PASSWORD_HISTORY_SIZE")
+	unsetEnv(t, "SJP_PASSWORD_EXPIRY_MAX_AGE_DAYS")
+	unsetEnv(t, "SJP_PASSWORD_EXPIRY_REMINDER_DAYS")
+	unsetEnv(t, "SJP_MFA_TOTP_ENCRYPTION_KEY")
+	unsetEnv(t, "SJP_MFA_TOTP_ISSUER")
+	unsetEnv(t, "SJP_MFA_TOKEN_LIFETIME")
+	unsetEnv(t, "SJP_WEBAUTHN_RP_ID")
+	unsetEnv(t, "SJP_WEBAUTHN_RP_NAME")
+	unsetEnv(t, "SJP_WEBAUTHN_ORIGINS")
+	unsetEnv(t, "SJP_MAGIC_LINK_LIFETIME")
+	unsetEnv(t, "SJP_LOGIN_CODE_LIFETIME")
+	unsetEnv(t, "SJP_LOGIN_CODE_MAX_ATTEMPTS")
+	unsetEnv(t, "SJP_LOGIN_HISTORY_RETENTION")
+	unsetEnv(t, "SJP_PASSWORD_RESET_TOKEN_LIFETIME")
+	unsetEnv(t, "SJP_INVITATION_LIFETIME")
+	unsetEnv(t, "SJP_CLEANUP_INTERVAL")
+	unsetEnv(t, "SJP_CLEANUP_UNVERIFIED_USER_RETENTION")
+	unsetEnv(t, "SJP_TOKEN_HMAC_KEY")
+	unsetEnv(t, "SJP_EMAIL_LOWERCASE_LOCAL_PART")
+	unsetEnv(t, "SJP_REALMS_ENABLE")
+	unsetEnv(t, "SJP_REALMS_MAIL_TEMPLATES_FOLDER_PATH")
 }
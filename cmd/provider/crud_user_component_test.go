@@ -12,7 +12,7 @@ func TestCRUDUser(t *testing.T) {
 	// 2) login
 	// 3) update user
 	// 4) login
-	// 5) get user
+	// 5) get user by email and id
 	// 6) delete user
 	// 7) login
 
@@ -36,16 +36,25 @@ func TestCRUDUser(t *testing.T) {
 
 	// 5)
 	expectedUser := User{
-		EMail:    email,
-		Password: "**********",
+		EMail:        email,
+		DisplayEMail: email,
+		Password:     "**********",
 		Claims: map[string]interface{}{
 			"myClaim": 5,
 		},
 	}
 	user := readUser(t, email)
+	if user.ID == "" {
+		t.Fatal("user id has not been set")
+	}
+	expectedUser.ID = user.ID
 	if fmt.Sprint(user) != fmt.Sprint(expectedUser) {
 		t.Fatalf("user is not as expected. Expected:\n%#v\nGiven:\n%#v", expectedUser, user)
 	}
+	user = readUser(t, expectedUser.ID)
+	if fmt.Sprint(user) != fmt.Sprint(expectedUser) {
+		t.Fatalf("user read by id is not as expected. Expected:\n%#v\nGiven:\n%#v", expectedUser, user)
+	}
 
 	// 6)
 	deleteUser(t, email)
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/mailaddr"
+	"github.com/sirupsen/logrus"
+	"sort"
+)
+
+// normalizeEMails normalizes all stored emails like they will be normalized on creation and login. The email
+// normalization migration neither converts internationalized domains to punycode nor lowercases local parts, which can
+// be enabled at any time. Colliding users and users with invalid emails will be skipped and logged to the given logger
+// since they have to be resolved manually.
+func normalizeEMails(s providerStorage, lowercaseLocalPart bool, log logrus.FieldLogger) error {
+	emails, err := s.UserEMails()
+	if err != nil {
+		return fmt.Errorf("failed to query emails: %w", err)
+	}
+
+	usersByEMail := map[string][]string{}
+	for id, email := range emails {
+		normalized, err := mailaddr.Normalize(email, lowercaseLocalPart)
+		if err != nil {
+			log.WithField("email", email).Warn("User has an invalid email and has to be resolved manually")
+			continue
+		}
+
+		usersByEMail[normalized] = append(usersByEMail[normalized], id)
+	}
+
+	var count int
+	for normalized, ids := range usersByEMail {
+		if len(ids) > 1 {
+			collision := make([]string, 0, len(ids))
+			for _, id := range ids {
+				collision = append(collision, emails[id])
+			}
+			sort.Strings(collision)
+
+			log.WithField("emails", collision).Warn("Users collide after email normalization and have to be resolved manually")
+			continue
+		}
+
+		if emails[ids[0]] == normalized {
+			continue
+		}
+
+		err = s.NormalizeUserEMail(ids[0], normalized)
+		if err != nil {
+			return fmt.Errorf("failed to normalize email of user %q: %w", ids[0], err)
+		}
+		count++
+	}
+
+	if count > 0 {
+		log.Infof("Normalized %d emails", count)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+// +build component
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestEMailNormalization(t *testing.T) {
+	// 1) create user with mixed case domain
+	// 2) login with differently cased domain and surrounding whitespaces
+	// 3) get user with its display email
+	// 4) login with malformed email
+	// 5) delete user
+
+	displayEMail := "Normalization_Test@LeberKleber.IO"
+	email := "Normalization_Test@leberkleber.io"
+	password := "s3cr3t"
+
+	// 1)
+	createUser(t, displayEMail, password)
+
+	// 2)
+	_, ok := loginUser(t, " normalization_test@LEBERKLEBER.io ", password)
+	if ok {
+		t.Fatal("Login with differently cased local part should not be possible by default")
+	}
+	_, ok = loginUser(t, " Normalization_Test@LEBERKLEBER.io ", password)
+	if !ok {
+		t.Fatal("Failed to login with normalized email")
+	}
+
+	// 3)
+	expectedUser := User{
+		EMail:        email,
+		DisplayEMail: displayEMail,
+		Password:     "**********",
+		Claims: map[string]interface{}{
+			"myCustomClaim": "customClaimValue",
+		},
+	}
+	user := readUser(t, displayEMail)
+	expectedUser.ID = user.ID
+	if fmt.Sprint(user) != fmt.Sprint(expectedUser) {
+		t.Fatalf("user is not as expected. Expected:\n%#v\nGiven:\n%#v", expectedUser, user)
+	}
+
+	// 4)
+	postJSON(t, "/v1/auth/login", fmt.Sprintf(`{"email": %q, "password": %q}`, "Normalization_Test@", password), http.StatusBadRequest, nil)
+
+	// 5)
+	deleteUser(t, email)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage/memory"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeEMails(t *testing.T) {
+	tests := []struct {
+		name               string
+		lowercaseLocalPart bool
+		givenEMails        []string
+		expectedEMails     map[string]string
+	}{
+		{
+			name:        "Happycase",
+			givenEMails: []string{"Alice@Bücher.example", " bob@EXAMPLE.com ", "carol@example.com"},
+			expectedEMails: map[string]string{
+				"Alice@Bücher.example": "Alice@xn--bcher-kva.example",
+				" bob@EXAMPLE.com ":    "bob@example.com",
+				"carol@example.com":    "carol@example.com",
+			},
+		},
+		{
+			name:               "Lowercase local part",
+			lowercaseLocalPart: true,
+			givenEMails:        []string{"Alice@example.com", "bob@example.com"},
+			expectedEMails: map[string]string{
+				"Alice@example.com": "alice@example.com",
+				"bob@example.com":   "bob@example.com",
+			},
+		},
+		{
+			name:        "Collisions will be skipped",
+			givenEMails: []string{"alice@Example.com", "alice@example.com ", "bob@EXAMPLE.com"},
+			expectedEMails: map[string]string{
+				"alice@Example.com":  "alice@Example.com",
+				"alice@example.com ": "alice@example.com ",
+				"bob@EXAMPLE.com":    "bob@example.com",
+			},
+		},
+		{
+			name:        "Invalid emails will be skipped",
+			givenEMails: []string{"alice@", "bob@EXAMPLE.com"},
+			expectedEMails: map[string]string{
+				"alice@":          "alice@",
+				"bob@EXAMPLE.com": "bob@example.com",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := memory.New()
+			ids := map[string]string{}
+			for i, email := range tt.givenEMails {
+				id := fmt.Sprintf("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7%d", i)
+				err := s.CreateUser(storage.User{ID: id, EMail: email})
+				if err != nil {
+					t.Fatalf("Failed to create user: %s", err)
+				}
+				ids[email] = id
+			}
+
+			log := logrus.New()
+			log.Out = ioutil.Discard
+
+			err := normalizeEMails(s, tt.lowercaseLocalPart, log)
+			if err != nil {
+				t.Fatalf("Failed to normalize emails: %s", err)
+			}
+
+			emails := map[string]string{}
+			for email, id := range ids {
+				u, err := s.UserByID(id)
+				if err != nil {
+					t.Fatalf("Failed to find user: %s", err)
+				}
+				if u.DisplayEMail != email {
+					t.Errorf("Display email is not as expected. Expected: %q, Given: %q", email, u.DisplayEMail)
+				}
+				emails[email] = u.EMail
+			}
+
+			if !reflect.DeepEqual(emails, tt.expectedEMails) {
+				t.Errorf("Emails are not as expected. Expected:\n%#v\nGiven:\n%#v", tt.expectedEMails, emails)
+			}
+		})
+	}
+}
@@ -13,9 +13,13 @@ import (
 )
 
 type User struct {
-	EMail    string                 `json:"email,omitempty"`
-	Password string                 `json:"password,omitempty"`
-	Claims   map[string]interface{} `json:"claims,omitempty"`
+	ID           string                 `json:"id,omitempty"`
+	EMail        string                 `json:"email,omitempty"`
+	DisplayEMail string                 `json:"display_email,omitempty"`
+	Username     string                 `json:"username,omitempty"`
+	Phone        string                 `json:"phone,omitempty"`
+	Password     string                 `json:"password,omitempty"`
+	Claims       map[string]interface{} `json:"claims,omitempty"`
 }
 
 func createUser(t *testing.T, email, password string) {
@@ -144,3 +148,32 @@ func deleteUser(t *testing.T, email string) {
 		t.Errorf("Invalid response status code. Expected: %d, Given: %d, Body: %s", http.StatusOK, resp.StatusCode, respBody)
 	}
 }
+
+func patchUser(t *testing.T, email, contentType, patch string, expectedStatusCode int) {
+	t.Helper()
+	req, err := http.NewRequest(
+		http.MethodPatch,
+		fmt.Sprintf("http://simple-jwt-provider/v1/admin/users/%s", url.PathEscape(email)),
+		bytes.NewReader([]byte(patch)),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create http request")
+	}
+
+	req.SetBasicAuth("username", "password")
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to patch user cause: %s", err)
+	}
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Errorf("Failed to read response body")
+	}
+
+	if resp.StatusCode != expectedStatusCode {
+		t.Errorf("Invalid response status code. Expected: %d, Given: %d, Body: %s", expectedStatusCode, resp.StatusCode, respBody)
+	}
+}
@@ -0,0 +1,80 @@
+// +build component
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+)
+
+func TestInvitation(t *testing.T) {
+	// 1) create user without password
+	// 2) user is pending
+	// 3) accept invitation with token from mail
+	// 4) login with new password
+	// 5) invitation can not be resent anymore
+
+	email := "invitation_test@leberkleber.io"
+	password := "s3cr3t"
+
+	// 1)
+	adminRequest(t, http.MethodPost, "http://simple-jwt-provider/v1/admin/users", fmt.Sprintf(`{"email": %q}`, email), http.StatusCreated, nil)
+
+	// 2)
+	user := struct {
+		InvitationPending bool `json:"invitation_pending"`
+	}{}
+	adminRequest(t, http.MethodGet, "http://simple-jwt-provider/v1/admin/users/"+email, "", http.StatusOK, &user)
+	if !user.InvitationPending {
+		t.Error("invitation of user is not pending")
+	}
+
+	// 3)
+	token := findInvitationTokenFromMail(t, email)
+	postJSON(t, "/v1/auth/invitation/accept", fmt.Sprintf(`{"email": %q, "token": %q, "password": %q}`, email, "wrong", password), http.StatusBadRequest, nil)
+	postJSON(t, "/v1/auth/invitation/accept", fmt.Sprintf(`{"email": %q, "token": %q, "password": %q}`, email, token, password), http.StatusNoContent, nil)
+
+	// 4)
+	_, ok := loginUser(t, email, password)
+	if !ok {
+		t.Fatal("login after accepted invitation failed")
+	}
+
+	// 5)
+	adminRequest(t, http.MethodPost, "http://simple-jwt-provider/v1/admin/users/"+email+"/invitation", "", http.StatusConflict, nil)
+}
+
+func findInvitationTokenFromMail(t *testing.T, email string) string {
+	t.Helper()
+	resp, err := http.Get("http://mail-server:8025/api/v2/messages")
+	if err != nil {
+		t.Fatalf("Failed to fetch mails cause: %s", err)
+	}
+	defer resp.Body.Close()
+
+	var mailhogRes MailhogResponse
+	err = json.NewDecoder(resp.Body).Decode(&mailhogRes)
+	if err != nil {
+		t.Fatalf("failed to encode smtp-server api-response: %s", err)
+	}
+
+	reg := regexp.MustCompile(`password with the token ([a-f0-9]{64})`)
+	for _, r := range mailhogRes.Items {
+		for i := range r.Raw.To {
+			if r.Raw.To[i] != email {
+				continue
+			}
+
+			res := reg.FindStringSubmatch(r.Raw.Data)
+			if len(res) == 2 {
+				return res[1]
+			}
+		}
+	}
+
+	t.Fatal("could not find invitation mail")
+	return ""
+}
@@ -0,0 +1,63 @@
+// +build component
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+)
+
+func TestLoginCodeLogin(t *testing.T) {
+	email := "loginCodeTest@leberkleber.io"
+	password := "s3cr3t"
+
+	createUser(t, email, password)
+	postJSON(t, "/v1/auth/login-code", fmt.Sprintf(`{"email": %q}`, email), http.StatusCreated, nil)
+	code := findLoginCodeFromMail(t, email)
+
+	postJSON(t, "/v1/auth/login-code/redeem", fmt.Sprintf(`{"email": %q, "code": %q}`, email, "wrong"), http.StatusUnauthorized, nil)
+
+	var loginResponse struct {
+		AccessToken string `json:"access_token"`
+	}
+	postJSON(t, "/v1/auth/login-code/redeem", fmt.Sprintf(`{"email": %q, "code": %q}`, email, code), http.StatusOK, &loginResponse)
+	validateJWT(t, loginResponse.AccessToken)
+
+	// login codes can be used once
+	postJSON(t, "/v1/auth/login-code/redeem", fmt.Sprintf(`{"email": %q, "code": %q}`, email, code), http.StatusUnauthorized, nil)
+}
+
+func findLoginCodeFromMail(t *testing.T, email string) string {
+	t.Helper()
+	resp, err := http.Get("http://mail-server:8025/api/v2/messages")
+	if err != nil {
+		t.Fatalf("Failed to fetch mails cause: %s", err)
+	}
+	defer resp.Body.Close()
+
+	var mailhogRes MailhogResponse
+	err = json.NewDecoder(resp.Body).Decode(&mailhogRes)
+	if err != nil {
+		t.Fatalf("failed to encode smtp-server api-response: %s", err)
+	}
+
+	reg := regexp.MustCompile(`login code is ([0-9]{6})`)
+	for _, r := range mailhogRes.Items {
+		for i := range r.Raw.To {
+			if r.Raw.To[i] != email {
+				continue
+			}
+
+			res := reg.FindStringSubmatch(r.Raw.Data)
+			if len(res) == 2 {
+				return res[1]
+			}
+		}
+	}
+
+	t.Fatal("could not find login code mail")
+	return ""
+}
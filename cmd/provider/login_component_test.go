@@ -36,7 +36,7 @@ func TestLogin(t *testing.T) {
 		t.Errorf("unexpected iss-privateClaim value. Expected: %q. Given: %q", expectedJWTIssuer, claims["iss"])
 	}
 
-	expectedJWTSubject := "<subject>"
+	expectedJWTSubject := readUser(t, email).ID
 	if claims["sub"] != expectedJWTSubject {
 		t.Errorf("unexpected sub-privateClaim value. Expected: %q. Given: %q", expectedJWTSubject, claims["sub"])
 	}
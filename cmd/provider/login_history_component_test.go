@@ -0,0 +1,63 @@
+// +build component
+
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestLoginHistory(t *testing.T) {
+	// 1) create user
+	// 2) login with wrong password
+	// 3) login
+	// 4) last login is set
+	// 5) both attempts are in the login history
+
+	email := "login_history_test@leberkleber.io"
+	password := "s3cr3t"
+
+	// 1)
+	createUser(t, email, password)
+
+	// 2)
+	_, ok := loginUser(t, email, "wrong")
+	if ok {
+		t.Fatal("login with wrong password succeeded")
+	}
+
+	// 3)
+	_, ok = loginUser(t, email, password)
+	if !ok {
+		t.Fatal("login failed")
+	}
+
+	// 4)
+	user := struct {
+		LastLoginAt *time.Time `json:"last_login_at"`
+	}{}
+	adminRequest(t, http.MethodGet, "http://simple-jwt-provider/v1/admin/users/"+email, "", http.StatusOK, &user)
+	if user.LastLoginAt == nil {
+		t.Error("last login has not been set")
+	}
+
+	// 5)
+	history := struct {
+		Logins []struct {
+			Outcome string `json:"outcome"`
+			Factor  string `json:"factor"`
+		} `json:"logins"`
+		Total int `json:"total"`
+	}{}
+	adminRequest(t, http.MethodGet, "http://simple-jwt-provider/v1/admin/users/"+email+"/logins?limit=10", "", http.StatusOK, &history)
+	if history.Total != 2 || len(history.Logins) != 2 {
+		t.Fatalf("unexpected count of logins. Expected: 2, Given: %d / %d", history.Total, len(history.Logins))
+	}
+	if history.Logins[0].Outcome != "success" || history.Logins[1].Outcome != "failed" {
+		t.Errorf("unexpected login outcomes. Given: %q, %q", history.Logins[0].Outcome, history.Logins[1].Outcome)
+	}
+	if history.Logins[0].Factor != "password" {
+		t.Errorf("unexpected login factor. Expected: %q, Given: %q", "password", history.Logins[0].Factor)
+	}
+}
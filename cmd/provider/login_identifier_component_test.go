@@ -0,0 +1,69 @@
+// +build component
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestLoginIdentifiers(t *testing.T) {
+	// 1) create user with username and phone but without email
+	// 2) login with username
+	// 3) login with phone number
+	// 4) get user by username
+	// 5) delete user by phone number
+
+	username := "identifier_test"
+	phone := "+491701234567"
+	password := "s3cr3t"
+
+	// 1)
+	req, err := http.NewRequest(
+		http.MethodPost,
+		"http://simple-jwt-provider/v1/admin/users",
+		bytes.NewReader([]byte(fmt.Sprintf(`{"username": %q, "phone": %q, "password": %q}`, username, phone, password))),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create http request")
+	}
+	req.SetBasicAuth("username", "password")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to create user cause: %s", err)
+	}
+	respBody, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Invalid response status code. Expected: %d, Given: %d, Body: %s", http.StatusCreated, resp.StatusCode, respBody)
+	}
+
+	// 2)
+	var loginResponse struct {
+		AccessToken string `json:"access_token"`
+	}
+	postJSON(t, "/v1/auth/login", fmt.Sprintf(`{"identifier": %q, "password": %q}`, "Identifier_Test", password), http.StatusOK, &loginResponse)
+	claims := validateJWT(t, loginResponse.AccessToken)
+	if _, ok := claims["email"]; ok {
+		t.Errorf("email-privateClaim must not be set for users without email. Given: %q", claims["email"])
+	}
+
+	// 3)
+	postJSON(t, "/v1/auth/login", fmt.Sprintf(`{"identifier": %q, "password": %q}`, "+49 170 1234567", password), http.StatusOK, nil)
+
+	// 4)
+	user := readUser(t, username)
+	if user.Username != username || user.Phone != phone || user.EMail != "" {
+		t.Fatalf("user is not as expected. Given:\n%#v", user)
+	}
+	if claims["sub"] != user.ID {
+		t.Errorf("unexpected sub-privateClaim value. Expected: %q. Given: %q", user.ID, claims["sub"])
+	}
+
+	// 5)
+	deleteUser(t, phone)
+}
@@ -0,0 +1,27 @@
+// +build component
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestMagicLinkLogin(t *testing.T) {
+	email := "magicLinkTest@leberkleber.io"
+	password := "s3cr3t"
+
+	createUser(t, email, password)
+	postJSON(t, "/v1/auth/magic-link", fmt.Sprintf(`{"email": %q}`, email), http.StatusCreated, nil)
+	token := findPasswordResetTokenFromMailAndVerifyContent(t, email)
+
+	var loginResponse struct {
+		AccessToken string `json:"access_token"`
+	}
+	postJSON(t, "/v1/auth/magic-link/redeem", fmt.Sprintf(`{"email": %q, "token": %q}`, email, token), http.StatusOK, &loginResponse)
+	validateJWT(t, loginResponse.AccessToken)
+
+	// magic links can be used once
+	postJSON(t, "/v1/auth/magic-link/redeem", fmt.Sprintf(`{"email": %q, "token": %q}`, email, token), http.StatusUnauthorized, nil)
+}
@@ -1,19 +1,31 @@
 package main
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/ardanlabs/conf"
 	"github.com/leberKleber/simple-jwt-provider/internal"
+	"github.com/leberKleber/simple-jwt-provider/internal/breach"
+	"github.com/leberKleber/simple-jwt-provider/internal/crypt"
+	"github.com/leberKleber/simple-jwt-provider/internal/jobs"
 	"github.com/leberKleber/simple-jwt-provider/internal/jwt"
 	"github.com/leberKleber/simple-jwt-provider/internal/mailer"
-	"github.com/leberKleber/simple-jwt-provider/internal/storage"
 	"github.com/leberKleber/simple-jwt-provider/internal/web"
+	"github.com/leberKleber/simple-jwt-provider/internal/webauthn"
 	"github.com/sirupsen/logrus"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	// database migration
 	_ "github.com/golang-migrate/migrate/v4/source/file"
-	// sql driver
+	// sql drivers
 	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
 )
 
 func main() {
@@ -29,22 +41,160 @@ func main() {
 	fmt.Print(cfgAsString)
 	logrus.Infof("Starting provider")
 
-	s, err := storage.New(cfg.DB.Host, cfg.DB.Port, cfg.DB.Username, cfg.DB.Password, cfg.DB.Name, false)
+	s, newRealmStorage, err := newStorage(cfg)
 	if err != nil {
 		logrus.WithError(err).Fatal("Could not create storage")
 	}
 
-	err = s.Migrate(cfg.DB.MigrationsFolderPath)
+	err = normalizeEMails(s, cfg.EMail.LowercaseLocalPart, logrus.StandardLogger())
 	if err != nil {
-		logrus.WithError(err).Fatal("Could not migrate database")
+		logrus.WithError(err).Error("Failed to normalize emails")
 	}
 
-	jwtGenerator, err := jwt.NewGenerator(cfg.JWT.PrivateKey, cfg.JWT.Audience, cfg.JWT.Issuer, cfg.JWT.Subject)
+	jwtGenerator, err := jwt.NewGenerator(cfg.JWT.PrivateKey, cfg.JWT.Audience, cfg.JWT.Issuer)
 	if err != nil {
 		logrus.WithError(err).Fatal("Failed to create jwt generator")
 	}
 
-	m, err := mailer.New(cfg.Mail.TemplatesFolderPath,
+	m, err := newMailer(cfg, cfg.Mail.TemplatesFolderPath)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create mailer")
+	}
+	if cfg.Mail.SMTPHost == "" {
+		logrus.Warn("No smtp host configured, mails will be logged instead of sent")
+	}
+
+	passwordBreachChecker, err := newPasswordBreachChecker(cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to load password breach dataset")
+	}
+
+	totpCrypter, err := newTOTPCrypter(cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create totp crypter")
+	}
+
+	tokenHasher, err := newTokenHasher(cfg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create token hasher")
+	}
+
+	provider := &internal.Provider{
+		Storage:                    s,
+		JWTGenerator:               jwtGenerator,
+		Mailer:                     m,
+		PasswordBreachChecker:      passwordBreachChecker,
+		PasswordBreachWarnOnly:     cfg.PasswordBreach.WarnOnly,
+		PasswordHistorySize:        cfg.PasswordHistory.Size,
+		PasswordMaxAgeDays:         cfg.PasswordExpiry.MaxAgeDays,
+		PasswordExpiryReminderDays: cfg.PasswordExpiry.ReminderDays,
+		TOTPCrypter:                totpCrypter,
+		TokenHasher:                tokenHasher,
+		TOTPIssuer:                 cfg.MFA.TOTPIssuer,
+		MFATokenLifetime:           cfg.MFA.TokenLifetime,
+		MagicLinkLifetime:          cfg.MagicLink.Lifetime,
+		LoginCodeLifetime:          cfg.LoginCode.Lifetime,
+		LoginCodeMaxAttempts:       cfg.LoginCode.MaxAttempts,
+		LowercaseEMailLocalPart:    cfg.EMail.LowercaseLocalPart,
+		LoginHistoryRetention:      cfg.LoginHistory.Retention,
+		PasswordResetTokenLifetime: cfg.PasswordReset.TokenLifetime,
+		InvitationLifetime:         cfg.Invitation.Lifetime,
+		UnverifiedUserRetention:    cfg.Cleanup.UnverifiedUserRetention,
+	}
+
+	if cfg.DB.SeedFile != "" {
+		err = seedUsers(provider, cfg.DB.SeedFile)
+		if err != nil {
+			logrus.WithError(err).Fatal("Failed to seed users")
+		}
+	}
+
+	if cfg.WebAuthn.RPID != "" {
+		provider.WebAuthn = webauthn.RelyingParty{
+			ID:      cfg.WebAuthn.RPID,
+			Name:    cfg.WebAuthn.RPName,
+			Origins: cfg.WebAuthn.Origins,
+			Timeout: cfg.MFA.TokenLifetime,
+		}
+	}
+
+	var realms *internal.Realms
+	if cfg.Realms.Enable {
+		factory := &realmProviderFactory{
+			cfg:             cfg,
+			newRealmStorage: newRealmStorage,
+			defaultProvider: provider,
+			realmStorages:   map[string]providerStorage{},
+		}
+
+		realmCrypter, err := newRealmCrypter(cfg)
+		if err != nil {
+			logrus.WithError(err).Fatal("Failed to create realm crypter")
+		}
+
+		realms, err = internal.NewRealms(s, realmCrypter, factory.newProvider)
+		if err != nil {
+			logrus.WithError(err).Fatal("Failed to load realms")
+		}
+	}
+
+	stop := make(chan struct{})
+
+	if cfg.PasswordExpiry.ReminderDays > 0 && cfg.Cleanup.Interval <= 0 {
+		logrus.Fatal("Password expiry reminders are sent by the cleanup jobs and require a cleanup interval > 0")
+	}
+
+	var webJobs web.Jobs
+	if cfg.Cleanup.Interval > 0 {
+		scheduler := newCleanupScheduler(s, provider, realms, cfg.Cleanup.Interval)
+		scheduler.Start(stop)
+		webJobs = scheduler
+	}
+
+	var server *web.Server
+	if realms != nil {
+		server = web.NewRealmServer(provider, webRealms{realms}, webJobs, cfg.AdminAPI.Enable, cfg.AdminAPI.Username, cfg.AdminAPI.Password)
+	} else {
+		server = web.NewRealmServer(provider, nil, webJobs, cfg.AdminAPI.Enable, cfg.AdminAPI.Username, cfg.AdminAPI.Password)
+	}
+
+	go func() {
+		if err := server.ListenAndServe(cfg.ServerAddress); err != nil {
+			logrus.WithError(err).Fatal("Failed to run server")
+		}
+	}()
+
+	awaitShutdownSignal()
+	logrus.Info("Stopping provider")
+	close(stop)
+
+	// persists the snapshot of the memory storage
+	err = s.Close()
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to close storage")
+	}
+}
+
+// awaitShutdownSignal blocks until the process receives SIGINT or SIGTERM
+func awaitShutdownSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	<-signals
+	signal.Stop(signals)
+}
+
+// newMailer creates the mailer with the templates of the given folder. Only the templates of the enabled mail features
+// are required.
+func newMailer(cfg config, templatesFolderPath string) (*mailer.Mailer, error) {
+	features := mailer.Features{
+		PasswordExpiryReminder: cfg.PasswordExpiry.ReminderDays > 0,
+		MagicLink:              cfg.MagicLink.Lifetime > 0,
+		LoginCode:              cfg.LoginCode.Lifetime > 0,
+		Invitation:             cfg.Invitation.Lifetime > 0,
+	}
+
+	return mailer.New(templatesFolderPath,
+		features,
 		cfg.Mail.SMTPUsername,
 		cfg.Mail.SMTPPassword,
 		cfg.Mail.SMTPHost,
@@ -52,14 +202,112 @@ func main() {
 		cfg.Mail.TLS.InsecureSkipVerify,
 		cfg.Mail.TLS.ServerName,
 	)
+}
+
+func newPasswordBreachChecker(cfg config) (internal.PasswordBreachChecker, error) {
+	if cfg.PasswordBreach.DatasetPath == "" {
+		return nil, nil
+	}
+
+	if cfg.PasswordBreach.DatasetFormat == "list" {
+		return breach.NewList(cfg.PasswordBreach.DatasetPath)
+	}
+
+	return breach.NewHIBPDataset(cfg.PasswordBreach.DatasetPath, cfg.PasswordBreach.MinCount)
+}
+
+func newTOTPCrypter(cfg config) (internal.SecretCrypter, error) {
+	if cfg.MFA.TOTPEncryptionKey == "" {
+		return nil, nil
+	}
+
+	key, err := hex.DecodeString(cfg.MFA.TOTPEncryptionKey)
 	if err != nil {
-		logrus.WithError(err).Fatal("Failed to create mailer")
+		return nil, fmt.Errorf("failed to decode mfa-totp-encryption-key: %w", err)
 	}
+	if len(key) != 32 {
+		return nil, errors.New("mfa-totp-encryption-key must be 32 bytes long")
+	}
+
+	return crypt.NewAESGCM(key)
+}
+
+// newTokenHasher creates the hasher of one-time tokens with the configured token-hmac-key. When no key has been
+// configured it will be derived from the jwt private key, so changing the jwt private key invalidates all open tokens.
+func newTokenHasher(cfg config) (internal.TokenHasher, error) {
+	if cfg.Token.HMACKey == "" {
+		mac := hmac.New(sha256.New, []byte(cfg.JWT.PrivateKey))
+		_, _ = mac.Write([]byte("simple-jwt-provider token-hmac-key"))
+		return crypt.NewHMACSHA256(mac.Sum(nil))
+	}
+
+	key, err := hex.DecodeString(cfg.Token.HMACKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode token-hmac-key: %w", err)
+	}
+
+	return crypt.NewHMACSHA256(key)
+}
+
+// newRealmCrypter creates the crypter of the jwt private keys of realms with the configured realms-encryption-key. When
+// no key has been configured it will be derived from the jwt private key, so the jwt private key must not be changed
+// while realms exist.
+func newRealmCrypter(cfg config) (internal.SecretCrypter, error) {
+	if cfg.Realms.EncryptionKey == "" {
+		mac := hmac.New(sha256.New, []byte(cfg.JWT.PrivateKey))
+		_, _ = mac.Write([]byte("simple-jwt-provider realms-encryption-key"))
+		return crypt.NewAESGCM(mac.Sum(nil))
+	}
+
+	key, err := hex.DecodeString(cfg.Realms.EncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode realms-encryption-key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, errors.New("realms-encryption-key must be 32 bytes long")
+	}
+
+	return crypt.NewAESGCM(key)
+}
+
+// newCleanupScheduler returns a scheduler which runs the maintenance jobs (purging expired one-time tokens, old login
+// history and unverified users, sending password-expiry reminders) for the default realm and all given realms (may be
+// nil) in the given interval. Each job will be locked by the given locker, so it runs on one instance at a time only.
+func newCleanupScheduler(locker jobs.Locker, provider *internal.Provider, realms *internal.Realms, interval time.Duration) *jobs.Scheduler {
+	return jobs.New(locker, interval,
+		jobs.Job{Name: "purge-tokens", Run: forAllRealms(provider, realms, func(p *internal.Provider) (int64, error) {
+			return p.PurgeTokens()
+		})},
+		jobs.Job{Name: "purge-login-history", Run: forAllRealms(provider, realms, func(p *internal.Provider) (int64, error) {
+			return p.PurgeLoginHistory()
+		})},
+		jobs.Job{Name: "purge-unverified-users", Run: forAllRealms(provider, realms, func(p *internal.Provider) (int64, error) {
+			return p.PurgeUnverifiedUsers()
+		})},
+		jobs.Job{Name: "remind-password-expiry", Run: forAllRealms(provider, realms, func(p *internal.Provider) (int64, error) {
+			return p.RemindPasswordExpiry()
+		})},
+	)
+}
+
+// forAllRealms returns a job which runs the given task for the default realm and all given realms (may be nil) and
+// returns the total count of affected entries
+func forAllRealms(provider *internal.Provider, realms *internal.Realms, task func(p *internal.Provider) (int64, error)) func() (int64, error) {
+	return func() (int64, error) {
+		providers := []*internal.Provider{provider}
+		if realms != nil {
+			providers = append(providers, realms.Providers()...)
+		}
 
-	provider := &internal.Provider{Storage: s, JWTGenerator: jwtGenerator, Mailer: m}
-	server := web.NewServer(provider, cfg.AdminAPI.Enable, cfg.AdminAPI.Username, cfg.AdminAPI.Password)
+		var count int64
+		for _, p := range providers {
+			n, err := task(p)
+			count += n
+			if err != nil {
+				return count, err
+			}
+		}
 
-	if err := server.ListenAndServe(cfg.ServerAddress); err != nil {
-		logrus.WithError(err).Fatal("Failed to run server")
+		return count, nil
 	}
 }
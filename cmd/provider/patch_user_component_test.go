@@ -0,0 +1,47 @@
+// +build component
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestPatchUser(t *testing.T) {
+	// 1) create user
+	// 2) add claim via merge patch
+	// 3) add claim via json patch
+	// 4) json patch with failing test operation
+	// 5) get user
+	// 6) delete user
+
+	email := "patch_test@leberkleber.io"
+
+	// 1)
+	createUser(t, email, "s3cr3t")
+
+	// 2)
+	patchUser(t, email, "application/merge-patch+json", `{"claims": {"role": "admin"}}`, http.StatusOK)
+
+	// 3)
+	patchUser(t, email, "application/json-patch+json", `[{"op": "add", "path": "/claims/team", "value": "a"}]`, http.StatusOK)
+
+	// 4)
+	patchUser(t, email, "application/json-patch+json",
+		`[{"op": "test", "path": "/claims/role", "value": "user"}, {"op": "remove", "path": "/claims"}]`, http.StatusConflict)
+
+	// 5)
+	user := readUser(t, email)
+	expectedClaims := map[string]interface{}{
+		"myCustomClaim": "customClaimValue",
+		"role":          "admin",
+		"team":          "a",
+	}
+	if fmt.Sprint(user.Claims) != fmt.Sprint(expectedClaims) {
+		t.Fatalf("claims are not as expected. Expected:\n%#v\nGiven:\n%#v", expectedClaims, user.Claims)
+	}
+
+	// 6)
+	deleteUser(t, email)
+}
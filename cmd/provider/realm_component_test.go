@@ -0,0 +1,125 @@
+// +build component
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"net/http"
+	"testing"
+)
+
+func TestRealms(t *testing.T) {
+	// 1) create realm
+	// 2) create user in realm
+	// 3) login in realm
+	// 4) login in default realm not possible
+	// 5) change realm issuer
+	// 6) login in realm with new issuer
+
+	realmURL := "http://simple-jwt-provider/v1/realms/component-test"
+	email := "realm_test@leberkleber.io"
+	password := "s3cr3t"
+
+	// 1)
+	realm := struct {
+		JWTPublicKey string `json:"jwt_public_key"`
+	}{}
+	adminRequest(t, http.MethodPost, "http://simple-jwt-provider/v1/admin/realms",
+		`{"name": "component-test", "jwt_issuer": "component-test-issuer"}`, http.StatusCreated, &realm)
+
+	// 2)
+	adminRequest(t, http.MethodPost, realmURL+"/v1/admin/users",
+		fmt.Sprintf(`{"email": %q, "password": %q}`, email, password), http.StatusCreated, nil)
+
+	// 3)
+	claims := loginRealmUser(t, realmURL, realm.JWTPublicKey, email, password)
+	if claims["iss"] != "component-test-issuer" {
+		t.Errorf("unexpected issuer. Expected: %q. Given: %q", "component-test-issuer", claims["iss"])
+	}
+
+	// 4)
+	_, ok := loginUser(t, email, password)
+	if ok {
+		t.Fatal("user of realm could login in default realm")
+	}
+
+	// 5)
+	adminRequest(t, http.MethodPut, "http://simple-jwt-provider/v1/admin/realms/component-test",
+		`{"jwt_issuer": "changed-issuer"}`, http.StatusOK, nil)
+
+	// 6)
+	claims = loginRealmUser(t, realmURL, realm.JWTPublicKey, email, password)
+	if claims["iss"] != "changed-issuer" {
+		t.Errorf("unexpected issuer. Expected: %q. Given: %q", "changed-issuer", claims["iss"])
+	}
+}
+
+func adminRequest(t *testing.T, method, url, body string, expectedStatusCode int, response interface{}) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, bytes.NewReader([]byte(body)))
+	if err != nil {
+		t.Fatalf("Failed to create http request")
+	}
+
+	req.SetBasicAuth("username", "password")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to call admin api cause: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != expectedStatusCode {
+		t.Fatalf("Invalid response status code. Expected: %d, Given: %d", expectedStatusCode, resp.StatusCode)
+	}
+
+	if response != nil {
+		err = json.NewDecoder(resp.Body).Decode(response)
+		if err != nil {
+			t.Fatalf("Failed to read response body: %s", err)
+		}
+	}
+}
+
+func loginRealmUser(t *testing.T, realmURL, publicKey, email, password string) jwt.MapClaims {
+	t.Helper()
+	resp, err := http.Post(
+		realmURL+"/v1/auth/login",
+		"application/json",
+		bytes.NewReader([]byte(fmt.Sprintf(`{"email": %q, "password": %q}`, email, password))),
+	)
+	if err != nil {
+		t.Fatalf("Failed to login with response: %v cause: %s", resp, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Invalid response status code. Expected: %d, Given: %d", http.StatusOK, resp.StatusCode)
+	}
+
+	responseBody := struct {
+		AccessToken string `json:"access_token"`
+	}{}
+	err = json.NewDecoder(resp.Body).Decode(&responseBody)
+	if err != nil {
+		t.Fatalf("Failed to read response body: %s", err)
+	}
+
+	pubKey, err := decodeECDSApubKey(publicKey)
+	if err != nil {
+		t.Fatalf("Failed to parse public key: %s", err)
+	}
+
+	var claims jwt.MapClaims
+	_, err = jwt.ParseWithClaims(responseBody.AccessToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		return pubKey, nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to parse jwt of realm: %s", err)
+	}
+
+	return claims
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal"
+	"github.com/leberKleber/simple-jwt-provider/internal/jwt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"github.com/leberKleber/simple-jwt-provider/internal/web"
+	"github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// realmProviderFactory creates the providers of realms. All settings but storage, jwt generator and mailer will be
+// taken from the provider of the default realm.
+type realmProviderFactory struct {
+	cfg config
+	// newRealmStorage opens and migrates the storage of the realm with the given name
+	newRealmStorage func(realm string) (providerStorage, error)
+	defaultProvider *internal.Provider
+
+	mu            sync.Mutex
+	realmStorages map[string]providerStorage
+}
+
+// newProvider creates the provider of the given realm. The storage of each realm will be created and migrated once and
+// reused by all following providers of the realm (e.g. after updates).
+func (f *realmProviderFactory) newProvider(r storage.Realm) (*internal.Provider, error) {
+	realmStorage, err := f.realmStorage(r.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	jwtGenerator, err := jwt.NewGenerator(r.JWTPrivateKey, r.JWTAudience, r.JWTIssuer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create jwt generator: %w", err)
+	}
+
+	templatesFolderPath := f.cfg.Mail.TemplatesFolderPath
+	realmTemplatesFolderPath := filepath.Join(f.cfg.Realms.MailTemplatesFolderPath, r.Name)
+	if info, err := os.Stat(realmTemplatesFolderPath); err == nil && info.IsDir() {
+		templatesFolderPath = realmTemplatesFolderPath
+	}
+
+	m, err := newMailer(f.cfg, templatesFolderPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mailer: %w", err)
+	}
+
+	p := *f.defaultProvider
+	p.Storage = realmStorage
+	p.JWTGenerator = jwtGenerator
+	p.Mailer = m
+	return &p, nil
+}
+
+// realmStorage returns the migrated storage of the realm with the given name
+func (f *realmProviderFactory) realmStorage(name string) (providerStorage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	realmStorage, ok := f.realmStorages[name]
+	if ok {
+		return realmStorage, nil
+	}
+
+	realmStorage, err := f.newRealmStorage(name)
+	if err != nil {
+		return nil, err
+	}
+
+	log := logrus.WithField("realm", name)
+	err = normalizeEMails(realmStorage, f.cfg.EMail.LowercaseLocalPart, log)
+	if err != nil {
+		log.WithError(err).Error("Failed to normalize emails")
+	}
+
+	f.realmStorages[name] = realmStorage
+	return realmStorage, nil
+}
+
+// webRealms adapts internal.Realms to web.Realms
+type webRealms struct {
+	*internal.Realms
+}
+
+func (r webRealms) Provider(realm string) (web.Provider, error) {
+	p, err := r.Realms.Provider(realm)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+)
+
+// seed is the content of seed files. Json is valid yaml, so seed files can be written in both formats
+type seed struct {
+	Users []seedUser `yaml:"users"`
+}
+
+// seedUser is a user of a seed file. It has the same properties as the users of the admin api
+type seedUser struct {
+	EMail              string                 `yaml:"email"`
+	Username           *string                `yaml:"username"`
+	Phone              *string                `yaml:"phone"`
+	Password           string                 `yaml:"password"`
+	Claims             map[string]interface{} `yaml:"claims"`
+	Metadata           map[string]interface{} `yaml:"metadata"`
+	PasswordMaxAgeDays *int                   `yaml:"password_max_age_days"`
+}
+
+// userCreator is implemented by internal.Provider
+type userCreator interface {
+	CreateUser(user internal.User) error
+}
+
+// seedUsers creates all users of the seed file with the given path which do not exist yet. Users without password will
+// be invited.
+func seedUsers(creator userCreator, path string) error {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read seed file: %w", err)
+	}
+
+	var s seed
+	err = yaml.UnmarshalStrict(raw, &s)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal seed file: %w", err)
+	}
+
+	for i, u := range s.Users {
+		err = creator.CreateUser(internal.User{
+			EMail:              u.EMail,
+			Username:           u.Username,
+			Phone:              u.Phone,
+			Password:           u.Password,
+			Claims:             jsonObject(u.Claims),
+			Metadata:           jsonObject(u.Metadata),
+			PasswordMaxAgeDays: u.PasswordMaxAgeDays,
+		})
+		if err != nil {
+			if errors.Is(err, internal.ErrUserAlreadyExists) {
+				logrus.WithField("user", i+1).Debug("Seed user already exists")
+				continue
+			}
+
+			return fmt.Errorf("failed to create user %d of seed file: %w", i+1, err)
+		}
+	}
+
+	logrus.WithField("users", len(s.Users)).Info("Seeded users")
+	return nil
+}
+
+// jsonObject converts the nested objects of the given yaml object which have been decoded as
+// map[interface{}]interface{} to map[string]interface{}, so it can be marshaled to json
+func jsonObject(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+
+	c := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		c[k] = jsonValue(v)
+	}
+
+	return c
+}
+
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			c[fmt.Sprint(k)] = jsonValue(e)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, e := range v {
+			c[i] = jsonValue(e)
+		}
+		return c
+	default:
+		return v
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// userCreatorFunc implements userCreator
+type userCreatorFunc func(user internal.User) error
+
+func (f userCreatorFunc) CreateUser(user internal.User) error {
+	return f(user)
+}
+
+func TestSeedUsers(t *testing.T) {
+	username := "leberkleber"
+	maxAgeDays := 30
+
+	tests := []struct {
+		name             string
+		givenSeed        string
+		givenCreateError error
+		expectedUsers    []internal.User
+		expectedError    error
+	}{
+		{
+			name: "yaml",
+			givenSeed: `
+users:
+  - email: info@leberkleber.io
+    username: leberkleber
+    password: s3cr3t
+    claims:
+      roles: [admin]
+      address:
+        city: Hamburg
+    password_max_age_days: 30
+  - email: invited@leberkleber.io
+`,
+			expectedUsers: []internal.User{
+				{
+					EMail:    "info@leberkleber.io",
+					Username: &username,
+					Password: "s3cr3t",
+					Claims: map[string]interface{}{
+						"roles":   []interface{}{"admin"},
+						"address": map[string]interface{}{"city": "Hamburg"},
+					},
+					PasswordMaxAgeDays: &maxAgeDays,
+				},
+				{
+					EMail: "invited@leberkleber.io",
+				},
+			},
+		},
+		{
+			name:      "json",
+			givenSeed: `{"users": [{"email": "info@leberkleber.io", "metadata": {"plan": {"name": "pro"}}}]}`,
+			expectedUsers: []internal.User{
+				{
+					EMail:    "info@leberkleber.io",
+					Metadata: map[string]interface{}{"plan": map[string]interface{}{"name": "pro"}},
+				},
+			},
+		},
+		{
+			name:             "user already exists",
+			givenSeed:        "users: [{email: info@leberkleber.io}]",
+			givenCreateError: internal.ErrUserAlreadyExists,
+			expectedUsers:    []internal.User{{EMail: "info@leberkleber.io"}},
+		},
+		{
+			name:             "create error",
+			givenSeed:        "users: [{email: info@leberkleber.io}]",
+			givenCreateError: internal.ErrInvalidEMail,
+			expectedUsers:    []internal.User{{EMail: "info@leberkleber.io"}},
+			expectedError:    fmt.Errorf("failed to create user 1 of seed file: %w", internal.ErrInvalidEMail),
+		},
+		{
+			name:          "unknown property",
+			givenSeed:     "users: [{mail: info@leberkleber.io}]",
+			expectedError: errors.New("failed to unmarshal seed file: yaml: unmarshal errors:\n  line 1: field mail not found in type main.seedUser"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "seed")
+			if err != nil {
+				t.Fatalf("failed to create temp dir: %s", err)
+			}
+			defer func() { _ = os.RemoveAll(dir) }()
+
+			path := filepath.Join(dir, "seed.yaml")
+			err = ioutil.WriteFile(path, []byte(tt.givenSeed), 0600)
+			if err != nil {
+				t.Fatalf("failed to write seed file: %s", err)
+			}
+
+			var givenUsers []internal.User
+			err = seedUsers(userCreatorFunc(func(user internal.User) error {
+				givenUsers = append(givenUsers, user)
+				return tt.givenCreateError
+			}), path)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("unexpected error. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(givenUsers, tt.expectedUsers) {
+				t.Errorf("unexpected users. Expected:\n%#v\nGiven:\n%#v", tt.expectedUsers, givenUsers)
+			}
+		})
+	}
+}
+
+func TestSeedUsers_MissingFile(t *testing.T) {
+	err := seedUsers(nil, "/not/existing/seed.yaml")
+	expectedError := errors.New("failed to read seed file: open /not/existing/seed.yaml: no such file or directory")
+	if fmt.Sprint(err) != fmt.Sprint(expectedError) {
+		t.Fatalf("unexpected error. Expected:\n%q\nGiven:\n%q", expectedError, err)
+	}
+}
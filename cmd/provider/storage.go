@@ -0,0 +1,210 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/leberKleber/simple-jwt-provider/internal"
+	"github.com/leberKleber/simple-jwt-provider/internal/jobs"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage/memory"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage/mysql"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage/sqlite"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strconv"
+)
+
+// providerStorage is implemented by the storages of all database types
+type providerStorage interface {
+	internal.Storage
+	internal.RealmStorage
+	jobs.Locker
+	UserEMails() (map[string]string, error)
+	NormalizeUserEMail(id, email string) error
+	Close() error
+}
+
+// migratableStorage is a storage which has to be migrated before use
+type migratableStorage interface {
+	Migrate(dbMigrationsPath string) error
+	Close() error
+}
+
+// newStorage opens and migrates the storage of the configured database type. The returned func opens and migrates the
+// storage of the realm with the given name.
+func newStorage(cfg config) (providerStorage, func(realm string) (providerStorage, error), error) {
+	switch cfg.DB.Type {
+	case "mysql":
+		tlsConfig, err := newDBTLSConfig(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		s, err := mysql.New(mysqlDSN(cfg), tlsConfig)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create storage: %w", err)
+		}
+
+		migrationsFolderPath := filepath.Join(cfg.DB.MigrationsFolderPath, "mysql")
+		err = migrateStorage(s, migrationsFolderPath)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return s, func(realm string) (providerStorage, error) {
+			realmStorage, err := s.RealmStorage(realm)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create storage: %w", err)
+			}
+
+			err = migrateStorage(realmStorage, migrationsFolderPath)
+			if err != nil {
+				return nil, err
+			}
+
+			return realmStorage, nil
+		}, nil
+	case "memory":
+		s, err := newMemoryStorage(cfg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create storage: %w", err)
+		}
+
+		return s, func(realm string) (providerStorage, error) {
+			return s.RealmStorage(realm)
+		}, nil
+	case "sqlite":
+		s, err := sqlite.New(cfg.DB.File)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create storage: %w", err)
+		}
+
+		migrationsFolderPath := filepath.Join(cfg.DB.MigrationsFolderPath, "sqlite")
+		err = migrateStorage(s, migrationsFolderPath)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return s, func(realm string) (providerStorage, error) {
+			realmStorage, err := s.RealmStorage(realm)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create storage: %w", err)
+			}
+
+			err = migrateStorage(realmStorage, migrationsFolderPath)
+			if err != nil {
+				return nil, err
+			}
+
+			return realmStorage, nil
+		}, nil
+	default:
+		s, err := storage.New(cfg.DB.Host, cfg.DB.Port, cfg.DB.Username, cfg.DB.Password, cfg.DB.Name, false)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create storage: %w", err)
+		}
+
+		err = migrateStorage(s, cfg.DB.MigrationsFolderPath)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return s, func(realm string) (providerStorage, error) {
+			realmStorage, err := s.RealmStorage(realm)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create storage: %w", err)
+			}
+
+			err = migrateStorage(realmStorage, cfg.DB.MigrationsFolderPath)
+			if err != nil {
+				return nil, err
+			}
+
+			return realmStorage, nil
+		}, nil
+	}
+}
+
+// newMemoryStorage creates the memory storage which will be persisted to the configured snapshot file. All data will
+// be lost on shutdown when no snapshot file has been configured.
+func newMemoryStorage(cfg config) (*memory.Storage, error) {
+	if cfg.DB.SnapshotFile == "" {
+		logrus.Warn("Memory storage has no snapshot file. All data will be lost on shutdown")
+		return memory.New(), nil
+	}
+
+	return memory.Open(cfg.DB.SnapshotFile)
+}
+
+// migrateStorage migrates the given storage with the migrations of the given folder. The storage will be closed when
+// the migration fails.
+func migrateStorage(s migratableStorage, dbMigrationsPath string) error {
+	err := s.Migrate(dbMigrationsPath)
+	if err != nil {
+		_ = s.Close()
+		return fmt.Errorf("failed to migrate storage: %w", err)
+	}
+
+	return nil
+}
+
+// mysqlDSN returns the configured data source name of the mysql database or builds it from host, port, name, username
+// and password when it has not been configured
+func mysqlDSN(cfg config) string {
+	if cfg.DB.DSN != "" {
+		return cfg.DB.DSN
+	}
+
+	dsnConfig := mysqldriver.NewConfig()
+	dsnConfig.User = cfg.DB.Username
+	dsnConfig.Passwd = cfg.DB.Password
+	dsnConfig.Net = "tcp"
+	dsnConfig.Addr = net.JoinHostPort(cfg.DB.Host, strconv.Itoa(cfg.DB.Port))
+	dsnConfig.DBName = cfg.DB.Name
+
+	return dsnConfig.FormatDSN()
+}
+
+// newDBTLSConfig returns the tls config of the database connections or nil when tls has not been enabled. The server
+// name defaults to the database host.
+func newDBTLSConfig(cfg config) (*tls.Config, error) {
+	if !cfg.DB.TLS.Enable {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         cfg.DB.TLS.ServerName,
+		InsecureSkipVerify: cfg.DB.TLS.InsecureSkipVerify,
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = cfg.DB.Host
+	}
+
+	if cfg.DB.TLS.CAFile != "" {
+		rawCA, err := ioutil.ReadFile(cfg.DB.TLS.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read db tls ca file: %w", err)
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(rawCA) {
+			return nil, errors.New("db tls ca file contains no pem encoded certificate")
+		}
+	}
+
+	if cfg.DB.TLS.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.DB.TLS.CertFile, cfg.DB.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load db tls client certificate: %w", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
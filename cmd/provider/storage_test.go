@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMysqlDSN(t *testing.T) {
+	tests := []struct {
+		name        string
+		givenConfig config
+		expectedDSN string
+	}{
+		{
+			name: "from host",
+			givenConfig: func() config {
+				var cfg config
+				cfg.DB.Host = "db.leberkleber.io"
+				cfg.DB.Port = 3306
+				cfg.DB.Name = "simple-jwt-provider"
+				cfg.DB.Username = "sjp"
+				cfg.DB.Password = "secret"
+				return cfg
+			}(),
+			expectedDSN: "sjp:secret@tcp(db.leberkleber.io:3306)/simple-jwt-provider",
+		},
+		{
+			name: "configured dsn",
+			givenConfig: func() config {
+				var cfg config
+				cfg.DB.Host = "db.leberkleber.io"
+				cfg.DB.DSN = "sjp:secret@unix(/run/mysqld/mysqld.sock)/sjp"
+				return cfg
+			}(),
+			expectedDSN: "sjp:secret@unix(/run/mysqld/mysqld.sock)/sjp",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dsn := mysqlDSN(tt.givenConfig)
+			if dsn != tt.expectedDSN {
+				t.Errorf("unexpected dsn. Expected: %q. Given: %q", tt.expectedDSN, dsn)
+			}
+		})
+	}
+}
+
+func TestNewDBTLSConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "db-tls")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	invalidCAFile := filepath.Join(dir, "ca.pem")
+	err = ioutil.WriteFile(invalidCAFile, []byte("no certificate"), 0600)
+	if err != nil {
+		t.Fatalf("failed to write ca file: %s", err)
+	}
+
+	tests := []struct {
+		name               string
+		givenEnable        bool
+		givenServerName    string
+		givenCAFile        string
+		givenCertFile      string
+		expectedServerName string
+		expectedNil        bool
+		expectedError      error
+	}{
+		{
+			name:        "disabled",
+			expectedNil: true,
+		},
+		{
+			name:               "server name defaults to host",
+			givenEnable:        true,
+			expectedServerName: "db.leberkleber.io",
+		},
+		{
+			name:               "server name",
+			givenEnable:        true,
+			givenServerName:    "mysql.leberkleber.io",
+			expectedServerName: "mysql.leberkleber.io",
+		},
+		{
+			name:          "missing ca file",
+			givenEnable:   true,
+			givenCAFile:   filepath.Join(dir, "missing.pem"),
+			expectedError: fmt.Errorf("failed to read db tls ca file: open %s: no such file or directory", filepath.Join(dir, "missing.pem")),
+		},
+		{
+			name:          "invalid ca file",
+			givenEnable:   true,
+			givenCAFile:   invalidCAFile,
+			expectedError: errors.New("db tls ca file contains no pem encoded certificate"),
+		},
+		{
+			name:          "invalid client certificate",
+			givenEnable:   true,
+			givenCertFile: invalidCAFile,
+			expectedError: errors.New("failed to load db tls client certificate: tls: failed to find any PEM data in certificate input"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg config
+			cfg.DB.Host = "db.leberkleber.io"
+			cfg.DB.TLS.Enable = tt.givenEnable
+			cfg.DB.TLS.ServerName = tt.givenServerName
+			cfg.DB.TLS.CAFile = tt.givenCAFile
+			cfg.DB.TLS.CertFile = tt.givenCertFile
+			cfg.DB.TLS.KeyFile = tt.givenCertFile
+
+			tlsConfig, err := newDBTLSConfig(cfg)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("unexpected error. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if (tlsConfig == nil) != tt.expectedNil {
+				t.Fatalf("unexpected tls config: %+v", tlsConfig)
+			}
+			if tlsConfig != nil && tlsConfig.ServerName != tt.expectedServerName {
+				t.Errorf("unexpected server name. Expected: %q. Given: %q", tt.expectedServerName, tlsConfig.ServerName)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+// +build component
+
+package main
+
+import (
+	"bytes"
+	"encoding/base32"
+	"encoding/json"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/totp"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTOTPLogin(t *testing.T) {
+	email := "totpTest@leberkleber.io"
+	password := "s3cr3t"
+
+	createUser(t, email, password)
+	secret := enrolTOTP(t, email, password)
+
+	now := time.Now()
+	var confirmResponse struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	postJSON(t, "/v1/auth/totp/confirm", fmt.Sprintf(`{"email": %q, "password": %q, "code": %q}`, email, password, totp.Code(secret, totp.Step(now))), http.StatusOK, &confirmResponse)
+	if len(confirmResponse.RecoveryCodes) == 0 {
+		t.Fatal("totp confirmation did not return recovery codes")
+	}
+
+	var loginResponse struct {
+		AccessToken string `json:"access_token"`
+		MFAToken    string `json:"mfa_token"`
+	}
+	postJSON(t, "/v1/auth/login", fmt.Sprintf(`{"email": %q, "password": %q}`, email, password), http.StatusOK, &loginResponse)
+	if loginResponse.AccessToken != "" || loginResponse.MFAToken == "" {
+		t.Fatalf("login did not return mfa challenge token: %#v", loginResponse)
+	}
+
+	// the code of the current step has already been used for confirmation
+	postJSON(t, "/v1/auth/login/mfa", fmt.Sprintf(`{"email": %q, "mfa_token": %q, "code": %q}`, email, loginResponse.MFAToken, totp.Code(secret, totp.Step(now)+1)), http.StatusOK, &loginResponse)
+
+	claims := validateJWT(t, loginResponse.AccessToken)
+	expectedAMR := []interface{}{"pwd", "otp"}
+	if !reflect.DeepEqual(claims["amr"], expectedAMR) {
+		t.Errorf("unexpected amr claim value. Expected: %v. Given: %v", expectedAMR, claims["amr"])
+	}
+
+	// login with a recovery code in place of the totp code, each recovery code can be used once
+	postJSON(t, "/v1/auth/login", fmt.Sprintf(`{"email": %q, "password": %q}`, email, password), http.StatusOK, &loginResponse)
+	postJSON(t, "/v1/auth/login/recovery", fmt.Sprintf(`{"email": %q, "mfa_token": %q, "recovery_code": %q}`, email, loginResponse.MFAToken, confirmResponse.RecoveryCodes[0]), http.StatusOK, &loginResponse)
+
+	claims = validateJWT(t, loginResponse.AccessToken)
+	expectedAMR = []interface{}{"pwd", "rc"}
+	if !reflect.DeepEqual(claims["amr"], expectedAMR) {
+		t.Errorf("unexpected amr claim value. Expected: %v. Given: %v", expectedAMR, claims["amr"])
+	}
+
+	postJSON(t, "/v1/auth/login", fmt.Sprintf(`{"email": %q, "password": %q}`, email, password), http.StatusOK, &loginResponse)
+	postJSON(t, "/v1/auth/login/recovery", fmt.Sprintf(`{"email": %q, "mfa_token": %q, "recovery_code": %q}`, email, loginResponse.MFAToken, confirmResponse.RecoveryCodes[0]), http.StatusUnauthorized, nil)
+}
+
+func enrolTOTP(t *testing.T, email, password string) []byte {
+	t.Helper()
+	var enrolment struct {
+		Secret string `json:"secret"`
+		URI    string `json:"uri"`
+	}
+	postJSON(t, "/v1/auth/totp", fmt.Sprintf(`{"email": %q, "password": %q}`, email, password), http.StatusCreated, &enrolment)
+
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(enrolment.Secret)
+	if err != nil {
+		t.Fatalf("Failed to decode totp secret: %s", err)
+	}
+
+	return secret
+}
+
+func postJSON(t *testing.T, path, body string, expectedStatusCode int, response interface{}) {
+	t.Helper()
+	resp, err := http.Post("http://simple-jwt-provider"+path, "application/json", bytes.NewReader([]byte(body)))
+	if err != nil {
+		t.Fatalf("Failed to call %s cause: %s", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != expectedStatusCode {
+		t.Fatalf("Invalid response status code of %s. Expected: %d, Given: %d", path, expectedStatusCode, resp.StatusCode)
+	}
+
+	if response != nil {
+		err = json.NewDecoder(resp.Body).Decode(response)
+		if err != nil {
+			t.Fatalf("Failed to decode response of %s: %s", path, err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// +build component
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestUserDataExportAndErasure(t *testing.T) {
+	// 1) create user
+	// 2) login
+	// 3) export own data
+	// 4) export data via admin api
+	// 5) erase own data
+	// 6) login not possible anymore
+
+	email := "user_data_test@leberkleber.io"
+	password := "s3cr3t"
+
+	// 1)
+	createUser(t, email, password)
+
+	// 2)
+	accessToken, ok := loginUser(t, email, password)
+	if !ok {
+		t.Fatal("login failed")
+	}
+
+	// 3)
+	export := struct {
+		User struct {
+			EMail string `json:"email"`
+		} `json:"user"`
+	}{}
+	selfServiceRequest(t, http.MethodGet, "http://simple-jwt-provider/v1/auth/data-export", accessToken, http.StatusOK, &export)
+	if export.User.EMail != email {
+		t.Errorf("unexpected exported email. Expected: %q, Given: %q", email, export.User.EMail)
+	}
+
+	// 4)
+	adminRequest(t, http.MethodGet, "http://simple-jwt-provider/v1/admin/users/"+email+"/data-export", "", http.StatusOK, nil)
+
+	// 5)
+	erasure := struct {
+		DeletedRows map[string]int64 `json:"deleted_rows"`
+	}{}
+	selfServiceRequest(t, http.MethodPost, "http://simple-jwt-provider/v1/auth/data-erasure", accessToken, http.StatusOK, &erasure)
+	if erasure.DeletedRows["users"] != 1 {
+		t.Errorf("unexpected count of deleted users. Expected: 1, Given: %d", erasure.DeletedRows["users"])
+	}
+
+	// 6)
+	_, ok = loginUser(t, email, password)
+	if ok {
+		t.Fatal("erased user could login")
+	}
+}
+
+func selfServiceRequest(t *testing.T, method, url, accessToken string, expectedStatusCode int, response interface{}) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatalf("Failed to create http request")
+	}
+
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to call self service api cause: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != expectedStatusCode {
+		t.Fatalf("Invalid response status code. Expected: %d, Given: %d", expectedStatusCode, resp.StatusCode)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(response)
+	if err != nil {
+		t.Fatalf("Failed to read response body: %s", err)
+	}
+}
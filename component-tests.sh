@@ -29,6 +29,26 @@ else
   set -e
 fi
 
+# the storage tests run against the databases of the compose setup when they have been configured
+# (SJP_TEST_DB_* for postgres, SJP_TEST_MYSQL_DSN for mysql / mariadb)
+if [[ "$test_result" -eq 0 && -n "$SJP_TEST_MYSQL_DSN" ]]; then
+  mysqlContainer=$(docker-compose -f component-tests.docker-compose.yml ps -q mysql-db)
+  for i in $(seq 1 30); do
+    [[ "$(docker inspect -f '{{.State.Health.Status}}' "$mysqlContainer")" == "healthy" ]] && break
+    sleep 2
+  done
+fi
+
+if [[ "$test_result" -eq 0 ]]; then
+  set +e
+      docker run --rm --network "${networkName}" \
+        -e SJP_TEST_DB_HOST -e SJP_TEST_DB_PORT -e SJP_TEST_DB_USERNAME -e SJP_TEST_DB_PASSWORD -e SJP_TEST_DB_NAME \
+        -e SJP_TEST_MYSQL_DSN \
+        ct-runner:${BUILD_ID} go test -count=1 ./internal/storage/...
+      test_result=$?
+  set -e
+fi
+
 if [[ "$test_result" -gt 0 ]]; then
   docker-compose -f component-tests.docker-compose.yml logs
 fi
@@ -0,0 +1,3 @@
+-- md5 based uuids because gen_random_uuid() is not available without extension before postgres 13
+ALTER TABLE users ADD COLUMN id uuid NOT NULL DEFAULT md5(random()::text || clock_timestamp()::text)::uuid;
+ALTER TABLE users ADD CONSTRAINT users_id_unique UNIQUE (id);
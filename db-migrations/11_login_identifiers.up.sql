@@ -0,0 +1,47 @@
+ALTER TABLE users ADD COLUMN username text, ADD COLUMN phone text;
+ALTER TABLE users ADD CONSTRAINT users_username_unique UNIQUE (username),
+    ADD CONSTRAINT users_phone_unique UNIQUE (phone);
+
+-- emails are optional from now on, so referencing rows reference the immutable user id instead
+ALTER TABLE tokens ADD COLUMN user_id uuid;
+ALTER TABLE password_history ADD COLUMN user_id uuid;
+ALTER TABLE user_totp ADD COLUMN user_id uuid;
+ALTER TABLE webauthn_credentials ADD COLUMN user_id uuid;
+ALTER TABLE mfa_recovery_codes ADD COLUMN user_id uuid;
+
+UPDATE tokens SET user_id = users.id FROM users WHERE users.email = tokens.email;
+UPDATE password_history SET user_id = users.id FROM users WHERE users.email = password_history.email;
+UPDATE user_totp SET user_id = users.id FROM users WHERE users.email = user_totp.email;
+UPDATE webauthn_credentials SET user_id = users.id FROM users WHERE users.email = webauthn_credentials.email;
+UPDATE mfa_recovery_codes SET user_id = users.id FROM users WHERE users.email = mfa_recovery_codes.email;
+
+ALTER TABLE tokens DROP CONSTRAINT tokens_email_fkey;
+ALTER TABLE password_history DROP CONSTRAINT password_history_email_fkey;
+ALTER TABLE user_totp DROP CONSTRAINT user_totp_email_fkey, DROP CONSTRAINT user_totp_email_unique;
+ALTER TABLE webauthn_credentials DROP CONSTRAINT webauthn_credentials_email_fkey;
+ALTER TABLE mfa_recovery_codes DROP CONSTRAINT mfa_recovery_codes_email_fkey;
+
+ALTER TABLE users DROP CONSTRAINT email_unique;
+ALTER TABLE users ALTER COLUMN email DROP NOT NULL;
+ALTER TABLE users ADD CONSTRAINT email_unique UNIQUE (email);
+ALTER TABLE users DROP CONSTRAINT users_id_unique;
+ALTER TABLE users ADD CONSTRAINT users_pkey PRIMARY KEY (id);
+ALTER TABLE users ADD CONSTRAINT users_login_identifier_check
+    CHECK (email IS NOT NULL OR username IS NOT NULL OR phone IS NOT NULL);
+
+ALTER TABLE tokens DROP COLUMN email, ALTER COLUMN user_id SET NOT NULL,
+    ADD CONSTRAINT tokens_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id);
+ALTER TABLE password_history DROP COLUMN email, ALTER COLUMN user_id SET NOT NULL,
+    ADD CONSTRAINT password_history_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id);
+ALTER TABLE user_totp DROP COLUMN email, ALTER COLUMN user_id SET NOT NULL,
+    ADD CONSTRAINT user_totp_user_id_unique PRIMARY KEY (user_id),
+    ADD CONSTRAINT user_totp_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id);
+ALTER TABLE webauthn_credentials DROP COLUMN email, ALTER COLUMN user_id SET NOT NULL,
+    ADD CONSTRAINT webauthn_credentials_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id);
+ALTER TABLE mfa_recovery_codes DROP COLUMN email, ALTER COLUMN user_id SET NOT NULL,
+    ADD CONSTRAINT mfa_recovery_codes_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id);
+
+CREATE INDEX tokens_user_id_idx ON tokens (user_id);
+CREATE INDEX password_history_user_id_idx ON password_history (user_id);
+CREATE INDEX webauthn_credentials_user_id_idx ON webauthn_credentials (user_id);
+CREATE INDEX mfa_recovery_codes_user_id_idx ON mfa_recovery_codes (user_id);
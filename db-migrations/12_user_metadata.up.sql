@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN metadata bytea;
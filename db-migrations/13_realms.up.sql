@@ -0,0 +1,20 @@
+-- realms are registered in the default schema only. Each realm has its own schema migrated with the same migrations.
+CREATE TABLE realms
+(
+    name            text,
+    jwt_private_key text        NOT NULL,
+    jwt_issuer      text        NOT NULL DEFAULT '',
+    jwt_audience    text        NOT NULL DEFAULT '',
+    created_at      timestamptz NOT NULL,
+    CONSTRAINT realms_pkey PRIMARY KEY (name)
+);
+
+CREATE TABLE realm_hosts
+(
+    host  text,
+    realm text NOT NULL,
+    CONSTRAINT realm_hosts_pkey PRIMARY KEY (host),
+    CONSTRAINT realm_hosts_realm_fkey FOREIGN KEY (realm) REFERENCES realms (name)
+);
+
+CREATE INDEX realm_hosts_realm_idx ON realm_hosts (realm);
@@ -0,0 +1,18 @@
+-- every login attempt of known users. Rows older than the configured retention will be deleted on insert.
+CREATE TABLE logins
+(
+    id         bigserial   NOT NULL,
+    user_id    uuid        NOT NULL,
+    created_at timestamptz NOT NULL,
+    ip         text        NOT NULL DEFAULT '',
+    user_agent text        NOT NULL DEFAULT '',
+    outcome    text        NOT NULL,
+    factor     text        NOT NULL,
+    CONSTRAINT logins_pkey PRIMARY KEY (id),
+    CONSTRAINT logins_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE INDEX logins_user_id_created_at_idx ON logins (user_id, created_at DESC);
+CREATE INDEX logins_created_at_idx ON logins (created_at);
+
+ALTER TABLE users ADD COLUMN last_login_at timestamptz;
@@ -0,0 +1,4 @@
+-- one-time tokens will only be stored as keyed hash from now on. Plaintext tokens can not be hashed here because the
+-- key is not known to the database, so all open tokens (password-reset, magic-link, login-code, invitation, mfa and
+-- webauthn challenges) will be invalidated and have to be requested again.
+DELETE FROM tokens;
@@ -0,0 +1,5 @@
+-- optional payload of one-time tokens (json)
+ALTER TABLE tokens ADD COLUMN metadata bytea;
+
+-- expired tokens will be purged by type and creation time
+CREATE INDEX tokens_type_created_at_idx ON tokens (type, created_at);
@@ -0,0 +1,3 @@
+-- time of the last invitation of users created without password. Unverified users (never accepted their invitation)
+-- will be purged by it. Users invited before this migration have no invitation time and will never be purged.
+ALTER TABLE users ADD COLUMN invited_at timestamptz;
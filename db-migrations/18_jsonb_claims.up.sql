@@ -0,0 +1,5 @@
+-- claims have been stored as json encoded bytea. As jsonb they can be queried and indexed. The default gin operator
+-- class supports containment (@>) and existence (?) which are used to find users by claims.
+ALTER TABLE users ALTER COLUMN claims TYPE jsonb USING convert_from(claims, 'UTF8')::jsonb;
+
+CREATE INDEX users_claims_idx ON users USING gin (claims);
@@ -0,0 +1,10 @@
+CREATE TABLE password_history
+(
+    id         serial      NOT NULL,
+    email      text        NOT NULL,
+    password   bytea       NOT NULL,
+    created_at timestamptz NOT NULL,
+    CONSTRAINT password_history_id_unique PRIMARY KEY (id),
+    CONSTRAINT password_history_email_fkey FOREIGN KEY (email) REFERENCES users (email)
+);
+CREATE INDEX password_history_email_idx ON password_history (email);
@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN password_changed_at timestamptz NOT NULL DEFAULT now();
+ALTER TABLE users ADD COLUMN password_max_age_days integer NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN password_expiry_reminded_at timestamptz;
@@ -0,0 +1,10 @@
+CREATE TABLE user_totp
+(
+    email          text        NOT NULL,
+    secret         bytea       NOT NULL,
+    confirmed      boolean     NOT NULL DEFAULT false,
+    last_used_step bigint      NOT NULL DEFAULT 0,
+    created_at     timestamptz NOT NULL,
+    CONSTRAINT user_totp_email_unique PRIMARY KEY (email),
+    CONSTRAINT user_totp_email_fkey FOREIGN KEY (email) REFERENCES users (email)
+);
@@ -0,0 +1,13 @@
+CREATE TABLE webauthn_credentials
+(
+    credential_id bytea       NOT NULL,
+    email         text        NOT NULL,
+    public_key    bytea       NOT NULL,
+    sign_count    bigint      NOT NULL DEFAULT 0,
+    created_at    timestamptz NOT NULL,
+    last_used_at  timestamptz,
+    CONSTRAINT webauthn_credentials_id_unique PRIMARY KEY (credential_id),
+    CONSTRAINT webauthn_credentials_email_fkey FOREIGN KEY (email) REFERENCES users (email)
+);
+
+CREATE INDEX webauthn_credentials_email_idx ON webauthn_credentials (email);
@@ -0,0 +1,12 @@
+CREATE TABLE mfa_recovery_codes
+(
+    id         serial      NOT NULL,
+    email      text        NOT NULL,
+    code_hash  bytea       NOT NULL,
+    created_at timestamptz NOT NULL,
+    used_at    timestamptz,
+    CONSTRAINT mfa_recovery_codes_id_unique PRIMARY KEY (id),
+    CONSTRAINT mfa_recovery_codes_email_fkey FOREIGN KEY (email) REFERENCES users (email)
+);
+
+CREATE INDEX mfa_recovery_codes_email_idx ON mfa_recovery_codes (email);
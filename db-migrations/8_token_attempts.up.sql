@@ -0,0 +1 @@
+ALTER TABLE tokens ADD COLUMN attempts integer NOT NULL DEFAULT 0;
@@ -0,0 +1,47 @@
+ALTER TABLE users ADD COLUMN display_email text;
+
+-- emails will be rewritten to their normalized form, referencing rows have to follow
+ALTER TABLE tokens DROP CONSTRAINT tokens_email_fkey,
+    ADD CONSTRAINT tokens_email_fkey FOREIGN KEY (email) REFERENCES users (email) ON UPDATE CASCADE;
+ALTER TABLE password_history DROP CONSTRAINT password_history_email_fkey,
+    ADD CONSTRAINT password_history_email_fkey FOREIGN KEY (email) REFERENCES users (email) ON UPDATE CASCADE;
+ALTER TABLE user_totp DROP CONSTRAINT user_totp_email_fkey,
+    ADD CONSTRAINT user_totp_email_fkey FOREIGN KEY (email) REFERENCES users (email) ON UPDATE CASCADE;
+ALTER TABLE webauthn_credentials DROP CONSTRAINT webauthn_credentials_email_fkey,
+    ADD CONSTRAINT webauthn_credentials_email_fkey FOREIGN KEY (email) REFERENCES users (email) ON UPDATE CASCADE;
+ALTER TABLE mfa_recovery_codes DROP CONSTRAINT mfa_recovery_codes_email_fkey,
+    ADD CONSTRAINT mfa_recovery_codes_email_fkey FOREIGN KEY (email) REFERENCES users (email) ON UPDATE CASCADE;
+
+-- users which only differ in the case of the domain or surrounding whitespaces. They have to be merged or deleted
+-- manually. The key is the normalized email the update below produces
+CREATE VIEW user_email_collisions AS
+SELECT substring(btrim(email) FROM '^(.*)@[^@]*$') || '@' || lower(substring(btrim(email) FROM '@([^@]*)$')) AS normalized_email,
+       array_agg(email ORDER BY email)                                                                     AS emails
+FROM users
+GROUP BY normalized_email
+HAVING count(*) > 1;
+
+DO
+$$
+    DECLARE
+        collision record;
+    BEGIN
+        FOR collision IN SELECT * FROM user_email_collisions
+            LOOP
+                RAISE WARNING 'users % collide after email normalization and have to be resolved manually', collision.emails;
+            END LOOP;
+    END
+$$;
+
+-- trim and lowercase the domain of all not colliding emails. The local part stays untouched because lowercasing it
+-- is optional and the conversion of internationalized domain names is not possible in sql. Both will be done by the
+-- provider on startup, which also reports the collisions it finds
+UPDATE users
+SET display_email = email,
+    email         = substring(btrim(email) FROM '^(.*)@[^@]*$') || '@' || lower(substring(btrim(email) FROM '@([^@]*)$'))
+WHERE btrim(email) LIKE '%_@_%'
+  AND email <> substring(btrim(email) FROM '^(.*)@[^@]*$') || '@' || lower(substring(btrim(email) FROM '@([^@]*)$'))
+  AND substring(btrim(email) FROM '^(.*)@[^@]*$') || '@' || lower(substring(btrim(email) FROM '@([^@]*)$')) NOT IN
+      (SELECT normalized_email FROM user_email_collisions);
+
+DROP VIEW user_email_collisions;
@@ -0,0 +1,128 @@
+-- schema of the mysql / mariadb storage. It equals the postgres schema after all postgres migrations (parent folder)
+-- with mysql types: uuids are char(36), bytea is blob and timestamps are datetime(6) in utc. All text columns use a
+-- binary collation, so they are compared case sensitive like in postgres. Primary keys are always named PRIMARY.
+CREATE TABLE users
+(
+    id                          char(36)     NOT NULL,
+    email                       varchar(255),
+    display_email               varchar(255),
+    username                    varchar(255),
+    phone                       varchar(32),
+    password                    blob         NOT NULL,
+    claims                      mediumblob,
+    metadata                    mediumblob,
+    password_changed_at         datetime(6)  NOT NULL,
+    password_max_age_days       int          NOT NULL DEFAULT 0,
+    password_expiry_reminded_at datetime(6),
+    last_login_at               datetime(6),
+    invited_at                  datetime(6),
+    PRIMARY KEY (id),
+    CONSTRAINT email_unique UNIQUE (email),
+    CONSTRAINT users_username_unique UNIQUE (username),
+    CONSTRAINT users_phone_unique UNIQUE (phone),
+    CONSTRAINT users_login_identifier_check CHECK (email IS NOT NULL OR username IS NOT NULL OR phone IS NOT NULL)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE tokens
+(
+    id         bigint       NOT NULL AUTO_INCREMENT,
+    user_id    char(36)     NOT NULL,
+    token      varchar(255) NOT NULL,
+    type       varchar(64)  NOT NULL,
+    created_at datetime(6)  NOT NULL,
+    attempts   int          NOT NULL DEFAULT 0,
+    metadata   mediumblob,
+    PRIMARY KEY (id),
+    CONSTRAINT tokens_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE INDEX tokens_user_id_idx ON tokens (user_id);
+CREATE INDEX tokens_type_created_at_idx ON tokens (type, created_at);
+
+CREATE TABLE password_history
+(
+    id         bigint      NOT NULL AUTO_INCREMENT,
+    user_id    char(36)    NOT NULL,
+    password   blob        NOT NULL,
+    created_at datetime(6) NOT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT password_history_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE INDEX password_history_user_id_idx ON password_history (user_id);
+
+CREATE TABLE user_totp
+(
+    user_id        char(36)    NOT NULL,
+    secret         blob        NOT NULL,
+    confirmed      boolean     NOT NULL DEFAULT false,
+    last_used_step bigint      NOT NULL DEFAULT 0,
+    created_at     datetime(6) NOT NULL,
+    PRIMARY KEY (user_id),
+    CONSTRAINT user_totp_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE webauthn_credentials
+(
+    credential_id varbinary(1023) NOT NULL,
+    user_id       char(36)        NOT NULL,
+    public_key    blob            NOT NULL,
+    sign_count    bigint          NOT NULL DEFAULT 0,
+    created_at    datetime(6)     NOT NULL,
+    last_used_at  datetime(6),
+    PRIMARY KEY (credential_id),
+    CONSTRAINT webauthn_credentials_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE INDEX webauthn_credentials_user_id_idx ON webauthn_credentials (user_id);
+
+CREATE TABLE mfa_recovery_codes
+(
+    id         bigint      NOT NULL AUTO_INCREMENT,
+    user_id    char(36)    NOT NULL,
+    code_hash  blob        NOT NULL,
+    created_at datetime(6) NOT NULL,
+    used_at    datetime(6),
+    PRIMARY KEY (id),
+    CONSTRAINT mfa_recovery_codes_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE INDEX mfa_recovery_codes_user_id_idx ON mfa_recovery_codes (user_id);
+
+CREATE TABLE logins
+(
+    id         bigint       NOT NULL AUTO_INCREMENT,
+    user_id    char(36)     NOT NULL,
+    created_at datetime(6)  NOT NULL,
+    ip         varchar(45)  NOT NULL DEFAULT '',
+    user_agent text         NOT NULL,
+    outcome    varchar(32)  NOT NULL,
+    factor     varchar(32)  NOT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT logins_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE INDEX logins_user_id_created_at_idx ON logins (user_id, created_at DESC);
+CREATE INDEX logins_created_at_idx ON logins (created_at);
+
+-- realms are registered in the default database only. Each realm has its own database migrated with the same
+-- migrations.
+CREATE TABLE realms
+(
+    name            varchar(64)  NOT NULL,
+    jwt_private_key text         NOT NULL,
+    jwt_issuer      varchar(255) NOT NULL DEFAULT '',
+    jwt_audience    varchar(255) NOT NULL DEFAULT '',
+    created_at      datetime(6)  NOT NULL,
+    PRIMARY KEY (name)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE TABLE realm_hosts
+(
+    host  varchar(253) NOT NULL,
+    realm varchar(64)  NOT NULL,
+    PRIMARY KEY (host),
+    CONSTRAINT realm_hosts_realm_fkey FOREIGN KEY (realm) REFERENCES realms (name)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
+
+CREATE INDEX realm_hosts_realm_idx ON realm_hosts (realm);
@@ -0,0 +1,123 @@
+-- schema of the sqlite storage. It equals the postgres schema after all postgres migrations (parent folder) with sqlite
+-- types: uuids are text, bytea is blob and timestamps are stored as utc text.
+CREATE TABLE users
+(
+    id                          text      NOT NULL,
+    email                       text,
+    display_email               text,
+    username                    text,
+    phone                       text,
+    password                    blob      NOT NULL,
+    claims                      blob,
+    metadata                    blob,
+    password_changed_at         timestamp NOT NULL,
+    password_max_age_days       integer   NOT NULL DEFAULT 0,
+    password_expiry_reminded_at timestamp,
+    last_login_at               timestamp,
+    invited_at                  timestamp,
+    CONSTRAINT users_pkey PRIMARY KEY (id),
+    CONSTRAINT email_unique UNIQUE (email),
+    CONSTRAINT users_username_unique UNIQUE (username),
+    CONSTRAINT users_phone_unique UNIQUE (phone),
+    CONSTRAINT users_login_identifier_check CHECK (email IS NOT NULL OR username IS NOT NULL OR phone IS NOT NULL)
+);
+
+CREATE TABLE tokens
+(
+    id         integer   NOT NULL CONSTRAINT tokens_pkey PRIMARY KEY AUTOINCREMENT,
+    user_id    text      NOT NULL,
+    token      text      NOT NULL,
+    type       text      NOT NULL,
+    created_at timestamp NOT NULL,
+    attempts   integer   NOT NULL DEFAULT 0,
+    metadata   blob,
+    CONSTRAINT tokens_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE INDEX tokens_user_id_idx ON tokens (user_id);
+CREATE INDEX tokens_type_created_at_idx ON tokens (type, created_at);
+
+CREATE TABLE password_history
+(
+    id         integer   NOT NULL CONSTRAINT password_history_pkey PRIMARY KEY AUTOINCREMENT,
+    user_id    text      NOT NULL,
+    password   blob      NOT NULL,
+    created_at timestamp NOT NULL,
+    CONSTRAINT password_history_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE INDEX password_history_user_id_idx ON password_history (user_id);
+
+CREATE TABLE user_totp
+(
+    user_id        text      NOT NULL,
+    secret         blob      NOT NULL,
+    confirmed      boolean   NOT NULL DEFAULT false,
+    last_used_step integer   NOT NULL DEFAULT 0,
+    created_at     timestamp NOT NULL,
+    CONSTRAINT user_totp_user_id_unique PRIMARY KEY (user_id),
+    CONSTRAINT user_totp_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE TABLE webauthn_credentials
+(
+    credential_id blob      NOT NULL,
+    user_id       text      NOT NULL,
+    public_key    blob      NOT NULL,
+    sign_count    integer   NOT NULL DEFAULT 0,
+    created_at    timestamp NOT NULL,
+    last_used_at  timestamp,
+    CONSTRAINT webauthn_credentials_id_unique PRIMARY KEY (credential_id),
+    CONSTRAINT webauthn_credentials_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE INDEX webauthn_credentials_user_id_idx ON webauthn_credentials (user_id);
+
+CREATE TABLE mfa_recovery_codes
+(
+    id         integer   NOT NULL CONSTRAINT mfa_recovery_codes_pkey PRIMARY KEY AUTOINCREMENT,
+    user_id    text      NOT NULL,
+    code_hash  blob      NOT NULL,
+    created_at timestamp NOT NULL,
+    used_at    timestamp,
+    CONSTRAINT mfa_recovery_codes_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE INDEX mfa_recovery_codes_user_id_idx ON mfa_recovery_codes (user_id);
+
+CREATE TABLE logins
+(
+    id         integer   NOT NULL CONSTRAINT logins_pkey PRIMARY KEY AUTOINCREMENT,
+    user_id    text      NOT NULL,
+    created_at timestamp NOT NULL,
+    ip         text      NOT NULL DEFAULT '',
+    user_agent text      NOT NULL DEFAULT '',
+    outcome    text      NOT NULL,
+    factor     text      NOT NULL,
+    CONSTRAINT logins_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE INDEX logins_user_id_created_at_idx ON logins (user_id, created_at DESC);
+CREATE INDEX logins_created_at_idx ON logins (created_at);
+
+-- realms are registered in the default database only. Each realm has its own database migrated with the same
+-- migrations.
+CREATE TABLE realms
+(
+    name            text      NOT NULL,
+    jwt_private_key text      NOT NULL,
+    jwt_issuer      text      NOT NULL DEFAULT '',
+    jwt_audience    text      NOT NULL DEFAULT '',
+    created_at      timestamp NOT NULL,
+    CONSTRAINT realms_pkey PRIMARY KEY (name)
+);
+
+CREATE TABLE realm_hosts
+(
+    host  text NOT NULL,
+    realm text NOT NULL,
+    CONSTRAINT realm_hosts_pkey PRIMARY KEY (host),
+    CONSTRAINT realm_hosts_realm_fkey FOREIGN KEY (realm) REFERENCES realms (name)
+);
+
+CREATE INDEX realm_hosts_realm_idx ON realm_hosts (realm);
@@ -0,0 +1,4 @@
+-- recovery codes have been hashed with plain sha256 before, now they are hashed with the keyed token hasher
+-- (SJP_TOKEN_HMAC_KEY). The old hashes can not be converted and will never match again, so the unused codes will be
+-- deleted and have to be regenerated by the users.
+DELETE FROM mfa_recovery_codes WHERE used_at IS NULL;
@@ -0,0 +1,8 @@
+#!/usr/bin/env sh
+
+if [ "$#" -ne  "3" ]; then
+   echo "Three arguments must be set e.g. ./change_password.sh email password new-password"
+   exit 1
+fi
+
+curl -X POST --data "{\"email\":\"$1\", \"password\":\"$2\", \"new_password\": \"$3\"}"  localhost:8080/v1/auth/password-change -v
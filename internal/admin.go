@@ -3,119 +3,390 @@ package internal
 import (
 	"errors"
 	"fmt"
+	"github.com/google/uuid"
 	"github.com/leberKleber/simple-jwt-provider/internal/storage"
 	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"time"
 )
 
 var bcryptCost = 12
 var blankedPassword = "**********"
 var ErrUserAlreadyExists = errors.New("user already exists")
+var ErrInvalidClaimPredicate = errors.New("invalid claim predicate")
 
-// User is the representation of a user for use in internal
+const (
+	// ClaimOperatorEquals matches users whose claim equals the value
+	ClaimOperatorEquals = storage.ClaimOperatorEquals
+	// ClaimOperatorContains matches users whose claim contains the value like the jsonb operator @> does, e.g. an array
+	// claim contains all elements of an array value and an object claim contains all properties of an object value
+	ClaimOperatorContains = storage.ClaimOperatorContains
+	// ClaimOperatorExists matches users who have the claim
+	ClaimOperatorExists = storage.ClaimOperatorExists
+)
+
+// User is the representation of a user for use in internal. At least one login identifier (email, username, phone)
+// is required.
 type User struct {
-	EMail    string
+	// ID is read only. It is generated on creation and will never change
+	ID string
+	// EMail can only be set on creation
+	EMail string
+	// DisplayEMail is read only. It is the email in the form it has been given on creation
+	DisplayEMail string
+	// Username is an optional login identifier. On update nil means unchanged and empty means removed
+	Username *string
+	// Phone is an optional login identifier in E.164 format. On update nil means unchanged and empty means removed
+	Phone    *string
 	Password string
-	Claims   map[string]interface{}
+	// Claims will be emitted into all jwts of the user. On update nil means unchanged
+	Claims map[string]interface{}
+	// Metadata is available in mail templates only and will never be emitted into jwts. On update nil means unchanged
+	Metadata map[string]interface{}
+	// PasswordChangedAt is read only
+	PasswordChangedAt time.Time
+	// PasswordMaxAgeDays overwrites the global password max age. 0 means the global one will be used. On update nil
+	// means unchanged
+	PasswordMaxAgeDays *int
+	// LastLoginAt is read only. It is the time of the last successful login and nil when the user never logged in
+	LastLoginAt *time.Time
+	// InvitationPending is read only. It is true when the user has been invited and has not set a password yet
+	InvitationPending bool
 }
 
-// CreateUser creates new user with given email, password and claims.
-// return ErrUserAlreadyExists when user already exists
+// CreateUser creates new user with given login identifiers (email, username, phone), password and claims.
+// return ErrNoLoginIdentifier when neither email nor username nor phone is given
+// return ErrInvalidEMail, ErrInvalidUsername or ErrInvalidPhone when a login identifier is malformed
+// return ErrUserAlreadyExists when a user with one of the login identifiers already exists
+// return ErrPasswordBreached when the password has been found in a data breach
+// When no password is given, the user will be invited by mail instead (see AcceptInvitation).
+// return ErrInvitationNotConfigured when no password is given and InvitationLifetime is 0
+// return ErrInvitationRequiresEMail when no password and no email is given
 func (p Provider) CreateUser(user User) error {
-	securedPassword, err := bcryptPassword(user.Password)
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return fmt.Errorf("failed to generate user id: %w", err)
+	}
+
+	dbUser := storage.User{
+		ID:                id.String(),
+		Claims:            user.Claims,
+		Metadata:          user.Metadata,
+		PasswordChangedAt: nowFunc(),
+	}
+
+	if strings.TrimSpace(user.EMail) != "" {
+		dbUser.EMail, err = p.normalizeEMail(user.EMail)
+		if err != nil {
+			return err
+		}
+		dbUser.DisplayEMail = strings.TrimSpace(user.EMail)
+	}
+
+	err = setLoginIdentifiers(&dbUser, user)
+	if err != nil {
+		return err
+	}
+
+	if user.PasswordMaxAgeDays != nil {
+		dbUser.PasswordMaxAgeDays = *user.PasswordMaxAgeDays
+	}
+
+	if user.Password == "" {
+		return p.inviteUser(dbUser)
+	}
+
+	err = p.checkNewPassword(dbUser, user.Password)
+	if err != nil {
+		return err
+	}
+
+	dbUser.Password, err = bcryptPassword(user.Password)
 	if err != nil {
 		return fmt.Errorf("failed to bcrypt password: %w", err)
 	}
 
-	err = p.Storage.CreateUser(storage.User{
-		EMail:    user.EMail,
-		Password: securedPassword,
-		Claims:   user.Claims,
-	})
+	err = p.storeNewUser(dbUser)
+	if err != nil {
+		return err
+	}
+
+	err = p.recordPasswordHistory(dbUser.ID, dbUser.Password)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// inviteUser creates the given user without password and sends an invite mail. The user has to set the password via
+// AcceptInvitation before the first password login.
+func (p Provider) inviteUser(dbUser storage.User) error {
+	if p.InvitationLifetime <= 0 {
+		return ErrInvitationNotConfigured
+	}
+
+	if dbUser.EMail == "" {
+		return ErrInvitationRequiresEMail
+	}
+
+	err := p.storeNewUser(dbUser)
+	if err != nil {
+		return err
+	}
+
+	return p.sendInvitation(dbUser)
+}
+
+// storeNewUser persists the given new user
+func (p Provider) storeNewUser(dbUser storage.User) error {
+	err := p.Storage.CreateUser(dbUser)
 	if err != nil {
 		if errors.Is(err, storage.ErrUserAlreadyExists) {
 			return ErrUserAlreadyExists
 		}
-		return fmt.Errorf("failed to query user with email %q: %w", user.EMail, err)
+		return fmt.Errorf("failed to create user %q: %w", loginName(dbUser), err)
 	}
 
 	return nil
 }
 
-// GetUser returns a user with the given email.
+// GetUser returns the user with the given id or login identifier.
 // return ErrUserNotFound when user does not exist
-func (p Provider) GetUser(email string) (User, error) {
-	user, err := p.Storage.User(email)
+func (p Provider) GetUser(idOrIdentifier string) (User, error) {
+	user, err := p.findUser(idOrIdentifier)
 	if err != nil {
-		if errors.Is(err, storage.ErrUserNotFound) {
-			return User{}, ErrUserNotFound
-		}
-
-		return User{}, fmt.Errorf("failed to delete user with email %q: %w", email, err)
+		return User{}, err
 	}
 
-	return User{
-		EMail:    user.EMail,
-		Password: blankedPassword,
-		Claims:   user.Claims,
-	}, nil
+	return toUser(user), nil
 }
 
-// UpdateUser updates user with given email.
+// UpdateUser updates the user with the given id or login identifier.
 // return ErrUserNotFound when user does not exist
-func (p Provider) UpdateUser(email string, user User) (User, error) {
-	dbUser, err := p.Storage.User(email)
+// return ErrInvalidUsername or ErrInvalidPhone when a login identifier is malformed
+// return ErrNoLoginIdentifier when the last login identifier would be removed
+// return ErrUserAlreadyExists when another user has the new username or phone
+// return ErrPasswordBreached when the new password has been found in a data breach
+// return ErrPasswordReused when the new password has been used recently
+func (p Provider) UpdateUser(idOrIdentifier string, user User) (User, error) {
+	dbUser, err := p.findUser(idOrIdentifier)
+	if err != nil {
+		return User{}, err
+	}
+
+	err = p.applyUserUpdate(&dbUser, user)
+	if err != nil {
+		return User{}, err
+	}
+
+	err = p.Storage.UpdateUser(dbUser)
 	if err != nil {
 		if errors.Is(err, storage.ErrUserNotFound) {
 			return User{}, ErrUserNotFound
 		}
+		if errors.Is(err, storage.ErrUserAlreadyExists) {
+			return User{}, ErrUserAlreadyExists
+		}
+
+		return User{}, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	if user.Password != "" {
+		err = p.passwordChanged(dbUser.ID, dbUser.Password)
+		if err != nil {
+			return User{}, err
+		}
+	}
 
-		return User{}, fmt.Errorf("failed to find user to update: %w", err)
+	return toUser(dbUser), nil
+}
+
+// applyUserUpdate sets all properties of the given user which are not nil or empty to the given dbUser like
+// UpdateUser. New passwords will be checked and bcrypted.
+func (p Provider) applyUserUpdate(dbUser *storage.User, user User) error {
+	err := setLoginIdentifiers(dbUser, user)
+	if err != nil {
+		return err
 	}
 
 	if user.Password != "" {
+		err = p.checkNewPassword(*dbUser, user.Password)
+		if err != nil {
+			return err
+		}
+
 		bcryptedPassword, err := bcryptPassword(user.Password)
 		if err != nil {
-			return User{}, fmt.Errorf("failed to bcrypt new password: %w", err)
+			return fmt.Errorf("failed to bcrypt new password: %w", err)
 		}
 		dbUser.Password = bcryptedPassword
+		dbUser.PasswordChangedAt = nowFunc()
 	}
 
 	if user.Claims != nil {
 		dbUser.Claims = user.Claims
 	}
 
-	err = p.Storage.UpdateUser(dbUser)
-	if err != nil {
-		if errors.Is(err, storage.ErrUserNotFound) {
-			return User{}, ErrUserNotFound
-		}
+	if user.Metadata != nil {
+		dbUser.Metadata = user.Metadata
+	}
 
-		return User{}, fmt.Errorf("failed to update user: %w", err)
+	if user.PasswordMaxAgeDays != nil {
+		dbUser.PasswordMaxAgeDays = *user.PasswordMaxAgeDays
 	}
 
-	return User{
-		EMail:    dbUser.EMail,
-		Password: blankedPassword,
-		Claims:   dbUser.Claims,
-	}, nil
+	return nil
 }
 
-// DeleteUser deletes user with given email.
+// DeleteUser deletes the user with the given id or login identifier.
 // return ErrUserNotFound when user does not exist
-// return ErrUserStillHasTokens when user still has tokens
-func (p Provider) DeleteUser(email string) error {
-	err := p.Storage.DeleteUser(email)
+func (p Provider) DeleteUser(idOrIdentifier string) error {
+	user, err := p.findUser(idOrIdentifier)
+	if err != nil {
+		return err
+	}
+
+	err = p.Storage.DeleteUser(user.ID)
 	if err != nil {
 		if errors.Is(err, storage.ErrUserNotFound) {
 			return ErrUserNotFound
 		}
 
-		return fmt.Errorf("failed to delete user with email %q: %w", email, err)
+		return fmt.Errorf("failed to delete user %q: %w", user.ID, err)
+	}
+
+	return nil
+}
+
+// ClaimPredicate is a condition on a top level claim of users
+type ClaimPredicate struct {
+	Claim string
+	// Operator is one of the ClaimOperator* constants
+	Operator string
+	// Value is the json decoded value the claim will be compared with. It is unused by ClaimOperatorExists
+	Value interface{}
+}
+
+// UserPage is a page of users
+type UserPage struct {
+	Users []User
+	// Total is the count of all matching users
+	Total int
+}
+
+// FindUsers returns 'limit' users ordered by id starting at 'offset' whose claims fulfill all given predicates. All
+// users will be returned when no predicate is given.
+// return ErrInvalidClaimPredicate when a predicate has no claim or an unknown operator
+func (p Provider) FindUsers(predicates []ClaimPredicate, limit, offset int) (UserPage, error) {
+	var dbPredicates []storage.ClaimPredicate
+	for i, predicate := range predicates {
+		dbPredicate := storage.ClaimPredicate{
+			Claim:    predicate.Claim,
+			Operator: predicate.Operator,
+			Value:    predicate.Value,
+		}
+		if predicate.Claim == "" {
+			return UserPage{}, fmt.Errorf("%w: claim of predicate %d is missing", ErrInvalidClaimPredicate, i+1)
+		}
+		err := dbPredicate.Validate()
+		if err != nil {
+			return UserPage{}, fmt.Errorf("%w: %s", ErrInvalidClaimPredicate, err)
+		}
+
+		dbPredicates = append(dbPredicates, dbPredicate)
+	}
+
+	users, total, err := p.Storage.UsersByClaims(dbPredicates, limit, offset)
+	if err != nil {
+		return UserPage{}, fmt.Errorf("failed to query users by claims: %w", err)
+	}
+
+	page := UserPage{Users: []User{}, Total: total}
+	for _, u := range users {
+		page.Users = append(page.Users, toUser(u))
+	}
+
+	return page, nil
+}
+
+// findUser finds the user identified by the given id or login identifier. Ids are uuids, everything else will be
+// handled like on login (see userByLoginIdentifier).
+// return ErrUserNotFound when user does not exist
+func (p Provider) findUser(idOrIdentifier string) (storage.User, error) {
+	id, err := uuid.Parse(idOrIdentifier)
+	if err == nil {
+		return queryUser(p.Storage.UserByID, "id", id.String())
+	}
+
+	return p.userByLoginIdentifier(idOrIdentifier)
+}
+
+// setLoginIdentifiers sets the normalized username and phone of the given user to the given dbUser. Nil values will
+// be left unchanged, empty ones remove the login identifier.
+// return ErrInvalidUsername or ErrInvalidPhone when a login identifier is malformed
+// return ErrNoLoginIdentifier when the dbUser has no login identifier afterwards
+func setLoginIdentifiers(dbUser *storage.User, user User) error {
+	var err error
+	if user.Username != nil {
+		dbUser.Username = ""
+		if strings.TrimSpace(*user.Username) != "" {
+			dbUser.Username, err = normalizeUsername(*user.Username)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if user.Phone != nil {
+		dbUser.Phone = ""
+		if strings.TrimSpace(*user.Phone) != "" {
+			dbUser.Phone, err = normalizePhone(*user.Phone)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if dbUser.EMail == "" && dbUser.Username == "" && dbUser.Phone == "" {
+		return ErrNoLoginIdentifier
 	}
 
 	return nil
 }
 
+// toUser converts the given storage.User to a User with blanked password
+func toUser(u storage.User) User {
+	user := User{
+		ID:                u.ID,
+		EMail:             u.EMail,
+		DisplayEMail:      u.DisplayEMail,
+		Username:          optionalString(u.Username),
+		Phone:             optionalString(u.Phone),
+		Password:          blankedPassword,
+		Claims:            u.Claims,
+		Metadata:          u.Metadata,
+		PasswordChangedAt: u.PasswordChangedAt,
+		LastLoginAt:       u.LastLoginAt,
+		InvitationPending: len(u.Password) == 0,
+	}
+	if u.PasswordMaxAgeDays > 0 {
+		passwordMaxAgeDays := u.PasswordMaxAgeDays
+		user.PasswordMaxAgeDays = &passwordMaxAgeDays
+	}
+
+	return user
+}
+
+// optionalString returns a pointer to the given string or nil when it is empty
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return &s
+}
+
 func bcryptPassword(password string) ([]byte, error) {
 	return bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
 }
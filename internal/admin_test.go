@@ -3,10 +3,12 @@ package internal
 import (
 	"errors"
 	"fmt"
+	"github.com/google/uuid"
 	"github.com/leberKleber/simple-jwt-provider/internal/storage"
 	"golang.org/x/crypto/bcrypt"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestProvider_CreateUser(t *testing.T) {
@@ -29,6 +31,24 @@ func TestProvider_CreateUser(t *testing.T) {
 				Password: []byte("s3cr3t"),
 				Claims:   map[string]interface{}{"cLaIM": "as"},
 			},
+		}, {
+			name: "Happycase with email to normalize",
+			givenUser: User{
+				EMail:    " Test@Test.TEST ",
+				Password: "s3cr3t",
+			},
+			dbExpectedUser: storage.User{
+				EMail:        "Test@test.test",
+				DisplayEMail: "Test@Test.TEST",
+				Password:     []byte("s3cr3t"),
+			},
+		}, {
+			name: "Invalid email",
+			givenUser: User{
+				EMail:    "test.test",
+				Password: "s3cr3t",
+			},
+			expectedError: ErrInvalidEMail,
 		}, {
 			name: "user already exists",
 			givenUser: User{
@@ -52,7 +72,40 @@ func TestProvider_CreateUser(t *testing.T) {
 				EMail:    "test@test.test",
 				Password: []byte("s3cr3t"),
 			},
-			expectedError: errors.New(`failed to query user with email "test@test.test": my custom error. ALARM`),
+			expectedError: errors.New(`failed to create user "test@test.test": my custom error. ALARM`),
+		}, {
+			name: "Happycase with username and phone only",
+			givenUser: User{
+				Username: stringPtr(" Alice "),
+				Phone:    stringPtr("+49 (170) 123-4567"),
+				Password: "s3cr3t",
+			},
+			dbExpectedUser: storage.User{
+				Username: "alice",
+				Phone:    "+491701234567",
+				Password: []byte("s3cr3t"),
+			},
+		}, {
+			name: "No login identifier",
+			givenUser: User{
+				Username: stringPtr(""),
+				Password: "s3cr3t",
+			},
+			expectedError: ErrNoLoginIdentifier,
+		}, {
+			name: "Invalid username",
+			givenUser: User{
+				Username: stringPtr("a"),
+				Password: "s3cr3t",
+			},
+			expectedError: ErrInvalidUsername,
+		}, {
+			name: "Invalid phone",
+			givenUser: User{
+				Phone:    stringPtr("0170 1234567"),
+				Password: "s3cr3t",
+			},
+			expectedError: ErrInvalidPhone,
 		},
 	}
 
@@ -77,6 +130,20 @@ func TestProvider_CreateUser(t *testing.T) {
 				t.Errorf("Given db user > email is not as expected: \nExpected:%s\nGiven:%s", tt.dbExpectedUser.EMail, givenDbUser.EMail)
 			}
 
+			if givenDbUser.Username != tt.dbExpectedUser.Username || givenDbUser.Phone != tt.dbExpectedUser.Phone {
+				t.Errorf("Given db user > username/phone is not as expected: \nExpected:%q/%q\nGiven:%q/%q", tt.dbExpectedUser.Username, tt.dbExpectedUser.Phone, givenDbUser.Username, givenDbUser.Phone)
+			}
+
+			if tt.dbExpectedUser.DisplayEMail != "" && givenDbUser.DisplayEMail != tt.dbExpectedUser.DisplayEMail {
+				t.Errorf("Given db user > display email is not as expected: \nExpected:%s\nGiven:%s", tt.dbExpectedUser.DisplayEMail, givenDbUser.DisplayEMail)
+			}
+
+			if tt.dbExpectedUser.Password == nil {
+				return
+			}
+			if _, err := uuid.Parse(givenDbUser.ID); err != nil {
+				t.Errorf("Given db user > id is not a uuid: %q", givenDbUser.ID)
+			}
 			if err := bcrypt.CompareHashAndPassword(givenDbUser.Password, tt.dbExpectedUser.Password); err != nil {
 				t.Errorf("Given db user > password is not as expected: \nExpected:%s\nGiven(bcrypted):%s", tt.dbExpectedUser.Password, givenDbUser.Password)
 			}
@@ -90,6 +157,7 @@ func TestProvider_GetUser(t *testing.T) {
 		name            string
 		givenEMail      string
 		dbExpectedEMail string
+		dbExpectedID    string
 		dbReturnUser    storage.User
 		dbReturnError   error
 		expectedError   error
@@ -113,6 +181,18 @@ func TestProvider_GetUser(t *testing.T) {
 					"claaa": "bbb",
 				},
 			},
+		}, {
+			name:            "Happycase invited user",
+			dbExpectedEMail: "test@test.test",
+			dbReturnUser: storage.User{
+				EMail: "test@test.test",
+			},
+			givenEMail: "test@test.test",
+			expectedUser: User{
+				EMail:             "test@test.test",
+				Password:          "**********",
+				InvitationPending: true,
+			},
 		}, {
 			name:            "user not found",
 			givenEMail:      "test@test.test",
@@ -124,19 +204,44 @@ func TestProvider_GetUser(t *testing.T) {
 			givenEMail:      "test@test.test",
 			dbExpectedEMail: "test@test.test",
 			dbReturnError:   errors.New("my custom error. ALARM"),
-			expectedError:   errors.New(`failed to delete user with email "test@test.test": my custom error. ALARM`),
+			expectedError:   errors.New(`failed to query user with email "test@test.test": my custom error. ALARM`),
+		}, {
+			name:         "Happycase by id",
+			givenEMail:   "C6A1E4A8-1A5E-4B8E-9D6B-5F1F6D0E2A7B",
+			dbExpectedID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			dbReturnUser: storage.User{
+				ID:       "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				EMail:    "test.test@test.test",
+				Password: []byte("password"),
+			},
+			expectedUser: User{
+				ID:       "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				EMail:    "test.test@test.test",
+				Password: "**********",
+			},
+		}, {
+			name:          "Some db error by id",
+			givenEMail:    "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			dbExpectedID:  "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			dbReturnError: errors.New("my custom error. ALARM"),
+			expectedError: errors.New(`failed to query user with id "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b": my custom error. ALARM`),
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			var givenEMail string
+			var givenID string
 			toTest := Provider{
 				Storage: &StorageMock{
 					UserFunc: func(email string) (storage.User, error) {
 						givenEMail = email
 						return tt.dbReturnUser, tt.dbReturnError
 					},
+					UserByIDFunc: func(id string) (storage.User, error) {
+						givenID = id
+						return tt.dbReturnUser, tt.dbReturnError
+					},
 				},
 			}
 
@@ -152,6 +257,10 @@ func TestProvider_GetUser(t *testing.T) {
 			if givenEMail != tt.dbExpectedEMail {
 				t.Errorf("Given db email is not as expected: \nExpected:%s\nGiven:%s", tt.dbExpectedEMail, givenEMail)
 			}
+
+			if givenID != tt.dbExpectedID {
+				t.Errorf("Given db id is not as expected: \nExpected:%s\nGiven:%s", tt.dbExpectedID, givenID)
+			}
 		})
 	}
 
@@ -164,8 +273,15 @@ func TestProvider_UpdateUser_Happycase(t *testing.T) {
 		Claims: map[string]interface{}{
 			"c": "g",
 		},
+		Metadata: map[string]interface{}{
+			"m": "x",
+		},
 	}
 	var dbUpdateUser storage.User
+	now := time.Date(2020, 5, 4, 3, 2, 1, 0, time.UTC)
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	nowFunc = func() time.Time { return now }
 	toTest := Provider{
 		Storage: &StorageMock{
 			UserFunc: func(email string) (storage.User, error) {
@@ -175,6 +291,9 @@ func TestProvider_UpdateUser_Happycase(t *testing.T) {
 				dbUpdateUser = user
 				return nil
 			},
+			TokensByUserIDAndTypeFunc: func(userID string, tokenType string) ([]storage.Token, error) {
+				return nil, nil
+			},
 		},
 	}
 
@@ -194,6 +313,10 @@ func TestProvider_UpdateUser_Happycase(t *testing.T) {
 		Claims: map[string]interface{}{
 			"d": "w",
 		},
+		Metadata: map[string]interface{}{
+			"m": "x",
+		},
+		PasswordChangedAt: now,
 	}
 	if !reflect.DeepEqual(updatedUser, expectedUpdatedUser) {
 		t.Errorf("returned updated user is not as expected. Expected:\n%#v\nGiven:\n%#v", expectedUpdatedUser, updatedUser)
@@ -218,6 +341,51 @@ func TestProvider_UpdateUser_Happycase(t *testing.T) {
 	if !reflect.DeepEqual(dbUpdateUser.Claims, expectedDBUpdateUser.Claims) {
 		t.Errorf("user.claims to update in db is not as expected. Expected:\n%#v\nGiven:\n%#v", expectedDBUpdateUser.Claims, dbUpdateUser.Claims)
 	}
+
+	if !reflect.DeepEqual(dbUpdateUser.Metadata, dbUserToUpdate.Metadata) {
+		t.Errorf("user.metadata to update in db is not as expected. Expected:\n%#v\nGiven:\n%#v", dbUserToUpdate.Metadata, dbUpdateUser.Metadata)
+	}
+}
+
+func TestProvider_UpdateUser_Metadata(t *testing.T) {
+	var dbUpdateUser storage.User
+	toTest := Provider{
+		Storage: &StorageMock{
+			UserFunc: func(email string) (storage.User, error) {
+				return storage.User{
+					EMail:    email,
+					Password: []byte("testSecret"),
+					Claims:   map[string]interface{}{"c": "g"},
+					Metadata: map[string]interface{}{"m": "x"},
+				}, nil
+			},
+			UpdateUserFunc: func(user storage.User) error {
+				dbUpdateUser = user
+				return nil
+			},
+		},
+	}
+
+	updatedUser, err := toTest.UpdateUser("test.test@test.test", User{
+		Metadata: map[string]interface{}{"n": "y"},
+	})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	expectedDBUpdateUser := storage.User{
+		EMail:    "test.test@test.test",
+		Password: []byte("testSecret"),
+		Claims:   map[string]interface{}{"c": "g"},
+		Metadata: map[string]interface{}{"n": "y"},
+	}
+	if !reflect.DeepEqual(dbUpdateUser, expectedDBUpdateUser) {
+		t.Errorf("user to update in db is not as expected. Expected:\n%#v\nGiven:\n%#v", expectedDBUpdateUser, dbUpdateUser)
+	}
+
+	if !reflect.DeepEqual(updatedUser.Claims, expectedDBUpdateUser.Claims) || !reflect.DeepEqual(updatedUser.Metadata, expectedDBUpdateUser.Metadata) {
+		t.Errorf("returned updated user is not as expected. Given:\n%#v", updatedUser)
+	}
 }
 
 func TestProvider_UpdateUser_UnableToGetUser(t *testing.T) {
@@ -234,7 +402,7 @@ func TestProvider_UpdateUser_UnableToGetUser(t *testing.T) {
 		{
 			name:                "unexpected error",
 			dbUserResponseError: errors.New("nope"),
-			expectedError:       errors.New("failed to find user to update: nope"),
+			expectedError:       errors.New("failed to query user with email \"test.test@test.test\": nope"),
 		},
 	}
 
@@ -322,38 +490,48 @@ func TestProvider_UpdateUser_UnableToUpdateUser(t *testing.T) {
 
 func TestProvider_DeleteUser(t *testing.T) {
 	tests := []struct {
-		name            string
-		givenEMail      string
-		expectedError   error
-		dbExpectedEMail string
-		dbReturnError   error
+		name          string
+		givenEMail    string
+		expectedError error
+		dbExpectedID  string
+		dbReturnError error
 	}{
 		{
-			name:            "Happycase",
-			dbExpectedEMail: "test@test.test",
-			givenEMail:      "test@test.test",
+			name:         "Happycase",
+			dbExpectedID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			givenEMail:   "test@test.test",
 		}, {
-			name:            "user not found",
-			givenEMail:      "test@test.test",
-			dbExpectedEMail: "test@test.test",
-			dbReturnError:   storage.ErrUserNotFound,
-			expectedError:   ErrUserNotFound,
+			name:          "user not found",
+			givenEMail:    "test@test.test",
+			dbExpectedID:  "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			dbReturnError: storage.ErrUserNotFound,
+			expectedError: ErrUserNotFound,
 		}, {
-			name:            "Some db error",
-			givenEMail:      "test@test.test",
-			dbExpectedEMail: "test@test.test",
-			dbReturnError:   errors.New("my custom error. ALARM"),
-			expectedError:   errors.New(`failed to delete user with email "test@test.test": my custom error. ALARM`),
+			name:          "Some db error",
+			givenEMail:    "test@test.test",
+			dbExpectedID:  "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			dbReturnError: errors.New("my custom error. ALARM"),
+			expectedError: errors.New(`failed to delete user "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b": my custom error. ALARM`),
+		}, {
+			name:         "Happycase by id",
+			givenEMail:   "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			dbExpectedID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			var givenEMail string
+			var givenID string
 			toTest := Provider{
 				Storage: &StorageMock{
-					DeleteUserFunc: func(email string) error {
-						givenEMail = email
+					UserFunc: func(email string) (storage.User, error) {
+						return storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", EMail: email}, nil
+					},
+					UserByIDFunc: func(id string) (storage.User, error) {
+						return storage.User{ID: id, EMail: "test@test.test"}, nil
+					},
+					DeleteUserFunc: func(id string) error {
+						givenID = id
 						return tt.dbReturnError
 					},
 				},
@@ -364,10 +542,101 @@ func TestProvider_DeleteUser(t *testing.T) {
 				t.Fatalf("Processing error is not as expected: \nExpected:%s\nGiven:%s", tt.expectedError, err)
 			}
 
-			if givenEMail != tt.dbExpectedEMail {
-				t.Errorf("Given db email is not as expected: \nExpected:%s\nGiven:%s", tt.dbExpectedEMail, givenEMail)
+			if givenID != tt.dbExpectedID {
+				t.Errorf("Given db id is not as expected: \nExpected:%s\nGiven:%s", tt.dbExpectedID, givenID)
+			}
+		})
+	}
+
+}
+
+func TestProvider_FindUsers(t *testing.T) {
+	passwordChangedAt := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+
+	tests := []struct {
+		name               string
+		givenPredicates    []ClaimPredicate
+		dbError            error
+		expectedPredicates []storage.ClaimPredicate
+		expectedPage       UserPage
+		expectedError      error
+	}{
+		{
+			name: "Happycase",
+			givenPredicates: []ClaimPredicate{
+				{Claim: "roles", Operator: ClaimOperatorContains, Value: []interface{}{"admin"}},
+				{Claim: "beta", Operator: ClaimOperatorExists},
+			},
+			expectedPredicates: []storage.ClaimPredicate{
+				{Claim: "roles", Operator: storage.ClaimOperatorContains, Value: []interface{}{"admin"}},
+				{Claim: "beta", Operator: storage.ClaimOperatorExists},
+			},
+			expectedPage: UserPage{
+				Users: []User{{
+					ID:                "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+					EMail:             "test@test.test",
+					Password:          blankedPassword,
+					Claims:            map[string]interface{}{"roles": []interface{}{"admin"}, "beta": true},
+					PasswordChangedAt: passwordChangedAt,
+				}},
+				Total: 3,
+			},
+		}, {
+			name:            "Missing claim",
+			givenPredicates: []ClaimPredicate{{Operator: ClaimOperatorExists}},
+			expectedError:   fmt.Errorf("%w: claim of predicate 1 is missing", ErrInvalidClaimPredicate),
+		}, {
+			name:            "Unknown operator",
+			givenPredicates: []ClaimPredicate{{Claim: "roles", Operator: "like", Value: "admin"}},
+			expectedError:   fmt.Errorf(`%w: unknown claim operator "like"`, ErrInvalidClaimPredicate),
+		}, {
+			name:               "Some db error",
+			givenPredicates:    []ClaimPredicate{{Claim: "beta", Operator: ClaimOperatorExists}},
+			dbError:            errors.New("nope"),
+			expectedPredicates: []storage.ClaimPredicate{{Claim: "beta", Operator: storage.ClaimOperatorExists}},
+			expectedError:      errors.New("failed to query users by claims: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenPredicates []storage.ClaimPredicate
+			var givenLimit, givenOffset int
+			toTest := Provider{
+				Storage: &StorageMock{
+					UsersByClaimsFunc: func(predicates []storage.ClaimPredicate, limit int, offset int) ([]storage.User, int, error) {
+						givenPredicates, givenLimit, givenOffset = predicates, limit, offset
+						return []storage.User{{
+							ID:                "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+							EMail:             "test@test.test",
+							Password:          []byte("bcryptedPassword"),
+							Claims:            map[string]interface{}{"roles": []interface{}{"admin"}, "beta": true},
+							PasswordChangedAt: passwordChangedAt,
+						}}, 3, tt.dbError
+					},
+				},
+			}
+
+			page, err := toTest.FindUsers(tt.givenPredicates, 10, 20)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:%s\nGiven:%s", tt.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(givenPredicates, tt.expectedPredicates) {
+				t.Errorf("Given db predicates are not as expected: \nExpected:%#v\nGiven:%#v", tt.expectedPredicates, givenPredicates)
+			}
+
+			if tt.expectedPredicates != nil && (givenLimit != 10 || givenOffset != 20) {
+				t.Errorf("Given db limit and offset are not as expected. Expected: 10, 20. Given: %d, %d", givenLimit, givenOffset)
+			}
+
+			if !reflect.DeepEqual(page, tt.expectedPage) {
+				t.Errorf("Page is not as expected: \nExpected:%#v\nGiven:%#v", tt.expectedPage, page)
 			}
 		})
 	}
+}
 
+func stringPtr(s string) *string {
+	return &s
 }
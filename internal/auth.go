@@ -1,7 +1,6 @@
 package internal
 
 import (
-	"crypto/rand"
 	"errors"
 	"fmt"
 	"github.com/leberKleber/simple-jwt-provider/internal/storage"
@@ -12,55 +11,139 @@ import (
 var ErrIncorrectPassword = errors.New("password incorrect")
 var ErrUserNotFound = errors.New("user not found")
 var ErrNoValidTokenFound = errors.New("no valid token found")
+
+// ErrTokenExpired is an ErrNoValidTokenFound for tokens which exist but have expired
+var ErrTokenExpired = fmt.Errorf("%w: token has expired", ErrNoValidTokenFound)
 var nowFunc = time.Now
 
-// Login checks email / password combination and return a new jwt if correct.
+// Login checks login identifier (email, username or phone) / password combination and return a new jwt if correct.
+// When the user has enabled totp or webauthn, a mfa challenge token will be returned instead which has to be redeemed
+// via LoginMFA or FinishWebAuthnLogin. The attempt will be recorded in the login history of the user.
 // return ErrIncorrectPassword when password is incorrect
 // return ErrUserNotFound when user not found
-func (p Provider) Login(email, password string) (string, error) {
-	u, err := p.Storage.User(email)
+// return ErrPasswordExpired when password is correct but expired. It has to be changed via ChangePassword
+func (p Provider) Login(identifier, password string, client Client) (result LoginResult, err error) {
+	u, err := p.userByLoginIdentifier(identifier)
+	if err != nil {
+		return LoginResult{}, err
+	}
+	defer func() { p.recordLogin(u.ID, client, LoginFactorPassword, result.MFAToken != "", err) }()
+
+	err = checkPassword(u, password)
+	if err != nil {
+		return LoginResult{}, err
+	}
+
+	if p.isPasswordExpired(u) {
+		return LoginResult{}, ErrPasswordExpired
+	}
+
+	mfaMethods, err := p.mfaMethods(u.ID)
 	if err != nil {
-		if errors.Is(err, storage.ErrUserNotFound) {
-			return "", ErrUserNotFound
+		return LoginResult{}, err
+	}
+
+	if len(mfaMethods) > 0 {
+		mfaToken, err := p.issueMFAToken(u.ID, amrPassword)
+		if err != nil {
+			return LoginResult{}, err
 		}
-		return "", fmt.Errorf("failed to query user with email %q: %w", email, err)
+
+		return LoginResult{MFAToken: mfaToken, MFAMethods: mfaMethods}, nil
+	}
+
+	jwt, err := p.JWTGenerator.Generate(u.ID, u.EMail, p.loginClaims(u.ID, password, u.Claims))
+	if err != nil {
+		return LoginResult{}, err
+	}
+
+	return LoginResult{AccessToken: jwt}, nil
+}
+
+// authenticate checks login identifier / password combination and returns the user if correct.
+// return ErrIncorrectPassword when password is incorrect
+// return ErrUserNotFound when user not found
+func (p Provider) authenticate(identifier, password string) (storage.User, error) {
+	u, err := p.userByLoginIdentifier(identifier)
+	if err != nil {
+		return storage.User{}, err
+	}
+
+	err = checkPassword(u, password)
+	if err != nil {
+		return storage.User{}, err
+	}
+
+	return u, nil
+}
+
+// checkPassword checks the given password against the password hash of the given user
+// return ErrIncorrectPassword when password is incorrect
+func checkPassword(u storage.User, password string) error {
+	err := bcrypt.CompareHashAndPassword(u.Password, []byte(password))
+	if err != nil {
+		return ErrIncorrectPassword
 	}
 
-	err = bcrypt.CompareHashAndPassword(u.Password, []byte(password))
+	return nil
+}
+
+// ChangePassword changes the password of the given user if the current password is correct.
+// return ErrIncorrectPassword when password is incorrect
+// return ErrUserNotFound when user not found
+// return ErrPasswordBreached when the new password has been found in a data breach
+// return ErrPasswordReused when the new password has been used recently
+func (p Provider) ChangePassword(identifier, password, newPassword string) error {
+	u, err := p.authenticate(identifier, password)
+	if err != nil {
+		return err
+	}
+
+	err = p.checkNewPassword(u, newPassword)
 	if err != nil {
-		return "", ErrIncorrectPassword
+		return err
 	}
 
-	return p.JWTGenerator.Generate(email, u.Claims)
+	securedPassword, err := bcryptPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("failed to bcrypt password: %w", err)
+	}
+	u.Password = securedPassword
+	u.PasswordChangedAt = nowFunc()
+
+	err = p.Storage.UpdateUser(u)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	return p.passwordChanged(u.ID, securedPassword)
 }
 
-// CreatePasswordResetRequest send a password-reset-request email to the give address.
+// CreatePasswordResetRequest send a password-reset-request email to the give address. Previously sent reset tokens
+// will be invalidated.
 // return ErrUserNotFound when user does not exists
 func (p Provider) CreatePasswordResetRequest(email string) error {
-	u, err := p.Storage.User(email)
+	email, err := p.normalizeEMail(email)
 	if err != nil {
-		if errors.Is(err, storage.ErrUserNotFound) {
-			return ErrUserNotFound
-		}
-		return fmt.Errorf("failed to query user with email %q: %w", email, err)
+		return err
 	}
 
-	t, err := generateHEXToken()
+	u, err := queryUser(p.Storage.User, "email", email)
 	if err != nil {
-		return fmt.Errorf("failed to generate password-reset-token")
+		return err
 	}
 
-	_, err = p.Storage.CreateToken(storage.Token{
-		EMail:     email,
-		Token:     t,
-		Type:      storage.TokenTypeReset,
-		CreatedAt: nowFunc(),
-	})
+	err = p.revokeTokens(u.ID, storage.TokenTypeReset)
 	if err != nil {
-		return fmt.Errorf("failed to create password-reset-token for email %q: %w", email, err)
+		return err
 	}
 
-	err = p.Mailer.SendPasswordResetRequestEMail(email, t, u.Claims)
+	t, err := p.issueToken(u.ID, storage.TokenTypeReset, nil)
+	if err != nil {
+		return err
+	}
+
+	err = p.Mailer.SendPasswordResetRequestEMail(email, t, u.Claims, u.Metadata)
 	if err != nil {
 		return fmt.Errorf("failed to send password-reset-email: %w", err)
 	}
@@ -68,30 +151,34 @@ func (p Provider) CreatePasswordResetRequest(email string) error {
 	return nil
 }
 
-// ResetPassword resets the password of the given account if the reset token is correct.
+// ResetPassword resets the password of the given account if the reset token is correct and not older than
+// PasswordResetTokenLifetime. All reset tokens of the user will be invalidated afterwards.
 // return ErrNoValidTokenFound no valid token could be found
+// return ErrTokenExpired when the reset token has expired
+// return ErrPasswordBreached when the new password has been found in a data breach
+// return ErrPasswordReused when the new password has been used recently
 func (p *Provider) ResetPassword(email, resetToken, newPassword string) error {
-	tokens, err := p.Storage.TokensByEMailAndToken(email, resetToken)
+	email, err := p.normalizeEMail(email)
 	if err != nil {
-		return fmt.Errorf("faild to find all avalilable tokens: %w", err)
+		return err
 	}
 
-	var t *storage.Token
-	for _, token := range tokens {
-		if token.Type == storage.TokenTypeReset {
-			//TODO check lifetime
-			t = &token
-			break
+	u, err := queryUser(p.Storage.User, "email", email)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return ErrNoValidTokenFound
 		}
+		return err
 	}
 
-	if t == nil {
-		return ErrNoValidTokenFound
+	_, err = p.findToken(u.ID, resetToken, storage.TokenTypeReset)
+	if err != nil {
+		return err
 	}
 
-	u, err := p.Storage.User(email)
+	err = p.checkNewPassword(u, newPassword)
 	if err != nil {
-		return fmt.Errorf("failed to find user: %w", err)
+		return err
 	}
 
 	securedPassword, err := bcryptPassword(newPassword)
@@ -99,23 +186,12 @@ func (p *Provider) ResetPassword(email, resetToken, newPassword string) error {
 		return fmt.Errorf("failed to bcrypt password: %w", err)
 	}
 	u.Password = securedPassword
+	u.PasswordChangedAt = nowFunc()
 
 	err = p.Storage.UpdateUser(u)
 	if err != nil {
 		return fmt.Errorf("failed to update user: %w", err)
 	}
 
-	err = p.Storage.DeleteToken(t.ID)
-	if err != nil {
-		return fmt.Errorf("failed to delete token: %w", err)
-	}
-
-	return nil
-}
-
-//generate 64 char long hex token  (32 bytes == 64 hex chars)
-func generateHEXToken() (string, error) {
-	b := make([]byte, 32)
-	_, err := rand.Read(b)
-	return fmt.Sprintf("%x", b), err
+	return p.passwordChanged(u.ID, securedPassword)
 }
@@ -25,6 +25,11 @@ func TestProvider_Login(t *testing.T) {
 		generatorError         error
 		dbReturnError          error
 		dbReturnUser           storage.User
+		dbTOTP                 storage.TOTP
+		dbTOTPError            error
+		expectedMFAToken       bool
+		expectedMFAMethods     []string
+		expectedLoginOutcome   string
 	}{
 		{
 			name:                   "Happycase",
@@ -33,12 +38,17 @@ func TestProvider_Login(t *testing.T) {
 			generatorExpectedEMail: "test@test.test",
 			generatorJWT:           "myJWT",
 			expectedJWT:            "myJWT",
+			expectedLoginOutcome:   storage.LoginOutcomeSuccess,
 			dbReturnUser: storage.User{
+				ID:       "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
 				Password: []byte("$2a$12$1v7O.pNLqugJjcePyxvUj.GK37YoAbJvSW/9bULSRmq5C4SkoU2OO"),
 				EMail:    "test@test.test",
 				Claims: map[string]interface{}{
 					"myCustomClaim": "value",
 				},
+				Metadata: map[string]interface{}{
+					"myMetadata": "never in jwt",
+				},
 			},
 		},
 		{
@@ -56,31 +66,102 @@ func TestProvider_Login(t *testing.T) {
 			dbReturnError: errors.New("unexpected error"),
 		},
 		{
-			name:          "Incorrect Password",
+			name:                 "Incorrect Password",
+			givenEMail:           "test@test.test",
+			givenPassword:        "wrongPassword",
+			expectedError:        ErrIncorrectPassword,
+			expectedLoginOutcome: storage.LoginOutcomeFailed,
+			dbReturnUser: storage.User{
+				ID:       "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				Password: []byte("$2a$12$1v7O.pNLqugJjcePyxvUj.GK37YoAbJvSW/9bULSRmq5C4SkoU2OO"),
+				EMail:    "test@test.test",
+			},
+		},
+		{
+			name:                 "Password expired",
+			givenEMail:           "test@test.test",
+			givenPassword:        "password",
+			expectedError:        ErrPasswordExpired,
+			expectedLoginOutcome: storage.LoginOutcomePasswordExpired,
+			dbReturnUser: storage.User{
+				ID:                 "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				Password:           []byte("$2a$12$1v7O.pNLqugJjcePyxvUj.GK37YoAbJvSW/9bULSRmq5C4SkoU2OO"),
+				EMail:              "test@test.test",
+				PasswordChangedAt:  time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+				PasswordMaxAgeDays: 90,
+			},
+		},
+		{
+			name:          "MFA required",
 			givenEMail:    "test@test.test",
-			givenPassword: "wrongPassword",
-			expectedError: ErrIncorrectPassword,
+			givenPassword: "password",
+			dbReturnUser: storage.User{
+				ID:       "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				Password: []byte("$2a$12$1v7O.pNLqugJjcePyxvUj.GK37YoAbJvSW/9bULSRmq5C4SkoU2OO"),
+				EMail:    "test@test.test",
+			},
+			dbTOTP:               storage.TOTP{UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Confirmed: true},
+			expectedMFAToken:     true,
+			expectedMFAMethods:   []string{MFAMethodTOTP},
+			expectedLoginOutcome: storage.LoginOutcomeMFARequired,
+		},
+		{
+			name:          "Unconfirmed totp",
+			givenEMail:    "test@test.test",
+			givenPassword: "password",
 			dbReturnUser: storage.User{
 				Password: []byte("$2a$12$1v7O.pNLqugJjcePyxvUj.GK37YoAbJvSW/9bULSRmq5C4SkoU2OO"),
 				EMail:    "test@test.test",
 			},
+			dbTOTP:                 storage.TOTP{UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b"},
+			generatorExpectedEMail: "test@test.test",
+			generatorJWT:           "myJWT",
+			expectedJWT:            "myJWT",
+		},
+		{
+			name:          "Unexpected totp db error",
+			givenEMail:    "test@test.test",
+			givenPassword: "password",
+			dbReturnUser: storage.User{
+				Password: []byte("$2a$12$1v7O.pNLqugJjcePyxvUj.GK37YoAbJvSW/9bULSRmq5C4SkoU2OO"),
+				EMail:    "test@test.test",
+			},
+			dbTOTPError:   errors.New("nope"),
+			expectedError: errors.New("failed to query totp: nope"),
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			var givenStorageEMail string
+			var givenGeneratorUserID string
 			var givenGeneratorEMail string
 			var givenGeneratorUserClaims map[string]interface{}
+			var givenLogin storage.Login
 			toTest := Provider{
 				Storage: &StorageMock{
 					UserFunc: func(email string) (storage.User, error) {
 						givenStorageEMail = email
 						return tt.dbReturnUser, tt.dbReturnError
 					},
+					AddLoginFunc: func(l storage.Login) error {
+						givenLogin = l
+						return nil
+					},
+					TOTPFunc: func(userID string) (storage.TOTP, error) {
+						return tt.dbTOTP, tt.dbTOTPError
+					},
+					CreateTokenFunc: func(t storage.Token) (int64, error) {
+						if t.Type != storage.TokenTypeMFA || t.UserID != tt.dbReturnUser.ID || t.Metadata["first_factor"] != "pwd" {
+							return 0, fmt.Errorf("unexpected token: %#v", t)
+						}
+						return 1, nil
+					},
 				},
+				TokenHasher: testTokenHasher,
 				JWTGenerator: &JWTGeneratorMock{
-					GenerateFunc: func(email string, userClaims map[string]interface{}) (string, error) {
+					GenerateFunc: func(userID string, email string, userClaims map[string]interface{}) (string, error) {
+						givenGeneratorUserID = userID
 						givenGeneratorEMail = email
 						givenGeneratorUserClaims = userClaims
 						return tt.generatorJWT, tt.generatorError
@@ -88,13 +169,21 @@ func TestProvider_Login(t *testing.T) {
 				},
 			}
 
-			jwt, err := toTest.Login(tt.givenEMail, tt.givenPassword)
+			result, err := toTest.Login(tt.givenEMail, tt.givenPassword, Client{IP: "127.0.0.1", UserAgent: "curl/7.68.0"})
 			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
 				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
 			}
 
-			if jwt != tt.expectedJWT {
-				t.Errorf("Given jwt is not as expected: \nExpected:%s\nGiven:%s", tt.expectedJWT, jwt)
+			if result.AccessToken != tt.expectedJWT {
+				t.Errorf("Given jwt is not as expected: \nExpected:%s\nGiven:%s", tt.expectedJWT, result.AccessToken)
+			}
+
+			if (len(result.MFAToken) == 64) != tt.expectedMFAToken {
+				t.Errorf("Given mfa token is not as expected: \nExpected: %t\nGiven:%s", tt.expectedMFAToken, result.MFAToken)
+			}
+
+			if !reflect.DeepEqual(result.MFAMethods, tt.expectedMFAMethods) {
+				t.Errorf("Given mfa methods are not as expected: \nExpected:%v\nGiven:%v", tt.expectedMFAMethods, result.MFAMethods)
 			}
 
 			if givenStorageEMail != tt.givenEMail {
@@ -105,9 +194,22 @@ func TestProvider_Login(t *testing.T) {
 				t.Errorf("Generator.Generate email ist not as expected: \nExpected:%s\nGiven:%s", tt.givenEMail, givenGeneratorEMail)
 			}
 
+			if givenGeneratorEMail != "" && givenGeneratorUserID != tt.dbReturnUser.ID {
+				t.Errorf("Generator.Generate user id ist not as expected: \nExpected:%s\nGiven:%s", tt.dbReturnUser.ID, givenGeneratorUserID)
+			}
+
 			if !reflect.DeepEqual(givenGeneratorUserClaims, tt.dbReturnUser.Claims) {
 				t.Errorf("Generator.Generate userClaims are not as expected: \nExpected:\n%#v\nGiven:\n%#v", tt.givenEMail, givenGeneratorEMail)
 			}
+
+			if givenLogin.Outcome != tt.expectedLoginOutcome {
+				t.Errorf("Recorded login outcome is not as expected: \nExpected:%s\nGiven:%s", tt.expectedLoginOutcome, givenLogin.Outcome)
+			}
+
+			if givenLogin.Outcome != "" && (givenLogin.UserID != tt.dbReturnUser.ID || givenLogin.Factor != LoginFactorPassword ||
+				givenLogin.IP != "127.0.0.1" || givenLogin.UserAgent != "curl/7.68.0") {
+				t.Errorf("Recorded login is not as expected: %#v", givenLogin)
+			}
 		})
 	}
 
@@ -122,6 +224,7 @@ func TestProvider_CreatePasswordResetRequest(t *testing.T) {
 		dbCreateTokenReturnError  error
 		mailerError               error
 		dbExpectedToken           storage.Token
+		expectedDeletedTokens     []int64
 		expectedMailRecipient     string
 		passwordResetTokenPresent bool
 	}{
@@ -130,11 +233,12 @@ func TestProvider_CreatePasswordResetRequest(t *testing.T) {
 			givenEMail:            "test.test@test.test",
 			expectedMailRecipient: "test.test@test.test",
 			dbExpectedToken: storage.Token{
-				Type:  "reset",
-				EMail: "test.test@test.test",
-				ID:    0,
+				Type:   "reset",
+				UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				ID:     0,
 			},
-			expectedError: nil,
+			expectedDeletedTokens: []int64{3},
+			expectedError:         nil,
 		}, {
 			name:              "User not found",
 			givenEMail:        "not@existing.user",
@@ -149,11 +253,11 @@ func TestProvider_CreatePasswordResetRequest(t *testing.T) {
 			name:                     "Unexpected db error while create token",
 			givenEMail:               "test.test@test",
 			dbCreateTokenReturnError: errors.New("random error"),
-			expectedError:            errors.New("failed to create password-reset-token for email \"test.test@test\": random error"),
+			expectedError:            errors.New("failed to create reset-token for user \"c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b\": random error"),
 			dbExpectedToken: storage.Token{
-				Type:  "reset",
-				EMail: "test.test@test",
-				ID:    0,
+				Type:   "reset",
+				UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				ID:     0,
 			},
 		}, {
 			name:                  "Mailer error",
@@ -162,9 +266,9 @@ func TestProvider_CreatePasswordResetRequest(t *testing.T) {
 			expectedError:         errors.New("failed to send password-reset-email: random error"),
 			expectedMailRecipient: "test.test@test",
 			dbExpectedToken: storage.Token{
-				Type:  "reset",
-				EMail: "test.test@test",
-				ID:    0,
+				Type:   "reset",
+				UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				ID:     0,
 			},
 		},
 	}
@@ -175,11 +279,19 @@ func TestProvider_CreatePasswordResetRequest(t *testing.T) {
 			var storageCreateTokenToken storage.Token
 			var mailerRecipient string
 			var mailerPasswordResetToken string
+			var deletedTokens []int64
 			toTest := Provider{
 				Storage: &StorageMock{
 					UserFunc: func(email string) (storage.User, error) {
 						storageUserEMail = email
-						return storage.User{}, tt.dbUserReturnError
+						return storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", EMail: email}, tt.dbUserReturnError
+					},
+					TokensByUserIDAndTypeFunc: func(userID string, tokenType string) ([]storage.Token, error) {
+						return []storage.Token{{ID: 3, UserID: userID, Type: tokenType}}, nil
+					},
+					DeleteTokenFunc: func(id int64) error {
+						deletedTokens = append(deletedTokens, id)
+						return nil
 					},
 					CreateTokenFunc: func(t storage.Token) (int64, error) {
 						storageCreateTokenToken = t
@@ -187,12 +299,13 @@ func TestProvider_CreatePasswordResetRequest(t *testing.T) {
 					},
 				},
 				Mailer: &MailerMock{
-					SendPasswordResetRequestEMailFunc: func(recipient string, passwordResetToken string, claims map[string]interface{}) error {
+					SendPasswordResetRequestEMailFunc: func(recipient string, passwordResetToken string, claims map[string]interface{}, metadata map[string]interface{}) error {
 						mailerRecipient = recipient
 						mailerPasswordResetToken = passwordResetToken
 						return tt.mailerError
 					},
 				},
+				TokenHasher: testTokenHasher,
 			}
 
 			err := toTest.CreatePasswordResetRequest(tt.givenEMail)
@@ -204,6 +317,10 @@ func TestProvider_CreatePasswordResetRequest(t *testing.T) {
 				t.Errorf("The sorage requested usermail is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.givenEMail, storageUserEMail)
 			}
 
+			if tt.passwordResetTokenPresent && storageCreateTokenToken.Token != "hashed-"+mailerPasswordResetToken {
+				t.Errorf("The stored token is not the hash of the mailed token. Stored: %q, Mailed: %q", storageCreateTokenToken.Token, mailerPasswordResetToken)
+			}
+
 			storageCreateTokenToken.Token = ""
 			storageCreateTokenToken.CreatedAt = time.Time{}
 			if !reflect.DeepEqual(storageCreateTokenToken, tt.dbExpectedToken) {
@@ -214,6 +331,10 @@ func TestProvider_CreatePasswordResetRequest(t *testing.T) {
 				t.Errorf("The mailer recipient is not as expected: \nExpected:\n%#v\nGiven:\n%#v", tt.expectedMailRecipient, mailerRecipient)
 			}
 
+			if tt.expectedDeletedTokens != nil && !reflect.DeepEqual(deletedTokens, tt.expectedDeletedTokens) {
+				t.Errorf("Previous reset tokens have not been invalidated. Expected: %v, Given: %v", tt.expectedDeletedTokens, deletedTokens)
+			}
+
 			if tt.passwordResetTokenPresent {
 				matched, err := regexp.Match("^[0-9A-Fa-f]{64}$", []byte(mailerPasswordResetToken))
 				if err != nil {
@@ -247,17 +368,17 @@ func TestProvider_ResetPassword(t *testing.T) {
 			name:             "Happycase",
 			givenNewPassword: "newPassword",
 			givenResetToken:  "resetToken",
-			givenEMail:       "email",
+			givenEMail:       "test@test.test",
 			dbToken: []storage.Token{
-				{ID: 4, CreatedAt: time.Now(), Token: "myToken1", Type: "reset", EMail: "email"},
-				{ID: 5, CreatedAt: time.Now(), Token: "myToken2", Type: "other", EMail: "email"},
+				{ID: 4, CreatedAt: time.Now(), Token: "hashed-resetToken", Type: "reset", UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b"},
+				{ID: 5, CreatedAt: time.Now(), Token: "myToken2", Type: "reset", UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b"},
 			},
 		},
 		{
 			name:             "No token found",
 			givenNewPassword: "newPassword",
 			givenResetToken:  "resetToken",
-			givenEMail:       "email",
+			givenEMail:       "test@test.test",
 			expectedError:    ErrNoValidTokenFound,
 			dbToken:          []storage.Token{},
 		},
@@ -265,31 +386,49 @@ func TestProvider_ResetPassword(t *testing.T) {
 			name:             "Error while find tokens",
 			givenNewPassword: "newPassword",
 			givenResetToken:  "resetToken",
-			givenEMail:       "email",
-			expectedError:    errors.New("faild to find all avalilable tokens: unexpected error"),
+			givenEMail:       "test@test.test",
+			expectedError:    errors.New("failed to find all available tokens: unexpected error"),
 			dbToken:          []storage.Token{},
 			dbTokenError:     errors.New("unexpected error"),
 		},
+		{
+			name:             "Expired token",
+			givenNewPassword: "newPassword",
+			givenResetToken:  "resetToken",
+			givenEMail:       "test@test.test",
+			dbToken: []storage.Token{
+				{ID: 4, CreatedAt: time.Now().Add(-2 * time.Hour), Token: "hashed-resetToken", Type: "reset", UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b"},
+			},
+			expectedError: ErrTokenExpired,
+		},
 		{
 			name:             "Error while find user",
 			givenNewPassword: "newPassword",
 			givenResetToken:  "resetToken",
-			givenEMail:       "email",
+			givenEMail:       "test@test.test",
 			dbToken: []storage.Token{
-				{ID: 4, CreatedAt: time.Now(), Token: "myToken1", Type: "reset", EMail: "email"},
-				{ID: 5, CreatedAt: time.Now(), Token: "myToken2", Type: "other", EMail: "email"},
+				{ID: 4, CreatedAt: time.Now(), Token: "hashed-resetToken", Type: "reset", UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b"},
+				{ID: 5, CreatedAt: time.Now(), Token: "myToken2", Type: "reset", UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b"},
 			},
 			dbUserError:   errors.New("unexpected error"),
-			expectedError: errors.New("failed to find user: unexpected error"),
+			expectedError: errors.New("failed to query user with email \"test@test.test\": unexpected error"),
+		},
+		{
+			name:             "User not found",
+			givenNewPassword: "newPassword",
+			givenResetToken:  "resetToken",
+			givenEMail:       "not@existing.user",
+			dbUserError:      storage.ErrUserNotFound,
+			expectedError:    ErrNoValidTokenFound,
 		},
 		{
 			name:             "Error while update user",
 			givenNewPassword: "newPassword",
 			givenResetToken:  "resetToken",
-			givenEMail:       "email",
+			givenEMail:       "test@test.test",
 			dbToken: []storage.Token{
-				{ID: 4, CreatedAt: time.Now(), Token: "myToken1", Type: "reset", EMail: "email"},
-				{ID: 5, CreatedAt: time.Now(), Token: "myToken2", Type: "other", EMail: "email"},
+				{ID: 4, CreatedAt: time.Now(), Token: "hashed-resetToken", Type: "reset", UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b"},
+				{ID: 5, CreatedAt: time.Now(), Token: "myToken2", Type: "reset", UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b"},
 			},
 			dbUpdateUserError: errors.New("unexpected error"),
 			expectedError:     errors.New("failed to update user: unexpected error"),
@@ -298,39 +437,157 @@ func TestProvider_ResetPassword(t *testing.T) {
 			name:             "Error while delete token",
 			givenNewPassword: "newPassword",
 			givenResetToken:  "resetToken",
-			givenEMail:       "email",
+			givenEMail:       "test@test.test",
 			dbToken: []storage.Token{
-				{ID: 4, CreatedAt: time.Now(), Token: "myToken1", Type: "reset", EMail: "email"},
-				{ID: 5, CreatedAt: time.Now(), Token: "myToken2", Type: "other", EMail: "email"},
+				{ID: 4, CreatedAt: time.Now(), Token: "hashed-resetToken", Type: "reset", UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b"},
+				{ID: 5, CreatedAt: time.Now(), Token: "myToken2", Type: "reset", UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b"},
 			},
 			dbDeleteTokenError: errors.New("unexpected error"),
-			expectedError:      errors.New("failed to delete token: unexpected error"),
+			expectedError:      errors.New("failed to delete reset-token: unexpected error"),
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			var deletedTokens []int64
 			toTest := Provider{
+				PasswordResetTokenLifetime: time.Hour,
 				Storage: &StorageMock{
-					TokensByEMailAndTokenFunc: func(email string, token string) ([]storage.Token, error) {
-						return tt.dbToken, tt.dbTokenError
-					},
 					UserFunc: func(email string) (storage.User, error) {
 						return tt.dbUser, tt.dbUserError
 					},
 					UpdateUserFunc: func(user storage.User) error {
 						return tt.dbUpdateUserError
 					},
+					TokensByUserIDAndTypeFunc: func(userID string, tokenType string) ([]storage.Token, error) {
+						return tt.dbToken, tt.dbTokenError
+					},
 					DeleteTokenFunc: func(id int64) error {
+						deletedTokens = append(deletedTokens, id)
 						return tt.dbDeleteTokenError
 					},
 				},
+				TokenHasher: testTokenHasher,
 			}
 
 			err := toTest.ResetPassword(tt.givenEMail, tt.givenResetToken, tt.givenNewPassword)
 			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
 				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
 			}
+
+			if err == nil && !reflect.DeepEqual(deletedTokens, []int64{4, 5}) {
+				t.Errorf("Reset tokens have not been invalidated. Given: %v", deletedTokens)
+			}
+		})
+	}
+}
+
+func TestProvider_ChangePassword(t *testing.T) {
+	bcryptCost = bcrypt.MinCost
+
+	tests := []struct {
+		name                  string
+		givenPassword         string
+		givenNewPassword      string
+		dbUser                storage.User
+		dbUserError           error
+		dbUpdateUserError     error
+		breachCheckerBreached bool
+		expectedError         error
+		expectedUpdate        bool
+	}{
+		{
+			name:             "Happycase",
+			givenPassword:    "password",
+			givenNewPassword: "newPassword",
+			dbUser: storage.User{
+				EMail:    "test@test.test",
+				Password: []byte("$2a$12$1v7O.pNLqugJjcePyxvUj.GK37YoAbJvSW/9bULSRmq5C4SkoU2OO"),
+			},
+			expectedUpdate: true,
+		}, {
+			name:             "User not found",
+			givenPassword:    "password",
+			givenNewPassword: "newPassword",
+			dbUserError:      storage.ErrUserNotFound,
+			expectedError:    ErrUserNotFound,
+		}, {
+			name:             "Unexpected db error",
+			givenPassword:    "password",
+			givenNewPassword: "newPassword",
+			dbUserError:      errors.New("unexpected error"),
+			expectedError:    errors.New("failed to query user with email \"test@test.test\": unexpected error"),
+		}, {
+			name:             "Incorrect password",
+			givenPassword:    "wrongPassword",
+			givenNewPassword: "newPassword",
+			dbUser: storage.User{
+				EMail:    "test@test.test",
+				Password: []byte("$2a$12$1v7O.pNLqugJjcePyxvUj.GK37YoAbJvSW/9bULSRmq5C4SkoU2OO"),
+			},
+			expectedError: ErrIncorrectPassword,
+		}, {
+			name:             "Breached new password",
+			givenPassword:    "password",
+			givenNewPassword: "123456",
+			dbUser: storage.User{
+				EMail:    "test@test.test",
+				Password: []byte("$2a$12$1v7O.pNLqugJjcePyxvUj.GK37YoAbJvSW/9bULSRmq5C4SkoU2OO"),
+			},
+			breachCheckerBreached: true,
+			expectedError:         ErrPasswordBreached,
+		}, {
+			name:             "Error while update user",
+			givenPassword:    "password",
+			givenNewPassword: "newPassword",
+			dbUser: storage.User{
+				EMail:    "test@test.test",
+				Password: []byte("$2a$12$1v7O.pNLqugJjcePyxvUj.GK37YoAbJvSW/9bULSRmq5C4SkoU2OO"),
+			},
+			dbUpdateUserError: errors.New("unexpected error"),
+			expectedError:     errors.New("failed to update user: unexpected error"),
+			expectedUpdate:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updatedUser *storage.User
+			toTest := Provider{
+				Storage: &StorageMock{
+					UserFunc: func(email string) (storage.User, error) {
+						return tt.dbUser, tt.dbUserError
+					},
+					UpdateUserFunc: func(user storage.User) error {
+						updatedUser = &user
+						return tt.dbUpdateUserError
+					},
+					TokensByUserIDAndTypeFunc: func(userID string, tokenType string) ([]storage.Token, error) {
+						return nil, nil
+					},
+				},
+				PasswordBreachChecker: &PasswordBreachCheckerMock{
+					IsBreachedFunc: func(password string) (bool, error) {
+						return tt.breachCheckerBreached, nil
+					},
+				},
+			}
+
+			err := toTest.ChangePassword("test@test.test", tt.givenPassword, tt.givenNewPassword)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			if (updatedUser != nil) != tt.expectedUpdate {
+				t.Fatalf("User update is not as expected. Expected: %t, Given: %t", tt.expectedUpdate, updatedUser != nil)
+			}
+
+			if updatedUser != nil {
+				err := bcrypt.CompareHashAndPassword(updatedUser.Password, []byte(tt.givenNewPassword))
+				if err != nil {
+					t.Errorf("Updated password does not match new password: %s", err)
+				}
+			}
 		})
 	}
 }
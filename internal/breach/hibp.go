@@ -0,0 +1,79 @@
+package breach
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// HIBPDataset looks up passwords in a local Have-I-Been-Pwned style k-anonymity dataset. The dataset folder contains
+// one file per 5 char SHA-1 prefix (e.g. '21BD1.txt') with lines in the format '<35 char SHA-1 suffix>:<count>' like
+// they are provided by the HIBP range api and downloader.
+type HIBPDataset struct {
+	folderPath string
+	minCount   int
+}
+
+// NewHIBPDataset creates a HIBPDataset for the given folder. A password counts as breached when it has been seen at
+// least 'minCount' times.
+func NewHIBPDataset(folderPath string, minCount int) (*HIBPDataset, error) {
+	fi, err := os.Stat(folderPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open hibp dataset folder: %w", err)
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("hibp dataset path %q is not a folder", folderPath)
+	}
+
+	if minCount < 1 {
+		minCount = 1
+	}
+
+	return &HIBPDataset{
+		folderPath: folderPath,
+		minCount:   minCount,
+	}, nil
+}
+
+// IsBreached returns true when the SHA-1 hash of the given password is listed in the dataset with a count of at least
+// the configured minimum count.
+func (d *HIBPDataset) IsBreached(password string) (bool, error) {
+	hash := fmt.Sprintf("%X", sha1.Sum([]byte(password)))
+	prefix, suffix := hash[:5], hash[5:]
+
+	f, err := os.Open(filepath.Join(d.folderPath, fmt.Sprintf("%s.txt", prefix)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to open hibp range file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 || !strings.EqualFold(parts[0], suffix) {
+			continue
+		}
+
+		count, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return false, fmt.Errorf("invalid count in hibp range file line %q: %w", line, err)
+		}
+
+		return count >= d.minCount, nil
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return false, fmt.Errorf("failed to read hibp range file: %w", err)
+	}
+
+	return false, nil
+}
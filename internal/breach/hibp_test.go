@@ -0,0 +1,101 @@
+package breach
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHIBPDataset_IsBreached(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hibp")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	// sha1("password") = 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	writeFile(t, filepath.Join(dir, "5BAA6.txt"), "003D68EB55068C33ACE09247EE4C639306B:3\r\n1E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\r\n")
+	// sha1("P@ssw0rd") = 21BD12DC183F740EE76F27B78EB39C8AD972A757
+	writeFile(t, filepath.Join(dir, "21BD1.txt"), "2DC183F740EE76F27B78EB39C8AD972A757:2\n")
+	// sha1("broken") = 0B8A1CAEC23D75D1154B8D9BEF9CEC6C03697638
+	writeFile(t, filepath.Join(dir, "0B8A1.txt"), "CAEC23D75D1154B8D9BEF9CEC6C03697638:many\n")
+
+	tests := []struct {
+		name             string
+		givenMinCount    int
+		givenPassword    string
+		expectedBreached bool
+		expectedError    error
+	}{
+		{
+			name:             "Breached password",
+			givenMinCount:    1,
+			givenPassword:    "password",
+			expectedBreached: true,
+		}, {
+			name:             "Breached password below min count",
+			givenMinCount:    3,
+			givenPassword:    "P@ssw0rd",
+			expectedBreached: false,
+		}, {
+			name:             "Breached password reaching min count",
+			givenMinCount:    2,
+			givenPassword:    "P@ssw0rd",
+			expectedBreached: true,
+		}, {
+			name:             "Unknown prefix",
+			givenMinCount:    1,
+			givenPassword:    "s3cr3t",
+			expectedBreached: false,
+		}, {
+			name:          "Invalid count",
+			givenMinCount: 1,
+			givenPassword: "broken",
+			expectedError: errors.New(`invalid count in hibp range file line "CAEC23D75D1154B8D9BEF9CEC6C03697638:many": strconv.Atoi: parsing "many": invalid syntax`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := NewHIBPDataset(dir, tt.givenMinCount)
+			if err != nil {
+				t.Fatalf("failed to create hibp dataset: %s", err)
+			}
+
+			breached, err := d.IsBreached(tt.givenPassword)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			if breached != tt.expectedBreached {
+				t.Errorf("Breached is not as expected. Expected: %t, Given: %t", tt.expectedBreached, breached)
+			}
+		})
+	}
+}
+
+func TestNewHIBPDatasetWithFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "hibp")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %s", err)
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+	_ = f.Close()
+
+	_, err = NewHIBPDataset(f.Name(), 1)
+	expectedError := fmt.Errorf("hibp dataset path %q is not a folder", f.Name())
+	if fmt.Sprint(err) != fmt.Sprint(expectedError) {
+		t.Errorf("Unexpected error. Expected: %q, Given: %q", expectedError, err)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	err := ioutil.WriteFile(path, []byte(content), 0600)
+	if err != nil {
+		t.Fatalf("failed to write file %q: %s", path, err)
+	}
+}
@@ -0,0 +1,46 @@
+package breach
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// List is a plain banned-password list which will be held in memory. Passwords are compared case-insensitive.
+type List struct {
+	passwords map[string]struct{}
+}
+
+// NewList loads the banned-password list from the given file. The file contains one password per line, empty lines
+// and lines starting with '#' will be ignored.
+func NewList(filePath string) (*List, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open banned-password list: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	passwords := map[string]struct{}{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		passwords[strings.ToLower(line)] = struct{}{}
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read banned-password list: %w", err)
+	}
+
+	return &List{passwords: passwords}, nil
+}
+
+// IsBreached returns true when the given password is part of the list
+func (l *List) IsBreached(password string) (bool, error) {
+	_, found := l.passwords[strings.ToLower(password)]
+	return found, nil
+}
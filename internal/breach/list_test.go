@@ -0,0 +1,56 @@
+package breach
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestList_IsBreached(t *testing.T) {
+	dir, err := ioutil.TempDir("", "banned")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	path := filepath.Join(dir, "banned-passwords.txt")
+	writeFile(t, path, "# most common passwords\npassword\n\n  Qwertz123  \n")
+
+	l, err := NewList(path)
+	if err != nil {
+		t.Fatalf("failed to load list: %s", err)
+	}
+
+	tests := []struct {
+		givenPassword    string
+		expectedBreached bool
+	}{
+		{givenPassword: "password", expectedBreached: true},
+		{givenPassword: "PASSWORD", expectedBreached: true},
+		{givenPassword: "qwertz123", expectedBreached: true},
+		{givenPassword: "# most common passwords", expectedBreached: false},
+		{givenPassword: "", expectedBreached: false},
+		{givenPassword: "s3cr3t", expectedBreached: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.givenPassword, func(t *testing.T) {
+			breached, err := l.IsBreached(tt.givenPassword)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if breached != tt.expectedBreached {
+				t.Errorf("Breached is not as expected. Expected: %t, Given: %t", tt.expectedBreached, breached)
+			}
+		})
+	}
+}
+
+func TestNewListWithMissingFile(t *testing.T) {
+	_, err := NewList("/not/existing/banned-passwords.txt")
+	if err == nil {
+		t.Error("expected error but was nil")
+	}
+}
@@ -0,0 +1,57 @@
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+var ErrCiphertextTooShort = errors.New("ciphertext too short")
+
+// AESGCM encrypts and decrypts secrets with AES-GCM. The random nonce will be prepended to the ciphertext.
+type AESGCM struct {
+	aead cipher.AEAD
+}
+
+// NewAESGCM creates an AESGCM with the given key. The key must be 16, 24 or 32 bytes long (AES-128, AES-192, AES-256).
+func NewAESGCM(key []byte) (*AESGCM, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create aes cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gcm: %w", err)
+	}
+
+	return &AESGCM{aead: aead}, nil
+}
+
+// Encrypt encrypts the given plaintext
+func (a AESGCM) Encrypt(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, a.aead.NonceSize())
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return a.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt decrypts the given ciphertext which has been encrypted by Encrypt
+func (a AESGCM) Decrypt(ciphertext []byte) ([]byte, error) {
+	nonceSize := a.aead.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return nil, ErrCiphertextTooShort
+	}
+
+	plaintext, err := a.aead.Open(nil, ciphertext[:nonceSize], ciphertext[nonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,83 @@
+package crypt
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestAESGCM_EncryptDecrypt(t *testing.T) {
+	a, err := NewAESGCM(testKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	plaintext := []byte("my secret")
+	ciphertext, err := a.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %s", err)
+	}
+
+	if bytes.Contains(ciphertext, plaintext) {
+		t.Errorf("ciphertext contains plaintext: %q", ciphertext)
+	}
+
+	decrypted, err := a.Decrypt(ciphertext)
+	if err != nil {
+		t.Fatalf("failed to decrypt: %s", err)
+	}
+
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("Decrypted plaintext is not as expected. Expected: %q, Given: %q", plaintext, decrypted)
+	}
+}
+
+func TestAESGCM_Decrypt(t *testing.T) {
+	a, err := NewAESGCM(testKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	otherKey, err := NewAESGCM([]byte("fedcba9876543210fedcba9876543210"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	encryptedWithOtherKey, err := otherKey.Encrypt([]byte("my secret"))
+	if err != nil {
+		t.Fatalf("failed to encrypt: %s", err)
+	}
+
+	tests := []struct {
+		name             string
+		givenCiphertext  []byte
+		expectedErrorMsg string
+	}{
+		{
+			name:             "Ciphertext too short",
+			givenCiphertext:  []byte("short"),
+			expectedErrorMsg: "ciphertext too short",
+		}, {
+			name:             "Encrypted with other key",
+			givenCiphertext:  encryptedWithOtherKey,
+			expectedErrorMsg: "failed to decrypt: cipher: message authentication failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := a.Decrypt(tt.givenCiphertext)
+			if fmt.Sprint(err) != tt.expectedErrorMsg {
+				t.Errorf("Error is not as expected. Expected: %q, Given: %q", tt.expectedErrorMsg, err)
+			}
+		})
+	}
+}
+
+func TestNewAESGCMWithInvalidKey(t *testing.T) {
+	_, err := NewAESGCM([]byte("too short"))
+	if err == nil {
+		t.Error("expected error but was nil")
+	}
+}
@@ -0,0 +1,35 @@
+package crypt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+)
+
+// MinHMACKeySize is the minimal size of HMAC keys in bytes
+const MinHMACKeySize = 32
+
+var ErrHMACKeyTooShort = errors.New("hmac key must be at least 32 bytes long")
+
+// HMACSHA256 hashes tokens with HMAC-SHA256 and a secret key. Equal tokens result in equal hashes, so hashed tokens can
+// be looked up without knowing the plaintext.
+type HMACSHA256 struct {
+	key []byte
+}
+
+// NewHMACSHA256 creates an HMACSHA256 with the given key. The key must be at least MinHMACKeySize bytes long.
+func NewHMACSHA256(key []byte) (*HMACSHA256, error) {
+	if len(key) < MinHMACKeySize {
+		return nil, ErrHMACKeyTooShort
+	}
+
+	return &HMACSHA256{key: key}, nil
+}
+
+// Hash returns the hex encoded HMAC-SHA256 of the given token
+func (h HMACSHA256) Hash(token string) string {
+	mac := hmac.New(sha256.New, h.key)
+	_, _ = mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,63 @@
+package crypt
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNewHMACSHA256(t *testing.T) {
+	tests := []struct {
+		name          string
+		givenKey      []byte
+		expectedError error
+	}{
+		{
+			name:     "Happycase",
+			givenKey: testKey,
+		}, {
+			name:          "Key too short",
+			givenKey:      []byte("0123456789abcdef"),
+			expectedError: ErrHMACKeyTooShort,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewHMACSHA256(tt.givenKey)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Errorf("Unexpected error. Expected: %q, Given: %q", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestHMACSHA256_Hash(t *testing.T) {
+	h, err := NewHMACSHA256(testKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	otherKey, err := NewHMACSHA256([]byte("fedcba9876543210fedcba9876543210"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	hash := h.Hash("my token")
+	// echo -n "my token" | openssl dgst -sha256 -hmac "0123456789abcdef0123456789abcdef"
+	expectedHash := "a124b94221941729db1c94357c06ddddb2390d35d04f15238562017399553729"
+	if hash != expectedHash {
+		t.Errorf("Hash is not as expected. Expected: %q, Given: %q", expectedHash, hash)
+	}
+
+	if h.Hash("my token") != hash {
+		t.Error("Hash of equal tokens differs")
+	}
+
+	if h.Hash("other token") == hash {
+		t.Error("Hash of different tokens is equal")
+	}
+
+	if otherKey.Hash("my token") == hash {
+		t.Error("Hash with different keys is equal")
+	}
+}
@@ -0,0 +1,20 @@
+package internal
+
+import (
+	"errors"
+	"github.com/leberKleber/simple-jwt-provider/internal/mailaddr"
+)
+
+// ErrInvalidEMail will be returned by all Provider functions which have been called with a malformed email
+var ErrInvalidEMail = errors.New("invalid email")
+
+// normalizeEMail returns the normalized form of the given email which identifies users in storage.
+// return ErrInvalidEMail when the given email is malformed
+func (p Provider) normalizeEMail(email string) (string, error) {
+	normalized, err := mailaddr.Normalize(email, p.LowercaseEMailLocalPart)
+	if err != nil {
+		return "", ErrInvalidEMail
+	}
+
+	return normalized, nil
+}
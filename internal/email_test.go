@@ -0,0 +1,46 @@
+package internal
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestProvider_normalizeEMail(t *testing.T) {
+	tests := []struct {
+		name                    string
+		givenEMail              string
+		lowercaseEMailLocalPart bool
+		expectedEMail           string
+		expectedError           error
+	}{
+		{
+			name:          "Lowercase domain",
+			givenEMail:    " Alice@Example.COM",
+			expectedEMail: "Alice@example.com",
+		}, {
+			name:                    "Lowercase whole email",
+			givenEMail:              "Alice@Example.COM",
+			lowercaseEMailLocalPart: true,
+			expectedEMail:           "alice@example.com",
+		}, {
+			name:          "Invalid email",
+			givenEMail:    "alice",
+			expectedError: ErrInvalidEMail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toTest := Provider{LowercaseEMailLocalPart: tt.lowercaseEMailLocalPart}
+
+			email, err := toTest.normalizeEMail(tt.givenEMail)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			if email != tt.expectedEMail {
+				t.Errorf("Email is not as expected. Expected: %q, Given: %q", tt.expectedEMail, email)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+var ErrPasswordExpired = errors.New("password expired")
+
+// passwordExpiresAt returns the time when the password of the given user expires. The returned bool is false when the
+// password never expires.
+func (p Provider) passwordExpiresAt(u storage.User) (time.Time, bool) {
+	maxAgeDays := p.PasswordMaxAgeDays
+	if u.PasswordMaxAgeDays > 0 {
+		maxAgeDays = u.PasswordMaxAgeDays
+	}
+
+	if maxAgeDays <= 0 {
+		return time.Time{}, false
+	}
+
+	return u.PasswordChangedAt.AddDate(0, 0, maxAgeDays), true
+}
+
+// isPasswordExpired returns true when the password of the given user is expired
+func (p Provider) isPasswordExpired(u storage.User) bool {
+	expiresAt, expires := p.passwordExpiresAt(u)
+	return expires && !nowFunc().Before(expiresAt)
+}
+
+// RemindPasswordExpiry sends a password-expiry-reminder mail to all users whose password expires within the next
+// PasswordExpiryReminderDays days and who have not been reminded since their last password change. Failures for
+// single users will be logged and do not stop reminding the remaining users. Returns the count of reminded users.
+func (p Provider) RemindPasswordExpiry() (int64, error) {
+	if p.PasswordExpiryReminderDays <= 0 {
+		return 0, nil
+	}
+
+	users, err := p.Storage.UsersToRemindOfPasswordExpiry(p.PasswordMaxAgeDays, p.PasswordExpiryReminderDays, nowFunc())
+	if err != nil {
+		return 0, fmt.Errorf("failed to find users to remind of password expiry: %w", err)
+	}
+
+	var reminded int64
+	failed := 0
+	for _, u := range users {
+		expiresAt, expires := p.passwordExpiresAt(u)
+		if !expires {
+			continue
+		}
+
+		err := p.Mailer.SendPasswordExpiryReminderEMail(u.EMail, expiresAt, u.Claims, u.Metadata)
+		if err != nil {
+			logrus.WithError(err).WithField("email", u.EMail).Error("Failed to send password-expiry-reminder-email")
+			failed++
+			continue
+		}
+
+		err = p.Storage.MarkPasswordExpiryReminded(u.ID, nowFunc())
+		if err != nil {
+			logrus.WithError(err).WithField("email", u.EMail).Error("Failed to mark user as reminded of password expiry")
+			failed++
+			continue
+		}
+
+		reminded++
+	}
+
+	if failed > 0 {
+		return reminded, fmt.Errorf("failed to remind %d of %d users of password expiry", failed, len(users))
+	}
+
+	return reminded, nil
+}
@@ -0,0 +1,178 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"testing"
+	"time"
+)
+
+func TestProvider_isPasswordExpired(t *testing.T) {
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	now := time.Date(2020, 2, 20, 0, 0, 0, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+
+	tests := []struct {
+		name                  string
+		globalMaxAgeDays      int
+		userMaxAgeDays        int
+		userPasswordChangedAt time.Time
+		expectedExpired       bool
+	}{
+		{
+			name:                  "Expiry disabled",
+			userPasswordChangedAt: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+		}, {
+			name:                  "Global max age not reached",
+			globalMaxAgeDays:      30,
+			userPasswordChangedAt: time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC),
+		}, {
+			name:                  "Global max age reached",
+			globalMaxAgeDays:      10,
+			userPasswordChangedAt: time.Date(2020, 2, 10, 0, 0, 0, 0, time.UTC),
+			expectedExpired:       true,
+		}, {
+			name:                  "User max age overwrites global max age",
+			globalMaxAgeDays:      30,
+			userMaxAgeDays:        5,
+			userPasswordChangedAt: time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC),
+			expectedExpired:       true,
+		}, {
+			name:                  "User max age without global max age",
+			userMaxAgeDays:        30,
+			userPasswordChangedAt: time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toTest := Provider{PasswordMaxAgeDays: tt.globalMaxAgeDays}
+
+			expired := toTest.isPasswordExpired(storage.User{
+				PasswordChangedAt:  tt.userPasswordChangedAt,
+				PasswordMaxAgeDays: tt.userMaxAgeDays,
+			})
+			if expired != tt.expectedExpired {
+				t.Errorf("Expired is not as expected. Expected: %t, Given: %t", tt.expectedExpired, expired)
+			}
+		})
+	}
+}
+
+func TestProvider_RemindPasswordExpiry(t *testing.T) {
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	now := time.Date(2020, 2, 20, 0, 0, 0, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+
+	users := []storage.User{
+		{
+			EMail:             "first@test.test",
+			Claims:            map[string]interface{}{"a": "b"},
+			PasswordChangedAt: time.Date(2020, 1, 25, 0, 0, 0, 0, time.UTC),
+		}, {
+			EMail:              "second@test.test",
+			PasswordChangedAt:  time.Date(2020, 2, 15, 0, 0, 0, 0, time.UTC),
+			PasswordMaxAgeDays: 7,
+		},
+	}
+
+	tests := []struct {
+		name                string
+		reminderDays        int
+		dbUsersError        error
+		mailerError         error
+		dbMarkError         error
+		expectedMails       map[string]time.Time
+		expectedMarkedUsers int
+		expectedReminded    int64
+		expectedError       error
+	}{
+		{
+			name: "Reminder disabled",
+		}, {
+			name:         "Happycase",
+			reminderDays: 7,
+			expectedMails: map[string]time.Time{
+				"first@test.test":  time.Date(2020, 2, 24, 0, 0, 0, 0, time.UTC),
+				"second@test.test": time.Date(2020, 2, 22, 0, 0, 0, 0, time.UTC),
+			},
+			expectedMarkedUsers: 2,
+			expectedReminded:    2,
+		}, {
+			name:          "Unable to find users",
+			reminderDays:  7,
+			dbUsersError:  errors.New("nope"),
+			expectedError: errors.New("failed to find users to remind of password expiry: nope"),
+		}, {
+			name:         "Unable to send mails",
+			reminderDays: 7,
+			mailerError:  errors.New("nope"),
+			expectedMails: map[string]time.Time{
+				"first@test.test":  time.Date(2020, 2, 24, 0, 0, 0, 0, time.UTC),
+				"second@test.test": time.Date(2020, 2, 22, 0, 0, 0, 0, time.UTC),
+			},
+			expectedError: errors.New("failed to remind 2 of 2 users of password expiry"),
+		}, {
+			name:         "Unable to mark users as reminded",
+			reminderDays: 7,
+			dbMarkError:  errors.New("nope"),
+			expectedMails: map[string]time.Time{
+				"first@test.test":  time.Date(2020, 2, 24, 0, 0, 0, 0, time.UTC),
+				"second@test.test": time.Date(2020, 2, 22, 0, 0, 0, 0, time.UTC),
+			},
+			expectedMarkedUsers: 2,
+			expectedError:       errors.New("failed to remind 2 of 2 users of password expiry"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storageMock := &StorageMock{
+				UsersToRemindOfPasswordExpiryFunc: func(defaultMaxAgeDays int, reminderDays int, givenNow time.Time) ([]storage.User, error) {
+					if defaultMaxAgeDays != 30 || reminderDays != tt.reminderDays || givenNow != now {
+						t.Errorf("Unexpected query params: %d, %d, %s", defaultMaxAgeDays, reminderDays, givenNow)
+					}
+					return users, tt.dbUsersError
+				},
+				MarkPasswordExpiryRemindedFunc: func(userID string, remindedAt time.Time) error {
+					return tt.dbMarkError
+				},
+			}
+			givenMails := map[string]time.Time{}
+			mailerMock := &MailerMock{
+				SendPasswordExpiryReminderEMailFunc: func(recipient string, passwordExpiresAt time.Time, claims map[string]interface{}, metadata map[string]interface{}) error {
+					givenMails[recipient] = passwordExpiresAt
+					return tt.mailerError
+				},
+			}
+
+			toTest := Provider{
+				Storage:                    storageMock,
+				Mailer:                     mailerMock,
+				PasswordMaxAgeDays:         30,
+				PasswordExpiryReminderDays: tt.reminderDays,
+			}
+
+			reminded, err := toTest.RemindPasswordExpiry()
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			if reminded != tt.expectedReminded {
+				t.Errorf("Count of reminded users is not as expected. Expected: %d, Given: %d", tt.expectedReminded, reminded)
+			}
+
+			if fmt.Sprint(givenMails) != fmt.Sprint(tt.expectedMails) && len(givenMails)+len(tt.expectedMails) > 0 {
+				t.Errorf("Sent mails are not as expected. Expected:\n%v\nGiven:\n%v", tt.expectedMails, givenMails)
+			}
+
+			markedUsers := len(storageMock.MarkPasswordExpiryRemindedCalls())
+			if markedUsers != tt.expectedMarkedUsers {
+				t.Errorf("Marked users are not as expected. Expected: %d, Given: %d", tt.expectedMarkedUsers, markedUsers)
+			}
+		})
+	}
+}
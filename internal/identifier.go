@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"regexp"
+	"strings"
+)
+
+var ErrInvalidUsername = errors.New("invalid username")
+var ErrInvalidPhone = errors.New("invalid phone number")
+var ErrNoLoginIdentifier = errors.New("user has neither email nor username nor phone")
+
+// usernamePattern allows 3 to 64 lower case letters, digits, '.', '_' and '-' beginning with a letter or digit
+var usernamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{2,63}$`)
+
+// phonePattern is the E.164 format: '+' followed by the country code and the subscriber number, at most 15 digits
+var phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
+// phoneSeparators are the characters phone numbers are commonly formatted with
+var phoneSeparators = strings.NewReplacer(" ", "", "-", "", ".", "", "/", "", "(", "", ")", "")
+
+// normalizeUsername returns the trimmed and lowercased form of the given username which identifies users in storage.
+// Usernames which are valid uuids are not allowed because user ids and usernames are accepted by the admin api.
+// return ErrInvalidUsername when the given username is malformed
+func normalizeUsername(username string) (string, error) {
+	username = strings.ToLower(strings.TrimSpace(username))
+	if !usernamePattern.MatchString(username) {
+		return "", ErrInvalidUsername
+	}
+
+	_, err := uuid.Parse(username)
+	if err == nil {
+		return "", ErrInvalidUsername
+	}
+
+	return username, nil
+}
+
+// normalizePhone returns the given phone number in E.164 format without formatting characters (spaces, dashes, dots,
+// slashes and parentheses) which identifies users in storage.
+// return ErrInvalidPhone when the given phone number is not in E.164 format
+func normalizePhone(phone string) (string, error) {
+	phone = phoneSeparators.Replace(strings.TrimSpace(phone))
+	if !phonePattern.MatchString(phone) {
+		return "", ErrInvalidPhone
+	}
+
+	return phone, nil
+}
+
+// userByLoginIdentifier finds the user identified by the given login identifier. Identifiers containing an '@' are
+// emails, identifiers starting with a '+' are phone numbers and all other identifiers are usernames.
+// return ErrInvalidEMail when the identifier is a malformed email
+// return ErrUserNotFound when user not found. Malformed usernames and phone numbers never identify a user
+func (p Provider) userByLoginIdentifier(identifier string) (storage.User, error) {
+	identifier = strings.TrimSpace(identifier)
+
+	if strings.Contains(identifier, "@") {
+		email, err := p.normalizeEMail(identifier)
+		if err != nil {
+			return storage.User{}, err
+		}
+
+		return queryUser(p.Storage.User, "email", email)
+	}
+
+	if strings.HasPrefix(identifier, "+") {
+		phone, err := normalizePhone(identifier)
+		if err != nil {
+			return storage.User{}, ErrUserNotFound
+		}
+
+		return queryUser(p.Storage.UserByPhone, "phone", phone)
+	}
+
+	username, err := normalizeUsername(identifier)
+	if err != nil {
+		return storage.User{}, ErrUserNotFound
+	}
+
+	return queryUser(p.Storage.UserByUsername, "username", username)
+}
+
+// challengedUser finds the user identified by the given login identifier like userByLoginIdentifier for the redemption
+// of a previously issued token (mfa token, webauthn challenge). Unknown users can not have such a token.
+// return ErrNoValidTokenFound when user not found
+func (p Provider) challengedUser(identifier string) (storage.User, error) {
+	u, err := p.userByLoginIdentifier(identifier)
+	if errors.Is(err, ErrUserNotFound) {
+		return storage.User{}, ErrNoValidTokenFound
+	}
+
+	return u, err
+}
+
+// queryUser finds the user with the given storage function by the given normalized identifier
+// return ErrUserNotFound when user not found
+func queryUser(find func(identifier string) (storage.User, error), identifierName, identifier string) (storage.User, error) {
+	u, err := find(identifier)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return storage.User{}, ErrUserNotFound
+		}
+		return storage.User{}, fmt.Errorf("failed to query user with %s %q: %w", identifierName, identifier, err)
+	}
+
+	return u, nil
+}
+
+// loginName is the name of the given user shown by authenticator apps and security keys. It is the email or, when the
+// user has none, the username or the phone number.
+func loginName(u storage.User) string {
+	switch {
+	case u.EMail != "":
+		return u.EMail
+	case u.Username != "":
+		return u.Username
+	default:
+		return u.Phone
+	}
+}
@@ -0,0 +1,179 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"testing"
+)
+
+func TestNormalizeUsername(t *testing.T) {
+	tests := []struct {
+		name             string
+		givenUsername    string
+		expectedUsername string
+		expectedError    error
+	}{
+		{
+			name:             "Already normalized",
+			givenUsername:    "alice",
+			expectedUsername: "alice",
+		}, {
+			name:             "Trim and lowercase",
+			givenUsername:    " Alice.Smith_1-2 ",
+			expectedUsername: "alice.smith_1-2",
+		}, {
+			name:          "Too short",
+			givenUsername: "al",
+			expectedError: ErrInvalidUsername,
+		}, {
+			name:          "Leading special character",
+			givenUsername: "_alice",
+			expectedError: ErrInvalidUsername,
+		}, {
+			name:          "Invalid character",
+			givenUsername: "alice@smith",
+			expectedError: ErrInvalidUsername,
+		}, {
+			name:          "UUID",
+			givenUsername: "C6A1E4A8-1A5E-4B8E-9D6B-5F1F6D0E2A7B",
+			expectedError: ErrInvalidUsername,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			username, err := normalizeUsername(tt.givenUsername)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			if username != tt.expectedUsername {
+				t.Errorf("Username is not as expected. Expected: %q, Given: %q", tt.expectedUsername, username)
+			}
+		})
+	}
+}
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		name          string
+		givenPhone    string
+		expectedPhone string
+		expectedError error
+	}{
+		{
+			name:          "Already normalized",
+			givenPhone:    "+491701234567",
+			expectedPhone: "+491701234567",
+		}, {
+			name:          "Formatted",
+			givenPhone:    " +49 (170) 123-45.6/7 ",
+			expectedPhone: "+491701234567",
+		}, {
+			name:          "Missing country code",
+			givenPhone:    "01701234567",
+			expectedError: ErrInvalidPhone,
+		}, {
+			name:          "Too long",
+			givenPhone:    "+4917012345678901",
+			expectedError: ErrInvalidPhone,
+		}, {
+			name:          "Letters",
+			givenPhone:    "+49170abc",
+			expectedError: ErrInvalidPhone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			phone, err := normalizePhone(tt.givenPhone)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			if phone != tt.expectedPhone {
+				t.Errorf("Phone is not as expected. Expected: %q, Given: %q", tt.expectedPhone, phone)
+			}
+		})
+	}
+}
+
+func TestProvider_userByLoginIdentifier(t *testing.T) {
+	tests := []struct {
+		name            string
+		givenIdentifier string
+		dbError         error
+		expectedLookup  string
+		expectedError   error
+	}{
+		{
+			name:            "EMail",
+			givenIdentifier: " Test@Test.TEST ",
+			expectedLookup:  "email:Test@test.test",
+		}, {
+			name:            "Username",
+			givenIdentifier: "Alice",
+			expectedLookup:  "username:alice",
+		}, {
+			name:            "Phone",
+			givenIdentifier: "+49 170 1234567",
+			expectedLookup:  "phone:+491701234567",
+		}, {
+			name:            "Invalid email",
+			givenIdentifier: "test@",
+			expectedError:   ErrInvalidEMail,
+		}, {
+			name:            "Invalid username",
+			givenIdentifier: "a",
+			expectedError:   ErrUserNotFound,
+		}, {
+			name:            "Invalid phone",
+			givenIdentifier: "+0",
+			expectedError:   ErrUserNotFound,
+		}, {
+			name:            "User not found",
+			givenIdentifier: "alice",
+			dbError:         storage.ErrUserNotFound,
+			expectedLookup:  "username:alice",
+			expectedError:   ErrUserNotFound,
+		}, {
+			name:            "Unexpected db error",
+			givenIdentifier: "alice",
+			dbError:         errors.New("nope"),
+			expectedLookup:  "username:alice",
+			expectedError:   errors.New("failed to query user with username \"alice\": nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lookup string
+			toTest := Provider{
+				Storage: &StorageMock{
+					UserFunc: func(email string) (storage.User, error) {
+						lookup = "email:" + email
+						return storage.User{}, tt.dbError
+					},
+					UserByUsernameFunc: func(username string) (storage.User, error) {
+						lookup = "username:" + username
+						return storage.User{}, tt.dbError
+					},
+					UserByPhoneFunc: func(phone string) (storage.User, error) {
+						lookup = "phone:" + phone
+						return storage.User{}, tt.dbError
+					},
+				},
+			}
+
+			_, err := toTest.userByLoginIdentifier(tt.givenIdentifier)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			if lookup != tt.expectedLookup {
+				t.Errorf("Storage lookup is not as expected. Expected: %q, Given: %q", tt.expectedLookup, lookup)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+var ErrInvitationNotConfigured = errors.New("invitations are not configured")
+var ErrInvitationRequiresEMail = errors.New("invited user must have an email")
+var ErrInvitationAccepted = errors.New("invitation has already been accepted")
+
+// sendInvitation replaces all open invitation tokens of the given user with a new one, persists the invitation time and
+// sends the token with an invite mail to the users email. The token is valid for InvitationLifetime and has to be
+// redeemed via AcceptInvitation.
+func (p Provider) sendInvitation(u storage.User) error {
+	err := p.revokeTokens(u.ID, storage.TokenTypeInvitation)
+	if err != nil {
+		return err
+	}
+
+	t, err := p.issueToken(u.ID, storage.TokenTypeInvitation, nil)
+	if err != nil {
+		return err
+	}
+
+	err = p.Storage.MarkUserInvited(u.ID, nowFunc())
+	if err != nil {
+		return fmt.Errorf("failed to mark user as invited: %w", err)
+	}
+
+	err = p.Mailer.SendInvitationEMail(u.EMail, t, nowFunc().Add(p.InvitationLifetime), u.Claims, u.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to send invitation-email: %w", err)
+	}
+
+	return nil
+}
+
+// ResendInvitation sends a new invite mail to the user with the given id or login identifier. All previously sent
+// invitation tokens will be invalidated.
+// return ErrInvitationNotConfigured when InvitationLifetime is 0
+// return ErrUserNotFound when user does not exist
+// return ErrInvitationAccepted when the user has already set a password
+// return ErrInvitationRequiresEMail when the user has no email
+func (p Provider) ResendInvitation(idOrIdentifier string) error {
+	if p.InvitationLifetime <= 0 {
+		return ErrInvitationNotConfigured
+	}
+
+	u, err := p.findUser(idOrIdentifier)
+	if err != nil {
+		return err
+	}
+
+	if len(u.Password) != 0 {
+		return ErrInvitationAccepted
+	}
+
+	if u.EMail == "" {
+		return ErrInvitationRequiresEMail
+	}
+
+	return p.sendInvitation(u)
+}
+
+// AcceptInvitation sets the initial password of an invited user if the invitation token is correct. The token can be
+// used once, it will be redeemed after the password has been checked.
+// return ErrInvitationNotConfigured when InvitationLifetime is 0
+// return ErrNoValidTokenFound when the token is unknown or expired
+// return ErrPasswordBreached when the password has been found in a data breach
+func (p Provider) AcceptInvitation(email, invitationToken, password string) error {
+	email, err := p.normalizeEMail(email)
+	if err != nil {
+		return err
+	}
+
+	if p.InvitationLifetime <= 0 {
+		return ErrInvitationNotConfigured
+	}
+
+	u, err := queryUser(p.Storage.User, "email", email)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return ErrNoValidTokenFound
+		}
+		return err
+	}
+
+	if len(u.Password) != 0 {
+		// the invitation has been accepted already or the password has been set otherwise
+		return ErrNoValidTokenFound
+	}
+
+	_, err = p.findToken(u.ID, invitationToken, storage.TokenTypeInvitation)
+	if err != nil {
+		return err
+	}
+
+	err = p.checkNewPassword(u, password)
+	if err != nil {
+		return err
+	}
+
+	u.Password, err = bcryptPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to bcrypt password: %w", err)
+	}
+	u.PasswordChangedAt = nowFunc()
+
+	// redeeming the token before the update ensures that concurrent requests can not accept the invitation twice
+	_, err = p.redeemToken(u.ID, invitationToken, storage.TokenTypeInvitation)
+	if err != nil {
+		return err
+	}
+
+	err = p.Storage.UpdateUser(u)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	return p.passwordChanged(u.ID, u.Password)
+}
+
+// PurgeUnverifiedUsers deletes all users who never accepted their invitation and have been invited the last time more
+// than UnverifiedUserRetention ago and returns the count of deleted users. Nothing will be deleted when
+// UnverifiedUserRetention is 0.
+func (p Provider) PurgeUnverifiedUsers() (int64, error) {
+	if p.UnverifiedUserRetention <= 0 {
+		return 0, nil
+	}
+
+	count, err := p.Storage.DeleteUnverifiedUsers(nowFunc().Add(-p.UnverifiedUserRetention))
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge unverified users: %w", err)
+	}
+
+	if count > 0 {
+		logrus.WithField("count", count).Info("Unverified users purged")
+	}
+	return count, nil
+}
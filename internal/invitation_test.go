@@ -0,0 +1,418 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"golang.org/x/crypto/bcrypt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProvider_CreateUser_Invitation(t *testing.T) {
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+
+	tests := []struct {
+		name               string
+		givenUser          User
+		lifetime           time.Duration
+		dbCreateUserError  error
+		dbMarkInvitedError error
+		mailerError        error
+		expectedUserStored bool
+		expectedMail       bool
+		expectedError      error
+	}{
+		{
+			name:               "Happycase",
+			givenUser:          User{EMail: "test@test.test"},
+			lifetime:           72 * time.Hour,
+			expectedUserStored: true,
+			expectedMail:       true,
+		}, {
+			name:          "Invitations not configured",
+			givenUser:     User{EMail: "test@test.test"},
+			expectedError: ErrInvitationNotConfigured,
+		}, {
+			name:          "Without email",
+			givenUser:     User{Username: stringPtr("alice")},
+			lifetime:      72 * time.Hour,
+			expectedError: ErrInvitationRequiresEMail,
+		}, {
+			name:               "User already exists",
+			givenUser:          User{EMail: "test@test.test"},
+			lifetime:           72 * time.Hour,
+			dbCreateUserError:  storage.ErrUserAlreadyExists,
+			expectedUserStored: true,
+			expectedError:      ErrUserAlreadyExists,
+		}, {
+			name:               "Unable to mark user as invited",
+			givenUser:          User{EMail: "test@test.test"},
+			lifetime:           72 * time.Hour,
+			dbMarkInvitedError: errors.New("nope"),
+			expectedUserStored: true,
+			expectedError:      errors.New("failed to mark user as invited: nope"),
+		}, {
+			name:               "Mailer error",
+			givenUser:          User{EMail: "test@test.test"},
+			lifetime:           72 * time.Hour,
+			mailerError:        errors.New("nope"),
+			expectedUserStored: true,
+			expectedMail:       true,
+			expectedError:      errors.New("failed to send invitation-email: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var storedUser *storage.User
+			var createdToken *storage.Token
+			var mailedToken string
+			var mailedExpiresAt time.Time
+			var invitedAt time.Time
+			toTest := Provider{
+				InvitationLifetime: tt.lifetime,
+				Storage: &StorageMock{
+					CreateUserFunc: func(user storage.User) error {
+						storedUser = &user
+						return tt.dbCreateUserError
+					},
+					TokensByUserIDAndTypeFunc: func(userID string, tokenType string) ([]storage.Token, error) {
+						return nil, nil
+					},
+					CreateTokenFunc: func(t storage.Token) (int64, error) {
+						createdToken = &t
+						return 1, nil
+					},
+					MarkUserInvitedFunc: func(id string, at time.Time) error {
+						invitedAt = at
+						return tt.dbMarkInvitedError
+					},
+				},
+				Mailer: &MailerMock{
+					SendInvitationEMailFunc: func(recipient string, invitationToken string, expiresAt time.Time, claims map[string]interface{}, metadata map[string]interface{}) error {
+						mailedToken = invitationToken
+						mailedExpiresAt = expiresAt
+						return tt.mailerError
+					},
+				},
+				TokenHasher: testTokenHasher,
+			}
+
+			err := toTest.CreateUser(tt.givenUser)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:%s\nGiven:%s", tt.expectedError, err)
+			}
+
+			if (storedUser != nil) != tt.expectedUserStored {
+				t.Fatalf("Unexpected user creation. Expected: %t, Given: %t", tt.expectedUserStored, storedUser != nil)
+			}
+			if storedUser != nil && len(storedUser.Password) != 0 {
+				t.Errorf("Invited user has been stored with a password: %q", storedUser.Password)
+			}
+
+			if (mailedToken != "") != tt.expectedMail {
+				t.Fatalf("Unexpected invitation mail. Expected: %t, Given: %t", tt.expectedMail, mailedToken != "")
+			}
+			if tt.expectedMail {
+				if createdToken == nil || createdToken.Token != "hashed-"+mailedToken || createdToken.Type != storage.TokenTypeInvitation {
+					t.Errorf("Mailed token is not the created invitation token. Mailed: %q, Created: %#v", mailedToken, createdToken)
+				}
+				if !mailedExpiresAt.Equal(now.Add(tt.lifetime)) {
+					t.Errorf("Mailed expiry is not as expected. Expected: %s, Given: %s", now.Add(tt.lifetime), mailedExpiresAt)
+				}
+				if !invitedAt.Equal(now) {
+					t.Errorf("Invitation time is not as expected. Expected: %s, Given: %s", now, invitedAt)
+				}
+			}
+		})
+	}
+}
+
+func TestProvider_ResendInvitation(t *testing.T) {
+	tests := []struct {
+		name                  string
+		lifetime              time.Duration
+		dbUser                storage.User
+		dbUserError           error
+		dbTokens              []storage.Token
+		dbDeleteTokenError    error
+		expectedDeletedTokens []int64
+		expectedMail          bool
+		expectedError         error
+	}{
+		{
+			name:                  "Happycase",
+			lifetime:              72 * time.Hour,
+			dbUser:                storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", EMail: "test@test.test"},
+			dbTokens:              []storage.Token{{ID: 4}, {ID: 5}},
+			expectedDeletedTokens: []int64{4, 5},
+			expectedMail:          true,
+		}, {
+			name:          "Invitations not configured",
+			expectedError: ErrInvitationNotConfigured,
+		}, {
+			name:          "User not found",
+			lifetime:      72 * time.Hour,
+			dbUserError:   storage.ErrUserNotFound,
+			expectedError: ErrUserNotFound,
+		}, {
+			name:          "Already accepted",
+			lifetime:      72 * time.Hour,
+			dbUser:        storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", EMail: "test@test.test", Password: []byte("hash")},
+			expectedError: ErrInvitationAccepted,
+		}, {
+			name:          "Without email",
+			lifetime:      72 * time.Hour,
+			dbUser:        storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Username: "alice"},
+			expectedError: ErrInvitationRequiresEMail,
+		}, {
+			name:                  "Unable to delete old token",
+			lifetime:              72 * time.Hour,
+			dbUser:                storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", EMail: "test@test.test"},
+			dbTokens:              []storage.Token{{ID: 4}},
+			dbDeleteTokenError:    errors.New("nope"),
+			expectedDeletedTokens: []int64{4},
+			expectedError:         errors.New("failed to delete invitation-token: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deletedTokens []int64
+			var mailed bool
+			toTest := Provider{
+				InvitationLifetime: tt.lifetime,
+				Storage: &StorageMock{
+					UserFunc: func(email string) (storage.User, error) {
+						return tt.dbUser, tt.dbUserError
+					},
+					TokensByUserIDAndTypeFunc: func(userID string, tokenType string) ([]storage.Token, error) {
+						if tokenType != storage.TokenTypeInvitation {
+							t.Errorf("Unexpected token type: %q", tokenType)
+						}
+						return tt.dbTokens, nil
+					},
+					DeleteTokenFunc: func(id int64) error {
+						deletedTokens = append(deletedTokens, id)
+						return tt.dbDeleteTokenError
+					},
+					CreateTokenFunc: func(t storage.Token) (int64, error) {
+						return 6, nil
+					},
+					MarkUserInvitedFunc: func(id string, invitedAt time.Time) error {
+						return nil
+					},
+				},
+				Mailer: &MailerMock{
+					SendInvitationEMailFunc: func(recipient string, invitationToken string, expiresAt time.Time, claims map[string]interface{}, metadata map[string]interface{}) error {
+						mailed = true
+						return nil
+					},
+				},
+				TokenHasher: testTokenHasher,
+			}
+
+			err := toTest.ResendInvitation("test@test.test")
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:%s\nGiven:%s", tt.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(deletedTokens, tt.expectedDeletedTokens) {
+				t.Errorf("Deleted tokens are not as expected. Expected: %v, Given: %v", tt.expectedDeletedTokens, deletedTokens)
+			}
+
+			if mailed != tt.expectedMail {
+				t.Errorf("Unexpected invitation mail. Expected: %t, Given: %t", tt.expectedMail, mailed)
+			}
+		})
+	}
+}
+
+func TestProvider_AcceptInvitation(t *testing.T) {
+	bcryptCost = bcrypt.MinCost
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+
+	validToken := storage.Token{ID: 4, UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Token: "hashed-abc", Type: storage.TokenTypeInvitation, CreatedAt: now.Add(-time.Hour)}
+
+	tests := []struct {
+		name                 string
+		lifetime             time.Duration
+		dbUser               storage.User
+		dbUserError          error
+		dbTokens             []storage.Token
+		dbUpdateUserError    error
+		dbDeleteTokenError   error
+		expectedDeletedToken int64
+		expectedUpdate       bool
+		expectedError        error
+	}{
+		{
+			name:                 "Happycase",
+			lifetime:             72 * time.Hour,
+			dbUser:               storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", EMail: "test@test.test"},
+			dbTokens:             []storage.Token{validToken},
+			expectedDeletedToken: 4,
+			expectedUpdate:       true,
+		}, {
+			name:          "Invitations not configured",
+			expectedError: ErrInvitationNotConfigured,
+		}, {
+			name:          "User not found",
+			lifetime:      72 * time.Hour,
+			dbUserError:   storage.ErrUserNotFound,
+			expectedError: ErrNoValidTokenFound,
+		}, {
+			name:          "Already accepted",
+			lifetime:      72 * time.Hour,
+			dbUser:        storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", EMail: "test@test.test", Password: []byte("hash")},
+			dbTokens:      []storage.Token{validToken},
+			expectedError: ErrNoValidTokenFound,
+		}, {
+			name:          "Expired token",
+			lifetime:      30 * time.Minute,
+			dbUser:        storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", EMail: "test@test.test"},
+			dbTokens:      []storage.Token{validToken},
+			expectedError: ErrTokenExpired,
+		}, {
+			name:     "Token of other type",
+			lifetime: 72 * time.Hour,
+			dbUser:   storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", EMail: "test@test.test"},
+			dbTokens: []storage.Token{
+				{ID: 5, UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Token: "hashed-abc", Type: storage.TokenTypeReset, CreatedAt: now},
+			},
+			expectedError: ErrNoValidTokenFound,
+		}, {
+			name:                 "Token redeemed concurrently",
+			lifetime:             72 * time.Hour,
+			dbUser:               storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", EMail: "test@test.test"},
+			dbTokens:             []storage.Token{validToken},
+			dbDeleteTokenError:   storage.ErrTokenNotFound,
+			expectedDeletedToken: 4,
+			expectedError:        ErrNoValidTokenFound,
+		}, {
+			name:                 "Unable to update user",
+			lifetime:             72 * time.Hour,
+			dbUser:               storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", EMail: "test@test.test"},
+			dbTokens:             []storage.Token{validToken},
+			dbUpdateUserError:    errors.New("nope"),
+			expectedDeletedToken: 4,
+			expectedUpdate:       true,
+			expectedError:        errors.New("failed to update user: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updatedUser *storage.User
+			var deletedToken int64
+			toTest := Provider{
+				InvitationLifetime: tt.lifetime,
+				Storage: &StorageMock{
+					UserFunc: func(email string) (storage.User, error) {
+						return tt.dbUser, tt.dbUserError
+					},
+					TokensByUserIDAndTypeFunc: func(userID string, tokenType string) ([]storage.Token, error) {
+						return tokensOfType(tt.dbTokens, tokenType), nil
+					},
+					UpdateUserFunc: func(user storage.User) error {
+						updatedUser = &user
+						return tt.dbUpdateUserError
+					},
+					DeleteTokenFunc: func(id int64) error {
+						deletedToken = id
+						return tt.dbDeleteTokenError
+					},
+				},
+				TokenHasher: testTokenHasher,
+			}
+
+			err := toTest.AcceptInvitation("test@test.test", "abc", "s3cr3t")
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:%s\nGiven:%s", tt.expectedError, err)
+			}
+
+			if (updatedUser != nil) != tt.expectedUpdate {
+				t.Fatalf("Unexpected user update. Expected: %t, Given: %t", tt.expectedUpdate, updatedUser != nil)
+			}
+			if updatedUser != nil {
+				if err := bcrypt.CompareHashAndPassword(updatedUser.Password, []byte("s3cr3t")); err != nil {
+					t.Errorf("Password of updated user is not as expected: %s", err)
+				}
+				if !updatedUser.PasswordChangedAt.Equal(now) {
+					t.Errorf("Password changed at is not as expected. Expected: %s, Given: %s", now, updatedUser.PasswordChangedAt)
+				}
+			}
+
+			if deletedToken != tt.expectedDeletedToken {
+				t.Errorf("Deleted token is not as expected. Expected: %d, Given: %d", tt.expectedDeletedToken, deletedToken)
+			}
+		})
+	}
+}
+
+func TestProvider_PurgeUnverifiedUsers(t *testing.T) {
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+
+	tests := []struct {
+		name                  string
+		retention             time.Duration
+		dbDeleteError         error
+		expectedInvitedBefore time.Time
+		expectedCount         int64
+		expectedError         error
+	}{
+		{
+			name:                  "Happycase",
+			retention:             720 * time.Hour,
+			expectedInvitedBefore: now.Add(-720 * time.Hour),
+			expectedCount:         2,
+		}, {
+			name: "Retention disabled",
+		}, {
+			name:                  "Unexpected db error",
+			retention:             720 * time.Hour,
+			dbDeleteError:         errors.New("nope"),
+			expectedInvitedBefore: now.Add(-720 * time.Hour),
+			expectedError:         errors.New("failed to purge unverified users: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var invitedBefore time.Time
+			toTest := Provider{
+				UnverifiedUserRetention: tt.retention,
+				Storage: &StorageMock{
+					DeleteUnverifiedUsersFunc: func(before time.Time) (int64, error) {
+						invitedBefore = before
+						return 2, tt.dbDeleteError
+					},
+				},
+			}
+
+			count, err := toTest.PurgeUnverifiedUsers()
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:%s\nGiven:%s", tt.expectedError, err)
+			}
+
+			if count != tt.expectedCount {
+				t.Errorf("Purged count is not as expected. Expected: %d, Given: %d", tt.expectedCount, count)
+			}
+
+			if !invitedBefore.Equal(tt.expectedInvitedBefore) {
+				t.Errorf("Purged users are not as expected. Expected invited before: %s, Given: %s", tt.expectedInvitedBefore, invitedBefore)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+var ErrJobNotFound = errors.New("job not found")
+var ErrJobLocked = errors.New("job is running on another instance")
+
+var nowFunc = time.Now
+
+//go:generate moq -out locker_moq_test.go . Locker
+type Locker interface {
+	// TryLock tries to acquire the lock with the given name which is shared by all instances. ok is false when the
+	// lock is held by someone else. The lock has to be released with unlock.
+	TryLock(name string) (unlock func() error, ok bool, err error)
+}
+
+// Job is a named task which will be run periodically by a Scheduler
+type Job struct {
+	Name string
+	// Run runs the job once and returns the count of affected entries e.g. deleted rows
+	Run func() (int64, error)
+}
+
+// Stats are the metrics of a job since start of the Scheduler
+type Stats struct {
+	Name string
+	// Runs is the count of runs on this instance (failed ones included)
+	Runs int
+	// Failures is the count of failed runs on this instance
+	Failures int
+	// Skipped is the count of runs which have been skipped because the job was running on another instance
+	Skipped int
+	// LastRunAt is the start of the last run on this instance. It is zero when the job has never run
+	LastRunAt time.Time
+	// LastDuration is the duration of the last run
+	LastDuration time.Duration
+	// LastAffected is the count of affected entries of the last run
+	LastAffected int64
+	// LastError is the error of the last run. It is empty when the last run succeeded
+	LastError string
+}
+
+// Scheduler runs all its jobs periodically. Each job will be locked while running, so it runs on one instance at a time
+// only.
+type Scheduler struct {
+	locker   Locker
+	interval time.Duration
+	jobs     []Job
+
+	mu    sync.Mutex
+	stats map[string]*Stats
+}
+
+// New returns a Scheduler which runs the given jobs in the given interval. Jobs will be locked by the given locker.
+func New(locker Locker, interval time.Duration, jobs ...Job) *Scheduler {
+	stats := map[string]*Stats{}
+	for _, j := range jobs {
+		stats[j.Name] = &Stats{Name: j.Name}
+	}
+
+	return &Scheduler{
+		locker:   locker,
+		interval: interval,
+		jobs:     jobs,
+		stats:    stats,
+	}
+}
+
+// Start runs all jobs immediately and afterwards in the configured interval in the background until stop gets closed.
+// Failures will be logged only.
+func (s *Scheduler) Start(stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			for _, j := range s.jobs {
+				_, err := s.run(j)
+				if err != nil && !errors.Is(err, ErrJobLocked) {
+					logrus.WithError(err).WithField("job", j.Name).Error("Failed to run job")
+				}
+			}
+
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Run runs the job with the given name immediately and returns its stats afterwards.
+// return ErrJobNotFound when there is no job with the given name
+// return ErrJobLocked when the job is running on another instance (or concurrently on this instance)
+func (s *Scheduler) Run(name string) (Stats, error) {
+	for _, j := range s.jobs {
+		if j.Name == name {
+			return s.run(j)
+		}
+	}
+
+	return Stats{}, ErrJobNotFound
+}
+
+// Stats returns the stats of all jobs in the order of the jobs
+func (s *Scheduler) Stats() []Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := make([]Stats, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		stats = append(stats, *s.stats[j.Name])
+	}
+
+	return stats
+}
+
+// run runs the given job when its lock can be acquired and records its stats
+func (s *Scheduler) run(j Job) (Stats, error) {
+	unlock, ok, err := s.locker.TryLock("job:" + j.Name)
+	if err != nil {
+		return s.record(j.Name, func(stats *Stats) {
+			stats.Runs++
+			stats.Failures++
+			stats.LastRunAt = nowFunc()
+			stats.LastDuration = 0
+			stats.LastAffected = 0
+			stats.LastError = err.Error()
+		}), fmt.Errorf("failed to lock job %q: %w", j.Name, err)
+	}
+	if !ok {
+		return s.record(j.Name, func(stats *Stats) {
+			stats.Skipped++
+		}), ErrJobLocked
+	}
+	defer func() {
+		err := unlock()
+		if err != nil {
+			logrus.WithError(err).WithField("job", j.Name).Error("Failed to unlock job")
+		}
+	}()
+
+	start := nowFunc()
+	affected, err := j.Run()
+	duration := nowFunc().Sub(start)
+
+	stats := s.record(j.Name, func(stats *Stats) {
+		stats.Runs++
+		stats.LastRunAt = start
+		stats.LastDuration = duration
+		stats.LastAffected = affected
+		stats.LastError = ""
+		if err != nil {
+			stats.Failures++
+			stats.LastError = err.Error()
+		}
+	})
+	if err != nil {
+		return stats, fmt.Errorf("job %q failed: %w", j.Name, err)
+	}
+
+	logrus.WithFields(logrus.Fields{"job": j.Name, "affected": affected, "duration": duration}).Debug("Job finished")
+	return stats, nil
+}
+
+// record updates the stats of the job with the given name and returns a copy of them
+func (s *Scheduler) record(name string, update func(stats *Stats)) Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	update(s.stats[name])
+	return *s.stats[name]
+}
@@ -0,0 +1,154 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestScheduler_Run(t *testing.T) {
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+
+	tests := []struct {
+		name             string
+		givenJob         string
+		lockError        error
+		locked           bool
+		jobError         error
+		expectedLockName string
+		expectedUnlock   bool
+		expectedStats    Stats
+		expectedError    error
+	}{
+		{
+			name:             "Happycase",
+			givenJob:         "purge-tokens",
+			expectedLockName: "job:purge-tokens",
+			expectedUnlock:   true,
+			expectedStats:    Stats{Name: "purge-tokens", Runs: 1, LastRunAt: now, LastDuration: time.Second, LastAffected: 3},
+		}, {
+			name:          "Unknown job",
+			givenJob:      "unknown",
+			expectedError: ErrJobNotFound,
+		}, {
+			name:             "Locked by another instance",
+			givenJob:         "purge-tokens",
+			locked:           true,
+			expectedLockName: "job:purge-tokens",
+			expectedStats:    Stats{Name: "purge-tokens", Skipped: 1},
+			expectedError:    ErrJobLocked,
+		}, {
+			name:             "Lock error",
+			givenJob:         "purge-tokens",
+			lockError:        errors.New("nope"),
+			expectedLockName: "job:purge-tokens",
+			expectedStats:    Stats{Name: "purge-tokens", Runs: 1, Failures: 1, LastRunAt: now, LastError: "nope"},
+			expectedError:    errors.New("failed to lock job \"purge-tokens\": nope"),
+		}, {
+			name:             "Job error",
+			givenJob:         "purge-tokens",
+			jobError:         errors.New("nope"),
+			expectedLockName: "job:purge-tokens",
+			expectedUnlock:   true,
+			expectedStats:    Stats{Name: "purge-tokens", Runs: 1, Failures: 1, LastRunAt: now, LastDuration: time.Second, LastAffected: 3, LastError: "nope"},
+			expectedError:    errors.New("job \"purge-tokens\" failed: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			nowFunc = func() time.Time {
+				calls++
+				return now.Add(time.Duration(calls-1) * time.Second)
+			}
+
+			var lockName string
+			var unlocked bool
+			toTest := New(&LockerMock{
+				TryLockFunc: func(name string) (func() error, bool, error) {
+					lockName = name
+					if tt.lockError != nil || tt.locked {
+						return nil, false, tt.lockError
+					}
+					return func() error {
+						unlocked = true
+						return nil
+					}, true, nil
+				},
+			}, time.Hour, Job{
+				Name: "purge-tokens",
+				Run: func() (int64, error) {
+					return 3, tt.jobError
+				},
+			})
+
+			stats, err := toTest.Run(tt.givenJob)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			if lockName != tt.expectedLockName {
+				t.Errorf("Lock name is not as expected. Expected: %q, Given: %q", tt.expectedLockName, lockName)
+			}
+
+			if unlocked != tt.expectedUnlock {
+				t.Errorf("Unexpected unlock. Expected: %t, Given: %t", tt.expectedUnlock, unlocked)
+			}
+
+			if !reflect.DeepEqual(stats, tt.expectedStats) {
+				t.Errorf("Stats are not as expected. Expected:\n%#v\nGiven:\n%#v", tt.expectedStats, stats)
+			}
+		})
+	}
+}
+
+func TestScheduler_Start(t *testing.T) {
+	runs := make(chan string, 10)
+	toTest := New(&LockerMock{
+		TryLockFunc: func(name string) (func() error, bool, error) {
+			return func() error { return nil }, true, nil
+		},
+	}, time.Millisecond, Job{
+		Name: "first",
+		Run: func() (int64, error) {
+			runs <- "first"
+			return 0, nil
+		},
+	}, Job{
+		Name: "second",
+		Run: func() (int64, error) {
+			runs <- "second"
+			return 0, errors.New("nope")
+		},
+	})
+
+	stop := make(chan struct{})
+	toTest.Start(stop)
+
+	var given []string
+	for i := 0; i < 4; i++ {
+		select {
+		case r := <-runs:
+			given = append(given, r)
+		case <-time.After(time.Second):
+			t.Fatalf("Jobs have not been run periodically. Runs: %v", given)
+		}
+	}
+	close(stop)
+
+	expected := []string{"first", "second", "first", "second"}
+	if !reflect.DeepEqual(given, expected) {
+		t.Errorf("Runs are not as expected. Expected: %v, Given: %v", expected, given)
+	}
+
+	stats := toTest.Stats()
+	if len(stats) != 2 || stats[0].Name != "first" || stats[1].Name != "second" || stats[1].Runs < 2 ||
+		stats[1].Failures != stats[1].Runs {
+		t.Errorf("Stats are not as expected: %#v", stats)
+	}
+}
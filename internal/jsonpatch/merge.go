@@ -0,0 +1,27 @@
+package jsonpatch
+
+// MergePatch applies the given merge patch (RFC 7396) to the given decoded json document and returns the patched
+// document. Patches which are no objects replace the whole document and null values remove members. The given
+// document may be modified.
+func MergePatch(doc, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	docObject, ok := doc.(map[string]interface{})
+	if !ok {
+		docObject = map[string]interface{}{}
+	}
+
+	for name, value := range patchObject {
+		if value == nil {
+			delete(docObject, name)
+			continue
+		}
+
+		docObject[name] = MergePatch(docObject[name], value)
+	}
+
+	return docObject
+}
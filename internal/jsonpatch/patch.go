@@ -0,0 +1,189 @@
+// Package jsonpatch applies json merge patches (RFC 7396) and json patches (RFC 6902) to decoded json documents
+// (map[string]interface{}, []interface{}, string, float64, bool and nil like encoding/json decodes into interface{}).
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrInvalid will be returned when a patch is malformed
+var ErrInvalid = errors.New("invalid patch")
+
+// ErrNotApplicable will be returned when a patch can not be applied to a document because a referenced value does not
+// exist or a test operation failed
+var ErrNotApplicable = errors.New("patch not applicable")
+
+// Patch is a parsed json patch (RFC 6902)
+type Patch []operation
+
+type operation struct {
+	op    string
+	path  pointer
+	from  pointer
+	value interface{}
+}
+
+// Parse parses the given json patch document.
+// return ErrInvalid when the patch is malformed
+func Parse(rawPatch []byte) (Patch, error) {
+	var rawOperations []map[string]interface{}
+	err := json.Unmarshal(rawPatch, &rawOperations)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalid, err)
+	}
+
+	patch := make(Patch, 0, len(rawOperations))
+	for i, rawOperation := range rawOperations {
+		o, err := parseOperation(rawOperation)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+
+		patch = append(patch, o)
+	}
+
+	return patch, nil
+}
+
+func parseOperation(rawOperation map[string]interface{}) (operation, error) {
+	var o operation
+	var ok bool
+	o.op, ok = rawOperation["op"].(string)
+	if !ok {
+		return operation{}, fmt.Errorf("%w: op is missing", ErrInvalid)
+	}
+
+	rawPath, ok := rawOperation["path"].(string)
+	if !ok {
+		return operation{}, fmt.Errorf("%w: path is missing", ErrInvalid)
+	}
+
+	var err error
+	o.path, err = parsePointer(rawPath)
+	if err != nil {
+		return operation{}, err
+	}
+
+	switch o.op {
+	case "add", "replace", "test":
+		o.value, ok = rawOperation["value"]
+		if !ok {
+			return operation{}, fmt.Errorf("%w: value is missing", ErrInvalid)
+		}
+	case "move", "copy":
+		rawFrom, ok := rawOperation["from"].(string)
+		if !ok {
+			return operation{}, fmt.Errorf("%w: from is missing", ErrInvalid)
+		}
+
+		o.from, err = parsePointer(rawFrom)
+		if err != nil {
+			return operation{}, err
+		}
+	case "remove":
+	default:
+		return operation{}, fmt.Errorf("%w: unknown op %q", ErrInvalid, o.op)
+	}
+
+	return o, nil
+}
+
+// Apply applies all operations of the patch one after another to the given document and returns the patched
+// document. The given document may be modified also when an operation could not be applied.
+// return ErrNotApplicable when an operation could not be applied
+func (p Patch) Apply(doc interface{}) (interface{}, error) {
+	for i, o := range p {
+		var err error
+		doc, err = o.apply(doc)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s): %w", i, o.op, err)
+		}
+	}
+
+	return doc, nil
+}
+
+func (o operation) apply(doc interface{}) (interface{}, error) {
+	switch o.op {
+	case "add":
+		return add(doc, o.path, deepCopy(o.value))
+	case "remove":
+		return remove(doc, o.path)
+	case "replace":
+		if len(o.path) == 0 {
+			return deepCopy(o.value), nil
+		}
+		return o.path.set(doc, func(container interface{}, token string) (interface{}, error) {
+			return replaceMember(container, token, deepCopy(o.value))
+		})
+	case "move":
+		if len(o.from) != len(o.path) && o.from.isPrefixOf(o.path) {
+			return nil, fmt.Errorf("%w: a value can not be moved into itself", ErrNotApplicable)
+		}
+		value, err := o.from.get(doc)
+		if err != nil {
+			return nil, err
+		}
+		doc, err = remove(doc, o.from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, o.path, value)
+	case "copy":
+		value, err := o.from.get(doc)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, o.path, deepCopy(value))
+	default: // test
+		value, err := o.path.get(doc)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(value, o.value) {
+			return nil, fmt.Errorf("%w: test failed", ErrNotApplicable)
+		}
+		return doc, nil
+	}
+}
+
+func add(doc interface{}, path pointer, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return path.set(doc, func(container interface{}, token string) (interface{}, error) {
+		return addMember(container, token, value)
+	})
+}
+
+func remove(doc interface{}, path pointer) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%w: the whole document can not be removed", ErrNotApplicable)
+	}
+
+	return path.set(doc, removeMember)
+}
+
+// deepCopy copies the given decoded json value so that it can be added multiple times and modified independently
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for name, member := range v {
+			c[name] = deepCopy(member)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, element := range v {
+			c[i] = deepCopy(element)
+		}
+		return c
+	default:
+		return v
+	}
+}
@@ -0,0 +1,246 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// samples of RFC 6902 appendix A
+func TestPatch_Apply(t *testing.T) {
+	tests := []struct {
+		name          string
+		givenDoc      string
+		givenPatch    string
+		expectedDoc   string
+		expectedError error
+	}{
+		{
+			name:        "Adding an object member",
+			givenDoc:    `{"foo": "bar"}`,
+			givenPatch:  `[{"op": "add", "path": "/baz", "value": "qux"}]`,
+			expectedDoc: `{"baz": "qux", "foo": "bar"}`,
+		}, {
+			name:        "Adding an array element",
+			givenDoc:    `{"foo": ["bar", "baz"]}`,
+			givenPatch:  `[{"op": "add", "path": "/foo/1", "value": "qux"}]`,
+			expectedDoc: `{"foo": ["bar", "qux", "baz"]}`,
+		}, {
+			name:        "Removing an object member",
+			givenDoc:    `{"baz": "qux", "foo": "bar"}`,
+			givenPatch:  `[{"op": "remove", "path": "/baz"}]`,
+			expectedDoc: `{"foo": "bar"}`,
+		}, {
+			name:        "Removing an array element",
+			givenDoc:    `{"foo": ["bar", "qux", "baz"]}`,
+			givenPatch:  `[{"op": "remove", "path": "/foo/1"}]`,
+			expectedDoc: `{"foo": ["bar", "baz"]}`,
+		}, {
+			name:        "Replacing a value",
+			givenDoc:    `{"baz": "qux", "foo": "bar"}`,
+			givenPatch:  `[{"op": "replace", "path": "/baz", "value": "boo"}]`,
+			expectedDoc: `{"baz": "boo", "foo": "bar"}`,
+		}, {
+			name:        "Moving a value",
+			givenDoc:    `{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`,
+			givenPatch:  `[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`,
+			expectedDoc: `{"foo": {"bar": "baz"}, "qux": {"corge": "grault", "thud": "fred"}}`,
+		}, {
+			name:        "Moving an array element",
+			givenDoc:    `{"foo": ["all", "grass", "cows", "eat"]}`,
+			givenPatch:  `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`,
+			expectedDoc: `{"foo": ["all", "cows", "eat", "grass"]}`,
+		}, {
+			name:        "Testing a value: success",
+			givenDoc:    `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+			givenPatch:  `[{"op": "test", "path": "/baz", "value": "qux"}, {"op": "test", "path": "/foo/1", "value": 2}]`,
+			expectedDoc: `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+		}, {
+			name:          "Testing a value: error",
+			givenDoc:      `{"baz": "qux"}`,
+			givenPatch:    `[{"op": "test", "path": "/baz", "value": "bar"}]`,
+			expectedError: errors.New("operation 0 (test): patch not applicable: test failed"),
+		}, {
+			name:        "Adding a nested member object",
+			givenDoc:    `{"foo": "bar"}`,
+			givenPatch:  `[{"op": "add", "path": "/child", "value": {"grandchild": {}}}]`,
+			expectedDoc: `{"foo": "bar", "child": {"grandchild": {}}}`,
+		}, {
+			name:          "Adding to a nonexistent target",
+			givenDoc:      `{"foo": "bar"}`,
+			givenPatch:    `[{"op": "add", "path": "/baz/bat", "value": "qux"}]`,
+			expectedError: errors.New("operation 0 (add): patch not applicable: member \"baz\" does not exist"),
+		}, {
+			name:        "~ escape ordering",
+			givenDoc:    `{"/": 9, "~1": 10}`,
+			givenPatch:  `[{"op": "test", "path": "/~01", "value": 10}]`,
+			expectedDoc: `{"/": 9, "~1": 10}`,
+		}, {
+			name:        "Adding an array value",
+			givenDoc:    `{"foo": ["bar"]}`,
+			givenPatch:  `[{"op": "add", "path": "/foo/-", "value": ["abc", "def"]}]`,
+			expectedDoc: `{"foo": ["bar", ["abc", "def"]]}`,
+		}, {
+			name:        "Copying a value",
+			givenDoc:    `{"foo": {"bar": 1}}`,
+			givenPatch:  `[{"op": "copy", "from": "/foo", "path": "/baz"}, {"op": "replace", "path": "/baz/bar", "value": 2}]`,
+			expectedDoc: `{"foo": {"bar": 1}, "baz": {"bar": 2}}`,
+		}, {
+			name:        "Replacing the whole document",
+			givenDoc:    `{"foo": "bar"}`,
+			givenPatch:  `[{"op": "replace", "path": "", "value": {"baz": "qux"}}]`,
+			expectedDoc: `{"baz": "qux"}`,
+		}, {
+			name:          "Moving a value into itself",
+			givenDoc:      `{"foo": {"bar": 1}}`,
+			givenPatch:    `[{"op": "move", "from": "/foo", "path": "/foo/bar"}]`,
+			expectedError: errors.New("operation 0 (move): patch not applicable: a value can not be moved into itself"),
+		}, {
+			name:          "Array index out of bounds",
+			givenDoc:      `{"foo": ["bar"]}`,
+			givenPatch:    `[{"op": "replace", "path": "/foo/1", "value": "baz"}]`,
+			expectedError: errors.New("operation 0 (replace): patch not applicable: array index 1 is out of bounds"),
+		}, {
+			name:          "Array index with leading zero",
+			givenDoc:      `{"foo": ["bar", "baz"]}`,
+			givenPatch:    `[{"op": "remove", "path": "/foo/01"}]`,
+			expectedError: errors.New("operation 0 (remove): patch not applicable: \"01\" is no array index"),
+		}, {
+			name:          "Removing a nonexistent member",
+			givenDoc:      `{"foo": "bar"}`,
+			givenPatch:    `[{"op": "remove", "path": "/baz"}]`,
+			expectedError: errors.New("operation 0 (remove): patch not applicable: member \"baz\" does not exist"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc interface{}
+			err := json.Unmarshal([]byte(tt.givenDoc), &doc)
+			if err != nil {
+				t.Fatal("Failed to unmarshal given doc", err)
+			}
+
+			patch, err := Parse([]byte(tt.givenPatch))
+			if err != nil {
+				t.Fatal("Failed to parse given patch", err)
+			}
+
+			doc, err = patch.Apply(doc)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			if tt.expectedError == nil {
+				assertDocument(t, tt.expectedDoc, doc)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name          string
+		givenPatch    string
+		expectedError error
+	}{
+		{
+			name:       "Happycase",
+			givenPatch: `[{"op": "remove", "path": "/a~1b"}, {"op": "add", "path": "/c", "value": null}]`,
+		}, {
+			name:          "No array",
+			givenPatch:    `{"op": "remove", "path": "/a"}`,
+			expectedError: errors.New("invalid patch: json: cannot unmarshal object into Go value of type []map[string]interface {}"),
+		}, {
+			name:          "Unknown op",
+			givenPatch:    `[{"op": "delete", "path": "/a"}]`,
+			expectedError: errors.New("operation 0: invalid patch: unknown op \"delete\""),
+		}, {
+			name:          "Missing path",
+			givenPatch:    `[{"op": "remove"}]`,
+			expectedError: errors.New("operation 0: invalid patch: path is missing"),
+		}, {
+			name:          "Missing value",
+			givenPatch:    `[{"op": "remove", "path": "/a"}, {"op": "add", "path": "/a"}]`,
+			expectedError: errors.New("operation 1: invalid patch: value is missing"),
+		}, {
+			name:          "Missing from",
+			givenPatch:    `[{"op": "copy", "path": "/a"}]`,
+			expectedError: errors.New("operation 0: invalid patch: from is missing"),
+		}, {
+			name:          "Relative pointer",
+			givenPatch:    `[{"op": "remove", "path": "a"}]`,
+			expectedError: errors.New("operation 0: invalid patch: pointer \"a\" does not start with '/'"),
+		}, {
+			name:          "Invalid escape",
+			givenPatch:    `[{"op": "remove", "path": "/a~2"}]`,
+			expectedError: errors.New("operation 0: invalid patch: pointer \"/a~2\" contains invalid escape"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.givenPatch))
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+// samples of RFC 7396 appendix A
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		givenDoc    string
+		givenPatch  string
+		expectedDoc string
+	}{
+		{givenDoc: `{"a":"b"}`, givenPatch: `{"a":"c"}`, expectedDoc: `{"a":"c"}`},
+		{givenDoc: `{"a":"b"}`, givenPatch: `{"b":"c"}`, expectedDoc: `{"a":"b","b":"c"}`},
+		{givenDoc: `{"a":"b"}`, givenPatch: `{"a":null}`, expectedDoc: `{}`},
+		{givenDoc: `{"a":"b","b":"c"}`, givenPatch: `{"a":null}`, expectedDoc: `{"b":"c"}`},
+		{givenDoc: `{"a":["b"]}`, givenPatch: `{"a":"c"}`, expectedDoc: `{"a":"c"}`},
+		{givenDoc: `{"a":"c"}`, givenPatch: `{"a":["b"]}`, expectedDoc: `{"a":["b"]}`},
+		{givenDoc: `{"a":{"b":"c"}}`, givenPatch: `{"a":{"b":"d","c":null}}`, expectedDoc: `{"a":{"b":"d"}}`},
+		{givenDoc: `{"a":[{"b":"c"}]}`, givenPatch: `{"a":[1]}`, expectedDoc: `{"a":[1]}`},
+		{givenDoc: `["a","b"]`, givenPatch: `["c","d"]`, expectedDoc: `["c","d"]`},
+		{givenDoc: `{"a":"b"}`, givenPatch: `["c"]`, expectedDoc: `["c"]`},
+		{givenDoc: `{"a":"foo"}`, givenPatch: `null`, expectedDoc: `null`},
+		{givenDoc: `{"a":"foo"}`, givenPatch: `"bar"`, expectedDoc: `"bar"`},
+		{givenDoc: `{"e":null}`, givenPatch: `{"a":1}`, expectedDoc: `{"e":null,"a":1}`},
+		{givenDoc: `[1,2]`, givenPatch: `{"a":"b","c":null}`, expectedDoc: `{"a":"b"}`},
+		{givenDoc: `{}`, givenPatch: `{"a":{"bb":{"ccc":null}}}`, expectedDoc: `{"a":{"bb":{}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.givenPatch, func(t *testing.T) {
+			var doc, patch interface{}
+			err := json.Unmarshal([]byte(tt.givenDoc), &doc)
+			if err != nil {
+				t.Fatal("Failed to unmarshal given doc", err)
+			}
+			err = json.Unmarshal([]byte(tt.givenPatch), &patch)
+			if err != nil {
+				t.Fatal("Failed to unmarshal given patch", err)
+			}
+
+			assertDocument(t, tt.expectedDoc, MergePatch(doc, patch))
+		})
+	}
+}
+
+func assertDocument(t *testing.T, expectedDoc string, doc interface{}) {
+	t.Helper()
+
+	var expected interface{}
+	err := json.Unmarshal([]byte(expectedDoc), &expected)
+	if err != nil {
+		t.Fatal("Failed to unmarshal expected doc", err)
+	}
+
+	if !reflect.DeepEqual(expected, doc) {
+		t.Errorf("Document is not as expected. Expected:\n%#v\nGiven:\n%#v", expected, doc)
+	}
+}
@@ -0,0 +1,181 @@
+package jsonpatch
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pointer is a parsed json pointer (RFC 6901). The empty pointer references the whole document.
+type pointer []string
+
+var pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// parsePointer parses the given json pointer
+// return ErrInvalid when the pointer is neither empty nor starts with '/' or contains invalid escapes
+func parsePointer(p string) (pointer, error) {
+	if p == "" {
+		return pointer{}, nil
+	}
+	if !strings.HasPrefix(p, "/") {
+		return nil, fmt.Errorf("%w: pointer %q does not start with '/'", ErrInvalid, p)
+	}
+
+	tokens := strings.Split(p[1:], "/")
+	for i, token := range tokens {
+		if strings.Contains(strings.NewReplacer("~0", "", "~1", "").Replace(token), "~") {
+			return nil, fmt.Errorf("%w: pointer %q contains invalid escape", ErrInvalid, p)
+		}
+		tokens[i] = pointerUnescaper.Replace(token)
+	}
+
+	return tokens, nil
+}
+
+// isPrefixOf returns true when the given pointer references a value within the value referenced by p or the same value
+func (p pointer) isPrefixOf(other pointer) bool {
+	if len(p) > len(other) {
+		return false
+	}
+
+	for i := range p {
+		if p[i] != other[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// get returns the value referenced by p
+// return ErrNotApplicable when the value does not exist
+func (p pointer) get(doc interface{}) (interface{}, error) {
+	value := doc
+	for _, token := range p {
+		switch container := value.(type) {
+		case map[string]interface{}:
+			member, ok := container[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: member %q does not exist", ErrNotApplicable, token)
+			}
+			value = member
+		case []interface{}:
+			i, err := arrayIndex(token, len(container)-1)
+			if err != nil {
+				return nil, err
+			}
+			value = container[i]
+		default:
+			return nil, fmt.Errorf("%w: %q can not be referenced in a scalar", ErrNotApplicable, token)
+		}
+	}
+
+	return value, nil
+}
+
+// set calls the given function with the container of the value referenced by p and stores the container returned by
+// it because arrays can not be modified in place when their length changes. p must not be empty.
+// return ErrNotApplicable when the container does not exist
+func (p pointer) set(doc interface{}, f func(container interface{}, token string) (interface{}, error)) (interface{}, error) {
+	parent, last := p[:len(p)-1], p[len(p)-1]
+	container, err := parent.get(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	container, err = f(container, last)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(parent) == 0 {
+		return container, nil
+	}
+
+	return parent.set(doc, func(grandparent interface{}, token string) (interface{}, error) {
+		return replaceMember(grandparent, token, container)
+	})
+}
+
+// arrayIndex parses the given array index which must be within 0 and max
+// return ErrNotApplicable when the index is malformed or out of bounds
+func arrayIndex(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.TrimLeft(token, "0123456789") != "" {
+		return 0, fmt.Errorf("%w: %q is no array index", ErrNotApplicable, token)
+	}
+
+	i, err := strconv.Atoi(token)
+	if err != nil || i > max {
+		return 0, fmt.Errorf("%w: array index %s is out of bounds", ErrNotApplicable, token)
+	}
+
+	return i, nil
+}
+
+// addMember adds the given value to the given container. Array elements will be inserted before the given index or
+// appended when the index is '-'. Object members will be replaced when they already exist.
+func addMember(container interface{}, token string, value interface{}) (interface{}, error) {
+	switch c := container.(type) {
+	case map[string]interface{}:
+		c[token] = value
+		return c, nil
+	case []interface{}:
+		i := len(c)
+		if token != "-" {
+			var err error
+			i, err = arrayIndex(token, len(c))
+			if err != nil {
+				return nil, err
+			}
+		}
+		c = append(c, nil)
+		copy(c[i+1:], c[i:])
+		c[i] = value
+		return c, nil
+	default:
+		return nil, fmt.Errorf("%w: %q can not be added to a scalar", ErrNotApplicable, token)
+	}
+}
+
+// removeMember removes the given existing member of the given container
+func removeMember(container interface{}, token string) (interface{}, error) {
+	switch c := container.(type) {
+	case map[string]interface{}:
+		_, ok := c[token]
+		if !ok {
+			return nil, fmt.Errorf("%w: member %q does not exist", ErrNotApplicable, token)
+		}
+		delete(c, token)
+		return c, nil
+	case []interface{}:
+		i, err := arrayIndex(token, len(c)-1)
+		if err != nil {
+			return nil, err
+		}
+		return append(c[:i], c[i+1:]...), nil
+	default:
+		return nil, fmt.Errorf("%w: %q can not be removed from a scalar", ErrNotApplicable, token)
+	}
+}
+
+// replaceMember replaces the given existing member of the given container with the given value
+func replaceMember(container interface{}, token string, value interface{}) (interface{}, error) {
+	switch c := container.(type) {
+	case map[string]interface{}:
+		_, ok := c[token]
+		if !ok {
+			return nil, fmt.Errorf("%w: member %q does not exist", ErrNotApplicable, token)
+		}
+		c[token] = value
+		return c, nil
+	case []interface{}:
+		i, err := arrayIndex(token, len(c)-1)
+		if err != nil {
+			return nil, err
+		}
+		c[i] = value
+		return c, nil
+	default:
+		return nil, fmt.Errorf("%w: %q can not be replaced in a scalar", ErrNotApplicable, token)
+	}
+}
@@ -20,13 +20,12 @@ type Generator struct {
 	privateClaims struct {
 		audience string
 		issuer   string
-		subject  string
 	}
 }
 
 // NewGenerator a Generator instance with the given jwt-configuration. Before instantiation the private key will be
 // checked and parsed
-func NewGenerator(privateKey, jwtAudience, jwtIssuer, jwtSubject string) (*Generator, error) {
+func NewGenerator(privateKey, jwtAudience, jwtIssuer string) (*Generator, error) {
 	privateKey = strings.Replace(privateKey, `\n`, "\n", -1) //TODO fix me (needed for start via ide)
 	blockPrv, _ := pem.Decode([]byte(privateKey))
 	if blockPrv == nil {
@@ -43,19 +42,17 @@ func NewGenerator(privateKey, jwtAudience, jwtIssuer, jwtSubject string) (*Gener
 		privateClaims: struct {
 			audience string
 			issuer   string
-			subject  string
 		}{
 			audience: jwtAudience,
 			issuer:   jwtIssuer,
-			subject:  jwtSubject,
 		},
 	}, err
 }
 
-// Generate generates a valid jwt based on the Generator.privateKey. The jwt is issued to the given email and enriched
-// with the given claims.
+// Generate generates a valid jwt based on the Generator.privateKey. The jwt is issued to the user with the given id
+// (subject) and email and enriched with the given claims. The email claim will be omitted for users without email.
 // 'userClaims' can be contain all json compatible types
-func (g Generator) Generate(email string, userClaims map[string]interface{}) (string, error) {
+func (g Generator) Generate(userID, email string, userClaims map[string]interface{}) (string, error) {
 	now := nowFunc()
 	jwtID, err := uuid.NewRandom()
 	if err != nil {
@@ -74,10 +71,12 @@ func (g Generator) Generate(email string, userClaims map[string]interface{}) (st
 	claims["iat"] = now.Unix()               //IssuedAt
 	claims["iss"] = g.privateClaims.issuer   //Issuer
 	claims["nbf"] = now.Unix()               //NotBefore
-	claims["sub"] = g.privateClaims.subject  //Subject
+	claims["sub"] = userID                   //Subject
 
 	//public claims by https://www.iana.org/assignments/jwt/jwt.xhtml#claims
-	claims["email"] = email //Recipient
+	if email != "" {
+		claims["email"] = email //Recipient
+	}
 
 	t := jwt.NewWithClaims(jwt.SigningMethodES512, claims)
 
@@ -27,12 +27,12 @@ r94giWgKjxYkB7xiy+IiZsWEBXU0rz7rb+IwJ82PfQ==
 `
 
 func TestNewGenerator(t *testing.T) {
-	g, err := NewGenerator(jwtPrvKey, "audience", "issuer", "subject")
+	g, err := NewGenerator(jwtPrvKey, "audience", "issuer")
 	if err != nil {
 		t.Fatalf("failed to crreate new generator: %s", err)
 	}
 
-	generatedJWT, err := g.Generate("myMailAddress", map[string]interface{}{"myCustomClaim": "mialc"})
+	generatedJWT, err := g.Generate("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", "myMailAddress", map[string]interface{}{"myCustomClaim": "mialc"})
 	if err != nil {
 		t.Fatalf("failed to generate jwt: %s", err)
 	}
@@ -48,7 +48,7 @@ func TestNewGenerator(t *testing.T) {
 		t.Errorf("unexpected iss-privateClaim value. Expected: %q. Given: %q", expectedJWTIssuer, claims["iss"])
 	}
 
-	expectedJWTSubject := "subject"
+	expectedJWTSubject := "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b"
 	if claims["sub"] != expectedJWTSubject {
 		t.Errorf("unexpected sub-privateClaim value. Expected: %q. Given: %q", expectedJWTSubject, claims["sub"])
 	}
@@ -80,8 +80,25 @@ func TestNewGenerator(t *testing.T) {
 	}
 }
 
+func TestGenerator_GenerateWithoutEMail(t *testing.T) {
+	g, err := NewGenerator(jwtPrvKey, "audience", "issuer")
+	if err != nil {
+		t.Fatalf("failed to crreate new generator: %s", err)
+	}
+
+	generatedJWT, err := g.Generate("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", "", nil)
+	if err != nil {
+		t.Fatalf("failed to generate jwt: %s", err)
+	}
+
+	claims := validateJWT(t, generatedJWT)
+	if _, ok := claims["email"]; ok {
+		t.Errorf("email-privateClaim must not be set for users without email. Given: %q", claims["email"])
+	}
+}
+
 func TestNewGeneratorWithoutPrivateKey(t *testing.T) {
-	_, err := NewGenerator("", "audience", "issuer", "subject")
+	_, err := NewGenerator("", "audience", "issuer")
 
 	expectedError := errors.New("no valid private key found")
 	if fmt.Sprint(err) != fmt.Sprint(expectedError) {
@@ -0,0 +1,48 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// GeneratePrivateKey generates a new ECDSA P-521 private key for ES512 signatures and returns it pem encoded like
+// NewGenerator expects it
+func GeneratePrivateKey() (string, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal private key: %w", err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})), nil
+}
+
+// PublicKey returns the pem encoded public key of the given pem encoded ECDSA private key which can be used to verify
+// the generated jwts
+func PublicKey(privateKey string) (string, error) {
+	blockPrv, _ := pem.Decode([]byte(privateKey))
+	if blockPrv == nil {
+		return "", errors.New("no valid private key found")
+	}
+
+	key, err := x509.ParseECPrivateKey(blockPrv.Bytes)
+	if err != nil {
+		return "", err
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal public key: %w", err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
@@ -0,0 +1,52 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestGeneratePrivateKey(t *testing.T) {
+	privateKey, err := GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate private key: %s", err)
+	}
+
+	_, err = NewGenerator(privateKey, "audience", "issuer")
+	if err != nil {
+		t.Errorf("generated private key can not be used by generator: %s", err)
+	}
+}
+
+func TestPublicKey(t *testing.T) {
+	tests := []struct {
+		name              string
+		givenPrivateKey   string
+		expectedPublicKey string
+		expectedError     error
+	}{
+		{
+			name:              "Happycase",
+			givenPrivateKey:   jwtPrvKey,
+			expectedPublicKey: jwtPubKey,
+		},
+		{
+			name:            "No pem",
+			givenPrivateKey: "no key",
+			expectedError:   errors.New("no valid private key found"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publicKey, err := PublicKey(tt.givenPrivateKey)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("unexpected error. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
+			}
+
+			if publicKey != tt.expectedPublicKey {
+				t.Errorf("unexpected public key. Expected:\n%s\nGiven:\n%s", tt.expectedPublicKey, publicKey)
+			}
+		})
+	}
+}
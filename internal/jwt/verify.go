@@ -0,0 +1,42 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+)
+
+var ErrInvalidToken = errors.New("invalid jwt")
+
+// Verify verifies the given jwt which must have been generated by Generate with the same private key, audience and
+// issuer and must not be expired. Returns the id of the user the jwt has been issued to (subject).
+// return ErrInvalidToken when the jwt is malformed, expired or has not been generated by this Generator
+func (g Generator) Verify(token string) (string, error) {
+	var claims jwt.MapClaims
+	parser := jwt.Parser{
+		ValidMethods:         []string{jwt.SigningMethodES512.Alg()},
+		SkipClaimsValidation: true,
+	}
+	_, err := parser.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return &g.privateKey.PublicKey, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidToken, err)
+	}
+
+	now := nowFunc().Unix()
+	if !claims.VerifyExpiresAt(now, true) || !claims.VerifyNotBefore(now, false) {
+		return "", fmt.Errorf("%w: expired or not yet valid", ErrInvalidToken)
+	}
+
+	if claims["aud"] != g.privateClaims.audience || claims["iss"] != g.privateClaims.issuer {
+		return "", fmt.Errorf("%w: unexpected audience or issuer", ErrInvalidToken)
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return "", fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+
+	return subject, nil
+}
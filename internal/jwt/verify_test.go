@@ -0,0 +1,90 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestGenerator_Verify(t *testing.T) {
+	g, err := NewGenerator(jwtPrvKey, "audience", "issuer")
+	if err != nil {
+		t.Fatalf("failed to create new generator: %s", err)
+	}
+
+	otherKey, err := GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate private key: %s", err)
+	}
+	otherKeyGenerator, err := NewGenerator(otherKey, "audience", "issuer")
+	if err != nil {
+		t.Fatalf("failed to create new generator: %s", err)
+	}
+	otherIssuerGenerator, err := NewGenerator(jwtPrvKey, "audience", "other")
+	if err != nil {
+		t.Fatalf("failed to create new generator: %s", err)
+	}
+
+	tests := []struct {
+		name           string
+		generator      *Generator
+		generatedAt    time.Time
+		token          string
+		expectedUserID string
+		expectedError  error
+	}{
+		{
+			name:           "Happycase",
+			generator:      g,
+			generatedAt:    time.Now(),
+			expectedUserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+		},
+		{
+			name:          "Expired",
+			generator:     g,
+			generatedAt:   time.Now().Add(-lifeTime - time.Minute),
+			expectedError: errors.New("invalid jwt: expired or not yet valid"),
+		},
+		{
+			name:          "Other key",
+			generator:     otherKeyGenerator,
+			generatedAt:   time.Now(),
+			expectedError: errors.New("invalid jwt: crypto/ecdsa: verification error"),
+		},
+		{
+			name:          "Other issuer",
+			generator:     otherIssuerGenerator,
+			generatedAt:   time.Now(),
+			expectedError: errors.New("invalid jwt: unexpected audience or issuer"),
+		},
+		{
+			name:          "Malformed",
+			token:         "no.jwt",
+			expectedError: errors.New("invalid jwt: token contains an invalid number of segments"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := tt.token
+			if tt.generator != nil {
+				nowFunc = func() time.Time { return tt.generatedAt }
+				token, err = tt.generator.Generate("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", "", nil)
+				nowFunc = time.Now
+				if err != nil {
+					t.Fatalf("failed to generate jwt: %s", err)
+				}
+			}
+
+			userID, err := g.Verify(token)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			if userID != tt.expectedUserID {
+				t.Errorf("Unexpected user id. Expected: %q, Given: %q", tt.expectedUserID, userID)
+			}
+		})
+	}
+}
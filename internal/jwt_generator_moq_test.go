@@ -9,6 +9,7 @@ import (
 
 var (
 	lockJWTGeneratorMockGenerate sync.RWMutex
+	lockJWTGeneratorMockVerify   sync.RWMutex
 )
 
 // Ensure, that JWTGeneratorMock does implement JWTGenerator.
@@ -21,9 +22,12 @@ var _ JWTGenerator = &JWTGeneratorMock{}
 //
 //         // make and configure a mocked JWTGenerator
 //         mockedJWTGenerator := &JWTGeneratorMock{
-//             GenerateFunc: func(email string, userClaims map[string]interface{}) (string, error) {
+//             GenerateFunc: func(userID string, email string, userClaims map[string]interface{}) (string, error) {
 // 	               panic("mock out the Generate method")
 //             },
+//             VerifyFunc: func(token string) (string, error) {
+// 	               panic("mock out the Verify method")
+//             },
 //         }
 //
 //         // use mockedJWTGenerator in code that requires JWTGenerator
@@ -32,46 +36,60 @@ var _ JWTGenerator = &JWTGeneratorMock{}
 //     }
 type JWTGeneratorMock struct {
 	// GenerateFunc mocks the Generate method.
-	GenerateFunc func(email string, userClaims map[string]interface{}) (string, error)
+	GenerateFunc func(userID string, email string, userClaims map[string]interface{}) (string, error)
+
+	// VerifyFunc mocks the Verify method.
+	VerifyFunc func(token string) (string, error)
 
 	// calls tracks calls to the methods.
 	calls struct {
 		// Generate holds details about calls to the Generate method.
 		Generate []struct {
+			// UserID is the userID argument value.
+			UserID string
 			// Email is the email argument value.
 			Email string
 			// UserClaims is the userClaims argument value.
 			UserClaims map[string]interface{}
 		}
+		// Verify holds details about calls to the Verify method.
+		Verify []struct {
+			// Token is the token argument value.
+			Token string
+		}
 	}
 }
 
 // Generate calls GenerateFunc.
-func (mock *JWTGeneratorMock) Generate(email string, userClaims map[string]interface{}) (string, error) {
+func (mock *JWTGeneratorMock) Generate(userID string, email string, userClaims map[string]interface{}) (string, error) {
 	if mock.GenerateFunc == nil {
 		panic("JWTGeneratorMock.GenerateFunc: method is nil but JWTGenerator.Generate was just called")
 	}
 	callInfo := struct {
+		UserID     string
 		Email      string
 		UserClaims map[string]interface{}
 	}{
+		UserID:     userID,
 		Email:      email,
 		UserClaims: userClaims,
 	}
 	lockJWTGeneratorMockGenerate.Lock()
 	mock.calls.Generate = append(mock.calls.Generate, callInfo)
 	lockJWTGeneratorMockGenerate.Unlock()
-	return mock.GenerateFunc(email, userClaims)
+	return mock.GenerateFunc(userID, email, userClaims)
 }
 
 // GenerateCalls gets all the calls that were made to Generate.
 // Check the length with:
 //     len(mockedJWTGenerator.GenerateCalls())
 func (mock *JWTGeneratorMock) GenerateCalls() []struct {
+	UserID     string
 	Email      string
 	UserClaims map[string]interface{}
 } {
 	var calls []struct {
+		UserID     string
 		Email      string
 		UserClaims map[string]interface{}
 	}
@@ -80,3 +98,34 @@ func (mock *JWTGeneratorMock) GenerateCalls() []struct {
 	lockJWTGeneratorMockGenerate.RUnlock()
 	return calls
 }
+
+// Verify calls VerifyFunc.
+func (mock *JWTGeneratorMock) Verify(token string) (string, error) {
+	if mock.VerifyFunc == nil {
+		panic("JWTGeneratorMock.VerifyFunc: method is nil but JWTGenerator.Verify was just called")
+	}
+	callInfo := struct {
+		Token string
+	}{
+		Token: token,
+	}
+	lockJWTGeneratorMockVerify.Lock()
+	mock.calls.Verify = append(mock.calls.Verify, callInfo)
+	lockJWTGeneratorMockVerify.Unlock()
+	return mock.VerifyFunc(token)
+}
+
+// VerifyCalls gets all the calls that were made to Verify.
+// Check the length with:
+//     len(mockedJWTGenerator.VerifyCalls())
+func (mock *JWTGeneratorMock) VerifyCalls() []struct {
+	Token string
+} {
+	var calls []struct {
+		Token string
+	}
+	lockJWTGeneratorMockVerify.RLock()
+	calls = mock.calls.Verify
+	lockJWTGeneratorMockVerify.RUnlock()
+	return calls
+}
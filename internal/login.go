@@ -0,0 +1,119 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+const (
+	LoginFactorPassword     = "password"
+	LoginFactorTOTP         = "totp"
+	LoginFactorRecoveryCode = "recovery_code"
+	LoginFactorWebAuthn     = "webauthn"
+	LoginFactorMagicLink    = "magic_link"
+	LoginFactorLoginCode    = "login_code"
+)
+
+// Client describes the client which attempts to login. It will be recorded in the login history of the user.
+type Client struct {
+	IP        string
+	UserAgent string
+}
+
+// Login is the representation of a recorded login attempt for use in internal
+type Login struct {
+	CreatedAt time.Time
+	IP        string
+	UserAgent string
+	// Outcome is 'success', 'mfa_required', 'password_expired' or 'failed'
+	Outcome string
+	// Factor is one of the LoginFactor* constants
+	Factor string
+}
+
+// LoginHistory is a page of the login history of a user
+type LoginHistory struct {
+	Logins []Login
+	// Total is the count of all recorded login attempts of the user
+	Total int
+}
+
+// Logins returns 'limit' recorded login attempts of the user with the given id or login identifier starting at
+// 'offset'. The newest attempt comes first.
+// return ErrUserNotFound when user does not exist
+func (p Provider) Logins(idOrIdentifier string, limit, offset int) (LoginHistory, error) {
+	dbUser, err := p.findUser(idOrIdentifier)
+	if err != nil {
+		return LoginHistory{}, err
+	}
+
+	logins, total, err := p.Storage.Logins(dbUser.ID, limit, offset)
+	if err != nil {
+		return LoginHistory{}, fmt.Errorf("failed to query logins of user %q: %w", dbUser.ID, err)
+	}
+
+	history := LoginHistory{Logins: []Login{}, Total: total}
+	for _, l := range logins {
+		history.Logins = append(history.Logins, Login{
+			CreatedAt: l.CreatedAt,
+			IP:        l.IP,
+			UserAgent: l.UserAgent,
+			Outcome:   l.Outcome,
+			Factor:    l.Factor,
+		})
+	}
+
+	return history, nil
+}
+
+// recordLogin records a login attempt of the user with the given id with the given factor. The outcome will be
+// derived from the given error. Attempts of unknown users (empty id) will not be recorded. Recording is best effort,
+// failures will be logged only and never fail the login.
+func (p Provider) recordLogin(userID string, c Client, factor string, mfaRequired bool, err error) {
+	if userID == "" {
+		return
+	}
+
+	outcome := storage.LoginOutcomeSuccess
+	switch {
+	case errors.Is(err, ErrPasswordExpired):
+		outcome = storage.LoginOutcomePasswordExpired
+	case err != nil:
+		outcome = storage.LoginOutcomeFailed
+	case mfaRequired:
+		outcome = storage.LoginOutcomeMFARequired
+	}
+
+	err = p.Storage.AddLogin(storage.Login{
+		UserID:    userID,
+		CreatedAt: nowFunc(),
+		IP:        c.IP,
+		UserAgent: c.UserAgent,
+		Outcome:   outcome,
+		Factor:    factor,
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("userID", userID).Error("Failed to record login")
+	}
+}
+
+// PurgeLoginHistory deletes all login attempts of all users which are older than LoginHistoryRetention and returns the
+// count of deleted attempts. Nothing will be deleted when LoginHistoryRetention is 0.
+func (p Provider) PurgeLoginHistory() (int64, error) {
+	if p.LoginHistoryRetention <= 0 {
+		return 0, nil
+	}
+
+	count, err := p.Storage.DeleteLoginsCreatedBefore(nowFunc().Add(-p.LoginHistoryRetention))
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge login history: %w", err)
+	}
+
+	if count > 0 {
+		logrus.WithField("count", count).Info("Login history purged")
+	}
+	return count, nil
+}
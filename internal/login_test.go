@@ -0,0 +1,223 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProvider_Logins(t *testing.T) {
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		givenUser       string
+		dbUserError     error
+		dbLoginsError   error
+		expectedHistory LoginHistory
+		expectedError   error
+	}{
+		{
+			name:      "Happycase",
+			givenUser: "test@test.test",
+			expectedHistory: LoginHistory{
+				Logins: []Login{{
+					CreatedAt: now,
+					IP:        "127.0.0.1",
+					UserAgent: "curl/7.68.0",
+					Outcome:   storage.LoginOutcomeSuccess,
+					Factor:    LoginFactorPassword,
+				}},
+				Total: 3,
+			},
+		}, {
+			name:          "User not found",
+			givenUser:     "test@test.test",
+			dbUserError:   storage.ErrUserNotFound,
+			expectedError: ErrUserNotFound,
+		}, {
+			name:          "Some db error",
+			givenUser:     "test@test.test",
+			dbLoginsError: errors.New("nope"),
+			expectedError: errors.New(`failed to query logins of user "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b": nope`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenLimit, givenOffset int
+			toTest := Provider{
+				Storage: &StorageMock{
+					UserFunc: func(email string) (storage.User, error) {
+						return storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", EMail: email}, tt.dbUserError
+					},
+					LoginsFunc: func(userID string, limit int, offset int) ([]storage.Login, int, error) {
+						givenLimit, givenOffset = limit, offset
+						return []storage.Login{{
+							ID:        1,
+							UserID:    userID,
+							CreatedAt: now,
+							IP:        "127.0.0.1",
+							UserAgent: "curl/7.68.0",
+							Outcome:   storage.LoginOutcomeSuccess,
+							Factor:    LoginFactorPassword,
+						}}, 3, tt.dbLoginsError
+					},
+				},
+			}
+
+			history, err := toTest.Logins(tt.givenUser, 10, 20)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:%s\nGiven:%s", tt.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(history, tt.expectedHistory) {
+				t.Errorf("Login history is not as expected: \nExpected:%#v\nGiven:%#v", tt.expectedHistory, history)
+			}
+
+			if err == nil && (givenLimit != 10 || givenOffset != 20) {
+				t.Errorf("Unexpected paging. Limit: %d, Offset: %d", givenLimit, givenOffset)
+			}
+		})
+	}
+}
+
+func TestProvider_recordLogin(t *testing.T) {
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	nowFunc = func() time.Time { return now }
+
+	tests := []struct {
+		name          string
+		givenUserID   string
+		givenError    error
+		dbError       error
+		expectedLogin *storage.Login
+	}{
+		{
+			name:        "Success",
+			givenUserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			expectedLogin: &storage.Login{
+				UserID:    "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				CreatedAt: now,
+				IP:        "127.0.0.1",
+				UserAgent: "curl/7.68.0",
+				Outcome:   storage.LoginOutcomeSuccess,
+				Factor:    LoginFactorPassword,
+			},
+		},
+		{
+			name:        "Failed",
+			givenUserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			givenError:  ErrIncorrectPassword,
+			expectedLogin: &storage.Login{
+				UserID:    "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				CreatedAt: now,
+				IP:        "127.0.0.1",
+				UserAgent: "curl/7.68.0",
+				Outcome:   storage.LoginOutcomeFailed,
+				Factor:    LoginFactorPassword,
+			},
+		},
+		{
+			name:        "Db error will be ignored",
+			givenUserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			dbError:     errors.New("nope"),
+			expectedLogin: &storage.Login{
+				UserID:    "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				CreatedAt: now,
+				IP:        "127.0.0.1",
+				UserAgent: "curl/7.68.0",
+				Outcome:   storage.LoginOutcomeSuccess,
+				Factor:    LoginFactorPassword,
+			},
+		},
+		{
+			name:       "Unknown user",
+			givenError: ErrNoValidTokenFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenLogin *storage.Login
+			toTest := Provider{
+				Storage: &StorageMock{
+					AddLoginFunc: func(l storage.Login) error {
+						givenLogin = &l
+						return tt.dbError
+					},
+				},
+			}
+
+			toTest.recordLogin(tt.givenUserID, Client{IP: "127.0.0.1", UserAgent: "curl/7.68.0"}, LoginFactorPassword, false, tt.givenError)
+
+			if !reflect.DeepEqual(givenLogin, tt.expectedLogin) {
+				t.Errorf("Recorded login is not as expected: \nExpected:%#v\nGiven:%#v", tt.expectedLogin, givenLogin)
+			}
+		})
+	}
+}
+
+func TestProvider_PurgeLoginHistory(t *testing.T) {
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+
+	tests := []struct {
+		name                  string
+		retention             time.Duration
+		dbDeleteError         error
+		expectedCreatedBefore time.Time
+		expectedCount         int64
+		expectedError         error
+	}{
+		{
+			name:                  "Happycase",
+			retention:             2160 * time.Hour,
+			expectedCreatedBefore: now.Add(-2160 * time.Hour),
+			expectedCount:         3,
+		}, {
+			name: "Retention disabled",
+		}, {
+			name:                  "Unexpected db error",
+			retention:             2160 * time.Hour,
+			dbDeleteError:         errors.New("nope"),
+			expectedCreatedBefore: now.Add(-2160 * time.Hour),
+			expectedError:         errors.New("failed to purge login history: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var createdBefore time.Time
+			toTest := Provider{
+				LoginHistoryRetention: tt.retention,
+				Storage: &StorageMock{
+					DeleteLoginsCreatedBeforeFunc: func(before time.Time) (int64, error) {
+						createdBefore = before
+						return 3, tt.dbDeleteError
+					},
+				},
+			}
+
+			count, err := toTest.PurgeLoginHistory()
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:%s\nGiven:%s", tt.expectedError, err)
+			}
+
+			if count != tt.expectedCount {
+				t.Errorf("Purged count is not as expected. Expected: %d, Given: %d", tt.expectedCount, count)
+			}
+
+			if !createdBefore.Equal(tt.expectedCreatedBefore) {
+				t.Errorf("Purged login attempts are not as expected. Expected created before: %s, Given: %s", tt.expectedCreatedBefore, createdBefore)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+)
+
+const loginCodeDigits = 6
+
+var ErrLoginCodeNotConfigured = errors.New("login code login is not configured")
+var ErrInvalidLoginCode = errors.New("invalid login code")
+
+// CreateLoginCode sends a login-code mail with a numeric one-time code to the given address. The code is valid for
+// LoginCodeLifetime and LoginCodeMaxAttempts attempts and has to be redeemed via LoginWithCode. Previously sent codes
+// will be invalidated.
+// return ErrLoginCodeNotConfigured when LoginCodeLifetime is 0
+// return ErrUserNotFound when user does not exists
+func (p Provider) CreateLoginCode(email string) error {
+	email, err := p.normalizeEMail(email)
+	if err != nil {
+		return err
+	}
+
+	if p.LoginCodeLifetime <= 0 {
+		return ErrLoginCodeNotConfigured
+	}
+
+	u, err := queryUser(p.Storage.User, "email", email)
+	if err != nil {
+		return err
+	}
+
+	err = p.revokeTokens(u.ID, storage.TokenTypeLoginCode)
+	if err != nil {
+		return err
+	}
+
+	code, err := p.issueToken(u.ID, storage.TokenTypeLoginCode, nil)
+	if err != nil {
+		return err
+	}
+
+	err = p.Mailer.SendLoginCodeEMail(email, code, u.Claims, u.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to send login-code-email: %w", err)
+	}
+
+	return nil
+}
+
+// LoginWithCode redeems the given login code (sent by CreateLoginCode) in place of the password and returns a new jwt.
+// Each attempt counts, the code will be invalidated after LoginCodeMaxAttempts attempts or a successful login. When the
+// user has enabled totp or webauthn, a mfa challenge token will be returned instead like on Login. The attempt will be
+// recorded in the login history of the user.
+// return ErrLoginCodeNotConfigured when LoginCodeLifetime is 0
+// return ErrNoValidTokenFound when there is no valid login code
+// return ErrInvalidLoginCode when the login code does not match
+func (p Provider) LoginWithCode(email, code string, client Client) (result LoginResult, err error) {
+	email, err = p.normalizeEMail(email)
+	if err != nil {
+		return LoginResult{}, err
+	}
+
+	if p.LoginCodeLifetime <= 0 {
+		return LoginResult{}, ErrLoginCodeNotConfigured
+	}
+
+	u, err := p.challengedUser(email)
+	if err != nil {
+		return LoginResult{}, err
+	}
+	defer func() { p.recordLogin(u.ID, client, LoginFactorLoginCode, result.MFAToken != "", err) }()
+
+	_, err = p.redeemToken(u.ID, code, storage.TokenTypeLoginCode)
+	if err != nil {
+		if errors.Is(err, ErrInvalidTokenCode) {
+			return LoginResult{}, ErrInvalidLoginCode
+		}
+		return LoginResult{}, err
+	}
+
+	return p.passwordlessLogin(u, amrOTP)
+}
@@ -0,0 +1,270 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestProvider_CreateLoginCode(t *testing.T) {
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+
+	tests := []struct {
+		name                     string
+		lifetime                 time.Duration
+		dbUserReturnError        error
+		dbTokens                 []storage.Token
+		dbCreateTokenReturnError error
+		mailerError              error
+		expectedDeletedTokens    []int64
+		expectedToken            bool
+		expectedMail             bool
+		expectedError            error
+	}{
+		{
+			name:          "Happycase",
+			lifetime:      5 * time.Minute,
+			expectedToken: true,
+			expectedMail:  true,
+		}, {
+			name:                  "Happycase with previous codes",
+			lifetime:              5 * time.Minute,
+			dbTokens:              []storage.Token{{ID: 1}, {ID: 2}},
+			expectedDeletedTokens: []int64{1, 2},
+			expectedToken:         true,
+			expectedMail:          true,
+		}, {
+			name:          "Login code not configured",
+			expectedError: ErrLoginCodeNotConfigured,
+		}, {
+			name:              "User not found",
+			lifetime:          5 * time.Minute,
+			dbUserReturnError: storage.ErrUserNotFound,
+			expectedError:     ErrUserNotFound,
+		}, {
+			name:                     "Unexpected db error while create token",
+			lifetime:                 5 * time.Minute,
+			dbCreateTokenReturnError: errors.New("nope"),
+			expectedToken:            true,
+			expectedError:            errors.New("failed to create login-code-token for user \"c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b\": nope"),
+		}, {
+			name:          "Mailer error",
+			lifetime:      5 * time.Minute,
+			mailerError:   errors.New("nope"),
+			expectedToken: true,
+			expectedMail:  true,
+			expectedError: errors.New("failed to send login-code-email: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var createdToken *storage.Token
+			var mailedCode string
+			var deletedTokens []int64
+			mailerMock := &MailerMock{
+				SendLoginCodeEMailFunc: func(recipient string, loginCode string, claims map[string]interface{}, metadata map[string]interface{}) error {
+					mailedCode = loginCode
+					return tt.mailerError
+				},
+			}
+			toTest := Provider{
+				LoginCodeLifetime: tt.lifetime,
+				Storage: &StorageMock{
+					UserFunc: func(email string) (storage.User, error) {
+						return storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", EMail: email}, tt.dbUserReturnError
+					},
+					TokensByUserIDAndTypeFunc: func(userID string, tokenType string) ([]storage.Token, error) {
+						if tokenType != storage.TokenTypeLoginCode {
+							t.Errorf("Unexpected token type %q", tokenType)
+						}
+						return tt.dbTokens, nil
+					},
+					DeleteTokenFunc: func(id int64) error {
+						deletedTokens = append(deletedTokens, id)
+						return nil
+					},
+					CreateTokenFunc: func(t storage.Token) (int64, error) {
+						createdToken = &t
+						return 1, tt.dbCreateTokenReturnError
+					},
+				},
+				Mailer:      mailerMock,
+				TokenHasher: testTokenHasher,
+			}
+
+			err := toTest.CreateLoginCode("test@test.test")
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(deletedTokens, tt.expectedDeletedTokens) {
+				t.Errorf("Deleted tokens are not as expected. Expected: %v, Given: %v", tt.expectedDeletedTokens, deletedTokens)
+			}
+
+			if (createdToken != nil) != tt.expectedToken {
+				t.Fatalf("Unexpected token creation: %#v", createdToken)
+			}
+			if createdToken != nil && (createdToken.Type != storage.TokenTypeLoginCode || !createdToken.CreatedAt.Equal(now)) {
+				t.Errorf("Created token is not as expected: %#v", createdToken)
+			}
+
+			if (len(mailerMock.SendLoginCodeEMailCalls()) == 1) != tt.expectedMail {
+				t.Fatalf("Unexpected count of SendLoginCodeEMail calls: %d", len(mailerMock.SendLoginCodeEMailCalls()))
+			}
+			if tt.expectedMail {
+				if !regexp.MustCompile(`^[0-9]{6}$`).MatchString(mailedCode) {
+					t.Errorf("Mailed login code has an unexpected format: %q", mailedCode)
+				}
+				if createdToken.Token != "hashed-"+mailedCode {
+					t.Errorf("Stored token is not the hash of the mailed code")
+				}
+			}
+		})
+	}
+}
+
+func TestProvider_LoginWithCode(t *testing.T) {
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+
+	validToken := storage.Token{ID: 42, UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Type: storage.TokenTypeLoginCode, Token: "hashed-123456", CreatedAt: now.Add(-time.Minute)}
+
+	tests := []struct {
+		name                string
+		lifetime            time.Duration
+		givenCode           string
+		dbTokens            []storage.Token
+		dbAttempts          int
+		dbAttemptsError     error
+		expectedTokenDelete bool
+		expectedResult      LoginResult
+		expectedError       error
+	}{
+		{
+			name:                "Happycase",
+			lifetime:            5 * time.Minute,
+			givenCode:           "123456",
+			dbTokens:            []storage.Token{validToken},
+			dbAttempts:          1,
+			expectedTokenDelete: true,
+			expectedResult:      LoginResult{AccessToken: "myJWT"},
+		}, {
+			name:          "Login code not configured",
+			givenCode:     "123456",
+			expectedError: ErrLoginCodeNotConfigured,
+		}, {
+			name:          "No login code",
+			lifetime:      5 * time.Minute,
+			givenCode:     "123456",
+			expectedError: ErrNoValidTokenFound,
+		}, {
+			name:      "Login code expired",
+			lifetime:  5 * time.Minute,
+			givenCode: "123456",
+			dbTokens: []storage.Token{
+				{ID: 42, Type: storage.TokenTypeLoginCode, Token: "hashed-123456", CreatedAt: now.Add(-10 * time.Minute)},
+			},
+			expectedError: ErrNoValidTokenFound,
+		}, {
+			name:          "Invalid code",
+			lifetime:      5 * time.Minute,
+			givenCode:     "654321",
+			dbTokens:      []storage.Token{validToken},
+			dbAttempts:    1,
+			expectedError: ErrInvalidLoginCode,
+		}, {
+			name:                "Invalid code with last attempt",
+			lifetime:            5 * time.Minute,
+			givenCode:           "654321",
+			dbTokens:            []storage.Token{validToken},
+			dbAttempts:          3,
+			expectedTokenDelete: true,
+			expectedError:       ErrInvalidLoginCode,
+		}, {
+			name:                "Too many attempts",
+			lifetime:            5 * time.Minute,
+			givenCode:           "123456",
+			dbTokens:            []storage.Token{validToken},
+			dbAttempts:          4,
+			expectedTokenDelete: true,
+			expectedError:       ErrNoValidTokenFound,
+		}, {
+			name:            "Login code deleted concurrently",
+			lifetime:        5 * time.Minute,
+			givenCode:       "123456",
+			dbTokens:        []storage.Token{validToken},
+			dbAttemptsError: storage.ErrTokenNotFound,
+			expectedError:   ErrNoValidTokenFound,
+		}, {
+			name:            "Unexpected db error while counting attempt",
+			lifetime:        5 * time.Minute,
+			givenCode:       "123456",
+			dbTokens:        []storage.Token{validToken},
+			dbAttemptsError: errors.New("nope"),
+			expectedError:   errors.New("failed to count login-code attempt: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storageMock := &StorageMock{
+				TokensByUserIDAndTypeFunc: func(userID string, tokenType string) ([]storage.Token, error) {
+					return tt.dbTokens, nil
+				},
+				IncrementTokenAttemptsFunc: func(id int64) (int, error) {
+					return tt.dbAttempts, tt.dbAttemptsError
+				},
+				DeleteTokenFunc: func(id int64) error {
+					return nil
+				},
+				UserFunc: func(email string) (storage.User, error) {
+					return storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", EMail: email}, nil
+				},
+				AddLoginFunc: func(l storage.Login) error {
+					if l.Factor != LoginFactorLoginCode {
+						t.Errorf("Unexpected login factor %q", l.Factor)
+					}
+					return nil
+				},
+				TOTPFunc: func(userID string) (storage.TOTP, error) {
+					return storage.TOTP{}, storage.ErrTOTPNotFound
+				},
+			}
+			toTest := Provider{
+				LoginCodeLifetime:    tt.lifetime,
+				LoginCodeMaxAttempts: 3,
+				Storage:              storageMock,
+				TokenHasher:          testTokenHasher,
+				JWTGenerator: &JWTGeneratorMock{
+					GenerateFunc: func(userID string, email string, userClaims map[string]interface{}) (string, error) {
+						return "myJWT", nil
+					},
+				},
+			}
+
+			result, err := toTest.LoginWithCode("test@test.test", tt.givenCode, Client{})
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(result, tt.expectedResult) {
+				t.Errorf("Login result is not as expected. Expected:\n%#v\nGiven:\n%#v", tt.expectedResult, result)
+			}
+
+			deleteCalls := storageMock.DeleteTokenCalls()
+			if (len(deleteCalls) == 1) != tt.expectedTokenDelete {
+				t.Errorf("Unexpected count of DeleteToken calls: %d", len(deleteCalls))
+			}
+		})
+	}
+}
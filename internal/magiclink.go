@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+)
+
+var ErrMagicLinkNotConfigured = errors.New("magic link login is not configured")
+
+// CreateMagicLink sends a magic-link mail with a one-time login token to the given address. The token is valid for
+// MagicLinkLifetime and has to be redeemed via LoginMagicLink.
+// return ErrMagicLinkNotConfigured when MagicLinkLifetime is 0
+// return ErrUserNotFound when user does not exists
+func (p Provider) CreateMagicLink(email string) error {
+	email, err := p.normalizeEMail(email)
+	if err != nil {
+		return err
+	}
+
+	if p.MagicLinkLifetime <= 0 {
+		return ErrMagicLinkNotConfigured
+	}
+
+	u, err := queryUser(p.Storage.User, "email", email)
+	if err != nil {
+		return err
+	}
+
+	t, err := p.issueToken(u.ID, storage.TokenTypeMagicLink, nil)
+	if err != nil {
+		return err
+	}
+
+	err = p.Mailer.SendMagicLinkEMail(email, t, u.Claims, u.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to send magic-link-email: %w", err)
+	}
+
+	return nil
+}
+
+// LoginMagicLink redeems the given magic link token (issued by CreateMagicLink) in place of the password and returns a
+// new jwt. The token can be used once. When the user has enabled totp or webauthn, a mfa challenge token will be
+// returned instead like on Login. The attempt will be recorded in the login history of the user.
+// return ErrMagicLinkNotConfigured when MagicLinkLifetime is 0
+// return ErrNoValidTokenFound when the token is unknown or expired
+func (p Provider) LoginMagicLink(email, magicLinkToken string, client Client) (result LoginResult, err error) {
+	email, err = p.normalizeEMail(email)
+	if err != nil {
+		return LoginResult{}, err
+	}
+
+	if p.MagicLinkLifetime <= 0 {
+		return LoginResult{}, ErrMagicLinkNotConfigured
+	}
+
+	u, err := p.challengedUser(email)
+	if err != nil {
+		return LoginResult{}, err
+	}
+	defer func() { p.recordLogin(u.ID, client, LoginFactorMagicLink, result.MFAToken != "", err) }()
+
+	_, err = p.redeemToken(u.ID, magicLinkToken, storage.TokenTypeMagicLink)
+	if err != nil {
+		return LoginResult{}, err
+	}
+
+	return p.passwordlessLogin(u, amrMail)
+}
+
+// passwordlessLogin completes a login of the given user which has been authenticated without password (magic link,
+// login code) by the given first factor ('amr' value). When the user has enabled totp or webauthn, a mfa challenge
+// token will be returned instead of the jwt.
+func (p Provider) passwordlessLogin(u storage.User, firstFactor string) (LoginResult, error) {
+	mfaMethods, err := p.mfaMethods(u.ID)
+	if err != nil {
+		return LoginResult{}, err
+	}
+
+	if len(mfaMethods) > 0 {
+		mfaToken, err := p.issueMFAToken(u.ID, firstFactor)
+		if err != nil {
+			return LoginResult{}, err
+		}
+
+		return LoginResult{MFAToken: mfaToken, MFAMethods: mfaMethods}, nil
+	}
+
+	jwt, err := p.JWTGenerator.Generate(u.ID, u.EMail, u.Claims)
+	if err != nil {
+		return LoginResult{}, err
+	}
+
+	return LoginResult{AccessToken: jwt}, nil
+}
@@ -0,0 +1,304 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"github.com/leberKleber/simple-jwt-provider/internal/totp"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProvider_CreateMagicLink(t *testing.T) {
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+
+	tests := []struct {
+		name                     string
+		lifetime                 time.Duration
+		dbUserReturnError        error
+		dbCreateTokenReturnError error
+		mailerError              error
+		expectedToken            *storage.Token
+		expectedMail             bool
+		expectedError            error
+	}{
+		{
+			name:          "Happycase",
+			lifetime:      15 * time.Minute,
+			expectedToken: &storage.Token{UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Type: storage.TokenTypeMagicLink, CreatedAt: now},
+			expectedMail:  true,
+		}, {
+			name:          "Magic link not configured",
+			expectedError: ErrMagicLinkNotConfigured,
+		}, {
+			name:              "User not found",
+			lifetime:          15 * time.Minute,
+			dbUserReturnError: storage.ErrUserNotFound,
+			expectedError:     ErrUserNotFound,
+		}, {
+			name:              "Unexpected db error while finding user",
+			lifetime:          15 * time.Minute,
+			dbUserReturnError: errors.New("nope"),
+			expectedError:     errors.New("failed to query user with email \"test@test.test\": nope"),
+		}, {
+			name:                     "Unexpected db error while create token",
+			lifetime:                 15 * time.Minute,
+			dbCreateTokenReturnError: errors.New("nope"),
+			expectedToken:            &storage.Token{UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Type: storage.TokenTypeMagicLink, CreatedAt: now},
+			expectedError:            errors.New("failed to create magic-link-token for user \"c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b\": nope"),
+		}, {
+			name:          "Mailer error",
+			lifetime:      15 * time.Minute,
+			mailerError:   errors.New("nope"),
+			expectedToken: &storage.Token{UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Type: storage.TokenTypeMagicLink, CreatedAt: now},
+			expectedMail:  true,
+			expectedError: errors.New("failed to send magic-link-email: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var createdToken *storage.Token
+			var mailedToken string
+			mailerMock := &MailerMock{
+				SendMagicLinkEMailFunc: func(recipient string, magicLinkToken string, claims map[string]interface{}, metadata map[string]interface{}) error {
+					mailedToken = magicLinkToken
+					return tt.mailerError
+				},
+			}
+			toTest := Provider{
+				MagicLinkLifetime: tt.lifetime,
+				Storage: &StorageMock{
+					UserFunc: func(email string) (storage.User, error) {
+						return storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", EMail: email, Metadata: map[string]interface{}{"firstName": "Alice"}}, tt.dbUserReturnError
+					},
+					CreateTokenFunc: func(t storage.Token) (int64, error) {
+						createdToken = &t
+						return 1, tt.dbCreateTokenReturnError
+					},
+				},
+				Mailer:      mailerMock,
+				TokenHasher: testTokenHasher,
+			}
+
+			err := toTest.CreateMagicLink("test@test.test")
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			if createdToken != nil {
+				if !strings.HasPrefix(createdToken.Token, "hashed-") || len(createdToken.Token) != 71 {
+					t.Errorf("Created token is not a hashed 64 char token: %q", createdToken.Token)
+				}
+				if tt.expectedMail && "hashed-"+mailedToken != createdToken.Token {
+					t.Errorf("Mailed token is not the created one. Mailed: %q, Created: %q", mailedToken, createdToken.Token)
+				}
+				createdToken.Token = ""
+			}
+
+			if !reflect.DeepEqual(createdToken, tt.expectedToken) {
+				t.Errorf("Created token is not as expected. Expected:\n%#v\nGiven:\n%#v", tt.expectedToken, createdToken)
+			}
+
+			if (len(mailerMock.SendMagicLinkEMailCalls()) == 1) != tt.expectedMail {
+				t.Fatalf("Unexpected count of SendMagicLinkEMail calls: %d", len(mailerMock.SendMagicLinkEMailCalls()))
+			}
+			if tt.expectedMail && mailerMock.SendMagicLinkEMailCalls()[0].Metadata["firstName"] != "Alice" {
+				t.Errorf("Mailed metadata is not as expected: %#v", mailerMock.SendMagicLinkEMailCalls()[0].Metadata)
+			}
+		})
+	}
+}
+
+func TestProvider_LoginMagicLink(t *testing.T) {
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+
+	validToken := storage.Token{ID: 42, UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Token: "hashed-myMagicLinkToken", Type: storage.TokenTypeMagicLink, CreatedAt: now.Add(-10 * time.Minute)}
+
+	tests := []struct {
+		name                string
+		lifetime            time.Duration
+		dbTokens            []storage.Token
+		dbTOTP              storage.TOTP
+		expectedTokenDelete bool
+		expectedResult      LoginResult
+		expectedMFAToken    bool
+		expectedError       error
+	}{
+		{
+			name:                "Happycase",
+			lifetime:            15 * time.Minute,
+			dbTokens:            []storage.Token{validToken},
+			expectedTokenDelete: true,
+			expectedResult:      LoginResult{AccessToken: "myJWT"},
+		}, {
+			name:                "Happycase with mfa",
+			lifetime:            15 * time.Minute,
+			dbTokens:            []storage.Token{validToken},
+			dbTOTP:              storage.TOTP{UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Confirmed: true},
+			expectedTokenDelete: true,
+			expectedResult:      LoginResult{MFAMethods: []string{MFAMethodTOTP}},
+			expectedMFAToken:    true,
+		}, {
+			name:          "Magic link not configured",
+			dbTokens:      []storage.Token{validToken},
+			expectedError: ErrMagicLinkNotConfigured,
+		}, {
+			name:          "Token expired",
+			lifetime:      5 * time.Minute,
+			dbTokens:      []storage.Token{validToken},
+			expectedError: ErrTokenExpired,
+		}, {
+			name:     "Token of other type",
+			lifetime: 15 * time.Minute,
+			dbTokens: []storage.Token{
+				{ID: 42, UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Token: "hashed-myMagicLinkToken", Type: storage.TokenTypeReset, CreatedAt: now},
+			},
+			expectedError: ErrNoValidTokenFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storageMock := &StorageMock{
+				TokensByUserIDAndTypeFunc: func(userID string, tokenType string) ([]storage.Token, error) {
+					return tokensOfType(tt.dbTokens, tokenType), nil
+				},
+				DeleteTokenFunc: func(id int64) error {
+					return nil
+				},
+				UserFunc: func(email string) (storage.User, error) {
+					return storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", EMail: email}, nil
+				},
+				AddLoginFunc: func(l storage.Login) error {
+					if l.Factor != LoginFactorMagicLink {
+						t.Errorf("Unexpected login factor %q", l.Factor)
+					}
+					return nil
+				},
+				TOTPFunc: func(userID string) (storage.TOTP, error) {
+					return tt.dbTOTP, nil
+				},
+				CreateTokenFunc: func(t storage.Token) (int64, error) {
+					return 43, nil
+				},
+			}
+			toTest := Provider{
+				MagicLinkLifetime: tt.lifetime,
+				MFATokenLifetime:  time.Minute,
+				Storage:           storageMock,
+				TokenHasher:       testTokenHasher,
+				JWTGenerator: &JWTGeneratorMock{
+					GenerateFunc: func(userID string, email string, userClaims map[string]interface{}) (string, error) {
+						return "myJWT", nil
+					},
+				},
+			}
+
+			result, err := toTest.LoginMagicLink("test@test.test", "myMagicLinkToken", Client{})
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			if (result.MFAToken != "") != tt.expectedMFAToken {
+				t.Errorf("Unexpected mfa token: %q", result.MFAToken)
+			}
+			result.MFAToken = ""
+
+			if !reflect.DeepEqual(result, tt.expectedResult) {
+				t.Errorf("Login result is not as expected. Expected:\n%#v\nGiven:\n%#v", tt.expectedResult, result)
+			}
+
+			deleteCalls := storageMock.DeleteTokenCalls()
+			if (len(deleteCalls) == 1) != tt.expectedTokenDelete {
+				t.Errorf("Unexpected count of DeleteToken calls: %d", len(deleteCalls))
+			}
+		})
+	}
+}
+
+func TestProvider_LoginMagicLinkFollowedByTOTP(t *testing.T) {
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+
+	secret := []byte("12345678901234567890")
+	tokens := []storage.Token{
+		{ID: 42, UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Token: "hashed-myMagicLinkToken", Type: storage.TokenTypeMagicLink, CreatedAt: now.Add(-time.Minute)},
+	}
+
+	var givenClaims map[string]interface{}
+	toTest := Provider{
+		MagicLinkLifetime: 15 * time.Minute,
+		MFATokenLifetime:  5 * time.Minute,
+		TOTPCrypter:       testCrypter,
+		TokenHasher:       testTokenHasher,
+		Storage: &StorageMock{
+			UserFunc: func(email string) (storage.User, error) {
+				return storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", EMail: email}, nil
+			},
+			TokensByUserIDAndTypeFunc: func(userID string, tokenType string) ([]storage.Token, error) {
+				return tokensOfType(tokens, tokenType), nil
+			},
+			CreateTokenFunc: func(t storage.Token) (int64, error) {
+				t.ID = int64(len(tokens) + 42)
+				tokens = append(tokens, t)
+				return t.ID, nil
+			},
+			DeleteTokenFunc: func(id int64) error {
+				for i, t := range tokens {
+					if t.ID == id {
+						tokens = append(tokens[:i], tokens[i+1:]...)
+						return nil
+					}
+				}
+				return storage.ErrTokenNotFound
+			},
+			TOTPFunc: func(userID string) (storage.TOTP, error) {
+				return storage.TOTP{UserID: userID, Secret: append([]byte("enc:"), secret...), Confirmed: true}, nil
+			},
+			SaveTOTPFunc: func(t storage.TOTP) error {
+				return nil
+			},
+			AddLoginFunc: func(l storage.Login) error {
+				return nil
+			},
+		},
+		JWTGenerator: &JWTGeneratorMock{
+			GenerateFunc: func(userID string, email string, userClaims map[string]interface{}) (string, error) {
+				givenClaims = userClaims
+				return "myJWT", nil
+			},
+		},
+	}
+
+	result, err := toTest.LoginMagicLink("test@test.test", "myMagicLinkToken", Client{})
+	if err != nil {
+		t.Fatalf("Failed to login with magic link: %s", err)
+	}
+	if result.MFAToken == "" {
+		t.Fatalf("Magic link login did not return a mfa token: %#v", result)
+	}
+
+	jwt, err := toTest.LoginMFA("test@test.test", result.MFAToken, totp.Code(secret, totp.Step(now)), Client{})
+	if err != nil {
+		t.Fatalf("Failed to login with totp: %s", err)
+	}
+	if jwt != "myJWT" {
+		t.Errorf("Given jwt is not as expected. Expected: %q, Given: %q", "myJWT", jwt)
+	}
+
+	expectedAMR := []string{"mail", "otp"}
+	if !reflect.DeepEqual(givenClaims[amrClaim], expectedAMR) {
+		t.Errorf("Unexpected amr claim. Expected: %q, Given: %q", expectedAMR, givenClaims[amrClaim])
+	}
+}
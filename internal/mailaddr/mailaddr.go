@@ -0,0 +1,77 @@
+package mailaddr
+
+import (
+	"errors"
+	"golang.org/x/net/idna"
+	"strings"
+	"unicode"
+)
+
+const (
+	// maxLength is the max length of an address (RFC 5321 4.5.3.1.3)
+	maxLength = 254
+	// maxLocalPartLength is the max length of the local part (RFC 5321 4.5.3.1.1)
+	maxLocalPartLength = 64
+	// maxLabelLength is the max length of a single domain label in its ascii form (RFC 1035 2.3.4)
+	maxLabelLength = 63
+)
+
+var ErrInvalid = errors.New("invalid email address")
+
+// Normalize returns the form of the given address which identifies an account: Surrounding whitespaces will be trimmed,
+// the domain will be lowercased and internationalized domain names will be converted to punycode. The local part will
+// only be lowercased when lowercaseLocalPart is true because it is case-sensitive by specification.
+// return ErrInvalid when the given address is malformed
+func Normalize(address string, lowercaseLocalPart bool) (string, error) {
+	address = strings.TrimSpace(address)
+
+	at := strings.LastIndex(address, "@")
+	if at <= 0 || at == len(address)-1 {
+		return "", ErrInvalid
+	}
+	localPart, domain := address[:at], address[at+1:]
+
+	for _, r := range address {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return "", ErrInvalid
+		}
+	}
+
+	if len(localPart) > maxLocalPartLength {
+		return "", ErrInvalid
+	}
+	if lowercaseLocalPart {
+		localPart = strings.ToLower(localPart)
+	}
+
+	domain, err := normalizeDomain(domain)
+	if err != nil {
+		return "", err
+	}
+
+	normalized := localPart + "@" + domain
+	if len(normalized) > maxLength {
+		return "", ErrInvalid
+	}
+
+	return normalized, nil
+}
+
+// normalizeDomain converts the given domain to the ascii form it will be looked up with: UTS #46 mapping (e.g.
+// lowercasing, full width characters and ideographic full stops), NFC normalization and punycode. So visually identical
+// domains result in the same address.
+func normalizeDomain(domain string) (string, error) {
+	domain, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return "", ErrInvalid
+	}
+
+	domain = strings.TrimSuffix(domain, ".")
+	for _, label := range strings.Split(domain, ".") {
+		if label == "" || len(label) > maxLabelLength {
+			return "", ErrInvalid
+		}
+	}
+
+	return domain, nil
+}
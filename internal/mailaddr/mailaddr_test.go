@@ -0,0 +1,109 @@
+package mailaddr
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name               string
+		givenAddress       string
+		lowercaseLocalPart bool
+		expectedAddress    string
+		expectedError      error
+	}{
+		{
+			name:            "Already normalized",
+			givenAddress:    "alice@example.com",
+			expectedAddress: "alice@example.com",
+		}, {
+			name:            "Trim and lowercase domain",
+			givenAddress:    "  Alice@Example.COM \n",
+			expectedAddress: "Alice@example.com",
+		}, {
+			name:               "Lowercase local part",
+			givenAddress:       "Alice@Example.com",
+			lowercaseLocalPart: true,
+			expectedAddress:    "alice@example.com",
+		}, {
+			name:            "Trailing dot of domain",
+			givenAddress:    "alice@example.com.",
+			expectedAddress: "alice@example.com",
+		}, {
+			name:            "Internationalized domain",
+			givenAddress:    "alice@Bücher.example",
+			expectedAddress: "alice@xn--bcher-kva.example",
+		}, {
+			name:            "Internationalized domain with ideographic full stop",
+			givenAddress:    "alice@例え。テスト",
+			expectedAddress: "alice@xn--r8jz45g.xn--zckzah",
+		}, {
+			name:            "Punycode domain",
+			givenAddress:    "alice@XN--BCHER-KVA.example",
+			expectedAddress: "alice@xn--bcher-kva.example",
+		}, {
+			name:            "Internationalized domain with full width characters",
+			givenAddress:    "alice@ＢÜCHER．example",
+			expectedAddress: "alice@xn--bcher-kva.example",
+		}, {
+			name:            "Internationalized domain in decomposed form",
+			givenAddress:    "alice@bu\u0308cher.example",
+			expectedAddress: "alice@xn--bcher-kva.example",
+		}, {
+			name:            "Quoted local part with at sign",
+			givenAddress:    `"a@b"@example.com`,
+			expectedAddress: `"a@b"@example.com`,
+		}, {
+			name:          "Empty",
+			givenAddress:  "  ",
+			expectedError: ErrInvalid,
+		}, {
+			name:          "Missing at sign",
+			givenAddress:  "alice.example.com",
+			expectedError: ErrInvalid,
+		}, {
+			name:          "Missing local part",
+			givenAddress:  "@example.com",
+			expectedError: ErrInvalid,
+		}, {
+			name:          "Missing domain",
+			givenAddress:  "alice@",
+			expectedError: ErrInvalid,
+		}, {
+			name:          "Empty domain label",
+			givenAddress:  "alice@example..com",
+			expectedError: ErrInvalid,
+		}, {
+			name:          "Disallowed domain character",
+			givenAddress:  "alice@exa$mple.com",
+			expectedError: ErrInvalid,
+		}, {
+			name:          "Whitespace within address",
+			givenAddress:  "alice smith@example.com",
+			expectedError: ErrInvalid,
+		}, {
+			name:          "Local part too long",
+			givenAddress:  strings.Repeat("a", 65) + "@example.com",
+			expectedError: ErrInvalid,
+		}, {
+			name:          "Domain label too long",
+			givenAddress:  "alice@" + strings.Repeat("a", 64) + ".com",
+			expectedError: ErrInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address, err := Normalize(tt.givenAddress, tt.lowercaseLocalPart)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			if address != tt.expectedAddress {
+				t.Errorf("Address is not as expected. Expected: %q, Given: %q", tt.expectedAddress, address)
+			}
+		})
+	}
+}
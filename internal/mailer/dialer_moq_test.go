@@ -9,7 +9,6 @@ import (
 )
 
 var (
-	lockdialerMockDial        sync.RWMutex
 	lockdialerMockDialAndSend sync.RWMutex
 )
 
@@ -23,9 +22,6 @@ var _ dialer = &dialerMock{}
 //
 //         // make and configure a mocked dialer
 //         mockeddialer := &dialerMock{
-//             DialFunc: func() (mail.SendCloser, error) {
-// 	               panic("mock out the Dial method")
-//             },
 //             DialAndSendFunc: func(msgs ...*mail.Message) error {
 // 	               panic("mock out the DialAndSend method")
 //             },
@@ -36,17 +32,11 @@ var _ dialer = &dialerMock{}
 //
 //     }
 type dialerMock struct {
-	// DialFunc mocks the Dial method.
-	DialFunc func() (mail.SendCloser, error)
-
 	// DialAndSendFunc mocks the DialAndSend method.
 	DialAndSendFunc func(msgs ...*mail.Message) error
 
 	// calls tracks calls to the methods.
 	calls struct {
-		// Dial holds details about calls to the Dial method.
-		Dial []struct {
-		}
 		// DialAndSend holds details about calls to the DialAndSend method.
 		DialAndSend []struct {
 			// Msgs is the msgs argument value.
@@ -55,32 +45,6 @@ type dialerMock struct {
 	}
 }
 
-// Dial calls DialFunc.
-func (mock *dialerMock) Dial() (mail.SendCloser, error) {
-	if mock.DialFunc == nil {
-		panic("dialerMock.DialFunc: method is nil but dialer.Dial was just called")
-	}
-	callInfo := struct {
-	}{}
-	lockdialerMockDial.Lock()
-	mock.calls.Dial = append(mock.calls.Dial, callInfo)
-	lockdialerMockDial.Unlock()
-	return mock.DialFunc()
-}
-
-// DialCalls gets all the calls that were made to Dial.
-// Check the length with:
-//     len(mockeddialer.DialCalls())
-func (mock *dialerMock) DialCalls() []struct {
-} {
-	var calls []struct {
-	}
-	lockdialerMockDial.RLock()
-	calls = mock.calls.Dial
-	lockdialerMockDial.RUnlock()
-	return calls
-}
-
 // DialAndSend calls DialAndSendFunc.
 func (mock *dialerMock) DialAndSend(msgs ...*mail.Message) error {
 	if mock.DialAndSendFunc == nil {
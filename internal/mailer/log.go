@@ -0,0 +1,27 @@
+package mailer
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/mail.v2"
+	"strings"
+)
+
+// logDialer writes mails to the log instead of sending them. It will be used when no smtp host has been configured e.g.
+// for development with the memory storage. The logged mails contain the one-time tokens in plaintext.
+type logDialer struct{}
+
+// DialAndSend logs the given messages
+func (logDialer) DialAndSend(msgs ...*mail.Message) error {
+	for _, msg := range msgs {
+		var sb strings.Builder
+		_, err := msg.WriteTo(&sb)
+		if err != nil {
+			return fmt.Errorf("failed to write mail: %w", err)
+		}
+
+		logrus.WithField("to", msg.GetHeader("To")).Info("Mail has not been sent (no smtp host configured):\n" + sb.String())
+	}
+
+	return nil
+}
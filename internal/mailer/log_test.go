@@ -0,0 +1,32 @@
+package mailer
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"gopkg.in/mail.v2"
+	"strings"
+	"testing"
+)
+
+func TestLogDialer_DialAndSend(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	msg := mail.NewMessage()
+	msg.SetHeader("To", "info@leberkleber.io")
+	msg.SetHeader("Subject", "Login code")
+	msg.SetBody("text/plain", "123456")
+
+	err := logDialer{}.DialAndSend(msg)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	entry := hook.LastEntry()
+	if entry == nil || entry.Level != logrus.InfoLevel {
+		t.Fatalf("unexpected log entry: %#v", entry)
+	}
+	if !strings.Contains(entry.Message, "Subject: Login code") || !strings.Contains(entry.Message, "123456") {
+		t.Errorf("logged mail is not as expected. Given:\n%s", entry.Message)
+	}
+}
@@ -4,15 +4,12 @@ import (
 	"crypto/tls"
 	"fmt"
 	"gopkg.in/mail.v2"
+	"time"
 )
 
-//go:generate moq -out send_closer_moq_test.go . sendCloser
-type sendCloser mail.SendCloser
-
 //go:generate moq -out dialer_moq_test.go . dialer
 type dialer interface {
 	DialAndSend(msgs ...*mail.Message) error
-	Dial() (mail.SendCloser, error)
 }
 
 //go:generate moq -out template_moq_test.go . template
@@ -32,48 +29,126 @@ var buildDialer = func(username string, password string, host string, port int,
 	return d
 }
 
-// New creates a Mailer instance with the given smtp-configuration. Before instantiation a dial tests the configuration
-// and all available templates will be parsed.
+// New creates a Mailer instance with the given smtp-configuration and parses the templates of the given features. The
+// smtp server will be dialed on each send only. Without host the mails will be logged instead of sent (see logDialer).
 // 'tlsServerName' is only required if 'tlsInsecureSkipVerify' is false.
-func New(templatesFolderPath, username, password, host string, port int, tlsInsecureSkipVerify bool, tlsServerName string) (*Mailer, error) {
-	d := buildDialer(username, password, host, port, tlsInsecureSkipVerify, tlsServerName)
-
-	//check connection and auth
-	sc, err := d.Dial()
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to smtp server: %w", err)
+func New(templatesFolderPath string, features Features, username, password, host string, port int, tlsInsecureSkipVerify bool, tlsServerName string) (*Mailer, error) {
+	var d dialer = logDialer{}
+	if host != "" {
+		d = buildDialer(username, password, host, port, tlsInsecureSkipVerify, tlsServerName)
 	}
-	defer func() { _ = sc.Close() }()
 
-	pwRestTmpl, err := loadTemplates(templatesFolderPath, passwordResetRequestTemplateName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load password-reset mailTemplate: %w", err)
+	templates := map[string]template{}
+	for _, name := range templateNames(features) {
+		tmpl, err := loadTemplates(templatesFolderPath, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s mailTemplate: %w", name, err)
+		}
+		templates[name] = tmpl
 	}
 
 	return &Mailer{
-		dialer: d,
-		templates: map[string]template{
-			passwordResetRequestTemplateName: pwRestTmpl,
-		},
+		dialer:    d,
+		templates: templates,
 	}, nil
 }
 
-// SendPasswordResetRequestEMail sends a password-reset-request mail to the given recipient. 'passwordResetToken' and
-// 'claims' can be used in mail-templates.
-func (m *Mailer) SendPasswordResetRequestEMail(recipient, passwordResetToken string, claims map[string]interface{}) error {
+// SendPasswordResetRequestEMail sends a password-reset-request mail to the given recipient. 'passwordResetToken',
+// 'claims' and 'metadata' can be used in mail-templates.
+func (m *Mailer) SendPasswordResetRequestEMail(recipient, passwordResetToken string, claims, metadata map[string]interface{}) error {
 	mailData := struct {
 		Recipient          string
 		PasswordResetToken string
 		Claims             map[string]interface{}
+		Metadata           map[string]interface{}
 	}{
 		Recipient:          recipient,
 		PasswordResetToken: passwordResetToken,
 		Claims:             claims,
+		Metadata:           metadata,
+	}
+
+	return m.send(passwordResetRequestTemplateName, mailData)
+}
+
+// SendPasswordExpiryReminderEMail sends a password-expiry-reminder mail to the given recipient. 'passwordExpiresAt',
+// 'claims' and 'metadata' can be used in mail-templates.
+func (m *Mailer) SendPasswordExpiryReminderEMail(recipient string, passwordExpiresAt time.Time, claims, metadata map[string]interface{}) error {
+	mailData := struct {
+		Recipient         string
+		PasswordExpiresAt time.Time
+		Claims            map[string]interface{}
+		Metadata          map[string]interface{}
+	}{
+		Recipient:         recipient,
+		PasswordExpiresAt: passwordExpiresAt,
+		Claims:            claims,
+		Metadata:          metadata,
+	}
+
+	return m.send(passwordExpiryReminderTemplateName, mailData)
+}
+
+// SendMagicLinkEMail sends a magic-link mail to the given recipient. 'magicLinkToken', 'claims' and 'metadata' can be
+// used in mail-templates.
+func (m *Mailer) SendMagicLinkEMail(recipient, magicLinkToken string, claims, metadata map[string]interface{}) error {
+	mailData := struct {
+		Recipient      string
+		MagicLinkToken string
+		Claims         map[string]interface{}
+		Metadata       map[string]interface{}
+	}{
+		Recipient:      recipient,
+		MagicLinkToken: magicLinkToken,
+		Claims:         claims,
+		Metadata:       metadata,
+	}
+
+	return m.send(magicLinkTemplateName, mailData)
+}
+
+// SendLoginCodeEMail sends a login-code mail to the given recipient. 'loginCode', 'claims' and 'metadata' can be used
+// in mail-templates.
+func (m *Mailer) SendLoginCodeEMail(recipient, loginCode string, claims, metadata map[string]interface{}) error {
+	mailData := struct {
+		Recipient string
+		LoginCode string
+		Claims    map[string]interface{}
+		Metadata  map[string]interface{}
+	}{
+		Recipient: recipient,
+		LoginCode: loginCode,
+		Claims:    claims,
+		Metadata:  metadata,
 	}
 
-	tpl, found := m.templates[passwordResetRequestTemplateName]
+	return m.send(loginCodeTemplateName, mailData)
+}
+
+// SendInvitationEMail sends an invite mail to the given recipient. 'invitationToken', 'expiresAt', 'claims' and
+// 'metadata' can be used in mail-templates.
+func (m *Mailer) SendInvitationEMail(recipient, invitationToken string, expiresAt time.Time, claims, metadata map[string]interface{}) error {
+	mailData := struct {
+		Recipient       string
+		InvitationToken string
+		ExpiresAt       time.Time
+		Claims          map[string]interface{}
+		Metadata        map[string]interface{}
+	}{
+		Recipient:       recipient,
+		InvitationToken: invitationToken,
+		ExpiresAt:       expiresAt,
+		Claims:          claims,
+		Metadata:        metadata,
+	}
+
+	return m.send(invitationTemplateName, mailData)
+}
+
+func (m *Mailer) send(templateName string, mailData interface{}) error {
+	tpl, found := m.templates[templateName]
 	if !found {
-		return fmt.Errorf("could not found mailTemplate with name %q", passwordResetRequestTemplateName)
+		return fmt.Errorf("could not found mailTemplate with name %q", templateName)
 	}
 
 	msg, err := tpl.Render(mailData)
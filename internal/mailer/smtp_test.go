@@ -6,42 +6,74 @@ import (
 	"gopkg.in/mail.v2"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestNew(t *testing.T) {
 	tests := []struct {
 		name                    string
-		dialerDialSendCloser    mail.SendCloser
-		dialerDialErr           error
-		loadTemplatesTemplate   mailTemplate
+		givenHost               string
+		givenFeatures           Features
 		loadTemplatesErr        error
 		expectedErr             error
 		expectedMailerTemplates map[string]template
 	}{
 		{
-			name: "Happycase",
-			dialerDialSendCloser: &sendCloserMock{
-				CloseFunc: func() error { return nil },
-			},
-			loadTemplatesTemplate: mailTemplate{
-				name: "password-reset-request",
+			name:          "Happycase",
+			givenHost:     ">host<",
+			givenFeatures: Features{PasswordExpiryReminder: true, MagicLink: true, LoginCode: true, Invitation: true},
+			expectedMailerTemplates: map[string]template{
+				"password-reset-request": mailTemplate{
+					name: "password-reset-request",
+				},
+				"password-expiry-reminder": mailTemplate{
+					name: "password-expiry-reminder",
+				},
+				"magic-link": mailTemplate{
+					name: "magic-link",
+				},
+				"login-code": mailTemplate{
+					name: "login-code",
+				},
+				"invite": mailTemplate{
+					name: "invite",
+				},
 			},
+		}, {
+			name:      "Without features",
+			givenHost: ">host<",
 			expectedMailerTemplates: map[string]template{
 				"password-reset-request": mailTemplate{
 					name: "password-reset-request",
 				},
 			},
 		}, {
-			name:          "Unable to connect to smtp server",
-			dialerDialErr: errors.New("unable to dial: !42"),
-			expectedErr:   errors.New("failed to connect to smtp server: unable to dial: !42"),
+			name:          "Some features",
+			givenHost:     ">host<",
+			givenFeatures: Features{MagicLink: true, Invitation: true},
+			expectedMailerTemplates: map[string]template{
+				"password-reset-request": mailTemplate{
+					name: "password-reset-request",
+				},
+				"magic-link": mailTemplate{
+					name: "magic-link",
+				},
+				"invite": mailTemplate{
+					name: "invite",
+				},
+			},
 		}, {
-			name: "Unable to load templates",
-			dialerDialSendCloser: &sendCloserMock{
-				CloseFunc: func() error { return nil },
+			name: "Without smtp host",
+			expectedMailerTemplates: map[string]template{
+				"password-reset-request": mailTemplate{
+					name: "password-reset-request",
+				},
 			},
+		}, {
+			name:             "Unable to load templates",
+			givenHost:        ">host<",
 			loadTemplatesErr: errors.New("angry file system: you're stupid peace of s*it"),
-			expectedErr:      errors.New("failed to load password-reset mailTemplate: angry file system: you're stupid peace of s*it"),
+			expectedErr:      errors.New("failed to load password-reset-request mailTemplate: angry file system: you're stupid peace of s*it"),
 		},
 	}
 	for _, tt := range tests {
@@ -56,46 +88,46 @@ func TestNew(t *testing.T) {
 			givenTemplatesFolderPath := "/my/mailTemplate/path"
 			givenUsername := ">username<"
 			givenPassword := ">password<"
-			givenHost := ">host<"
 			givenPort := 5555
 			givenTLSInsecureSkipVerify := true
 			givenTLSServerName := ">tlsServerName<"
-			givenDialer := &dialerMock{
-				DialFunc: func() (mail.SendCloser, error) {
-					return tt.dialerDialSendCloser, tt.dialerDialErr
-				},
-			}
+			givenDialer := &dialerMock{}
 
 			buildDialer = func(username, password, host string, port int, tlsInsecureSkipVerify bool, tlsServerName string) dialer {
 				return givenDialer
 			}
 
+			var loadedTemplateNames []string
 			loadTemplates = func(path, name string) (mailTemplate, error) {
 				if path != givenTemplatesFolderPath {
 					t.Errorf("unexpected loadTemplates.path. Given: %q, Expected: %q", path, givenTemplatesFolderPath)
 				}
+				loadedTemplateNames = append(loadedTemplateNames, name)
 
-				expectedLoadedTemplateName := "password-reset-request"
-				if name != expectedLoadedTemplateName {
-					t.Errorf("unexpected loadTemplates.name. Given: %q, Expected: %q", name, expectedLoadedTemplateName)
-				}
-
-				return tt.loadTemplatesTemplate, tt.loadTemplatesErr
+				return mailTemplate{name: name}, tt.loadTemplatesErr
 			}
 
-			mailer, err := New(givenTemplatesFolderPath, givenUsername, givenPassword, givenHost, givenPort, givenTLSInsecureSkipVerify, givenTLSServerName)
+			mailer, err := New(givenTemplatesFolderPath, tt.givenFeatures, givenUsername, givenPassword, tt.givenHost, givenPort, givenTLSInsecureSkipVerify, givenTLSServerName)
 			if fmt.Sprint(err) != fmt.Sprint(tt.expectedErr) {
 				t.Fatalf("Unexpected error. Given:\n%q\nExpected:\n%q", err, tt.loadTemplatesErr)
 			} else if err != nil {
 				return
 			}
 
+			if len(loadedTemplateNames) != len(tt.expectedMailerTemplates) {
+				t.Errorf("unexpected loadTemplates.name(s). Given: %q, Expected: %d templates", loadedTemplateNames, len(tt.expectedMailerTemplates))
+			}
+
 			if !reflect.DeepEqual(mailer.templates, tt.expectedMailerTemplates) {
 				t.Fatalf("mailer.templates are not as expected. Given:\n%#v\nExpected:\n%#v", mailer.templates, tt.expectedMailerTemplates)
 			}
 
-			if !reflect.DeepEqual(mailer.dialer, givenDialer) {
-				t.Fatalf("mailer.dialer is not as expected. Given:\n%#v\nExpected:\n%#v", mailer.dialer, givenDialer)
+			var expectedDialer dialer = givenDialer
+			if tt.givenHost == "" {
+				expectedDialer = logDialer{}
+			}
+			if !reflect.DeepEqual(mailer.dialer, expectedDialer) {
+				t.Fatalf("mailer.dialer is not as expected. Given:\n%#v\nExpected:\n%#v", mailer.dialer, expectedDialer)
 			}
 		})
 	}
@@ -108,6 +140,9 @@ func TestMailer_SendPasswordResetRequestEMail_HappyCase(t *testing.T) {
 	givenClaims := map[string]interface{}{
 		"customClaim4711": 3,
 	}
+	givenMetadata := map[string]interface{}{
+		"firstName": "Alice",
+	}
 
 	prrMail := mail.NewMessage(mail.SetCharset("UTF-8"))
 	prrMail.SetHeader("test_id", "yay")
@@ -138,7 +173,7 @@ func TestMailer_SendPasswordResetRequestEMail_HappyCase(t *testing.T) {
 		templates: tpls,
 	}
 
-	err := m.SendPasswordResetRequestEMail(givenRecipient, givenPasswordResetToken, givenClaims)
+	err := m.SendPasswordResetRequestEMail(givenRecipient, givenPasswordResetToken, givenClaims, givenMetadata)
 	if err != nil {
 		t.Fatal("Unexpected error", err)
 	}
@@ -162,19 +197,17 @@ func TestMailer_SendPasswordResetRequestEMail_HappyCase(t *testing.T) {
 		Recipient          string
 		PasswordResetToken string
 		Claims             map[string]interface{}
+		Metadata           map[string]interface{}
 	}{
 		Recipient:          givenRecipient,
 		PasswordResetToken: givenPasswordResetToken,
 		Claims:             givenClaims,
+		Metadata:           givenMetadata,
 	}
 	if !reflect.DeepEqual(expectedMailData, calledMailData) {
 		t.Errorf("called mail data are not as expected. Expected:\n%#v\nGiven:\n%#v", expectedMailData, calledMailData)
 	}
 
-	dialerDialCalls := dialer.DialCalls()
-	if len(dialerDialCalls) != 0 {
-		t.Errorf("dialer.DialAndSendCalls should be called 0 time but was %d", len(dialerDialCalls))
-	}
 }
 
 func TestMailer_SendPasswordResetRequestEMail_TemplateNotFound(t *testing.T) {
@@ -183,6 +216,9 @@ func TestMailer_SendPasswordResetRequestEMail_TemplateNotFound(t *testing.T) {
 	givenClaims := map[string]interface{}{
 		"customClaim4711": 3,
 	}
+	givenMetadata := map[string]interface{}{
+		"firstName": "Alice",
+	}
 
 	prrMail := mail.NewMessage(mail.SetCharset("UTF-8"))
 	prrMail.SetHeader("test_id", "yay")
@@ -191,7 +227,7 @@ func TestMailer_SendPasswordResetRequestEMail_TemplateNotFound(t *testing.T) {
 		templates: map[string]template{},
 	}
 
-	err := m.SendPasswordResetRequestEMail(givenRecipient, givenPasswordResetToken, givenClaims)
+	err := m.SendPasswordResetRequestEMail(givenRecipient, givenPasswordResetToken, givenClaims, givenMetadata)
 	expectedError := errors.New("could not found mailTemplate with name \"password-reset-request\"")
 	if fmt.Sprint(err) != fmt.Sprint(expectedError) {
 		t.Fatalf("Unexpected error. Error:\n%q,\nExpected:\n%q", err, expectedError)
@@ -204,6 +240,9 @@ func TestMailer_SendPasswordResetRequestEMail_FailedToRenderTemplate(t *testing.
 	givenClaims := map[string]interface{}{
 		"customClaim4711": 3,
 	}
+	givenMetadata := map[string]interface{}{
+		"firstName": "Alice",
+	}
 
 	prrMail := mail.NewMessage(mail.SetCharset("UTF-8"))
 	prrMail.SetHeader("test_id", "yay")
@@ -224,7 +263,7 @@ func TestMailer_SendPasswordResetRequestEMail_FailedToRenderTemplate(t *testing.
 		templates: tpls,
 	}
 
-	err := m.SendPasswordResetRequestEMail(givenRecipient, givenPasswordResetToken, givenClaims)
+	err := m.SendPasswordResetRequestEMail(givenRecipient, givenPasswordResetToken, givenClaims, givenMetadata)
 	expectedError := errors.New("failed to render mail mailTemplate: i dont think so")
 	if fmt.Sprint(err) != fmt.Sprint(expectedError) {
 		t.Fatalf("Unexpected error. Error:\n%q,\nExpected:\n%q", err, expectedError)
@@ -239,10 +278,12 @@ func TestMailer_SendPasswordResetRequestEMail_FailedToRenderTemplate(t *testing.
 		Recipient          string
 		PasswordResetToken string
 		Claims             map[string]interface{}
+		Metadata           map[string]interface{}
 	}{
 		Recipient:          givenRecipient,
 		PasswordResetToken: givenPasswordResetToken,
 		Claims:             givenClaims,
+		Metadata:           givenMetadata,
 	}
 	if !reflect.DeepEqual(expectedMailData, calledMailData) {
 		t.Errorf("called mail data are not as expected. Expected:\n%#v\nGiven:\n%#v", expectedMailData, calledMailData)
@@ -255,6 +296,9 @@ func TestMailer_SendPasswordResetRequestEMail_FailedToSendMail(t *testing.T) {
 	givenClaims := map[string]interface{}{
 		"customClaim4711": 3,
 	}
+	givenMetadata := map[string]interface{}{
+		"firstName": "Alice",
+	}
 
 	prrMail := mail.NewMessage(mail.SetCharset("UTF-8"))
 	prrMail.SetHeader("test_id", "yay")
@@ -285,7 +329,7 @@ func TestMailer_SendPasswordResetRequestEMail_FailedToSendMail(t *testing.T) {
 		templates: tpls,
 	}
 
-	err := m.SendPasswordResetRequestEMail(givenRecipient, givenPasswordResetToken, givenClaims)
+	err := m.SendPasswordResetRequestEMail(givenRecipient, givenPasswordResetToken, givenClaims, givenMetadata)
 	expectedError := errors.New("failed to send email: perhaps yes but no")
 	if fmt.Sprint(err) != fmt.Sprint(expectedError) {
 		t.Fatalf("Unexpected error. Error:\n%q,\nExpected:\n%q", err, expectedError)
@@ -310,17 +354,265 @@ func TestMailer_SendPasswordResetRequestEMail_FailedToSendMail(t *testing.T) {
 		Recipient          string
 		PasswordResetToken string
 		Claims             map[string]interface{}
+		Metadata           map[string]interface{}
 	}{
 		Recipient:          givenRecipient,
 		PasswordResetToken: givenPasswordResetToken,
 		Claims:             givenClaims,
+		Metadata:           givenMetadata,
+	}
+	if !reflect.DeepEqual(expectedMailData, calledMailData) {
+		t.Errorf("called mail data are not as expected. Expected:\n%#v\nGiven:\n%#v", expectedMailData, calledMailData)
+	}
+
+}
+
+func TestMailer_SendPasswordExpiryReminderEMail(t *testing.T) {
+	givenRecipient := ">recipient<"
+	givenPasswordExpiresAt := time.Date(2020, 8, 1, 4, 46, 45, 2, time.UTC)
+	givenClaims := map[string]interface{}{
+		"customClaim4711": 3,
+	}
+	givenMetadata := map[string]interface{}{
+		"firstName": "Alice",
+	}
+
+	perMail := mail.NewMessage(mail.SetCharset("UTF-8"))
+	perMail.SetHeader("test_id", "yay")
+
+	var mailsToSend []*mail.Message
+	dialer := &dialerMock{
+		DialAndSendFunc: func(msgs ...*mail.Message) error {
+			mailsToSend = msgs
+			return nil
+		},
+	}
+
+	var calledMailData interface{}
+	tplMock := &templateMock{
+		RenderFunc: func(mailData interface{}) (*mail.Message, error) {
+			calledMailData = mailData
+			return perMail, nil
+		},
+	}
+
+	m := Mailer{
+		dialer: dialer,
+		templates: map[string]template{
+			"password-expiry-reminder": tplMock,
+		},
+	}
+
+	err := m.SendPasswordExpiryReminderEMail(givenRecipient, givenPasswordExpiresAt, givenClaims, givenMetadata)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+
+	expectedSendMails := []*mail.Message{perMail}
+	if !reflect.DeepEqual(mailsToSend, expectedSendMails) {
+		t.Errorf("The send mail(s) are not the rendered. Rendered: %#v. Send: %#v", mailsToSend, expectedSendMails)
+	}
+
+	expectedMailData := struct {
+		Recipient         string
+		PasswordExpiresAt time.Time
+		Claims            map[string]interface{}
+		Metadata          map[string]interface{}
+	}{
+		Recipient:         givenRecipient,
+		PasswordExpiresAt: givenPasswordExpiresAt,
+		Claims:            givenClaims,
+		Metadata:          givenMetadata,
+	}
+	if !reflect.DeepEqual(expectedMailData, calledMailData) {
+		t.Errorf("called mail data are not as expected. Expected:\n%#v\nGiven:\n%#v", expectedMailData, calledMailData)
+	}
+}
+
+func TestMailer_SendMagicLinkEMail(t *testing.T) {
+	givenRecipient := ">recipient<"
+	givenMagicLinkToken := ">token<"
+	givenClaims := map[string]interface{}{
+		"customClaim4711": 3,
+	}
+	givenMetadata := map[string]interface{}{
+		"firstName": "Alice",
+	}
+
+	perMail := mail.NewMessage(mail.SetCharset("UTF-8"))
+	perMail.SetHeader("test_id", "yay")
+
+	var mailsToSend []*mail.Message
+	dialer := &dialerMock{
+		DialAndSendFunc: func(msgs ...*mail.Message) error {
+			mailsToSend = msgs
+			return nil
+		},
+	}
+
+	var calledMailData interface{}
+	tplMock := &templateMock{
+		RenderFunc: func(mailData interface{}) (*mail.Message, error) {
+			calledMailData = mailData
+			return perMail, nil
+		},
+	}
+
+	m := Mailer{
+		dialer: dialer,
+		templates: map[string]template{
+			"magic-link": tplMock,
+		},
+	}
+
+	err := m.SendMagicLinkEMail(givenRecipient, givenMagicLinkToken, givenClaims, givenMetadata)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+
+	expectedSendMails := []*mail.Message{perMail}
+	if !reflect.DeepEqual(mailsToSend, expectedSendMails) {
+		t.Errorf("The send mail(s) are not the rendered. Rendered: %#v. Send: %#v", mailsToSend, expectedSendMails)
+	}
+
+	expectedMailData := struct {
+		Recipient      string
+		MagicLinkToken string
+		Claims         map[string]interface{}
+		Metadata       map[string]interface{}
+	}{
+		Recipient:      givenRecipient,
+		MagicLinkToken: givenMagicLinkToken,
+		Claims:         givenClaims,
+		Metadata:       givenMetadata,
+	}
+	if !reflect.DeepEqual(expectedMailData, calledMailData) {
+		t.Errorf("called mail data are not as expected. Expected:\n%#v\nGiven:\n%#v", expectedMailData, calledMailData)
+	}
+}
+func TestMailer_SendLoginCodeEMail(t *testing.T) {
+	givenRecipient := ">recipient<"
+	givenLoginCode := ">code<"
+	givenClaims := map[string]interface{}{
+		"customClaim4711": 3,
+	}
+	givenMetadata := map[string]interface{}{
+		"firstName": "Alice",
+	}
+
+	perMail := mail.NewMessage(mail.SetCharset("UTF-8"))
+	perMail.SetHeader("test_id", "yay")
+
+	var mailsToSend []*mail.Message
+	dialer := &dialerMock{
+		DialAndSendFunc: func(msgs ...*mail.Message) error {
+			mailsToSend = msgs
+			return nil
+		},
+	}
+
+	var calledMailData interface{}
+	tplMock := &templateMock{
+		RenderFunc: func(mailData interface{}) (*mail.Message, error) {
+			calledMailData = mailData
+			return perMail, nil
+		},
+	}
+
+	m := Mailer{
+		dialer: dialer,
+		templates: map[string]template{
+			"login-code": tplMock,
+		},
+	}
+
+	err := m.SendLoginCodeEMail(givenRecipient, givenLoginCode, givenClaims, givenMetadata)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+
+	expectedSendMails := []*mail.Message{perMail}
+	if !reflect.DeepEqual(mailsToSend, expectedSendMails) {
+		t.Errorf("The send mail(s) are not the rendered. Rendered: %#v. Send: %#v", mailsToSend, expectedSendMails)
+	}
+
+	expectedMailData := struct {
+		Recipient string
+		LoginCode string
+		Claims    map[string]interface{}
+		Metadata  map[string]interface{}
+	}{
+		Recipient: givenRecipient,
+		LoginCode: givenLoginCode,
+		Claims:    givenClaims,
+		Metadata:  givenMetadata,
 	}
 	if !reflect.DeepEqual(expectedMailData, calledMailData) {
 		t.Errorf("called mail data are not as expected. Expected:\n%#v\nGiven:\n%#v", expectedMailData, calledMailData)
 	}
+}
+
+func TestMailer_SendInvitationEMail(t *testing.T) {
+	givenRecipient := ">recipient<"
+	givenInvitationToken := ">token<"
+	givenExpiresAt := time.Date(2020, 2, 8, 4, 46, 45, 0, time.UTC)
+	givenClaims := map[string]interface{}{
+		"customClaim4711": 3,
+	}
+	givenMetadata := map[string]interface{}{
+		"firstName": "Alice",
+	}
+
+	perMail := mail.NewMessage(mail.SetCharset("UTF-8"))
+	perMail.SetHeader("test_id", "yay")
+
+	var mailsToSend []*mail.Message
+	dialer := &dialerMock{
+		DialAndSendFunc: func(msgs ...*mail.Message) error {
+			mailsToSend = msgs
+			return nil
+		},
+	}
 
-	dialerDialCalls := dialer.DialCalls()
-	if len(dialerDialCalls) != 0 {
-		t.Errorf("dialer.DialAndSendCalls should be called 0 time but was %d", len(dialerDialCalls))
+	var calledMailData interface{}
+	tplMock := &templateMock{
+		RenderFunc: func(mailData interface{}) (*mail.Message, error) {
+			calledMailData = mailData
+			return perMail, nil
+		},
+	}
+
+	m := Mailer{
+		dialer: dialer,
+		templates: map[string]template{
+			"invite": tplMock,
+		},
+	}
+
+	err := m.SendInvitationEMail(givenRecipient, givenInvitationToken, givenExpiresAt, givenClaims, givenMetadata)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+
+	expectedSendMails := []*mail.Message{perMail}
+	if !reflect.DeepEqual(mailsToSend, expectedSendMails) {
+		t.Errorf("The send mail(s) are not the rendered. Rendered: %#v. Send: %#v", mailsToSend, expectedSendMails)
+	}
+
+	expectedMailData := struct {
+		Recipient       string
+		InvitationToken string
+		ExpiresAt       time.Time
+		Claims          map[string]interface{}
+		Metadata        map[string]interface{}
+	}{
+		Recipient:       givenRecipient,
+		InvitationToken: givenInvitationToken,
+		ExpiresAt:       givenExpiresAt,
+		Claims:          givenClaims,
+		Metadata:        givenMetadata,
+	}
+	if !reflect.DeepEqual(expectedMailData, calledMailData) {
+		t.Errorf("called mail data are not as expected. Expected:\n%#v\nGiven:\n%#v", expectedMailData, calledMailData)
 	}
 }
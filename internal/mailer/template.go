@@ -11,6 +11,39 @@ import (
 )
 
 const passwordResetRequestTemplateName = "password-reset-request"
+const passwordExpiryReminderTemplateName = "password-expiry-reminder"
+const magicLinkTemplateName = "magic-link"
+const loginCodeTemplateName = "login-code"
+const invitationTemplateName = "invite"
+
+// Features are the optional mail features. Their templates will be loaded only when the feature is enabled, so custom
+// template folders need the templates of the enabled features only. The password-reset-request template is required
+// always.
+type Features struct {
+	PasswordExpiryReminder bool
+	MagicLink              bool
+	LoginCode              bool
+	Invitation             bool
+}
+
+// templateNames returns the names of all templates which are required for the given features
+func templateNames(f Features) []string {
+	names := []string{passwordResetRequestTemplateName}
+	if f.PasswordExpiryReminder {
+		names = append(names, passwordExpiryReminderTemplateName)
+	}
+	if f.MagicLink {
+		names = append(names, magicLinkTemplateName)
+	}
+	if f.LoginCode {
+		names = append(names, loginCodeTemplateName)
+	}
+	if f.Invitation {
+		names = append(names, invitationTemplateName)
+	}
+
+	return names
+}
 
 var htmlTemplateParseFiles = htmlTemplate.ParseFiles
 var textTemplateParseFiles = textTemplate.ParseFiles
@@ -5,10 +5,15 @@ package internal
 
 import (
 	"sync"
+	"time"
 )
 
 var (
-	lockMailerMockSendPasswordResetRequestEMail sync.RWMutex
+	lockMailerMockSendInvitationEMail             sync.RWMutex
+	lockMailerMockSendLoginCodeEMail              sync.RWMutex
+	lockMailerMockSendMagicLinkEMail              sync.RWMutex
+	lockMailerMockSendPasswordExpiryReminderEMail sync.RWMutex
+	lockMailerMockSendPasswordResetRequestEMail   sync.RWMutex
 )
 
 // Ensure, that MailerMock does implement Mailer.
@@ -21,7 +26,19 @@ var _ Mailer = &MailerMock{}
 //
 //         // make and configure a mocked Mailer
 //         mockedMailer := &MailerMock{
-//             SendPasswordResetRequestEMailFunc: func(recipient string, passwordResetToken string, claims map[string]interface{}) error {
+//             SendInvitationEMailFunc: func(recipient string, invitationToken string, expiresAt time.Time, claims map[string]interface{}, metadata map[string]interface{}) error {
+// 	               panic("mock out the SendInvitationEMail method")
+//             },
+//             SendLoginCodeEMailFunc: func(recipient string, loginCode string, claims map[string]interface{}, metadata map[string]interface{}) error {
+// 	               panic("mock out the SendLoginCodeEMail method")
+//             },
+//             SendMagicLinkEMailFunc: func(recipient string, magicLinkToken string, claims map[string]interface{}, metadata map[string]interface{}) error {
+// 	               panic("mock out the SendMagicLinkEMail method")
+//             },
+//             SendPasswordExpiryReminderEMailFunc: func(recipient string, passwordExpiresAt time.Time, claims map[string]interface{}, metadata map[string]interface{}) error {
+// 	               panic("mock out the SendPasswordExpiryReminderEMail method")
+//             },
+//             SendPasswordResetRequestEMailFunc: func(recipient string, passwordResetToken string, claims map[string]interface{}, metadata map[string]interface{}) error {
 // 	               panic("mock out the SendPasswordResetRequestEMail method")
 //             },
 //         }
@@ -31,11 +48,69 @@ var _ Mailer = &MailerMock{}
 //
 //     }
 type MailerMock struct {
+	// SendInvitationEMailFunc mocks the SendInvitationEMail method.
+	SendInvitationEMailFunc func(recipient string, invitationToken string, expiresAt time.Time, claims map[string]interface{}, metadata map[string]interface{}) error
+
+	// SendLoginCodeEMailFunc mocks the SendLoginCodeEMail method.
+	SendLoginCodeEMailFunc func(recipient string, loginCode string, claims map[string]interface{}, metadata map[string]interface{}) error
+
+	// SendMagicLinkEMailFunc mocks the SendMagicLinkEMail method.
+	SendMagicLinkEMailFunc func(recipient string, magicLinkToken string, claims map[string]interface{}, metadata map[string]interface{}) error
+
+	// SendPasswordExpiryReminderEMailFunc mocks the SendPasswordExpiryReminderEMail method.
+	SendPasswordExpiryReminderEMailFunc func(recipient string, passwordExpiresAt time.Time, claims map[string]interface{}, metadata map[string]interface{}) error
+
 	// SendPasswordResetRequestEMailFunc mocks the SendPasswordResetRequestEMail method.
-	SendPasswordResetRequestEMailFunc func(recipient string, passwordResetToken string, claims map[string]interface{}) error
+	SendPasswordResetRequestEMailFunc func(recipient string, passwordResetToken string, claims map[string]interface{}, metadata map[string]interface{}) error
 
 	// calls tracks calls to the methods.
 	calls struct {
+		// SendInvitationEMail holds details about calls to the SendInvitationEMail method.
+		SendInvitationEMail []struct {
+			// Recipient is the recipient argument value.
+			Recipient string
+			// InvitationToken is the invitationToken argument value.
+			InvitationToken string
+			// ExpiresAt is the expiresAt argument value.
+			ExpiresAt time.Time
+			// Claims is the claims argument value.
+			Claims map[string]interface{}
+			// Metadata is the metadata argument value.
+			Metadata map[string]interface{}
+		}
+		// SendLoginCodeEMail holds details about calls to the SendLoginCodeEMail method.
+		SendLoginCodeEMail []struct {
+			// Recipient is the recipient argument value.
+			Recipient string
+			// LoginCode is the loginCode argument value.
+			LoginCode string
+			// Claims is the claims argument value.
+			Claims map[string]interface{}
+			// Metadata is the metadata argument value.
+			Metadata map[string]interface{}
+		}
+		// SendMagicLinkEMail holds details about calls to the SendMagicLinkEMail method.
+		SendMagicLinkEMail []struct {
+			// Recipient is the recipient argument value.
+			Recipient string
+			// MagicLinkToken is the magicLinkToken argument value.
+			MagicLinkToken string
+			// Claims is the claims argument value.
+			Claims map[string]interface{}
+			// Metadata is the metadata argument value.
+			Metadata map[string]interface{}
+		}
+		// SendPasswordExpiryReminderEMail holds details about calls to the SendPasswordExpiryReminderEMail method.
+		SendPasswordExpiryReminderEMail []struct {
+			// Recipient is the recipient argument value.
+			Recipient string
+			// PasswordExpiresAt is the passwordExpiresAt argument value.
+			PasswordExpiresAt time.Time
+			// Claims is the claims argument value.
+			Claims map[string]interface{}
+			// Metadata is the metadata argument value.
+			Metadata map[string]interface{}
+		}
 		// SendPasswordResetRequestEMail holds details about calls to the SendPasswordResetRequestEMail method.
 		SendPasswordResetRequestEMail []struct {
 			// Recipient is the recipient argument value.
@@ -44,12 +119,190 @@ type MailerMock struct {
 			PasswordResetToken string
 			// Claims is the claims argument value.
 			Claims map[string]interface{}
+			// Metadata is the metadata argument value.
+			Metadata map[string]interface{}
 		}
 	}
 }
 
+// SendInvitationEMail calls SendInvitationEMailFunc.
+func (mock *MailerMock) SendInvitationEMail(recipient string, invitationToken string, expiresAt time.Time, claims map[string]interface{}, metadata map[string]interface{}) error {
+	if mock.SendInvitationEMailFunc == nil {
+		panic("MailerMock.SendInvitationEMailFunc: method is nil but Mailer.SendInvitationEMail was just called")
+	}
+	callInfo := struct {
+		Recipient       string
+		InvitationToken string
+		ExpiresAt       time.Time
+		Claims          map[string]interface{}
+		Metadata        map[string]interface{}
+	}{
+		Recipient:       recipient,
+		InvitationToken: invitationToken,
+		ExpiresAt:       expiresAt,
+		Claims:          claims,
+		Metadata:        metadata,
+	}
+	lockMailerMockSendInvitationEMail.Lock()
+	mock.calls.SendInvitationEMail = append(mock.calls.SendInvitationEMail, callInfo)
+	lockMailerMockSendInvitationEMail.Unlock()
+	return mock.SendInvitationEMailFunc(recipient, invitationToken, expiresAt, claims, metadata)
+}
+
+// SendInvitationEMailCalls gets all the calls that were made to SendInvitationEMail.
+// Check the length with:
+//     len(mockedMailer.SendInvitationEMailCalls())
+func (mock *MailerMock) SendInvitationEMailCalls() []struct {
+	Recipient       string
+	InvitationToken string
+	ExpiresAt       time.Time
+	Claims          map[string]interface{}
+	Metadata        map[string]interface{}
+} {
+	var calls []struct {
+		Recipient       string
+		InvitationToken string
+		ExpiresAt       time.Time
+		Claims          map[string]interface{}
+		Metadata        map[string]interface{}
+	}
+	lockMailerMockSendInvitationEMail.RLock()
+	calls = mock.calls.SendInvitationEMail
+	lockMailerMockSendInvitationEMail.RUnlock()
+	return calls
+}
+
+// SendLoginCodeEMail calls SendLoginCodeEMailFunc.
+func (mock *MailerMock) SendLoginCodeEMail(recipient string, loginCode string, claims map[string]interface{}, metadata map[string]interface{}) error {
+	if mock.SendLoginCodeEMailFunc == nil {
+		panic("MailerMock.SendLoginCodeEMailFunc: method is nil but Mailer.SendLoginCodeEMail was just called")
+	}
+	callInfo := struct {
+		Recipient string
+		LoginCode string
+		Claims    map[string]interface{}
+		Metadata  map[string]interface{}
+	}{
+		Recipient: recipient,
+		LoginCode: loginCode,
+		Claims:    claims,
+		Metadata:  metadata,
+	}
+	lockMailerMockSendLoginCodeEMail.Lock()
+	mock.calls.SendLoginCodeEMail = append(mock.calls.SendLoginCodeEMail, callInfo)
+	lockMailerMockSendLoginCodeEMail.Unlock()
+	return mock.SendLoginCodeEMailFunc(recipient, loginCode, claims, metadata)
+}
+
+// SendLoginCodeEMailCalls gets all the calls that were made to SendLoginCodeEMail.
+// Check the length with:
+//     len(mockedMailer.SendLoginCodeEMailCalls())
+func (mock *MailerMock) SendLoginCodeEMailCalls() []struct {
+	Recipient string
+	LoginCode string
+	Claims    map[string]interface{}
+	Metadata  map[string]interface{}
+} {
+	var calls []struct {
+		Recipient string
+		LoginCode string
+		Claims    map[string]interface{}
+		Metadata  map[string]interface{}
+	}
+	lockMailerMockSendLoginCodeEMail.RLock()
+	calls = mock.calls.SendLoginCodeEMail
+	lockMailerMockSendLoginCodeEMail.RUnlock()
+	return calls
+}
+
+// SendMagicLinkEMail calls SendMagicLinkEMailFunc.
+func (mock *MailerMock) SendMagicLinkEMail(recipient string, magicLinkToken string, claims map[string]interface{}, metadata map[string]interface{}) error {
+	if mock.SendMagicLinkEMailFunc == nil {
+		panic("MailerMock.SendMagicLinkEMailFunc: method is nil but Mailer.SendMagicLinkEMail was just called")
+	}
+	callInfo := struct {
+		Recipient      string
+		MagicLinkToken string
+		Claims         map[string]interface{}
+		Metadata       map[string]interface{}
+	}{
+		Recipient:      recipient,
+		MagicLinkToken: magicLinkToken,
+		Claims:         claims,
+		Metadata:       metadata,
+	}
+	lockMailerMockSendMagicLinkEMail.Lock()
+	mock.calls.SendMagicLinkEMail = append(mock.calls.SendMagicLinkEMail, callInfo)
+	lockMailerMockSendMagicLinkEMail.Unlock()
+	return mock.SendMagicLinkEMailFunc(recipient, magicLinkToken, claims, metadata)
+}
+
+// SendMagicLinkEMailCalls gets all the calls that were made to SendMagicLinkEMail.
+// Check the length with:
+//     len(mockedMailer.SendMagicLinkEMailCalls())
+func (mock *MailerMock) SendMagicLinkEMailCalls() []struct {
+	Recipient      string
+	MagicLinkToken string
+	Claims         map[string]interface{}
+	Metadata       map[string]interface{}
+} {
+	var calls []struct {
+		Recipient      string
+		MagicLinkToken string
+		Claims         map[string]interface{}
+		Metadata       map[string]interface{}
+	}
+	lockMailerMockSendMagicLinkEMail.RLock()
+	calls = mock.calls.SendMagicLinkEMail
+	lockMailerMockSendMagicLinkEMail.RUnlock()
+	return calls
+}
+
+// SendPasswordExpiryReminderEMail calls SendPasswordExpiryReminderEMailFunc.
+func (mock *MailerMock) SendPasswordExpiryReminderEMail(recipient string, passwordExpiresAt time.Time, claims map[string]interface{}, metadata map[string]interface{}) error {
+	if mock.SendPasswordExpiryReminderEMailFunc == nil {
+		panic("MailerMock.SendPasswordExpiryReminderEMailFunc: method is nil but Mailer.SendPasswordExpiryReminderEMail was just called")
+	}
+	callInfo := struct {
+		Recipient         string
+		PasswordExpiresAt time.Time
+		Claims            map[string]interface{}
+		Metadata          map[string]interface{}
+	}{
+		Recipient:         recipient,
+		PasswordExpiresAt: passwordExpiresAt,
+		Claims:            claims,
+		Metadata:          metadata,
+	}
+	lockMailerMockSendPasswordExpiryReminderEMail.Lock()
+	mock.calls.SendPasswordExpiryReminderEMail = append(mock.calls.SendPasswordExpiryReminderEMail, callInfo)
+	lockMailerMockSendPasswordExpiryReminderEMail.Unlock()
+	return mock.SendPasswordExpiryReminderEMailFunc(recipient, passwordExpiresAt, claims, metadata)
+}
+
+// SendPasswordExpiryReminderEMailCalls gets all the calls that were made to SendPasswordExpiryReminderEMail.
+// Check the length with:
+//     len(mockedMailer.SendPasswordExpiryReminderEMailCalls())
+func (mock *MailerMock) SendPasswordExpiryReminderEMailCalls() []struct {
+	Recipient         string
+	PasswordExpiresAt time.Time
+	Claims            map[string]interface{}
+	Metadata          map[string]interface{}
+} {
+	var calls []struct {
+		Recipient         string
+		PasswordExpiresAt time.Time
+		Claims            map[string]interface{}
+		Metadata          map[string]interface{}
+	}
+	lockMailerMockSendPasswordExpiryReminderEMail.RLock()
+	calls = mock.calls.SendPasswordExpiryReminderEMail
+	lockMailerMockSendPasswordExpiryReminderEMail.RUnlock()
+	return calls
+}
+
 // SendPasswordResetRequestEMail calls SendPasswordResetRequestEMailFunc.
-func (mock *MailerMock) SendPasswordResetRequestEMail(recipient string, passwordResetToken string, claims map[string]interface{}) error {
+func (mock *MailerMock) SendPasswordResetRequestEMail(recipient string, passwordResetToken string, claims map[string]interface{}, metadata map[string]interface{}) error {
 	if mock.SendPasswordResetRequestEMailFunc == nil {
 		panic("MailerMock.SendPasswordResetRequestEMailFunc: method is nil but Mailer.SendPasswordResetRequestEMail was just called")
 	}
@@ -57,15 +310,17 @@ func (mock *MailerMock) SendPasswordResetRequestEMail(recipient string, password
 		Recipient          string
 		PasswordResetToken string
 		Claims             map[string]interface{}
+		Metadata           map[string]interface{}
 	}{
 		Recipient:          recipient,
 		PasswordResetToken: passwordResetToken,
 		Claims:             claims,
+		Metadata:           metadata,
 	}
 	lockMailerMockSendPasswordResetRequestEMail.Lock()
 	mock.calls.SendPasswordResetRequestEMail = append(mock.calls.SendPasswordResetRequestEMail, callInfo)
 	lockMailerMockSendPasswordResetRequestEMail.Unlock()
-	return mock.SendPasswordResetRequestEMailFunc(recipient, passwordResetToken, claims)
+	return mock.SendPasswordResetRequestEMailFunc(recipient, passwordResetToken, claims, metadata)
 }
 
 // SendPasswordResetRequestEMailCalls gets all the calls that were made to SendPasswordResetRequestEMail.
@@ -75,11 +330,13 @@ func (mock *MailerMock) SendPasswordResetRequestEMailCalls() []struct {
 	Recipient          string
 	PasswordResetToken string
 	Claims             map[string]interface{}
+	Metadata           map[string]interface{}
 } {
 	var calls []struct {
 		Recipient          string
 		PasswordResetToken string
 		Claims             map[string]interface{}
+		Metadata           map[string]interface{}
 	}
 	lockMailerMockSendPasswordResetRequestEMail.RLock()
 	calls = mock.calls.SendPasswordResetRequestEMail
@@ -0,0 +1,337 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"github.com/leberKleber/simple-jwt-provider/internal/totp"
+	"github.com/leberKleber/simple-jwt-provider/internal/webauthn"
+)
+
+const amrClaim = "amr"
+
+// values of the 'amr' claim (see RFC 8176)
+const (
+	amrPassword     = "pwd"
+	amrMail         = "mail"
+	amrOTP          = "otp"
+	amrHardwareKey  = "hwk"
+	amrRecoveryCode = "rc"
+)
+
+// mfaTokenFirstFactor is the metadata key of mfa challenge tokens which holds the 'amr' value of the first factor
+const mfaTokenFirstFactor = "first_factor"
+
+const (
+	MFAMethodTOTP     = "totp"
+	MFAMethodWebAuthn = "webauthn"
+)
+
+var ErrTOTPNotConfigured = errors.New("totp is not configured")
+var ErrTOTPAlreadyEnabled = errors.New("totp is already enabled")
+var ErrTOTPNotEnrolled = errors.New("totp is not enrolled")
+var ErrInvalidMFACode = errors.New("invalid mfa code")
+var ErrSecondFactorRequired = errors.New("second factor required")
+var ErrInvalidSecondFactor = errors.New("invalid second factor")
+
+// LoginResult is the result of a successful Login. Either AccessToken or MFAToken is set.
+type LoginResult struct {
+	AccessToken string
+	// MFAToken is a short-lived challenge token which has to be redeemed with a second factor via LoginMFA
+	MFAToken string
+	// MFAMethods are the second factors the user can redeem the MFAToken with
+	MFAMethods []string
+}
+
+// SecondFactor proves the possession of an enabled second factor. Besides the password it is required to change the
+// second factors of users who have enabled one, so a leaked password is not enough to replace them.
+type SecondFactor struct {
+	// Code is a totp code or an unused recovery code
+	Code string
+	// WebAuthn is the response of navigator.credentials.get() to the options of BeginWebAuthnLogin. It will be used in
+	// place of the code when given.
+	WebAuthn *webauthn.AssertionResponse
+}
+
+// TOTPEnrolment contains everything an authenticator app needs to generate codes
+type TOTPEnrolment struct {
+	// Secret is the base32 encoded totp secret
+	Secret string
+	// URI is the otpauth:// key-uri which can be rendered as qr-code
+	URI string
+}
+
+// EnrolTOTP generates and stores a new totp secret for the given user. The totp has to be confirmed via ConfirmTOTP
+// before it will be required on login. Unconfirmed enrolments will be replaced. Users with another enabled second
+// factor (webauthn, recovery codes) have to prove it.
+// return ErrTOTPNotConfigured when no TOTPCrypter has been configured
+// return ErrIncorrectPassword when password is incorrect
+// return ErrUserNotFound when user not found
+// return ErrTOTPAlreadyEnabled when the user already has a confirmed totp
+// return ErrSecondFactorRequired when the user has another enabled second factor but none has been given
+// return ErrInvalidSecondFactor when the given second factor is invalid
+func (p Provider) EnrolTOTP(identifier, password string, secondFactor SecondFactor) (TOTPEnrolment, error) {
+	if p.TOTPCrypter == nil {
+		return TOTPEnrolment{}, ErrTOTPNotConfigured
+	}
+
+	u, err := p.authenticate(identifier, password)
+	if err != nil {
+		return TOTPEnrolment{}, err
+	}
+
+	t, err := p.Storage.TOTP(u.ID)
+	if err != nil && !errors.Is(err, storage.ErrTOTPNotFound) {
+		return TOTPEnrolment{}, fmt.Errorf("failed to query totp: %w", err)
+	}
+	if err == nil && t.Confirmed {
+		return TOTPEnrolment{}, ErrTOTPAlreadyEnabled
+	}
+
+	err = p.requireSecondFactor(u.ID, secondFactor)
+	if err != nil {
+		return TOTPEnrolment{}, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return TOTPEnrolment{}, fmt.Errorf("failed to generate totp secret: %w", err)
+	}
+
+	encryptedSecret, err := p.TOTPCrypter.Encrypt(secret)
+	if err != nil {
+		return TOTPEnrolment{}, fmt.Errorf("failed to encrypt totp secret: %w", err)
+	}
+
+	err = p.Storage.SaveTOTP(storage.TOTP{
+		UserID:    u.ID,
+		Secret:    encryptedSecret,
+		CreatedAt: nowFunc(),
+	})
+	if err != nil {
+		return TOTPEnrolment{}, fmt.Errorf("failed to save totp: %w", err)
+	}
+
+	return TOTPEnrolment{
+		Secret: totp.EncodeSecret(secret),
+		URI:    totp.URI(p.TOTPIssuer, loginName(u), secret),
+	}, nil
+}
+
+// ConfirmTOTP enables the enrolled totp of the given user if the given code is valid. Returns new recovery codes when
+// the user had no unused ones before. Users with another enabled second factor have to prove it like on EnrolTOTP.
+// return ErrTOTPNotConfigured when no TOTPCrypter has been configured
+// return ErrIncorrectPassword when password is incorrect
+// return ErrUserNotFound when user not found
+// return ErrTOTPNotEnrolled when the user has no totp enrolment
+// return ErrTOTPAlreadyEnabled when the totp has already been confirmed
+// return ErrSecondFactorRequired when the user has another enabled second factor but none has been given
+// return ErrInvalidSecondFactor when the given second factor is invalid
+// return ErrInvalidMFACode when the code is invalid
+func (p Provider) ConfirmTOTP(identifier, password, code string, secondFactor SecondFactor) ([]string, error) {
+	if p.TOTPCrypter == nil {
+		return nil, ErrTOTPNotConfigured
+	}
+
+	u, err := p.authenticate(identifier, password)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := p.Storage.TOTP(u.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTOTPNotFound) {
+			return nil, ErrTOTPNotEnrolled
+		}
+		return nil, fmt.Errorf("failed to query totp: %w", err)
+	}
+
+	if t.Confirmed {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	err = p.requireSecondFactor(u.ID, secondFactor)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.useTOTPCode(t, code)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.issueRecoveryCodes(u.ID)
+}
+
+// LoginMFA redeems the given mfa challenge token (issued by Login, LoginMagicLink or LoginWithCode) together with a
+// totp code and returns a new jwt with the 'amr' claim of the first factor and "otp" (e.g. ["pwd", "otp"]). The
+// challenge token can be used once, also when the code is invalid. The attempt will be recorded in the login history of
+// the user.
+// return ErrNoValidTokenFound when the mfa token is unknown or expired
+// return ErrTOTPNotConfigured when no TOTPCrypter has been configured
+// return ErrTOTPNotEnrolled when the user has no confirmed totp
+// return ErrInvalidMFACode when the code is invalid
+func (p Provider) LoginMFA(identifier, mfaToken, code string, client Client) (accessToken string, err error) {
+	u, err := p.challengedUser(identifier)
+	if err != nil {
+		return "", err
+	}
+	defer func() { p.recordLogin(u.ID, client, LoginFactorTOTP, false, err) }()
+
+	firstFactor, err := p.redeemMFAToken(u.ID, mfaToken)
+	if err != nil {
+		return "", err
+	}
+
+	if p.TOTPCrypter == nil {
+		return "", ErrTOTPNotConfigured
+	}
+
+	t, err := p.Storage.TOTP(u.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTOTPNotFound) {
+			return "", ErrTOTPNotEnrolled
+		}
+		return "", fmt.Errorf("failed to query totp: %w", err)
+	}
+
+	if !t.Confirmed {
+		return "", ErrTOTPNotEnrolled
+	}
+
+	err = p.useTOTPCode(t, code)
+	if err != nil {
+		return "", err
+	}
+
+	return p.JWTGenerator.Generate(u.ID, u.EMail, withClaim(u.Claims, amrClaim, []string{firstFactor, amrOTP}))
+}
+
+// issueMFAToken issues a mfa challenge token which stores the 'amr' value of the given first factor
+func (p Provider) issueMFAToken(userID, firstFactor string) (string, error) {
+	return p.issueToken(userID, storage.TokenTypeMFA, map[string]interface{}{mfaTokenFirstFactor: firstFactor})
+}
+
+// redeemMFAToken redeems the given mfa challenge token and returns the 'amr' value of the first factor it has been
+// issued for.
+// return ErrNoValidTokenFound when the mfa token is unknown or expired
+func (p Provider) redeemMFAToken(userID, mfaToken string) (string, error) {
+	t, err := p.redeemToken(userID, mfaToken, storage.TokenTypeMFA)
+	if err != nil {
+		return "", err
+	}
+
+	firstFactor, ok := t.Metadata[mfaTokenFirstFactor].(string)
+	if !ok {
+		// tokens without first factor have been issued by Login
+		return amrPassword, nil
+	}
+
+	return firstFactor, nil
+}
+
+// mfaMethods returns the enabled second factors ('totp', 'webauthn') of the user with the given id. Login requires a
+// second factor when at least one is enabled.
+func (p Provider) mfaMethods(userID string) ([]string, error) {
+	var methods []string
+
+	t, err := p.Storage.TOTP(userID)
+	if err != nil && !errors.Is(err, storage.ErrTOTPNotFound) {
+		return nil, fmt.Errorf("failed to query totp: %w", err)
+	}
+	if err == nil && t.Confirmed {
+		methods = append(methods, MFAMethodTOTP)
+	}
+
+	if p.WebAuthn != nil {
+		credentials, err := p.Storage.WebAuthnCredentials(userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query webauthn credentials: %w", err)
+		}
+		if len(credentials) > 0 {
+			methods = append(methods, MFAMethodWebAuthn)
+		}
+	}
+
+	return methods, nil
+}
+
+// requireSecondFactor verifies the given second factor when the user with the given id has an enabled second factor or
+// unused recovery codes. Users without any second factor need none.
+// return ErrSecondFactorRequired when the user has a second factor but none has been given
+// return ErrInvalidSecondFactor when the given second factor is invalid
+func (p Provider) requireSecondFactor(userID string, secondFactor SecondFactor) error {
+	methods, err := p.mfaMethods(userID)
+	if err != nil {
+		return err
+	}
+
+	if len(methods) == 0 {
+		count, err := p.Storage.UnusedRecoveryCodeCount(userID)
+		if err != nil {
+			return fmt.Errorf("failed to count recovery codes: %w", err)
+		}
+		if count == 0 {
+			return nil
+		}
+	}
+
+	if secondFactor.WebAuthn != nil {
+		if p.WebAuthn == nil {
+			return ErrInvalidSecondFactor
+		}
+
+		err = p.verifyWebAuthnAssertion(userID, *secondFactor.WebAuthn, false)
+		if errors.Is(err, ErrNoValidTokenFound) || errors.Is(err, ErrInvalidWebAuthnResponse) {
+			return fmt.Errorf("%w: %s", ErrInvalidSecondFactor, err)
+		}
+
+		return err
+	}
+
+	if secondFactor.Code == "" {
+		return ErrSecondFactorRequired
+	}
+
+	err = p.verifySecondFactorCode(userID, secondFactor.Code)
+	if errors.Is(err, ErrInvalidMFACode) {
+		return ErrInvalidSecondFactor
+	}
+
+	return err
+}
+
+// useTOTPCode validates the given code against the given totp, marks the totp as confirmed and stores the used time
+// step to prevent replays.
+// return ErrInvalidMFACode when the code is invalid
+func (p Provider) useTOTPCode(t storage.TOTP, code string) error {
+	secret, err := p.TOTPCrypter.Decrypt(t.Secret)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt totp secret: %w", err)
+	}
+
+	step, ok := totp.Validate(secret, code, nowFunc(), t.LastUsedStep)
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	t.Confirmed = true
+	t.LastUsedStep = step
+	err = p.Storage.SaveTOTP(t)
+	if err != nil {
+		return fmt.Errorf("failed to save totp: %w", err)
+	}
+
+	return nil
+}
+
+// withClaim returns a copy of the given claims with the given additional claim
+func withClaim(claims map[string]interface{}, key string, value interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(claims)+1)
+	for k, v := range claims {
+		c[k] = v
+	}
+	c[key] = value
+
+	return c
+}
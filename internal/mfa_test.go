@@ -0,0 +1,515 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"github.com/leberKleber/simple-jwt-provider/internal/totp"
+	"golang.org/x/crypto/bcrypt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// bcrypt hash of 'password'
+var testPasswordHash = []byte("$2a$12$1v7O.pNLqugJjcePyxvUj.GK37YoAbJvSW/9bULSRmq5C4SkoU2OO")
+
+// testCrypter "encrypts" by prefixing with 'enc:'
+var testCrypter = &SecretCrypterMock{
+	EncryptFunc: func(plaintext []byte) ([]byte, error) {
+		return append([]byte("enc:"), plaintext...), nil
+	},
+	DecryptFunc: func(ciphertext []byte) ([]byte, error) {
+		return bytes.TrimPrefix(ciphertext, []byte("enc:")), nil
+	},
+}
+
+// testTokenHasher "hashes" by prefixing with 'hashed-'
+var testTokenHasher = &TokenHasherMock{
+	HashFunc: func(token string) string {
+		return "hashed-" + token
+	},
+}
+
+// tokensOfType returns all given tokens with the given type like Storage.TokensByUserIDAndType
+func tokensOfType(tokens []storage.Token, tokenType string) []storage.Token {
+	var filtered []storage.Token
+	for _, t := range tokens {
+		if t.Type == tokenType {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered
+}
+
+func TestProvider_EnrolTOTP(t *testing.T) {
+	bcryptCost = bcrypt.MinCost
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+
+	tests := []struct {
+		name              string
+		crypter           SecretCrypter
+		givenPassword     string
+		givenSecondFactor SecondFactor
+		dbUserError       error
+		dbTOTP            storage.TOTP
+		dbTOTPError       error
+		dbCodeCount       int
+		dbUseCodeError    error
+		dbSaveError       error
+		expectedSave      bool
+		expectedError     error
+	}{
+		{
+			name:          "Happycase",
+			crypter:       testCrypter,
+			givenPassword: "password",
+			dbTOTPError:   storage.ErrTOTPNotFound,
+			expectedSave:  true,
+		}, {
+			name:          "Replace unconfirmed enrolment",
+			crypter:       testCrypter,
+			givenPassword: "password",
+			dbTOTP:        storage.TOTP{UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b"},
+			expectedSave:  true,
+		}, {
+			name:              "Happycase with recovery code as second factor",
+			crypter:           testCrypter,
+			givenPassword:     "password",
+			givenSecondFactor: SecondFactor{Code: "abcde-fghjk"},
+			dbTOTPError:       storage.ErrTOTPNotFound,
+			dbCodeCount:       3,
+			expectedSave:      true,
+		}, {
+			name:          "Second factor required",
+			crypter:       testCrypter,
+			givenPassword: "password",
+			dbTOTPError:   storage.ErrTOTPNotFound,
+			dbCodeCount:   3,
+			expectedError: ErrSecondFactorRequired,
+		}, {
+			name:              "Invalid second factor",
+			crypter:           testCrypter,
+			givenPassword:     "password",
+			givenSecondFactor: SecondFactor{Code: "abcde-fghjk"},
+			dbTOTPError:       storage.ErrTOTPNotFound,
+			dbCodeCount:       3,
+			dbUseCodeError:    storage.ErrRecoveryCodeNotFound,
+			expectedError:     ErrInvalidSecondFactor,
+		}, {
+			name:          "TOTP not configured",
+			givenPassword: "password",
+			expectedError: ErrTOTPNotConfigured,
+		}, {
+			name:          "Incorrect password",
+			crypter:       testCrypter,
+			givenPassword: "wrongPassword",
+			expectedError: ErrIncorrectPassword,
+		}, {
+			name:          "User not found",
+			crypter:       testCrypter,
+			givenPassword: "password",
+			dbUserError:   storage.ErrUserNotFound,
+			expectedError: ErrUserNotFound,
+		}, {
+			name:          "TOTP already enabled",
+			crypter:       testCrypter,
+			givenPassword: "password",
+			dbTOTP:        storage.TOTP{UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Confirmed: true},
+			expectedError: ErrTOTPAlreadyEnabled,
+		}, {
+			name:          "Unexpected totp db error",
+			crypter:       testCrypter,
+			givenPassword: "password",
+			dbTOTPError:   errors.New("nope"),
+			expectedError: errors.New("failed to query totp: nope"),
+		}, {
+			name:          "Unexpected save db error",
+			crypter:       testCrypter,
+			givenPassword: "password",
+			dbTOTPError:   storage.ErrTOTPNotFound,
+			dbSaveError:   errors.New("nope"),
+			expectedSave:  true,
+			expectedError: errors.New("failed to save totp: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var savedTOTP *storage.TOTP
+			toTest := Provider{
+				TOTPCrypter: tt.crypter,
+				TOTPIssuer:  "myIssuer",
+				TokenHasher: testTokenHasher,
+				Storage: &StorageMock{
+					UserFunc: func(email string) (storage.User, error) {
+						return storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", EMail: email, Password: testPasswordHash}, tt.dbUserError
+					},
+					TOTPFunc: func(userID string) (storage.TOTP, error) {
+						return tt.dbTOTP, tt.dbTOTPError
+					},
+					UnusedRecoveryCodeCountFunc: func(userID string) (int, error) {
+						return tt.dbCodeCount, nil
+					},
+					UseRecoveryCodeFunc: func(userID string, codeHash []byte, usedAt time.Time) error {
+						return tt.dbUseCodeError
+					},
+					SaveTOTPFunc: func(t storage.TOTP) error {
+						savedTOTP = &t
+						return tt.dbSaveError
+					},
+				},
+			}
+
+			enrolment, err := toTest.EnrolTOTP("test@test.test", tt.givenPassword, tt.givenSecondFactor)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			if (savedTOTP != nil) != tt.expectedSave {
+				t.Fatalf("Unexpected save call. Expected: %t", tt.expectedSave)
+			}
+
+			if tt.expectedError != nil {
+				return
+			}
+
+			expectedSavedTOTP := storage.TOTP{
+				UserID:    "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				Secret:    savedTOTP.Secret,
+				CreatedAt: now,
+			}
+			if !reflect.DeepEqual(*savedTOTP, expectedSavedTOTP) {
+				t.Errorf("Saved totp is not as expected. Expected:\n%#v\nGiven:\n%#v", expectedSavedTOTP, *savedTOTP)
+			}
+
+			expectedSecret := "enc:" + string(mustDecodeSecret(t, enrolment.Secret))
+			if string(savedTOTP.Secret) != expectedSecret {
+				t.Errorf("Saved secret is not encrypted returned secret. Expected: %q, Given: %q", expectedSecret, savedTOTP.Secret)
+			}
+
+			expectedURIPrefix := "otpauth://totp/myIssuer:test@test.test?"
+			if !strings.HasPrefix(enrolment.URI, expectedURIPrefix) || !strings.Contains(enrolment.URI, enrolment.Secret) {
+				t.Errorf("URI is not as expected. Given: %q", enrolment.URI)
+			}
+		})
+	}
+}
+
+func TestProvider_ConfirmTOTP(t *testing.T) {
+	bcryptCost = bcrypt.MinCost
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+
+	secret := []byte("12345678901234567890")
+	validCode := totp.Code(secret, totp.Step(now))
+
+	tests := []struct {
+		name              string
+		crypter           SecretCrypter
+		givenPassword     string
+		givenCode         string
+		givenSecondFactor SecondFactor
+		dbTOTP            storage.TOTP
+		dbTOTPError       error
+		dbCodeCount       int
+		expectedSaved     *storage.TOTP
+		expectedCodes     int
+		expectedError     error
+	}{
+		{
+			name:          "Happycase",
+			crypter:       testCrypter,
+			givenPassword: "password",
+			givenCode:     validCode,
+			dbTOTP:        storage.TOTP{UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Secret: append([]byte("enc:"), secret...)},
+			expectedSaved: &storage.TOTP{
+				UserID:       "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				Secret:       append([]byte("enc:"), secret...),
+				Confirmed:    true,
+				LastUsedStep: totp.Step(now),
+			},
+			expectedCodes: recoveryCodeCount,
+		}, {
+			name:              "Happycase with existing recovery codes",
+			crypter:           testCrypter,
+			givenPassword:     "password",
+			givenCode:         validCode,
+			givenSecondFactor: SecondFactor{Code: "abcde-fghjk"},
+			dbTOTP:            storage.TOTP{UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Secret: append([]byte("enc:"), secret...)},
+			dbCodeCount:       3,
+			expectedSaved: &storage.TOTP{
+				UserID:       "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				Secret:       append([]byte("enc:"), secret...),
+				Confirmed:    true,
+				LastUsedStep: totp.Step(now),
+			},
+		}, {
+			name:          "Second factor required with existing recovery codes",
+			crypter:       testCrypter,
+			givenPassword: "password",
+			givenCode:     validCode,
+			dbTOTP:        storage.TOTP{UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Secret: append([]byte("enc:"), secret...)},
+			dbCodeCount:   3,
+			expectedError: ErrSecondFactorRequired,
+		}, {
+			name:          "TOTP not configured",
+			givenPassword: "password",
+			givenCode:     validCode,
+			expectedError: ErrTOTPNotConfigured,
+		}, {
+			name:          "Incorrect password",
+			crypter:       testCrypter,
+			givenPassword: "wrongPassword",
+			givenCode:     validCode,
+			expectedError: ErrIncorrectPassword,
+		}, {
+			name:          "TOTP not enrolled",
+			crypter:       testCrypter,
+			givenPassword: "password",
+			givenCode:     validCode,
+			dbTOTPError:   storage.ErrTOTPNotFound,
+			expectedError: ErrTOTPNotEnrolled,
+		}, {
+			name:          "TOTP already enabled",
+			crypter:       testCrypter,
+			givenPassword: "password",
+			givenCode:     validCode,
+			dbTOTP:        storage.TOTP{UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Confirmed: true},
+			expectedError: ErrTOTPAlreadyEnabled,
+		}, {
+			name:          "Invalid code",
+			crypter:       testCrypter,
+			givenPassword: "password",
+			givenCode:     "000000",
+			dbTOTP:        storage.TOTP{UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Secret: append([]byte("enc:"), secret...)},
+			expectedError: ErrInvalidMFACode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var savedTOTP *storage.TOTP
+			toTest := Provider{
+				TOTPCrypter: tt.crypter,
+				TokenHasher: testTokenHasher,
+				Storage: &StorageMock{
+					UserFunc: func(email string) (storage.User, error) {
+						return storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", EMail: email, Password: testPasswordHash}, nil
+					},
+					TOTPFunc: func(userID string) (storage.TOTP, error) {
+						return tt.dbTOTP, tt.dbTOTPError
+					},
+					SaveTOTPFunc: func(t storage.TOTP) error {
+						savedTOTP = &t
+						return nil
+					},
+					UnusedRecoveryCodeCountFunc: func(userID string) (int, error) {
+						return tt.dbCodeCount, nil
+					},
+					ReplaceRecoveryCodesFunc: func(userID string, codeHashes [][]byte, createdAt time.Time) error {
+						return nil
+					},
+					UseRecoveryCodeFunc: func(userID string, codeHash []byte, usedAt time.Time) error {
+						return nil
+					},
+				},
+			}
+
+			codes, err := toTest.ConfirmTOTP("test@test.test", tt.givenPassword, tt.givenCode, tt.givenSecondFactor)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			if len(codes) != tt.expectedCodes {
+				t.Errorf("Count of returned recovery codes is not as expected. Expected: %d. Given: %d", tt.expectedCodes, len(codes))
+			}
+
+			if !reflect.DeepEqual(savedTOTP, tt.expectedSaved) {
+				t.Errorf("Saved totp is not as expected. Expected:\n%#v\nGiven:\n%#v", tt.expectedSaved, savedTOTP)
+			}
+		})
+	}
+}
+
+func TestProvider_LoginMFA(t *testing.T) {
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+
+	secret := []byte("12345678901234567890")
+	validCode := totp.Code(secret, totp.Step(now))
+	confirmedTOTP := storage.TOTP{UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Secret: append([]byte("enc:"), secret...), Confirmed: true}
+	validToken := storage.Token{ID: 42, UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Token: "hashed-myMFAToken", Type: storage.TokenTypeMFA, CreatedAt: now.Add(-time.Minute)}
+
+	tests := []struct {
+		name                string
+		crypter             SecretCrypter
+		givenCode           string
+		dbTokens            []storage.Token
+		dbTokensError       error
+		dbTOTP              storage.TOTP
+		dbTOTPError         error
+		expectedTokenDelete bool
+		expectedJWT         string
+		expectedClaims      map[string]interface{}
+		expectedError       error
+	}{
+		{
+			name:                "Happycase",
+			crypter:             testCrypter,
+			givenCode:           validCode,
+			dbTokens:            []storage.Token{validToken},
+			dbTOTP:              confirmedTOTP,
+			expectedTokenDelete: true,
+			expectedJWT:         "myJWT",
+			expectedClaims:      map[string]interface{}{"myCustomClaim": "value", "amr": []string{"pwd", "otp"}},
+		}, {
+			name:      "Happycase after login code",
+			crypter:   testCrypter,
+			givenCode: validCode,
+			dbTokens: []storage.Token{
+				{ID: 42, UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Token: "hashed-myMFAToken", Type: storage.TokenTypeMFA, CreatedAt: now.Add(-time.Minute), Metadata: map[string]interface{}{"first_factor": "otp"}},
+			},
+			dbTOTP:              confirmedTOTP,
+			expectedTokenDelete: true,
+			expectedJWT:         "myJWT",
+			expectedClaims:      map[string]interface{}{"myCustomClaim": "value", "amr": []string{"otp", "otp"}},
+		}, {
+			name:          "Unexpected tokens db error",
+			crypter:       testCrypter,
+			givenCode:     validCode,
+			dbTokensError: errors.New("nope"),
+			expectedError: errors.New("failed to find all available tokens: nope"),
+		}, {
+			name:          "Token not found",
+			crypter:       testCrypter,
+			givenCode:     validCode,
+			expectedError: ErrNoValidTokenFound,
+		}, {
+			name:      "Token expired",
+			crypter:   testCrypter,
+			givenCode: validCode,
+			dbTokens: []storage.Token{
+				{ID: 42, UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Token: "hashed-myMFAToken", Type: storage.TokenTypeMFA, CreatedAt: now.Add(-10 * time.Minute)},
+			},
+			expectedError: ErrTokenExpired,
+		}, {
+			name:      "Token of other type",
+			crypter:   testCrypter,
+			givenCode: validCode,
+			dbTokens: []storage.Token{
+				{ID: 42, UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Token: "hashed-myMFAToken", Type: storage.TokenTypeReset, CreatedAt: now},
+			},
+			expectedError: ErrNoValidTokenFound,
+		}, {
+			name:                "TOTP not configured",
+			givenCode:           validCode,
+			dbTokens:            []storage.Token{validToken},
+			expectedTokenDelete: true,
+			expectedError:       ErrTOTPNotConfigured,
+		}, {
+			name:                "TOTP not enrolled",
+			crypter:             testCrypter,
+			givenCode:           validCode,
+			dbTokens:            []storage.Token{validToken},
+			dbTOTPError:         storage.ErrTOTPNotFound,
+			expectedTokenDelete: true,
+			expectedError:       ErrTOTPNotEnrolled,
+		}, {
+			name:                "TOTP not confirmed",
+			crypter:             testCrypter,
+			givenCode:           validCode,
+			dbTokens:            []storage.Token{validToken},
+			dbTOTP:              storage.TOTP{UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Secret: append([]byte("enc:"), secret...)},
+			expectedTokenDelete: true,
+			expectedError:       ErrTOTPNotEnrolled,
+		}, {
+			name:                "Invalid code",
+			crypter:             testCrypter,
+			givenCode:           "000000",
+			dbTokens:            []storage.Token{validToken},
+			dbTOTP:              confirmedTOTP,
+			expectedTokenDelete: true,
+			expectedError:       ErrInvalidMFACode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenClaims map[string]interface{}
+			storageMock := &StorageMock{
+				TokensByUserIDAndTypeFunc: func(userID string, tokenType string) ([]storage.Token, error) {
+					return tokensOfType(tt.dbTokens, tokenType), tt.dbTokensError
+				},
+				DeleteTokenFunc: func(id int64) error {
+					return nil
+				},
+				TOTPFunc: func(userID string) (storage.TOTP, error) {
+					return tt.dbTOTP, tt.dbTOTPError
+				},
+				SaveTOTPFunc: func(t storage.TOTP) error {
+					return nil
+				},
+				UserFunc: func(email string) (storage.User, error) {
+					return storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", EMail: email, Claims: map[string]interface{}{"myCustomClaim": "value"}}, nil
+				},
+				AddLoginFunc: func(l storage.Login) error {
+					if l.Factor != LoginFactorTOTP {
+						t.Errorf("Unexpected login factor %q", l.Factor)
+					}
+					return nil
+				},
+			}
+			toTest := Provider{
+				TOTPCrypter:      tt.crypter,
+				MFATokenLifetime: 5 * time.Minute,
+				Storage:          storageMock,
+				TokenHasher:      testTokenHasher,
+				JWTGenerator: &JWTGeneratorMock{
+					GenerateFunc: func(userID string, email string, userClaims map[string]interface{}) (string, error) {
+						givenClaims = userClaims
+						return "myJWT", nil
+					},
+				},
+			}
+
+			jwt, err := toTest.LoginMFA("test@test.test", "myMFAToken", tt.givenCode, Client{})
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			if jwt != tt.expectedJWT {
+				t.Errorf("Given jwt is not as expected. Expected: %q, Given: %q", tt.expectedJWT, jwt)
+			}
+
+			deleteCalls := storageMock.DeleteTokenCalls()
+			if (len(deleteCalls) == 1) != tt.expectedTokenDelete {
+				t.Errorf("Unexpected count of DeleteToken calls: %d", len(deleteCalls))
+			}
+
+			if !reflect.DeepEqual(givenClaims, tt.expectedClaims) {
+				t.Errorf("Generator claims are not as expected. Expected:\n%#v\nGiven:\n%#v", tt.expectedClaims, givenClaims)
+			}
+		})
+	}
+}
+
+func mustDecodeSecret(t *testing.T, secret string) []byte {
+	t.Helper()
+	decoded, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatalf("failed to decode secret: %s", err)
+	}
+
+	return decoded
+}
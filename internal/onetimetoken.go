@@ -0,0 +1,283 @@
+package internal
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"github.com/sirupsen/logrus"
+	"math/big"
+	"time"
+)
+
+var ErrInvalidTokenCode = errors.New("invalid code")
+
+// tokenTypes are all types of one-time tokens
+var tokenTypes = []string{
+	storage.TokenTypeReset,
+	storage.TokenTypeMFA,
+	storage.TokenTypeWebAuthnRegistration,
+	storage.TokenTypeWebAuthnLogin,
+	storage.TokenTypeMagicLink,
+	storage.TokenTypeLoginCode,
+	storage.TokenTypeInvitation,
+}
+
+// tokenPolicy configures how one-time tokens of a type will be issued and redeemed
+type tokenPolicy struct {
+	// lifetime of the tokens
+	lifetime time.Duration
+	// maxAttempts limits the redemption attempts per token. Tokens with an attempt limit will be compared with the
+	// newest open token of the user. 0 allows unlimited attempts
+	maxAttempts int
+	// codeDigits issues numeric codes with the given count of digits instead of 64 char hex tokens when > 0
+	codeDigits int
+}
+
+// tokenPolicy returns the policy of tokens with the given type. Magic link tokens live MagicLinkLifetime, login codes
+// LoginCodeLifetime (with LoginCodeMaxAttempts attempts), invitations InvitationLifetime, password reset tokens
+// PasswordResetTokenLifetime and all other tokens (mfa challenges, webauthn challenges) MFATokenLifetime.
+func (p Provider) tokenPolicy(tokenType string) tokenPolicy {
+	switch tokenType {
+	case storage.TokenTypeMagicLink:
+		return tokenPolicy{lifetime: p.MagicLinkLifetime}
+	case storage.TokenTypeLoginCode:
+		return tokenPolicy{lifetime: p.LoginCodeLifetime, maxAttempts: p.LoginCodeMaxAttempts, codeDigits: loginCodeDigits}
+	case storage.TokenTypeInvitation:
+		return tokenPolicy{lifetime: p.InvitationLifetime}
+	case storage.TokenTypeReset:
+		return tokenPolicy{lifetime: p.PasswordResetTokenLifetime}
+	default:
+		return tokenPolicy{lifetime: p.MFATokenLifetime}
+	}
+}
+
+// issueToken generates a new one-time token of the given type for the user with the given id and stores it with the
+// given metadata (optional) like createToken. Open tokens of the user stay valid, see revokeTokens.
+func (p Provider) issueToken(userID, tokenType string, metadata map[string]interface{}) (string, error) {
+	var t string
+	var err error
+	if digits := p.tokenPolicy(tokenType).codeDigits; digits > 0 {
+		t, err = generateNumericCode(digits)
+	} else {
+		t, err = generateHEXToken()
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to generate %s-token: %w", tokenType, err)
+	}
+
+	err = p.createToken(userID, tokenType, t, metadata)
+	if err != nil {
+		return "", err
+	}
+
+	return t, nil
+}
+
+// createToken stores the given (externally generated) one-time token of the given type for the user with the given id.
+// Only the hash of the token will be stored.
+func (p Provider) createToken(userID, tokenType, token string, metadata map[string]interface{}) error {
+	_, err := p.Storage.CreateToken(storage.Token{
+		UserID:    userID,
+		Token:     p.TokenHasher.Hash(token),
+		Type:      tokenType,
+		CreatedAt: nowFunc(),
+		Metadata:  metadata,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create %s-token for user %q: %w", tokenType, userID, err)
+	}
+
+	tokenLog(userID, tokenType).Info("One-time token issued")
+	return nil
+}
+
+// findToken finds a not expired token of the given type. Tokens will be compared by their hash in constant time.
+// return ErrNoValidTokenFound when there is no such token
+// return ErrTokenExpired (which is an ErrNoValidTokenFound too) when the token has expired
+func (p Provider) findToken(userID, token, tokenType string) (storage.Token, error) {
+	tokens, err := p.Storage.TokensByUserIDAndType(userID, tokenType)
+	if err != nil {
+		return storage.Token{}, fmt.Errorf("failed to find all available tokens: %w", err)
+	}
+
+	hash := p.TokenHasher.Hash(token)
+	lifetime := p.tokenPolicy(tokenType).lifetime
+	expired := false
+	for _, t := range tokens {
+		if !tokenHashEqual(t.Token, hash) {
+			continue
+		}
+		if nowFunc().Before(t.CreatedAt.Add(lifetime)) {
+			return t, nil
+		}
+		expired = true
+	}
+
+	if expired {
+		return storage.Token{}, ErrTokenExpired
+	}
+	return storage.Token{}, ErrNoValidTokenFound
+}
+
+// redeemToken finds a not expired token like findToken and deletes it, so it can only be used once. Tokens of types
+// with an attempt limit will be redeemed like redeemCode.
+// return ErrNoValidTokenFound when there is no such token or it has been redeemed concurrently
+// return ErrInvalidTokenCode when the token does not match the newest open token of a type with an attempt limit
+func (p Provider) redeemToken(userID, token, tokenType string) (storage.Token, error) {
+	if p.tokenPolicy(tokenType).maxAttempts > 0 {
+		return p.redeemCode(userID, token, tokenType)
+	}
+
+	t, err := p.findToken(userID, token, tokenType)
+	if err != nil {
+		return storage.Token{}, err
+	}
+
+	err = p.Storage.DeleteToken(t.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTokenNotFound) {
+			return storage.Token{}, ErrNoValidTokenFound
+		}
+		return storage.Token{}, fmt.Errorf("failed to delete token: %w", err)
+	}
+
+	tokenLog(userID, tokenType).Info("One-time token redeemed")
+	return t, nil
+}
+
+// redeemCode compares the given code with the newest open token of the given type. Each attempt counts, the token
+// will be deleted after maxAttempts attempts or a successful redemption.
+// return ErrNoValidTokenFound when there is no open token or its attempts are exhausted
+// return ErrInvalidTokenCode when the code does not match
+func (p Provider) redeemCode(userID, code, tokenType string) (storage.Token, error) {
+	tokens, err := p.Storage.TokensByUserIDAndType(userID, tokenType)
+	if err != nil {
+		return storage.Token{}, fmt.Errorf("failed to find all available tokens: %w", err)
+	}
+
+	policy := p.tokenPolicy(tokenType)
+	var t *storage.Token
+	for _, token := range tokens {
+		if nowFunc().Before(token.CreatedAt.Add(policy.lifetime)) {
+			t = &token
+			break
+		}
+	}
+	if t == nil {
+		return storage.Token{}, ErrNoValidTokenFound
+	}
+
+	// count the attempt before comparing, so concurrent attempts can not exceed the limit
+	attempts, err := p.Storage.IncrementTokenAttempts(t.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTokenNotFound) {
+			return storage.Token{}, ErrNoValidTokenFound
+		}
+		return storage.Token{}, fmt.Errorf("failed to count %s attempt: %w", tokenType, err)
+	}
+
+	if attempts > policy.maxAttempts {
+		return storage.Token{}, p.invalidateToken(userID, tokenType, t.ID, ErrNoValidTokenFound)
+	}
+
+	if !tokenHashEqual(t.Token, p.TokenHasher.Hash(code)) {
+		if attempts == policy.maxAttempts {
+			return storage.Token{}, p.invalidateToken(userID, tokenType, t.ID, ErrInvalidTokenCode)
+		}
+		return storage.Token{}, ErrInvalidTokenCode
+	}
+
+	err = p.Storage.DeleteToken(t.ID)
+	if err != nil {
+		return storage.Token{}, fmt.Errorf("failed to delete token: %w", err)
+	}
+
+	tokenLog(userID, tokenType).Info("One-time token redeemed")
+	return *t, nil
+}
+
+// invalidateToken deletes the token with the given id after its attempts have been exhausted and returns the given
+// error when successful
+func (p Provider) invalidateToken(userID, tokenType string, id int64, reason error) error {
+	err := p.Storage.DeleteToken(id)
+	if err != nil && !errors.Is(err, storage.ErrTokenNotFound) {
+		return fmt.Errorf("failed to delete token: %w", err)
+	}
+
+	tokenLog(userID, tokenType).Warn("One-time token invalidated after too many attempts")
+	return reason
+}
+
+// revokeTokens deletes all tokens with the given type of the user with the given id
+func (p Provider) revokeTokens(userID, tokenType string) error {
+	tokens, err := p.Storage.TokensByUserIDAndType(userID, tokenType)
+	if err != nil {
+		return fmt.Errorf("failed to find %s-tokens of user %q: %w", tokenType, userID, err)
+	}
+
+	for _, t := range tokens {
+		err = p.Storage.DeleteToken(t.ID)
+		if err != nil && !errors.Is(err, storage.ErrTokenNotFound) {
+			return fmt.Errorf("failed to delete %s-token: %w", tokenType, err)
+		}
+	}
+
+	if len(tokens) > 0 {
+		tokenLog(userID, tokenType).WithField("count", len(tokens)).Info("One-time tokens revoked")
+	}
+	return nil
+}
+
+// PurgeTokens deletes the expired one-time tokens of all types and returns the count of deleted tokens. All tokens of
+// disabled types (lifetime 0) will be deleted.
+func (p Provider) PurgeTokens() (int64, error) {
+	now := nowFunc()
+
+	var count int64
+	for _, tokenType := range tokenTypes {
+		n, err := p.Storage.DeleteTokensCreatedBefore(tokenType, now.Add(-p.tokenPolicy(tokenType).lifetime))
+		if err != nil {
+			return count, fmt.Errorf("failed to purge %s-tokens: %w", tokenType, err)
+		}
+		count += n
+	}
+
+	if count > 0 {
+		logrus.WithField("count", count).Info("Expired one-time tokens purged")
+	}
+	return count, nil
+}
+
+// tokenHashEqual compares the given token hashes in constant time
+func tokenHashEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// tokenLog returns the audit log entry of one-time token operations. Tokens themselves will never be logged.
+func tokenLog(userID, tokenType string) *logrus.Entry {
+	return logrus.WithFields(logrus.Fields{"user_id": userID, "token_type": tokenType})
+}
+
+// generateHEXToken generates a random 64 char long hex token (32 bytes == 64 hex chars)
+func generateHEXToken() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	return fmt.Sprintf("%x", b), err
+}
+
+// generateNumericCode generates a random numeric code with the given count of digits
+func generateNumericCode(digits int) (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < digits; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%0*d", digits, n), nil
+}
@@ -0,0 +1,322 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestProvider_IssueToken(t *testing.T) {
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+
+	tests := []struct {
+		name                string
+		givenTokenType      string
+		givenMetadata       map[string]interface{}
+		dbCreateTokenError  error
+		expectedTokenFormat string
+		expectedError       error
+	}{
+		{
+			name:                "Happycase token",
+			givenTokenType:      storage.TokenTypeReset,
+			expectedTokenFormat: "^[0-9a-f]{64}$",
+		}, {
+			name:                "Happycase code",
+			givenTokenType:      storage.TokenTypeLoginCode,
+			expectedTokenFormat: "^[0-9]{6}$",
+		}, {
+			name:                "Happycase with metadata",
+			givenTokenType:      storage.TokenTypeInvitation,
+			givenMetadata:       map[string]interface{}{"redirect": "/welcome"},
+			expectedTokenFormat: "^[0-9a-f]{64}$",
+		}, {
+			name:               "Unexpected db error",
+			givenTokenType:     storage.TokenTypeReset,
+			dbCreateTokenError: errors.New("nope"),
+			expectedError:      errors.New("failed to create reset-token for user \"c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b\": nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var createdToken storage.Token
+			toTest := Provider{
+				Storage: &StorageMock{
+					CreateTokenFunc: func(t storage.Token) (int64, error) {
+						createdToken = t
+						return 1, tt.dbCreateTokenError
+					},
+				},
+				TokenHasher: testTokenHasher,
+			}
+
+			token, err := toTest.issueToken("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", tt.givenTokenType, tt.givenMetadata)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+			if tt.expectedError != nil {
+				return
+			}
+
+			if !regexp.MustCompile(tt.expectedTokenFormat).MatchString(token) {
+				t.Errorf("Token %q does not match the expected format %q", token, tt.expectedTokenFormat)
+			}
+
+			expectedToken := storage.Token{
+				UserID:    "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				Token:     "hashed-" + token,
+				Type:      tt.givenTokenType,
+				CreatedAt: now,
+				Metadata:  tt.givenMetadata,
+			}
+			if !reflect.DeepEqual(createdToken, expectedToken) {
+				t.Errorf("Created token is not as expected. Expected:\n%#v\nGiven:\n%#v", expectedToken, createdToken)
+			}
+		})
+	}
+}
+
+func TestProvider_RedeemToken(t *testing.T) {
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+
+	validToken := storage.Token{ID: 42, UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Token: "hashed-myToken", Type: storage.TokenTypeInvitation, CreatedAt: now.Add(-time.Hour), Metadata: map[string]interface{}{"redirect": "/welcome"}}
+	validCode := storage.Token{ID: 43, UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Token: "hashed-123456", Type: storage.TokenTypeLoginCode, CreatedAt: now.Add(-time.Minute)}
+
+	tests := []struct {
+		name              string
+		givenToken        string
+		givenTokenType    string
+		dbTokens          []storage.Token
+		dbAttempts        int
+		dbDeleteError     error
+		expectedToken     storage.Token
+		expectedDeleteIDs []int64
+		expectedError     error
+	}{
+		{
+			name:              "Happycase token with metadata",
+			givenToken:        "myToken",
+			givenTokenType:    storage.TokenTypeInvitation,
+			dbTokens:          []storage.Token{validToken},
+			expectedToken:     validToken,
+			expectedDeleteIDs: []int64{42},
+		}, {
+			name:           "Unknown token",
+			givenToken:     "otherToken",
+			givenTokenType: storage.TokenTypeInvitation,
+			dbTokens:       []storage.Token{validToken},
+			expectedError:  ErrNoValidTokenFound,
+		}, {
+			name:              "Token redeemed concurrently",
+			givenToken:        "myToken",
+			givenTokenType:    storage.TokenTypeInvitation,
+			dbTokens:          []storage.Token{validToken},
+			dbDeleteError:     storage.ErrTokenNotFound,
+			expectedDeleteIDs: []int64{42},
+			expectedError:     ErrNoValidTokenFound,
+		}, {
+			name:              "Unexpected db error while deleting token",
+			givenToken:        "myToken",
+			givenTokenType:    storage.TokenTypeInvitation,
+			dbTokens:          []storage.Token{validToken},
+			dbDeleteError:     errors.New("nope"),
+			expectedDeleteIDs: []int64{42},
+			expectedError:     errors.New("failed to delete token: nope"),
+		}, {
+			name:              "Happycase code",
+			givenToken:        "123456",
+			givenTokenType:    storage.TokenTypeLoginCode,
+			dbTokens:          []storage.Token{validCode},
+			dbAttempts:        1,
+			expectedToken:     validCode,
+			expectedDeleteIDs: []int64{43},
+		}, {
+			name:           "Invalid code",
+			givenToken:     "654321",
+			givenTokenType: storage.TokenTypeLoginCode,
+			dbTokens:       []storage.Token{validCode},
+			dbAttempts:     1,
+			expectedError:  ErrInvalidTokenCode,
+		}, {
+			name:              "Invalid code with last attempt",
+			givenToken:        "654321",
+			givenTokenType:    storage.TokenTypeLoginCode,
+			dbTokens:          []storage.Token{validCode},
+			dbAttempts:        3,
+			expectedDeleteIDs: []int64{43},
+			expectedError:     ErrInvalidTokenCode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deletedIDs []int64
+			toTest := Provider{
+				InvitationLifetime:   72 * time.Hour,
+				LoginCodeLifetime:    5 * time.Minute,
+				LoginCodeMaxAttempts: 3,
+				Storage: &StorageMock{
+					TokensByUserIDAndTypeFunc: func(userID string, tokenType string) ([]storage.Token, error) {
+						return tokensOfType(tt.dbTokens, tokenType), nil
+					},
+					IncrementTokenAttemptsFunc: func(id int64) (int, error) {
+						return tt.dbAttempts, nil
+					},
+					DeleteTokenFunc: func(id int64) error {
+						deletedIDs = append(deletedIDs, id)
+						return tt.dbDeleteError
+					},
+				},
+				TokenHasher: testTokenHasher,
+			}
+
+			token, err := toTest.redeemToken("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", tt.givenToken, tt.givenTokenType)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(token, tt.expectedToken) {
+				t.Errorf("Redeemed token is not as expected. Expected:\n%#v\nGiven:\n%#v", tt.expectedToken, token)
+			}
+
+			if !reflect.DeepEqual(deletedIDs, tt.expectedDeleteIDs) {
+				t.Errorf("Deleted tokens are not as expected. Expected: %v, Given: %v", tt.expectedDeleteIDs, deletedIDs)
+			}
+		})
+	}
+}
+
+func TestProvider_RevokeTokens(t *testing.T) {
+	tests := []struct {
+		name              string
+		dbTokens          []storage.Token
+		dbTokensError     error
+		dbDeleteError     error
+		expectedDeleteIDs []int64
+		expectedError     error
+	}{
+		{
+			name:              "Happycase",
+			dbTokens:          []storage.Token{{ID: 1}, {ID: 2}},
+			expectedDeleteIDs: []int64{1, 2},
+		}, {
+			name:              "Token deleted concurrently",
+			dbTokens:          []storage.Token{{ID: 1}},
+			dbDeleteError:     storage.ErrTokenNotFound,
+			expectedDeleteIDs: []int64{1},
+		}, {
+			name:          "Unexpected db error while finding tokens",
+			dbTokensError: errors.New("nope"),
+			expectedError: errors.New("failed to find reset-tokens of user \"c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b\": nope"),
+		}, {
+			name:              "Unexpected db error while deleting token",
+			dbTokens:          []storage.Token{{ID: 1}, {ID: 2}},
+			dbDeleteError:     errors.New("nope"),
+			expectedDeleteIDs: []int64{1},
+			expectedError:     errors.New("failed to delete reset-token: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deletedIDs []int64
+			toTest := Provider{
+				Storage: &StorageMock{
+					TokensByUserIDAndTypeFunc: func(userID string, tokenType string) ([]storage.Token, error) {
+						return tt.dbTokens, tt.dbTokensError
+					},
+					DeleteTokenFunc: func(id int64) error {
+						deletedIDs = append(deletedIDs, id)
+						return tt.dbDeleteError
+					},
+				},
+			}
+
+			err := toTest.revokeTokens("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", storage.TokenTypeReset)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(deletedIDs, tt.expectedDeleteIDs) {
+				t.Errorf("Deleted tokens are not as expected. Expected: %v, Given: %v", tt.expectedDeleteIDs, deletedIDs)
+			}
+		})
+	}
+}
+
+func TestProvider_PurgeTokens(t *testing.T) {
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+
+	tests := []struct {
+		name          string
+		dbDeleteError error
+		expectedCount int64
+		expectedError error
+	}{
+		{
+			name:          "Happycase",
+			expectedCount: 7,
+		}, {
+			name:          "Unexpected db error",
+			dbDeleteError: errors.New("nope"),
+			expectedError: errors.New("failed to purge reset-tokens: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			createdBefore := map[string]time.Time{}
+			toTest := Provider{
+				PasswordResetTokenLifetime: time.Hour,
+				MFATokenLifetime:           5 * time.Minute,
+				MagicLinkLifetime:          15 * time.Minute,
+				InvitationLifetime:         72 * time.Hour,
+				Storage: &StorageMock{
+					DeleteTokensCreatedBeforeFunc: func(tokenType string, before time.Time) (int64, error) {
+						createdBefore[tokenType] = before
+						return 1, tt.dbDeleteError
+					},
+				},
+			}
+
+			count, err := toTest.PurgeTokens()
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+			if tt.expectedError != nil {
+				return
+			}
+
+			if count != tt.expectedCount {
+				t.Errorf("Purged count is not as expected. Expected: %d, Given: %d", tt.expectedCount, count)
+			}
+
+			expectedCreatedBefore := map[string]time.Time{
+				storage.TokenTypeReset:                now.Add(-time.Hour),
+				storage.TokenTypeMFA:                  now.Add(-5 * time.Minute),
+				storage.TokenTypeWebAuthnRegistration: now.Add(-5 * time.Minute),
+				storage.TokenTypeWebAuthnLogin:        now.Add(-5 * time.Minute),
+				storage.TokenTypeMagicLink:            now.Add(-15 * time.Minute),
+				storage.TokenTypeLoginCode:            now,
+				storage.TokenTypeInvitation:           now.Add(-72 * time.Hour),
+			}
+			if !reflect.DeepEqual(createdBefore, expectedCreatedBefore) {
+				t.Errorf("Purged tokens are not as expected. Expected:\n%v\nGiven:\n%v", expectedCreatedBefore, createdBefore)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const passwordBreachedClaim = "pwd_breached"
+
+var ErrPasswordBreached = errors.New("password has been found in a data breach")
+var ErrPasswordReused = errors.New("password has been used recently")
+
+// checkNewPassword checks the given password which should be set for the given user against the configured
+// PasswordBreachChecker and the password history of the user.
+// return ErrPasswordBreached when the password is breached and PasswordBreachWarnOnly is false
+// return ErrPasswordReused when the password matches the current or one of the last passwords of the user
+func (p Provider) checkNewPassword(u storage.User, password string) error {
+	err := p.checkPasswordBreach(u.ID, password)
+	if err != nil {
+		return err
+	}
+
+	return p.checkPasswordHistory(u, password)
+}
+
+func (p Provider) checkPasswordBreach(userID, password string) error {
+	if p.PasswordBreachChecker == nil {
+		return nil
+	}
+
+	breached, err := p.PasswordBreachChecker.IsBreached(password)
+	if err != nil {
+		return fmt.Errorf("failed to check password against breach dataset: %w", err)
+	}
+
+	if !breached {
+		return nil
+	}
+
+	if p.PasswordBreachWarnOnly {
+		logrus.WithField("user", userID).Warn("User has set a password which has been found in a data breach")
+		return nil
+	}
+
+	return ErrPasswordBreached
+}
+
+// checkPasswordHistory compares the given password with the current and the last PasswordHistorySize passwords of the
+// given user. Users without a current password (not created yet) will not be checked.
+func (p Provider) checkPasswordHistory(u storage.User, password string) error {
+	if p.PasswordHistorySize <= 0 || u.Password == nil {
+		return nil
+	}
+
+	hashes, err := p.Storage.PasswordHistory(u.ID, p.PasswordHistorySize)
+	if err != nil {
+		return fmt.Errorf("failed to find password history: %w", err)
+	}
+	hashes = append(hashes, u.Password)
+
+	for _, hash := range hashes {
+		if bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil {
+			return ErrPasswordReused
+		}
+	}
+
+	return nil
+}
+
+// passwordChanged invalidates all open password reset tokens of the user with the given id and adds the given new
+// password hash to the password history. It has to be called after each password change.
+func (p Provider) passwordChanged(userID string, password []byte) error {
+	err := p.revokeTokens(userID, storage.TokenTypeReset)
+	if err != nil {
+		return err
+	}
+
+	return p.recordPasswordHistory(userID, password)
+}
+
+// recordPasswordHistory adds the given password hash to the password history of the user with the given id
+func (p Provider) recordPasswordHistory(userID string, password []byte) error {
+	if p.PasswordHistorySize <= 0 {
+		return nil
+	}
+
+	err := p.Storage.AddPasswordHistory(userID, password, nowFunc(), p.PasswordHistorySize)
+	if err != nil {
+		return fmt.Errorf("failed to add password to history: %w", err)
+	}
+
+	return nil
+}
+
+// loginClaims returns the claims for a jwt issued after a successful login with the given password. In
+// PasswordBreachWarnOnly mode, breached passwords will be marked with the 'pwd_breached' claim.
+func (p Provider) loginClaims(userID, password string, userClaims map[string]interface{}) map[string]interface{} {
+	if p.PasswordBreachChecker == nil || !p.PasswordBreachWarnOnly {
+		return userClaims
+	}
+
+	breached, err := p.PasswordBreachChecker.IsBreached(password)
+	if err != nil {
+		logrus.WithError(err).WithField("user", userID).Error("Failed to check password against breach dataset")
+		return userClaims
+	}
+
+	if !breached {
+		return userClaims
+	}
+
+	logrus.WithField("user", userID).Warn("User logged in with a password which has been found in a data breach")
+	return withClaim(userClaims, passwordBreachedClaim, true)
+}
@@ -0,0 +1,283 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"golang.org/x/crypto/bcrypt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProvider_checkPasswordBreach(t *testing.T) {
+	tests := []struct {
+		name                  string
+		breachChecker         bool
+		breachWarnOnly        bool
+		breachCheckerBreached bool
+		breachCheckerError    error
+		expectedError         error
+	}{
+		{
+			name: "Without breach checker",
+		}, {
+			name:          "Password not breached",
+			breachChecker: true,
+		}, {
+			name:                  "Password breached",
+			breachChecker:         true,
+			breachCheckerBreached: true,
+			expectedError:         ErrPasswordBreached,
+		}, {
+			name:                  "Password breached in warn only mode",
+			breachChecker:         true,
+			breachWarnOnly:        true,
+			breachCheckerBreached: true,
+		}, {
+			name:               "Breach checker error",
+			breachChecker:      true,
+			breachCheckerError: errors.New("dataset gone"),
+			expectedError:      errors.New("failed to check password against breach dataset: dataset gone"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenPassword string
+			toTest := Provider{PasswordBreachWarnOnly: tt.breachWarnOnly}
+			if tt.breachChecker {
+				toTest.PasswordBreachChecker = &PasswordBreachCheckerMock{
+					IsBreachedFunc: func(password string) (bool, error) {
+						givenPassword = password
+						return tt.breachCheckerBreached, tt.breachCheckerError
+					},
+				}
+			}
+
+			err := toTest.checkPasswordBreach("test@test.test", "s3cr3t")
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			if tt.breachChecker && givenPassword != "s3cr3t" {
+				t.Errorf("Checked password is not as expected. Expected: %q, Given: %q", "s3cr3t", givenPassword)
+			}
+		})
+	}
+}
+
+func TestProvider_checkPasswordHistory(t *testing.T) {
+	//bcrypt hash of "password"
+	passwordHash := []byte("$2a$12$1v7O.pNLqugJjcePyxvUj.GK37YoAbJvSW/9bULSRmq5C4SkoU2OO")
+	otherHash, err := bcrypt.GenerateFromPassword([]byte("other"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to bcrypt password: %s", err)
+	}
+
+	tests := []struct {
+		name                 string
+		givenHistorySize     int
+		givenUser            storage.User
+		givenPassword        string
+		dbHistory            [][]byte
+		dbHistoryError       error
+		expectedHistoryQuery bool
+		expectedError        error
+	}{
+		{
+			name:          "History disabled",
+			givenUser:     storage.User{EMail: "test@test.test", Password: passwordHash},
+			givenPassword: "password",
+		}, {
+			name:             "New user",
+			givenHistorySize: 3,
+			givenUser:        storage.User{EMail: "test@test.test"},
+			givenPassword:    "password",
+		}, {
+			name:                 "Password not used before",
+			givenHistorySize:     3,
+			givenUser:            storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Password: otherHash},
+			givenPassword:        "password",
+			dbHistory:            [][]byte{otherHash},
+			expectedHistoryQuery: true,
+		}, {
+			name:                 "Password matches current password",
+			givenHistorySize:     3,
+			givenUser:            storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Password: passwordHash},
+			givenPassword:        "password",
+			expectedHistoryQuery: true,
+			expectedError:        ErrPasswordReused,
+		}, {
+			name:                 "Password matches history",
+			givenHistorySize:     3,
+			givenUser:            storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Password: otherHash},
+			givenPassword:        "password",
+			dbHistory:            [][]byte{otherHash, passwordHash},
+			expectedHistoryQuery: true,
+			expectedError:        ErrPasswordReused,
+		}, {
+			name:                 "Unexpected db error",
+			givenHistorySize:     3,
+			givenUser:            storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Password: otherHash},
+			givenPassword:        "password",
+			dbHistoryError:       errors.New("nope"),
+			expectedHistoryQuery: true,
+			expectedError:        errors.New("failed to find password history: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			historyQueried := false
+			toTest := Provider{
+				PasswordHistorySize: tt.givenHistorySize,
+				Storage: &StorageMock{
+					PasswordHistoryFunc: func(userID string, limit int) ([][]byte, error) {
+						historyQueried = true
+						if userID != tt.givenUser.ID {
+							t.Errorf("Unexpected user id. Expected: %q, Given: %q", tt.givenUser.ID, userID)
+						}
+						if limit != tt.givenHistorySize {
+							t.Errorf("Unexpected limit. Expected: %d, Given: %d", tt.givenHistorySize, limit)
+						}
+						return tt.dbHistory, tt.dbHistoryError
+					},
+				},
+			}
+
+			err := toTest.checkPasswordHistory(tt.givenUser, tt.givenPassword)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			if historyQueried != tt.expectedHistoryQuery {
+				t.Errorf("History query is not as expected. Expected: %t, Given: %t", tt.expectedHistoryQuery, historyQueried)
+			}
+		})
+	}
+}
+
+func TestProvider_recordPasswordHistory(t *testing.T) {
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	now := time.Date(2020, 2, 1, 4, 46, 45, 2, time.UTC)
+	nowFunc = func() time.Time { return now }
+
+	tests := []struct {
+		name             string
+		givenHistorySize int
+		dbError          error
+		expectedCall     bool
+		expectedError    error
+	}{
+		{
+			name: "History disabled",
+		}, {
+			name:             "Happycase",
+			givenHistorySize: 3,
+			expectedCall:     true,
+		}, {
+			name:             "Unexpected db error",
+			givenHistorySize: 3,
+			dbError:          errors.New("nope"),
+			expectedCall:     true,
+			expectedError:    errors.New("failed to add password to history: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storageMock := &StorageMock{
+				AddPasswordHistoryFunc: func(userID string, password []byte, createdAt time.Time, keep int) error {
+					return tt.dbError
+				},
+			}
+			toTest := Provider{PasswordHistorySize: tt.givenHistorySize, Storage: storageMock}
+
+			err := toTest.recordPasswordHistory("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", []byte("hash"))
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			calls := storageMock.AddPasswordHistoryCalls()
+			if (len(calls) == 1) != tt.expectedCall {
+				t.Fatalf("Unexpected count of AddPasswordHistory calls: %d", len(calls))
+			}
+
+			if tt.expectedCall {
+				expectedCall := struct {
+					UserID    string
+					Password  []byte
+					CreatedAt time.Time
+					Keep      int
+				}{UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Password: []byte("hash"), CreatedAt: now, Keep: 3}
+				if !reflect.DeepEqual(calls[0], expectedCall) {
+					t.Errorf("AddPasswordHistory call is not as expected. Expected:\n%#v\nGiven:\n%#v", expectedCall, calls[0])
+				}
+			}
+		})
+	}
+}
+
+func TestProvider_loginClaims(t *testing.T) {
+	userClaims := map[string]interface{}{"myCustomClaim": "value"}
+
+	tests := []struct {
+		name                  string
+		breachChecker         bool
+		breachWarnOnly        bool
+		breachCheckerBreached bool
+		breachCheckerError    error
+		expectedClaims        map[string]interface{}
+	}{
+		{
+			name:           "Without breach checker",
+			expectedClaims: map[string]interface{}{"myCustomClaim": "value"},
+		}, {
+			name:                  "Reject mode",
+			breachChecker:         true,
+			breachCheckerBreached: true,
+			expectedClaims:        map[string]interface{}{"myCustomClaim": "value"},
+		}, {
+			name:                  "Warn only mode with breached password",
+			breachChecker:         true,
+			breachWarnOnly:        true,
+			breachCheckerBreached: true,
+			expectedClaims:        map[string]interface{}{"myCustomClaim": "value", "pwd_breached": true},
+		}, {
+			name:           "Warn only mode with not breached password",
+			breachChecker:  true,
+			breachWarnOnly: true,
+			expectedClaims: map[string]interface{}{"myCustomClaim": "value"},
+		}, {
+			name:               "Warn only mode with breach checker error",
+			breachChecker:      true,
+			breachWarnOnly:     true,
+			breachCheckerError: errors.New("dataset gone"),
+			expectedClaims:     map[string]interface{}{"myCustomClaim": "value"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toTest := Provider{PasswordBreachWarnOnly: tt.breachWarnOnly}
+			if tt.breachChecker {
+				toTest.PasswordBreachChecker = &PasswordBreachCheckerMock{
+					IsBreachedFunc: func(password string) (bool, error) {
+						return tt.breachCheckerBreached, tt.breachCheckerError
+					},
+				}
+			}
+
+			claims := toTest.loginClaims("test@test.test", "s3cr3t", userClaims)
+			if !reflect.DeepEqual(claims, tt.expectedClaims) {
+				t.Errorf("Claims are not as expected: \nExpected:\n%#v\nGiven:\n%#v", tt.expectedClaims, claims)
+			}
+
+			if _, found := userClaims[passwordBreachedClaim]; found {
+				t.Error("User claims must not be modified")
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/jsonpatch"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+)
+
+// PatchFormat is the format of a patch applied by PatchUser
+type PatchFormat int
+
+const (
+	// MergePatch is a json merge patch (RFC 7396)
+	MergePatch PatchFormat = iota
+	// JSONPatch is a json patch (RFC 6902)
+	JSONPatch
+)
+
+var ErrInvalidPatch = jsonpatch.ErrInvalid
+var ErrPatchNotApplicable = jsonpatch.ErrNotApplicable
+var ErrInvalidPatchedUser = errors.New("patched user is invalid")
+
+// userDocument is the json document of all mutable properties of a user which will be patched by PatchUser. The
+// password is write only and therefore never part of the document before patching.
+type userDocument struct {
+	Username           *string                `json:"username,omitempty"`
+	Phone              *string                `json:"phone,omitempty"`
+	Password           string                 `json:"password,omitempty"`
+	Claims             map[string]interface{} `json:"claims"`
+	Metadata           map[string]interface{} `json:"metadata"`
+	PasswordMaxAgeDays *int                   `json:"password_max_age_days,omitempty"`
+}
+
+// PatchUser applies the given patch to the mutable properties (username, phone, password, claims, metadata and
+// password_max_age_days) of the user with the given id or login identifier. The user is locked in storage while the
+// patch is applied, so concurrent patches of different properties or claims will never overwrite each other. Removed
+// properties will be removed from the user, the last login identifier can not be removed.
+// return ErrUserNotFound when user does not exist
+// return ErrInvalidPatch when the patch is malformed
+// return ErrPatchNotApplicable when the patch references a property which does not exist or a test operation failed
+// return ErrInvalidPatchedUser when the patched document contains unknown properties or properties of the wrong type
+// return all errors of UpdateUser
+func (p Provider) PatchUser(idOrIdentifier string, format PatchFormat, patch []byte) (User, error) {
+	apply, err := parsePatch(format, patch)
+	if err != nil {
+		return User{}, err
+	}
+
+	dbUser, err := p.findUser(idOrIdentifier)
+	if err != nil {
+		return User{}, err
+	}
+
+	var user User
+	var patchErr error
+	err = p.Storage.PatchUser(dbUser.ID, func(u *storage.User) error {
+		user, patchErr = patchUser(*u, apply)
+		if patchErr != nil {
+			return patchErr
+		}
+
+		dbUser = *u
+		patchErr = p.applyUserUpdate(&dbUser, user)
+		if patchErr != nil {
+			return patchErr
+		}
+
+		*u = dbUser
+		return nil
+	})
+	if patchErr != nil {
+		return User{}, patchErr
+	}
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return User{}, ErrUserNotFound
+		}
+		if errors.Is(err, storage.ErrUserAlreadyExists) {
+			return User{}, ErrUserAlreadyExists
+		}
+
+		return User{}, fmt.Errorf("failed to patch user: %w", err)
+	}
+
+	if user.Password != "" {
+		err = p.passwordChanged(dbUser.ID, dbUser.Password)
+		if err != nil {
+			return User{}, err
+		}
+	}
+
+	return toUser(dbUser), nil
+}
+
+// parsePatch parses the given patch of the given format and returns a function which applies it to a decoded json
+// document
+// return ErrInvalidPatch when the patch is malformed
+func parsePatch(format PatchFormat, patch []byte) (func(doc interface{}) (interface{}, error), error) {
+	switch format {
+	case MergePatch:
+		var mergePatch interface{}
+		err := json.Unmarshal(patch, &mergePatch)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPatch, err)
+		}
+
+		return func(doc interface{}) (interface{}, error) {
+			return jsonpatch.MergePatch(doc, mergePatch), nil
+		}, nil
+	case JSONPatch:
+		jsonPatch, err := jsonpatch.Parse(patch)
+		if err != nil {
+			return nil, err
+		}
+
+		return jsonPatch.Apply, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown format %d", ErrInvalidPatch, format)
+	}
+}
+
+// patchUser applies the given patch to the userDocument of the given user and returns the patched properties as User
+// for UpdateUser. Removed login identifiers will be empty, removed claims, metadata and password max age will be
+// empty but not nil.
+// return ErrInvalidPatchedUser when the patched document is not a valid userDocument
+func patchUser(u storage.User, apply func(doc interface{}) (interface{}, error)) (User, error) {
+	document := userDocument{
+		Username: optionalString(u.Username),
+		Phone:    optionalString(u.Phone),
+		Claims:   u.Claims,
+		Metadata: u.Metadata,
+	}
+	if u.PasswordMaxAgeDays > 0 {
+		document.PasswordMaxAgeDays = &u.PasswordMaxAgeDays
+	}
+	if document.Claims == nil {
+		document.Claims = map[string]interface{}{}
+	}
+	if document.Metadata == nil {
+		document.Metadata = map[string]interface{}{}
+	}
+
+	// json round trip to get a generic document with the same types the patch has been decoded with
+	rawDocument, err := json.Marshal(document)
+	if err != nil {
+		return User{}, fmt.Errorf("failed to marshal user document: %w", err)
+	}
+
+	var doc interface{}
+	err = json.Unmarshal(rawDocument, &doc)
+	if err != nil {
+		return User{}, fmt.Errorf("failed to unmarshal user document: %w", err)
+	}
+
+	doc, err = apply(doc)
+	if err != nil {
+		return User{}, err
+	}
+
+	rawDocument, err = json.Marshal(doc)
+	if err != nil {
+		return User{}, fmt.Errorf("failed to marshal patched user document: %w", err)
+	}
+
+	var patched userDocument
+	decoder := json.NewDecoder(bytes.NewReader(rawDocument))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&patched)
+	if err != nil {
+		return User{}, fmt.Errorf("%w: %s", ErrInvalidPatchedUser, err)
+	}
+
+	user := User{
+		Username:           patched.Username,
+		Phone:              patched.Phone,
+		Password:           patched.Password,
+		Claims:             patched.Claims,
+		Metadata:           patched.Metadata,
+		PasswordMaxAgeDays: patched.PasswordMaxAgeDays,
+	}
+	if user.Username == nil {
+		user.Username = new(string)
+	}
+	if user.Phone == nil {
+		user.Phone = new(string)
+	}
+	if user.Claims == nil {
+		user.Claims = map[string]interface{}{}
+	}
+	if user.Metadata == nil {
+		user.Metadata = map[string]interface{}{}
+	}
+	if user.PasswordMaxAgeDays == nil {
+		user.PasswordMaxAgeDays = new(int)
+	}
+
+	return user, nil
+}
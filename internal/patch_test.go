@@ -0,0 +1,196 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"golang.org/x/crypto/bcrypt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProvider_PatchUser(t *testing.T) {
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+
+	oldBcryptCost := bcryptCost
+	defer func() { bcryptCost = oldBcryptCost }()
+	bcryptCost = bcrypt.MinCost
+
+	tests := []struct {
+		name             string
+		givenFormat      PatchFormat
+		givenPatch       string
+		dbUserError      error
+		dbPatchUserError error
+		expectedDBUser   storage.User
+		expectedPassword string
+		expectedError    error
+	}{
+		{
+			name:        "Merge patch",
+			givenFormat: MergePatch,
+			givenPatch:  `{"claims": {"role": "admin", "plan": null}, "password_max_age_days": 30}`,
+			expectedDBUser: storage.User{
+				ID:                 "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				EMail:              "test@test.test",
+				Username:           "alice",
+				Password:           []byte("oldPassword"),
+				Claims:             map[string]interface{}{"team": "a", "role": "admin"},
+				Metadata:           map[string]interface{}{"firstName": "Alice"},
+				PasswordMaxAgeDays: 30,
+			},
+		}, {
+			name:        "Merge patch removing username and metadata",
+			givenFormat: MergePatch,
+			givenPatch:  `{"username": null, "metadata": null}`,
+			expectedDBUser: storage.User{
+				ID:       "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				EMail:    "test@test.test",
+				Password: []byte("oldPassword"),
+				Claims:   map[string]interface{}{"team": "a", "plan": "pro"},
+				Metadata: map[string]interface{}{},
+			},
+		}, {
+			name:        "JSON patch",
+			givenFormat: JSONPatch,
+			givenPatch: `[{"op": "test", "path": "/claims/plan", "value": "pro"}, {"op": "replace", "path": "/claims/plan", "value": "free"},
+				{"op": "add", "path": "/phone", "value": "+49 170 1234567"}]`,
+			expectedDBUser: storage.User{
+				ID:       "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				EMail:    "test@test.test",
+				Username: "alice",
+				Phone:    "+491701234567",
+				Password: []byte("oldPassword"),
+				Claims:   map[string]interface{}{"team": "a", "plan": "free"},
+				Metadata: map[string]interface{}{"firstName": "Alice"},
+			},
+		}, {
+			name:             "New password",
+			givenFormat:      JSONPatch,
+			givenPatch:       `[{"op": "add", "path": "/password", "value": "n3wS3cr3t"}]`,
+			expectedPassword: "n3wS3cr3t",
+			expectedDBUser: storage.User{
+				ID:                "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				EMail:             "test@test.test",
+				Username:          "alice",
+				Claims:            map[string]interface{}{"team": "a", "plan": "pro"},
+				Metadata:          map[string]interface{}{"firstName": "Alice"},
+				PasswordChangedAt: now,
+			},
+		}, {
+			name:          "Malformed merge patch",
+			givenFormat:   MergePatch,
+			givenPatch:    `{"claims": `,
+			expectedError: errors.New("invalid patch: unexpected end of JSON input"),
+		}, {
+			name:          "Malformed json patch",
+			givenFormat:   JSONPatch,
+			givenPatch:    `[{"op": "delete", "path": "/claims"}]`,
+			expectedError: errors.New("operation 0: invalid patch: unknown op \"delete\""),
+		}, {
+			name:          "Failed test operation",
+			givenFormat:   JSONPatch,
+			givenPatch:    `[{"op": "test", "path": "/claims/plan", "value": "free"}]`,
+			expectedError: errors.New("operation 0 (test): patch not applicable: test failed"),
+		}, {
+			name:          "Read only property",
+			givenFormat:   MergePatch,
+			givenPatch:    `{"email": "new@test.test"}`,
+			expectedError: errors.New("patched user is invalid: json: unknown field \"email\""),
+		}, {
+			name:          "Property of wrong type",
+			givenFormat:   MergePatch,
+			givenPatch:    `{"claims": "admin"}`,
+			expectedError: errors.New("patched user is invalid: json: cannot unmarshal string into Go struct field userDocument.claims of type map[string]interface {}"),
+		}, {
+			name:          "Invalid username",
+			givenFormat:   MergePatch,
+			givenPatch:    `{"username": "a"}`,
+			expectedError: errors.New("invalid username"),
+		}, {
+			name:          "User not found",
+			givenFormat:   MergePatch,
+			givenPatch:    `{}`,
+			dbUserError:   storage.ErrUserNotFound,
+			expectedError: ErrUserNotFound,
+		}, {
+			name:             "Username already taken",
+			givenFormat:      MergePatch,
+			givenPatch:       `{"username": "bob"}`,
+			dbPatchUserError: storage.ErrUserAlreadyExists,
+			expectedError:    ErrUserAlreadyExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var dbUser storage.User
+			var addedPasswordHistory []byte
+			toTest := Provider{
+				PasswordHistorySize: 3,
+				Storage: &StorageMock{
+					UserByIDFunc: func(id string) (storage.User, error) {
+						return storage.User{ID: id}, tt.dbUserError
+					},
+					TokensByUserIDAndTypeFunc: func(userID string, tokenType string) ([]storage.Token, error) {
+						return nil, nil
+					},
+					PatchUserFunc: func(id string, patch func(user *storage.User) error) error {
+						u := storage.User{
+							ID:       id,
+							EMail:    "test@test.test",
+							Username: "alice",
+							Password: []byte("oldPassword"),
+							Claims:   map[string]interface{}{"team": "a", "plan": "pro"},
+							Metadata: map[string]interface{}{"firstName": "Alice"},
+						}
+						err := patch(&u)
+						if err != nil {
+							return err
+						}
+
+						dbUser = u
+						return tt.dbPatchUserError
+					},
+					PasswordHistoryFunc: func(userID string, limit int) ([][]byte, error) {
+						return nil, nil
+					},
+					AddPasswordHistoryFunc: func(userID string, password []byte, createdAt time.Time, keep int) error {
+						addedPasswordHistory = password
+						return nil
+					},
+				},
+			}
+
+			user, err := toTest.PatchUser("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", tt.givenFormat, []byte(tt.givenPatch))
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+			if tt.expectedError != nil {
+				return
+			}
+
+			if tt.expectedPassword != "" {
+				if bcrypt.CompareHashAndPassword(dbUser.Password, []byte(tt.expectedPassword)) != nil {
+					t.Errorf("Password has not been changed to %q", tt.expectedPassword)
+				}
+				if !reflect.DeepEqual(addedPasswordHistory, dbUser.Password) {
+					t.Error("New password has not been added to the password history")
+				}
+				dbUser.Password = nil
+			}
+
+			if !reflect.DeepEqual(dbUser, tt.expectedDBUser) {
+				t.Errorf("Patched user is not as expected. Expected:\n%#v\nGiven:\n%#v", tt.expectedDBUser, dbUser)
+			}
+
+			if user.ID != tt.expectedDBUser.ID || user.Password != blankedPassword {
+				t.Errorf("Returned user is not as expected. Given:\n%#v", user)
+			}
+		})
+	}
+}
@@ -2,31 +2,130 @@ package internal
 
 import (
 	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"github.com/leberKleber/simple-jwt-provider/internal/webauthn"
+	"time"
 )
 
 //go:generate moq -out storage_moq_test.go . Storage
 type Storage interface {
 	User(email string) (storage.User, error)
+	UserByID(id string) (storage.User, error)
+	UserByUsername(username string) (storage.User, error)
+	UserByPhone(phone string) (storage.User, error)
+	UsersByClaims(predicates []storage.ClaimPredicate, limit, offset int) ([]storage.User, int, error)
 	CreateUser(user storage.User) error
 	UpdateUser(user storage.User) error
-	DeleteUser(email string) error
+	PatchUser(id string, patch func(user *storage.User) error) error
+	DeleteUser(id string) error
+	UserData(id string) (storage.UserData, error)
+	EraseUser(id string) (map[string]int64, error)
+	MarkUserInvited(id string, invitedAt time.Time) error
+	DeleteUnverifiedUsers(invitedBefore time.Time) (int64, error)
+	PasswordHistory(userID string, limit int) ([][]byte, error)
+	AddPasswordHistory(userID string, password []byte, createdAt time.Time, keep int) error
+	UsersToRemindOfPasswordExpiry(defaultMaxAgeDays, reminderDays int, now time.Time) ([]storage.User, error)
+	MarkPasswordExpiryReminded(userID string, remindedAt time.Time) error
+	TOTP(userID string) (storage.TOTP, error)
+	SaveTOTP(t storage.TOTP) error
+	WebAuthnCredentials(userID string) ([]storage.WebAuthnCredential, error)
+	CreateWebAuthnCredential(c storage.WebAuthnCredential) error
+	UpdateWebAuthnCredentialUsage(id []byte, signCount uint32, lastUsedAt time.Time) error
+	UnusedRecoveryCodeCount(userID string) (int, error)
+	ReplaceRecoveryCodes(userID string, codeHashes [][]byte, createdAt time.Time) error
+	UseRecoveryCode(userID string, codeHash []byte, usedAt time.Time) error
+	DeleteMFA(userID string) error
 	CreateToken(t storage.Token) (int64, error)
-	TokensByEMailAndToken(email, token string) ([]storage.Token, error)
+	TokensByUserIDAndType(userID, tokenType string) ([]storage.Token, error)
+	IncrementTokenAttempts(id int64) (int, error)
 	DeleteToken(id int64) error
+	DeleteTokensCreatedBefore(tokenType string, createdBefore time.Time) (int64, error)
+	AddLogin(l storage.Login) error
+	DeleteLoginsCreatedBefore(createdBefore time.Time) (int64, error)
+	Logins(userID string, limit, offset int) ([]storage.Login, int, error)
 }
 
 //go:generate moq -out jwt_generator_moq_test.go . JWTGenerator
 type JWTGenerator interface {
-	Generate(email string, userClaims map[string]interface{}) (string, error)
+	Generate(userID, email string, userClaims map[string]interface{}) (string, error)
+	Verify(token string) (string, error)
 }
 
 //go:generate moq -out mailer_moq_test.go . Mailer
 type Mailer interface {
-	SendPasswordResetRequestEMail(recipient, passwordResetToken string, claims map[string]interface{}) error
+	SendPasswordResetRequestEMail(recipient, passwordResetToken string, claims, metadata map[string]interface{}) error
+	SendPasswordExpiryReminderEMail(recipient string, passwordExpiresAt time.Time, claims, metadata map[string]interface{}) error
+	SendMagicLinkEMail(recipient, magicLinkToken string, claims, metadata map[string]interface{}) error
+	SendLoginCodeEMail(recipient, loginCode string, claims, metadata map[string]interface{}) error
+	SendInvitationEMail(recipient, invitationToken string, expiresAt time.Time, claims, metadata map[string]interface{}) error
+}
+
+//go:generate moq -out secret_crypter_moq_test.go . SecretCrypter
+type SecretCrypter interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+//go:generate moq -out token_hasher_moq_test.go . TokenHasher
+type TokenHasher interface {
+	Hash(token string) string
+}
+
+//go:generate moq -out web_authn_relying_party_moq_test.go . WebAuthnRelyingParty
+type WebAuthnRelyingParty interface {
+	CreationOptions(challenge, userID []byte, userName string, excludeCredentialIDs [][]byte) webauthn.CredentialCreationOptions
+	RequestOptions(challenge []byte, allowCredentialIDs [][]byte, userVerification string) webauthn.CredentialRequestOptions
+	VerifyRegistration(challenge []byte, r webauthn.AttestationResponse, requireUserVerification bool) (webauthn.Credential, error)
+	VerifyAssertion(challenge []byte, r webauthn.AssertionResponse, c webauthn.Credential, requireUserVerification bool) (uint32, error)
+}
+
+//go:generate moq -out password_breach_checker_moq_test.go . PasswordBreachChecker
+type PasswordBreachChecker interface {
+	IsBreached(password string) (bool, error)
 }
 
 type Provider struct {
 	Storage      Storage
 	JWTGenerator JWTGenerator
 	Mailer       Mailer
+	// TokenHasher hashes all one-time tokens (reset, invitation, magic link, login code, mfa and webauthn challenges)
+	// and recovery codes. Tokens will only be stored hashed
+	TokenHasher TokenHasher
+	// PasswordBreachChecker is optional. When set, new passwords will be checked against it
+	PasswordBreachChecker PasswordBreachChecker
+	// PasswordBreachWarnOnly logs breached passwords and marks issued jwts with the 'pwd_breached' claim instead of
+	// rejecting them
+	PasswordBreachWarnOnly bool
+	// PasswordHistorySize is the count of last passwords per user which can not be reused. 0 disables the history
+	PasswordHistorySize int
+	// PasswordMaxAgeDays is the global max age of passwords. Users can overwrite it. 0 disables the password expiry
+	PasswordMaxAgeDays int
+	// PasswordExpiryReminderDays is the count of days before a password expires a reminder mail will be sent. 0
+	// disables reminders
+	PasswordExpiryReminderDays int
+	// TOTPCrypter encrypts and decrypts totp secrets. TOTP enrolment is disabled when nil
+	TOTPCrypter SecretCrypter
+	// TOTPIssuer is the issuer shown in authenticator apps
+	TOTPIssuer string
+	// WebAuthn verifies security key / passkey ceremonies. WebAuthn is disabled when nil
+	WebAuthn WebAuthnRelyingParty
+	// MFATokenLifetime is the lifetime of mfa challenge tokens issued by Login and of webauthn challenges
+	MFATokenLifetime time.Duration
+	// MagicLinkLifetime is the lifetime of magic link login tokens. Magic link login is disabled when 0
+	MagicLinkLifetime time.Duration
+	// LoginCodeLifetime is the lifetime of login codes sent by mail. Login code login is disabled when 0
+	LoginCodeLifetime time.Duration
+	// LoginCodeMaxAttempts is the count of attempts per login code. The code will be invalidated afterwards
+	LoginCodeMaxAttempts int
+	// LowercaseEMailLocalPart lowercases the whole email on normalization instead of the domain only
+	LowercaseEMailLocalPart bool
+	// LoginHistoryRetention is the duration login attempts will be kept in the login history. 0 keeps them forever
+	LoginHistoryRetention time.Duration
+	// PasswordResetTokenLifetime is the lifetime of password reset tokens
+	PasswordResetTokenLifetime time.Duration
+	// InvitationLifetime is the lifetime of invitation tokens of users created without password. Invitations are
+	// disabled when 0
+	InvitationLifetime time.Duration
+	// UnverifiedUserRetention is the duration users who never accepted their invitation will be kept after their last
+	// invitation. 0 keeps them forever
+	UnverifiedUserRetention time.Duration
 }
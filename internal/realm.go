@@ -0,0 +1,436 @@
+package internal
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/jwt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrRealmNotFound = errors.New("realm not found")
+var ErrRealmAlreadyExists = errors.New("realm already exists")
+var ErrRealmHostAlreadyUsed = errors.New("realm host is already used by another realm")
+var ErrInvalidRealmName = errors.New("invalid realm name")
+var ErrInvalidRealmHost = errors.New("invalid realm host")
+var ErrInvalidJWTPrivateKey = errors.New("invalid jwt private key")
+
+// realmNamePattern allows 1 to 48 lower case letters, digits and '-' beginning with a letter or digit
+var realmNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,47}$`)
+
+// plaintextRealmKeyPrefix starts the pem encoded jwt private keys of realms which have been stored before the keys were
+// encrypted
+const plaintextRealmKeyPrefix = "-----BEGIN"
+
+// realmHostPattern allows lower case host names without port
+var realmHostPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9.-]{0,251}[a-z0-9])?$`)
+
+//go:generate moq -out realm_storage_moq_test.go . RealmStorage
+type RealmStorage interface {
+	Realms() ([]storage.Realm, error)
+	Realm(name string) (storage.Realm, error)
+	CreateRealm(r storage.Realm) error
+	UpdateRealm(r storage.Realm) error
+}
+
+// Realm is the representation of a realm for use in internal
+type Realm struct {
+	// Name can only be set on creation
+	Name string
+	// Hosts select the realm by host header. On update nil means unchanged
+	Hosts []string
+	// JWTIssuer is the issuer claim of all jwts of the realm. On update nil means unchanged
+	JWTIssuer *string
+	// JWTAudience is the audience claim of all jwts of the realm. On update nil means unchanged
+	JWTAudience *string
+	// JWTPrivateKey is write only. It is the pem encoded ECDSA P-521 key the jwts of the realm will be signed with. It
+	// will be generated on creation when empty. On update empty means unchanged
+	JWTPrivateKey string
+	// JWTPublicKey is read only. It is the pem encoded public key to verify the jwts of the realm
+	JWTPublicKey string
+	// CreatedAt is read only
+	CreatedAt time.Time
+}
+
+// Realms manages all realms and their providers. Each realm has its own Provider (storage, jwt generator, mailer)
+// created by the provider factory and is therefore isolated from the default realm and all other realms. The jwt
+// private keys of realms will be stored encrypted by the crypter, the provider factory gets them decrypted.
+type Realms struct {
+	storage     RealmStorage
+	crypter     SecretCrypter
+	newProvider func(r storage.Realm) (*Provider, error)
+
+	mu        sync.RWMutex
+	providers map[string]*Provider
+	hosts     map[string]string
+}
+
+// NewRealms creates the providers of all stored realms with the given provider factory. Jwt private keys which have
+// been stored in plaintext will be encrypted with the given crypter.
+func NewRealms(s RealmStorage, crypter SecretCrypter, newProvider func(r storage.Realm) (*Provider, error)) (*Realms, error) {
+	realms := &Realms{
+		storage:     s,
+		crypter:     crypter,
+		newProvider: newProvider,
+		providers:   map[string]*Provider{},
+		hosts:       map[string]string{},
+	}
+
+	dbRealms, err := s.Realms()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query realms: %w", err)
+	}
+
+	for _, dbRealm := range dbRealms {
+		if strings.HasPrefix(dbRealm.JWTPrivateKey, plaintextRealmKeyPrefix) {
+			err = realms.encryptStoredKey(dbRealm)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		dbRealm, err = realms.decryptKey(dbRealm)
+		if err != nil {
+			return nil, err
+		}
+
+		p, err := newProvider(dbRealm)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create provider of realm %q: %w", dbRealm.Name, err)
+		}
+
+		realms.setProvider(dbRealm, p)
+	}
+
+	return realms, nil
+}
+
+// Provider returns the provider of the realm with the given name. Realms which have been created by other instances
+// will be loaded from storage.
+// return ErrRealmNotFound when realm does not exist
+func (r *Realms) Provider(name string) (*Provider, error) {
+	r.mu.RLock()
+	p, ok := r.providers[name]
+	r.mu.RUnlock()
+	if ok {
+		return p, nil
+	}
+
+	dbRealm, err := r.queryRealm(name)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err = r.newProvider(dbRealm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider of realm %q: %w", name, err)
+	}
+
+	r.setProvider(dbRealm, p)
+	return p, nil
+}
+
+// RealmByHost returns the name of the realm the given host (without port) belongs to
+func (r *Realms) RealmByHost(host string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	name, ok := r.hosts[normalizeRealmHost(host)]
+	return name, ok
+}
+
+// Providers returns the providers of all realms known by this instance
+func (r *Realms) Providers() []*Provider {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	providers := make([]*Provider, 0, len(r.providers))
+	for _, p := range r.providers {
+		providers = append(providers, p)
+	}
+
+	return providers
+}
+
+// List returns all realms ordered by name
+func (r *Realms) List() ([]Realm, error) {
+	dbRealms, err := r.storage.Realms()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query realms: %w", err)
+	}
+
+	realms := make([]Realm, 0, len(dbRealms))
+	for _, dbRealm := range dbRealms {
+		dbRealm, err = r.decryptKey(dbRealm)
+		if err != nil {
+			return nil, err
+		}
+
+		realm, err := toRealm(dbRealm)
+		if err != nil {
+			return nil, err
+		}
+
+		realms = append(realms, realm)
+	}
+
+	return realms, nil
+}
+
+// Realm returns the realm with the given name
+// return ErrRealmNotFound when realm does not exist
+func (r *Realms) Realm(name string) (Realm, error) {
+	dbRealm, err := r.queryRealm(name)
+	if err != nil {
+		return Realm{}, err
+	}
+
+	return toRealm(dbRealm)
+}
+
+// CreateRealm creates a new realm with its own database schema. A jwt private key will be generated when none is
+// given. The schema will only be provisioned when no realm with the same name exists.
+// return ErrInvalidRealmName, ErrInvalidRealmHost or ErrInvalidJWTPrivateKey when the realm is malformed
+// return ErrRealmAlreadyExists when a realm with the same name already exists
+// return ErrRealmHostAlreadyUsed when one of the hosts belongs to another realm
+func (r *Realms) CreateRealm(realm Realm) (Realm, error) {
+	if !realmNamePattern.MatchString(realm.Name) {
+		return Realm{}, ErrInvalidRealmName
+	}
+
+	_, err := r.storage.Realm(realm.Name)
+	if err == nil {
+		return Realm{}, ErrRealmAlreadyExists
+	}
+	if !errors.Is(err, storage.ErrRealmNotFound) {
+		return Realm{}, fmt.Errorf("failed to query realm %q: %w", realm.Name, err)
+	}
+
+	dbRealm := storage.Realm{
+		Name:          realm.Name,
+		JWTPrivateKey: realm.JWTPrivateKey,
+		CreatedAt:     nowFunc(),
+	}
+
+	if dbRealm.JWTPrivateKey == "" {
+		dbRealm.JWTPrivateKey, err = jwt.GeneratePrivateKey()
+		if err != nil {
+			return Realm{}, fmt.Errorf("failed to generate jwt private key: %w", err)
+		}
+	}
+
+	err = setRealmProperties(&dbRealm, realm)
+	if err != nil {
+		return Realm{}, err
+	}
+
+	p, err := r.newProvider(dbRealm)
+	if err != nil {
+		return Realm{}, fmt.Errorf("failed to create provider of realm %q: %w", dbRealm.Name, err)
+	}
+
+	encryptedRealm, err := r.encryptKey(dbRealm)
+	if err != nil {
+		return Realm{}, err
+	}
+
+	err = r.storage.CreateRealm(encryptedRealm)
+	if err != nil {
+		if errors.Is(err, storage.ErrRealmAlreadyExists) {
+			return Realm{}, ErrRealmAlreadyExists
+		}
+		if errors.Is(err, storage.ErrRealmHostAlreadyUsed) {
+			return Realm{}, ErrRealmHostAlreadyUsed
+		}
+		return Realm{}, fmt.Errorf("failed to create realm %q: %w", dbRealm.Name, err)
+	}
+
+	r.setProvider(dbRealm, p)
+	return toRealm(dbRealm)
+}
+
+// UpdateRealm updates the realm with the given name. Its provider will be replaced, so new logins will immediately be
+// signed with the new key.
+// return ErrRealmNotFound when realm does not exist
+// return ErrInvalidRealmHost or ErrInvalidJWTPrivateKey when the realm is malformed
+// return ErrRealmHostAlreadyUsed when one of the hosts belongs to another realm
+func (r *Realms) UpdateRealm(name string, realm Realm) (Realm, error) {
+	dbRealm, err := r.queryRealm(name)
+	if err != nil {
+		return Realm{}, err
+	}
+
+	if realm.JWTPrivateKey != "" {
+		dbRealm.JWTPrivateKey = realm.JWTPrivateKey
+	}
+
+	err = setRealmProperties(&dbRealm, realm)
+	if err != nil {
+		return Realm{}, err
+	}
+
+	p, err := r.newProvider(dbRealm)
+	if err != nil {
+		return Realm{}, fmt.Errorf("failed to create provider of realm %q: %w", dbRealm.Name, err)
+	}
+
+	encryptedRealm, err := r.encryptKey(dbRealm)
+	if err != nil {
+		return Realm{}, err
+	}
+
+	err = r.storage.UpdateRealm(encryptedRealm)
+	if err != nil {
+		if errors.Is(err, storage.ErrRealmNotFound) {
+			return Realm{}, ErrRealmNotFound
+		}
+		if errors.Is(err, storage.ErrRealmHostAlreadyUsed) {
+			return Realm{}, ErrRealmHostAlreadyUsed
+		}
+		return Realm{}, fmt.Errorf("failed to update realm %q: %w", dbRealm.Name, err)
+	}
+
+	r.setProvider(dbRealm, p)
+	return toRealm(dbRealm)
+}
+
+// queryRealm finds the realm with the given name. Its jwt private key will be decrypted.
+// return ErrRealmNotFound when realm does not exist
+func (r *Realms) queryRealm(name string) (storage.Realm, error) {
+	if !realmNamePattern.MatchString(name) {
+		return storage.Realm{}, ErrRealmNotFound
+	}
+
+	dbRealm, err := r.storage.Realm(name)
+	if err != nil {
+		if errors.Is(err, storage.ErrRealmNotFound) {
+			return storage.Realm{}, ErrRealmNotFound
+		}
+		return storage.Realm{}, fmt.Errorf("failed to query realm %q: %w", name, err)
+	}
+
+	return r.decryptKey(dbRealm)
+}
+
+// encryptKey returns the given realm with encrypted and base64 encoded jwt private key to store it
+func (r *Realms) encryptKey(dbRealm storage.Realm) (storage.Realm, error) {
+	encryptedKey, err := r.crypter.Encrypt([]byte(dbRealm.JWTPrivateKey))
+	if err != nil {
+		return storage.Realm{}, fmt.Errorf("failed to encrypt jwt private key of realm %q: %w", dbRealm.Name, err)
+	}
+
+	dbRealm.JWTPrivateKey = base64.StdEncoding.EncodeToString(encryptedKey)
+	return dbRealm, nil
+}
+
+// decryptKey returns the given stored realm with decrypted jwt private key. Keys which have been stored in plaintext
+// will be returned unchanged.
+func (r *Realms) decryptKey(dbRealm storage.Realm) (storage.Realm, error) {
+	if strings.HasPrefix(dbRealm.JWTPrivateKey, plaintextRealmKeyPrefix) {
+		return dbRealm, nil
+	}
+
+	encryptedKey, err := base64.StdEncoding.DecodeString(dbRealm.JWTPrivateKey)
+	if err != nil {
+		return storage.Realm{}, fmt.Errorf("failed to decode jwt private key of realm %q: %w", dbRealm.Name, err)
+	}
+
+	key, err := r.crypter.Decrypt(encryptedKey)
+	if err != nil {
+		return storage.Realm{}, fmt.Errorf("failed to decrypt jwt private key of realm %q: %w", dbRealm.Name, err)
+	}
+
+	dbRealm.JWTPrivateKey = string(key)
+	return dbRealm, nil
+}
+
+// encryptStoredKey replaces the plaintext jwt private key of the given stored realm by the encrypted one
+func (r *Realms) encryptStoredKey(dbRealm storage.Realm) error {
+	encryptedRealm, err := r.encryptKey(dbRealm)
+	if err != nil {
+		return err
+	}
+
+	err = r.storage.UpdateRealm(encryptedRealm)
+	if err != nil {
+		return fmt.Errorf("failed to store encrypted jwt private key of realm %q: %w", dbRealm.Name, err)
+	}
+
+	return nil
+}
+
+// setProvider sets the provider and the hosts of the given realm and removes the previous hosts of the realm
+func (r *Realms) setProvider(dbRealm storage.Realm, p *Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for host, name := range r.hosts {
+		if name == dbRealm.Name {
+			delete(r.hosts, host)
+		}
+	}
+	for _, host := range dbRealm.Hosts {
+		r.hosts[host] = dbRealm.Name
+	}
+
+	r.providers[dbRealm.Name] = p
+}
+
+// setRealmProperties sets the normalized hosts, issuer and audience of the given realm to the given dbRealm. Nil values
+// will be left unchanged. The private key of the dbRealm will be validated.
+// return ErrInvalidRealmHost or ErrInvalidJWTPrivateKey when the realm is malformed
+func setRealmProperties(dbRealm *storage.Realm, realm Realm) error {
+	if realm.Hosts != nil {
+		dbRealm.Hosts = make([]string, 0, len(realm.Hosts))
+		for _, host := range realm.Hosts {
+			host = normalizeRealmHost(host)
+			if !realmHostPattern.MatchString(host) {
+				return ErrInvalidRealmHost
+			}
+
+			dbRealm.Hosts = append(dbRealm.Hosts, host)
+		}
+	}
+
+	if realm.JWTIssuer != nil {
+		dbRealm.JWTIssuer = *realm.JWTIssuer
+	}
+
+	if realm.JWTAudience != nil {
+		dbRealm.JWTAudience = *realm.JWTAudience
+	}
+
+	_, err := jwt.PublicKey(dbRealm.JWTPrivateKey)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidJWTPrivateKey, err)
+	}
+
+	return nil
+}
+
+// normalizeRealmHost returns the trimmed and lowercased form of the given host without trailing dot
+func normalizeRealmHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
+
+// toRealm converts the given storage.Realm to a Realm without private key
+func toRealm(r storage.Realm) (Realm, error) {
+	publicKey, err := jwt.PublicKey(r.JWTPrivateKey)
+	if err != nil {
+		return Realm{}, fmt.Errorf("failed to get jwt public key of realm %q: %w", r.Name, err)
+	}
+
+	jwtIssuer, jwtAudience := r.JWTIssuer, r.JWTAudience
+	return Realm{
+		Name:         r.Name,
+		Hosts:        r.Hosts,
+		JWTIssuer:    &jwtIssuer,
+		JWTAudience:  &jwtAudience,
+		JWTPublicKey: publicKey,
+		CreatedAt:    r.CreatedAt,
+	}, nil
+}
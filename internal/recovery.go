@@ -0,0 +1,193 @@
+package internal
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"strings"
+)
+
+const (
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+	// crockford base32 alphabet without ambiguous characters
+	recoveryCodeAlphabet = "0123456789abcdefghjkmnpqrstvwxyz"
+)
+
+var ErrMFANotEnabled = errors.New("mfa is not enabled")
+var ErrInvalidRecoveryCode = errors.New("invalid recovery code")
+
+// LoginRecoveryCode redeems the given mfa challenge token (issued by Login, LoginMagicLink or LoginWithCode) together
+// with a recovery code in place of the second factor and returns a new jwt with the 'amr' claim of the first factor and
+// "rc" (e.g. ["pwd", "rc"]), so relying parties can tell recovery code logins from totp logins. The challenge token can
+// be used once, also when the recovery code is invalid. Each recovery code can be used once. The attempt will be
+// recorded in the login history of the user.
+// return ErrNoValidTokenFound when the mfa token is unknown or expired
+// return ErrInvalidRecoveryCode when the recovery code is invalid or has already been used
+func (p Provider) LoginRecoveryCode(identifier, mfaToken, recoveryCode string, client Client) (accessToken string, err error) {
+	u, err := p.challengedUser(identifier)
+	if err != nil {
+		return "", err
+	}
+	defer func() { p.recordLogin(u.ID, client, LoginFactorRecoveryCode, false, err) }()
+
+	firstFactor, err := p.redeemMFAToken(u.ID, mfaToken)
+	if err != nil {
+		return "", err
+	}
+
+	err = p.useRecoveryCode(u.ID, recoveryCode)
+	if err != nil {
+		return "", err
+	}
+
+	return p.JWTGenerator.Generate(u.ID, u.EMail, withClaim(u.Claims, amrClaim, []string{firstFactor, amrRecoveryCode}))
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of the given user by new ones. Besides the password a current
+// totp code or one of the current recovery codes is required.
+// return ErrIncorrectPassword when password is incorrect
+// return ErrUserNotFound when user not found
+// return ErrMFANotEnabled when the user has no enabled second factor
+// return ErrInvalidMFACode when the code is neither a valid totp code nor a valid recovery code
+func (p Provider) RegenerateRecoveryCodes(identifier, password, code string) ([]string, error) {
+	u, err := p.authenticate(identifier, password)
+	if err != nil {
+		return nil, err
+	}
+
+	methods, err := p.mfaMethods(u.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(methods) == 0 {
+		return nil, ErrMFANotEnabled
+	}
+
+	err = p.verifySecondFactorCode(u.ID, code)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.replaceRecoveryCodes(u.ID)
+}
+
+// ResetMFA disables all second factors of the user with the given id or login identifier (totp, webauthn credentials
+// and recovery codes). The user can login with the password only afterwards.
+// return ErrUserNotFound when user not found
+func (p Provider) ResetMFA(idOrIdentifier string) error {
+	user, err := p.findUser(idOrIdentifier)
+	if err != nil {
+		return err
+	}
+
+	err = p.Storage.DeleteMFA(user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to delete mfa of user %q: %w", user.ID, err)
+	}
+
+	return nil
+}
+
+// issueRecoveryCodes generates new recovery codes when the user has no unused ones. It will be called when a second
+// factor has been enabled and returns nil when the user already has recovery codes.
+func (p Provider) issueRecoveryCodes(userID string) ([]string, error) {
+	count, err := p.Storage.UnusedRecoveryCodeCount(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	if count > 0 {
+		return nil, nil
+	}
+
+	return p.replaceRecoveryCodes(userID)
+}
+
+// replaceRecoveryCodes generates new recovery codes, stores their hashes and returns the plain codes
+func (p Provider) replaceRecoveryCodes(userID string) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([][]byte, recoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+
+		codes[i] = code
+		hashes[i] = p.hashRecoveryCode(code)
+	}
+
+	err := p.Storage.ReplaceRecoveryCodes(userID, hashes, nowFunc())
+	if err != nil {
+		return nil, fmt.Errorf("failed to replace recovery codes: %w", err)
+	}
+
+	return codes, nil
+}
+
+// useRecoveryCode marks the given recovery code as used
+// return ErrInvalidRecoveryCode when the recovery code is invalid or has already been used
+func (p Provider) useRecoveryCode(userID, recoveryCode string) error {
+	err := p.Storage.UseRecoveryCode(userID, p.hashRecoveryCode(recoveryCode), nowFunc())
+	if err != nil {
+		if errors.Is(err, storage.ErrRecoveryCodeNotFound) {
+			return ErrInvalidRecoveryCode
+		}
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	return nil
+}
+
+// verifySecondFactorCode accepts a valid totp code or an unused recovery code (which will be used up)
+// return ErrInvalidMFACode when the code is neither a valid totp code nor a valid recovery code
+func (p Provider) verifySecondFactorCode(userID, code string) error {
+	if p.TOTPCrypter != nil {
+		t, err := p.Storage.TOTP(userID)
+		if err != nil && !errors.Is(err, storage.ErrTOTPNotFound) {
+			return fmt.Errorf("failed to query totp: %w", err)
+		}
+
+		if err == nil && t.Confirmed {
+			err = p.useTOTPCode(t, code)
+			if err == nil || !errors.Is(err, ErrInvalidMFACode) {
+				return err
+			}
+		}
+	}
+
+	err := p.useRecoveryCode(userID, code)
+	if errors.Is(err, ErrInvalidRecoveryCode) {
+		return ErrInvalidMFACode
+	}
+
+	return err
+}
+
+// generateRecoveryCode generates a random recovery code formatted like 'xxxxx-xxxxx'
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, recoveryCodeLength)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for i, c := range b {
+		if i == recoveryCodeLength/2 {
+			sb.WriteByte('-')
+		}
+		sb.WriteByte(recoveryCodeAlphabet[int(c)%len(recoveryCodeAlphabet)])
+	}
+
+	return sb.String(), nil
+}
+
+// hashRecoveryCode hashes the normalized (lower case, without separators) recovery code with the TokenHasher, so the
+// stored hashes can not be brute forced without the hashing key. The hash is deterministic and allows to look the
+// codes up directly.
+func (p Provider) hashRecoveryCode(code string) []byte {
+	normalized := strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+	return []byte(p.TokenHasher.Hash(normalized))
+}
@@ -0,0 +1,311 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/crypt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"github.com/leberKleber/simple-jwt-provider/internal/totp"
+	"golang.org/x/crypto/bcrypt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProvider_LoginRecoveryCode(t *testing.T) {
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+
+	validToken := storage.Token{ID: 42, UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Token: "hashed-myMFAToken", Type: storage.TokenTypeMFA, CreatedAt: now.Add(-time.Minute)}
+
+	tests := []struct {
+		name                string
+		dbTokens            []storage.Token
+		dbUseCodeError      error
+		expectedTokenDelete bool
+		expectedJWT         string
+		expectedClaims      map[string]interface{}
+		expectedError       error
+	}{
+		{
+			name:                "Happycase",
+			dbTokens:            []storage.Token{validToken},
+			expectedTokenDelete: true,
+			expectedJWT:         "myJWT",
+			expectedClaims:      map[string]interface{}{"myCustomClaim": "value", "amr": []string{"pwd", "rc"}},
+		}, {
+			name:          "Token not found",
+			expectedError: ErrNoValidTokenFound,
+		}, {
+			name:                "Invalid recovery code",
+			dbTokens:            []storage.Token{validToken},
+			dbUseCodeError:      storage.ErrRecoveryCodeNotFound,
+			expectedTokenDelete: true,
+			expectedError:       ErrInvalidRecoveryCode,
+		}, {
+			name:                "Unexpected db error",
+			dbTokens:            []storage.Token{validToken},
+			dbUseCodeError:      errors.New("nope"),
+			expectedTokenDelete: true,
+			expectedError:       errors.New("failed to use recovery code: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenClaims map[string]interface{}
+			var givenHash []byte
+			storageMock := &StorageMock{
+				TokensByUserIDAndTypeFunc: func(userID string, tokenType string) ([]storage.Token, error) {
+					return tokensOfType(tt.dbTokens, tokenType), nil
+				},
+				DeleteTokenFunc: func(id int64) error {
+					return nil
+				},
+				UseRecoveryCodeFunc: func(email string, codeHash []byte, usedAt time.Time) error {
+					givenHash = codeHash
+					return tt.dbUseCodeError
+				},
+				UserFunc: func(email string) (storage.User, error) {
+					return storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", EMail: email, Claims: map[string]interface{}{"myCustomClaim": "value"}}, nil
+				},
+				AddLoginFunc: func(l storage.Login) error {
+					if l.Factor != LoginFactorRecoveryCode {
+						t.Errorf("Unexpected login factor %q", l.Factor)
+					}
+					return nil
+				},
+			}
+			toTest := Provider{
+				MFATokenLifetime: 5 * time.Minute,
+				Storage:          storageMock,
+				TokenHasher:      testTokenHasher,
+				JWTGenerator: &JWTGeneratorMock{
+					GenerateFunc: func(userID string, email string, userClaims map[string]interface{}) (string, error) {
+						givenClaims = userClaims
+						return "myJWT", nil
+					},
+				},
+			}
+
+			jwt, err := toTest.LoginRecoveryCode("test@test.test", "myMFAToken", "ABCDE-fghjk", Client{})
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			if jwt != tt.expectedJWT {
+				t.Errorf("Given jwt is not as expected. Expected: %q, Given: %q", tt.expectedJWT, jwt)
+			}
+
+			if tt.expectedTokenDelete && !bytes.Equal(givenHash, []byte("hashed-abcdefghjk")) {
+				t.Errorf("Recovery code has not been normalized before hashing")
+			}
+
+			deleteCalls := storageMock.DeleteTokenCalls()
+			if (len(deleteCalls) == 1) != tt.expectedTokenDelete {
+				t.Errorf("Unexpected count of DeleteToken calls: %d", len(deleteCalls))
+			}
+
+			if !reflect.DeepEqual(givenClaims, tt.expectedClaims) {
+				t.Errorf("Generator claims are not as expected. Expected:\n%#v\nGiven:\n%#v", tt.expectedClaims, givenClaims)
+			}
+		})
+	}
+}
+
+func TestProvider_RegenerateRecoveryCodes(t *testing.T) {
+	bcryptCost = bcrypt.MinCost
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+
+	secret := []byte("12345678901234567890")
+	validCode := totp.Code(secret, totp.Step(now))
+	confirmedTOTP := storage.TOTP{UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Secret: append([]byte("enc:"), secret...), Confirmed: true}
+
+	tests := []struct {
+		name              string
+		givenPassword     string
+		givenCode         string
+		dbTOTP            storage.TOTP
+		dbTOTPError       error
+		dbUseCodeError    error
+		expectedUseCode   bool
+		expectedCodes     int
+		expectedError     error
+		expectedReplacing bool
+	}{
+		{
+			name:              "Happycase with totp code",
+			givenPassword:     "password",
+			givenCode:         validCode,
+			dbTOTP:            confirmedTOTP,
+			expectedCodes:     recoveryCodeCount,
+			expectedReplacing: true,
+		}, {
+			name:              "Happycase with recovery code",
+			givenPassword:     "password",
+			givenCode:         "abcde-fghjk",
+			dbTOTP:            confirmedTOTP,
+			expectedUseCode:   true,
+			expectedCodes:     recoveryCodeCount,
+			expectedReplacing: true,
+		}, {
+			name:          "Incorrect password",
+			givenPassword: "wrongPassword",
+			givenCode:     validCode,
+			expectedError: ErrIncorrectPassword,
+		}, {
+			name:          "MFA not enabled",
+			givenPassword: "password",
+			givenCode:     validCode,
+			dbTOTPError:   storage.ErrTOTPNotFound,
+			expectedError: ErrMFANotEnabled,
+		}, {
+			name:            "Invalid code",
+			givenPassword:   "password",
+			givenCode:       "abcde-fghjk",
+			dbTOTP:          confirmedTOTP,
+			dbUseCodeError:  storage.ErrRecoveryCodeNotFound,
+			expectedUseCode: true,
+			expectedError:   ErrInvalidMFACode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storageMock := &StorageMock{
+				UserFunc: func(email string) (storage.User, error) {
+					return storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", EMail: email, Password: testPasswordHash}, nil
+				},
+				TOTPFunc: func(userID string) (storage.TOTP, error) {
+					return tt.dbTOTP, tt.dbTOTPError
+				},
+				SaveTOTPFunc: func(t storage.TOTP) error {
+					return nil
+				},
+				UseRecoveryCodeFunc: func(email string, codeHash []byte, usedAt time.Time) error {
+					return tt.dbUseCodeError
+				},
+				ReplaceRecoveryCodesFunc: func(userID string, codeHashes [][]byte, createdAt time.Time) error {
+					return nil
+				},
+			}
+			toTest := Provider{TOTPCrypter: testCrypter, TokenHasher: testTokenHasher, Storage: storageMock}
+
+			codes, err := toTest.RegenerateRecoveryCodes("test@test.test", tt.givenPassword, tt.givenCode)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			if len(codes) != tt.expectedCodes {
+				t.Errorf("Count of returned recovery codes is not as expected. Expected: %d. Given: %d", tt.expectedCodes, len(codes))
+			}
+
+			if (len(storageMock.UseRecoveryCodeCalls()) == 1) != tt.expectedUseCode {
+				t.Errorf("Unexpected count of UseRecoveryCode calls: %d", len(storageMock.UseRecoveryCodeCalls()))
+			}
+
+			replaceCalls := storageMock.ReplaceRecoveryCodesCalls()
+			if (len(replaceCalls) == 1) != tt.expectedReplacing {
+				t.Fatalf("Unexpected count of ReplaceRecoveryCodes calls: %d", len(replaceCalls))
+			}
+			if tt.expectedReplacing {
+				for i, code := range codes {
+					if !bytes.Equal(replaceCalls[0].CodeHashes[i], []byte("hashed-"+strings.Replace(code, "-", "", 1))) {
+						t.Errorf("Stored hash of recovery code %d does not match", i)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestProvider_ResetMFA(t *testing.T) {
+	tests := []struct {
+		name           string
+		dbUserError    error
+		dbDeleteError  error
+		expectedDelete bool
+		expectedError  error
+	}{
+		{
+			name:           "Happycase",
+			expectedDelete: true,
+		}, {
+			name:          "User not found",
+			dbUserError:   storage.ErrUserNotFound,
+			expectedError: ErrUserNotFound,
+		}, {
+			name:          "Unexpected user db error",
+			dbUserError:   errors.New("nope"),
+			expectedError: errors.New("failed to query user with email \"test@test.test\": nope"),
+		}, {
+			name:           "Unexpected delete db error",
+			dbDeleteError:  errors.New("nope"),
+			expectedDelete: true,
+			expectedError:  errors.New("failed to delete mfa of user \"c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b\": nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storageMock := &StorageMock{
+				UserFunc: func(email string) (storage.User, error) {
+					return storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", EMail: email}, tt.dbUserError
+				},
+				DeleteMFAFunc: func(userID string) error {
+					return tt.dbDeleteError
+				},
+			}
+			toTest := Provider{Storage: storageMock}
+
+			err := toTest.ResetMFA("test@test.test")
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			if (len(storageMock.DeleteMFACalls()) == 1) != tt.expectedDelete {
+				t.Errorf("Unexpected count of DeleteMFA calls: %d", len(storageMock.DeleteMFACalls()))
+			}
+		})
+	}
+}
+
+func TestGenerateRecoveryCode(t *testing.T) {
+	code, err := generateRecoveryCode()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if !regexp.MustCompile(`^[0-9a-hjkmnp-tv-z]{5}-[0-9a-hjkmnp-tv-z]{5}$`).MatchString(code) {
+		t.Errorf("Recovery code has an unexpected format: %q", code)
+	}
+}
+
+func TestProvider_hashRecoveryCode(t *testing.T) {
+	hasher, err := crypt.NewHMACSHA256([]byte("01234567890123456789012345678901"))
+	if err != nil {
+		t.Fatalf("Failed to create hasher: %s", err)
+	}
+	toTest := Provider{TokenHasher: hasher}
+
+	hash := toTest.hashRecoveryCode("ABCDE-fghjk")
+
+	plain := sha256.Sum256([]byte("abcdefghjk"))
+	if string(hash) == hex.EncodeToString(plain[:]) || bytes.Equal(hash, plain[:]) {
+		t.Error("Recovery code has been stored as plain sha256")
+	}
+
+	if !bytes.Equal(hash, toTest.hashRecoveryCode("abcde fghjk")) {
+		t.Error("Hashes of equal normalized recovery codes differ")
+	}
+}
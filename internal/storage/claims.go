@@ -0,0 +1,201 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ClaimOperatorEquals matches users whose claim equals the value
+	ClaimOperatorEquals = "eq"
+	// ClaimOperatorContains matches users whose claim contains the value like the jsonb operator @> e.g. an array claim
+	// contains all elements of an array value and an object claim contains all properties of an object value. Scalar
+	// values are contained in equal scalar claims only (not in array claims).
+	ClaimOperatorContains = "contains"
+	// ClaimOperatorExists matches users who have the claim (with any value)
+	ClaimOperatorExists = "exists"
+)
+
+// ClaimPredicate is a condition on a top level claim of users
+type ClaimPredicate struct {
+	// Claim is the name of the top level claim
+	Claim string
+	// Operator is one of the ClaimOperator* constants
+	Operator string
+	// Value is the json decoded value (numbers as float64) the claim will be compared with. It is unused by
+	// ClaimOperatorExists
+	Value interface{}
+}
+
+// Validate returns an error when the operator of p is unknown
+func (p ClaimPredicate) Validate() error {
+	switch p.Operator {
+	case ClaimOperatorEquals, ClaimOperatorContains, ClaimOperatorExists:
+		return nil
+	default:
+		return fmt.Errorf("unknown claim operator %q", p.Operator)
+	}
+}
+
+// Matches returns true when the given json decoded claims fulfill p. It evaluates p like the postgres storage does, so
+// storages without json operators can filter users after the query.
+func (p ClaimPredicate) Matches(claims map[string]interface{}) bool {
+	claim, ok := claims[p.Claim]
+	if !ok {
+		return false
+	}
+
+	switch p.Operator {
+	case ClaimOperatorEquals:
+		return reflect.DeepEqual(claim, p.Value)
+	case ClaimOperatorContains:
+		return jsonContains(claim, p.Value)
+	case ClaimOperatorExists:
+		return true
+	default:
+		return false
+	}
+}
+
+// MatchClaims returns true when the given json decoded claims fulfill all given predicates
+func MatchClaims(predicates []ClaimPredicate, claims map[string]interface{}) bool {
+	for _, p := range predicates {
+		if !p.Matches(claims) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// jsonContains returns true when the json decoded value a contains b like the jsonb operator @> does for values which
+// are not at top level: objects contain objects with a subset of their properties containing each other, arrays contain
+// arrays whose elements are each contained by one of their elements and scalars contain equal scalars.
+func jsonContains(a, b interface{}) bool {
+	switch b := b.(type) {
+	case map[string]interface{}:
+		a, ok := a.(map[string]interface{})
+		if !ok {
+			return false
+		}
+
+		for k, bv := range b {
+			av, ok := a[k]
+			if !ok || !jsonContains(av, bv) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		a, ok := a.([]interface{})
+		if !ok {
+			return false
+		}
+
+		for _, bv := range b {
+			found := false
+			for _, av := range a {
+				if jsonContains(av, bv) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+// claimPredicatesCondition returns the sql condition (e.g. for a where clause) of all given predicates combined with
+// AND and its arguments. The placeholders of the arguments start at $firstArg. Equality and containment will be
+// checked via containment of the whole claims (@>) and existence via ?, so all predicates can use the gin index of
+// claims.
+func claimPredicatesCondition(predicates []ClaimPredicate, firstArg int) (string, []interface{}, error) {
+	if len(predicates) == 0 {
+		return "TRUE", nil, nil
+	}
+
+	var conditions []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(firstArg+len(args)-1)
+	}
+
+	for _, p := range predicates {
+		err := p.Validate()
+		if err != nil {
+			return "", nil, err
+		}
+
+		if p.Operator == ClaimOperatorExists {
+			conditions = append(conditions, "claims ? "+arg(p.Claim))
+			continue
+		}
+
+		rawValue, err := json.Marshal(p.Value)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to marshal value of claim %q: %w", p.Claim, err)
+		}
+
+		claim := arg(p.Claim)
+		value := arg(string(rawValue))
+		condition := fmt.Sprintf("claims @> jsonb_build_object(%s::text, %s::jsonb)", claim, value)
+		if p.Operator == ClaimOperatorEquals {
+			// containment of objects and arrays is no equality
+			condition += fmt.Sprintf(" AND claims -> %s::text = %s::jsonb", claim, value)
+		}
+		conditions = append(conditions, condition)
+	}
+
+	return strings.Join(conditions, " AND "), args, nil
+}
+
+// UsersByClaims finds 'limit' users ordered by id starting at 'offset' whose claims fulfill all given predicates and
+// the total count of these users. All users will be found when no predicate has been given.
+func (s *Storage) UsersByClaims(predicates []ClaimPredicate, limit, offset int) ([]User, int, error) {
+	condition, args, err := claimPredicatesCondition(predicates, 1)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	err = s.db.QueryRow("SELECT COUNT(*) FROM users WHERE "+condition+";", args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to exec count-users-stmt: %w", err)
+	}
+
+	limitArg := len(args) + 1
+	rows, err := s.db.Query(
+		fmt.Sprintf("SELECT %s FROM users WHERE %s ORDER BY id LIMIT $%d OFFSET $%d;", userColumns, condition, limitArg, limitArg+1),
+		append(args, limit, offset)...,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to exec select-users-stmt: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var users []User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		users = append(users, u)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read select-users-stmt result: %w", err)
+	}
+
+	return users, total, nil
+}
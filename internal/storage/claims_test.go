@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestClaimPredicate_Matches(t *testing.T) {
+	claims := map[string]interface{}{
+		"role":   "admin",
+		"level":  float64(4711),
+		"groups": []interface{}{"dev", "ops", map[string]interface{}{"name": "qa", "lead": true}},
+		"org":    map[string]interface{}{"name": "acme", "tags": []interface{}{"eu"}},
+	}
+
+	tests := []struct {
+		name            string
+		givenPredicate  ClaimPredicate
+		expectedMatches bool
+	}{
+		{
+			name:            "equals scalar",
+			givenPredicate:  ClaimPredicate{Claim: "level", Operator: ClaimOperatorEquals, Value: float64(4711)},
+			expectedMatches: true,
+		},
+		{
+			name:           "equals other scalar",
+			givenPredicate: ClaimPredicate{Claim: "role", Operator: ClaimOperatorEquals, Value: "user"},
+		},
+		{
+			name:           "equals object subset",
+			givenPredicate: ClaimPredicate{Claim: "org", Operator: ClaimOperatorEquals, Value: map[string]interface{}{"name": "acme"}},
+		},
+		{
+			name:            "contains array subset",
+			givenPredicate:  ClaimPredicate{Claim: "groups", Operator: ClaimOperatorContains, Value: []interface{}{"ops", "dev"}},
+			expectedMatches: true,
+		},
+		{
+			name:            "contains nested object in array",
+			givenPredicate:  ClaimPredicate{Claim: "groups", Operator: ClaimOperatorContains, Value: []interface{}{map[string]interface{}{"name": "qa"}}},
+			expectedMatches: true,
+		},
+		{
+			name:           "contains scalar in array",
+			givenPredicate: ClaimPredicate{Claim: "groups", Operator: ClaimOperatorContains, Value: "dev"},
+		},
+		{
+			name:            "contains object subset",
+			givenPredicate:  ClaimPredicate{Claim: "org", Operator: ClaimOperatorContains, Value: map[string]interface{}{"tags": []interface{}{"eu"}}},
+			expectedMatches: true,
+		},
+		{
+			name:           "contains unknown property",
+			givenPredicate: ClaimPredicate{Claim: "org", Operator: ClaimOperatorContains, Value: map[string]interface{}{"city": "Hamburg"}},
+		},
+		{
+			name:            "exists",
+			givenPredicate:  ClaimPredicate{Claim: "role", Operator: ClaimOperatorExists},
+			expectedMatches: true,
+		},
+		{
+			name:           "missing claim",
+			givenPredicate: ClaimPredicate{Claim: "email", Operator: ClaimOperatorExists},
+		},
+		{
+			name:           "unknown operator",
+			givenPredicate: ClaimPredicate{Claim: "role", Operator: "like", Value: "admin"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := tt.givenPredicate.Matches(claims)
+			if matches != tt.expectedMatches {
+				t.Errorf("Unexpected match. Expected: %t, Given: %t", tt.expectedMatches, matches)
+			}
+		})
+	}
+}
+
+func TestStorage_UsersByClaims(t *testing.T) {
+	passwordChangedAt := time.Date(2020, 2, 1, 4, 46, 45, 2, time.UTC)
+	predicates := []ClaimPredicate{
+		{Claim: "role", Operator: ClaimOperatorEquals, Value: "admin"},
+		{Claim: "groups", Operator: ClaimOperatorContains, Value: []interface{}{"dev"}},
+		{Claim: "beta", Operator: ClaimOperatorExists},
+	}
+	condition := `claims @> jsonb_build_object($1::text, $2::jsonb) AND claims -> $1::text = $2::jsonb AND ` +
+		`claims @> jsonb_build_object($3::text, $4::jsonb) AND claims ? $5`
+
+	tests := []struct {
+		name            string
+		givenPredicates []ClaimPredicate
+		countDBErr      error
+		selectDBErr     error
+		expectedUsers   []User
+		expectedTotal   int
+		expectedError   error
+	}{
+		{
+			name:            "Happycase",
+			givenPredicates: predicates,
+			expectedUsers: []User{{
+				ID:                "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				EMail:             "info@leberkleber.io",
+				DisplayEMail:      "info@leberkleber.io",
+				Password:          []byte("bcryptedPassword"),
+				Claims:            map[string]interface{}{"role": "admin"},
+				PasswordChangedAt: passwordChangedAt,
+			}},
+			expectedTotal: 7,
+		},
+		{
+			name:            "Unknown operator",
+			givenPredicates: []ClaimPredicate{{Claim: "role", Operator: "like", Value: "admin"}},
+			expectedError:   errors.New(`unknown claim operator "like"`),
+		},
+		{
+			name:            "Unexpected count db error",
+			givenPredicates: predicates,
+			countDBErr:      errors.New("nope"),
+			expectedError:   errors.New("failed to exec count-users-stmt: nope"),
+		},
+		{
+			name:            "Unexpected select db error",
+			givenPredicates: predicates,
+			selectDBErr:     errors.New("nope"),
+			expectedError:   errors.New("failed to exec select-users-stmt: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM users WHERE `+condition+`;`)).
+				WithArgs("role", `"admin"`, "groups", `["dev"]`, "beta").
+				WillReturnError(tt.countDBErr).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT `+userColumns+` FROM users WHERE `+condition+` ORDER BY id LIMIT $6 OFFSET $7;`)).
+				WithArgs("role", `"admin"`, "groups", `["dev"]`, "beta", 1, 2).
+				WillReturnError(tt.selectDBErr).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password", "claims", "password_changed_at", "password_max_age_days", "display_email", "username", "phone", "metadata", "last_login_at"}).
+					AddRow("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", "info@leberkleber.io", "bcryptedPassword", `{"role": "admin"}`, passwordChangedAt, 0, "info@leberkleber.io", "", "", nil, nil))
+
+			s := Storage{db: db}
+
+			users, total, err := s.UsersByClaims(tt.givenPredicates, 1, 2)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
+			}
+
+			if total != tt.expectedTotal {
+				t.Errorf("Returned total is not as expected. Expected: %d, Given: %d", tt.expectedTotal, total)
+			}
+
+			if !reflect.DeepEqual(users, tt.expectedUsers) {
+				t.Errorf("Returned users are not as expected. Expected:\n%#v\nGiven:\n%#v", tt.expectedUsers, users)
+			}
+		})
+	}
+}
+
+func TestStorage_UsersByClaims_WithoutPredicates(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("Failed to create sql mock", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM users WHERE TRUE;`)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT `+userColumns+` FROM users WHERE TRUE ORDER BY id LIMIT $1 OFFSET $2;`)).
+		WithArgs(10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password", "claims", "password_changed_at", "password_max_age_days", "display_email", "username", "phone", "metadata", "last_login_at"}))
+
+	s := Storage{db: db}
+
+	users, total, err := s.UsersByClaims(nil, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to find users: %s", err)
+	}
+
+	if total != 0 || len(users) != 0 {
+		t.Errorf("Unexpected users. Expected none, Given: %d of %d", len(users), total)
+	}
+
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("Unfulfilled db expectations: %s", err)
+	}
+}
@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"fmt"
+)
+
+// TryLock tries to acquire the database wide advisory lock with the given name. The lock is held by a transaction, so
+// it will be released by the returned unlock func or when the connection gets lost. ok is false (without error) when
+// the lock is held by someone else, e.g. another instance of the provider. Locks are shared by all realms.
+func (s *Storage) TryLock(name string) (unlock func() error, ok bool, err error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin lock transaction: %w", err)
+	}
+
+	err = tx.QueryRow("SELECT pg_try_advisory_xact_lock(hashtext($1));", name).Scan(&ok)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, false, fmt.Errorf("failed to exec try-lock-stmt: %w", err)
+	}
+
+	if !ok {
+		_ = tx.Rollback()
+		return nil, false, nil
+	}
+
+	return tx.Rollback, true, nil
+}
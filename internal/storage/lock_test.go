@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"testing"
+)
+
+func TestStorage_TryLock(t *testing.T) {
+	tests := []struct {
+		name             string
+		dbBeginErr       error
+		dbResponseErr    error
+		dbLocked         bool
+		expectedOK       bool
+		expectedUnlocked bool
+		expectedErr      error
+	}{
+		{
+			name:             "Happycase",
+			dbLocked:         true,
+			expectedOK:       true,
+			expectedUnlocked: true,
+		},
+		{
+			name:     "Locked by someone else",
+			dbLocked: false,
+		},
+		{
+			name:        "Error while begin",
+			dbBeginErr:  errors.New("nope"),
+			expectedErr: errors.New("failed to begin lock transaction: nope"),
+		},
+		{
+			name:          "Error while query",
+			dbResponseErr: errors.New("nope"),
+			expectedErr:   errors.New("failed to exec try-lock-stmt: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			mock.ExpectBegin().WillReturnError(tt.dbBeginErr)
+			if tt.dbBeginErr == nil {
+				mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock\(hashtext\(\$1\)\);`).
+					WithArgs("my-lock").
+					WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(tt.dbLocked)).
+					WillReturnError(tt.dbResponseErr)
+				mock.ExpectRollback()
+			}
+
+			s := Storage{db: db}
+
+			unlock, ok, err := s.TryLock("my-lock")
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedErr) {
+				t.Fatalf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedErr, err)
+			}
+			if ok != tt.expectedOK {
+				t.Fatalf("Returned ok is not as expected. Expected: %t. Given: %t", tt.expectedOK, ok)
+			}
+			if (unlock != nil) != tt.expectedUnlocked {
+				t.Fatalf("Returned unlock func is not as expected. Expected: %t. Given: %t", tt.expectedUnlocked, unlock != nil)
+			}
+			if unlock != nil {
+				err = unlock()
+				if err != nil {
+					t.Fatalf("Unexpected unlock error: %s", err)
+				}
+			}
+
+			err = mock.ExpectationsWereMet()
+			if err != nil {
+				t.Errorf("Not all expectations were met: %s", err)
+			}
+		})
+	}
+}
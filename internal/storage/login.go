@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	LoginOutcomeSuccess         = "success"
+	LoginOutcomeMFARequired     = "mfa_required"
+	LoginOutcomePasswordExpired = "password_expired"
+	LoginOutcomeFailed          = "failed"
+)
+
+// Login is the representation of a login attempt for use in storage
+type Login struct {
+	ID        int64
+	UserID    string
+	CreatedAt time.Time
+	IP        string
+	UserAgent string
+	// Outcome is one of the LoginOutcome* constants
+	Outcome string
+	// Factor is the factor which has been used on the attempt e.g. 'password' or 'totp'
+	Factor string
+}
+
+// AddLogin persists the given login attempt and sets the last login of the user when the attempt succeeded in one
+// transaction.
+func (s *Storage) AddLogin(l Login) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin login transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.Exec(
+		"INSERT INTO logins (user_id, created_at, ip, user_agent, outcome, factor) VALUES($1, $2, $3, $4, $5, $6);",
+		l.UserID, l.CreatedAt, l.IP, l.UserAgent, l.Outcome, l.Factor,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to exec insert login stmt: %w", err)
+	}
+
+	if l.Outcome == LoginOutcomeSuccess {
+		_, err = tx.Exec("UPDATE users SET last_login_at = $2 WHERE id = $1;", l.UserID, l.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to exec update last login stmt: %w", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit login transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Logins finds 'limit' login attempts of the user with the given id starting at 'offset' and the total count of login
+// attempts of the user. The newest attempt comes first.
+func (s *Storage) Logins(userID string, limit, offset int) ([]Login, int, error) {
+	var total int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM logins WHERE user_id = $1;", userID).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to exec count-logins-stmt: %w", err)
+	}
+
+	rows, err := s.db.Query(
+		"SELECT id, user_id, created_at, ip, user_agent, outcome, factor FROM logins WHERE user_id = $1 "+
+			"ORDER BY created_at DESC, id DESC LIMIT $2 OFFSET $3;",
+		userID, limit, offset,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to exec select-logins-stmt: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var logins []Login
+	for rows.Next() {
+		var l Login
+		err := rows.Scan(&l.ID, &l.UserID, &l.CreatedAt, &l.IP, &l.UserAgent, &l.Outcome, &l.Factor)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan select-logins-stmt result: %w", err)
+		}
+
+		logins = append(logins, l)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read select-logins-stmt result: %w", err)
+	}
+
+	return logins, total, nil
+}
+
+// DeleteLoginsCreatedBefore deletes the login attempts of all users which have been created before the given time and
+// returns the count of deleted attempts.
+func (s *Storage) DeleteLoginsCreatedBefore(createdBefore time.Time) (int64, error) {
+	res, err := s.db.Exec("DELETE FROM logins WHERE created_at < $1;", createdBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to exec purge logins stmt: %w", err)
+	}
+
+	i, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get count of affected rows: %w", err)
+	}
+
+	return i, nil
+}
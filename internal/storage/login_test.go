@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStorage_AddLogin(t *testing.T) {
+	createdAt := time.Date(2020, 2, 1, 4, 46, 45, 2, time.UTC)
+
+	tests := []struct {
+		name                  string
+		givenOutcome          string
+		insertDBResponseErr   error
+		updateDBResponseErr   error
+		commitDBResponseErr   error
+		expectedUpdateExecute bool
+		expectedError         error
+	}{
+		{
+			name:                  "Happycase",
+			givenOutcome:          LoginOutcomeSuccess,
+			expectedUpdateExecute: true,
+		},
+		{
+			name:         "Failed login",
+			givenOutcome: LoginOutcomeFailed,
+		},
+		{
+			name:                "Unexpected insert db error",
+			givenOutcome:        LoginOutcomeSuccess,
+			insertDBResponseErr: errors.New("nope"),
+			expectedError:       errors.New("failed to exec insert login stmt: nope"),
+		},
+		{
+			name:                  "Unexpected update db error",
+			givenOutcome:          LoginOutcomeSuccess,
+			updateDBResponseErr:   errors.New("nope"),
+			expectedUpdateExecute: true,
+			expectedError:         errors.New("failed to exec update last login stmt: nope"),
+		},
+		{
+			name:                "Unexpected commit error",
+			givenOutcome:        LoginOutcomeFailed,
+			commitDBResponseErr: errors.New("nope"),
+			expectedError:       errors.New("failed to commit login transaction: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			mock.ExpectBegin()
+
+			mock.
+				ExpectExec(`INSERT INTO logins \(user_id, created_at, ip, user_agent, outcome, factor\) VALUES\(\$1, \$2, \$3, \$4, \$5, \$6\);`).
+				WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", createdAt, "127.0.0.1", "curl/7.68.0", tt.givenOutcome, "password").
+				WillReturnError(tt.insertDBResponseErr).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+
+			if tt.expectedUpdateExecute {
+				mock.
+					ExpectExec(`UPDATE users SET last_login_at = \$2 WHERE id = \$1;`).
+					WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", createdAt).
+					WillReturnError(tt.updateDBResponseErr).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			if tt.expectedError == nil || tt.commitDBResponseErr != nil {
+				mock.ExpectCommit().WillReturnError(tt.commitDBResponseErr)
+			}
+
+			s := Storage{db: db}
+
+			err = s.AddLogin(Login{
+				UserID:    "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				CreatedAt: createdAt,
+				IP:        "127.0.0.1",
+				UserAgent: "curl/7.68.0",
+				Outcome:   tt.givenOutcome,
+				Factor:    "password",
+			})
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Errorf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
+			}
+
+			err = mock.ExpectationsWereMet()
+			if err != nil {
+				t.Errorf("Not all expectations were met: %s", err)
+			}
+		})
+	}
+}
+
+func TestStorage_Logins(t *testing.T) {
+	createdAt := time.Date(2020, 2, 1, 4, 46, 45, 2, time.UTC)
+
+	tests := []struct {
+		name           string
+		countDBErr     error
+		selectDBErr    error
+		expectedLogins []Login
+		expectedTotal  int
+		expectedError  error
+	}{
+		{
+			name: "Happycase",
+			expectedLogins: []Login{{
+				ID:        2,
+				UserID:    "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				CreatedAt: createdAt,
+				IP:        "127.0.0.1",
+				UserAgent: "curl/7.68.0",
+				Outcome:   LoginOutcomeFailed,
+				Factor:    "password",
+			}},
+			expectedTotal: 7,
+		},
+		{
+			name:          "Unexpected count db error",
+			countDBErr:    errors.New("nope"),
+			expectedError: errors.New("failed to exec count-logins-stmt: nope"),
+		},
+		{
+			name:          "Unexpected select db error",
+			selectDBErr:   errors.New("nope"),
+			expectedError: errors.New("failed to exec select-logins-stmt: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			mock.ExpectQuery(`SELECT COUNT\(\*\) FROM logins WHERE user_id = \$1;`).
+				WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b").
+				WillReturnError(tt.countDBErr).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+			mock.ExpectQuery(`SELECT id, user_id, created_at, ip, user_agent, outcome, factor FROM logins WHERE user_id = \$1 ORDER BY created_at DESC, id DESC LIMIT \$2 OFFSET \$3;`).
+				WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", 1, 2).
+				WillReturnError(tt.selectDBErr).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "created_at", "ip", "user_agent", "outcome", "factor"}).
+					AddRow(2, "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", createdAt, "127.0.0.1", "curl/7.68.0", LoginOutcomeFailed, "password"))
+
+			s := Storage{db: db}
+
+			logins, total, err := s.Logins("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", 1, 2)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
+			}
+
+			if total != tt.expectedTotal {
+				t.Errorf("Returned total is not as expected. Expected: %d, Given: %d", tt.expectedTotal, total)
+			}
+
+			if !reflect.DeepEqual(logins, tt.expectedLogins) {
+				t.Errorf("Returned logins are not as expected. Expected:\n%#v\nGiven:\n%#v", tt.expectedLogins, logins)
+			}
+		})
+	}
+}
+
+func TestStorage_DeleteLoginsCreatedBefore(t *testing.T) {
+	tests := []struct {
+		name             string
+		dbResponseErr    error
+		dbResponseResult driver.Result
+		expectedCount    int64
+		expectedErr      error
+	}{
+		{
+			name:             "Happycase",
+			dbResponseResult: sqlmock.NewResult(0, 3),
+			expectedCount:    3,
+		},
+		{
+			name:          "Error while exec",
+			dbResponseErr: errors.New("nope"),
+			expectedErr:   errors.New("failed to exec purge logins stmt: nope"),
+		},
+		{
+			name:             "Error while get affected rows (should not be possible)",
+			dbResponseResult: sqlmock.NewErrorResult(errors.New("aaaaaaaaaa")),
+			expectedErr:      errors.New("failed to get count of affected rows: aaaaaaaaaa"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			mock.
+				ExpectExec(`DELETE FROM logins WHERE created_at < \$1;`).
+				WithArgs(time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)).
+				WillReturnResult(tt.dbResponseResult).
+				WillReturnError(tt.dbResponseErr)
+
+			s := Storage{db: db}
+
+			count, err := s.DeleteLoginsCreatedBefore(time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC))
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedErr) {
+				t.Errorf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedErr, err)
+			}
+			if count != tt.expectedCount {
+				t.Errorf("Returned count is not as expected. Expected: %d. Given: %d", tt.expectedCount, count)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package memory
+
+import (
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"sort"
+)
+
+// UsersByClaims finds 'limit' users ordered by id starting at 'offset' whose claims fulfill all given predicates and
+// the total count of these users. All users will be found when no predicate has been given.
+func (s *Storage) UsersByClaims(predicates []storage.ClaimPredicate, limit, offset int) ([]storage.User, int, error) {
+	for _, p := range predicates {
+		err := p.Validate()
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matching []*user
+	for _, u := range s.data.Users {
+		if storage.MatchClaims(predicates, u.Claims) {
+			matching = append(matching, u)
+		}
+	}
+
+	sort.Slice(matching, func(i, j int) bool { return matching[i].ID < matching[j].ID })
+
+	var users []storage.User
+	for i := offset; i < len(matching) && len(users) < limit; i++ {
+		c, err := matching[i].copy()
+		if err != nil {
+			return nil, 0, err
+		}
+
+		users = append(users, c)
+	}
+
+	return users, len(matching), nil
+}
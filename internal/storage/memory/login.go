@@ -0,0 +1,107 @@
+package memory
+
+import (
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"sort"
+	"time"
+)
+
+// AddLogin persists the given login attempt and sets the last login of the user when the attempt succeeded.
+func (s *Storage) AddLogin(l storage.Login) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.data.Users[l.UserID]
+	if !ok {
+		return fmt.Errorf("failed to add login: user with id %q does not exist", l.UserID)
+	}
+
+	l.ID = s.data.nextID()
+	s.data.Logins = append(s.data.Logins, &l)
+
+	if l.Outcome == storage.LoginOutcomeSuccess {
+		lastLoginAt := l.CreatedAt
+		u.LastLoginAt = &lastLoginAt
+	}
+
+	return nil
+}
+
+// Logins finds 'limit' login attempts of the user with the given id starting at 'offset' and the total count of login
+// attempts of the user. The newest attempt comes first.
+func (s *Storage) Logins(userID string, limit, offset int) ([]storage.Login, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	logins := s.userLogins(userID)
+	sort.SliceStable(logins, func(i, j int) bool {
+		return loginBefore(logins[j], logins[i])
+	})
+
+	total := len(logins)
+	if offset > total {
+		offset = total
+	}
+	logins = logins[offset:]
+	if limit >= 0 && limit < len(logins) {
+		logins = logins[:limit]
+	}
+	if len(logins) == 0 {
+		return nil, total, nil
+	}
+
+	return logins, total, nil
+}
+
+// DeleteLoginsCreatedBefore deletes the login attempts of all users which have been created before the given time and
+// returns the count of deleted attempts.
+func (s *Storage) DeleteLoginsCreatedBefore(createdBefore time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.deleteLogins(func(l *storage.Login) bool { return l.CreatedAt.Before(createdBefore) }), nil
+}
+
+// userLogins returns copies of all login attempts of the user with the given id ordered by creation. mu has to be
+// locked
+func (s *Storage) userLogins(userID string) []storage.Login {
+	var logins []storage.Login
+	for _, l := range s.data.Logins {
+		if l.UserID == userID {
+			logins = append(logins, *l)
+		}
+	}
+
+	sort.SliceStable(logins, func(i, j int) bool {
+		return loginBefore(logins[i], logins[j])
+	})
+
+	return logins
+}
+
+// loginBefore returns true when login a has been created before login b. Logins with the same creation time are
+// ordered by id
+func loginBefore(a, b storage.Login) bool {
+	if a.CreatedAt.Equal(b.CreatedAt) {
+		return a.ID < b.ID
+	}
+
+	return a.CreatedAt.Before(b.CreatedAt)
+}
+
+// deleteLogins deletes all matching login attempts and returns their count. mu has to be locked
+func (s *Storage) deleteLogins(match func(l *storage.Login) bool) int64 {
+	var deleted int64
+	kept := s.data.Logins[:0]
+	for _, l := range s.data.Logins {
+		if match(l) {
+			deleted++
+			continue
+		}
+		kept = append(kept, l)
+	}
+	s.data.Logins = kept
+
+	return deleted
+}
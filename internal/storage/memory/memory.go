@@ -0,0 +1,265 @@
+// Package memory is the in-memory implementation of the storage for development and tests. It uses the types and
+// errors of package storage and behaves like the postgres storage. All data can be persisted to a snapshot file on
+// close and will be loaded from it on open.
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type Storage struct {
+	mu   sync.RWMutex
+	data *data
+	// patchMu serializes PatchUser and UpdateUser, so concurrent patches of the same user will be applied one after
+	// another without holding mu while the patch function reads the storage
+	patchMu sync.Mutex
+	// locks are the names of all held locks. They are shared by all realms
+	locks *locks
+	// realms are the storages of all realms. They are shared by all realms
+	realms *realms
+	// snapshotPath is the path of the snapshot file. It is empty for realm storages and storages without snapshot
+	snapshotPath string
+}
+
+// locks are process wide locks
+type locks struct {
+	mu    sync.Mutex
+	names map[string]bool
+}
+
+// realms are the storages of all realms by name
+type realms struct {
+	mu       sync.Mutex
+	storages map[string]*Storage
+}
+
+// data is everything stored in a Storage
+type data struct {
+	// LastID is the last id of all tokens, password history entries, recovery codes and logins
+	LastID              int64
+	Users               map[string]*user
+	Tokens              []*storage.Token
+	PasswordHistory     []*passwordHistoryEntry
+	TOTPs               map[string]*storage.TOTP
+	WebAuthnCredentials []*storage.WebAuthnCredential
+	RecoveryCodes       []*recoveryCode
+	Logins              []*storage.Login
+	Realms              map[string]*storage.Realm
+}
+
+// snapshot is the content of snapshot files
+type snapshot struct {
+	Default *data
+	Realms  map[string]*data
+}
+
+// New creates an empty storage without snapshot. All data will be lost on close.
+func New() *Storage {
+	return newStorage(&data{}, &locks{names: map[string]bool{}}, &realms{storages: map[string]*Storage{}}, "")
+}
+
+// Open creates a storage which will be persisted to the snapshot file with the given path on close. The data of the
+// default realm and all realms will be loaded from the snapshot file when it exists.
+func Open(snapshotPath string) (*Storage, error) {
+	s := New()
+	s.snapshotPath = snapshotPath
+
+	raw, err := ioutil.ReadFile(snapshotPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var snap snapshot
+	err = json.Unmarshal(raw, &snap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal snapshot: %w", err)
+	}
+
+	if snap.Default != nil {
+		s.data = snap.Default.init()
+	}
+	for name, d := range snap.Realms {
+		s.realms.storages[name] = newStorage(d, s.locks, s.realms, "")
+	}
+
+	return s, nil
+}
+
+func newStorage(d *data, l *locks, r *realms, snapshotPath string) *Storage {
+	return &Storage{
+		data:         d.init(),
+		locks:        l,
+		realms:       r,
+		snapshotPath: snapshotPath,
+	}
+}
+
+// init initializes all nil maps of d and returns it
+func (d *data) init() *data {
+	if d.Users == nil {
+		d.Users = map[string]*user{}
+	}
+	if d.TOTPs == nil {
+		d.TOTPs = map[string]*storage.TOTP{}
+	}
+	if d.Realms == nil {
+		d.Realms = map[string]*storage.Realm{}
+	}
+
+	return d
+}
+
+// nextID returns the next id of tokens, password history entries, recovery codes and logins. mu has to be locked
+func (d *data) nextID() int64 {
+	d.LastID++
+	return d.LastID
+}
+
+// RealmStorage returns the Storage of the realm with the given name. It will be created on first call and persisted
+// with the snapshot of s.
+func (s *Storage) RealmStorage(name string) (*Storage, error) {
+	s.realms.mu.Lock()
+	defer s.realms.mu.Unlock()
+
+	realmStorage, ok := s.realms.storages[name]
+	if !ok {
+		realmStorage = newStorage(&data{}, s.locks, s.realms, "")
+		s.realms.storages[name] = realmStorage
+	}
+
+	return realmStorage, nil
+}
+
+// Close persists the data of s and of all realms to the snapshot file. The file will be replaced atomically. Storages
+// without snapshot file (realm storages too) will not be persisted.
+func (s *Storage) Close() error {
+	if s.snapshotPath == "" {
+		return nil
+	}
+
+	s.realms.mu.Lock()
+	defer s.realms.mu.Unlock()
+
+	snap := snapshot{
+		Realms: map[string]*data{},
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snap.Default = s.data
+
+	for name, realmStorage := range s.realms.storages {
+		realmStorage.mu.RLock()
+		//noinspection GoDeferInLoop
+		defer realmStorage.mu.RUnlock()
+		snap.Realms[name] = realmStorage.data
+	}
+
+	raw, err := json.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.snapshotPath), filepath.Base(s.snapshotPath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	_, err = tmp.Write(raw)
+	if err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close snapshot file: %w", err)
+	}
+
+	err = os.Rename(tmp.Name(), s.snapshotPath)
+	if err != nil {
+		return fmt.Errorf("failed to replace snapshot file: %w", err)
+	}
+
+	return nil
+}
+
+// TryLock tries to acquire the lock with the given name. The lock is held by this process only, since the data of a
+// memory storage can not be shared by multiple instances of the provider. ok is false (without error) when the lock is
+// already held. Locks are shared by all realms.
+func (s *Storage) TryLock(name string) (unlock func() error, ok bool, err error) {
+	s.locks.mu.Lock()
+	defer s.locks.mu.Unlock()
+
+	if s.locks.names[name] {
+		return nil, false, nil
+	}
+	s.locks.names[name] = true
+
+	return func() error {
+		s.locks.mu.Lock()
+		defer s.locks.mu.Unlock()
+
+		delete(s.locks.names, name)
+		return nil
+	}, true, nil
+}
+
+// requireUser returns an error when there is no user with the given id like the foreign keys of the sql storages. mu
+// has to be locked
+func (s *Storage) requireUser(id string) error {
+	if _, ok := s.data.Users[id]; !ok {
+		return fmt.Errorf("user with id %q does not exist", id)
+	}
+
+	return nil
+}
+
+// copyBytes returns a copy of the given bytes. Empty bytes stay empty but not nil
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+
+	c := make([]byte, len(b))
+	copy(c, b)
+	return c
+}
+
+// copyTime returns a copy of the given time
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	c := *t
+	return &c
+}
+
+// copyJSON returns a deep copy of the given json object. Values will be converted like by the sql storages (e.g.
+// all numbers become float64).
+func copyJSON(m map[string]interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	var c map[string]interface{}
+	err = json.Unmarshal(raw, &c)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
@@ -0,0 +1,187 @@
+package memory
+
+import (
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage/storagetest"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Storage {
+		return New()
+	})
+}
+
+func TestStorage_RealmStorage(t *testing.T) {
+	s := New()
+
+	realmStorage, err := s.RealmStorage("my-realm")
+	if err != nil {
+		t.Fatalf("failed to create realm storage: %s", err)
+	}
+
+	sameRealmStorage, err := s.RealmStorage("my-realm")
+	if err != nil {
+		t.Fatalf("failed to create realm storage: %s", err)
+	}
+	if sameRealmStorage != realmStorage {
+		t.Fatal("realm storages of the same realm must be the same")
+	}
+
+	err = realmStorage.CreateUser(storage.User{ID: "a8a3d6c6-3bbf-4c5a-a0a6-bcd6ee9a0ba7", EMail: "info@leberkleber.io"})
+	if err != nil {
+		t.Fatalf("failed to create user: %s", err)
+	}
+
+	_, err = s.User("info@leberkleber.io")
+	if err != storage.ErrUserNotFound {
+		t.Fatalf("unexpected error. Expected:\n%q\nGiven:\n%q", storage.ErrUserNotFound, err)
+	}
+
+	unlock, ok, err := s.TryLock("my-lock")
+	if err != nil || !ok {
+		t.Fatalf("failed to acquire lock. ok: %t, err: %v", ok, err)
+	}
+	defer func() { _ = unlock() }()
+
+	_, ok, err = realmStorage.TryLock("my-lock")
+	if err != nil || ok {
+		t.Fatalf("locks must be shared by all realms. ok: %t, err: %v", ok, err)
+	}
+}
+
+func TestStorage_PatchUserReadsStorage(t *testing.T) {
+	s := New()
+
+	err := s.CreateUser(storage.User{ID: "a8a3d6c6-3bbf-4c5a-a0a6-bcd6ee9a0ba7", EMail: "info@leberkleber.io"})
+	if err != nil {
+		t.Fatalf("failed to create user: %s", err)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- s.PatchUser("a8a3d6c6-3bbf-4c5a-a0a6-bcd6ee9a0ba7", func(u *storage.User) error {
+			_, err := s.PasswordHistory(u.ID, 5)
+			return err
+		})
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("failed to patch user: %s", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("patch function must be able to read the storage")
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "memory")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	snapshotPath := filepath.Join(dir, "snapshot.json")
+	createdAt := time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	s, err := Open(snapshotPath)
+	if err != nil {
+		t.Fatalf("failed to open storage without snapshot: %s", err)
+	}
+
+	err = s.CreateUser(storage.User{
+		ID:                "a8a3d6c6-3bbf-4c5a-a0a6-bcd6ee9a0ba7",
+		EMail:             "info@leberkleber.io",
+		Password:          []byte("password"),
+		Claims:            map[string]interface{}{"role": "admin"},
+		PasswordChangedAt: createdAt,
+	})
+	if err != nil {
+		t.Fatalf("failed to create user: %s", err)
+	}
+
+	tokenID, err := s.CreateToken(storage.Token{UserID: "a8a3d6c6-3bbf-4c5a-a0a6-bcd6ee9a0ba7", Token: "token", Type: "reset", CreatedAt: createdAt})
+	if err != nil {
+		t.Fatalf("failed to create token: %s", err)
+	}
+
+	realmStorage, err := s.RealmStorage("my-realm")
+	if err != nil {
+		t.Fatalf("failed to create realm storage: %s", err)
+	}
+
+	err = realmStorage.CreateUser(storage.User{ID: "e0a9f1b8-6a5a-4e53-9b0c-2b3e0f6c2a11", EMail: "realm@leberkleber.io"})
+	if err != nil {
+		t.Fatalf("failed to create realm user: %s", err)
+	}
+
+	err = s.Close()
+	if err != nil {
+		t.Fatalf("failed to close storage: %s", err)
+	}
+
+	s, err = Open(snapshotPath)
+	if err != nil {
+		t.Fatalf("failed to open storage with snapshot: %s", err)
+	}
+
+	u, err := s.User("info@leberkleber.io")
+	if err != nil {
+		t.Fatalf("failed to find user: %s", err)
+	}
+	if string(u.Password) != "password" || u.Claims["role"] != "admin" || !u.PasswordChangedAt.Equal(createdAt) {
+		t.Errorf("unexpected user: %+v", u)
+	}
+
+	tokens, err := s.TokensByUserIDAndType("a8a3d6c6-3bbf-4c5a-a0a6-bcd6ee9a0ba7", "reset")
+	if err != nil {
+		t.Fatalf("failed to find tokens: %s", err)
+	}
+	if len(tokens) != 1 || tokens[0].ID != tokenID || tokens[0].Token != "token" {
+		t.Errorf("unexpected tokens: %+v", tokens)
+	}
+
+	nextTokenID, err := s.CreateToken(storage.Token{UserID: "a8a3d6c6-3bbf-4c5a-a0a6-bcd6ee9a0ba7", Token: "next", Type: "reset", CreatedAt: createdAt})
+	if err != nil {
+		t.Fatalf("failed to create token: %s", err)
+	}
+	if nextTokenID <= tokenID {
+		t.Errorf("ids must not be reused. Expected id > %d. Given: %d", tokenID, nextTokenID)
+	}
+
+	realmStorage, err = s.RealmStorage("my-realm")
+	if err != nil {
+		t.Fatalf("failed to create realm storage: %s", err)
+	}
+
+	_, err = realmStorage.User("realm@leberkleber.io")
+	if err != nil {
+		t.Fatalf("failed to find realm user: %s", err)
+	}
+}
+
+func TestOpen_InvalidSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "memory")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	snapshotPath := filepath.Join(dir, "snapshot.json")
+	err = ioutil.WriteFile(snapshotPath, []byte("no json"), 0600)
+	if err != nil {
+		t.Fatalf("failed to write snapshot: %s", err)
+	}
+
+	_, err = Open(snapshotPath)
+	expectedError := "failed to unmarshal snapshot: invalid character 'o' in literal null (expecting 'u')"
+	if err == nil || err.Error() != expectedError {
+		t.Fatalf("unexpected error. Expected:\n%q\nGiven:\n%q", expectedError, err)
+	}
+}
@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"time"
+)
+
+// UsersToRemindOfPasswordExpiry finds all users whose password expires within the next 'reminderDays' days (at 'now')
+// and who have not been reminded since their last password change. 'defaultMaxAgeDays' will be used for all users
+// without a user specific password max age. Users without email can not be reminded and will be skipped like users
+// without password (pending invitations) whose password can not expire.
+func (s *Storage) UsersToRemindOfPasswordExpiry(defaultMaxAgeDays, reminderDays int, now time.Time) ([]storage.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var users []storage.User
+	for _, u := range s.data.Users {
+		if u.EMail == "" || len(u.Password) == 0 {
+			continue
+		}
+		if u.PasswordExpiryRemindedAt != nil && !u.PasswordExpiryRemindedAt.Before(u.PasswordChangedAt) {
+			continue
+		}
+
+		maxAgeDays := defaultMaxAgeDays
+		if u.PasswordMaxAgeDays > 0 {
+			maxAgeDays = u.PasswordMaxAgeDays
+		}
+		if maxAgeDays <= 0 || u.PasswordChangedAt.AddDate(0, 0, maxAgeDays-reminderDays).After(now) {
+			continue
+		}
+
+		c, err := u.copy()
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, c)
+	}
+
+	return users, nil
+}
+
+// MarkPasswordExpiryReminded persists that the user with the given id has been reminded of the password expiry.
+// return storage.ErrUserNotFound when user not found
+func (s *Storage) MarkPasswordExpiryReminded(userID string, remindedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.data.Users[userID]
+	if !ok {
+		return storage.ErrUserNotFound
+	}
+
+	u.PasswordExpiryRemindedAt = &remindedAt
+	return nil
+}
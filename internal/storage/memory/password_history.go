@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// passwordHistoryEntry is a stored password history entry
+type passwordHistoryEntry struct {
+	ID        int64
+	UserID    string
+	Password  []byte
+	CreatedAt time.Time
+}
+
+// PasswordHistory finds the password hashes of the newest 'limit' history entries of the user with the given id.
+// The newest entry comes first.
+func (s *Storage) PasswordHistory(userID string, limit int) ([][]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var passwords [][]byte
+	for _, e := range s.newestPasswordHistory(userID) {
+		if len(passwords) >= limit {
+			break
+		}
+		passwords = append(passwords, copyBytes(e.Password))
+	}
+
+	return passwords, nil
+}
+
+// AddPasswordHistory persists the given password hash as newest history entry of the user with the given id and
+// removes all entries except the newest 'keep' ones.
+func (s *Storage) AddPasswordHistory(userID string, password []byte, createdAt time.Time, keep int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.requireUser(userID)
+	if err != nil {
+		return fmt.Errorf("failed to add password history: %w", err)
+	}
+
+	s.data.PasswordHistory = append(s.data.PasswordHistory, &passwordHistoryEntry{
+		ID:        s.data.nextID(),
+		UserID:    userID,
+		Password:  copyBytes(password),
+		CreatedAt: createdAt,
+	})
+
+	purged := map[*passwordHistoryEntry]bool{}
+	for i, e := range s.newestPasswordHistory(userID) {
+		if i >= keep {
+			purged[e] = true
+		}
+	}
+
+	kept := s.data.PasswordHistory[:0]
+	for _, e := range s.data.PasswordHistory {
+		if !purged[e] {
+			kept = append(kept, e)
+		}
+	}
+	s.data.PasswordHistory = kept
+
+	return nil
+}
+
+// newestPasswordHistory returns all history entries of the user with the given id. The newest entry comes first. mu
+// has to be locked
+func (s *Storage) newestPasswordHistory(userID string) []*passwordHistoryEntry {
+	var entries []*passwordHistoryEntry
+	for _, e := range s.data.PasswordHistory {
+		if e.UserID == userID {
+			entries = append(entries, e)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+			return entries[i].ID > entries[j].ID
+		}
+		return entries[i].CreatedAt.After(entries[j].CreatedAt)
+	})
+
+	return entries
+}
@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"sort"
+)
+
+// Realms returns all realms ordered by name
+func (s *Storage) Realms() ([]storage.Realm, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var realms []storage.Realm
+	for _, r := range s.data.Realms {
+		realms = append(realms, copyRealm(*r))
+	}
+
+	sort.Slice(realms, func(i, j int) bool { return realms[i].Name < realms[j].Name })
+
+	return realms, nil
+}
+
+// Realm finds the realm with the given name
+// return storage.ErrRealmNotFound when realm not found
+func (s *Storage) Realm(name string) (storage.Realm, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.data.Realms[name]
+	if !ok {
+		return storage.Realm{}, storage.ErrRealmNotFound
+	}
+
+	return copyRealm(*r), nil
+}
+
+// CreateRealm persists the given realm with its hosts
+// return storage.ErrRealmAlreadyExists when a realm with the same name already exists
+// return storage.ErrRealmHostAlreadyUsed when one of the hosts belongs to another realm
+func (s *Storage) CreateRealm(r storage.Realm) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.data.Realms[r.Name]; ok {
+		return storage.ErrRealmAlreadyExists
+	}
+	if s.realmHostUsed(r) {
+		return storage.ErrRealmHostAlreadyUsed
+	}
+
+	c := copyRealm(r)
+	s.data.Realms[r.Name] = &c
+
+	return nil
+}
+
+// UpdateRealm updates all properties (excluding name and created at) of the given realm and replaces its hosts
+// return storage.ErrRealmNotFound when realm not found
+// return storage.ErrRealmHostAlreadyUsed when one of the hosts belongs to another realm
+func (s *Storage) UpdateRealm(r storage.Realm) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.data.Realms[r.Name]
+	if !ok {
+		return storage.ErrRealmNotFound
+	}
+	if s.realmHostUsed(r) {
+		return storage.ErrRealmHostAlreadyUsed
+	}
+
+	c := copyRealm(r)
+	c.CreatedAt = stored.CreatedAt
+	s.data.Realms[r.Name] = &c
+
+	return nil
+}
+
+// realmHostUsed returns true when one of the hosts of the given realm belongs to another realm or is given twice. mu
+// has to be locked
+func (s *Storage) realmHostUsed(r storage.Realm) bool {
+	used := map[string]bool{}
+	for _, other := range s.data.Realms {
+		if other.Name == r.Name {
+			continue
+		}
+		for _, host := range other.Hosts {
+			used[host] = true
+		}
+	}
+
+	for _, host := range r.Hosts {
+		if used[host] {
+			return true
+		}
+		used[host] = true
+	}
+
+	return false
+}
+
+// copyRealm returns a copy of the given realm with sorted hosts. Hosts are never nil
+func copyRealm(r storage.Realm) storage.Realm {
+	hosts := make([]string, len(r.Hosts))
+	copy(hosts, r.Hosts)
+	sort.Strings(hosts)
+	r.Hosts = hosts
+
+	return r
+}
@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"time"
+)
+
+// recoveryCode is a stored mfa recovery code
+type recoveryCode struct {
+	ID        int64
+	UserID    string
+	CodeHash  []byte
+	CreatedAt time.Time
+	UsedAt    *time.Time
+}
+
+// UnusedRecoveryCodeCount counts the not yet used mfa recovery codes of the user with the given id
+func (s *Storage) UnusedRecoveryCodeCount(userID string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var count int
+	for _, c := range s.data.RecoveryCodes {
+		if c.UserID == userID && c.UsedAt == nil {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// ReplaceRecoveryCodes replaces all mfa recovery codes of the user with the given id by the given code hashes. UserID
+// must match to a users id.
+func (s *Storage) ReplaceRecoveryCodes(userID string, codeHashes [][]byte, createdAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(codeHashes) > 0 {
+		err := s.requireUser(userID)
+		if err != nil {
+			return fmt.Errorf("failed to replace recovery codes: %w", err)
+		}
+	}
+
+	s.deleteRecoveryCodes(userID)
+	for _, codeHash := range codeHashes {
+		s.data.RecoveryCodes = append(s.data.RecoveryCodes, &recoveryCode{
+			ID:        s.data.nextID(),
+			UserID:    userID,
+			CodeHash:  copyBytes(codeHash),
+			CreatedAt: createdAt,
+		})
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks the not yet used mfa recovery code with the given hash of the user with the given id as used
+// return storage.ErrRecoveryCodeNotFound when there is no such unused recovery code
+func (s *Storage) UseRecoveryCode(userID string, codeHash []byte, usedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := false
+	for _, c := range s.data.RecoveryCodes {
+		if c.UserID == userID && c.UsedAt == nil && bytes.Equal(c.CodeHash, codeHash) {
+			usedAt := usedAt
+			c.UsedAt = &usedAt
+			found = true
+		}
+	}
+	if !found {
+		return storage.ErrRecoveryCodeNotFound
+	}
+
+	return nil
+}
+
+// DeleteMFA deletes the totp enrolment, all webauthn credentials and all recovery codes of the user with the given id
+func (s *Storage) DeleteMFA(userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.data.TOTPs, userID)
+	s.deleteWebAuthnCredentials(userID)
+	s.deleteRecoveryCodes(userID)
+
+	return nil
+}
+
+// deleteRecoveryCodes deletes all recovery codes of the user with the given id and returns their count. mu has to be
+// locked
+func (s *Storage) deleteRecoveryCodes(userID string) int64 {
+	var deleted int64
+	kept := s.data.RecoveryCodes[:0]
+	for _, c := range s.data.RecoveryCodes {
+		if c.UserID == userID {
+			deleted++
+			continue
+		}
+		kept = append(kept, c)
+	}
+	s.data.RecoveryCodes = kept
+
+	return deleted
+}
+
+// deleteWebAuthnCredentials deletes all webauthn credentials of the user with the given id and returns their count. mu
+// has to be locked
+func (s *Storage) deleteWebAuthnCredentials(userID string) int64 {
+	var deleted int64
+	kept := s.data.WebAuthnCredentials[:0]
+	for _, c := range s.data.WebAuthnCredentials {
+		if c.UserID == userID {
+			deleted++
+			continue
+		}
+		kept = append(kept, c)
+	}
+	s.data.WebAuthnCredentials = kept
+
+	return deleted
+}
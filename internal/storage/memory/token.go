@@ -0,0 +1,121 @@
+package memory
+
+import (
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"sort"
+	"time"
+)
+
+// CreateToken persists the given token. UserID must match to a users id.
+func (s *Storage) CreateToken(t storage.Token) (int64, error) {
+	metadata, err := copyJSON(t.Metadata)
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy token>metadata: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err = s.requireUser(t.UserID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create token: %w", err)
+	}
+
+	t.ID = s.data.nextID()
+	t.Attempts = 0
+	t.Metadata = metadata
+	s.data.Tokens = append(s.data.Tokens, &t)
+
+	return t.ID, nil
+}
+
+// TokensByUserIDAndType finds all tokens of the given type which belong to the user with the given id. The newest
+// token comes first.
+func (s *Storage) TokensByUserIDAndType(userID, tokenType string) ([]storage.Token, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var tokens []storage.Token
+	for _, t := range s.data.Tokens {
+		if t.UserID != userID || t.Type != tokenType {
+			continue
+		}
+
+		c := *t
+		var err error
+		c.Metadata, err = copyJSON(t.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy token>metadata: %w", err)
+		}
+
+		tokens = append(tokens, c)
+	}
+
+	sort.SliceStable(tokens, func(i, j int) bool {
+		if tokens[i].CreatedAt.Equal(tokens[j].CreatedAt) {
+			return tokens[i].ID > tokens[j].ID
+		}
+		return tokens[i].CreatedAt.After(tokens[j].CreatedAt)
+	})
+
+	return tokens, nil
+}
+
+// IncrementTokenAttempts increments the count of failed redemptions of the token with the given ID and returns the new
+// count.
+// return storage.ErrTokenNotFound there is no token with the given ID
+func (s *Storage) IncrementTokenAttempts(id int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.data.Tokens {
+		if t.ID == id {
+			t.Attempts++
+			return t.Attempts, nil
+		}
+	}
+
+	return 0, storage.ErrTokenNotFound
+}
+
+// DeleteToken deletes token with the given ID.
+// return storage.ErrTokenNotFound there is no token with the given ID
+func (s *Storage) DeleteToken(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := s.deleteTokens(func(t *storage.Token) bool { return t.ID == id })
+	if deleted != 1 {
+		return storage.ErrTokenNotFound
+	}
+
+	return nil
+}
+
+// DeleteTokensCreatedBefore deletes all tokens of the given type which have been created before the given time and
+// returns the count of deleted tokens.
+func (s *Storage) DeleteTokensCreatedBefore(tokenType string, createdBefore time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.deleteTokens(func(t *storage.Token) bool {
+		return t.Type == tokenType && t.CreatedAt.Before(createdBefore)
+	}), nil
+}
+
+// deleteTokens deletes all matching tokens and returns their count. mu has to be locked
+func (s *Storage) deleteTokens(match func(t *storage.Token) bool) int64 {
+	var deleted int64
+	kept := s.data.Tokens[:0]
+	for _, t := range s.data.Tokens {
+		if match(t) {
+			deleted++
+			continue
+		}
+		kept = append(kept, t)
+	}
+	s.data.Tokens = kept
+
+	return deleted
+}
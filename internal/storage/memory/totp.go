@@ -0,0 +1,38 @@
+package memory
+
+import (
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+)
+
+// TOTP finds the totp enrolment of the user with the given id
+// return storage.ErrTOTPNotFound when the user has no totp enrolment
+func (s *Storage) TOTP(userID string) (storage.TOTP, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.data.TOTPs[userID]
+	if !ok {
+		return storage.TOTP{}, storage.ErrTOTPNotFound
+	}
+
+	c := *t
+	c.Secret = copyBytes(t.Secret)
+	return c, nil
+}
+
+// SaveTOTP creates or replaces the totp enrolment of the user with the given id. UserID must match to a users id.
+func (s *Storage) SaveTOTP(t storage.TOTP) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.requireUser(t.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to save totp: %w", err)
+	}
+
+	t.Secret = copyBytes(t.Secret)
+	s.data.TOTPs[t.UserID] = &t
+
+	return nil
+}
@@ -0,0 +1,246 @@
+package memory
+
+import (
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"time"
+)
+
+// user is a stored user with the properties which are not part of storage.User
+type user struct {
+	storage.User
+	InvitedAt                *time.Time
+	PasswordExpiryRemindedAt *time.Time
+}
+
+// CreateUser persists the given user.
+// return storage.ErrUserAlreadyExists when a user with the same email, username or phone already exists
+func (s *Storage) CreateUser(u storage.User) error {
+	stored, err := copyUser(u)
+	if err != nil {
+		return err
+	}
+	stored.LastLoginAt = nil
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.data.Users[u.ID]; ok || s.loginIdentifierUsed(u, "") {
+		return storage.ErrUserAlreadyExists
+	}
+
+	s.data.Users[u.ID] = &user{User: stored}
+	return nil
+}
+
+// User finds the user identified by email
+// return storage.ErrUserNotFound when user not found
+func (s *Storage) User(email string) (storage.User, error) {
+	return s.findUser(func(u *user) bool { return email != "" && u.EMail == email })
+}
+
+// UserByID finds the user identified by id
+// return storage.ErrUserNotFound when user not found
+func (s *Storage) UserByID(id string) (storage.User, error) {
+	return s.findUser(func(u *user) bool { return u.ID == id })
+}
+
+// UserByUsername finds the user identified by the given normalized username
+// return storage.ErrUserNotFound when user not found
+func (s *Storage) UserByUsername(username string) (storage.User, error) {
+	return s.findUser(func(u *user) bool { return username != "" && u.Username == username })
+}
+
+// UserByPhone finds the user identified by the given phone number in E.164 format
+// return storage.ErrUserNotFound when user not found
+func (s *Storage) UserByPhone(phone string) (storage.User, error) {
+	return s.findUser(func(u *user) bool { return phone != "" && u.Phone == phone })
+}
+
+// findUser returns a copy of the first user which matches. All users will be scanned, so lookups take linear time.
+// return storage.ErrUserNotFound when no user matches
+func (s *Storage) findUser(match func(u *user) bool) (storage.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u := range s.data.Users {
+		if match(u) {
+			return u.copy()
+		}
+	}
+
+	return storage.User{}, storage.ErrUserNotFound
+}
+
+// UpdateUser updates all properties (excluding id, email and display email) from the given user which will be
+// identified by id
+// return storage.ErrUserNotFound when user not found
+// return storage.ErrUserAlreadyExists when another user has the same username or phone
+func (s *Storage) UpdateUser(u storage.User) error {
+	s.patchMu.Lock()
+	defer s.patchMu.Unlock()
+
+	return s.updateUser(u)
+}
+
+// PatchUser calls the given patch function with the user with the given id and updates all properties like UpdateUser.
+// Patches are serialized, so concurrent patches of the same user will be applied one after another. The patch function
+// may read the storage. Errors of the patch function will be returned unwrapped and nothing will be updated.
+// return storage.ErrUserNotFound when user not found
+// return storage.ErrUserAlreadyExists when another user has the same username or phone
+func (s *Storage) PatchUser(id string, patch func(u *storage.User) error) error {
+	s.patchMu.Lock()
+	defer s.patchMu.Unlock()
+
+	u, err := s.UserByID(id)
+	if err != nil {
+		return err
+	}
+
+	err = patch(&u)
+	if err != nil {
+		return err
+	}
+
+	return s.updateUser(u)
+}
+
+func (s *Storage) updateUser(u storage.User) error {
+	patched, err := copyUser(u)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.data.Users[u.ID]
+	if !ok {
+		return storage.ErrUserNotFound
+	}
+	if s.loginIdentifierUsed(patched, u.ID) {
+		return storage.ErrUserAlreadyExists
+	}
+
+	stored.Password = patched.Password
+	stored.Claims = patched.Claims
+	stored.PasswordChangedAt = patched.PasswordChangedAt
+	stored.PasswordMaxAgeDays = patched.PasswordMaxAgeDays
+	stored.Username = patched.Username
+	stored.Phone = patched.Phone
+	stored.Metadata = patched.Metadata
+
+	return nil
+}
+
+// loginIdentifierUsed returns true when a user other than the one with the given id has the same email, username or
+// phone like the given user. Empty login identifiers are never used. mu has to be locked
+func (s *Storage) loginIdentifierUsed(u storage.User, id string) bool {
+	for _, other := range s.data.Users {
+		if other.ID == id {
+			continue
+		}
+
+		if (u.EMail != "" && other.EMail == u.EMail) ||
+			(u.Username != "" && other.Username == u.Username) ||
+			(u.Phone != "" && other.Phone == u.Phone) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DeleteUser deletes the user with the given id and everything stored about the user like EraseUser.
+// return storage.ErrUserNotFound when user not found
+func (s *Storage) DeleteUser(id string) error {
+	_, err := s.EraseUser(id)
+	return err
+}
+
+// MarkUserInvited persists that the user with the given id has been invited (again) at the given time.
+// return storage.ErrUserNotFound when user not found
+func (s *Storage) MarkUserInvited(id string, invitedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.data.Users[id]
+	if !ok {
+		return storage.ErrUserNotFound
+	}
+
+	u.InvitedAt = &invitedAt
+	return nil
+}
+
+// UserEMails returns the stored emails of all users with email by the ids of the users
+func (s *Storage) UserEMails() (map[string]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	emails := map[string]string{}
+	for id, u := range s.data.Users {
+		if u.EMail != "" {
+			emails[id] = u.EMail
+		}
+	}
+
+	return emails, nil
+}
+
+// NormalizeUserEMail replaces the email of the user with the given id by the given normalized email. The previous
+// email will be kept as display email unless the user already has one.
+// return storage.ErrUserNotFound when user not found
+// return storage.ErrUserAlreadyExists when another user has the same email
+func (s *Storage) NormalizeUserEMail(id, email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.data.Users[id]
+	if !ok {
+		return storage.ErrUserNotFound
+	}
+
+	if s.loginIdentifierUsed(storage.User{EMail: email}, id) {
+		return storage.ErrUserAlreadyExists
+	}
+
+	if u.DisplayEMail == "" {
+		u.DisplayEMail = u.EMail
+	}
+	u.EMail = email
+	return nil
+}
+
+// copy returns a deep copy of the stored user. The display email equals the email when unset
+func (u *user) copy() (storage.User, error) {
+	c, err := copyUser(u.User)
+	if err != nil {
+		return storage.User{}, err
+	}
+
+	if c.DisplayEMail == "" {
+		c.DisplayEMail = c.EMail
+	}
+
+	return c, nil
+}
+
+// copyUser returns a deep copy of the given user. Claims and metadata will be converted like by the sql storages
+func copyUser(u storage.User) (storage.User, error) {
+	var err error
+	u.Claims, err = copyJSON(u.Claims)
+	if err != nil {
+		return storage.User{}, fmt.Errorf("failed to copy user>claims: %w", err)
+	}
+
+	u.Metadata, err = copyJSON(u.Metadata)
+	if err != nil {
+		return storage.User{}, fmt.Errorf("failed to copy user>metadata: %w", err)
+	}
+
+	u.Password = copyBytes(u.Password)
+	u.LastLoginAt = copyTime(u.LastLoginAt)
+
+	return u, nil
+}
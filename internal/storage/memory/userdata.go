@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"sort"
+	"time"
+)
+
+// UserData finds everything stored about the user with the given id.
+// return storage.ErrUserNotFound when user not found
+func (s *Storage) UserData(id string) (storage.UserData, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.data.Users[id]
+	if !ok {
+		return storage.UserData{}, storage.ErrUserNotFound
+	}
+
+	c, err := u.copy()
+	if err != nil {
+		return storage.UserData{}, err
+	}
+
+	data := storage.UserData{User: c}
+
+	var tokens []*storage.Token
+	for _, t := range s.data.Tokens {
+		if t.UserID == id {
+			tokens = append(tokens, t)
+		}
+	}
+	sort.SliceStable(tokens, func(i, j int) bool { return tokens[i].CreatedAt.Before(tokens[j].CreatedAt) })
+	for _, t := range tokens {
+		data.Tokens = append(data.Tokens, storage.TokenData{Type: t.Type, CreatedAt: t.CreatedAt, Attempts: t.Attempts})
+	}
+
+	for _, e := range s.data.PasswordHistory {
+		if e.UserID == id {
+			data.PasswordHistory = append(data.PasswordHistory, e.CreatedAt)
+		}
+	}
+	sort.SliceStable(data.PasswordHistory, func(i, j int) bool {
+		return data.PasswordHistory[i].Before(data.PasswordHistory[j])
+	})
+
+	if t, ok := s.data.TOTPs[id]; ok {
+		data.TOTP = &storage.TOTPData{Confirmed: t.Confirmed, CreatedAt: t.CreatedAt}
+	}
+
+	for _, c := range s.userWebAuthnCredentials(id) {
+		data.WebAuthnCredentials = append(data.WebAuthnCredentials, storage.WebAuthnCredentialData{
+			ID:         copyBytes(c.ID),
+			SignCount:  c.SignCount,
+			CreatedAt:  c.CreatedAt,
+			LastUsedAt: copyTime(c.LastUsedAt),
+		})
+	}
+
+	// recovery codes are stored in order of their ids
+	for _, c := range s.data.RecoveryCodes {
+		if c.UserID == id {
+			data.RecoveryCodes = append(data.RecoveryCodes, storage.RecoveryCodeData{
+				CreatedAt: c.CreatedAt,
+				UsedAt:    copyTime(c.UsedAt),
+			})
+		}
+	}
+
+	data.Logins = s.userLogins(id)
+
+	return data, nil
+}
+
+// EraseUser deletes the user with the given id and everything stored about the user and returns the count of deleted
+// entries per table of the sql storages.
+// return storage.ErrUserNotFound when user not found
+func (s *Storage) EraseUser(id string) (map[string]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.data.Users[id]; !ok {
+		return nil, storage.ErrUserNotFound
+	}
+
+	return s.eraseUser(id), nil
+}
+
+// DeleteUnverifiedUsers deletes all users without password (who never accepted their invitation) which have been
+// invited the last time before the given time together with everything stored about them and returns the count of
+// deleted users. Users without invitation time will be kept.
+func (s *Storage) DeleteUnverifiedUsers(invitedBefore time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for id, u := range s.data.Users {
+		if len(u.Password) == 0 && u.InvitedAt != nil && u.InvitedAt.Before(invitedBefore) {
+			s.eraseUser(id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// eraseUser deletes the user with the given id and everything stored about the user and returns the count of deleted
+// entries per table of the sql storages. mu has to be locked
+func (s *Storage) eraseUser(id string) map[string]int64 {
+	deletedRows := map[string]int64{
+		"tokens": s.deleteTokens(func(t *storage.Token) bool { return t.UserID == id }),
+	}
+
+	kept := s.data.PasswordHistory[:0]
+	for _, e := range s.data.PasswordHistory {
+		if e.UserID == id {
+			deletedRows["password_history"]++
+			continue
+		}
+		kept = append(kept, e)
+	}
+	s.data.PasswordHistory = kept
+
+	if _, ok := s.data.TOTPs[id]; ok {
+		delete(s.data.TOTPs, id)
+		deletedRows["user_totp"] = 1
+	} else {
+		deletedRows["user_totp"] = 0
+	}
+
+	deletedRows["webauthn_credentials"] = s.deleteWebAuthnCredentials(id)
+	deletedRows["mfa_recovery_codes"] = s.deleteRecoveryCodes(id)
+	deletedRows["logins"] = s.deleteLogins(func(l *storage.Login) bool { return l.UserID == id })
+
+	delete(s.data.Users, id)
+	deletedRows["users"] = 1
+
+	return deletedRows
+}
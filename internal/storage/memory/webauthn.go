@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"sort"
+	"time"
+)
+
+// WebAuthnCredentials finds all webauthn credentials of the user with the given id
+func (s *Storage) WebAuthnCredentials(userID string) ([]storage.WebAuthnCredential, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var credentials []storage.WebAuthnCredential
+	for _, c := range s.userWebAuthnCredentials(userID) {
+		credentials = append(credentials, storage.WebAuthnCredential{
+			ID:         copyBytes(c.ID),
+			UserID:     c.UserID,
+			PublicKey:  copyBytes(c.PublicKey),
+			SignCount:  c.SignCount,
+			CreatedAt:  c.CreatedAt,
+			LastUsedAt: copyTime(c.LastUsedAt),
+		})
+	}
+
+	return credentials, nil
+}
+
+// CreateWebAuthnCredential persists the given webauthn credential. UserID must match to a users id.
+// return storage.ErrWebAuthnCredentialAlreadyExists when a credential with the same id has already been registered
+func (s *Storage) CreateWebAuthnCredential(c storage.WebAuthnCredential) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.webAuthnCredential(c.ID) != nil {
+		return storage.ErrWebAuthnCredentialAlreadyExists
+	}
+
+	err := s.requireUser(c.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to create webauthn credential: %w", err)
+	}
+
+	c.ID = copyBytes(c.ID)
+	c.PublicKey = copyBytes(c.PublicKey)
+	c.LastUsedAt = nil
+	s.data.WebAuthnCredentials = append(s.data.WebAuthnCredentials, &c)
+
+	return nil
+}
+
+// UpdateWebAuthnCredentialUsage stores the new sign count and the last usage of the webauthn credential with the given
+// id
+// return storage.ErrWebAuthnCredentialNotFound when there is no credential with the given id
+func (s *Storage) UpdateWebAuthnCredentialUsage(id []byte, signCount uint32, lastUsedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.webAuthnCredential(id)
+	if c == nil {
+		return storage.ErrWebAuthnCredentialNotFound
+	}
+
+	c.SignCount = signCount
+	c.LastUsedAt = &lastUsedAt
+
+	return nil
+}
+
+// webAuthnCredential returns the stored webauthn credential with the given id or nil. mu has to be locked
+func (s *Storage) webAuthnCredential(id []byte) *storage.WebAuthnCredential {
+	for _, c := range s.data.WebAuthnCredentials {
+		if bytes.Equal(c.ID, id) {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// userWebAuthnCredentials returns the stored webauthn credentials of the user with the given id ordered by creation.
+// mu has to be locked
+func (s *Storage) userWebAuthnCredentials(userID string) []*storage.WebAuthnCredential {
+	var credentials []*storage.WebAuthnCredential
+	for _, c := range s.data.WebAuthnCredentials {
+		if c.UserID == userID {
+			credentials = append(credentials, c)
+		}
+	}
+
+	sort.SliceStable(credentials, func(i, j int) bool {
+		return credentials[i].CreatedAt.Before(credentials[j].CreatedAt)
+	})
+
+	return credentials
+}
@@ -0,0 +1,52 @@
+package mysql
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+)
+
+// claimsDocument is the claims column as json text. Claims are stored as blob which the json functions do not accept.
+// The binary collation keeps string comparisons case sensitive on mariadb which compares json as text.
+const claimsDocument = "CONVERT(claims USING utf8mb4) COLLATE utf8mb4_bin"
+
+// ClaimCondition evaluates the given predicate with the json functions of mysql. The claim will be compared with the
+// json encoded value of the predicate: JSON_CONTAINS is used for objects and arrays which should be contained,
+// everything else has to be equal. JSON_CONTAINS would find scalars in arrays, which the postgres storage does not.
+func (dialect) ClaimCondition(p storage.ClaimPredicate) (string, []interface{}, error) {
+	claim, err := json.Marshal(p.Claim)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to marshal claim: %w", err)
+	}
+	path := "$." + string(claim)
+
+	if p.Operator == storage.ClaimOperatorExists {
+		return "JSON_CONTAINS_PATH(" + claimsDocument + ", 'one', ?)", []interface{}{path}, nil
+	}
+
+	value, err := json.Marshal(p.Value)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	if p.Operator == storage.ClaimOperatorContains {
+		var jsonType string
+		switch p.Value.(type) {
+		case map[string]interface{}:
+			jsonType = "OBJECT"
+		case []interface{}:
+			jsonType = "ARRAY"
+		}
+
+		if jsonType != "" {
+			condition := fmt.Sprintf(
+				"JSON_TYPE(JSON_EXTRACT(%[1]s, ?)) = '%[2]s' AND JSON_CONTAINS(%[1]s, ?, ?)",
+				claimsDocument,
+				jsonType,
+			)
+			return condition, []interface{}{path, string(value), path}, nil
+		}
+	}
+
+	return "JSON_EXTRACT(" + claimsDocument + ", ?) = JSON_EXTRACT(?, '$')", []interface{}{path, string(value)}, nil
+}
@@ -0,0 +1,241 @@
+// Package mysql is the mysql / mariadb implementation of the storage. The statements are shared with the sqlite storage
+// (see package sqlstore), so it behaves like the postgres storage.
+package mysql
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	migratemysql "github.com/golang-migrate/migrate/v4/database/mysql"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage/sqlstore"
+	"strings"
+	"time"
+)
+
+// tlsConfigName is the name of the tls config registered at the mysql driver
+const tlsConfigName = "simple-jwt-provider"
+
+// maxNameLength is the max length of database and lock names
+const maxNameLength = 64
+
+// duplicateEntryErrorNumber is the number of the mysql error of unique and primary key violations
+const duplicateEntryErrorNumber = 1062
+
+// uniqueKeys are the names of the unique keys of the table columns of the shared statements. Primary keys are always
+// named 'PRIMARY'
+var uniqueKeys = map[string]string{
+	"users.email":                        "email_unique",
+	"users.username":                     "users_username_unique",
+	"users.phone":                        "users_phone_unique",
+	"realms.name":                        "PRIMARY",
+	"realm_hosts.host":                   "PRIMARY",
+	"webauthn_credentials.credential_id": "PRIMARY",
+}
+
+var mysqlWithInstance = migratemysql.WithInstance
+var sqlstoreMigrate = sqlstore.Migrate
+
+var sqlOpen = sql.Open
+
+type Storage struct {
+	*sqlstore.Storage
+	db *sql.DB
+	// cfg is the driver config of db
+	cfg *mysql.Config
+	// lockPrefix prefixes the names of all locks. It is the name of the default database, so locks are shared by all
+	// realms
+	lockPrefix string
+}
+
+// New opens a connection pool to the database of the given dsn e.g. 'user:password@tcp(localhost:3306)/db'. Times
+// will always be parsed in utc. tlsConfig will be used for all connections when it is not nil and overrides the tls
+// parameter of the dsn.
+func New(dsn string, tlsConfig *tls.Config) (*Storage, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse dsn: %w", err)
+	}
+	if cfg.DBName == "" {
+		return nil, errors.New("dsn must contain a database name")
+	}
+
+	if tlsConfig != nil {
+		err = mysql.RegisterTLSConfig(tlsConfigName, tlsConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to register tls config: %w", err)
+		}
+		cfg.TLSConfig = tlsConfigName
+	}
+
+	// rows affected has to count matched rows like postgres does, otherwise updates without changes would not find
+	// their rows
+	cfg.ParseTime = true
+	cfg.Loc = time.UTC
+	cfg.ClientFoundRows = true
+
+	return open(cfg, cfg.DBName)
+}
+
+func open(cfg *mysql.Config, lockPrefix string) (*Storage, error) {
+	db, err := sqlOpen("mysql", cfg.FormatDSN())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database connection: %w", err)
+	}
+
+	return &Storage{
+		Storage:    sqlstore.New(db, dialect{}),
+		db:         db,
+		cfg:        cfg,
+		lockPrefix: lockPrefix,
+	}, nil
+}
+
+// RealmStorage returns a new Storage for the realm with the given name. All of its data will be stored in its own
+// database on the same server named after the default database and the realm schema (see storage.RealmSchema) e.g.
+// 'provider_realm_my_realm' for 'provider'. The database will be created when it does not exist. It has to be
+// migrated before use.
+func (s *Storage) RealmStorage(name string) (*Storage, error) {
+	dbName := fmt.Sprintf("%s_%s", s.cfg.DBName, storage.RealmSchema(name))
+	if len(dbName) > maxNameLength {
+		return nil, fmt.Errorf("database name %q is longer than %d characters", dbName, maxNameLength)
+	}
+
+	_, err := s.db.Exec(fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s` CHARACTER SET utf8mb4 COLLATE utf8mb4_bin;", dbName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create realm database: %w", err)
+	}
+
+	cfg := *s.cfg
+	cfg.DBName = dbName
+
+	return open(&cfg, s.lockPrefix)
+}
+
+// Migrate executes all sql migration files from the given mysql db-migrations folder. Should always be called before
+// start. Migrations consist of multiple statements, so they will be executed via a separate connection pool which
+// allows multi statements. The connection pool of the storage does not allow them.
+func (s *Storage) Migrate(dbMigrationsPath string) error {
+	cfg := *s.cfg
+	cfg.MultiStatements = true
+
+	db, err := sqlOpen("mysql", cfg.FormatDSN())
+	if err != nil {
+		return fmt.Errorf("failed to open database connection for database schema migration: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	driver, err := mysqlWithInstance(db, &migratemysql.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to create driver for database schema migration: %w", err)
+	}
+
+	return sqlstoreMigrate(driver, "mysql", dbMigrationsPath)
+}
+
+// Close warps sql.DB.Close
+func (s *Storage) Close() error {
+	return s.db.Close()
+}
+
+// TryLock tries to acquire the named lock with the given name (see GET_LOCK) without waiting. Named locks belong to a
+// connection, so the lock holds a connection of the pool until it will be released. ok is false (without error) when
+// the lock is already held. Locks are shared by all instances using the same database and all of their realms.
+func (s *Storage) TryLock(name string) (unlock func() error, ok bool, err error) {
+	ctx := context.Background()
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get lock connection: %w", err)
+	}
+
+	lockName := lockName(s.lockPrefix, name)
+	var acquired sql.NullInt64
+	err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0);", lockName).Scan(&acquired)
+	if err != nil {
+		_ = conn.Close()
+		return nil, false, fmt.Errorf("failed to exec get-lock-stmt: %w", err)
+	}
+	if acquired.Int64 != 1 {
+		_ = conn.Close()
+		return nil, false, nil
+	}
+
+	return func() error {
+		defer func() { _ = conn.Close() }()
+
+		_, err := conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?);", lockName)
+		if err != nil {
+			return fmt.Errorf("failed to exec release-lock-stmt: %w", err)
+		}
+
+		return nil
+	}, true, nil
+}
+
+// lockName returns the name of the named lock with the given prefix and name. Names longer than allowed by mysql will
+// be replaced by their hash.
+func lockName(prefix, name string) string {
+	n := fmt.Sprintf("%s:%s", prefix, name)
+	if len(n) > maxNameLength {
+		return fmt.Sprintf("%x", sha256.Sum256([]byte(n)))
+	}
+
+	return n
+}
+
+// isDuplicateEntry returns true when the given error is a violation of the unique or primary key with the given name
+// of the given table e.g. 'users' and 'email_unique'. Primary keys are always named 'PRIMARY'
+func isDuplicateEntry(err error, table, key string) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) || mysqlErr.Number != duplicateEntryErrorNumber {
+		return false
+	}
+
+	// mysql >= 8.0.19 qualifies the key name with the table name
+	return strings.HasSuffix(mysqlErr.Message, fmt.Sprintf("'%s'", key)) ||
+		strings.HasSuffix(mysqlErr.Message, fmt.Sprintf("'%s.%s'", table, key))
+}
+
+// dialect is the sqlstore.Dialect of mysql
+type dialect struct{}
+
+// Timestamp returns the given time, the driver converts it to utc (see New)
+func (dialect) Timestamp(t time.Time) time.Time {
+	return t
+}
+
+// DaysElapsed adds the days as interval
+func (dialect) DaysElapsed(timestamp, days string) string {
+	return fmt.Sprintf("%s + INTERVAL (%s) DAY <= ?", timestamp, days)
+}
+
+// ForUpdate locks the selected rows
+func (dialect) ForUpdate() string {
+	return " FOR UPDATE"
+}
+
+// GroupConcat returns the GROUP_CONCAT aggregation
+func (dialect) GroupConcat(column string) string {
+	return fmt.Sprintf("GROUP_CONCAT(%s ORDER BY %s SEPARATOR ',')", column, column)
+}
+
+// Upsert returns an 'ON DUPLICATE KEY UPDATE' clause. The key column is implied by the violated key
+func (dialect) Upsert(key string, columns ...string) string {
+	updates := make([]string, len(columns))
+	for i, c := range columns {
+		updates[i] = fmt.Sprintf("%s = VALUES(%s)", c, c)
+	}
+
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", ")
+}
+
+// IsUniqueViolation returns true when the given error is a violation of the unique key of the given table column (see
+// uniqueKeys)
+func (dialect) IsUniqueViolation(err error, table, column string) bool {
+	key, ok := uniqueKeys[table+"."+column]
+	return ok && isDuplicateEntry(err, table, key)
+}
@@ -0,0 +1,390 @@
+package mysql
+
+import (
+	"crypto/tls"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/golang-migrate/migrate/v4/database"
+	migratemysql "github.com/golang-migrate/migrate/v4/database/mysql"
+	"github.com/google/uuid"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage/storagetest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	// database migration
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+)
+
+// testStorage returns a storage of the database configured by SJP_TEST_MYSQL_DSN e.g.
+// 'root:secret@tcp(localhost:3306)/provider'. The test will be skipped when no dsn has been configured.
+func testStorage(t *testing.T) *Storage {
+	dsn := os.Getenv("SJP_TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("SJP_TEST_MYSQL_DSN is not set")
+	}
+
+	s, err := New(dsn, nil)
+	if err != nil {
+		t.Fatalf("failed to create storage: %s", err)
+	}
+
+	return s
+}
+
+// TestStorage runs the behavioral tests of all storage backends. Each test uses its own realm database.
+func TestStorage(t *testing.T) {
+	s := testStorage(t)
+	defer func() { _ = s.Close() }()
+
+	storagetest.Run(t, func(t *testing.T) storagetest.Storage {
+		realmStorage, err := s.RealmStorage("storagetest-" + uuid.New().String()[:8])
+		if err != nil {
+			t.Fatalf("failed to create realm storage: %s", err)
+		}
+
+		err = realmStorage.Migrate("../../../db-migrations/mysql")
+		if err != nil {
+			t.Fatalf("failed to migrate realm storage: %s", err)
+		}
+
+		return realmStorage
+	})
+}
+
+func TestStorage_RealmStorage(t *testing.T) {
+	s := testStorage(t)
+	defer func() { _ = s.Close() }()
+
+	realmStorage, err := s.RealmStorage("my-realm")
+	if err != nil {
+		t.Fatalf("failed to create realm storage: %s", err)
+	}
+	defer func() { _ = realmStorage.Close() }()
+
+	err = realmStorage.Migrate("../../../db-migrations/mysql")
+	if err != nil {
+		t.Fatalf("failed to migrate realm storage: %s", err)
+	}
+
+	expectedDBName := s.cfg.DBName + "_realm_my_realm"
+	if realmStorage.cfg.DBName != expectedDBName {
+		t.Errorf("unexpected realm database. Expected: %q. Given: %q", expectedDBName, realmStorage.cfg.DBName)
+	}
+
+	_, err = realmStorage.User("info@leberkleber.io")
+	if err != storage.ErrUserNotFound {
+		t.Fatalf("unexpected error. Expected:\n%q\nGiven:\n%q", storage.ErrUserNotFound, err)
+	}
+
+	unlock, ok, err := s.TryLock("my-lock")
+	if err != nil || !ok {
+		t.Fatalf("failed to acquire lock. ok: %t, err: %v", ok, err)
+	}
+	defer func() { _ = unlock() }()
+
+	_, ok, err = realmStorage.TryLock("my-lock")
+	if err != nil || ok {
+		t.Fatalf("locks must be shared by all realms. ok: %t, err: %v", ok, err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name              string
+		givenDSN          string
+		givenTLSConfig    *tls.Config
+		expectedDSNConfig func(cfg *mysql.Config) bool
+		expectedError     error
+	}{
+		{
+			name:     "Happycase",
+			givenDSN: "user:secret@tcp(localhost:3306)/provider?parseTime=false&timeout=30s",
+			expectedDSNConfig: func(cfg *mysql.Config) bool {
+				return cfg.User == "user" && cfg.Passwd == "secret" && cfg.Addr == "localhost:3306" &&
+					cfg.DBName == "provider" && cfg.Timeout == 30*time.Second && cfg.ParseTime && cfg.Loc == time.UTC &&
+					cfg.ClientFoundRows && !cfg.MultiStatements && cfg.TLSConfig == ""
+			},
+		},
+		{
+			name:           "with tls config",
+			givenDSN:       "user:secret@tcp(localhost:3306)/provider?tls=false",
+			givenTLSConfig: &tls.Config{ServerName: "db.leberkleber.io"},
+			expectedDSNConfig: func(cfg *mysql.Config) bool {
+				return cfg.TLSConfig == tlsConfigName
+			},
+		},
+		{
+			name:          "invalid dsn",
+			givenDSN:      "user:secret@tcp(localhost:3306)",
+			expectedError: errors.New("failed to parse dsn: invalid DSN: missing the slash separating the database name"),
+		},
+		{
+			name:          "without database name",
+			givenDSN:      "user:secret@tcp(localhost:3306)/",
+			expectedError: errors.New("dsn must contain a database name"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldSQLOpen := sqlOpen
+			defer func() { sqlOpen = oldSQLOpen }()
+
+			var givenDSNConfig *mysql.Config
+			sqlOpen = func(driverName, dataSourceName string) (*sql.DB, error) {
+				if driverName != "mysql" {
+					t.Errorf("unexpected driver name. Expected: %q. Given: %q", "mysql", driverName)
+				}
+
+				var err error
+				givenDSNConfig, err = mysql.ParseDSN(dataSourceName)
+				if err != nil {
+					t.Fatalf("failed to parse dsn: %s", err)
+				}
+
+				return nil, nil
+			}
+
+			_, err := New(tt.givenDSN, tt.givenTLSConfig)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("unexpected error. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
+			}
+
+			if tt.expectedDSNConfig != nil && !tt.expectedDSNConfig(givenDSNConfig) {
+				t.Errorf("unexpected dsn config: %+v", givenDSNConfig)
+			}
+		})
+	}
+}
+
+func TestIsDuplicateEntry(t *testing.T) {
+	tests := []struct {
+		name           string
+		givenError     error
+		givenTable     string
+		givenKey       string
+		expectedResult bool
+	}{
+		{
+			name:           "mysql 5 / mariadb",
+			givenError:     &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'info@leberkleber.io' for key 'email_unique'"},
+			givenTable:     "users",
+			givenKey:       "email_unique",
+			expectedResult: true,
+		},
+		{
+			name:           "mysql 8",
+			givenError:     &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'info@leberkleber.io' for key 'users.email_unique'"},
+			givenTable:     "users",
+			givenKey:       "email_unique",
+			expectedResult: true,
+		},
+		{
+			name:           "wrapped",
+			givenError:     fmt.Errorf("failed: %w", &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'my-realm' for key 'PRIMARY'"}),
+			givenTable:     "realms",
+			givenKey:       "PRIMARY",
+			expectedResult: true,
+		},
+		{
+			name:           "other key",
+			givenError:     &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'leberkleber' for key 'users.users_username_unique'"},
+			givenTable:     "users",
+			givenKey:       "email_unique",
+			expectedResult: false,
+		},
+		{
+			name:           "other error number",
+			givenError:     &mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row: a foreign key constraint fails"},
+			givenTable:     "users",
+			givenKey:       "email_unique",
+			expectedResult: false,
+		},
+		{
+			name:           "other error",
+			givenError:     errors.New("Duplicate entry 'info@leberkleber.io' for key 'email_unique'"),
+			givenTable:     "users",
+			givenKey:       "email_unique",
+			expectedResult: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := isDuplicateEntry(tt.givenError, tt.givenTable, tt.givenKey)
+			if result != tt.expectedResult {
+				t.Errorf("unexpected result. Expected: %t. Given: %t", tt.expectedResult, result)
+			}
+		})
+	}
+}
+
+func TestLockName(t *testing.T) {
+	name := lockName("provider", "cleanup")
+	if name != "provider:cleanup" {
+		t.Errorf("unexpected lock name. Expected: %q. Given: %q", "provider:cleanup", name)
+	}
+
+	name = lockName("provider", strings.Repeat("a", 64))
+	if len(name) != maxNameLength || name != lockName("provider", strings.Repeat("a", 64)) {
+		t.Errorf("long lock names must be hashed to %d characters. Given: %q", maxNameLength, name)
+	}
+}
+
+func TestStorage_Migrate(t *testing.T) {
+	tests := []struct {
+		name                         string
+		sqlOpenError                 error
+		mysqlWithInstanceReturnError error
+		migrateError                 error
+		expectedMigrate              bool
+		expectedError                error
+	}{
+		{
+			name:            "Happycase",
+			expectedMigrate: true,
+		},
+		{
+			name:          "open connection error",
+			sqlOpenError:  errors.New("nope"),
+			expectedError: errors.New("failed to open database connection for database schema migration: nope"),
+		},
+		{
+			name:                         "mysql instance error",
+			mysqlWithInstanceReturnError: errors.New("nope"),
+			expectedError:                errors.New("failed to create driver for database schema migration: nope"),
+		},
+		{
+			name:            "migrate error",
+			migrateError:    errors.New("nope"),
+			expectedMigrate: true,
+			expectedError:   errors.New("nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldSQLOpen := sqlOpen
+			oldMysqlWithInstance := mysqlWithInstance
+			oldSqlstoreMigrate := sqlstoreMigrate
+			defer func() {
+				sqlOpen = oldSQLOpen
+				mysqlWithInstance = oldMysqlWithInstance
+				sqlstoreMigrate = oldSqlstoreMigrate
+			}()
+
+			var migrationDB *sql.DB
+			sqlOpen = func(driverName, dataSourceName string) (*sql.DB, error) {
+				cfg, err := mysql.ParseDSN(dataSourceName)
+				if err != nil {
+					t.Fatalf("failed to parse dsn: %s", err)
+				}
+				if !cfg.MultiStatements || cfg.DBName != "provider" {
+					t.Errorf("migration connection must allow multi statements: %+v", cfg)
+				}
+				if tt.sqlOpenError != nil {
+					return nil, tt.sqlOpenError
+				}
+
+				migrationDB, err = sql.Open(driverName, dataSourceName)
+				return migrationDB, err
+			}
+			mysqlWithInstance = func(instance *sql.DB, config *migratemysql.Config) (database.Driver, error) {
+				if instance != migrationDB {
+					t.Error("migration must use its own connection pool")
+				}
+				return nil, tt.mysqlWithInstanceReturnError
+			}
+			var migrated bool
+			sqlstoreMigrate = func(driver database.Driver, databaseName, dbMigrationsPath string) error {
+				migrated = true
+				if databaseName != "mysql" || dbMigrationsPath != "pathToDBMigrationFolder" {
+					t.Errorf("unexpected migration of database %q from %q", databaseName, dbMigrationsPath)
+				}
+				return tt.migrateError
+			}
+
+			s := Storage{cfg: &mysql.Config{User: "user", Net: "tcp", Addr: "localhost:3306", DBName: "provider"}}
+
+			err := s.Migrate("pathToDBMigrationFolder")
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("unexpected error. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
+			}
+
+			if migrated != tt.expectedMigrate {
+				t.Errorf("unexpected migration. Expected: %t. Given: %t", tt.expectedMigrate, migrated)
+			}
+		})
+	}
+}
+
+func TestDialect_ClaimCondition(t *testing.T) {
+	tests := []struct {
+		name              string
+		givenPredicate    storage.ClaimPredicate
+		expectedCondition string
+		expectedArgs      []interface{}
+	}{
+		{
+			name:              "equals scalar",
+			givenPredicate:    storage.ClaimPredicate{Claim: "role", Operator: storage.ClaimOperatorEquals, Value: "admin"},
+			expectedCondition: "JSON_EXTRACT(" + claimsDocument + ", ?) = JSON_EXTRACT(?, '$')",
+			expectedArgs:      []interface{}{`$."role"`, `"admin"`},
+		},
+		{
+			name:              "equals object",
+			givenPredicate:    storage.ClaimPredicate{Claim: "org", Operator: storage.ClaimOperatorEquals, Value: map[string]interface{}{"name": "acme"}},
+			expectedCondition: "JSON_EXTRACT(" + claimsDocument + ", ?) = JSON_EXTRACT(?, '$')",
+			expectedArgs:      []interface{}{`$."org"`, `{"name":"acme"}`},
+		},
+		{
+			name:           "contains array",
+			givenPredicate: storage.ClaimPredicate{Claim: "my groups", Operator: storage.ClaimOperatorContains, Value: []interface{}{"dev"}},
+			expectedCondition: "JSON_TYPE(JSON_EXTRACT(" + claimsDocument + ", ?)) = 'ARRAY' AND " +
+				"JSON_CONTAINS(" + claimsDocument + ", ?, ?)",
+			expectedArgs: []interface{}{`$."my groups"`, `["dev"]`, `$."my groups"`},
+		},
+		{
+			name:           "contains object",
+			givenPredicate: storage.ClaimPredicate{Claim: "org", Operator: storage.ClaimOperatorContains, Value: map[string]interface{}{"tier": float64(1)}},
+			expectedCondition: "JSON_TYPE(JSON_EXTRACT(" + claimsDocument + ", ?)) = 'OBJECT' AND " +
+				"JSON_CONTAINS(" + claimsDocument + ", ?, ?)",
+			expectedArgs: []interface{}{`$."org"`, `{"tier":1}`, `$."org"`},
+		},
+		{
+			name:              "contains scalar",
+			givenPredicate:    storage.ClaimPredicate{Claim: "groups", Operator: storage.ClaimOperatorContains, Value: "dev"},
+			expectedCondition: "JSON_EXTRACT(" + claimsDocument + ", ?) = JSON_EXTRACT(?, '$')",
+			expectedArgs:      []interface{}{`$."groups"`, `"dev"`},
+		},
+		{
+			name:              "exists",
+			givenPredicate:    storage.ClaimPredicate{Claim: `say "hi"`, Operator: storage.ClaimOperatorExists},
+			expectedCondition: "JSON_CONTAINS_PATH(" + claimsDocument + ", 'one', ?)",
+			expectedArgs:      []interface{}{`$."say \"hi\""`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition, args, err := dialect{}.ClaimCondition(tt.givenPredicate)
+			if err != nil {
+				t.Fatalf("failed to build condition: %s", err)
+			}
+
+			if condition != tt.expectedCondition {
+				t.Errorf("unexpected condition. Expected:\n%s\nGiven:\n%s", tt.expectedCondition, condition)
+			}
+
+			if !reflect.DeepEqual(args, tt.expectedArgs) {
+				t.Errorf("unexpected args. Expected:\n%#v\nGiven:\n%#v", tt.expectedArgs, args)
+			}
+		})
+	}
+}
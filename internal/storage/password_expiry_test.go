@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStorage_UsersToRemindOfPasswordExpiry(t *testing.T) {
+	now := time.Date(2020, 8, 1, 4, 46, 45, 2, time.UTC)
+	changedAt := time.Date(2020, 2, 1, 4, 46, 45, 2, time.UTC)
+
+	tests := []struct {
+		name           string
+		dbResponseErr  error
+		dbResponseRows *sqlmock.Rows
+		expectedUsers  []User
+		expectedErr    error
+	}{
+		{
+			name: "Happycase",
+			dbResponseRows: sqlmock.NewRows([]string{"id", "email", "claims", "password_changed_at", "password_max_age_days", "metadata"}).
+				AddRow("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", "info@leberkleber.io", `{"customClaim1": 4711}`, changedAt, 0, `{"plan": "pro"}`).
+				AddRow("4f0d5b0e-8e4a-4c36-9a57-3c4c2f4b8b8e", "test@leberkleber.io", `null`, changedAt, 30, nil),
+			expectedUsers: []User{
+				{
+					ID:                "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+					EMail:             "info@leberkleber.io",
+					Claims:            map[string]interface{}{"customClaim1": float64(4711)},
+					Metadata:          map[string]interface{}{"plan": "pro"},
+					PasswordChangedAt: changedAt,
+				},
+				{
+					ID:                 "4f0d5b0e-8e4a-4c36-9a57-3c4c2f4b8b8e",
+					EMail:              "test@leberkleber.io",
+					PasswordChangedAt:  changedAt,
+					PasswordMaxAgeDays: 30,
+				},
+			},
+		},
+		{
+			name:          "Error while exec stmt",
+			dbResponseErr: errors.New("nope"),
+			expectedErr:   errors.New("failed to exec select-users-to-remind-stmt: nope"),
+		},
+		{
+			name: "Unable to scan sql response",
+			dbResponseRows: sqlmock.NewRows([]string{"email"}).
+				AddRow("info@leberkleber.io"),
+			expectedErr: errors.New("failed to scan select-users-to-remind-stmt result: sql: expected 1 destination arguments in Scan, not 6"),
+		},
+		{
+			name: "Non json claims (should not be possible)",
+			dbResponseRows: sqlmock.NewRows([]string{"id", "email", "claims", "password_changed_at", "password_max_age_days", "metadata"}).
+				AddRow("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", "info@leberkleber.io", "customClaim1\n4711}", changedAt, 0, nil),
+			expectedErr: errors.New("failed to unmarshal user>claims: invalid character 'c' looking for beginning of value"),
+		},
+		{
+			name: "Error while reading sql response",
+			dbResponseRows: sqlmock.NewRows([]string{"id", "email", "claims", "password_changed_at", "password_max_age_days", "metadata"}).
+				AddRow("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", "info@leberkleber.io", `null`, changedAt, 0, nil).
+				RowError(0, errors.New("nope")),
+			expectedErr: errors.New("failed to read select-users-to-remind-stmt result: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			expectedQuery := mock.
+				ExpectQuery(`SELECT id, email, claims, password_changed_at, password_max_age_days, metadata FROM users WHERE email IS NOT NULL AND length\(password\) > 0 AND .+ AND \(password_expiry_reminded_at IS NULL OR password_expiry_reminded_at < password_changed_at\);`).
+				WithArgs(180, 14, now).
+				WillReturnError(tt.dbResponseErr)
+			if tt.dbResponseRows != nil {
+				expectedQuery.WillReturnRows(tt.dbResponseRows)
+			}
+
+			s := Storage{db: db}
+
+			users, err := s.UsersToRemindOfPasswordExpiry(180, 14, now)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedErr) {
+				t.Errorf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedErr, err)
+			}
+			if !reflect.DeepEqual(users, tt.expectedUsers) {
+				t.Errorf("Returned users are not as expected. Expected:\n%#v\nGiven:\n%#v", tt.expectedUsers, users)
+			}
+		})
+	}
+}
+
+func TestStorage_MarkPasswordExpiryReminded(t *testing.T) {
+	remindedAt := time.Date(2020, 8, 1, 4, 46, 45, 2, time.UTC)
+
+	tests := []struct {
+		name          string
+		dbResponseErr error
+		dbResult      driver.Result
+		expectedError error
+	}{
+		{
+			name:     "Happycase",
+			dbResult: sqlmock.NewResult(0, 1),
+		},
+		{
+			name:          "Unexpected db error",
+			dbResponseErr: errors.New("nope"),
+			expectedError: errors.New("failed to exec update stmt: nope"),
+		},
+		{
+			name:          "User not found",
+			dbResult:      sqlmock.NewResult(0, 0),
+			expectedError: ErrUserNotFound,
+		},
+		{
+			name:          "Unexpected result error",
+			dbResult:      sqlmock.NewErrorResult(errors.New("a random error")),
+			expectedError: errors.New("failed to get count of affected rows: a random error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			mock.
+				ExpectExec(`UPDATE users SET password_expiry_reminded_at = \$2 WHERE id = \$1;`).
+				WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", remindedAt).
+				WillReturnError(tt.dbResponseErr).
+				WillReturnResult(tt.dbResult)
+
+			s := Storage{db: db}
+
+			err = s.MarkPasswordExpiryReminded("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", remindedAt)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Errorf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// PasswordHistory finds the password hashes of the newest 'limit' history entries of the user with the given id.
+// The newest entry comes first.
+func (s Storage) PasswordHistory(userID string, limit int) ([][]byte, error) {
+	rows, err := s.db.Query(
+		"SELECT password FROM password_history WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2;",
+		userID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exec select-password-history-stmt: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var passwords [][]byte
+	for rows.Next() {
+		var password []byte
+		err := rows.Scan(&password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan select-password-history-stmt result: %w", err)
+		}
+
+		passwords = append(passwords, password)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read select-password-history-stmt result: %w", err)
+	}
+
+	return passwords, nil
+}
+
+// AddPasswordHistory persists the given password hash as newest history entry of the user with the given id and
+// removes all entries except the newest 'keep' ones in one transaction.
+func (s Storage) AddPasswordHistory(userID string, password []byte, createdAt time.Time, keep int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin password-history transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.Exec(
+		"INSERT INTO password_history (user_id, password, created_at) VALUES($1, $2, $3);",
+		userID, password, createdAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to exec insert password-history stmt: %w", err)
+	}
+
+	_, err = tx.Exec(
+		"DELETE FROM password_history WHERE user_id = $1 AND id NOT IN "+
+			"(SELECT id FROM password_history WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2);",
+		userID, keep,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to exec purge password-history stmt: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit password-history transaction: %w", err)
+	}
+
+	return nil
+}
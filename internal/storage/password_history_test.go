@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStorage_PasswordHistory(t *testing.T) {
+	tests := []struct {
+		name              string
+		givenUserID       string
+		givenLimit        int
+		dbResponseErr     error
+		dbResponseRows    *sqlmock.Rows
+		expectedPasswords [][]byte
+		expectedErr       error
+	}{
+		{
+			name:        "Happycase",
+			givenUserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			givenLimit:  5,
+			dbResponseRows: sqlmock.NewRows([]string{"password"}).
+				AddRow([]byte("bcryptedPassword2")).
+				AddRow([]byte("bcryptedPassword1")),
+			expectedPasswords: [][]byte{[]byte("bcryptedPassword2"), []byte("bcryptedPassword1")},
+		},
+		{
+			name:          "Error while exec stmt",
+			givenUserID:   "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			givenLimit:    5,
+			dbResponseErr: errors.New("nope"),
+			expectedErr:   errors.New("failed to exec select-password-history-stmt: nope"),
+		},
+		{
+			name:        "Unable to scan sql response",
+			givenUserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			givenLimit:  5,
+			dbResponseRows: sqlmock.NewRows([]string{"password", "created_at"}).
+				AddRow([]byte("bcryptedPassword2"), time.Now()),
+			expectedErr: errors.New("failed to scan select-password-history-stmt result: sql: expected 2 destination arguments in Scan, not 1"),
+		},
+		{
+			name:        "Error while reading sql response",
+			givenUserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			givenLimit:  5,
+			dbResponseRows: sqlmock.NewRows([]string{"password"}).
+				AddRow([]byte("bcryptedPassword2")).
+				AddRow([]byte("bcryptedPassword1")).
+				RowError(1, errors.New("nope")),
+			expectedErr: errors.New("failed to read select-password-history-stmt result: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			expectedQuery := mock.
+				ExpectQuery(`SELECT password FROM password_history WHERE user_id = \$1 ORDER BY created_at DESC, id DESC LIMIT \$2;`).
+				WithArgs(tt.givenUserID, tt.givenLimit).
+				WillReturnError(tt.dbResponseErr)
+			if tt.dbResponseRows != nil {
+				expectedQuery.WillReturnRows(tt.dbResponseRows)
+			}
+
+			s := Storage{db: db}
+
+			passwords, err := s.PasswordHistory(tt.givenUserID, tt.givenLimit)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedErr) {
+				t.Errorf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedErr, err)
+			}
+			if !reflect.DeepEqual(passwords, tt.expectedPasswords) {
+				t.Errorf("Returned passwords are not as expected. Expected:\n%#v\nGiven:\n%#v", tt.expectedPasswords, passwords)
+			}
+		})
+	}
+}
+
+func TestStorage_AddPasswordHistory(t *testing.T) {
+	createdAt := time.Date(2020, 2, 1, 4, 46, 45, 2, time.UTC)
+
+	tests := []struct {
+		name                 string
+		insertDBResponseErr  error
+		insertDBResult       driver.Result
+		purgeDBResponseErr   error
+		purgeDBResult        driver.Result
+		commitDBResponseErr  error
+		expectedError        error
+		expectedPurgeExecute bool
+	}{
+		{
+			name:                 "Happycase",
+			insertDBResult:       sqlmock.NewResult(1, 1),
+			purgeDBResult:        sqlmock.NewResult(0, 1),
+			expectedPurgeExecute: true,
+		},
+		{
+			name:                "Unexpected insert db error",
+			insertDBResponseErr: errors.New("nope"),
+			expectedError:       errors.New("failed to exec insert password-history stmt: nope"),
+		},
+		{
+			name:                 "Unexpected purge db error",
+			insertDBResult:       sqlmock.NewResult(1, 1),
+			purgeDBResponseErr:   errors.New("nope"),
+			expectedPurgeExecute: true,
+			expectedError:        errors.New("failed to exec purge password-history stmt: nope"),
+		},
+		{
+			name:                 "Unexpected commit error",
+			insertDBResult:       sqlmock.NewResult(1, 1),
+			purgeDBResult:        sqlmock.NewResult(0, 1),
+			commitDBResponseErr:  errors.New("nope"),
+			expectedPurgeExecute: true,
+			expectedError:        errors.New("failed to commit password-history transaction: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			mock.ExpectBegin()
+
+			mock.
+				ExpectExec(`INSERT INTO password_history \(user_id, password, created_at\) VALUES\(\$1, \$2, \$3\);`).
+				WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", []byte("bcryptedPassword"), createdAt).
+				WillReturnError(tt.insertDBResponseErr).
+				WillReturnResult(tt.insertDBResult)
+
+			if tt.expectedPurgeExecute {
+				mock.
+					ExpectExec(`DELETE FROM password_history WHERE user_id = \$1 AND id NOT IN \(SELECT id FROM password_history WHERE user_id = \$1 ORDER BY created_at DESC, id DESC LIMIT \$2\);`).
+					WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", 3).
+					WillReturnError(tt.purgeDBResponseErr).
+					WillReturnResult(tt.purgeDBResult)
+			}
+
+			if tt.expectedError == nil || tt.commitDBResponseErr != nil {
+				mock.ExpectCommit().WillReturnError(tt.commitDBResponseErr)
+			}
+
+			s := Storage{db: db}
+
+			err = s.AddPasswordHistory("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", []byte("bcryptedPassword"), createdAt, 3)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Errorf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
+			}
+		})
+	}
+}
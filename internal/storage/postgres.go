@@ -15,9 +15,13 @@ var migrateNewWithDatabaseInstance = func(sourceURL string, databaseName string,
 	return m, e
 }
 
+var sqlOpen = sql.Open
+
 type Storage struct {
 	db     *sql.DB
 	dbName string
+	// dataSourceName is the connection string of the default schema
+	dataSourceName string
 }
 
 //go:generate moq -out migration_moq_test.go . migration
@@ -32,17 +36,16 @@ func New(dbHost string, dbPort int, dbUsername, dbPassword, dbName string, sslMo
 		sslMode = "enable"
 	}
 
-	db, err := sql.Open(
-		"postgres",
-		fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s connect_timeout=30", dbHost, dbPort, dbUsername, dbPassword, dbName, sslMode),
-	)
+	dataSourceName := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s connect_timeout=30", dbHost, dbPort, dbUsername, dbPassword, dbName, sslMode)
+	db, err := sqlOpen("postgres", dataSourceName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database connection: %w", err)
 	}
 
 	return &Storage{
-		db:     db,
-		dbName: dbName,
+		db:             db,
+		dbName:         dbName,
+		dataSourceName: dataSourceName,
 	}, nil
 }
 
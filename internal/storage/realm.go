@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"strings"
+	"time"
+)
+
+// Realm is the representation of a realm for use in storage
+type Realm struct {
+	// Name identifies the realm. It consists of lower case letters, digits and '-'
+	Name string
+	// Hosts select the realm by host header. Each host belongs to one realm only
+	Hosts []string
+	// JWTPrivateKey is the pem encoded ECDSA private key the jwts of the realm will be signed with
+	JWTPrivateKey string
+	JWTIssuer     string
+	JWTAudience   string
+	CreatedAt     time.Time
+}
+
+var ErrRealmNotFound = errors.New("realm not found")
+var ErrRealmAlreadyExists = errors.New("realm already exists")
+var ErrRealmHostAlreadyUsed = errors.New("realm host is already used by another realm")
+
+// realmColumns are the selected columns of realms in the order scanRealm scans them
+const realmColumns = "realms.name, realms.jwt_private_key, realms.jwt_issuer, realms.jwt_audience, realms.created_at, " +
+	"array_remove(array_agg(realm_hosts.host ORDER BY realm_hosts.host), NULL)"
+
+// realmFrom is the from clause for realmColumns
+const realmFrom = "FROM realms LEFT JOIN realm_hosts ON realm_hosts.realm = realms.name"
+
+// RealmSchema returns the name of the database schema of the realm with the given name
+func RealmSchema(name string) string {
+	return "realm_" + strings.Replace(name, "-", "_", -1)
+}
+
+// RealmStorage returns a new Storage for the realm with the given name. All of its data will be stored in the realm
+// schema (see RealmSchema) which will be created when it does not exist. It has to be migrated before use.
+func (s *Storage) RealmStorage(name string) (*Storage, error) {
+	schema := RealmSchema(name)
+	_, err := s.db.Exec("CREATE SCHEMA IF NOT EXISTS " + pq.QuoteIdentifier(schema) + ";")
+	if err != nil {
+		return nil, fmt.Errorf("failed to exec create schema stmt: %w", err)
+	}
+
+	dataSourceName := fmt.Sprintf("%s search_path=%s", s.dataSourceName, schema)
+	db, err := sqlOpen("postgres", dataSourceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database connection: %w", err)
+	}
+
+	return &Storage{
+		db:             db,
+		dbName:         s.dbName,
+		dataSourceName: dataSourceName,
+	}, nil
+}
+
+// Realms returns all realms ordered by name
+func (s *Storage) Realms() ([]Realm, error) {
+	rows, err := s.db.Query("SELECT " + realmColumns + " " + realmFrom + " GROUP BY realms.name ORDER BY realms.name;")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query realms: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var realms []Realm
+	for rows.Next() {
+		r, err := scanRealm(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		realms = append(realms, r)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read realms: %w", err)
+	}
+
+	return realms, nil
+}
+
+// Realm finds the realm with the given name
+// return ErrRealmNotFound when realm not found
+func (s *Storage) Realm(name string) (Realm, error) {
+	r, err := scanRealm(s.db.QueryRow("SELECT "+realmColumns+" "+realmFrom+" WHERE realms.name = $1 GROUP BY realms.name;", name))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Realm{}, ErrRealmNotFound
+		}
+
+		return Realm{}, err
+	}
+
+	return r, nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRealm(row scanner) (Realm, error) {
+	var r Realm
+	err := row.Scan(&r.Name, &r.JWTPrivateKey, &r.JWTIssuer, &r.JWTAudience, &r.CreatedAt, pq.Array(&r.Hosts))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Realm{}, err
+		}
+
+		return Realm{}, fmt.Errorf("failed to scan realm: %w", err)
+	}
+
+	return r, nil
+}
+
+// CreateRealm persists the given realm with its hosts in one transaction
+// return ErrRealmAlreadyExists when a realm with the same name already exists
+// return ErrRealmHostAlreadyUsed when one of the hosts belongs to another realm
+func (s *Storage) CreateRealm(r Realm) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin create realm transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.Exec(
+		"INSERT INTO realms (name, jwt_private_key, jwt_issuer, jwt_audience, created_at) VALUES($1, $2, $3, $4, $5);",
+		r.Name, r.JWTPrivateKey, r.JWTIssuer, r.JWTAudience, r.CreatedAt,
+	)
+	if err != nil {
+		pqErr, ok := err.(*pq.Error)
+		if ok && pqErr.Constraint == "realms_pkey" {
+			return ErrRealmAlreadyExists
+		}
+		return fmt.Errorf("failed to exec insert realm stmt: %w", err)
+	}
+
+	err = insertRealmHosts(tx, r)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit create realm transaction: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateRealm updates all properties (excluding name and created at) of the given realm and replaces its hosts in one
+// transaction
+// return ErrRealmNotFound when realm not found
+// return ErrRealmHostAlreadyUsed when one of the hosts belongs to another realm
+func (s *Storage) UpdateRealm(r Realm) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin update realm transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	resp, err := tx.Exec(
+		"UPDATE realms SET jwt_private_key = $2, jwt_issuer = $3, jwt_audience = $4 WHERE name = $1;",
+		r.Name, r.JWTPrivateKey, r.JWTIssuer, r.JWTAudience,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to exec update realm stmt: %w", err)
+	}
+
+	ra, err := resp.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get count of affected rows: %w", err)
+	}
+	if ra == 0 {
+		return ErrRealmNotFound
+	}
+
+	_, err = tx.Exec("DELETE FROM realm_hosts WHERE realm = $1;", r.Name)
+	if err != nil {
+		return fmt.Errorf("failed to exec delete realm hosts stmt: %w", err)
+	}
+
+	err = insertRealmHosts(tx, r)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit update realm transaction: %w", err)
+	}
+
+	return nil
+}
+
+func insertRealmHosts(tx *sql.Tx, r Realm) error {
+	for _, host := range r.Hosts {
+		_, err := tx.Exec("INSERT INTO realm_hosts (host, realm) VALUES($1, $2);", host, r.Name)
+		if err != nil {
+			pqErr, ok := err.(*pq.Error)
+			if ok && pqErr.Constraint == "realm_hosts_pkey" {
+				return ErrRealmHostAlreadyUsed
+			}
+			return fmt.Errorf("failed to exec insert realm host stmt: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,435 @@
+package storage
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var realmColumnsPattern = regexp.QuoteMeta(realmColumns + " " + realmFrom)
+
+func TestStorage_RealmStorage(t *testing.T) {
+	tests := []struct {
+		name               string
+		dbResponseErr      error
+		openErr            error
+		expectOpen         bool
+		expectedError      error
+		expectedDataSource string
+	}{
+		{
+			name:               "Happycase",
+			expectOpen:         true,
+			expectedDataSource: "host=db search_path=realm_acme_corp",
+		},
+		{
+			name:          "Unexpected db error",
+			dbResponseErr: errors.New("nope"),
+			expectedError: errors.New("failed to exec create schema stmt: nope"),
+		},
+		{
+			name:          "Unexpected open error",
+			openErr:       errors.New("nope"),
+			expectOpen:    true,
+			expectedError: errors.New("failed to open database connection: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			oldSQLOpen := sqlOpen
+			defer func() { sqlOpen = oldSQLOpen }()
+			var opened bool
+			var givenDataSource string
+			sqlOpen = func(driverName, dataSourceName string) (*sql.DB, error) {
+				opened = true
+				givenDataSource = dataSourceName
+				return db, tt.openErr
+			}
+
+			mock.
+				ExpectExec(`CREATE SCHEMA IF NOT EXISTS "realm_acme_corp";`).
+				WillReturnError(tt.dbResponseErr).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+
+			s := Storage{db: db, dbName: "simple-jwt-provider", dataSourceName: "host=db"}
+
+			realmStorage, err := s.RealmStorage("acme-corp")
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
+			}
+
+			if opened != tt.expectOpen {
+				t.Errorf("Database connection open is not as expected. Expected: %t, Given: %t", tt.expectOpen, opened)
+			}
+
+			if tt.expectedError == nil && (givenDataSource != tt.expectedDataSource || realmStorage.dataSourceName != tt.expectedDataSource || realmStorage.dbName != "simple-jwt-provider") {
+				t.Errorf("Realm storage is not as expected. Given data source: %q", givenDataSource)
+			}
+		})
+	}
+}
+
+func TestStorage_Realms(t *testing.T) {
+	createdAt := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		dbResponseRows *sqlmock.Rows
+		dbResponseErr  error
+		expectedRealms []Realm
+		expectedError  error
+	}{
+		{
+			name: "Happycase",
+			dbResponseRows: sqlmock.NewRows([]string{"name", "jwt_private_key", "jwt_issuer", "jwt_audience", "created_at", "hosts"}).
+				AddRow("acme", "privateKey1", "acme-issuer", "acme-audience", createdAt, "{login.acme.example,acme.example}").
+				AddRow("globex", "privateKey2", "", "", createdAt, "{}"),
+			expectedRealms: []Realm{
+				{
+					Name:          "acme",
+					Hosts:         []string{"login.acme.example", "acme.example"},
+					JWTPrivateKey: "privateKey1",
+					JWTIssuer:     "acme-issuer",
+					JWTAudience:   "acme-audience",
+					CreatedAt:     createdAt,
+				}, {
+					Name:          "globex",
+					Hosts:         []string{},
+					JWTPrivateKey: "privateKey2",
+					CreatedAt:     createdAt,
+				},
+			},
+		},
+		{
+			name:          "Unexpected db error",
+			dbResponseErr: errors.New("nope"),
+			expectedError: errors.New("failed to query realms: nope"),
+		},
+		{
+			name: "Unexpected scan error",
+			dbResponseRows: sqlmock.NewRows([]string{"name", "jwt_private_key", "jwt_issuer", "jwt_audience", "created_at", "hosts"}).
+				AddRow("acme", "privateKey1", "", "", "no time", "{}"),
+			expectedError: errors.New("failed to scan realm: sql: Scan error on column index 4, name \"created_at\": unsupported Scan, storing driver.Value type string into type *time.Time"),
+		},
+		{
+			name: "Unexpected read error",
+			dbResponseRows: sqlmock.NewRows([]string{"name", "jwt_private_key", "jwt_issuer", "jwt_audience", "created_at", "hosts"}).
+				AddRow("acme", "privateKey1", "", "", createdAt, "{}").
+				RowError(0, errors.New("nope")),
+			expectedError: errors.New("failed to read realms: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			expectedQuery := mock.
+				ExpectQuery(`SELECT ` + realmColumnsPattern + ` GROUP BY realms.name ORDER BY realms.name;`).
+				WillReturnError(tt.dbResponseErr)
+			if tt.dbResponseRows != nil {
+				expectedQuery.WillReturnRows(tt.dbResponseRows)
+			}
+
+			s := Storage{db: db}
+
+			realms, err := s.Realms()
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(realms, tt.expectedRealms) {
+				t.Errorf("Returned realms are not as expected. Expected:\n%#v\nGiven:\n%#v", tt.expectedRealms, realms)
+			}
+		})
+	}
+}
+
+func TestStorage_Realm(t *testing.T) {
+	createdAt := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		dbResponseRows *sqlmock.Rows
+		dbResponseErr  error
+		expectedRealm  Realm
+		expectedError  error
+	}{
+		{
+			name: "Happycase",
+			dbResponseRows: sqlmock.NewRows([]string{"name", "jwt_private_key", "jwt_issuer", "jwt_audience", "created_at", "hosts"}).
+				AddRow("acme", "privateKey1", "acme-issuer", "acme-audience", createdAt, "{acme.example}"),
+			expectedRealm: Realm{
+				Name:          "acme",
+				Hosts:         []string{"acme.example"},
+				JWTPrivateKey: "privateKey1",
+				JWTIssuer:     "acme-issuer",
+				JWTAudience:   "acme-audience",
+				CreatedAt:     createdAt,
+			},
+		},
+		{
+			name:          "Realm not found",
+			dbResponseErr: sql.ErrNoRows,
+			expectedError: ErrRealmNotFound,
+		},
+		{
+			name:          "Unexpected db error",
+			dbResponseErr: errors.New("nope"),
+			expectedError: errors.New("failed to scan realm: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			expectedQuery := mock.
+				ExpectQuery(`SELECT ` + realmColumnsPattern + ` WHERE realms.name = \$1 GROUP BY realms.name;`).
+				WithArgs("acme").
+				WillReturnError(tt.dbResponseErr)
+			if tt.dbResponseRows != nil {
+				expectedQuery.WillReturnRows(tt.dbResponseRows)
+			}
+
+			s := Storage{db: db}
+
+			realm, err := s.Realm("acme")
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(realm, tt.expectedRealm) {
+				t.Errorf("Returned realm is not as expected. Expected:\n%#v\nGiven:\n%#v", tt.expectedRealm, realm)
+			}
+		})
+	}
+}
+
+func TestStorage_CreateRealm(t *testing.T) {
+	realm := Realm{
+		Name:          "acme",
+		Hosts:         []string{"acme.example", "login.acme.example"},
+		JWTPrivateKey: "privateKey",
+		JWTIssuer:     "acme-issuer",
+		JWTAudience:   "acme-audience",
+		CreatedAt:     time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name                string
+		realmDBResponseErr  error
+		hostDBResponseErr   error
+		commitDBResponseErr error
+		expectedHostExecs   int
+		expectCommit        bool
+		expectedError       error
+	}{
+		{
+			name:              "Happycase",
+			expectedHostExecs: 2,
+			expectCommit:      true,
+		},
+		{
+			name:               "Realm already exists",
+			realmDBResponseErr: &pq.Error{Constraint: "realms_pkey"},
+			expectedError:      ErrRealmAlreadyExists,
+		},
+		{
+			name:               "Unexpected realm db error",
+			realmDBResponseErr: errors.New("nope"),
+			expectedError:      errors.New("failed to exec insert realm stmt: nope"),
+		},
+		{
+			name:              "Host already used",
+			hostDBResponseErr: &pq.Error{Constraint: "realm_hosts_pkey"},
+			expectedHostExecs: 1,
+			expectedError:     ErrRealmHostAlreadyUsed,
+		},
+		{
+			name:              "Unexpected host db error",
+			hostDBResponseErr: errors.New("nope"),
+			expectedHostExecs: 1,
+			expectedError:     errors.New("failed to exec insert realm host stmt: nope"),
+		},
+		{
+			name:                "Unexpected commit error",
+			commitDBResponseErr: errors.New("nope"),
+			expectedHostExecs:   2,
+			expectCommit:        true,
+			expectedError:       errors.New("failed to commit create realm transaction: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			mock.ExpectBegin()
+
+			mock.
+				ExpectExec(`INSERT INTO realms \(name, jwt_private_key, jwt_issuer, jwt_audience, created_at\) VALUES\(\$1, \$2, \$3, \$4, \$5\);`).
+				WithArgs(realm.Name, realm.JWTPrivateKey, realm.JWTIssuer, realm.JWTAudience, realm.CreatedAt).
+				WillReturnError(tt.realmDBResponseErr).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			for i := 0; i < tt.expectedHostExecs; i++ {
+				mock.
+					ExpectExec(`INSERT INTO realm_hosts \(host, realm\) VALUES\(\$1, \$2\);`).
+					WithArgs(realm.Hosts[i], realm.Name).
+					WillReturnError(tt.hostDBResponseErr).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			if tt.expectCommit {
+				mock.ExpectCommit().WillReturnError(tt.commitDBResponseErr)
+			}
+
+			s := Storage{db: db}
+
+			err = s.CreateRealm(realm)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Errorf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled db expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestStorage_UpdateRealm(t *testing.T) {
+	realm := Realm{
+		Name:          "acme",
+		Hosts:         []string{"acme.example"},
+		JWTPrivateKey: "privateKey",
+		JWTIssuer:     "acme-issuer",
+		JWTAudience:   "acme-audience",
+	}
+
+	tests := []struct {
+		name                string
+		realmDBResponseErr  error
+		realmDBResult       driver.Result
+		deleteDBResponseErr error
+		hostDBResponseErr   error
+		commitDBResponseErr error
+		expectDelete        bool
+		expectHostExec      bool
+		expectCommit        bool
+		expectedError       error
+	}{
+		{
+			name:           "Happycase",
+			realmDBResult:  sqlmock.NewResult(0, 1),
+			expectDelete:   true,
+			expectHostExec: true,
+			expectCommit:   true,
+		},
+		{
+			name:          "Realm not found",
+			realmDBResult: sqlmock.NewResult(0, 0),
+			expectedError: ErrRealmNotFound,
+		},
+		{
+			name:               "Unexpected realm db error",
+			realmDBResponseErr: errors.New("nope"),
+			expectedError:      errors.New("failed to exec update realm stmt: nope"),
+		},
+		{
+			name:                "Unexpected delete hosts db error",
+			realmDBResult:       sqlmock.NewResult(0, 1),
+			deleteDBResponseErr: errors.New("nope"),
+			expectDelete:        true,
+			expectedError:       errors.New("failed to exec delete realm hosts stmt: nope"),
+		},
+		{
+			name:              "Host already used",
+			realmDBResult:     sqlmock.NewResult(0, 1),
+			hostDBResponseErr: &pq.Error{Constraint: "realm_hosts_pkey"},
+			expectDelete:      true,
+			expectHostExec:    true,
+			expectedError:     ErrRealmHostAlreadyUsed,
+		},
+		{
+			name:                "Unexpected commit error",
+			realmDBResult:       sqlmock.NewResult(0, 1),
+			commitDBResponseErr: errors.New("nope"),
+			expectDelete:        true,
+			expectHostExec:      true,
+			expectCommit:        true,
+			expectedError:       errors.New("failed to commit update realm transaction: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			mock.ExpectBegin()
+
+			mock.
+				ExpectExec(`UPDATE realms SET jwt_private_key = \$2, jwt_issuer = \$3, jwt_audience = \$4 WHERE name = \$1;`).
+				WithArgs(realm.Name, realm.JWTPrivateKey, realm.JWTIssuer, realm.JWTAudience).
+				WillReturnError(tt.realmDBResponseErr).
+				WillReturnResult(tt.realmDBResult)
+
+			if tt.expectDelete {
+				mock.
+					ExpectExec(`DELETE FROM realm_hosts WHERE realm = \$1;`).
+					WithArgs(realm.Name).
+					WillReturnError(tt.deleteDBResponseErr).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+			}
+
+			if tt.expectHostExec {
+				mock.
+					ExpectExec(`INSERT INTO realm_hosts \(host, realm\) VALUES\(\$1, \$2\);`).
+					WithArgs("acme.example", realm.Name).
+					WillReturnError(tt.hostDBResponseErr).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			if tt.expectCommit {
+				mock.ExpectCommit().WillReturnError(tt.commitDBResponseErr)
+			}
+
+			s := Storage{db: db}
+
+			err = s.UpdateRealm(realm)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Errorf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled db expectations: %s", err)
+			}
+		})
+	}
+}
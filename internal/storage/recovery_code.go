@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrRecoveryCodeNotFound = errors.New("could not found unused recovery code")
+
+// UnusedRecoveryCodeCount counts the not yet used mfa recovery codes of the user with the given id
+func (s Storage) UnusedRecoveryCodeCount(userID string) (int, error) {
+	var count int
+	err := s.db.QueryRow(
+		"SELECT count(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL;",
+		userID,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query recovery code count: %w", err)
+	}
+
+	return count, nil
+}
+
+// ReplaceRecoveryCodes replaces all mfa recovery codes of the user with the given id by the given code hashes in
+// one transaction. UserID must match to a users id.
+func (s Storage) ReplaceRecoveryCodes(userID string, codeHashes [][]byte, createdAt time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin recovery-codes transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.Exec("DELETE FROM mfa_recovery_codes WHERE user_id = $1;", userID)
+	if err != nil {
+		return fmt.Errorf("failed to exec delete recovery-codes stmt: %w", err)
+	}
+
+	for _, codeHash := range codeHashes {
+		_, err = tx.Exec(
+			"INSERT INTO mfa_recovery_codes (user_id, code_hash, created_at) VALUES($1, $2, $3);",
+			userID, codeHash, createdAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to exec insert recovery-code stmt: %w", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit recovery-codes transaction: %w", err)
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks the not yet used mfa recovery code with the given hash of the user with the given id as used
+// return ErrRecoveryCodeNotFound when there is no such unused recovery code
+func (s Storage) UseRecoveryCode(userID string, codeHash []byte, usedAt time.Time) error {
+	res, err := s.db.Exec(
+		"UPDATE mfa_recovery_codes SET used_at = $3 WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;",
+		userID, codeHash, usedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to exec use recovery-code stmt: %w", err)
+	}
+
+	ra, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get count of affected rows: %w", err)
+	}
+	if ra == 0 {
+		return ErrRecoveryCodeNotFound
+	}
+
+	return nil
+}
+
+// DeleteMFA deletes the totp enrolment, all webauthn credentials and all recovery codes of the user with the given id
+// in one transaction
+func (s Storage) DeleteMFA(userID string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin delete-mfa transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.Exec("DELETE FROM user_totp WHERE user_id = $1;", userID)
+	if err != nil {
+		return fmt.Errorf("failed to exec delete totp stmt: %w", err)
+	}
+
+	_, err = tx.Exec("DELETE FROM webauthn_credentials WHERE user_id = $1;", userID)
+	if err != nil {
+		return fmt.Errorf("failed to exec delete webauthn credentials stmt: %w", err)
+	}
+
+	_, err = tx.Exec("DELETE FROM mfa_recovery_codes WHERE user_id = $1;", userID)
+	if err != nil {
+		return fmt.Errorf("failed to exec delete recovery-codes stmt: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit delete-mfa transaction: %w", err)
+	}
+
+	return nil
+}
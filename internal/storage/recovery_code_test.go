@@ -0,0 +1,273 @@
+package storage
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"testing"
+	"time"
+)
+
+func TestStorage_UnusedRecoveryCodeCount(t *testing.T) {
+	tests := []struct {
+		name           string
+		dbResponseErr  error
+		dbResponseRows *sqlmock.Rows
+		expectedCount  int
+		expectedErr    error
+	}{
+		{
+			name:           "Happycase",
+			dbResponseRows: sqlmock.NewRows([]string{"count"}).AddRow(7),
+			expectedCount:  7,
+		},
+		{
+			name:          "Unexpected db error",
+			dbResponseErr: errors.New("nope"),
+			expectedErr:   errors.New("failed to query recovery code count: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			expectedQuery := mock.
+				ExpectQuery(`SELECT count\(\*\) FROM mfa_recovery_codes WHERE user_id = \$1 AND used_at IS NULL;`).
+				WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b").
+				WillReturnError(tt.dbResponseErr)
+			if tt.dbResponseRows != nil {
+				expectedQuery.WillReturnRows(tt.dbResponseRows)
+			}
+
+			s := Storage{db: db}
+
+			count, err := s.UnusedRecoveryCodeCount("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b")
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedErr) {
+				t.Errorf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedErr, err)
+			}
+			if count != tt.expectedCount {
+				t.Errorf("Returned count is not as expected. Expected: %d. Given: %d", tt.expectedCount, count)
+			}
+		})
+	}
+}
+
+func TestStorage_ReplaceRecoveryCodes(t *testing.T) {
+	createdAt := time.Date(2020, 2, 1, 4, 46, 45, 2, time.UTC)
+
+	tests := []struct {
+		name                string
+		deleteDBResponseErr error
+		insertDBResponseErr error
+		commitDBResponseErr error
+		expectedInsertExecs int
+		expectedError       error
+		expectCommit        bool
+	}{
+		{
+			name:                "Happycase",
+			expectedInsertExecs: 2,
+			expectCommit:        true,
+		},
+		{
+			name:                "Unexpected delete db error",
+			deleteDBResponseErr: errors.New("nope"),
+			expectedError:       errors.New("failed to exec delete recovery-codes stmt: nope"),
+		},
+		{
+			name:                "Unexpected insert db error",
+			insertDBResponseErr: errors.New("nope"),
+			expectedInsertExecs: 1,
+			expectedError:       errors.New("failed to exec insert recovery-code stmt: nope"),
+		},
+		{
+			name:                "Unexpected commit error",
+			commitDBResponseErr: errors.New("nope"),
+			expectedInsertExecs: 2,
+			expectCommit:        true,
+			expectedError:       errors.New("failed to commit recovery-codes transaction: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			mock.ExpectBegin()
+
+			mock.
+				ExpectExec(`DELETE FROM mfa_recovery_codes WHERE user_id = \$1;`).
+				WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b").
+				WillReturnError(tt.deleteDBResponseErr).
+				WillReturnResult(sqlmock.NewResult(0, 10))
+
+			hashes := [][]byte{[]byte("hash1"), []byte("hash2")}
+			for i := 0; i < tt.expectedInsertExecs; i++ {
+				mock.
+					ExpectExec(`INSERT INTO mfa_recovery_codes \(user_id, code_hash, created_at\) VALUES\(\$1, \$2, \$3\);`).
+					WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", hashes[i], createdAt).
+					WillReturnError(tt.insertDBResponseErr).
+					WillReturnResult(sqlmock.NewResult(int64(i), 1))
+			}
+
+			if tt.expectCommit {
+				mock.ExpectCommit().WillReturnError(tt.commitDBResponseErr)
+			}
+
+			s := Storage{db: db}
+
+			err = s.ReplaceRecoveryCodes("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", hashes, createdAt)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Errorf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled db expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestStorage_UseRecoveryCode(t *testing.T) {
+	usedAt := time.Date(2020, 2, 1, 4, 46, 45, 2, time.UTC)
+
+	tests := []struct {
+		name          string
+		dbResponseErr error
+		dbResult      driver.Result
+		expectedError error
+	}{
+		{
+			name:     "Happycase",
+			dbResult: sqlmock.NewResult(0, 1),
+		},
+		{
+			name:          "Recovery code not found",
+			dbResult:      sqlmock.NewResult(0, 0),
+			expectedError: ErrRecoveryCodeNotFound,
+		},
+		{
+			name:          "Unexpected db error",
+			dbResponseErr: errors.New("nope"),
+			expectedError: errors.New("failed to exec use recovery-code stmt: nope"),
+		},
+		{
+			name:          "Could not get count of affected rows",
+			dbResult:      sqlmock.NewErrorResult(errors.New("a random error")),
+			expectedError: errors.New("failed to get count of affected rows: a random error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			mock.
+				ExpectExec(`UPDATE mfa_recovery_codes SET used_at = \$3 WHERE user_id = \$1 AND code_hash = \$2 AND used_at IS NULL;`).
+				WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", []byte("hash"), usedAt).
+				WillReturnError(tt.dbResponseErr).
+				WillReturnResult(tt.dbResult)
+
+			s := Storage{db: db}
+
+			err = s.UseRecoveryCode("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", []byte("hash"), usedAt)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Errorf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestStorage_DeleteMFA(t *testing.T) {
+	tests := []struct {
+		name                  string
+		totpDBResponseErr     error
+		webAuthnDBResponseErr error
+		recoveryDBResponseErr error
+		commitDBResponseErr   error
+		expectedExecs         int
+		expectedError         error
+	}{
+		{
+			name:          "Happycase",
+			expectedExecs: 3,
+		},
+		{
+			name:              "Unexpected totp db error",
+			totpDBResponseErr: errors.New("nope"),
+			expectedExecs:     1,
+			expectedError:     errors.New("failed to exec delete totp stmt: nope"),
+		},
+		{
+			name:                  "Unexpected webauthn db error",
+			webAuthnDBResponseErr: errors.New("nope"),
+			expectedExecs:         2,
+			expectedError:         errors.New("failed to exec delete webauthn credentials stmt: nope"),
+		},
+		{
+			name:                  "Unexpected recovery-codes db error",
+			recoveryDBResponseErr: errors.New("nope"),
+			expectedExecs:         3,
+			expectedError:         errors.New("failed to exec delete recovery-codes stmt: nope"),
+		},
+		{
+			name:                "Unexpected commit error",
+			commitDBResponseErr: errors.New("nope"),
+			expectedExecs:       3,
+			expectedError:       errors.New("failed to commit delete-mfa transaction: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			mock.ExpectBegin()
+
+			execs := []struct {
+				query string
+				err   error
+			}{
+				{query: `DELETE FROM user_totp WHERE user_id = \$1;`, err: tt.totpDBResponseErr},
+				{query: `DELETE FROM webauthn_credentials WHERE user_id = \$1;`, err: tt.webAuthnDBResponseErr},
+				{query: `DELETE FROM mfa_recovery_codes WHERE user_id = \$1;`, err: tt.recoveryDBResponseErr},
+			}
+			for _, e := range execs[:tt.expectedExecs] {
+				mock.
+					ExpectExec(e.query).
+					WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b").
+					WillReturnError(e.err).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			if tt.expectedError == nil || tt.commitDBResponseErr != nil {
+				mock.ExpectCommit().WillReturnError(tt.commitDBResponseErr)
+			}
+
+			s := Storage{db: db}
+
+			err = s.DeleteMFA("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b")
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Errorf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled db expectations: %s", err)
+			}
+		})
+	}
+}
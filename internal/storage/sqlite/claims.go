@@ -0,0 +1,190 @@
+package sqlite
+
+import (
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"sort"
+	"strings"
+)
+
+// claimsDocument is the claims column as json text. Claims are stored as blob which the json functions do not accept.
+const claimsDocument = "CAST(claims AS TEXT)"
+
+// ClaimCondition evaluates the given predicate with the json functions of sqlite (json_type, json_extract, json_each).
+// Sqlite has no json equality or containment, so the condition will be built along the value: objects and arrays will
+// be compared property by property and element by element and each element of a contained array will be looked up in
+// the elements of the claim.
+func (dialect) ClaimCondition(p storage.ClaimPredicate) (string, []interface{}, error) {
+	root, err := jsonPath{sql: "?", args: []interface{}{"$"}}.key(p.Claim)
+	if err != nil {
+		return "", nil, err
+	}
+
+	c := &jsonCondition{}
+	var condition string
+	switch p.Operator {
+	case storage.ClaimOperatorEquals:
+		condition, err = c.equals(root, p.Value)
+	case storage.ClaimOperatorContains:
+		condition, err = c.contains(root, p.Value)
+	default:
+		condition = c.call("json_type", root) + " IS NOT NULL"
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	return condition, c.args, nil
+}
+
+// jsonPath is a sql expression of a json path and its arguments
+type jsonPath struct {
+	sql  string
+	args []interface{}
+}
+
+// key returns the path of the property with the given name. Sqlite can not escape '"' in json paths
+func (p jsonPath) key(name string) (jsonPath, error) {
+	if strings.Contains(name, `"`) {
+		return jsonPath{}, fmt.Errorf("property %q can not be queried", name)
+	}
+
+	return p.append(fmt.Sprintf(`."%s"`, name)), nil
+}
+
+// index returns the path of the array element with the given index
+func (p jsonPath) index(i int) jsonPath {
+	return p.append(fmt.Sprintf("[%d]", i))
+}
+
+func (p jsonPath) append(segment string) jsonPath {
+	args := append([]interface{}{}, p.args...)
+	return jsonPath{sql: p.sql + " || ?", args: append(args, segment)}
+}
+
+// jsonCondition collects the arguments of a condition in the order of their placeholders, so all parts of the
+// condition have to be built in the order they appear in the condition
+type jsonCondition struct {
+	args []interface{}
+	// aliases is the count of json_each aliases
+	aliases int
+}
+
+// call returns the call of the given json function on the claims document at the given path
+func (c *jsonCondition) call(function string, path jsonPath) string {
+	c.args = append(c.args, path.args...)
+	return fmt.Sprintf("%s(%s, %s)", function, claimsDocument, path.sql)
+}
+
+func (c *jsonCondition) arg(v interface{}) string {
+	c.args = append(c.args, v)
+	return "?"
+}
+
+// equals returns the condition of the json value at the given path being equal to the given json decoded value
+func (c *jsonCondition) equals(path jsonPath, v interface{}) (string, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		conditions := []string{
+			c.call("json_type", path) + " = 'object'",
+			"(SELECT COUNT(*) FROM " + c.call("json_each", path) + ") = " + c.arg(len(v)),
+		}
+		for _, name := range sortedKeys(v) {
+			propertyPath, err := path.key(name)
+			if err != nil {
+				return "", err
+			}
+
+			condition, err := c.equals(propertyPath, v[name])
+			if err != nil {
+				return "", err
+			}
+			conditions = append(conditions, condition)
+		}
+
+		return strings.Join(conditions, " AND "), nil
+	case []interface{}:
+		conditions := []string{
+			c.call("json_type", path) + " = 'array'",
+			c.call("json_array_length", path) + " = " + c.arg(len(v)),
+		}
+		for i, element := range v {
+			condition, err := c.equals(path.index(i), element)
+			if err != nil {
+				return "", err
+			}
+			conditions = append(conditions, condition)
+		}
+
+		return strings.Join(conditions, " AND "), nil
+	default:
+		return c.scalarEquals(path, v)
+	}
+}
+
+// contains returns the condition of the json value at the given path containing the given json decoded value like the
+// jsonb operator @> does for values which are not at top level (see storage.ClaimOperatorContains)
+func (c *jsonCondition) contains(path jsonPath, v interface{}) (string, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		conditions := []string{c.call("json_type", path) + " = 'object'"}
+		for _, name := range sortedKeys(v) {
+			propertyPath, err := path.key(name)
+			if err != nil {
+				return "", err
+			}
+
+			condition, err := c.contains(propertyPath, v[name])
+			if err != nil {
+				return "", err
+			}
+			conditions = append(conditions, condition)
+		}
+
+		return strings.Join(conditions, " AND "), nil
+	case []interface{}:
+		conditions := []string{c.call("json_type", path) + " = 'array'"}
+		for _, element := range v {
+			alias := fmt.Sprintf("element%d", c.aliases)
+			c.aliases++
+
+			elements := c.call("json_each", path) + " AS " + alias
+			condition, err := c.contains(jsonPath{sql: alias + ".fullkey"}, element)
+			if err != nil {
+				return "", err
+			}
+			conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM %s WHERE %s)", elements, condition))
+		}
+
+		return strings.Join(conditions, " AND "), nil
+	default:
+		return c.scalarEquals(path, v)
+	}
+}
+
+// scalarEquals returns the condition of the json value at the given path being equal to the given scalar. json_extract
+// returns sql values for scalars, so the json type has to be compared too (e.g. true would equal 1)
+func (c *jsonCondition) scalarEquals(path jsonPath, v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return c.call("json_type", path) + " = 'null'", nil
+	case bool:
+		return c.call("json_type", path) + " = " + c.arg(fmt.Sprint(v)), nil
+	case string:
+		return c.call("json_type", path) + " = 'text' AND " + c.call("json_extract", path) + " = " + c.arg(v), nil
+	case float64:
+		return c.call("json_type", path) + " IN ('integer', 'real') AND " + c.call("json_extract", path) + " = " + c.arg(v), nil
+	default:
+		return "", fmt.Errorf("unsupported json value of type %T", v)
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
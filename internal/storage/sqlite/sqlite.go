@@ -0,0 +1,152 @@
+// Package sqlite is the sqlite implementation of the storage. The statements are shared with the mysql storage (see
+// package sqlstore), so it behaves like the postgres storage and can be used for small setups without a database
+// server.
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage/sqlstore"
+	sqlite "github.com/mattn/go-sqlite3"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var sqlite3WithInstance = sqlite3.WithInstance
+var sqlstoreMigrate = sqlstore.Migrate
+
+var sqlOpen = sql.Open
+
+type Storage struct {
+	*sqlstore.Storage
+	db *sql.DB
+	// path is the path of the database file
+	path string
+	// locks are the names of all held locks. They are shared by all realms of the database file
+	locks *locks
+}
+
+// locks are process wide locks
+type locks struct {
+	mu    sync.Mutex
+	names map[string]bool
+}
+
+// New opens the sqlite database file with the given path which will be created when it does not exist. All
+// transactions lock the database for writing on begin, so concurrent transactions will be serialized.
+func New(path string) (*Storage, error) {
+	return open(path, &locks{names: map[string]bool{}})
+}
+
+func open(path string, l *locks) (*Storage, error) {
+	db, err := sqlOpen("sqlite3", fmt.Sprintf("file:%s?_foreign_keys=on&_journal_mode=WAL&_busy_timeout=30000&_txlock=immediate", path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database connection: %w", err)
+	}
+
+	return &Storage{
+		Storage: sqlstore.New(db, dialect{}),
+		db:      db,
+		path:    path,
+		locks:   l,
+	}, nil
+}
+
+// RealmStorage returns a new Storage for the realm with the given name. All of its data will be stored in its own
+// database file next to the one of s named after the realm schema (see storage.RealmSchema) e.g.
+// 'provider.realm_my_realm.db' for 'provider.db'. It has to be migrated before use.
+func (s *Storage) RealmStorage(name string) (*Storage, error) {
+	ext := filepath.Ext(s.path)
+	return open(fmt.Sprintf("%s.%s%s", strings.TrimSuffix(s.path, ext), storage.RealmSchema(name), ext), s.locks)
+}
+
+// Migrate executes all sql migration files from the given sqlite db-migrations folder. Should always be called before
+// start
+func (s *Storage) Migrate(dbMigrationsPath string) error {
+	driver, err := sqlite3WithInstance(s.db, &sqlite3.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to create driver for database schema migration: %w", err)
+	}
+
+	return sqlstoreMigrate(driver, "sqlite3", dbMigrationsPath)
+}
+
+// Close warps sql.DB.Close
+func (s *Storage) Close() error {
+	return s.db.Close()
+}
+
+// TryLock tries to acquire the lock with the given name. The lock is held by this process only, since a sqlite
+// database is not meant to be shared by multiple instances of the provider. ok is false (without error) when the lock
+// is already held. Locks are shared by all realms.
+func (s *Storage) TryLock(name string) (unlock func() error, ok bool, err error) {
+	s.locks.mu.Lock()
+	defer s.locks.mu.Unlock()
+
+	if s.locks.names[name] {
+		return nil, false, nil
+	}
+	s.locks.names[name] = true
+
+	return func() error {
+		s.locks.mu.Lock()
+		defer s.locks.mu.Unlock()
+
+		delete(s.locks.names, name)
+		return nil
+	}, true, nil
+}
+
+// dialect is the sqlstore.Dialect of sqlite
+type dialect struct{}
+
+// Timestamp converts the given time to utc. Sqlite stores timestamps as text, so they have to be in the same time
+// zone to be comparable.
+func (dialect) Timestamp(t time.Time) time.Time {
+	return t.UTC()
+}
+
+// DaysElapsed compares julian days, sqlite has no interval arithmetic
+func (dialect) DaysElapsed(timestamp, days string) string {
+	return fmt.Sprintf("julianday(%s) + (%s) <= julianday(?)", timestamp, days)
+}
+
+// ForUpdate returns no suffix, all transactions lock the database for writing on begin
+func (dialect) ForUpdate() string {
+	return ""
+}
+
+// GroupConcat returns the group_concat aggregation
+func (dialect) GroupConcat(column string) string {
+	return fmt.Sprintf("group_concat(%s, ',')", column)
+}
+
+// Upsert returns an 'ON CONFLICT' clause
+func (dialect) Upsert(key string, columns ...string) string {
+	updates := make([]string, len(columns))
+	for i, c := range columns {
+		updates[i] = fmt.Sprintf("%s = excluded.%s", c, c)
+	}
+
+	return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", key, strings.Join(updates, ", "))
+}
+
+// IsUniqueViolation returns true when the given error is a violation of the unique or primary key constraint of the
+// given table column. Sqlite reports violations with the qualified column e.g. 'users.email'
+func (dialect) IsUniqueViolation(err error, table, column string) bool {
+	var sqliteErr sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+
+	if sqliteErr.ExtendedCode != sqlite.ErrConstraintUnique && sqliteErr.ExtendedCode != sqlite.ErrConstraintPrimaryKey {
+		return false
+	}
+
+	return strings.HasSuffix(sqliteErr.Error(), fmt.Sprintf(" %s.%s", table, column))
+}
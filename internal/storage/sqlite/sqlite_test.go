@@ -0,0 +1,148 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage/storagetest"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	// database migration
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+)
+
+func TestStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqlite")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	var count int
+	storagetest.Run(t, func(t *testing.T) storagetest.Storage {
+		count++
+		s, err := New(filepath.Join(dir, fmt.Sprintf("provider-%d.db", count)))
+		if err != nil {
+			t.Fatalf("failed to create storage: %s", err)
+		}
+
+		err = s.Migrate("../../../db-migrations/sqlite")
+		if err != nil {
+			t.Fatalf("failed to migrate storage: %s", err)
+		}
+
+		return s
+	})
+}
+
+func TestStorage_RealmStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqlite")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	s, err := New(filepath.Join(dir, "provider.db"))
+	if err != nil {
+		t.Fatalf("failed to create storage: %s", err)
+	}
+	defer func() { _ = s.Close() }()
+
+	realmStorage, err := s.RealmStorage("my-realm")
+	if err != nil {
+		t.Fatalf("failed to create realm storage: %s", err)
+	}
+	defer func() { _ = realmStorage.Close() }()
+
+	err = realmStorage.Migrate("../../../db-migrations/sqlite")
+	if err != nil {
+		t.Fatalf("failed to migrate realm storage: %s", err)
+	}
+
+	_, err = os.Stat(filepath.Join(dir, "provider.realm_my_realm.db"))
+	if err != nil {
+		t.Fatalf("realm database file has not been created: %s", err)
+	}
+
+	_, err = realmStorage.User("info@leberkleber.io")
+	if err != storage.ErrUserNotFound {
+		t.Fatalf("unexpected error. Expected:\n%q\nGiven:\n%q", storage.ErrUserNotFound, err)
+	}
+
+	unlock, ok, err := s.TryLock("my-lock")
+	if err != nil || !ok {
+		t.Fatalf("failed to acquire lock. ok: %t, err: %v", ok, err)
+	}
+	defer func() { _ = unlock() }()
+
+	_, ok, err = realmStorage.TryLock("my-lock")
+	if err != nil || ok {
+		t.Fatalf("locks must be shared by all realms. ok: %t, err: %v", ok, err)
+	}
+}
+
+func TestStorage_Migrate(t *testing.T) {
+	tests := []struct {
+		name                           string
+		sqlite3WithInstanceReturnError error
+		migrateError                   error
+		expectedMigrate                bool
+		expectedError                  error
+	}{
+		{
+			name:            "Happycase",
+			expectedMigrate: true,
+		},
+		{
+			name:                           "sqlite3 instance error",
+			sqlite3WithInstanceReturnError: errors.New("nope"),
+			expectedError:                  errors.New("failed to create driver for database schema migration: nope"),
+		},
+		{
+			name:            "migrate error",
+			migrateError:    errors.New("nope"),
+			expectedMigrate: true,
+			expectedError:   errors.New("nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldSqlite3WithInstance := sqlite3WithInstance
+			oldSqlstoreMigrate := sqlstoreMigrate
+			defer func() {
+				sqlite3WithInstance = oldSqlite3WithInstance
+				sqlstoreMigrate = oldSqlstoreMigrate
+			}()
+
+			sqlite3WithInstance = func(instance *sql.DB, config *sqlite3.Config) (database.Driver, error) {
+				return nil, tt.sqlite3WithInstanceReturnError
+			}
+			var migrated bool
+			sqlstoreMigrate = func(driver database.Driver, databaseName, dbMigrationsPath string) error {
+				migrated = true
+				if databaseName != "sqlite3" || dbMigrationsPath != "pathToDBMigrationFolder" {
+					t.Errorf("unexpected migration of database %q from %q", databaseName, dbMigrationsPath)
+				}
+				return tt.migrateError
+			}
+
+			s := Storage{}
+
+			err := s.Migrate("pathToDBMigrationFolder")
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("unexpected error. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
+			}
+
+			if migrated != tt.expectedMigrate {
+				t.Errorf("unexpected migration. Expected: %t. Given: %t", tt.expectedMigrate, migrated)
+			}
+		})
+	}
+}
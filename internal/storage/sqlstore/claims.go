@@ -0,0 +1,75 @@
+package sqlstore
+
+import (
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"strings"
+)
+
+// UsersByClaims finds 'limit' users ordered by id starting at 'offset' whose claims fulfill all given predicates and
+// the total count of these users. All users will be found when no predicate has been given. The predicates will be
+// evaluated by the database (see Dialect.ClaimCondition).
+func (s *Storage) UsersByClaims(predicates []storage.ClaimPredicate, limit, offset int) ([]storage.User, int, error) {
+	condition, args, err := s.claimPredicatesCondition(predicates)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	err = s.db.QueryRow("SELECT COUNT(*) FROM users WHERE "+condition+";", args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to exec count-users-stmt: %w", err)
+	}
+
+	rows, err := s.db.Query(
+		"SELECT "+userColumns+" FROM users WHERE "+condition+" ORDER BY id LIMIT ? OFFSET ?;",
+		append(args, limit, offset)...,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to exec select-users-stmt: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var users []storage.User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		users = append(users, u)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read select-users-stmt result: %w", err)
+	}
+
+	return users, total, nil
+}
+
+// claimPredicatesCondition returns the sql condition of all given predicates combined with AND and its arguments
+func (s *Storage) claimPredicatesCondition(predicates []storage.ClaimPredicate) (string, []interface{}, error) {
+	if len(predicates) == 0 {
+		return "TRUE", nil, nil
+	}
+
+	var conditions []string
+	var args []interface{}
+	for _, p := range predicates {
+		err := p.Validate()
+		if err != nil {
+			return "", nil, err
+		}
+
+		condition, conditionArgs, err := s.dialect.ClaimCondition(p)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to build condition of claim %q: %w", p.Claim, err)
+		}
+
+		conditions = append(conditions, "("+condition+")")
+		args = append(args, conditionArgs...)
+	}
+
+	return strings.Join(conditions, " AND "), args, nil
+}
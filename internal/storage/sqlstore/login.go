@@ -0,0 +1,93 @@
+package sqlstore
+
+import (
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"time"
+)
+
+// AddLogin persists the given login attempt and sets the last login of the user when the attempt succeeded in one
+// transaction.
+func (s *Storage) AddLogin(l storage.Login) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin login transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.Exec(
+		"INSERT INTO logins (user_id, created_at, ip, user_agent, outcome, factor) VALUES(?, ?, ?, ?, ?, ?);",
+		l.UserID, s.dialect.Timestamp(l.CreatedAt), l.IP, l.UserAgent, l.Outcome, l.Factor,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to exec insert login stmt: %w", err)
+	}
+
+	if l.Outcome == storage.LoginOutcomeSuccess {
+		_, err = tx.Exec("UPDATE users SET last_login_at = ? WHERE id = ?;", s.dialect.Timestamp(l.CreatedAt), l.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to exec update last login stmt: %w", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit login transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Logins finds 'limit' login attempts of the user with the given id starting at 'offset' and the total count of login
+// attempts of the user. The newest attempt comes first.
+func (s *Storage) Logins(userID string, limit, offset int) ([]storage.Login, int, error) {
+	var total int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM logins WHERE user_id = ?;", userID).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to exec count-logins-stmt: %w", err)
+	}
+
+	rows, err := s.db.Query(
+		"SELECT id, user_id, created_at, ip, user_agent, outcome, factor FROM logins WHERE user_id = ? "+
+			"ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?;",
+		userID, limit, offset,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to exec select-logins-stmt: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var logins []storage.Login
+	for rows.Next() {
+		var l storage.Login
+		err := rows.Scan(&l.ID, &l.UserID, &l.CreatedAt, &l.IP, &l.UserAgent, &l.Outcome, &l.Factor)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan select-logins-stmt result: %w", err)
+		}
+
+		logins = append(logins, l)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read select-logins-stmt result: %w", err)
+	}
+
+	return logins, total, nil
+}
+
+// DeleteLoginsCreatedBefore deletes the login attempts of all users which have been created before the given time and
+// returns the count of deleted attempts.
+func (s *Storage) DeleteLoginsCreatedBefore(createdBefore time.Time) (int64, error) {
+	res, err := s.db.Exec("DELETE FROM logins WHERE created_at < ?;", s.dialect.Timestamp(createdBefore))
+	if err != nil {
+		return 0, fmt.Errorf("failed to exec purge logins stmt: %w", err)
+	}
+
+	i, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get count of affected rows: %w", err)
+	}
+
+	return i, nil
+}
@@ -0,0 +1,68 @@
+package sqlstore
+
+import (
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"time"
+)
+
+// UsersToRemindOfPasswordExpiry finds all users whose password expires within the next 'reminderDays' days (at 'now')
+// and who have not been reminded since their last password change. 'defaultMaxAgeDays' will be used for all users
+// without a user specific password max age. Users without email can not be reminded and will be skipped like users
+// without password (pending invitations) whose password can not expire.
+func (s *Storage) UsersToRemindOfPasswordExpiry(defaultMaxAgeDays, reminderDays int, now time.Time) ([]storage.User, error) {
+	rows, err := s.db.Query(
+		"SELECT id, email, claims, password_changed_at, password_max_age_days, metadata FROM users "+
+			"WHERE email IS NOT NULL AND length(password) > 0 AND (password_max_age_days > 0 OR ? > 0) "+
+			"AND "+s.dialect.DaysElapsed("password_changed_at", "CASE WHEN password_max_age_days > 0 THEN password_max_age_days ELSE ? END - ?")+" "+
+			"AND (password_expiry_reminded_at IS NULL OR password_expiry_reminded_at < password_changed_at);",
+		defaultMaxAgeDays, defaultMaxAgeDays, reminderDays, s.dialect.Timestamp(now),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exec select-users-to-remind-stmt: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var users []storage.User
+	for rows.Next() {
+		var u storage.User
+		var rawClaims, rawMetadata []byte
+		err := rows.Scan(&u.ID, &u.EMail, &rawClaims, &u.PasswordChangedAt, &u.PasswordMaxAgeDays, &rawMetadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan select-users-to-remind-stmt result: %w", err)
+		}
+
+		err = unmarshalClaims(rawClaims, rawMetadata, &u)
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, u)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read select-users-to-remind-stmt result: %w", err)
+	}
+
+	return users, nil
+}
+
+// MarkPasswordExpiryReminded persists that the user with the given id has been reminded of the password expiry.
+// return storage.ErrUserNotFound when user not found
+func (s *Storage) MarkPasswordExpiryReminded(userID string, remindedAt time.Time) error {
+	resp, err := s.db.Exec("UPDATE users SET password_expiry_reminded_at = ? WHERE id = ?;", s.dialect.Timestamp(remindedAt), userID)
+	if err != nil {
+		return fmt.Errorf("failed to exec update stmt: %w", err)
+	}
+
+	ra, err := resp.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get count of affected rows: %w", err)
+	}
+	if ra == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	return nil
+}
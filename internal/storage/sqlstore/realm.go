@@ -0,0 +1,175 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"sort"
+	"strings"
+)
+
+// realmColumns returns the selected columns of realms in the order scanRealm scans them. Hosts consist of host name
+// characters only, so they can be concatenated with ','
+func (s *Storage) realmColumns() string {
+	return "realms.name, realms.jwt_private_key, realms.jwt_issuer, realms.jwt_audience, realms.created_at, " +
+		"COALESCE(" + s.dialect.GroupConcat("realm_hosts.host") + ", '')"
+}
+
+// realmFrom is the from clause for realmColumns
+const realmFrom = "FROM realms LEFT JOIN realm_hosts ON realm_hosts.realm = realms.name"
+
+// Realms returns all realms ordered by name
+func (s *Storage) Realms() ([]storage.Realm, error) {
+	rows, err := s.db.Query("SELECT " + s.realmColumns() + " " + realmFrom + " GROUP BY realms.name ORDER BY realms.name;")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query realms: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var realms []storage.Realm
+	for rows.Next() {
+		r, err := scanRealm(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		realms = append(realms, r)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read realms: %w", err)
+	}
+
+	return realms, nil
+}
+
+// Realm finds the realm with the given name
+// return storage.ErrRealmNotFound when realm not found
+func (s *Storage) Realm(name string) (storage.Realm, error) {
+	r, err := scanRealm(s.db.QueryRow("SELECT "+s.realmColumns()+" "+realmFrom+" WHERE realms.name = ? GROUP BY realms.name;", name))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return storage.Realm{}, storage.ErrRealmNotFound
+		}
+
+		return storage.Realm{}, err
+	}
+
+	return r, nil
+}
+
+// scanRealm scans the given row of realmColumns. Hosts will be sorted
+func scanRealm(row scanner) (storage.Realm, error) {
+	var r storage.Realm
+	var hosts string
+	err := row.Scan(&r.Name, &r.JWTPrivateKey, &r.JWTIssuer, &r.JWTAudience, &r.CreatedAt, &hosts)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return storage.Realm{}, err
+		}
+
+		return storage.Realm{}, fmt.Errorf("failed to scan realm: %w", err)
+	}
+
+	r.Hosts = []string{}
+	if hosts != "" {
+		r.Hosts = strings.Split(hosts, ",")
+		sort.Strings(r.Hosts)
+	}
+
+	return r, nil
+}
+
+// CreateRealm persists the given realm with its hosts in one transaction
+// return storage.ErrRealmAlreadyExists when a realm with the same name already exists
+// return storage.ErrRealmHostAlreadyUsed when one of the hosts belongs to another realm
+func (s *Storage) CreateRealm(r storage.Realm) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin create realm transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.Exec(
+		"INSERT INTO realms (name, jwt_private_key, jwt_issuer, jwt_audience, created_at) VALUES(?, ?, ?, ?, ?);",
+		r.Name, r.JWTPrivateKey, r.JWTIssuer, r.JWTAudience, s.dialect.Timestamp(r.CreatedAt),
+	)
+	if err != nil {
+		if s.dialect.IsUniqueViolation(err, "realms", "name") {
+			return storage.ErrRealmAlreadyExists
+		}
+		return fmt.Errorf("failed to exec insert realm stmt: %w", err)
+	}
+
+	err = s.insertRealmHosts(tx, r)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit create realm transaction: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateRealm updates all properties (excluding name and created at) of the given realm and replaces its hosts in one
+// transaction
+// return storage.ErrRealmNotFound when realm not found
+// return storage.ErrRealmHostAlreadyUsed when one of the hosts belongs to another realm
+func (s *Storage) UpdateRealm(r storage.Realm) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin update realm transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	resp, err := tx.Exec(
+		"UPDATE realms SET jwt_private_key = ?, jwt_issuer = ?, jwt_audience = ? WHERE name = ?;",
+		r.JWTPrivateKey, r.JWTIssuer, r.JWTAudience, r.Name,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to exec update realm stmt: %w", err)
+	}
+
+	ra, err := resp.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get count of affected rows: %w", err)
+	}
+	if ra == 0 {
+		return storage.ErrRealmNotFound
+	}
+
+	_, err = tx.Exec("DELETE FROM realm_hosts WHERE realm = ?;", r.Name)
+	if err != nil {
+		return fmt.Errorf("failed to exec delete realm hosts stmt: %w", err)
+	}
+
+	err = s.insertRealmHosts(tx, r)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit update realm transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) insertRealmHosts(tx *sql.Tx, r storage.Realm) error {
+	for _, host := range r.Hosts {
+		_, err := tx.Exec("INSERT INTO realm_hosts (host, realm) VALUES(?, ?);", host, r.Name)
+		if err != nil {
+			if s.dialect.IsUniqueViolation(err, "realm_hosts", "host") {
+				return storage.ErrRealmHostAlreadyUsed
+			}
+			return fmt.Errorf("failed to exec insert realm host stmt: %w", err)
+		}
+	}
+
+	return nil
+}
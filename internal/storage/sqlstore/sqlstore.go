@@ -0,0 +1,89 @@
+// Package sqlstore is the database/sql implementation of the storage shared by the sqlite and mysql storages. It uses
+// the types and errors of package storage and behaves like the postgres storage. All statements use '?' placeholders,
+// everything else which differs between the databases is provided by a Dialect.
+package sqlstore
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+var migrateNewWithDatabaseInstance = func(sourceURL string, databaseName string, databaseInstance database.Driver) (migration, error) {
+	m, e := migrate.NewWithDatabaseInstance(sourceURL, databaseName, databaseInstance)
+	return m, e
+}
+
+//go:generate moq -out migration_moq_test.go . migration
+type migration interface {
+	Up() error
+}
+
+// Dialect provides the database specific parts of statements and error handling
+type Dialect interface {
+	// Timestamp converts the given time into the representation which will be stored
+	Timestamp(t time.Time) time.Time
+	// DaysElapsed returns a condition which is true when at least the given days (expression) have elapsed between the
+	// given timestamp (expression) and the time bound to the next placeholder
+	DaysElapsed(timestamp, days string) string
+	// ForUpdate returns the suffix of select statements which locks the selected rows until the end of the transaction
+	ForUpdate() string
+	// GroupConcat returns the aggregation which concatenates all values of the given column with ','
+	GroupConcat(column string) string
+	// Upsert returns the suffix of an insert statement which updates the given columns of the existing row when the
+	// unique key column already exists
+	Upsert(key string, columns ...string) string
+	// IsUniqueViolation returns true when the given error is a violation of the unique or primary key constraint of the
+	// given table column e.g. 'users' and 'email'
+	IsUniqueViolation(err error, table, column string) bool
+	// ClaimCondition returns the condition of the given valid predicate on the json encoded claims column and its
+	// arguments. It has to evaluate the predicate like the postgres storage does (see storage.ClaimPredicate.Matches)
+	ClaimCondition(p storage.ClaimPredicate) (string, []interface{}, error)
+}
+
+type Storage struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+// New returns a Storage which uses the given connection pool and dialect
+func New(db *sql.DB, dialect Dialect) *Storage {
+	return &Storage{
+		db:      db,
+		dialect: dialect,
+	}
+}
+
+// Migrate executes all sql migration files from the given db-migrations folder with the given database driver e.g.
+// 'sqlite3'
+func Migrate(driver database.Driver, databaseName, dbMigrationsPath string) error {
+	m, err := migrateNewWithDatabaseInstance(fmt.Sprintf("file://%s", dbMigrationsPath), databaseName, driver)
+	if err != nil {
+		return fmt.Errorf("failed to create a migrate for database schema migration: %w", err)
+	}
+
+	err = m.Up()
+	if err != nil {
+		if err != migrate.ErrNoChange {
+			return fmt.Errorf("failed to executed database schema migration: %w", err)
+		}
+		logrus.Info("no database schema changes")
+		return nil
+	}
+
+	logrus.Info("executed database schema migration successfully")
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// execer is implemented by *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
@@ -0,0 +1,60 @@
+package sqlstore
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name                                      string
+		migrateNewWithDatabaseInstanceReturnError error
+		migrateUpError                            error
+		expectedError                             error
+	}{
+		{
+			name: "Happycase",
+		},
+		{
+			name: "new migration error",
+			migrateNewWithDatabaseInstanceReturnError: errors.New("nope"),
+			expectedError: errors.New("failed to create a migrate for database schema migration: nope"),
+		},
+		{
+			name:           "migrate up error",
+			migrateUpError: errors.New("nope"),
+			expectedError:  errors.New("failed to executed database schema migration: nope"),
+		},
+		{
+			name:           "no migration change",
+			migrateUpError: migrate.ErrNoChange,
+			expectedError:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldMigrateNewWithDatabaseInstance := migrateNewWithDatabaseInstance
+			defer func() { migrateNewWithDatabaseInstance = oldMigrateNewWithDatabaseInstance }()
+
+			migrateNewWithDatabaseInstance = func(sourceURL string, databaseName string, databaseInstance database.Driver) (migration, error) {
+				if sourceURL != "file://pathToDBMigrationFolder" || databaseName != "sqlite3" {
+					t.Errorf("unexpected migration source %q of database %q", sourceURL, databaseName)
+				}
+				return &migrationMock{
+					UpFunc: func() error {
+						return tt.migrateUpError
+					},
+				}, tt.migrateNewWithDatabaseInstanceReturnError
+			}
+
+			err := Migrate(nil, "sqlite3", "pathToDBMigrationFolder")
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("unexpected error. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
+			}
+		})
+	}
+}
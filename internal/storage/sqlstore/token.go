@@ -0,0 +1,149 @@
+package sqlstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"time"
+)
+
+// CreateToken persists the given token in database. UserID must match to a users id. Tokens without metadata will be
+// stored with NULL metadata.
+func (s *Storage) CreateToken(t storage.Token) (int64, error) {
+	var rawMetadata []byte
+	if t.Metadata != nil {
+		var err error
+		rawMetadata, err = json.Marshal(t.Metadata)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal token>metadata: %w", err)
+		}
+	}
+
+	res, err := s.db.Exec(
+		"INSERT INTO tokens (user_id, token, type, created_at, metadata) VALUES(?, ?, ?, ?, ?);",
+		t.UserID, t.Token, t.Type, s.dialect.Timestamp(t.CreatedAt), rawMetadata,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to exec stmt: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get id of inserted token: %w", err)
+	}
+
+	return id, nil
+}
+
+// TokensByUserIDAndType finds all tokens of the given type which belong to the user with the given id.
+func (s *Storage) TokensByUserIDAndType(userID, tokenType string) ([]storage.Token, error) {
+	rows, err := s.db.Query(
+		"SELECT id, token, created_at, attempts, metadata FROM tokens WHERE user_id = ? AND type = ? ORDER BY created_at DESC;",
+		userID, tokenType,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exec select-token-stmt: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var tokens []storage.Token
+	for rows.Next() {
+		t := storage.Token{
+			UserID: userID,
+			Type:   tokenType,
+		}
+		var rawMetadata []byte
+		err := rows.Scan(&t.ID, &t.Token, &t.CreatedAt, &t.Attempts, &rawMetadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan select-token-stmt result: %w", err)
+		}
+
+		if rawMetadata != nil {
+			err = json.Unmarshal(rawMetadata, &t.Metadata)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unmarshal token>metadata: %w", err)
+			}
+		}
+
+		tokens = append(tokens, t)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read select-token-stmt result: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// IncrementTokenAttempts increments the count of failed redemptions of the token with the given ID and returns the new
+// count in one transaction.
+// return storage.ErrTokenNotFound there is no token with the given ID
+func (s *Storage) IncrementTokenAttempts(id int64) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin increment-token-attempts transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.Exec("UPDATE tokens SET attempts = attempts + 1 WHERE id = ?;", id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to exec increment-token-attempts-stmt: %w", err)
+	}
+
+	ra, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get count of affected rows: %w", err)
+	}
+	if ra == 0 {
+		return 0, storage.ErrTokenNotFound
+	}
+
+	var attempts int
+	err = tx.QueryRow("SELECT attempts FROM tokens WHERE id = ?;", id).Scan(&attempts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to exec select-token-attempts-stmt: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("failed to commit increment-token-attempts transaction: %w", err)
+	}
+
+	return attempts, nil
+}
+
+// DeleteToken deletes token with the given ID.
+// return storage.ErrTokenNotFound there is no token with the given ID
+func (s *Storage) DeleteToken(id int64) error {
+	res, err := s.db.Exec("DELETE FROM tokens WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete token: %w", err)
+	}
+
+	i, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("could not get num of affected row: %w", err)
+	}
+	if i != 1 {
+		return storage.ErrTokenNotFound
+	}
+
+	return nil
+}
+
+// DeleteTokensCreatedBefore deletes all tokens of the given type which have been created before the given time and
+// returns the count of deleted tokens.
+func (s *Storage) DeleteTokensCreatedBefore(tokenType string, createdBefore time.Time) (int64, error) {
+	res, err := s.db.Exec("DELETE FROM tokens WHERE type = ? AND created_at < ?;", tokenType, s.dialect.Timestamp(createdBefore))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete tokens: %w", err)
+	}
+
+	i, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("could not get num of affected row: %w", err)
+	}
+
+	return i, nil
+}
@@ -0,0 +1,42 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+)
+
+// TOTP finds the totp enrolment of the user with the given id
+// return storage.ErrTOTPNotFound when the user has no totp enrolment
+func (s *Storage) TOTP(userID string) (storage.TOTP, error) {
+	t := storage.TOTP{
+		UserID: userID,
+	}
+	err := s.db.QueryRow(
+		"SELECT secret, confirmed, last_used_step, created_at FROM user_totp WHERE user_id = ?;",
+		userID,
+	).Scan(&t.Secret, &t.Confirmed, &t.LastUsedStep, &t.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return storage.TOTP{}, storage.ErrTOTPNotFound
+		}
+
+		return storage.TOTP{}, fmt.Errorf("failed to query totp: %w", err)
+	}
+
+	return t, nil
+}
+
+// SaveTOTP creates or replaces the totp enrolment of the user with the given id. UserID must match to a users id.
+func (s *Storage) SaveTOTP(t storage.TOTP) error {
+	_, err := s.db.Exec(
+		"INSERT INTO user_totp (user_id, secret, confirmed, last_used_step, created_at) VALUES(?, ?, ?, ?, ?) "+
+			s.dialect.Upsert("user_id", "secret", "confirmed", "last_used_step", "created_at")+";",
+		t.UserID, t.Secret, t.Confirmed, t.LastUsedStep, s.dialect.Timestamp(t.CreatedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to exec save totp stmt: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,296 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"time"
+)
+
+// userColumns are the selected columns of users in the order scanUser scans them
+const userColumns = "id, COALESCE(email, ''), password, claims, password_changed_at, password_max_age_days, " +
+	"COALESCE(display_email, email, ''), COALESCE(username, ''), COALESCE(phone, ''), metadata, last_login_at"
+
+// loginIdentifierColumns are the unique columns of users of all login identifiers
+var loginIdentifierColumns = []string{"email", "username", "phone"}
+
+// CreateUser persists the given user in database. Empty emails, usernames and phones will be stored as NULL.
+// return storage.ErrUserAlreadyExists when a user with the same email, username or phone already exists
+func (s *Storage) CreateUser(u storage.User) error {
+	rawClaims, err := json.Marshal(u.Claims)
+	if err != nil {
+		return fmt.Errorf("failed to marhsal user>claims: %w", err)
+	}
+
+	rawMetadata, err := json.Marshal(u.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marhsal user>metadata: %w", err)
+	}
+
+	_, err = s.db.Exec(
+		"INSERT INTO users (id, email, password, claims, password_changed_at, password_max_age_days, display_email, username, phone, metadata) "+
+			"VALUES(?, NULLIF(?, ''), ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), ?);",
+		u.ID, u.EMail, storedPassword(u.Password), rawClaims, s.dialect.Timestamp(u.PasswordChangedAt), u.PasswordMaxAgeDays, u.DisplayEMail, u.Username,
+		u.Phone, rawMetadata,
+	)
+	if err != nil {
+		if s.isLoginIdentifierViolation(err) {
+			return storage.ErrUserAlreadyExists
+		}
+		return fmt.Errorf("failed to exec create stmt: %w", err)
+	}
+
+	return nil
+}
+
+// User finds the user identified by email
+// return storage.ErrUserNotFound when user not found
+func (s *Storage) User(email string) (storage.User, error) {
+	return scanUser(s.db.QueryRow("SELECT "+userColumns+" FROM users WHERE email = ?;", email))
+}
+
+// UserByID finds the user identified by id
+// return storage.ErrUserNotFound when user not found
+func (s *Storage) UserByID(id string) (storage.User, error) {
+	return scanUser(s.db.QueryRow("SELECT "+userColumns+" FROM users WHERE id = ?;", id))
+}
+
+// UserByUsername finds the user identified by the given normalized username
+// return storage.ErrUserNotFound when user not found
+func (s *Storage) UserByUsername(username string) (storage.User, error) {
+	return scanUser(s.db.QueryRow("SELECT "+userColumns+" FROM users WHERE username = ?;", username))
+}
+
+// UserByPhone finds the user identified by the given phone number in E.164 format
+// return storage.ErrUserNotFound when user not found
+func (s *Storage) UserByPhone(phone string) (storage.User, error) {
+	return scanUser(s.db.QueryRow("SELECT "+userColumns+" FROM users WHERE phone = ?;", phone))
+}
+
+// storedPassword returns an empty password for users without password (e.g. invited users) because the password
+// column is NOT NULL
+func storedPassword(password []byte) []byte {
+	if password == nil {
+		return []byte{}
+	}
+
+	return password
+}
+
+// scanUser scans the given row of userColumns
+// return storage.ErrUserNotFound when there is no row
+func scanUser(row scanner) (storage.User, error) {
+	var user storage.User
+	var rawClaims, rawMetadata []byte
+	var lastLoginAt sql.NullTime
+	err := row.Scan(
+		&user.ID, &user.EMail, &user.Password, &rawClaims, &user.PasswordChangedAt, &user.PasswordMaxAgeDays, &user.DisplayEMail,
+		&user.Username, &user.Phone, &rawMetadata, &lastLoginAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return storage.User{}, storage.ErrUserNotFound
+		}
+
+		return storage.User{}, fmt.Errorf("failed to query user: %w", err)
+	}
+
+	if lastLoginAt.Valid {
+		user.LastLoginAt = &lastLoginAt.Time
+	}
+
+	err = unmarshalClaims(rawClaims, rawMetadata, &user)
+	if err != nil {
+		return storage.User{}, err
+	}
+
+	return user, nil
+}
+
+// unmarshalClaims unmarshals the given raw claims and metadata into the given user. NULL metadata will be skipped
+func unmarshalClaims(rawClaims, rawMetadata []byte, user *storage.User) error {
+	err := json.Unmarshal(rawClaims, &user.Claims)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal user>claims: %w", err)
+	}
+
+	if rawMetadata == nil {
+		return nil
+	}
+
+	err = json.Unmarshal(rawMetadata, &user.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal user>metadata: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateUser updates all properties (excluding id, email and display email) from the given user which will be
+// identified by id
+// return storage.ErrUserNotFound when user not found
+// return storage.ErrUserAlreadyExists when another user has the same username or phone
+func (s *Storage) UpdateUser(u storage.User) error {
+	return s.updateUser(s.db, u)
+}
+
+// PatchUser calls the given patch function with the user with the given id and updates all properties like UpdateUser
+// in one transaction. The user row will be locked (sqlite: transactions lock the database), so concurrent patches of
+// the same user will be applied one after another. Errors of the patch function will be returned unwrapped and nothing
+// will be updated.
+// return storage.ErrUserNotFound when user not found
+// return storage.ErrUserAlreadyExists when another user has the same username or phone
+func (s *Storage) PatchUser(id string, patch func(u *storage.User) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin patch transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	u, err := scanUser(tx.QueryRow("SELECT "+userColumns+" FROM users WHERE id = ?"+s.dialect.ForUpdate()+";", id))
+	if err != nil {
+		return err
+	}
+
+	err = patch(&u)
+	if err != nil {
+		return err
+	}
+
+	err = s.updateUser(tx, u)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit patch transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) updateUser(db execer, u storage.User) error {
+	rawClaims, err := json.Marshal(u.Claims)
+	if err != nil {
+		return fmt.Errorf("failed to marhsal user>claims: %w", err)
+	}
+
+	rawMetadata, err := json.Marshal(u.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marhsal user>metadata: %w", err)
+	}
+
+	resp, err := db.Exec(
+		"UPDATE users SET password = ?, claims = ?, password_changed_at = ?, password_max_age_days = ?, "+
+			"username = NULLIF(?, ''), phone = NULLIF(?, ''), metadata = ? WHERE id = ?;",
+		storedPassword(u.Password), rawClaims, s.dialect.Timestamp(u.PasswordChangedAt), u.PasswordMaxAgeDays, u.Username, u.Phone, rawMetadata, u.ID,
+	)
+	if err != nil {
+		if s.isLoginIdentifierViolation(err) {
+			return storage.ErrUserAlreadyExists
+		}
+		return fmt.Errorf("failed to exec update stmt: %w", err)
+	}
+
+	ra, err := resp.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get count of affected rows: %w", err)
+	}
+	if ra == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// isLoginIdentifierViolation returns true when the given error is a violation of the unique constraint of a login
+// identifier
+func (s *Storage) isLoginIdentifierViolation(err error) bool {
+	for _, column := range loginIdentifierColumns {
+		if s.dialect.IsUniqueViolation(err, "users", column) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DeleteUser deletes the user with the given id and all of its rows in all other tables in one transaction like
+// EraseUser.
+// return storage.ErrUserNotFound when user not found
+func (s *Storage) DeleteUser(id string) error {
+	_, err := s.EraseUser(id)
+	return err
+}
+
+// MarkUserInvited persists that the user with the given id has been invited (again) at the given time.
+// return storage.ErrUserNotFound when user not found
+func (s *Storage) MarkUserInvited(id string, invitedAt time.Time) error {
+	resp, err := s.db.Exec("UPDATE users SET invited_at = ? WHERE id = ?;", s.dialect.Timestamp(invitedAt), id)
+	if err != nil {
+		return fmt.Errorf("failed to exec update stmt: %w", err)
+	}
+
+	ra, err := resp.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get count of affected rows: %w", err)
+	}
+	if ra == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// UserEMails returns the stored emails of all users with email by the ids of the users
+func (s *Storage) UserEMails() (map[string]string, error) {
+	rows, err := s.db.Query("SELECT id, email FROM users WHERE email IS NOT NULL;")
+	if err != nil {
+		return nil, fmt.Errorf("failed to exec select-emails-stmt: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	emails := map[string]string{}
+	for rows.Next() {
+		var id, email string
+		err := rows.Scan(&id, &email)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan select-emails-stmt result: %w", err)
+		}
+
+		emails[id] = email
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read select-emails-stmt result: %w", err)
+	}
+
+	return emails, nil
+}
+
+// NormalizeUserEMail replaces the email of the user with the given id by the given normalized email. The previous
+// email will be kept as display email unless the user already has one.
+// return storage.ErrUserNotFound when user not found
+// return storage.ErrUserAlreadyExists when another user has the same email
+func (s *Storage) NormalizeUserEMail(id, email string) error {
+	resp, err := s.db.Exec("UPDATE users SET display_email = COALESCE(display_email, email), email = ? WHERE id = ?;", email, id)
+	if err != nil {
+		if s.isLoginIdentifierViolation(err) {
+			return storage.ErrUserAlreadyExists
+		}
+		return fmt.Errorf("failed to exec update stmt: %w", err)
+	}
+
+	ra, err := resp.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get count of affected rows: %w", err)
+	}
+	if ra == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,228 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"time"
+)
+
+// userDataTable is a table which contains rows of users
+type userDataTable struct {
+	table string
+	// name is the name of the table in error messages
+	name string
+}
+
+// userDataTables are all tables besides users which contain rows of users. The rows will be deleted in this order on
+// user deletion.
+var userDataTables = []userDataTable{
+	{table: "tokens", name: "tokens"},
+	{table: "password_history", name: "password-history"},
+	{table: "user_totp", name: "totp"},
+	{table: "webauthn_credentials", name: "webauthn credentials"},
+	{table: "mfa_recovery_codes", name: "recovery-codes"},
+	{table: "logins", name: "logins"},
+}
+
+// UserData finds everything stored about the user with the given id. All rows will be read in one transaction.
+// return storage.ErrUserNotFound when user not found
+func (s *Storage) UserData(id string) (storage.UserData, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return storage.UserData{}, fmt.Errorf("failed to begin user-data transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	u, err := scanUser(tx.QueryRow("SELECT "+userColumns+" FROM users WHERE id = ?;", id))
+	if err != nil {
+		return storage.UserData{}, err
+	}
+
+	data := storage.UserData{User: u}
+
+	err = queryRows(tx, "tokens", "SELECT type, created_at, attempts FROM tokens WHERE user_id = ? ORDER BY created_at;", id,
+		func(rows *sql.Rows) error {
+			var t storage.TokenData
+			err := rows.Scan(&t.Type, &t.CreatedAt, &t.Attempts)
+			data.Tokens = append(data.Tokens, t)
+			return err
+		})
+	if err != nil {
+		return storage.UserData{}, err
+	}
+
+	err = queryRows(tx, "password-history", "SELECT created_at FROM password_history WHERE user_id = ? ORDER BY created_at;", id,
+		func(rows *sql.Rows) error {
+			var createdAt time.Time
+			err := rows.Scan(&createdAt)
+			data.PasswordHistory = append(data.PasswordHistory, createdAt)
+			return err
+		})
+	if err != nil {
+		return storage.UserData{}, err
+	}
+
+	err = queryRows(tx, "totp", "SELECT confirmed, created_at FROM user_totp WHERE user_id = ?;", id,
+		func(rows *sql.Rows) error {
+			data.TOTP = &storage.TOTPData{}
+			return rows.Scan(&data.TOTP.Confirmed, &data.TOTP.CreatedAt)
+		})
+	if err != nil {
+		return storage.UserData{}, err
+	}
+
+	err = queryRows(tx, "webauthn-credentials", "SELECT credential_id, sign_count, created_at, last_used_at "+
+		"FROM webauthn_credentials WHERE user_id = ? ORDER BY created_at;", id,
+		func(rows *sql.Rows) error {
+			var c storage.WebAuthnCredentialData
+			var lastUsedAt sql.NullTime
+			err := rows.Scan(&c.ID, &c.SignCount, &c.CreatedAt, &lastUsedAt)
+			if lastUsedAt.Valid {
+				c.LastUsedAt = &lastUsedAt.Time
+			}
+			data.WebAuthnCredentials = append(data.WebAuthnCredentials, c)
+			return err
+		})
+	if err != nil {
+		return storage.UserData{}, err
+	}
+
+	err = queryRows(tx, "recovery-codes", "SELECT created_at, used_at FROM mfa_recovery_codes WHERE user_id = ? ORDER BY id;", id,
+		func(rows *sql.Rows) error {
+			var c storage.RecoveryCodeData
+			var usedAt sql.NullTime
+			err := rows.Scan(&c.CreatedAt, &usedAt)
+			if usedAt.Valid {
+				c.UsedAt = &usedAt.Time
+			}
+			data.RecoveryCodes = append(data.RecoveryCodes, c)
+			return err
+		})
+	if err != nil {
+		return storage.UserData{}, err
+	}
+
+	err = queryRows(tx, "logins", "SELECT id, user_id, created_at, ip, user_agent, outcome, factor "+
+		"FROM logins WHERE user_id = ? ORDER BY created_at, id;", id,
+		func(rows *sql.Rows) error {
+			var l storage.Login
+			err := rows.Scan(&l.ID, &l.UserID, &l.CreatedAt, &l.IP, &l.UserAgent, &l.Outcome, &l.Factor)
+			data.Logins = append(data.Logins, l)
+			return err
+		})
+	if err != nil {
+		return storage.UserData{}, err
+	}
+
+	return data, nil
+}
+
+// EraseUser deletes the user with the given id and all of its rows in all other tables in one transaction and returns
+// the count of deleted rows per table.
+// return storage.ErrUserNotFound when user not found
+func (s *Storage) EraseUser(id string) (map[string]int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin delete transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	deletedRows := map[string]int64{}
+	for _, t := range userDataTables {
+		resp, err := tx.Exec("DELETE FROM "+t.table+" WHERE user_id = ?;", id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to exec delete %s from user stmt: %w", t.name, err)
+		}
+
+		deletedRows[t.table], err = resp.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get count of affected rows: %w", err)
+		}
+	}
+
+	resp, err := tx.Exec("DELETE FROM users WHERE id = ?;", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exec delete user stmt: %w", err)
+	}
+
+	ra, err := resp.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get count of affected rows: %w", err)
+	}
+	if ra == 0 {
+		return nil, storage.ErrUserNotFound
+	}
+	deletedRows["users"] = ra
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit delete transaction: %w", err)
+	}
+
+	return deletedRows, nil
+}
+
+// unverifiedUsersCondition matches all users without password which have been invited the last time before the bound
+// time
+const unverifiedUsersCondition = "length(password) = 0 AND invited_at < ?"
+
+// DeleteUnverifiedUsers deletes all users without password (who never accepted their invitation) which have been
+// invited the last time before the given time together with all of their rows in all other tables in one transaction
+// and returns the count of deleted users. Users without invitation time will be kept. Mysql can not delete from a table
+// selected by a subquery, so the users will be deleted by condition.
+func (s *Storage) DeleteUnverifiedUsers(invitedBefore time.Time) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin delete transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, t := range userDataTables {
+		_, err := tx.Exec("DELETE FROM "+t.table+" WHERE user_id IN (SELECT id FROM users WHERE "+unverifiedUsersCondition+");", s.dialect.Timestamp(invitedBefore))
+		if err != nil {
+			return 0, fmt.Errorf("failed to exec delete %s from users stmt: %w", t.name, err)
+		}
+	}
+
+	resp, err := tx.Exec("DELETE FROM users WHERE "+unverifiedUsersCondition+";", s.dialect.Timestamp(invitedBefore))
+	if err != nil {
+		return 0, fmt.Errorf("failed to exec delete users stmt: %w", err)
+	}
+
+	ra, err := resp.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get count of affected rows: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("failed to commit delete transaction: %w", err)
+	}
+
+	return ra, nil
+}
+
+// queryRows executes the given query with the given user id and calls scan for each row. name is the name of the
+// queried rows in error messages.
+func queryRows(tx *sql.Tx, name, query, id string, scan func(rows *sql.Rows) error) error {
+	rows, err := tx.Query(query, id)
+	if err != nil {
+		return fmt.Errorf("failed to exec select-%s-stmt: %w", name, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		err = scan(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan select-%s-stmt result: %w", name, err)
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return fmt.Errorf("failed to read select-%s-stmt result: %w", name, err)
+	}
+
+	return nil
+}
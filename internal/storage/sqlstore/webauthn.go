@@ -0,0 +1,86 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"time"
+)
+
+// WebAuthnCredentials finds all webauthn credentials of the user with the given id
+func (s *Storage) WebAuthnCredentials(userID string) ([]storage.WebAuthnCredential, error) {
+	rows, err := s.db.Query(
+		"SELECT credential_id, public_key, sign_count, created_at, last_used_at FROM webauthn_credentials "+
+			"WHERE user_id = ? ORDER BY created_at;",
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exec select-webauthn-credentials-stmt: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var credentials []storage.WebAuthnCredential
+	for rows.Next() {
+		c := storage.WebAuthnCredential{
+			UserID: userID,
+		}
+		var lastUsedAt sql.NullTime
+		err := rows.Scan(&c.ID, &c.PublicKey, &c.SignCount, &c.CreatedAt, &lastUsedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan select-webauthn-credentials-stmt result: %w", err)
+		}
+		if lastUsedAt.Valid {
+			c.LastUsedAt = &lastUsedAt.Time
+		}
+
+		credentials = append(credentials, c)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read select-webauthn-credentials-stmt result: %w", err)
+	}
+
+	return credentials, nil
+}
+
+// CreateWebAuthnCredential persists the given webauthn credential. UserID must match to a users id.
+// return storage.ErrWebAuthnCredentialAlreadyExists when a credential with the same id has already been registered
+func (s *Storage) CreateWebAuthnCredential(c storage.WebAuthnCredential) error {
+	_, err := s.db.Exec(
+		"INSERT INTO webauthn_credentials (credential_id, user_id, public_key, sign_count, created_at) "+
+			"VALUES(?, ?, ?, ?, ?);",
+		c.ID, c.UserID, c.PublicKey, c.SignCount, s.dialect.Timestamp(c.CreatedAt),
+	)
+	if err != nil {
+		if s.dialect.IsUniqueViolation(err, "webauthn_credentials", "credential_id") {
+			return storage.ErrWebAuthnCredentialAlreadyExists
+		}
+		return fmt.Errorf("failed to exec create webauthn credential stmt: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateWebAuthnCredentialUsage stores the new sign count and the last usage of the webauthn credential with the given
+// id
+// return storage.ErrWebAuthnCredentialNotFound when there is no credential with the given id
+func (s *Storage) UpdateWebAuthnCredentialUsage(id []byte, signCount uint32, lastUsedAt time.Time) error {
+	res, err := s.db.Exec(
+		"UPDATE webauthn_credentials SET sign_count = ?, last_used_at = ? WHERE credential_id = ?;",
+		signCount, s.dialect.Timestamp(lastUsedAt), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to exec update webauthn credential stmt: %w", err)
+	}
+
+	ra, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get count of affected rows: %w", err)
+	}
+	if ra == 0 {
+		return storage.ErrWebAuthnCredentialNotFound
+	}
+
+	return nil
+}
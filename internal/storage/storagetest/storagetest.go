@@ -0,0 +1,1056 @@
+// Package storagetest contains the behavioral tests all storage backends have to pass.
+package storagetest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/leberKleber/simple-jwt-provider/internal"
+	"github.com/leberKleber/simple-jwt-provider/internal/jobs"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Storage is implemented by all storage backends
+type Storage interface {
+	internal.Storage
+	internal.RealmStorage
+	jobs.Locker
+	UserEMails() (map[string]string, error)
+	NormalizeUserEMail(id, email string) error
+}
+
+// now is the base of all times in the tests. It has microsecond precision like all supported databases
+var now = time.Date(2020, 6, 1, 12, 30, 15, 123456000, time.UTC)
+
+// Run runs the behavioral tests against the storages returned by newStorage. newStorage has to return a new empty and
+// migrated storage on each call.
+func Run(t *testing.T, newStorage func(t *testing.T) Storage) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s Storage)
+	}{
+		{name: "User", test: testUser},
+		{name: "UsersByClaims", test: testUsersByClaims},
+		{name: "CreateUser duplicates", test: testCreateUserDuplicates},
+		{name: "UpdateUser", test: testUpdateUser},
+		{name: "PatchUser", test: testPatchUser},
+		{name: "DeleteUser", test: testDeleteUser},
+		{name: "NormalizeUserEMail", test: testNormalizeUserEMail},
+		{name: "Invitations", test: testInvitations},
+		{name: "Tokens", test: testTokens},
+		{name: "PasswordHistory", test: testPasswordHistory},
+		{name: "PasswordExpiry", test: testPasswordExpiry},
+		{name: "TOTP", test: testTOTP},
+		{name: "WebAuthn", test: testWebAuthn},
+		{name: "RecoveryCodes", test: testRecoveryCodes},
+		{name: "Logins", test: testLogins},
+		{name: "UserData", test: testUserData},
+		{name: "Realms", test: testRealms},
+		{name: "TryLock", test: testTryLock},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStorage(t))
+		})
+	}
+}
+
+// newUser returns a new user with a random id and the given login identifiers
+func newUser(email, username, phone string) storage.User {
+	return storage.User{
+		ID:                 uuid.New().String(),
+		EMail:              email,
+		DisplayEMail:       email,
+		Username:           username,
+		Phone:              phone,
+		Password:           []byte("bcryptedPassword"),
+		Claims:             map[string]interface{}{"role": "admin", "level": float64(4711)},
+		Metadata:           map[string]interface{}{"plan": "pro"},
+		PasswordChangedAt:  now,
+		PasswordMaxAgeDays: 0,
+	}
+}
+
+func createUser(t *testing.T, s Storage, u storage.User) storage.User {
+	t.Helper()
+
+	err := s.CreateUser(u)
+	if err != nil {
+		t.Fatalf("failed to create user: %s", err)
+	}
+
+	return u
+}
+
+func expectError(t *testing.T, expected, given error) {
+	t.Helper()
+
+	if !errors.Is(given, expected) {
+		t.Fatalf("unexpected error. Expected:\n%q\nGiven:\n%q", expected, given)
+	}
+}
+
+func expectEqual(t *testing.T, name string, expected, given interface{}) {
+	t.Helper()
+
+	if !reflect.DeepEqual(expected, given) {
+		t.Fatalf("unexpected %s. Expected:\n%#v\nGiven:\n%#v", name, expected, given)
+	}
+}
+
+func expectTime(t *testing.T, name string, expected, given time.Time) {
+	t.Helper()
+
+	if !expected.Equal(given) {
+		t.Fatalf("unexpected %s. Expected:\n%s\nGiven:\n%s", name, expected, given)
+	}
+}
+
+// expectUser compares the given users. Times will be compared by instant
+func expectUser(t *testing.T, expected, given storage.User) {
+	t.Helper()
+
+	expectTime(t, "password changed at", expected.PasswordChangedAt, given.PasswordChangedAt)
+	if (expected.LastLoginAt == nil) != (given.LastLoginAt == nil) ||
+		(expected.LastLoginAt != nil && !expected.LastLoginAt.Equal(*given.LastLoginAt)) {
+		t.Fatalf("unexpected last login at. Expected:\n%v\nGiven:\n%v", expected.LastLoginAt, given.LastLoginAt)
+	}
+
+	expected.PasswordChangedAt, given.PasswordChangedAt = time.Time{}, time.Time{}
+	expected.LastLoginAt, given.LastLoginAt = nil, nil
+	expectEqual(t, "user", expected, given)
+}
+
+func testUser(t *testing.T, s Storage) {
+	u := createUser(t, s, newUser("info@leberkleber.io", "leberkleber", "+4915112345678"))
+	u2 := newUser("", "", "+4915187654321")
+	u2.DisplayEMail = ""
+	u2.Claims = nil
+	u2.Metadata = nil
+	createUser(t, s, u2)
+
+	for name, find := range map[string]func() (storage.User, error){
+		"User":           func() (storage.User, error) { return s.User(u.EMail) },
+		"UserByID":       func() (storage.User, error) { return s.UserByID(u.ID) },
+		"UserByUsername": func() (storage.User, error) { return s.UserByUsername(u.Username) },
+		"UserByPhone":    func() (storage.User, error) { return s.UserByPhone(u.Phone) },
+	} {
+		given, err := find()
+		if err != nil {
+			t.Fatalf("%s: failed to find user: %s", name, err)
+		}
+		expectUser(t, u, given)
+	}
+
+	given, err := s.UserByPhone(u2.Phone)
+	if err != nil {
+		t.Fatalf("failed to find user without email: %s", err)
+	}
+	expectUser(t, u2, given)
+
+	_, err = s.User("unknown@leberkleber.io")
+	expectError(t, storage.ErrUserNotFound, err)
+	_, err = s.UserByID(uuid.New().String())
+	expectError(t, storage.ErrUserNotFound, err)
+	_, err = s.UserByUsername("unknown")
+	expectError(t, storage.ErrUserNotFound, err)
+	_, err = s.UserByPhone("+4900000000")
+	expectError(t, storage.ErrUserNotFound, err)
+}
+
+func testUsersByClaims(t *testing.T, s Storage) {
+	admin := newUser("admin@leberkleber.io", "", "")
+	admin.ID = "00000000-0000-0000-0000-000000000001"
+	admin.Claims = map[string]interface{}{
+		"role":   "admin",
+		"groups": []interface{}{"dev", "ops"},
+		"org":    map[string]interface{}{"name": "acme", "tier": float64(1)},
+		"teams":  []interface{}{map[string]interface{}{"name": "qa", "lead": true}},
+	}
+	admin = createUser(t, s, admin)
+
+	dev := newUser("dev@leberkleber.io", "", "")
+	dev.ID = "00000000-0000-0000-0000-000000000002"
+	dev.Claims = map[string]interface{}{
+		"role":   "dev",
+		"groups": []interface{}{"dev"},
+		"org":    map[string]interface{}{"name": "acme"},
+		"beta":   true,
+	}
+	dev = createUser(t, s, dev)
+
+	noClaims := newUser("none@leberkleber.io", "", "")
+	noClaims.ID = "00000000-0000-0000-0000-000000000003"
+	noClaims.Claims = nil
+	noClaims = createUser(t, s, noClaims)
+
+	tests := []struct {
+		name            string
+		givenPredicates []storage.ClaimPredicate
+		givenLimit      int
+		givenOffset     int
+		expectedUsers   []storage.User
+		expectedTotal   int
+	}{
+		{
+			name:          "all users",
+			givenLimit:    10,
+			expectedUsers: []storage.User{admin, dev, noClaims},
+			expectedTotal: 3,
+		},
+		{
+			name:            "equals scalar",
+			givenPredicates: []storage.ClaimPredicate{{Claim: "role", Operator: storage.ClaimOperatorEquals, Value: "dev"}},
+			givenLimit:      10,
+			expectedUsers:   []storage.User{dev},
+			expectedTotal:   1,
+		},
+		{
+			name:            "equals object is no containment",
+			givenPredicates: []storage.ClaimPredicate{{Claim: "org", Operator: storage.ClaimOperatorEquals, Value: map[string]interface{}{"name": "acme"}}},
+			givenLimit:      10,
+			expectedUsers:   []storage.User{dev},
+			expectedTotal:   1,
+		},
+		{
+			name:            "equals array in order",
+			givenPredicates: []storage.ClaimPredicate{{Claim: "groups", Operator: storage.ClaimOperatorEquals, Value: []interface{}{"dev", "ops"}}},
+			givenLimit:      10,
+			expectedUsers:   []storage.User{admin},
+			expectedTotal:   1,
+		},
+		{
+			name:            "equals array in other order",
+			givenPredicates: []storage.ClaimPredicate{{Claim: "groups", Operator: storage.ClaimOperatorEquals, Value: []interface{}{"ops", "dev"}}},
+			givenLimit:      10,
+			expectedTotal:   0,
+		},
+		{
+			name:            "equals bool",
+			givenPredicates: []storage.ClaimPredicate{{Claim: "beta", Operator: storage.ClaimOperatorEquals, Value: true}},
+			givenLimit:      10,
+			expectedUsers:   []storage.User{dev},
+			expectedTotal:   1,
+		},
+		{
+			name:            "contains array elements",
+			givenPredicates: []storage.ClaimPredicate{{Claim: "groups", Operator: storage.ClaimOperatorContains, Value: []interface{}{"dev"}}},
+			givenLimit:      10,
+			expectedUsers:   []storage.User{admin, dev},
+			expectedTotal:   2,
+		},
+		{
+			name:            "contains object properties",
+			givenPredicates: []storage.ClaimPredicate{{Claim: "org", Operator: storage.ClaimOperatorContains, Value: map[string]interface{}{"tier": float64(1)}}},
+			givenLimit:      10,
+			expectedUsers:   []storage.User{admin},
+			expectedTotal:   1,
+		},
+		{
+			name:            "contains object in array",
+			givenPredicates: []storage.ClaimPredicate{{Claim: "teams", Operator: storage.ClaimOperatorContains, Value: []interface{}{map[string]interface{}{"name": "qa"}}}},
+			givenLimit:      10,
+			expectedUsers:   []storage.User{admin},
+			expectedTotal:   1,
+		},
+		{
+			name:            "number is no string",
+			givenPredicates: []storage.ClaimPredicate{{Claim: "org", Operator: storage.ClaimOperatorContains, Value: map[string]interface{}{"tier": "1"}}},
+			givenLimit:      10,
+			expectedTotal:   0,
+		},
+		{
+			name:            "scalar is not contained in array",
+			givenPredicates: []storage.ClaimPredicate{{Claim: "groups", Operator: storage.ClaimOperatorContains, Value: "ops"}},
+			givenLimit:      10,
+			expectedTotal:   0,
+		},
+		{
+			name:            "exists",
+			givenPredicates: []storage.ClaimPredicate{{Claim: "beta", Operator: storage.ClaimOperatorExists}},
+			givenLimit:      10,
+			expectedUsers:   []storage.User{dev},
+			expectedTotal:   1,
+		},
+		{
+			name: "all predicates",
+			givenPredicates: []storage.ClaimPredicate{
+				{Claim: "groups", Operator: storage.ClaimOperatorContains, Value: []interface{}{"dev"}},
+				{Claim: "role", Operator: storage.ClaimOperatorEquals, Value: "admin"},
+			},
+			givenLimit:    10,
+			expectedUsers: []storage.User{admin},
+			expectedTotal: 1,
+		},
+		{
+			name:            "page",
+			givenPredicates: []storage.ClaimPredicate{{Claim: "org", Operator: storage.ClaimOperatorExists}},
+			givenLimit:      1,
+			givenOffset:     1,
+			expectedUsers:   []storage.User{dev},
+			expectedTotal:   2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, total, err := s.UsersByClaims(tt.givenPredicates, tt.givenLimit, tt.givenOffset)
+			if err != nil {
+				t.Fatalf("failed to find users: %s", err)
+			}
+
+			expectEqual(t, "total", tt.expectedTotal, total)
+			expectEqual(t, "count of users", len(tt.expectedUsers), len(users))
+			for i := range users {
+				expectUser(t, tt.expectedUsers[i], users[i])
+			}
+		})
+	}
+
+	_, _, err := s.UsersByClaims([]storage.ClaimPredicate{{Claim: "role", Operator: "like"}}, 10, 0)
+	if fmt.Sprint(err) != `unknown claim operator "like"` {
+		t.Fatalf("unexpected error. Expected:\n%q\nGiven:\n%q", `unknown claim operator "like"`, err)
+	}
+}
+
+func testCreateUserDuplicates(t *testing.T, s Storage) {
+	createUser(t, s, newUser("info@leberkleber.io", "leberkleber", "+4915112345678"))
+
+	// users without login identifiers must not collide with each other
+	createUser(t, s, newUser("other@leberkleber.io", "", ""))
+	createUser(t, s, newUser("another@leberkleber.io", "", ""))
+
+	for _, u := range []storage.User{
+		newUser("info@leberkleber.io", "", ""),
+		newUser("new@leberkleber.io", "leberkleber", ""),
+		newUser("new@leberkleber.io", "", "+4915112345678"),
+	} {
+		err := s.CreateUser(u)
+		expectError(t, storage.ErrUserAlreadyExists, err)
+	}
+}
+
+func testUpdateUser(t *testing.T, s Storage) {
+	u := createUser(t, s, newUser("info@leberkleber.io", "leberkleber", ""))
+	createUser(t, s, newUser("other@leberkleber.io", "other", "+4915112345678"))
+
+	u.Password = []byte("newPassword")
+	u.Claims = map[string]interface{}{"role": "user"}
+	u.Metadata = map[string]interface{}{"plan": "free"}
+	u.PasswordChangedAt = now.Add(time.Hour)
+	u.PasswordMaxAgeDays = 30
+	u.Username = ""
+	u.Phone = "+4915187654321"
+	err := s.UpdateUser(u)
+	if err != nil {
+		t.Fatalf("failed to update user: %s", err)
+	}
+
+	given, err := s.UserByID(u.ID)
+	if err != nil {
+		t.Fatalf("failed to find user: %s", err)
+	}
+	expectUser(t, u, given)
+
+	u.Username = "other"
+	err = s.UpdateUser(u)
+	expectError(t, storage.ErrUserAlreadyExists, err)
+
+	u.Username = ""
+	u.Phone = "+4915112345678"
+	err = s.UpdateUser(u)
+	expectError(t, storage.ErrUserAlreadyExists, err)
+
+	err = s.UpdateUser(newUser("unknown@leberkleber.io", "", ""))
+	expectError(t, storage.ErrUserNotFound, err)
+}
+
+func testPatchUser(t *testing.T, s Storage) {
+	u := createUser(t, s, newUser("info@leberkleber.io", "", ""))
+	createUser(t, s, newUser("other@leberkleber.io", "other", ""))
+
+	err := s.PatchUser(u.ID, func(user *storage.User) error {
+		user.Claims = map[string]interface{}{"role": "user"}
+		user.Username = "leberkleber"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to patch user: %s", err)
+	}
+
+	u.Claims = map[string]interface{}{"role": "user"}
+	u.Username = "leberkleber"
+	given, err := s.UserByID(u.ID)
+	if err != nil {
+		t.Fatalf("failed to find user: %s", err)
+	}
+	expectUser(t, u, given)
+
+	patchErr := errors.New("patch error")
+	err = s.PatchUser(u.ID, func(user *storage.User) error {
+		user.Username = "ignored"
+		return patchErr
+	})
+	if err != patchErr {
+		t.Fatalf("unexpected error. Expected:\n%q\nGiven:\n%q", patchErr, err)
+	}
+
+	err = s.PatchUser(u.ID, func(user *storage.User) error {
+		user.Username = "other"
+		return nil
+	})
+	expectError(t, storage.ErrUserAlreadyExists, err)
+
+	given, err = s.UserByID(u.ID)
+	if err != nil {
+		t.Fatalf("failed to find user: %s", err)
+	}
+	expectUser(t, u, given)
+
+	err = s.PatchUser(uuid.New().String(), func(user *storage.User) error {
+		t.Fatal("patch must not be called for unknown users")
+		return nil
+	})
+	expectError(t, storage.ErrUserNotFound, err)
+}
+
+func testDeleteUser(t *testing.T, s Storage) {
+	u := createUser(t, s, newUser("info@leberkleber.io", "", ""))
+	_, err := s.CreateToken(storage.Token{UserID: u.ID, Token: "token", Type: storage.TokenTypeReset, CreatedAt: now})
+	if err != nil {
+		t.Fatalf("failed to create token: %s", err)
+	}
+
+	err = s.DeleteUser(u.ID)
+	if err != nil {
+		t.Fatalf("failed to delete user: %s", err)
+	}
+
+	_, err = s.UserByID(u.ID)
+	expectError(t, storage.ErrUserNotFound, err)
+
+	err = s.DeleteUser(u.ID)
+	expectError(t, storage.ErrUserNotFound, err)
+
+	// the email can be used again
+	createUser(t, s, newUser("info@leberkleber.io", "", ""))
+}
+
+func testNormalizeUserEMail(t *testing.T, s Storage) {
+	// users created before the normalization have no display email
+	u := newUser(" Info@LeberKleber.IO", "", "")
+	u.DisplayEMail = ""
+	createUser(t, s, u)
+	u2 := createUser(t, s, newUser("other@leberkleber.io", "", ""))
+	createUser(t, s, newUser("", "leberkleber", ""))
+
+	emails, err := s.UserEMails()
+	expectError(t, nil, err)
+	expectEqual(t, "emails", map[string]string{u.ID: " Info@LeberKleber.IO", u2.ID: "other@leberkleber.io"}, emails)
+
+	err = s.NormalizeUserEMail(u.ID, "Info@leberkleber.io")
+	expectError(t, nil, err)
+
+	normalized, err := s.User("Info@leberkleber.io")
+	expectError(t, nil, err)
+	expectEqual(t, "display email", " Info@LeberKleber.IO", normalized.DisplayEMail)
+
+	err = s.NormalizeUserEMail(u2.ID, "Info@leberkleber.io")
+	expectError(t, storage.ErrUserAlreadyExists, err)
+
+	err = s.NormalizeUserEMail(uuid.New().String(), "new@leberkleber.io")
+	expectError(t, storage.ErrUserNotFound, err)
+}
+
+func testInvitations(t *testing.T, s Storage) {
+	invited := newUser("invited@leberkleber.io", "", "")
+	invited.Password = nil
+	createUser(t, s, invited)
+	reinvited := newUser("reinvited@leberkleber.io", "", "")
+	reinvited.Password = nil
+	createUser(t, s, reinvited)
+	notInvited := newUser("not-invited@leberkleber.io", "", "")
+	notInvited.Password = nil
+	createUser(t, s, notInvited)
+	accepted := createUser(t, s, newUser("accepted@leberkleber.io", "", ""))
+
+	for _, u := range []storage.User{invited, reinvited, accepted} {
+		err := s.MarkUserInvited(u.ID, now)
+		if err != nil {
+			t.Fatalf("failed to mark user invited: %s", err)
+		}
+	}
+	err := s.MarkUserInvited(reinvited.ID, now.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("failed to mark user invited: %s", err)
+	}
+	_, err = s.CreateToken(storage.Token{UserID: invited.ID, Token: "token", Type: storage.TokenTypeInvitation, CreatedAt: now})
+	if err != nil {
+		t.Fatalf("failed to create token: %s", err)
+	}
+
+	err = s.MarkUserInvited(uuid.New().String(), now)
+	expectError(t, storage.ErrUserNotFound, err)
+
+	deleted, err := s.DeleteUnverifiedUsers(now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("failed to delete unverified users: %s", err)
+	}
+	expectEqual(t, "count of deleted users", int64(1), deleted)
+
+	_, err = s.UserByID(invited.ID)
+	expectError(t, storage.ErrUserNotFound, err)
+	for _, u := range []storage.User{reinvited, notInvited, accepted} {
+		_, err = s.UserByID(u.ID)
+		if err != nil {
+			t.Fatalf("user %q must not be deleted: %s", u.EMail, err)
+		}
+	}
+
+	deleted, err = s.DeleteUnverifiedUsers(now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("failed to delete unverified users: %s", err)
+	}
+	expectEqual(t, "count of deleted users", int64(0), deleted)
+}
+
+func testTokens(t *testing.T, s Storage) {
+	u := createUser(t, s, newUser("info@leberkleber.io", "", ""))
+
+	oldID, err := s.CreateToken(storage.Token{UserID: u.ID, Token: "old", Type: storage.TokenTypeReset, CreatedAt: now})
+	if err != nil {
+		t.Fatalf("failed to create token: %s", err)
+	}
+	newID, err := s.CreateToken(storage.Token{
+		UserID:    u.ID,
+		Token:     "new",
+		Type:      storage.TokenTypeReset,
+		CreatedAt: now.Add(time.Minute),
+		Metadata:  map[string]interface{}{"ip": "127.0.0.1"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create token: %s", err)
+	}
+	_, err = s.CreateToken(storage.Token{UserID: u.ID, Token: "code", Type: storage.TokenTypeLoginCode, CreatedAt: now})
+	if err != nil {
+		t.Fatalf("failed to create token: %s", err)
+	}
+
+	attempts, err := s.IncrementTokenAttempts(newID)
+	if err != nil {
+		t.Fatalf("failed to increment token attempts: %s", err)
+	}
+	expectEqual(t, "attempts", 1, attempts)
+	attempts, err = s.IncrementTokenAttempts(newID)
+	if err != nil {
+		t.Fatalf("failed to increment token attempts: %s", err)
+	}
+	expectEqual(t, "attempts", 2, attempts)
+
+	_, err = s.IncrementTokenAttempts(newID + oldID + 4711)
+	expectError(t, storage.ErrTokenNotFound, err)
+
+	tokens, err := s.TokensByUserIDAndType(u.ID, storage.TokenTypeReset)
+	if err != nil {
+		t.Fatalf("failed to find tokens: %s", err)
+	}
+	if len(tokens) != 2 {
+		t.Fatalf("unexpected count of tokens. Expected: 2, Given: %d", len(tokens))
+	}
+	expectTime(t, "token created at", now.Add(time.Minute), tokens[0].CreatedAt)
+	expectTime(t, "token created at", now, tokens[1].CreatedAt)
+	tokens[0].CreatedAt, tokens[1].CreatedAt = time.Time{}, time.Time{}
+	expectEqual(t, "tokens", []storage.Token{
+		{ID: newID, UserID: u.ID, Token: "new", Type: storage.TokenTypeReset, Attempts: 2, Metadata: map[string]interface{}{"ip": "127.0.0.1"}},
+		{ID: oldID, UserID: u.ID, Token: "old", Type: storage.TokenTypeReset},
+	}, tokens)
+
+	tokens, err = s.TokensByUserIDAndType(u.ID, storage.TokenTypeMFA)
+	if err != nil {
+		t.Fatalf("failed to find tokens: %s", err)
+	}
+	expectEqual(t, "count of tokens", 0, len(tokens))
+
+	err = s.DeleteToken(oldID)
+	if err != nil {
+		t.Fatalf("failed to delete token: %s", err)
+	}
+	err = s.DeleteToken(oldID)
+	expectError(t, storage.ErrTokenNotFound, err)
+
+	deleted, err := s.DeleteTokensCreatedBefore(storage.TokenTypeReset, now.Add(2*time.Minute))
+	if err != nil {
+		t.Fatalf("failed to delete tokens: %s", err)
+	}
+	expectEqual(t, "count of deleted tokens", int64(1), deleted)
+
+	tokens, err = s.TokensByUserIDAndType(u.ID, storage.TokenTypeLoginCode)
+	if err != nil {
+		t.Fatalf("failed to find tokens: %s", err)
+	}
+	expectEqual(t, "count of tokens", 1, len(tokens))
+}
+
+func testPasswordHistory(t *testing.T, s Storage) {
+	u := createUser(t, s, newUser("info@leberkleber.io", "", ""))
+
+	for i := 1; i <= 4; i++ {
+		err := s.AddPasswordHistory(u.ID, []byte(fmt.Sprintf("password%d", i)), now.Add(time.Duration(i)*time.Minute), 3)
+		if err != nil {
+			t.Fatalf("failed to add password history: %s", err)
+		}
+	}
+
+	passwords, err := s.PasswordHistory(u.ID, 10)
+	if err != nil {
+		t.Fatalf("failed to find password history: %s", err)
+	}
+	expectEqual(t, "password history", [][]byte{[]byte("password4"), []byte("password3"), []byte("password2")}, passwords)
+
+	passwords, err = s.PasswordHistory(u.ID, 1)
+	if err != nil {
+		t.Fatalf("failed to find password history: %s", err)
+	}
+	expectEqual(t, "password history", [][]byte{[]byte("password4")}, passwords)
+}
+
+func testPasswordExpiry(t *testing.T, s Storage) {
+	expiring := newUser("expiring@leberkleber.io", "", "")
+	expiring.PasswordChangedAt = now.AddDate(0, 0, -85)
+	createUser(t, s, expiring)
+
+	notExpiring := newUser("not-expiring@leberkleber.io", "", "")
+	notExpiring.PasswordChangedAt = now.AddDate(0, 0, -80)
+	createUser(t, s, notExpiring)
+
+	ownMaxAge := newUser("own-max-age@leberkleber.io", "", "")
+	ownMaxAge.PasswordChangedAt = now.AddDate(0, 0, -25)
+	ownMaxAge.PasswordMaxAgeDays = 30
+	createUser(t, s, ownMaxAge)
+
+	reminded := newUser("reminded@leberkleber.io", "", "")
+	reminded.PasswordChangedAt = now.AddDate(0, 0, -85)
+	createUser(t, s, reminded)
+	err := s.MarkPasswordExpiryReminded(reminded.ID, now.AddDate(0, 0, -1))
+	if err != nil {
+		t.Fatalf("failed to mark password expiry reminded: %s", err)
+	}
+
+	withoutEMail := newUser("", "without-email", "")
+	withoutEMail.PasswordChangedAt = now.AddDate(0, 0, -85)
+	createUser(t, s, withoutEMail)
+
+	invited := newUser("invited@leberkleber.io", "", "")
+	invited.Password = nil
+	invited.PasswordChangedAt = now.AddDate(0, 0, -85)
+	createUser(t, s, invited)
+	err = s.MarkUserInvited(invited.ID, now.AddDate(0, 0, -85))
+	if err != nil {
+		t.Fatalf("failed to mark user invited: %s", err)
+	}
+
+	err = s.MarkPasswordExpiryReminded(uuid.New().String(), now)
+	expectError(t, storage.ErrUserNotFound, err)
+
+	users, err := s.UsersToRemindOfPasswordExpiry(90, 7, now)
+	if err != nil {
+		t.Fatalf("failed to find users to remind: %s", err)
+	}
+	emails := map[string]bool{}
+	for _, u := range users {
+		emails[u.EMail] = true
+	}
+	expectEqual(t, "users to remind", map[string]bool{"expiring@leberkleber.io": true, "own-max-age@leberkleber.io": true}, emails)
+
+	users, err = s.UsersToRemindOfPasswordExpiry(0, 7, now)
+	if err != nil {
+		t.Fatalf("failed to find users to remind: %s", err)
+	}
+	if len(users) != 1 || users[0].ID != ownMaxAge.ID {
+		t.Fatalf("unexpected users to remind without global max age: %#v", users)
+	}
+	expectEqual(t, "claims", ownMaxAge.Claims, users[0].Claims)
+	expectEqual(t, "metadata", ownMaxAge.Metadata, users[0].Metadata)
+}
+
+func testTOTP(t *testing.T, s Storage) {
+	u := createUser(t, s, newUser("info@leberkleber.io", "", ""))
+
+	_, err := s.TOTP(u.ID)
+	expectError(t, storage.ErrTOTPNotFound, err)
+
+	totp := storage.TOTP{UserID: u.ID, Secret: []byte("secret"), CreatedAt: now}
+	err = s.SaveTOTP(totp)
+	if err != nil {
+		t.Fatalf("failed to save totp: %s", err)
+	}
+
+	totp.Confirmed = true
+	totp.LastUsedStep = 4711
+	err = s.SaveTOTP(totp)
+	if err != nil {
+		t.Fatalf("failed to save totp: %s", err)
+	}
+
+	given, err := s.TOTP(u.ID)
+	if err != nil {
+		t.Fatalf("failed to find totp: %s", err)
+	}
+	expectTime(t, "totp created at", totp.CreatedAt, given.CreatedAt)
+	given.CreatedAt = totp.CreatedAt
+	expectEqual(t, "totp", totp, given)
+}
+
+func testWebAuthn(t *testing.T, s Storage) {
+	u := createUser(t, s, newUser("info@leberkleber.io", "", ""))
+
+	credentials, err := s.WebAuthnCredentials(u.ID)
+	if err != nil {
+		t.Fatalf("failed to find webauthn credentials: %s", err)
+	}
+	expectEqual(t, "count of webauthn credentials", 0, len(credentials))
+
+	err = s.CreateWebAuthnCredential(storage.WebAuthnCredential{ID: []byte{1, 2}, UserID: u.ID, PublicKey: []byte("key1"), SignCount: 1, CreatedAt: now})
+	if err != nil {
+		t.Fatalf("failed to create webauthn credential: %s", err)
+	}
+	err = s.CreateWebAuthnCredential(storage.WebAuthnCredential{ID: []byte{3, 4}, UserID: u.ID, PublicKey: []byte("key2"), CreatedAt: now.Add(time.Minute)})
+	if err != nil {
+		t.Fatalf("failed to create webauthn credential: %s", err)
+	}
+	err = s.CreateWebAuthnCredential(storage.WebAuthnCredential{ID: []byte{1, 2}, UserID: u.ID, PublicKey: []byte("key3"), CreatedAt: now})
+	expectError(t, storage.ErrWebAuthnCredentialAlreadyExists, err)
+
+	err = s.UpdateWebAuthnCredentialUsage([]byte{1, 2}, 5, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("failed to update webauthn credential usage: %s", err)
+	}
+	err = s.UpdateWebAuthnCredentialUsage([]byte{5, 6}, 5, now.Add(time.Hour))
+	expectError(t, storage.ErrWebAuthnCredentialNotFound, err)
+
+	credentials, err = s.WebAuthnCredentials(u.ID)
+	if err != nil {
+		t.Fatalf("failed to find webauthn credentials: %s", err)
+	}
+	if len(credentials) != 2 {
+		t.Fatalf("unexpected count of webauthn credentials. Expected: 2, Given: %d", len(credentials))
+	}
+	if credentials[0].LastUsedAt == nil || !credentials[0].LastUsedAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("unexpected last used at: %v", credentials[0].LastUsedAt)
+	}
+	expectEqual(t, "last used at", (*time.Time)(nil), credentials[1].LastUsedAt)
+	expectEqual(t, "credential ids", [][]byte{{1, 2}, {3, 4}}, [][]byte{credentials[0].ID, credentials[1].ID})
+	expectEqual(t, "public key", []byte("key1"), credentials[0].PublicKey)
+	expectEqual(t, "sign count", uint32(5), credentials[0].SignCount)
+}
+
+func testRecoveryCodes(t *testing.T, s Storage) {
+	u := createUser(t, s, newUser("info@leberkleber.io", "", ""))
+
+	err := s.ReplaceRecoveryCodes(u.ID, [][]byte{[]byte("old")}, now)
+	if err != nil {
+		t.Fatalf("failed to replace recovery codes: %s", err)
+	}
+	err = s.ReplaceRecoveryCodes(u.ID, [][]byte{[]byte("code1"), []byte("code2")}, now)
+	if err != nil {
+		t.Fatalf("failed to replace recovery codes: %s", err)
+	}
+
+	count, err := s.UnusedRecoveryCodeCount(u.ID)
+	if err != nil {
+		t.Fatalf("failed to count recovery codes: %s", err)
+	}
+	expectEqual(t, "count of unused recovery codes", 2, count)
+
+	err = s.UseRecoveryCode(u.ID, []byte("old"), now)
+	expectError(t, storage.ErrRecoveryCodeNotFound, err)
+	err = s.UseRecoveryCode(u.ID, []byte("code1"), now)
+	if err != nil {
+		t.Fatalf("failed to use recovery code: %s", err)
+	}
+	err = s.UseRecoveryCode(u.ID, []byte("code1"), now)
+	expectError(t, storage.ErrRecoveryCodeNotFound, err)
+
+	count, err = s.UnusedRecoveryCodeCount(u.ID)
+	if err != nil {
+		t.Fatalf("failed to count recovery codes: %s", err)
+	}
+	expectEqual(t, "count of unused recovery codes", 1, count)
+
+	err = s.SaveTOTP(storage.TOTP{UserID: u.ID, Secret: []byte("secret"), CreatedAt: now})
+	if err != nil {
+		t.Fatalf("failed to save totp: %s", err)
+	}
+	err = s.CreateWebAuthnCredential(storage.WebAuthnCredential{ID: []byte{1}, UserID: u.ID, PublicKey: []byte("key"), CreatedAt: now})
+	if err != nil {
+		t.Fatalf("failed to create webauthn credential: %s", err)
+	}
+
+	err = s.DeleteMFA(u.ID)
+	if err != nil {
+		t.Fatalf("failed to delete mfa: %s", err)
+	}
+
+	_, err = s.TOTP(u.ID)
+	expectError(t, storage.ErrTOTPNotFound, err)
+	credentials, err := s.WebAuthnCredentials(u.ID)
+	if err != nil {
+		t.Fatalf("failed to find webauthn credentials: %s", err)
+	}
+	expectEqual(t, "count of webauthn credentials", 0, len(credentials))
+	count, err = s.UnusedRecoveryCodeCount(u.ID)
+	if err != nil {
+		t.Fatalf("failed to count recovery codes: %s", err)
+	}
+	expectEqual(t, "count of unused recovery codes", 0, count)
+}
+
+func testLogins(t *testing.T, s Storage) {
+	u := createUser(t, s, newUser("info@leberkleber.io", "", ""))
+	other := createUser(t, s, newUser("other@leberkleber.io", "", ""))
+
+	logins := []storage.Login{
+		{UserID: u.ID, CreatedAt: now, IP: "127.0.0.1", UserAgent: "curl", Outcome: storage.LoginOutcomeSuccess, Factor: "password"},
+		{UserID: u.ID, CreatedAt: now.Add(time.Minute), IP: "127.0.0.2", Outcome: storage.LoginOutcomeFailed, Factor: "password"},
+		{UserID: u.ID, CreatedAt: now.Add(2 * time.Minute), Outcome: storage.LoginOutcomeMFARequired, Factor: "password"},
+		{UserID: other.ID, CreatedAt: now, Outcome: storage.LoginOutcomeSuccess, Factor: "password"},
+	}
+	for _, l := range logins {
+		err := s.AddLogin(l)
+		if err != nil {
+			t.Fatalf("failed to add login: %s", err)
+		}
+	}
+
+	given, err := s.UserByID(u.ID)
+	if err != nil {
+		t.Fatalf("failed to find user: %s", err)
+	}
+	if given.LastLoginAt == nil || !given.LastLoginAt.Equal(now) {
+		t.Fatalf("unexpected last login at: %v", given.LastLoginAt)
+	}
+
+	page, total, err := s.Logins(u.ID, 2, 1)
+	if err != nil {
+		t.Fatalf("failed to find logins: %s", err)
+	}
+	expectEqual(t, "total count of logins", 3, total)
+	if len(page) != 2 {
+		t.Fatalf("unexpected count of logins. Expected: 2, Given: %d", len(page))
+	}
+	expectTime(t, "login created at", now.Add(time.Minute), page[0].CreatedAt)
+	expectTime(t, "login created at", now, page[1].CreatedAt)
+	expectEqual(t, "login", []string{"127.0.0.2", "", storage.LoginOutcomeFailed}, []string{page[0].IP, page[0].UserAgent, page[0].Outcome})
+	expectEqual(t, "login", []string{u.ID, "127.0.0.1", "curl", storage.LoginOutcomeSuccess, "password"},
+		[]string{page[1].UserID, page[1].IP, page[1].UserAgent, page[1].Outcome, page[1].Factor})
+
+	// the retention removes the logins of all users
+	deleted, err := s.DeleteLoginsCreatedBefore(now.Add(90 * time.Second))
+	if err != nil {
+		t.Fatalf("failed to delete logins: %s", err)
+	}
+	expectEqual(t, "count of deleted logins", int64(3), deleted)
+
+	_, total, err = s.Logins(u.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to find logins: %s", err)
+	}
+	expectEqual(t, "total count of logins", 1, total)
+	_, total, err = s.Logins(other.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to find logins: %s", err)
+	}
+	expectEqual(t, "total count of logins", 0, total)
+}
+
+func testUserData(t *testing.T, s Storage) {
+	u := createUser(t, s, newUser("info@leberkleber.io", "", ""))
+	other := createUser(t, s, newUser("other@leberkleber.io", "", ""))
+
+	for _, user := range []storage.User{u, other} {
+		_, err := s.CreateToken(storage.Token{UserID: user.ID, Token: "token", Type: storage.TokenTypeReset, CreatedAt: now})
+		if err != nil {
+			t.Fatalf("failed to create token: %s", err)
+		}
+	}
+	err := s.AddPasswordHistory(u.ID, []byte("password"), now, 5)
+	if err != nil {
+		t.Fatalf("failed to add password history: %s", err)
+	}
+	err = s.SaveTOTP(storage.TOTP{UserID: u.ID, Secret: []byte("secret"), Confirmed: true, CreatedAt: now})
+	if err != nil {
+		t.Fatalf("failed to save totp: %s", err)
+	}
+	err = s.CreateWebAuthnCredential(storage.WebAuthnCredential{ID: []byte{1}, UserID: u.ID, PublicKey: []byte("key"), SignCount: 3, CreatedAt: now})
+	if err != nil {
+		t.Fatalf("failed to create webauthn credential: %s", err)
+	}
+	err = s.ReplaceRecoveryCodes(u.ID, [][]byte{[]byte("code1"), []byte("code2")}, now)
+	if err != nil {
+		t.Fatalf("failed to replace recovery codes: %s", err)
+	}
+	err = s.UseRecoveryCode(u.ID, []byte("code2"), now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("failed to use recovery code: %s", err)
+	}
+	err = s.AddLogin(storage.Login{UserID: u.ID, CreatedAt: now, IP: "127.0.0.1", Outcome: storage.LoginOutcomeSuccess, Factor: "password"})
+	if err != nil {
+		t.Fatalf("failed to add login: %s", err)
+	}
+
+	data, err := s.UserData(u.ID)
+	if err != nil {
+		t.Fatalf("failed to find user data: %s", err)
+	}
+	expectEqual(t, "user id", u.ID, data.User.ID)
+	expectEqual(t, "tokens", 1, len(data.Tokens))
+	expectEqual(t, "token type", storage.TokenTypeReset, data.Tokens[0].Type)
+	expectTime(t, "token created at", now, data.Tokens[0].CreatedAt)
+	expectEqual(t, "password history", 1, len(data.PasswordHistory))
+	expectTime(t, "password history created at", now, data.PasswordHistory[0])
+	if data.TOTP == nil || !data.TOTP.Confirmed {
+		t.Fatalf("unexpected totp: %#v", data.TOTP)
+	}
+	expectEqual(t, "webauthn credentials", 1, len(data.WebAuthnCredentials))
+	expectEqual(t, "webauthn credential", []interface{}{[]byte{1}, uint32(3)}, []interface{}{data.WebAuthnCredentials[0].ID, data.WebAuthnCredentials[0].SignCount})
+	expectEqual(t, "recovery codes", 2, len(data.RecoveryCodes))
+	expectEqual(t, "first recovery code used at", (*time.Time)(nil), data.RecoveryCodes[0].UsedAt)
+	if data.RecoveryCodes[1].UsedAt == nil || !data.RecoveryCodes[1].UsedAt.Equal(now.Add(time.Minute)) {
+		t.Fatalf("unexpected second recovery code used at: %v", data.RecoveryCodes[1].UsedAt)
+	}
+	expectEqual(t, "logins", 1, len(data.Logins))
+	expectEqual(t, "login ip", "127.0.0.1", data.Logins[0].IP)
+
+	_, err = s.UserData(uuid.New().String())
+	expectError(t, storage.ErrUserNotFound, err)
+
+	deletedRows, err := s.EraseUser(u.ID)
+	if err != nil {
+		t.Fatalf("failed to erase user: %s", err)
+	}
+	expectEqual(t, "deleted rows", map[string]int64{
+		"tokens":               1,
+		"password_history":     1,
+		"user_totp":            1,
+		"webauthn_credentials": 1,
+		"mfa_recovery_codes":   2,
+		"logins":               1,
+		"users":                1,
+	}, deletedRows)
+
+	_, err = s.EraseUser(u.ID)
+	expectError(t, storage.ErrUserNotFound, err)
+
+	tokens, err := s.TokensByUserIDAndType(other.ID, storage.TokenTypeReset)
+	if err != nil {
+		t.Fatalf("failed to find tokens: %s", err)
+	}
+	expectEqual(t, "count of tokens of other user", 1, len(tokens))
+}
+
+func testRealms(t *testing.T, s Storage) {
+	realms, err := s.Realms()
+	if err != nil {
+		t.Fatalf("failed to find realms: %s", err)
+	}
+	expectEqual(t, "count of realms", 0, len(realms))
+
+	r := storage.Realm{
+		Name:          "tenant-b",
+		Hosts:         []string{"b.example.com", "a.example.com"},
+		JWTPrivateKey: "privateKey",
+		JWTIssuer:     "issuer",
+		JWTAudience:   "audience",
+		CreatedAt:     now,
+	}
+	err = s.CreateRealm(r)
+	if err != nil {
+		t.Fatalf("failed to create realm: %s", err)
+	}
+	err = s.CreateRealm(storage.Realm{Name: "tenant-a", JWTPrivateKey: "otherKey", CreatedAt: now})
+	if err != nil {
+		t.Fatalf("failed to create realm: %s", err)
+	}
+
+	err = s.CreateRealm(storage.Realm{Name: "tenant-b", JWTPrivateKey: "key", CreatedAt: now})
+	expectError(t, storage.ErrRealmAlreadyExists, err)
+	err = s.CreateRealm(storage.Realm{Name: "tenant-c", Hosts: []string{"a.example.com"}, JWTPrivateKey: "key", CreatedAt: now})
+	expectError(t, storage.ErrRealmHostAlreadyUsed, err)
+	_, err = s.Realm("tenant-c")
+	expectError(t, storage.ErrRealmNotFound, err)
+
+	given, err := s.Realm("tenant-b")
+	if err != nil {
+		t.Fatalf("failed to find realm: %s", err)
+	}
+	expectTime(t, "realm created at", now, given.CreatedAt)
+	given.CreatedAt = now
+	r.Hosts = []string{"a.example.com", "b.example.com"}
+	expectEqual(t, "realm", r, given)
+
+	realms, err = s.Realms()
+	if err != nil {
+		t.Fatalf("failed to find realms: %s", err)
+	}
+	if len(realms) != 2 {
+		t.Fatalf("unexpected count of realms. Expected: 2, Given: %d", len(realms))
+	}
+	expectEqual(t, "realm names", []string{"tenant-a", "tenant-b"}, []string{realms[0].Name, realms[1].Name})
+	if len(realms[0].Hosts) != 0 {
+		t.Fatalf("unexpected hosts of realm without hosts: %#v", realms[0].Hosts)
+	}
+
+	r.Hosts = []string{"c.example.com"}
+	r.JWTPrivateKey = "newKey"
+	r.JWTIssuer = ""
+	err = s.UpdateRealm(r)
+	if err != nil {
+		t.Fatalf("failed to update realm: %s", err)
+	}
+	given, err = s.Realm("tenant-b")
+	if err != nil {
+		t.Fatalf("failed to find realm: %s", err)
+	}
+	given.CreatedAt = now
+	expectEqual(t, "realm", r, given)
+
+	err = s.UpdateRealm(storage.Realm{Name: "tenant-a", Hosts: []string{"c.example.com"}, JWTPrivateKey: "key"})
+	expectError(t, storage.ErrRealmHostAlreadyUsed, err)
+	err = s.UpdateRealm(storage.Realm{Name: "tenant-c", JWTPrivateKey: "key"})
+	expectError(t, storage.ErrRealmNotFound, err)
+}
+
+func testTryLock(t *testing.T, s Storage) {
+	name := "storagetest-" + uuid.New().String()
+
+	unlock, ok, err := s.TryLock(name)
+	if err != nil || !ok {
+		t.Fatalf("failed to acquire lock. ok: %t, err: %v", ok, err)
+	}
+
+	_, ok, err = s.TryLock(name)
+	if err != nil || ok {
+		t.Fatalf("lock must not be acquired twice. ok: %t, err: %v", ok, err)
+	}
+
+	otherUnlock, ok, err := s.TryLock(name + "-other")
+	if err != nil || !ok {
+		t.Fatalf("failed to acquire other lock. ok: %t, err: %v", ok, err)
+	}
+	_ = otherUnlock()
+
+	err = unlock()
+	if err != nil {
+		t.Fatalf("failed to unlock: %s", err)
+	}
+
+	unlock, ok, err = s.TryLock(name)
+	if err != nil || !ok {
+		t.Fatalf("failed to acquire lock again. ok: %t, err: %v", ok, err)
+	}
+	_ = unlock()
+}
@@ -0,0 +1,50 @@
+package storage_test
+
+import (
+	"github.com/google/uuid"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage/storagetest"
+	"os"
+	"strconv"
+	"testing"
+
+	// database migration
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	// sql driver
+	_ "github.com/lib/pq"
+)
+
+// TestStorage runs the behavioral tests of all storage backends against the postgres database configured by
+// SJP_TEST_DB_HOST, SJP_TEST_DB_PORT, SJP_TEST_DB_USERNAME, SJP_TEST_DB_PASSWORD and SJP_TEST_DB_NAME. Each test uses
+// its own realm schema. Skipped when no host has been configured.
+func TestStorage(t *testing.T) {
+	host := os.Getenv("SJP_TEST_DB_HOST")
+	if host == "" {
+		t.Skip("SJP_TEST_DB_HOST is not set")
+	}
+
+	port, err := strconv.Atoi(os.Getenv("SJP_TEST_DB_PORT"))
+	if err != nil {
+		port = 5432
+	}
+
+	s, err := storage.New(host, port, os.Getenv("SJP_TEST_DB_USERNAME"), os.Getenv("SJP_TEST_DB_PASSWORD"), os.Getenv("SJP_TEST_DB_NAME"), false)
+	if err != nil {
+		t.Fatalf("failed to create storage: %s", err)
+	}
+	defer func() { _ = s.Close() }()
+
+	storagetest.Run(t, func(t *testing.T) storagetest.Storage {
+		realmStorage, err := s.RealmStorage("storagetest-" + uuid.New().String()[:8])
+		if err != nil {
+			t.Fatalf("failed to create realm storage: %s", err)
+		}
+
+		err = realmStorage.Migrate("../../db-migrations")
+		if err != nil {
+			t.Fatalf("failed to migrate realm storage: %s", err)
+		}
+
+		return realmStorage
+	})
+}
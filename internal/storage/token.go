@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -9,21 +11,41 @@ import (
 var ErrTokenNotFound = errors.New("no token has been deleted")
 
 const TokenTypeReset string = "reset"
+const TokenTypeMFA string = "mfa"
+const TokenTypeWebAuthnRegistration string = "webauthn-registration"
+const TokenTypeWebAuthnLogin string = "webauthn-login"
+const TokenTypeMagicLink string = "magic-link"
+const TokenTypeLoginCode string = "login-code"
+const TokenTypeInvitation string = "invitation"
 
 type Token struct {
 	ID        int64
-	EMail     string
+	UserID    string
 	Token     string
 	Type      string
 	CreatedAt time.Time
+	// Attempts is the count of redemption attempts. It is only maintained for tokens with an attempt limit
+	Attempts int
+	// Metadata is an optional payload which will be stored along with the token
+	Metadata map[string]interface{}
 }
 
-// CreateToken persists the given token in database. EMail must match to a users email.
+// CreateToken persists the given token in database. UserID must match to a users id. Tokens without metadata will be
+// stored with NULL metadata.
 func (s Storage) CreateToken(t Token) (int64, error) {
+	var rawMetadata []byte
+	if t.Metadata != nil {
+		var err error
+		rawMetadata, err = json.Marshal(t.Metadata)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal token>metadata: %w", err)
+		}
+	}
+
 	var id int64
 	err := s.db.QueryRow(
-		"INSERT INTO tokens (email, token, type, created_at) VALUES($1, $2, $3, $4) RETURNING id;",
-		t.EMail, t.Token, t.Type, t.CreatedAt,
+		"INSERT INTO tokens (user_id, token, type, created_at, metadata) VALUES($1, $2, $3, $4, $5) RETURNING id;",
+		t.UserID, t.Token, t.Type, t.CreatedAt, rawMetadata,
 	).Scan(&id)
 	if err != nil {
 		return 0, fmt.Errorf("failed to exec stmt: %w", err)
@@ -32,9 +54,12 @@ func (s Storage) CreateToken(t Token) (int64, error) {
 	return id, nil
 }
 
-// TokensByEMailAndToken finds all tokens which matches the given email and token.
-func (s Storage) TokensByEMailAndToken(email, token string) ([]Token, error) {
-	rows, err := s.db.Query("SELECT id, type, created_at FROM tokens WHERE email = $1 AND token = $2;", email, token)
+// TokensByUserIDAndType finds all tokens of the given type which belong to the user with the given id.
+func (s Storage) TokensByUserIDAndType(userID, tokenType string) ([]Token, error) {
+	rows, err := s.db.Query(
+		"SELECT id, token, created_at, attempts, metadata FROM tokens WHERE user_id = $1 AND type = $2 ORDER BY created_at DESC;",
+		userID, tokenType,
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to exec select-token-stmt: %w", err)
 	}
@@ -43,20 +68,49 @@ func (s Storage) TokensByEMailAndToken(email, token string) ([]Token, error) {
 	var tokens []Token
 	for rows.Next() {
 		t := Token{
-			Token: token,
-			EMail: email,
+			UserID: userID,
+			Type:   tokenType,
 		}
-		err := rows.Scan(&t.ID, &t.Type, &t.CreatedAt)
+		var rawMetadata []byte
+		err := rows.Scan(&t.ID, &t.Token, &t.CreatedAt, &t.Attempts, &rawMetadata)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan select-token-stmt result: %w", err)
 		}
 
+		if rawMetadata != nil {
+			err = json.Unmarshal(rawMetadata, &t.Metadata)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unmarshal token>metadata: %w", err)
+			}
+		}
+
 		tokens = append(tokens, t)
 	}
 
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read select-token-stmt result: %w", err)
+	}
+
 	return tokens, nil
 }
 
+// IncrementTokenAttempts increments the count of failed redemptions of the token with the given ID and returns the new
+// count.
+// return ErrTokenNotFound there is no token with the given ID
+func (s Storage) IncrementTokenAttempts(id int64) (int, error) {
+	var attempts int
+	err := s.db.QueryRow("UPDATE tokens SET attempts = attempts + 1 WHERE id = $1 RETURNING attempts;", id).Scan(&attempts)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrTokenNotFound
+		}
+		return 0, fmt.Errorf("failed to exec increment-token-attempts-stmt: %w", err)
+	}
+
+	return attempts, nil
+}
+
 // DeleteToken deletes token with the given ID.
 // return ErrTokenNotFound there is no token with the given ID
 func (s Storage) DeleteToken(id int64) error {
@@ -75,3 +129,19 @@ func (s Storage) DeleteToken(id int64) error {
 
 	return nil
 }
+
+// DeleteTokensCreatedBefore deletes all tokens of the given type which have been created before the given time and
+// returns the count of deleted tokens.
+func (s Storage) DeleteTokensCreatedBefore(tokenType string, createdBefore time.Time) (int64, error) {
+	res, err := s.db.Exec("DELETE FROM tokens WHERE type = $1 AND created_at < $2;", tokenType, createdBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete tokens: %w", err)
+	}
+
+	i, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("could not get num of affected row: %w", err)
+	}
+
+	return i, nil
+}
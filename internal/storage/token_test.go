@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"database/sql"
 	"database/sql/driver"
 	"errors"
 	"fmt"
@@ -16,39 +17,57 @@ func TestStorage_CreateToken(t *testing.T) {
 		givenToken          Token
 		dbResponseErr       error
 		dbResponseRows      *sqlmock.Rows
-		expectedDBEMail     string
+		expectedDBUserID    string
 		expectedDBToken     string
 		expectedDBType      string
 		expectedDBCreatedAt time.Time
+		expectedDBMetadata  []byte
 		expectedID          int64
 		expectedErr         error
 	}{
 		{
 			name: "Happycase",
 			givenToken: Token{
-				EMail:     "info@leberkleber.io",
+				UserID:    "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
 				CreatedAt: time.Date(2020, 2, 1, 4, 46, 45, 2, time.UTC),
 				Token:     "myGeneratedToken",
 				Type:      "reset",
 			},
 			dbResponseRows:      sqlmock.NewRows([]string{"id"}).AddRow(42),
-			expectedDBEMail:     "info@leberkleber.io",
+			expectedDBUserID:    "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
 			expectedDBType:      "reset",
 			expectedDBToken:     "myGeneratedToken",
 			expectedDBCreatedAt: time.Date(2020, 2, 1, 4, 46, 45, 2, time.UTC),
 			expectedID:          42,
 		},
+		{
+			name: "Happycase with metadata",
+			givenToken: Token{
+				UserID:    "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				CreatedAt: time.Date(2020, 2, 1, 4, 46, 45, 2, time.UTC),
+				Token:     "myGeneratedToken",
+				Type:      "reset",
+				Metadata:  map[string]interface{}{"redirect": "/home"},
+			},
+			dbResponseRows:      sqlmock.NewRows([]string{"id"}).AddRow(42),
+			expectedDBUserID:    "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			expectedDBType:      "reset",
+			expectedDBToken:     "myGeneratedToken",
+			expectedDBCreatedAt: time.Date(2020, 2, 1, 4, 46, 45, 2, time.UTC),
+			expectedDBMetadata:  []byte(`{"redirect":"/home"}`),
+			expectedID:          42,
+		},
 		{
 			name: "Unexpected db error",
 			givenToken: Token{
-				EMail:     "info@leberkleber.io",
+				UserID:    "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
 				CreatedAt: time.Date(2020, 2, 1, 4, 46, 45, 2, time.UTC),
 				Token:     "myGeneratedToken",
 				Type:      "reset",
 			},
 			dbResponseRows:      sqlmock.NewRows([]string{"id"}).AddRow(42),
 			dbResponseErr:       errors.New("nope"),
-			expectedDBEMail:     "info@leberkleber.io",
+			expectedDBUserID:    "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
 			expectedDBType:      "reset",
 			expectedDBToken:     "myGeneratedToken",
 			expectedDBCreatedAt: time.Date(2020, 2, 1, 4, 46, 45, 2, time.UTC),
@@ -64,8 +83,8 @@ func TestStorage_CreateToken(t *testing.T) {
 			}
 
 			expectedQuery := mock.
-				ExpectQuery(`INSERT INTO tokens \(email, token, type, created_at\) VALUES\(\$1, \$2, \$3, \$4\) RETURNING id;`).
-				WithArgs(tt.expectedDBEMail, tt.expectedDBToken, tt.expectedDBType, tt.expectedDBCreatedAt).
+				ExpectQuery(`INSERT INTO tokens \(user_id, token, type, created_at, metadata\) VALUES\(\$1, \$2, \$3, \$4, \$5\) RETURNING id;`).
+				WithArgs(tt.expectedDBUserID, tt.expectedDBToken, tt.expectedDBType, tt.expectedDBCreatedAt, tt.expectedDBMetadata).
 				WillReturnError(tt.dbResponseErr)
 			if tt.dbResponseRows != nil {
 				expectedQuery.WillReturnRows(tt.dbResponseRows)
@@ -84,51 +103,47 @@ func TestStorage_CreateToken(t *testing.T) {
 	}
 }
 
-func TestStorage_TokensByEMailAndToken(t *testing.T) {
+func TestStorage_TokensByUserIDAndType(t *testing.T) {
 	tests := []struct {
-		name            string
-		givenEMail      string
-		givenToken      string
-		dbResponseErr   error
-		dbResponseRows  *sqlmock.Rows
-		expectedDBEMail string
-		expectedDBToken string
-		expectedTokens  []Token
-		expectedErr     error
+		name           string
+		dbResponseErr  error
+		dbResponseRows *sqlmock.Rows
+		expectedTokens []Token
+		expectedErr    error
 	}{
 		{
-			name:       "Happycase",
-			givenEMail: "info@leberkleber.io",
-			givenToken: "myGeneratedToken",
-			dbResponseRows: sqlmock.NewRows([]string{"id", "type", "created_at"}).
-				AddRow(1, "reset", time.Date(2020, 01, 01, 01, 01, 01, 01, time.UTC)).
-				AddRow(42, "reset", time.Date(1999, 01, 01, 01, 01, 01, 01, time.UTC)),
-			expectedDBEMail: "info@leberkleber.io",
-			expectedDBToken: "myGeneratedToken",
+			name: "Happycase",
+			dbResponseRows: sqlmock.NewRows([]string{"id", "token", "created_at", "attempts", "metadata"}).
+				AddRow(42, "hash2", time.Date(2020, 01, 01, 01, 01, 01, 01, time.UTC), 2, []byte(`{"redirect":"/home"}`)).
+				AddRow(1, "hash1", time.Date(1999, 01, 01, 01, 01, 01, 01, time.UTC), 0, nil),
 			expectedTokens: []Token{
-				{ID: 1, EMail: "info@leberkleber.io", Type: "reset", CreatedAt: time.Date(2020, 01, 01, 01, 01, 01, 01, time.UTC), Token: "myGeneratedToken"},
-				{ID: 42, EMail: "info@leberkleber.io", Type: "reset", CreatedAt: time.Date(1999, 01, 01, 01, 01, 01, 01, time.UTC), Token: "myGeneratedToken"},
+				{ID: 42, UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Type: "login-code", Token: "hash2", CreatedAt: time.Date(2020, 01, 01, 01, 01, 01, 01, time.UTC), Attempts: 2, Metadata: map[string]interface{}{"redirect": "/home"}},
+				{ID: 1, UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Type: "login-code", Token: "hash1", CreatedAt: time.Date(1999, 01, 01, 01, 01, 01, 01, time.UTC)},
 			},
 		},
 		{
-			name:            "Error while exec stmt",
-			givenEMail:      "info@leberkleber.io",
-			givenToken:      "myGeneratedToken",
-			dbResponseErr:   errors.New("nope"),
-			expectedDBEMail: "info@leberkleber.io",
-			expectedDBToken: "myGeneratedToken",
-			expectedErr:     errors.New("failed to exec select-token-stmt: nope"),
+			name:          "Error while exec stmt",
+			dbResponseErr: errors.New("nope"),
+			expectedErr:   errors.New("failed to exec select-token-stmt: nope"),
+		},
+		{
+			name: "Unable to scan sql response",
+			dbResponseRows: sqlmock.NewRows([]string{"id", "token"}).
+				AddRow(1, "hash1"),
+			expectedErr: errors.New("failed to scan select-token-stmt result: sql: expected 2 destination arguments in Scan, not 5"),
 		},
 		{
-			name:       "Unable to scan sql response",
-			givenEMail: "info@leberkleber.io",
-			givenToken: "myGeneratedToken",
-			dbResponseRows: sqlmock.NewRows([]string{"id", "type"}).
-				AddRow(1, "reset").
-				AddRow(42, "reset"),
-			expectedDBEMail: "info@leberkleber.io",
-			expectedDBToken: "myGeneratedToken",
-			expectedErr:     errors.New("failed to scan select-token-stmt result: sql: expected 2 destination arguments in Scan, not 3"),
+			name: "Unable to unmarshal metadata",
+			dbResponseRows: sqlmock.NewRows([]string{"id", "token", "created_at", "attempts", "metadata"}).
+				AddRow(1, "hash1", time.Date(1999, 01, 01, 01, 01, 01, 01, time.UTC), 0, []byte("{")),
+			expectedErr: errors.New("failed to unmarshal token>metadata: unexpected end of JSON input"),
+		},
+		{
+			name: "Error while reading sql response",
+			dbResponseRows: sqlmock.NewRows([]string{"id", "token", "created_at", "attempts", "metadata"}).
+				AddRow(1, "hash1", time.Date(1999, 01, 01, 01, 01, 01, 01, time.UTC), 0, nil).
+				RowError(0, errors.New("nope")),
+			expectedErr: errors.New("failed to read select-token-stmt result: nope"),
 		},
 	}
 
@@ -140,8 +155,8 @@ func TestStorage_TokensByEMailAndToken(t *testing.T) {
 			}
 
 			expectedQuery := mock.
-				ExpectQuery(`SELECT id, type, created_at FROM tokens WHERE email = \$1 AND token = \$2;`).
-				WithArgs(tt.expectedDBEMail, tt.expectedDBToken).
+				ExpectQuery(`SELECT id, token, created_at, attempts, metadata FROM tokens WHERE user_id = \$1 AND type = \$2 ORDER BY created_at DESC;`).
+				WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", "login-code").
 				WillReturnError(tt.dbResponseErr)
 			if tt.dbResponseRows != nil {
 				expectedQuery.WillReturnRows(tt.dbResponseRows)
@@ -149,12 +164,65 @@ func TestStorage_TokensByEMailAndToken(t *testing.T) {
 
 			s := Storage{db: db}
 
-			tokens, err := s.TokensByEMailAndToken(tt.givenEMail, tt.givenToken)
+			tokens, err := s.TokensByUserIDAndType("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", "login-code")
 			if fmt.Sprint(err) != fmt.Sprint(tt.expectedErr) {
 				t.Errorf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedErr, err)
 			}
 			if !reflect.DeepEqual(tokens, tt.expectedTokens) {
-				t.Errorf("Returned id is not as expected. Expected:\n%#v\nGiven:\n%#v", tt.expectedTokens, tokens)
+				t.Errorf("Returned tokens are not as expected. Expected:\n%#v\nGiven:\n%#v", tt.expectedTokens, tokens)
+			}
+		})
+	}
+}
+
+func TestStorage_IncrementTokenAttempts(t *testing.T) {
+	tests := []struct {
+		name             string
+		dbResponseErr    error
+		dbResponseRows   *sqlmock.Rows
+		expectedAttempts int
+		expectedErr      error
+	}{
+		{
+			name:             "Happycase",
+			dbResponseRows:   sqlmock.NewRows([]string{"attempts"}).AddRow(3),
+			expectedAttempts: 3,
+		},
+		{
+			name:          "Token not found",
+			dbResponseErr: sql.ErrNoRows,
+			expectedErr:   ErrTokenNotFound,
+		},
+		{
+			name:          "Error while exec stmt",
+			dbResponseErr: errors.New("nope"),
+			expectedErr:   errors.New("failed to exec increment-token-attempts-stmt: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			expectedQuery := mock.
+				ExpectQuery(`UPDATE tokens SET attempts = attempts \+ 1 WHERE id = \$1 RETURNING attempts;`).
+				WithArgs(int64(42)).
+				WillReturnError(tt.dbResponseErr)
+			if tt.dbResponseRows != nil {
+				expectedQuery.WillReturnRows(tt.dbResponseRows)
+			}
+
+			s := Storage{db: db}
+
+			attempts, err := s.IncrementTokenAttempts(42)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedErr) {
+				t.Errorf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedErr, err)
+			}
+			if attempts != tt.expectedAttempts {
+				t.Errorf("Returned attempts are not as expected. Expected: %d. Given: %d", tt.expectedAttempts, attempts)
 			}
 		})
 	}
@@ -220,3 +288,54 @@ func TestStorage_DeleteToken(t *testing.T) {
 		})
 	}
 }
+
+func TestStorage_DeleteTokensCreatedBefore(t *testing.T) {
+	tests := []struct {
+		name             string
+		dbResponseErr    error
+		dbResponseResult driver.Result
+		expectedCount    int64
+		expectedErr      error
+	}{
+		{
+			name:             "Happycase",
+			dbResponseResult: sqlmock.NewResult(0, 3),
+			expectedCount:    3,
+		},
+		{
+			name:          "Error while exec",
+			dbResponseErr: errors.New("nope"),
+			expectedErr:   errors.New("failed to delete tokens: nope"),
+		},
+		{
+			name:             "Error while get affected rows (should not be possible)",
+			dbResponseResult: sqlmock.NewErrorResult(errors.New("aaaaaaaaaa")),
+			expectedErr:      errors.New("could not get num of affected row: aaaaaaaaaa"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			mock.
+				ExpectExec(`DELETE FROM tokens WHERE type = \$1 AND created_at < \$2;`).
+				WithArgs("reset", time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)).
+				WillReturnResult(tt.dbResponseResult).
+				WillReturnError(tt.dbResponseErr)
+
+			s := Storage{db: db}
+
+			count, err := s.DeleteTokensCreatedBefore("reset", time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC))
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedErr) {
+				t.Errorf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedErr, err)
+			}
+			if count != tt.expectedCount {
+				t.Errorf("Returned count is not as expected. Expected: %d. Given: %d", tt.expectedCount, count)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrTOTPNotFound = errors.New("could not found totp")
+
+// TOTP is the totp enrolment of a user
+type TOTP struct {
+	UserID string
+	// Secret is the encrypted totp secret
+	Secret    []byte
+	Confirmed bool
+	// LastUsedStep is the time step of the last accepted code. Codes of older or equal steps must not be accepted again
+	LastUsedStep int64
+	CreatedAt    time.Time
+}
+
+// TOTP finds the totp enrolment of the user with the given id
+// return ErrTOTPNotFound when the user has no totp enrolment
+func (s Storage) TOTP(userID string) (TOTP, error) {
+	t := TOTP{
+		UserID: userID,
+	}
+	err := s.db.QueryRow(
+		"SELECT secret, confirmed, last_used_step, created_at FROM user_totp WHERE user_id = $1;",
+		userID,
+	).Scan(&t.Secret, &t.Confirmed, &t.LastUsedStep, &t.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return TOTP{}, ErrTOTPNotFound
+		}
+
+		return TOTP{}, fmt.Errorf("failed to query totp: %w", err)
+	}
+
+	return t, nil
+}
+
+// SaveTOTP creates or replaces the totp enrolment of the user with the given id. UserID must match to a users id.
+func (s Storage) SaveTOTP(t TOTP) error {
+	_, err := s.db.Exec(
+		"INSERT INTO user_totp (user_id, secret, confirmed, last_used_step, created_at) VALUES($1, $2, $3, $4, $5) "+
+			"ON CONFLICT (user_id) DO UPDATE SET secret = $2, confirmed = $3, last_used_step = $4, created_at = $5;",
+		t.UserID, t.Secret, t.Confirmed, t.LastUsedStep, t.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to exec save totp stmt: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStorage_TOTP(t *testing.T) {
+	createdAt := time.Date(2020, 2, 1, 4, 46, 45, 2, time.UTC)
+
+	tests := []struct {
+		name           string
+		dbResponseErr  error
+		dbResponseRows *sqlmock.Rows
+		expectedTOTP   TOTP
+		expectedErr    error
+	}{
+		{
+			name: "Happycase",
+			dbResponseRows: sqlmock.NewRows([]string{"secret", "confirmed", "last_used_step", "created_at"}).
+				AddRow([]byte("encryptedSecret"), true, 4711, createdAt),
+			expectedTOTP: TOTP{
+				UserID:       "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				Secret:       []byte("encryptedSecret"),
+				Confirmed:    true,
+				LastUsedStep: 4711,
+				CreatedAt:    createdAt,
+			},
+		},
+		{
+			name:          "TOTP not found",
+			dbResponseErr: sql.ErrNoRows,
+			expectedErr:   ErrTOTPNotFound,
+		},
+		{
+			name:          "Unexpected db error",
+			dbResponseErr: errors.New("nope"),
+			expectedErr:   errors.New("failed to query totp: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			expectedQuery := mock.
+				ExpectQuery(`SELECT secret, confirmed, last_used_step, created_at FROM user_totp WHERE user_id = \$1;`).
+				WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b").
+				WillReturnError(tt.dbResponseErr)
+			if tt.dbResponseRows != nil {
+				expectedQuery.WillReturnRows(tt.dbResponseRows)
+			}
+
+			s := Storage{db: db}
+
+			totp, err := s.TOTP("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b")
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedErr) {
+				t.Errorf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedErr, err)
+			}
+			if !reflect.DeepEqual(totp, tt.expectedTOTP) {
+				t.Errorf("Returned totp is not as expected. Expected:\n%#v\nGiven:\n%#v", tt.expectedTOTP, totp)
+			}
+		})
+	}
+}
+
+func TestStorage_SaveTOTP(t *testing.T) {
+	createdAt := time.Date(2020, 2, 1, 4, 46, 45, 2, time.UTC)
+
+	tests := []struct {
+		name          string
+		dbResponseErr error
+		expectedError error
+	}{
+		{
+			name: "Happycase",
+		},
+		{
+			name:          "Unexpected db error",
+			dbResponseErr: errors.New("nope"),
+			expectedError: errors.New("failed to exec save totp stmt: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			mock.
+				ExpectExec(`INSERT INTO user_totp \(user_id, secret, confirmed, last_used_step, created_at\) VALUES\(\$1, \$2, \$3, \$4, \$5\) ON CONFLICT \(user_id\) DO UPDATE SET secret = \$2, confirmed = \$3, last_used_step = \$4, created_at = \$5;`).
+				WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", []byte("encryptedSecret"), true, int64(4711), createdAt).
+				WillReturnResult(sqlmock.NewResult(0, 1)).
+				WillReturnError(tt.dbResponseErr)
+
+			s := Storage{db: db}
+
+			err = s.SaveTOTP(TOTP{
+				UserID:       "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				Secret:       []byte("encryptedSecret"),
+				Confirmed:    true,
+				LastUsedStep: 4711,
+				CreatedAt:    createdAt,
+			})
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Errorf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
+			}
+		})
+	}
+}
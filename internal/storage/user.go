@@ -6,31 +6,70 @@ import (
 	"errors"
 	"fmt"
 	"github.com/lib/pq"
+	"time"
 )
 
 // User is the representation of a user for use in storage
 type User struct {
-	EMail    string
+	// ID is the generated immutable id of the user
+	ID string
+	// EMail is the normalized email which identifies the user. It is empty when the user has no email
+	EMail string
+	// DisplayEMail is the email in the form it has been given on creation. It is read only and equals EMail when unset
+	DisplayEMail string
+	// Username is an optional unique login identifier
+	Username string
+	// Phone is an optional unique login identifier in E.164 format
+	Phone    string
 	Password []byte
-	Claims   map[string]interface{}
+	// Claims will be emitted into all jwts of the user and are available in mail templates
+	Claims map[string]interface{}
+	// Metadata is available in mail templates and the admin api only. It will never be emitted into jwts
+	Metadata          map[string]interface{}
+	PasswordChangedAt time.Time
+	// PasswordMaxAgeDays overwrites the global password max age for this user. 0 means the global one will be used
+	PasswordMaxAgeDays int
+	// LastLoginAt is the time of the last successful login. It is read only and nil when the user never logged in
+	LastLoginAt *time.Time
 }
 
 var ErrUserNotFound = errors.New("could not found user")
 var ErrUserAlreadyExists = errors.New("user already exists")
 
-// CreateUser persists the given user in database
-// return ErrUserNotFound when user not found
-// return ErrUserAlreadyExists when user already exists
+// userColumns are the selected columns of users in the order queryUser scans them
+const userColumns = "id, COALESCE(email, ''), password, claims, password_changed_at, password_max_age_days, " +
+	"COALESCE(display_email, email, ''), COALESCE(username, ''), COALESCE(phone, ''), metadata, last_login_at"
+
+// loginIdentifierConstraints are the unique constraints of all login identifiers
+var loginIdentifierConstraints = map[string]bool{
+	"email_unique":          true,
+	"users_username_unique": true,
+	"users_phone_unique":    true,
+}
+
+// CreateUser persists the given user in database. Empty emails, usernames and phones will be stored as NULL.
+// return ErrUserAlreadyExists when a user with the same email, username or phone already exists
 func (s *Storage) CreateUser(u User) error {
 	rawClaims, err := json.Marshal(u.Claims)
 	if err != nil {
 		return fmt.Errorf("failed to marhsal user>claims: %w", err)
 	}
 
-	_, err = s.db.Exec("INSERT INTO users (email, password, claims) VALUES($1, $2, $3);", u.EMail, u.Password, rawClaims)
+	rawMetadata, err := json.Marshal(u.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marhsal user>metadata: %w", err)
+	}
+
+	// claims are jsonb which has to be passed as text, []byte would be passed as bytea
+	_, err = s.db.Exec(
+		"INSERT INTO users (id, email, password, claims, password_changed_at, password_max_age_days, display_email, username, phone, metadata) "+
+			"VALUES($1, NULLIF($2, ''), $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''), $10);",
+		u.ID, u.EMail, u.Password, string(rawClaims), u.PasswordChangedAt, u.PasswordMaxAgeDays, u.DisplayEMail, u.Username, u.Phone,
+		rawMetadata,
+	)
 	if err != nil {
 		pqErr, ok := err.(*pq.Error)
-		if ok && pqErr.Constraint == "email_unique" {
+		if ok && loginIdentifierConstraints[pqErr.Constraint] {
 			return ErrUserAlreadyExists
 		}
 		return fmt.Errorf("failed to exec create stmt: %w", err)
@@ -42,14 +81,41 @@ func (s *Storage) CreateUser(u User) error {
 // User finds the user identified by email
 // return ErrUserNotFound when user not found
 func (s *Storage) User(email string) (User, error) {
-	user := User{
-		EMail: email,
-	}
-	var rawClaims []byte
-	err := s.db.QueryRow(
-		"SELECT password, claims FROM users WHERE email = $1;",
-		email,
-	).Scan(&user.Password, &rawClaims)
+	return s.queryUser("SELECT "+userColumns+" FROM users WHERE email = $1;", email)
+}
+
+// UserByID finds the user identified by id
+// return ErrUserNotFound when user not found
+func (s *Storage) UserByID(id string) (User, error) {
+	return s.queryUser("SELECT "+userColumns+" FROM users WHERE id = $1;", id)
+}
+
+// UserByUsername finds the user identified by the given normalized username
+// return ErrUserNotFound when user not found
+func (s *Storage) UserByUsername(username string) (User, error) {
+	return s.queryUser("SELECT "+userColumns+" FROM users WHERE username = $1;", username)
+}
+
+// UserByPhone finds the user identified by the given phone number in E.164 format
+// return ErrUserNotFound when user not found
+func (s *Storage) UserByPhone(phone string) (User, error) {
+	return s.queryUser("SELECT "+userColumns+" FROM users WHERE phone = $1;", phone)
+}
+
+func (s *Storage) queryUser(query string, args ...interface{}) (User, error) {
+	return scanUser(s.db.QueryRow(query, args...))
+}
+
+// scanUser scans the given row of userColumns
+// return ErrUserNotFound when there is no row
+func scanUser(row scanner) (User, error) {
+	var user User
+	var rawClaims, rawMetadata []byte
+	var lastLoginAt sql.NullTime
+	err := row.Scan(
+		&user.ID, &user.EMail, &user.Password, &rawClaims, &user.PasswordChangedAt, &user.PasswordMaxAgeDays, &user.DisplayEMail,
+		&user.Username, &user.Phone, &rawMetadata, &lastLoginAt,
+	)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return User{}, ErrUserNotFound
@@ -58,24 +124,106 @@ func (s *Storage) User(email string) (User, error) {
 		return User{}, fmt.Errorf("failed to query user: %w", err)
 	}
 
+	if lastLoginAt.Valid {
+		user.LastLoginAt = &lastLoginAt.Time
+	}
+
 	err = json.Unmarshal(rawClaims, &user.Claims)
 	if err != nil {
 		return User{}, fmt.Errorf("failed to unmarshal user>claims: %w", err)
 	}
 
+	err = unmarshalMetadata(rawMetadata, &user.Metadata)
+	if err != nil {
+		return User{}, err
+	}
+
 	return user, nil
 }
 
-// UpdateUser updates all properties (excluding email) from the given user which will be identified by email
+// unmarshalMetadata unmarshals the given raw metadata which is NULL for users created before metadata existed
+func unmarshalMetadata(rawMetadata []byte, metadata *map[string]interface{}) error {
+	if rawMetadata == nil {
+		return nil
+	}
+
+	err := json.Unmarshal(rawMetadata, metadata)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal user>metadata: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateUser updates all properties (excluding id, email and display email) from the given user which will be
+// identified by id
 // return ErrUserNotFound when user not found
+// return ErrUserAlreadyExists when another user has the same username or phone
 func (s *Storage) UpdateUser(u User) error {
+	return updateUser(s.db, u)
+}
+
+// PatchUser locks the user with the given id, calls the given patch function with it and updates all properties like
+// UpdateUser in one transaction. Concurrent patches of the same user will therefore be applied one after another.
+// Errors of the patch function will be returned unwrapped and nothing will be updated.
+// return ErrUserNotFound when user not found
+// return ErrUserAlreadyExists when another user has the same username or phone
+func (s *Storage) PatchUser(id string, patch func(u *User) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin patch transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	u, err := scanUser(tx.QueryRow("SELECT "+userColumns+" FROM users WHERE id = $1 FOR UPDATE;", id))
+	if err != nil {
+		return err
+	}
+
+	err = patch(&u)
+	if err != nil {
+		return err
+	}
+
+	err = updateUser(tx, u)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit patch transaction: %w", err)
+	}
+
+	return nil
+}
+
+// execer is implemented by *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func updateUser(db execer, u User) error {
 	rawClaims, err := json.Marshal(u.Claims)
 	if err != nil {
 		return fmt.Errorf("failed to marhsal user>claims: %w", err)
 	}
 
-	resp, err := s.db.Exec("UPDATE users SET password = $2, claims = $3 WHERE email = $1;", u.EMail, u.Password, rawClaims)
+	rawMetadata, err := json.Marshal(u.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marhsal user>metadata: %w", err)
+	}
+
+	resp, err := db.Exec(
+		"UPDATE users SET password = $2, claims = $3, password_changed_at = $4, password_max_age_days = $5, "+
+			"username = NULLIF($6, ''), phone = NULLIF($7, ''), metadata = $8 WHERE id = $1;",
+		u.ID, u.Password, string(rawClaims), u.PasswordChangedAt, u.PasswordMaxAgeDays, u.Username, u.Phone, rawMetadata,
+	)
 	if err != nil {
+		pqErr, ok := err.(*pq.Error)
+		if ok && loginIdentifierConstraints[pqErr.Constraint] {
+			return ErrUserAlreadyExists
+		}
 		return fmt.Errorf("failed to exec update stmt: %w", err)
 	}
 
@@ -90,22 +238,72 @@ func (s *Storage) UpdateUser(u User) error {
 	return nil
 }
 
-// DeleteUser deletes the user with the given email and all corresponding tokes in one transaction.
+// DeleteUser deletes the user with the given id and all of its rows in all other tables in one transaction like
+// EraseUser.
 // return ErrUserNotFound when user not found
-func (s *Storage) DeleteUser(email string) error {
-	tx, err := s.db.Begin()
+func (s *Storage) DeleteUser(id string) error {
+	_, err := s.EraseUser(id)
+	return err
+}
+
+// MarkUserInvited persists that the user with the given id has been invited (again) at the given time.
+// return ErrUserNotFound when user not found
+func (s *Storage) MarkUserInvited(id string, invitedAt time.Time) error {
+	resp, err := s.db.Exec("UPDATE users SET invited_at = $2 WHERE id = $1;", id, invitedAt)
 	if err != nil {
-		return fmt.Errorf("failed to begin delete transaction: %w", err)
+		return fmt.Errorf("failed to exec update stmt: %w", err)
 	}
 
-	_, err = tx.Exec("DELETE FROM tokens WHERE email = $1;", email)
+	ra, err := resp.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("failed to exec delete tokens from user stmt: %w", err)
+		return fmt.Errorf("failed to get count of affected rows: %w", err)
+	}
+	if ra == 0 {
+		return ErrUserNotFound
 	}
 
-	resp, err := tx.Exec("DELETE FROM users WHERE email = $1;", email)
+	return nil
+}
+
+// UserEMails returns the stored emails of all users with email by the ids of the users
+func (s *Storage) UserEMails() (map[string]string, error) {
+	rows, err := s.db.Query("SELECT id, email FROM users WHERE email IS NOT NULL;")
+	if err != nil {
+		return nil, fmt.Errorf("failed to exec select-emails-stmt: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	emails := map[string]string{}
+	for rows.Next() {
+		var id, email string
+		err := rows.Scan(&id, &email)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan select-emails-stmt result: %w", err)
+		}
+
+		emails[id] = email
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read select-emails-stmt result: %w", err)
+	}
+
+	return emails, nil
+}
+
+// NormalizeUserEMail replaces the email of the user with the given id by the given normalized email. The previous
+// email will be kept as display email unless the user already has one.
+// return ErrUserNotFound when user not found
+// return ErrUserAlreadyExists when another user has the same email
+func (s *Storage) NormalizeUserEMail(id, email string) error {
+	resp, err := s.db.Exec("UPDATE users SET display_email = COALESCE(display_email, email), email = $2 WHERE id = $1;", id, email)
 	if err != nil {
-		return fmt.Errorf("failed to exec delete user stmt: %w", err)
+		pqErr, ok := err.(*pq.Error)
+		if ok && loginIdentifierConstraints[pqErr.Constraint] {
+			return ErrUserAlreadyExists
+		}
+		return fmt.Errorf("failed to exec update stmt: %w", err)
 	}
 
 	ra, err := resp.RowsAffected()
@@ -116,10 +314,5 @@ func (s *Storage) DeleteUser(email string) error {
 		return ErrUserNotFound
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		return fmt.Errorf("failed to commit delete transaction: %w", err)
-	}
-
 	return nil
 }
@@ -7,10 +7,17 @@ import (
 	"fmt"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/lib/pq"
+	"reflect"
+	"regexp"
 	"testing"
+	"time"
 )
 
+var userColumnsPattern = regexp.QuoteMeta(userColumns)
+
 func TestStorage_User(t *testing.T) {
+	lastLoginAt := time.Date(2020, 2, 2, 4, 46, 45, 2, time.UTC)
+
 	tests := []struct {
 		name           string
 		givenEMail     string
@@ -22,14 +29,23 @@ func TestStorage_User(t *testing.T) {
 		{
 			name:       "Happycase",
 			givenEMail: "info@leberkleber.io",
-			dbResponseRows: sqlmock.NewRows([]string{"password", "claims"}).
-				AddRow("bcryptedPassword", `{"customClaim1": 4711}`),
+			dbResponseRows: sqlmock.NewRows([]string{"id", "email", "password", "claims", "password_changed_at", "password_max_age_days", "display_email", "username", "phone", "metadata", "last_login_at"}).
+				AddRow("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", "info@leberkleber.io", "bcryptedPassword", `{"customClaim1": 4711}`, time.Date(2020, 2, 1, 4, 46, 45, 2, time.UTC), 90, "Info@LeberKleber.io", "", "", `{"plan": "pro"}`, lastLoginAt),
 			expectedUser: User{
-				EMail:    "info@leberkleber.io",
-				Password: []byte("bcryptedPassword"),
+				ID:           "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				EMail:        "info@leberkleber.io",
+				DisplayEMail: "Info@LeberKleber.io",
+				Password:     []byte("bcryptedPassword"),
 				Claims: map[string]interface{}{
 					"customClaim1": 4711,
-				}},
+				},
+				Metadata: map[string]interface{}{
+					"plan": "pro",
+				},
+				PasswordChangedAt:  time.Date(2020, 2, 1, 4, 46, 45, 2, time.UTC),
+				PasswordMaxAgeDays: 90,
+				LastLoginAt:        &lastLoginAt,
+			},
 		},
 		{
 			name:          "No results",
@@ -46,10 +62,17 @@ func TestStorage_User(t *testing.T) {
 		{
 			name:       "Non json claims (should not be possible)",
 			givenEMail: "info@leberkleber.io",
-			dbResponseRows: sqlmock.NewRows([]string{"password", "claims"}).
-				AddRow("bcryptedPassword", "customClaim1\n4711}"),
+			dbResponseRows: sqlmock.NewRows([]string{"id", "email", "password", "claims", "password_changed_at", "password_max_age_days", "display_email", "username", "phone", "metadata", "last_login_at"}).
+				AddRow("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", "info@leberkleber.io", "bcryptedPassword", "customClaim1\n4711}", time.Date(2020, 2, 1, 4, 46, 45, 2, time.UTC), 0, "info@leberkleber.io", "", "", nil, nil),
 			expectedError: errors.New("failed to unmarshal user>claims: invalid character 'c' looking for beginning of value"),
 		},
+		{
+			name:       "Non json metadata (should not be possible)",
+			givenEMail: "info@leberkleber.io",
+			dbResponseRows: sqlmock.NewRows([]string{"id", "email", "password", "claims", "password_changed_at", "password_max_age_days", "display_email", "username", "phone", "metadata", "last_login_at"}).
+				AddRow("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", "info@leberkleber.io", "bcryptedPassword", `null`, time.Date(2020, 2, 1, 4, 46, 45, 2, time.UTC), 0, "info@leberkleber.io", "", "", "plan\npro}", nil),
+			expectedError: errors.New("failed to unmarshal user>metadata: invalid character 'p' looking for beginning of value"),
+		},
 	}
 
 	for _, tt := range tests {
@@ -60,7 +83,7 @@ func TestStorage_User(t *testing.T) {
 			}
 
 			expectedQuery := mock.
-				ExpectQuery(`SELECT password, claims FROM users WHERE email = \$1;`).
+				ExpectQuery(`SELECT ` + userColumnsPattern + ` FROM users WHERE email = \$1;`).
 				WithArgs(tt.givenEMail).
 				WillReturnError(tt.dbResponseErr)
 
@@ -83,6 +106,140 @@ func TestStorage_User(t *testing.T) {
 	}
 }
 
+func TestStorage_UserByID(t *testing.T) {
+	tests := []struct {
+		name           string
+		dbResponseRows *sqlmock.Rows
+		dbResponseErr  error
+		expectedUser   User
+		expectedError  error
+	}{
+		{
+			name: "Happycase",
+			dbResponseRows: sqlmock.NewRows([]string{"id", "email", "password", "claims", "password_changed_at", "password_max_age_days", "display_email", "username", "phone", "metadata", "last_login_at"}).
+				AddRow("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", "info@leberkleber.io", "bcryptedPassword", `{"customClaim1": 4711}`, time.Date(2020, 2, 1, 4, 46, 45, 2, time.UTC), 0, "info@leberkleber.io", "", "", nil, nil),
+			expectedUser: User{
+				ID:           "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				EMail:        "info@leberkleber.io",
+				DisplayEMail: "info@leberkleber.io",
+				Password:     []byte("bcryptedPassword"),
+				Claims: map[string]interface{}{
+					"customClaim1": float64(4711),
+				},
+				PasswordChangedAt: time.Date(2020, 2, 1, 4, 46, 45, 2, time.UTC),
+			},
+		},
+		{
+			name:          "No results",
+			dbResponseErr: sql.ErrNoRows,
+			expectedError: ErrUserNotFound,
+		},
+		{
+			name:          "Unexpected db error",
+			dbResponseErr: errors.New("nope"),
+			expectedError: errors.New("failed to query user: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			expectedQuery := mock.
+				ExpectQuery(`SELECT ` + userColumnsPattern + ` FROM users WHERE id = \$1;`).
+				WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b").
+				WillReturnError(tt.dbResponseErr)
+			if tt.dbResponseRows != nil {
+				expectedQuery.WillReturnRows(tt.dbResponseRows)
+			}
+
+			s := Storage{db: db}
+
+			user, err := s.UserByID("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b")
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Errorf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(user, tt.expectedUser) {
+				t.Errorf("Returned user is not as expected. Expected:\n%#v\nGiven:\n%#v", tt.expectedUser, user)
+			}
+		})
+	}
+}
+
+func TestStorage_UserByUsernameAndPhone(t *testing.T) {
+	tests := []struct {
+		name          string
+		find          func(s *Storage) (User, error)
+		expectedQuery string
+		expectedArg   string
+		dbResponseErr error
+		expectedUser  User
+		expectedError error
+	}{
+		{
+			name:          "By username",
+			find:          func(s *Storage) (User, error) { return s.UserByUsername("leberkleber") },
+			expectedQuery: `SELECT ` + userColumnsPattern + ` FROM users WHERE username = \$1;`,
+			expectedArg:   "leberkleber",
+			expectedUser: User{
+				ID:       "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				Username: "leberkleber",
+				Phone:    "+4917012345678",
+				Password: []byte("bcryptedPassword"),
+			},
+		},
+		{
+			name:          "By phone",
+			find:          func(s *Storage) (User, error) { return s.UserByPhone("+4917012345678") },
+			expectedQuery: `SELECT ` + userColumnsPattern + ` FROM users WHERE phone = \$1;`,
+			expectedArg:   "+4917012345678",
+			expectedUser: User{
+				ID:       "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				Username: "leberkleber",
+				Phone:    "+4917012345678",
+				Password: []byte("bcryptedPassword"),
+			},
+		},
+		{
+			name:          "No results",
+			find:          func(s *Storage) (User, error) { return s.UserByUsername("leberkleber") },
+			expectedQuery: `SELECT ` + userColumnsPattern + ` FROM users WHERE username = \$1;`,
+			expectedArg:   "leberkleber",
+			dbResponseErr: sql.ErrNoRows,
+			expectedError: ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			mock.
+				ExpectQuery(tt.expectedQuery).
+				WithArgs(tt.expectedArg).
+				WillReturnError(tt.dbResponseErr).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password", "claims", "password_changed_at", "password_max_age_days", "display_email", "username", "phone", "metadata", "last_login_at"}).
+					AddRow("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", "", "bcryptedPassword", `null`, time.Time{}, 0, "", "leberkleber", "+4917012345678", nil, nil))
+
+			user, err := tt.find(&Storage{db: db})
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Errorf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(user, tt.expectedUser) {
+				t.Errorf("Returned user is not as expected. Expected:\n%#v\nGiven:\n%#v", tt.expectedUser, user)
+			}
+		})
+	}
+}
+
 func TestStorage_CreateUser(t *testing.T) {
 	tests := []struct {
 		name               string
@@ -90,20 +247,30 @@ func TestStorage_CreateUser(t *testing.T) {
 		dbResponseErr      error
 		expectedDBEMail    string
 		expectedDBPassword []byte
-		expectedDBClaims   []byte
+		expectedDBClaims   string
+		expectedDBMetadata []byte
 		expectedError      error
 	}{
 		{
 			name: "Happycase",
 			givenUser: User{
-				EMail:    "info@leberkleber.io",
-				Password: []byte("bcryptedPassword"),
+				ID:           "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				EMail:        "info@leberkleber.io",
+				DisplayEMail: "Info@LeberKleber.io",
+				Password:     []byte("bcryptedPassword"),
 				Claims: map[string]interface{}{
 					"customClaim1": 4711,
-				}},
+				},
+				Metadata: map[string]interface{}{
+					"plan": "pro",
+				},
+				PasswordChangedAt:  time.Date(2020, 2, 1, 4, 46, 45, 2, time.UTC),
+				PasswordMaxAgeDays: 90,
+			},
 			expectedDBEMail:    "info@leberkleber.io",
 			expectedDBPassword: []byte("bcryptedPassword"),
-			expectedDBClaims:   []byte(`{"customClaim1":4711}`),
+			expectedDBClaims:   `{"customClaim1":4711}`,
+			expectedDBMetadata: []byte(`{"plan":"pro"}`),
 		},
 		{
 			name: "Unexpected db error",
@@ -116,7 +283,8 @@ func TestStorage_CreateUser(t *testing.T) {
 			dbResponseErr:      errors.New("nope"),
 			expectedDBEMail:    "info@leberkleber.io",
 			expectedDBPassword: []byte("bcryptedPassword"),
-			expectedDBClaims:   []byte(`{"customClaim1":4711}`),
+			expectedDBClaims:   `{"customClaim1":4711}`,
+			expectedDBMetadata: []byte(`null`),
 			expectedError:      errors.New("failed to exec create stmt: nope"),
 		},
 		{
@@ -132,7 +300,22 @@ func TestStorage_CreateUser(t *testing.T) {
 			},
 			expectedDBEMail:    "info@leberkleber.io",
 			expectedDBPassword: []byte("bcryptedPassword"),
-			expectedDBClaims:   []byte(`{"customClaim1":4711}`),
+			expectedDBClaims:   `{"customClaim1":4711}`,
+			expectedDBMetadata: []byte(`null`),
+			expectedError:      ErrUserAlreadyExists,
+		},
+		{
+			name: "Username already taken",
+			givenUser: User{
+				Username: "leberkleber",
+				Password: []byte("bcryptedPassword"),
+			},
+			dbResponseErr: &pq.Error{
+				Constraint: "users_username_unique",
+			},
+			expectedDBPassword: []byte("bcryptedPassword"),
+			expectedDBClaims:   `null`,
+			expectedDBMetadata: []byte(`null`),
 			expectedError:      ErrUserAlreadyExists,
 		},
 	}
@@ -145,8 +328,8 @@ func TestStorage_CreateUser(t *testing.T) {
 			}
 
 			mock.
-				ExpectExec(`INSERT INTO users \(email, password, claims\) VALUES\(\$1, \$2, \$3\);`).
-				WithArgs(tt.expectedDBEMail, tt.expectedDBPassword, tt.expectedDBClaims).
+				ExpectExec(`INSERT INTO users \(id, email, password, claims, password_changed_at, password_max_age_days, display_email, username, phone, metadata\) VALUES\(\$1, NULLIF\(\$2, ''\), \$3, \$4, \$5, \$6, NULLIF\(\$7, ''\), NULLIF\(\$8, ''\), NULLIF\(\$9, ''\), \$10\);`).
+				WithArgs(tt.givenUser.ID, tt.expectedDBEMail, tt.expectedDBPassword, tt.expectedDBClaims, tt.givenUser.PasswordChangedAt, tt.givenUser.PasswordMaxAgeDays, tt.givenUser.DisplayEMail, tt.givenUser.Username, tt.givenUser.Phone, tt.expectedDBMetadata).
 				WillReturnError(tt.dbResponseErr).
 				WillReturnResult(sqlmock.NewResult(0, 1))
 
@@ -166,65 +349,92 @@ func TestStorage_UpdateUser(t *testing.T) {
 		givenUser          User
 		dbResponseErr      error
 		dbResult           driver.Result
-		expectedDBEMail    string
+		expectedDBID       string
 		expectedDBPassword []byte
-		expectedDBClaims   []byte
+		expectedDBClaims   string
+		expectedDBMetadata []byte
 		expectedError      error
 	}{
 		{
 			name: "Happycase",
 			givenUser: User{
-				EMail:    "info@leberkleber.io",
+				ID:       "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
 				Password: []byte("bcryptedPassword"),
 				Claims: map[string]interface{}{
 					"customClaim1": 4711,
-				}},
+				},
+				Metadata: map[string]interface{}{
+					"plan": "pro",
+				},
+				PasswordChangedAt:  time.Date(2020, 2, 1, 4, 46, 45, 2, time.UTC),
+				PasswordMaxAgeDays: 90,
+			},
 			dbResult:           sqlmock.NewResult(0, 1),
-			expectedDBEMail:    "info@leberkleber.io",
+			expectedDBID:       "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
 			expectedDBPassword: []byte("bcryptedPassword"),
-			expectedDBClaims:   []byte(`{"customClaim1":4711}`),
+			expectedDBClaims:   `{"customClaim1":4711}`,
+			expectedDBMetadata: []byte(`{"plan":"pro"}`),
 		},
 		{
 			name: "Unexpected db error",
 			givenUser: User{
-				EMail:    "info@leberkleber.io",
+				ID:       "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
 				Password: []byte("bcryptedPassword"),
 				Claims: map[string]interface{}{
 					"customClaim1": 4711,
 				}},
 			dbResult:           sqlmock.NewResult(0, 1),
 			dbResponseErr:      errors.New("nope"),
-			expectedDBEMail:    "info@leberkleber.io",
+			expectedDBID:       "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
 			expectedDBPassword: []byte("bcryptedPassword"),
-			expectedDBClaims:   []byte(`{"customClaim1":4711}`),
+			expectedDBClaims:   `{"customClaim1":4711}`,
+			expectedDBMetadata: []byte(`null`),
 			expectedError:      errors.New("failed to exec update stmt: nope"),
 		},
 		{
-			name: "User already exists",
+			name: "User not found",
 			givenUser: User{
-				EMail:    "info@leberkleber.io",
+				ID:       "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
 				Password: []byte("bcryptedPassword"),
 				Claims: map[string]interface{}{
 					"customClaim1": 4711,
 				}},
 			dbResult:           sqlmock.NewResult(0, 0),
-			expectedDBEMail:    "info@leberkleber.io",
+			expectedDBID:       "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
 			expectedDBPassword: []byte("bcryptedPassword"),
-			expectedDBClaims:   []byte(`{"customClaim1":4711}`),
+			expectedDBClaims:   `{"customClaim1":4711}`,
+			expectedDBMetadata: []byte(`null`),
 			expectedError:      ErrUserNotFound,
 		},
+		{
+			name: "Phone already taken",
+			givenUser: User{
+				ID:       "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				Phone:    "+4917012345678",
+				Password: []byte("bcryptedPassword"),
+			},
+			dbResponseErr: &pq.Error{
+				Constraint: "users_phone_unique",
+			},
+			expectedDBID:       "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			expectedDBPassword: []byte("bcryptedPassword"),
+			expectedDBClaims:   `null`,
+			expectedDBMetadata: []byte(`null`),
+			expectedError:      ErrUserAlreadyExists,
+		},
 		{
 			name: "Unexpected result error",
 			givenUser: User{
-				EMail:    "info@leberkleber.io",
+				ID:       "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
 				Password: []byte("bcryptedPassword"),
 				Claims: map[string]interface{}{
 					"customClaim1": 4711,
 				}},
 			dbResult:           sqlmock.NewErrorResult(errors.New("a random error")),
-			expectedDBEMail:    "info@leberkleber.io",
+			expectedDBID:       "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
 			expectedDBPassword: []byte("bcryptedPassword"),
-			expectedDBClaims:   []byte(`{"customClaim1":4711}`),
+			expectedDBClaims:   `{"customClaim1":4711}`,
+			expectedDBMetadata: []byte(`null`),
 			expectedError:      errors.New("failed to get count of affected rows: a random error"),
 		},
 	}
@@ -237,8 +447,8 @@ func TestStorage_UpdateUser(t *testing.T) {
 			}
 
 			mock.
-				ExpectExec(`UPDATE users SET password = \$2, claims = \$3 WHERE email = \$1;`).
-				WithArgs(tt.expectedDBEMail, tt.expectedDBPassword, tt.expectedDBClaims).
+				ExpectExec(`UPDATE users SET password = \$2, claims = \$3, password_changed_at = \$4, password_max_age_days = \$5, username = NULLIF\(\$6, ''\), phone = NULLIF\(\$7, ''\), metadata = \$8 WHERE id = \$1;`).
+				WithArgs(tt.expectedDBID, tt.expectedDBPassword, tt.expectedDBClaims, tt.givenUser.PasswordChangedAt, tt.givenUser.PasswordMaxAgeDays, tt.givenUser.Username, tt.givenUser.Phone, tt.expectedDBMetadata).
 				WillReturnError(tt.dbResponseErr).
 				WillReturnResult(tt.dbResult)
 
@@ -252,59 +462,212 @@ func TestStorage_UpdateUser(t *testing.T) {
 	}
 }
 
+func TestStorage_PatchUser(t *testing.T) {
+	tests := []struct {
+		name                string
+		selectDBResponseErr error
+		patchErr            error
+		updateDBResponseErr error
+		commitDBResponseErr error
+		expectUpdate        bool
+		expectCommit        bool
+		expectedError       error
+	}{
+		{
+			name:         "Happycase",
+			expectUpdate: true,
+			expectCommit: true,
+		},
+		{
+			name:                "User not found",
+			selectDBResponseErr: sql.ErrNoRows,
+			expectedError:       ErrUserNotFound,
+		},
+		{
+			name:          "Patch error",
+			patchErr:      errors.New("invalid patch"),
+			expectedError: errors.New("invalid patch"),
+		},
+		{
+			name:                "Unexpected update db error",
+			updateDBResponseErr: errors.New("nope"),
+			expectUpdate:        true,
+			expectedError:       errors.New("failed to exec update stmt: nope"),
+		},
+		{
+			name:                "Unexpected commit error",
+			commitDBResponseErr: errors.New("nope"),
+			expectUpdate:        true,
+			expectCommit:        true,
+			expectedError:       errors.New("failed to commit patch transaction: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			mock.ExpectBegin()
+
+			mock.
+				ExpectQuery(`SELECT ` + userColumnsPattern + ` FROM users WHERE id = \$1 FOR UPDATE;`).
+				WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b").
+				WillReturnError(tt.selectDBResponseErr).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password", "claims", "password_changed_at", "password_max_age_days", "display_email", "username", "phone", "metadata", "last_login_at"}).
+					AddRow("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", "info@leberkleber.io", "bcryptedPassword", `{"a": "b"}`, time.Date(2020, 2, 1, 4, 46, 45, 2, time.UTC), 0, "info@leberkleber.io", "", "", nil, nil))
+
+			if tt.expectUpdate {
+				mock.
+					ExpectExec(`UPDATE users SET password = \$2, claims = \$3, password_changed_at = \$4, password_max_age_days = \$5, username = NULLIF\(\$6, ''\), phone = NULLIF\(\$7, ''\), metadata = \$8 WHERE id = \$1;`).
+					WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", []byte("bcryptedPassword"), `{"a":"b","c":"d"}`, time.Date(2020, 2, 1, 4, 46, 45, 2, time.UTC), 0, "alice", "", []byte(`null`)).
+					WillReturnError(tt.updateDBResponseErr).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			if tt.expectCommit {
+				mock.ExpectCommit().WillReturnError(tt.commitDBResponseErr)
+			}
+
+			s := Storage{db: db}
+
+			err = s.PatchUser("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", func(u *User) error {
+				if u.EMail != "info@leberkleber.io" {
+					t.Errorf("Patched user is not as expected. Given email: %q", u.EMail)
+				}
+
+				u.Username = "alice"
+				u.Claims["c"] = "d"
+				return tt.patchErr
+			})
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Errorf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unfulfilled db expectations: %s", err)
+			}
+		})
+	}
+}
+
 func TestStorage_DeleteUser(t *testing.T) {
 	tests := []struct {
 		name                  string
-		givenEMail            string
+		givenID               string
 		tokensDBResponseErr   error
 		tokensDBResult        driver.Result
+		historyDBResponseErr  error
+		historyDBResult       driver.Result
+		totpDBResponseErr     error
+		totpDBResult          driver.Result
+		webAuthnDBResponseErr error
+		webAuthnDBResult      driver.Result
+		recoveryDBResponseErr error
+		recoveryDBResult      driver.Result
 		usersDBResponseErr    error
 		usersDBResult         driver.Result
-		expectedTokensDBEMail string
-		expectedUsersDBEMail  string
+		expectedTokensDBID    string
+		expectedUsersDBID     string
 		expectedError         error
 	}{
 		{
-			name:                  "Happycase",
-			givenEMail:            "info@leberkleber.io",
-			tokensDBResult:        sqlmock.NewResult(0, 5),
-			usersDBResult:         sqlmock.NewResult(0, 1),
-			expectedTokensDBEMail: "info@leberkleber.io",
-			expectedUsersDBEMail:  "info@leberkleber.io",
+			name:               "Happycase",
+			givenID:            "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			tokensDBResult:     sqlmock.NewResult(0, 5),
+			historyDBResult:    sqlmock.NewResult(0, 3),
+			totpDBResult:       sqlmock.NewResult(0, 1),
+			webAuthnDBResult:   sqlmock.NewResult(0, 2),
+			recoveryDBResult:   sqlmock.NewResult(0, 10),
+			usersDBResult:      sqlmock.NewResult(0, 1),
+			expectedTokensDBID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			expectedUsersDBID:  "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+		},
+		{
+			name:                "Unexpected tokens db error",
+			givenID:             "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			tokensDBResponseErr: errors.New("nope"),
+			expectedTokensDBID:  "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			expectedError:       errors.New("failed to exec delete tokens from user stmt: nope"),
+		},
+		{
+			name:                 "Unexpected password-history db error",
+			givenID:              "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			tokensDBResult:       sqlmock.NewResult(0, 5),
+			historyDBResponseErr: errors.New("nope"),
+			expectedTokensDBID:   "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			expectedError:        errors.New("failed to exec delete password-history from user stmt: nope"),
 		},
 		{
-			name:                  "Unexpected tokens db error",
-			givenEMail:            "info@leberkleber.io",
-			tokensDBResponseErr:   errors.New("nope"),
-			expectedTokensDBEMail: "info@leberkleber.io",
-			expectedError:         errors.New("failed to exec delete tokens from user stmt: nope"),
+			name:               "Unexpected totp db error",
+			givenID:            "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			tokensDBResult:     sqlmock.NewResult(0, 5),
+			historyDBResult:    sqlmock.NewResult(0, 3),
+			totpDBResponseErr:  errors.New("nope"),
+			expectedTokensDBID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			expectedError:      errors.New("failed to exec delete totp from user stmt: nope"),
 		},
 		{
-			name:                  "Unexpected user db error",
-			givenEMail:            "info@leberkleber.io",
+			name:                  "Unexpected webauthn db error",
+			givenID:               "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
 			tokensDBResult:        sqlmock.NewResult(0, 5),
-			usersDBResponseErr:    errors.New("nope"),
-			expectedTokensDBEMail: "info@leberkleber.io",
-			expectedUsersDBEMail:  "info@leberkleber.io",
-			expectedError:         errors.New("failed to exec delete user stmt: nope"),
+			historyDBResult:       sqlmock.NewResult(0, 3),
+			totpDBResult:          sqlmock.NewResult(0, 1),
+			webAuthnDBResponseErr: errors.New("nope"),
+			expectedTokensDBID:    "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			expectedError:         errors.New("failed to exec delete webauthn credentials from user stmt: nope"),
 		},
 		{
-			name:                  "User doesn't exist",
-			givenEMail:            "info@leberkleber.io",
-			tokensDBResult:        sqlmock.NewResult(0, 0),
-			usersDBResult:         sqlmock.NewResult(0, 0),
-			expectedTokensDBEMail: "info@leberkleber.io",
-			expectedUsersDBEMail:  "info@leberkleber.io",
-			expectedError:         ErrUserNotFound,
+			name:                  "Unexpected recovery-codes db error",
+			givenID:               "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			tokensDBResult:        sqlmock.NewResult(0, 5),
+			historyDBResult:       sqlmock.NewResult(0, 3),
+			totpDBResult:          sqlmock.NewResult(0, 1),
+			webAuthnDBResult:      sqlmock.NewResult(0, 2),
+			recoveryDBResponseErr: errors.New("nope"),
+			expectedTokensDBID:    "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			expectedError:         errors.New("failed to exec delete recovery-codes from user stmt: nope"),
 		},
 		{
-			name:                  "Could not get could of affected rows",
-			givenEMail:            "info@leberkleber.io",
-			tokensDBResult:        sqlmock.NewResult(0, 0),
-			usersDBResult:         sqlmock.NewErrorResult(errors.New("a random error")),
-			expectedTokensDBEMail: "info@leberkleber.io",
-			expectedUsersDBEMail:  "info@leberkleber.io",
-			expectedError:         errors.New("failed to get count of affected rows: a random error"),
+			name:               "Unexpected user db error",
+			givenID:            "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			tokensDBResult:     sqlmock.NewResult(0, 5),
+			historyDBResult:    sqlmock.NewResult(0, 0),
+			totpDBResult:       sqlmock.NewResult(0, 0),
+			webAuthnDBResult:   sqlmock.NewResult(0, 0),
+			recoveryDBResult:   sqlmock.NewResult(0, 0),
+			usersDBResponseErr: errors.New("nope"),
+			expectedTokensDBID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			expectedUsersDBID:  "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			expectedError:      errors.New("failed to exec delete user stmt: nope"),
+		},
+		{
+			name:               "User doesn't exist",
+			givenID:            "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			tokensDBResult:     sqlmock.NewResult(0, 0),
+			historyDBResult:    sqlmock.NewResult(0, 0),
+			totpDBResult:       sqlmock.NewResult(0, 0),
+			webAuthnDBResult:   sqlmock.NewResult(0, 0),
+			recoveryDBResult:   sqlmock.NewResult(0, 0),
+			usersDBResult:      sqlmock.NewResult(0, 0),
+			expectedTokensDBID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			expectedUsersDBID:  "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			expectedError:      ErrUserNotFound,
+		},
+		{
+			name:               "Could not get could of affected rows",
+			givenID:            "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			tokensDBResult:     sqlmock.NewResult(0, 0),
+			historyDBResult:    sqlmock.NewResult(0, 0),
+			totpDBResult:       sqlmock.NewResult(0, 0),
+			webAuthnDBResult:   sqlmock.NewResult(0, 0),
+			recoveryDBResult:   sqlmock.NewResult(0, 0),
+			usersDBResult:      sqlmock.NewErrorResult(errors.New("a random error")),
+			expectedTokensDBID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			expectedUsersDBID:  "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			expectedError:      errors.New("failed to get count of affected rows: a random error"),
 		},
 	}
 
@@ -318,14 +681,43 @@ func TestStorage_DeleteUser(t *testing.T) {
 			mock.ExpectBegin()
 
 			mock.
-				ExpectExec(`DELETE FROM tokens WHERE email = \$1;`).
-				WithArgs(tt.expectedTokensDBEMail).
+				ExpectExec(`DELETE FROM tokens WHERE user_id = \$1;`).
+				WithArgs(tt.expectedTokensDBID).
 				WillReturnError(tt.tokensDBResponseErr).
 				WillReturnResult(tt.tokensDBResult)
 
 			mock.
-				ExpectExec(`DELETE FROM users WHERE email = \$1;`).
-				WithArgs(tt.expectedUsersDBEMail).
+				ExpectExec(`DELETE FROM password_history WHERE user_id = \$1;`).
+				WithArgs(tt.expectedTokensDBID).
+				WillReturnError(tt.historyDBResponseErr).
+				WillReturnResult(tt.historyDBResult)
+
+			mock.
+				ExpectExec(`DELETE FROM user_totp WHERE user_id = \$1;`).
+				WithArgs(tt.expectedTokensDBID).
+				WillReturnError(tt.totpDBResponseErr).
+				WillReturnResult(tt.totpDBResult)
+
+			mock.
+				ExpectExec(`DELETE FROM webauthn_credentials WHERE user_id = \$1;`).
+				WithArgs(tt.expectedTokensDBID).
+				WillReturnError(tt.webAuthnDBResponseErr).
+				WillReturnResult(tt.webAuthnDBResult)
+
+			mock.
+				ExpectExec(`DELETE FROM mfa_recovery_codes WHERE user_id = \$1;`).
+				WithArgs(tt.expectedTokensDBID).
+				WillReturnError(tt.recoveryDBResponseErr).
+				WillReturnResult(tt.recoveryDBResult)
+
+			mock.
+				ExpectExec(`DELETE FROM logins WHERE user_id = \$1;`).
+				WithArgs(tt.expectedTokensDBID).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+
+			mock.
+				ExpectExec(`DELETE FROM users WHERE id = \$1;`).
+				WithArgs(tt.expectedUsersDBID).
 				WillReturnError(tt.usersDBResponseErr).
 				WillReturnResult(tt.usersDBResult)
 
@@ -335,7 +727,186 @@ func TestStorage_DeleteUser(t *testing.T) {
 
 			s := Storage{db: db}
 
-			err = s.DeleteUser(tt.givenEMail)
+			err = s.DeleteUser(tt.givenID)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Errorf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestStorage_UserEMails(t *testing.T) {
+	tests := []struct {
+		name           string
+		dbResponseErr  error
+		dbResponseRows *sqlmock.Rows
+		expectedEMails map[string]string
+		expectedErr    error
+	}{
+		{
+			name: "Happycase",
+			dbResponseRows: sqlmock.NewRows([]string{"id", "email"}).
+				AddRow("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", "Alice@Example.com").
+				AddRow("0d6b2e5c-8f4a-4c1b-9e3d-7a2f1b6c5d4e", "bob@example.com"),
+			expectedEMails: map[string]string{
+				"c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b": "Alice@Example.com",
+				"0d6b2e5c-8f4a-4c1b-9e3d-7a2f1b6c5d4e": "bob@example.com",
+			},
+		},
+		{
+			name:           "No users",
+			dbResponseRows: sqlmock.NewRows([]string{"id", "email"}),
+			expectedEMails: map[string]string{},
+		},
+		{
+			name:          "Error while exec stmt",
+			dbResponseErr: errors.New("nope"),
+			expectedErr:   errors.New("failed to exec select-emails-stmt: nope"),
+		},
+		{
+			name: "Unable to scan sql response",
+			dbResponseRows: sqlmock.NewRows([]string{"id", "email"}).
+				AddRow("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", nil),
+			expectedErr: errors.New("failed to scan select-emails-stmt result: sql: Scan error on column index 1, name \"email\": converting NULL to string is unsupported"),
+		},
+		{
+			name: "Error while reading rows",
+			dbResponseRows: sqlmock.NewRows([]string{"id", "email"}).
+				AddRow("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", "Alice@Example.com").
+				RowError(0, errors.New("nope")),
+			expectedErr: errors.New("failed to read select-emails-stmt result: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			expectedQuery := mock.
+				ExpectQuery(`SELECT id, email FROM users WHERE email IS NOT NULL;`).
+				WillReturnError(tt.dbResponseErr)
+			if tt.dbResponseRows != nil {
+				expectedQuery.WillReturnRows(tt.dbResponseRows)
+			}
+
+			s := Storage{db: db}
+
+			emails, err := s.UserEMails()
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedErr) {
+				t.Errorf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedErr, err)
+			}
+			if !reflect.DeepEqual(emails, tt.expectedEMails) {
+				t.Errorf("Returned emails are not as expected. Expected:\n%#v\nGiven:\n%#v", tt.expectedEMails, emails)
+			}
+		})
+	}
+}
+
+func TestStorage_NormalizeUserEMail(t *testing.T) {
+	tests := []struct {
+		name          string
+		dbResponseErr error
+		dbResult      driver.Result
+		expectedError error
+	}{
+		{
+			name:     "Happycase",
+			dbResult: sqlmock.NewResult(0, 1),
+		},
+		{
+			name:          "Email already exists",
+			dbResponseErr: &pq.Error{Code: "23505", Constraint: "email_unique"},
+			expectedError: ErrUserAlreadyExists,
+		},
+		{
+			name:          "Unexpected db error",
+			dbResponseErr: errors.New("nope"),
+			expectedError: errors.New("failed to exec update stmt: nope"),
+		},
+		{
+			name:          "User not found",
+			dbResult:      sqlmock.NewResult(0, 0),
+			expectedError: ErrUserNotFound,
+		},
+		{
+			name:          "Unexpected result error",
+			dbResult:      sqlmock.NewErrorResult(errors.New("a random error")),
+			expectedError: errors.New("failed to get count of affected rows: a random error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			mock.
+				ExpectExec(`UPDATE users SET display_email = COALESCE\(display_email, email\), email = \$2 WHERE id = \$1;`).
+				WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", "Info@leberkleber.io").
+				WillReturnError(tt.dbResponseErr).
+				WillReturnResult(tt.dbResult)
+
+			s := Storage{db: db}
+
+			err = s.NormalizeUserEMail("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", "Info@leberkleber.io")
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Errorf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestStorage_MarkUserInvited(t *testing.T) {
+	invitedAt := time.Date(2020, 8, 1, 4, 46, 45, 2, time.UTC)
+
+	tests := []struct {
+		name          string
+		dbResponseErr error
+		dbResult      driver.Result
+		expectedError error
+	}{
+		{
+			name:     "Happycase",
+			dbResult: sqlmock.NewResult(0, 1),
+		},
+		{
+			name:          "Unexpected db error",
+			dbResponseErr: errors.New("nope"),
+			expectedError: errors.New("failed to exec update stmt: nope"),
+		},
+		{
+			name:          "User not found",
+			dbResult:      sqlmock.NewResult(0, 0),
+			expectedError: ErrUserNotFound,
+		},
+		{
+			name:          "Unexpected result error",
+			dbResult:      sqlmock.NewErrorResult(errors.New("a random error")),
+			expectedError: errors.New("failed to get count of affected rows: a random error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			mock.
+				ExpectExec(`UPDATE users SET invited_at = \$2 WHERE id = \$1;`).
+				WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", invitedAt).
+				WillReturnError(tt.dbResponseErr).
+				WillReturnResult(tt.dbResult)
+
+			s := Storage{db: db}
+
+			err = s.MarkUserInvited("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", invitedAt)
 			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
 				t.Errorf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
 			}
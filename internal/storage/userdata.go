@@ -0,0 +1,289 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
+	"time"
+)
+
+// UserData is everything stored about a user. Secrets (password hashes, token values, totp secrets, webauthn public
+// keys and recovery code hashes) are never part of it.
+type UserData struct {
+	User                User
+	Tokens              []TokenData
+	PasswordHistory     []time.Time
+	TOTP                *TOTPData
+	WebAuthnCredentials []WebAuthnCredentialData
+	RecoveryCodes       []RecoveryCodeData
+	Logins              []Login
+}
+
+// TokenData describes a stored token without its value
+type TokenData struct {
+	Type      string
+	CreatedAt time.Time
+	Attempts  int
+}
+
+// TOTPData describes the stored totp enrolment without its secret
+type TOTPData struct {
+	Confirmed bool
+	CreatedAt time.Time
+}
+
+// WebAuthnCredentialData describes a stored webauthn credential without its public key
+type WebAuthnCredentialData struct {
+	ID         []byte
+	SignCount  uint32
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+}
+
+// RecoveryCodeData describes a stored recovery code without its hash
+type RecoveryCodeData struct {
+	CreatedAt time.Time
+	UsedAt    *time.Time
+}
+
+// userDataTable is a table which contains rows of users
+type userDataTable struct {
+	table string
+	// name is the name of the table in error messages
+	name string
+}
+
+// userDataTables are all tables besides users which contain rows of users. The rows will be deleted in this order on
+// user deletion.
+var userDataTables = []userDataTable{
+	{table: "tokens", name: "tokens"},
+	{table: "password_history", name: "password-history"},
+	{table: "user_totp", name: "totp"},
+	{table: "webauthn_credentials", name: "webauthn credentials"},
+	{table: "mfa_recovery_codes", name: "recovery-codes"},
+	{table: "logins", name: "logins"},
+}
+
+// UserData finds everything stored about the user with the given id. All rows will be read from the same snapshot.
+// return ErrUserNotFound when user not found
+func (s *Storage) UserData(id string) (UserData, error) {
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return UserData{}, fmt.Errorf("failed to begin user-data transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	u, err := scanUser(tx.QueryRow("SELECT "+userColumns+" FROM users WHERE id = $1;", id))
+	if err != nil {
+		return UserData{}, err
+	}
+
+	data := UserData{User: u}
+
+	err = queryRows(tx, "tokens", "SELECT type, created_at, attempts FROM tokens WHERE user_id = $1 ORDER BY created_at;", id,
+		func(rows *sql.Rows) error {
+			var t TokenData
+			err := rows.Scan(&t.Type, &t.CreatedAt, &t.Attempts)
+			data.Tokens = append(data.Tokens, t)
+			return err
+		})
+	if err != nil {
+		return UserData{}, err
+	}
+
+	err = queryRows(tx, "password-history", "SELECT created_at FROM password_history WHERE user_id = $1 ORDER BY created_at;", id,
+		func(rows *sql.Rows) error {
+			var createdAt time.Time
+			err := rows.Scan(&createdAt)
+			data.PasswordHistory = append(data.PasswordHistory, createdAt)
+			return err
+		})
+	if err != nil {
+		return UserData{}, err
+	}
+
+	err = queryRows(tx, "totp", "SELECT confirmed, created_at FROM user_totp WHERE user_id = $1;", id,
+		func(rows *sql.Rows) error {
+			data.TOTP = &TOTPData{}
+			return rows.Scan(&data.TOTP.Confirmed, &data.TOTP.CreatedAt)
+		})
+	if err != nil {
+		return UserData{}, err
+	}
+
+	err = queryRows(tx, "webauthn-credentials", "SELECT credential_id, sign_count, created_at, last_used_at "+
+		"FROM webauthn_credentials WHERE user_id = $1 ORDER BY created_at;", id,
+		func(rows *sql.Rows) error {
+			var c WebAuthnCredentialData
+			var lastUsedAt sql.NullTime
+			err := rows.Scan(&c.ID, &c.SignCount, &c.CreatedAt, &lastUsedAt)
+			if lastUsedAt.Valid {
+				c.LastUsedAt = &lastUsedAt.Time
+			}
+			data.WebAuthnCredentials = append(data.WebAuthnCredentials, c)
+			return err
+		})
+	if err != nil {
+		return UserData{}, err
+	}
+
+	err = queryRows(tx, "recovery-codes", "SELECT created_at, used_at FROM mfa_recovery_codes WHERE user_id = $1 ORDER BY id;", id,
+		func(rows *sql.Rows) error {
+			var c RecoveryCodeData
+			var usedAt sql.NullTime
+			err := rows.Scan(&c.CreatedAt, &usedAt)
+			if usedAt.Valid {
+				c.UsedAt = &usedAt.Time
+			}
+			data.RecoveryCodes = append(data.RecoveryCodes, c)
+			return err
+		})
+	if err != nil {
+		return UserData{}, err
+	}
+
+	err = queryRows(tx, "logins", "SELECT id, user_id, created_at, ip, user_agent, outcome, factor "+
+		"FROM logins WHERE user_id = $1 ORDER BY created_at, id;", id,
+		func(rows *sql.Rows) error {
+			var l Login
+			err := rows.Scan(&l.ID, &l.UserID, &l.CreatedAt, &l.IP, &l.UserAgent, &l.Outcome, &l.Factor)
+			data.Logins = append(data.Logins, l)
+			return err
+		})
+	if err != nil {
+		return UserData{}, err
+	}
+
+	return data, nil
+}
+
+// EraseUser deletes the user with the given id and all of its rows in all other tables in one transaction and returns
+// the count of deleted rows per table.
+// return ErrUserNotFound when user not found
+func (s *Storage) EraseUser(id string) (map[string]int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin delete transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	deletedRows := map[string]int64{}
+	for _, t := range userDataTables {
+		resp, err := tx.Exec("DELETE FROM "+t.table+" WHERE user_id = $1;", id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to exec delete %s from user stmt: %w", t.name, err)
+		}
+
+		deletedRows[t.table], err = resp.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get count of affected rows: %w", err)
+		}
+	}
+
+	resp, err := tx.Exec("DELETE FROM users WHERE id = $1;", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exec delete user stmt: %w", err)
+	}
+
+	ra, err := resp.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get count of affected rows: %w", err)
+	}
+	if ra == 0 {
+		return nil, ErrUserNotFound
+	}
+	deletedRows["users"] = ra
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit delete transaction: %w", err)
+	}
+
+	return deletedRows, nil
+}
+
+// DeleteUnverifiedUsers deletes all users without password (who never accepted their invitation) which have been
+// invited the last time before the given time together with all of their rows in all other tables in one transaction
+// and returns the count of deleted users. Users without invitation time will be kept.
+func (s *Storage) DeleteUnverifiedUsers(invitedBefore time.Time) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin delete transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// lock the users, so they can not accept their invitation while they will be deleted
+	rows, err := tx.Query("SELECT id FROM users WHERE length(password) = 0 AND invited_at < $1 FOR UPDATE;", invitedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to exec select-unverified-users-stmt: %w", err)
+	}
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		err = rows.Scan(&id)
+		if err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("failed to scan select-unverified-users-stmt result: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	err = rows.Err()
+	_ = rows.Close()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read select-unverified-users-stmt result: %w", err)
+	}
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	for _, t := range userDataTables {
+		_, err := tx.Exec("DELETE FROM "+t.table+" WHERE user_id = ANY($1::uuid[]);", pq.Array(ids))
+		if err != nil {
+			return 0, fmt.Errorf("failed to exec delete %s from users stmt: %w", t.name, err)
+		}
+	}
+
+	resp, err := tx.Exec("DELETE FROM users WHERE id = ANY($1::uuid[]);", pq.Array(ids))
+	if err != nil {
+		return 0, fmt.Errorf("failed to exec delete users stmt: %w", err)
+	}
+
+	ra, err := resp.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get count of affected rows: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("failed to commit delete transaction: %w", err)
+	}
+
+	return ra, nil
+}
+
+// queryRows executes the given query with the given user id and calls scan for each row. name is the name of the
+// queried rows in error messages.
+func queryRows(tx *sql.Tx, name, query, id string, scan func(rows *sql.Rows) error) error {
+	rows, err := tx.Query(query, id)
+	if err != nil {
+		return fmt.Errorf("failed to exec select-%s-stmt: %w", name, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		err = scan(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan select-%s-stmt result: %w", name, err)
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return fmt.Errorf("failed to read select-%s-stmt result: %w", name, err)
+	}
+
+	return nil
+}
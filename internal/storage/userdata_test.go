@@ -0,0 +1,240 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStorage_UserData(t *testing.T) {
+	createdAt := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+	usedAt := time.Date(2020, 2, 2, 4, 46, 45, 0, time.UTC)
+
+	tests := []struct {
+		name             string
+		userDBErr        error
+		tokensDBErr      error
+		recoveryDBErr    error
+		expectedUserData UserData
+		expectedError    error
+	}{
+		{
+			name: "Happycase",
+			expectedUserData: UserData{
+				User: User{
+					ID:                "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+					EMail:             "info@leberkleber.io",
+					DisplayEMail:      "info@leberkleber.io",
+					Password:          []byte("bcryptedPassword"),
+					Claims:            map[string]interface{}{},
+					PasswordChangedAt: createdAt,
+				},
+				Tokens:          []TokenData{{Type: TokenTypeReset, CreatedAt: createdAt, Attempts: 1}},
+				PasswordHistory: []time.Time{createdAt},
+				TOTP:            &TOTPData{Confirmed: true, CreatedAt: createdAt},
+				WebAuthnCredentials: []WebAuthnCredentialData{
+					{ID: []byte("credential"), SignCount: 3, CreatedAt: createdAt, LastUsedAt: &usedAt},
+				},
+				RecoveryCodes: []RecoveryCodeData{{CreatedAt: createdAt}, {CreatedAt: createdAt, UsedAt: &usedAt}},
+				Logins: []Login{{
+					ID:        1,
+					UserID:    "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+					CreatedAt: usedAt,
+					IP:        "127.0.0.1",
+					UserAgent: "curl/7.68.0",
+					Outcome:   LoginOutcomeSuccess,
+					Factor:    "password",
+				}},
+			},
+		},
+		{
+			name:          "User not found",
+			userDBErr:     sql.ErrNoRows,
+			expectedError: ErrUserNotFound,
+		},
+		{
+			name:          "Unexpected tokens db error",
+			tokensDBErr:   errors.New("nope"),
+			expectedError: errors.New("failed to exec select-tokens-stmt: nope"),
+		},
+		{
+			name:          "Unexpected recovery-codes db error",
+			recoveryDBErr: errors.New("nope"),
+			expectedError: errors.New("failed to exec select-recovery-codes-stmt: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(`SELECT ` + userColumnsPattern + ` FROM users WHERE id = \$1;`).
+				WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b").
+				WillReturnError(tt.userDBErr).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password", "claims", "password_changed_at", "password_max_age_days", "display_email", "username", "phone", "metadata", "last_login_at"}).
+					AddRow("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", "info@leberkleber.io", "bcryptedPassword", `{}`, createdAt, 0, "info@leberkleber.io", "", "", nil, nil))
+			mock.ExpectQuery(`SELECT type, created_at, attempts FROM tokens WHERE user_id = \$1 ORDER BY created_at;`).
+				WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b").
+				WillReturnError(tt.tokensDBErr).
+				WillReturnRows(sqlmock.NewRows([]string{"type", "created_at", "attempts"}).AddRow(TokenTypeReset, createdAt, 1))
+			mock.ExpectQuery(`SELECT created_at FROM password_history WHERE user_id = \$1 ORDER BY created_at;`).
+				WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b").
+				WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(createdAt))
+			mock.ExpectQuery(`SELECT confirmed, created_at FROM user_totp WHERE user_id = \$1;`).
+				WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b").
+				WillReturnRows(sqlmock.NewRows([]string{"confirmed", "created_at"}).AddRow(true, createdAt))
+			mock.ExpectQuery(`SELECT credential_id, sign_count, created_at, last_used_at FROM webauthn_credentials WHERE user_id = \$1 ORDER BY created_at;`).
+				WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b").
+				WillReturnRows(sqlmock.NewRows([]string{"credential_id", "sign_count", "created_at", "last_used_at"}).
+					AddRow([]byte("credential"), 3, createdAt, usedAt))
+			mock.ExpectQuery(`SELECT created_at, used_at FROM mfa_recovery_codes WHERE user_id = \$1 ORDER BY id;`).
+				WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b").
+				WillReturnError(tt.recoveryDBErr).
+				WillReturnRows(sqlmock.NewRows([]string{"created_at", "used_at"}).AddRow(createdAt, nil).AddRow(createdAt, usedAt))
+			mock.ExpectQuery(`SELECT id, user_id, created_at, ip, user_agent, outcome, factor FROM logins WHERE user_id = \$1 ORDER BY created_at, id;`).
+				WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "created_at", "ip", "user_agent", "outcome", "factor"}).
+					AddRow(1, "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", usedAt, "127.0.0.1", "curl/7.68.0", LoginOutcomeSuccess, "password"))
+			mock.ExpectRollback()
+
+			s := Storage{db: db}
+
+			data, err := s.UserData("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b")
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(data, tt.expectedUserData) {
+				t.Errorf("Returned user data is not as expected. Expected:\n%#v\nGiven:\n%#v", tt.expectedUserData, data)
+			}
+		})
+	}
+}
+
+func TestStorage_EraseUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("Failed to create sql mock", err)
+	}
+
+	mock.ExpectBegin()
+	for i, table := range []string{"tokens", "password_history", "user_totp", "webauthn_credentials", "mfa_recovery_codes", "logins"} {
+		mock.ExpectExec(`DELETE FROM ` + table + ` WHERE user_id = \$1;`).
+			WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b").
+			WillReturnResult(sqlmock.NewResult(0, int64(i)))
+	}
+	mock.ExpectExec(`DELETE FROM users WHERE id = \$1;`).
+		WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	s := Storage{db: db}
+
+	deletedRows, err := s.EraseUser("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expectedDeletedRows := map[string]int64{
+		"tokens":               0,
+		"password_history":     1,
+		"user_totp":            2,
+		"webauthn_credentials": 3,
+		"mfa_recovery_codes":   4,
+		"logins":               5,
+		"users":                1,
+	}
+	if !reflect.DeepEqual(deletedRows, expectedDeletedRows) {
+		t.Errorf("Deleted rows are not as expected. Expected:\n%#v\nGiven:\n%#v", expectedDeletedRows, deletedRows)
+	}
+
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Errorf("Not all expectations were met: %s", err)
+	}
+}
+
+func TestStorage_DeleteUnverifiedUsers(t *testing.T) {
+	invitedBefore := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		dbUserIDs     []string
+		dbSelectErr   error
+		dbDeleteErr   error
+		expectedCount int64
+		expectedErr   error
+	}{
+		{
+			name:          "Happycase",
+			dbUserIDs:     []string{"c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", "0b1e4f3c-7d1a-4c5e-8b2f-3a9d6e1c4f7a"},
+			expectedCount: 2,
+		},
+		{
+			name: "No unverified users",
+		},
+		{
+			name:        "Unexpected select db error",
+			dbSelectErr: errors.New("nope"),
+			expectedErr: errors.New("failed to exec select-unverified-users-stmt: nope"),
+		},
+		{
+			name:        "Unexpected delete db error",
+			dbUserIDs:   []string{"c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b"},
+			dbDeleteErr: errors.New("nope"),
+			expectedErr: errors.New("failed to exec delete tokens from users stmt: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			mock.ExpectBegin()
+			rows := sqlmock.NewRows([]string{"id"})
+			for _, id := range tt.dbUserIDs {
+				rows.AddRow(id)
+			}
+			mock.ExpectQuery(`SELECT id FROM users WHERE length\(password\) = 0 AND invited_at < \$1 FOR UPDATE;`).
+				WithArgs(invitedBefore).
+				WillReturnRows(rows).
+				WillReturnError(tt.dbSelectErr)
+
+			if len(tt.dbUserIDs) > 0 {
+				if tt.dbDeleteErr != nil {
+					mock.ExpectExec(`DELETE FROM tokens WHERE user_id = ANY\(\$1::uuid\[\]\);`).WillReturnError(tt.dbDeleteErr)
+				} else {
+					for _, table := range []string{"tokens", "password_history", "user_totp", "webauthn_credentials", "mfa_recovery_codes", "logins"} {
+						mock.ExpectExec(`DELETE FROM ` + table + ` WHERE user_id = ANY\(\$1::uuid\[\]\);`).
+							WillReturnResult(sqlmock.NewResult(0, 1))
+					}
+					mock.ExpectExec(`DELETE FROM users WHERE id = ANY\(\$1::uuid\[\]\);`).
+						WillReturnResult(sqlmock.NewResult(0, int64(len(tt.dbUserIDs))))
+					mock.ExpectCommit()
+				}
+			}
+			mock.ExpectRollback()
+
+			s := Storage{db: db}
+
+			count, err := s.DeleteUnverifiedUsers(invitedBefore)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedErr) {
+				t.Fatalf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedErr, err)
+			}
+			if count != tt.expectedCount {
+				t.Errorf("Returned count is not as expected. Expected: %d. Given: %d", tt.expectedCount, count)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"time"
+)
+
+var ErrWebAuthnCredentialAlreadyExists = errors.New("webauthn credential already exists")
+var ErrWebAuthnCredentialNotFound = errors.New("could not found webauthn credential")
+
+// WebAuthnCredential is a registered webauthn public key credential (security key, platform authenticator) of a user
+type WebAuthnCredential struct {
+	ID     []byte
+	UserID string
+	// PublicKey is the COSE encoded public key
+	PublicKey  []byte
+	SignCount  uint32
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+}
+
+// WebAuthnCredentials finds all webauthn credentials of the user with the given id
+func (s Storage) WebAuthnCredentials(userID string) ([]WebAuthnCredential, error) {
+	rows, err := s.db.Query(
+		"SELECT credential_id, public_key, sign_count, created_at, last_used_at FROM webauthn_credentials "+
+			"WHERE user_id = $1 ORDER BY created_at;",
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exec select-webauthn-credentials-stmt: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var credentials []WebAuthnCredential
+	for rows.Next() {
+		c := WebAuthnCredential{
+			UserID: userID,
+		}
+		var lastUsedAt sql.NullTime
+		err := rows.Scan(&c.ID, &c.PublicKey, &c.SignCount, &c.CreatedAt, &lastUsedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan select-webauthn-credentials-stmt result: %w", err)
+		}
+		if lastUsedAt.Valid {
+			c.LastUsedAt = &lastUsedAt.Time
+		}
+
+		credentials = append(credentials, c)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read select-webauthn-credentials-stmt result: %w", err)
+	}
+
+	return credentials, nil
+}
+
+// CreateWebAuthnCredential persists the given webauthn credential. UserID must match to a users id.
+// return ErrWebAuthnCredentialAlreadyExists when a credential with the same id has already been registered
+func (s Storage) CreateWebAuthnCredential(c WebAuthnCredential) error {
+	_, err := s.db.Exec(
+		"INSERT INTO webauthn_credentials (credential_id, user_id, public_key, sign_count, created_at) "+
+			"VALUES($1, $2, $3, $4, $5);",
+		c.ID, c.UserID, c.PublicKey, c.SignCount, c.CreatedAt,
+	)
+	if err != nil {
+		pqErr, ok := err.(*pq.Error)
+		if ok && pqErr.Constraint == "webauthn_credentials_id_unique" {
+			return ErrWebAuthnCredentialAlreadyExists
+		}
+		return fmt.Errorf("failed to exec create webauthn credential stmt: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateWebAuthnCredentialUsage stores the new sign count and the last usage of the webauthn credential with the given
+// id
+// return ErrWebAuthnCredentialNotFound when there is no credential with the given id
+func (s Storage) UpdateWebAuthnCredentialUsage(id []byte, signCount uint32, lastUsedAt time.Time) error {
+	res, err := s.db.Exec(
+		"UPDATE webauthn_credentials SET sign_count = $2, last_used_at = $3 WHERE credential_id = $1;",
+		id, signCount, lastUsedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to exec update webauthn credential stmt: %w", err)
+	}
+
+	ra, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get count of affected rows: %w", err)
+	}
+	if ra == 0 {
+		return ErrWebAuthnCredentialNotFound
+	}
+
+	return nil
+}
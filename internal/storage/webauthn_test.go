@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStorage_WebAuthnCredentials(t *testing.T) {
+	createdAt := time.Date(2020, 2, 1, 4, 46, 45, 2, time.UTC)
+	lastUsedAt := time.Date(2020, 3, 1, 4, 46, 45, 2, time.UTC)
+
+	tests := []struct {
+		name                string
+		dbResponseErr       error
+		dbResponseRows      *sqlmock.Rows
+		expectedCredentials []WebAuthnCredential
+		expectedErr         error
+	}{
+		{
+			name: "Happycase",
+			dbResponseRows: sqlmock.NewRows([]string{"credential_id", "public_key", "sign_count", "created_at", "last_used_at"}).
+				AddRow([]byte("id1"), []byte("key1"), 4, createdAt, lastUsedAt).
+				AddRow([]byte("id2"), []byte("key2"), 0, createdAt, nil),
+			expectedCredentials: []WebAuthnCredential{
+				{
+					ID:         []byte("id1"),
+					UserID:     "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+					PublicKey:  []byte("key1"),
+					SignCount:  4,
+					CreatedAt:  createdAt,
+					LastUsedAt: &lastUsedAt,
+				},
+				{
+					ID:        []byte("id2"),
+					UserID:    "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+					PublicKey: []byte("key2"),
+					CreatedAt: createdAt,
+				},
+			},
+		},
+		{
+			name:           "No credentials",
+			dbResponseRows: sqlmock.NewRows([]string{"credential_id", "public_key", "sign_count", "created_at", "last_used_at"}),
+		},
+		{
+			name:          "Unexpected db error",
+			dbResponseErr: errors.New("nope"),
+			expectedErr:   errors.New("failed to exec select-webauthn-credentials-stmt: nope"),
+		},
+		{
+			name: "Scan error",
+			dbResponseRows: sqlmock.NewRows([]string{"credential_id", "public_key", "sign_count", "created_at", "last_used_at"}).
+				AddRow([]byte("id1"), []byte("key1"), "nan", createdAt, nil),
+			expectedErr: errors.New(`failed to scan select-webauthn-credentials-stmt result: sql: Scan error on column index 2, name "sign_count": converting driver.Value type string ("nan") to a uint32: invalid syntax`),
+		},
+		{
+			name: "Read error",
+			dbResponseRows: sqlmock.NewRows([]string{"credential_id", "public_key", "sign_count", "created_at", "last_used_at"}).
+				AddRow([]byte("id1"), []byte("key1"), 4, createdAt, nil).
+				RowError(0, errors.New("nope")),
+			expectedErr: errors.New("failed to read select-webauthn-credentials-stmt result: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			expectedQuery := mock.
+				ExpectQuery(`SELECT credential_id, public_key, sign_count, created_at, last_used_at FROM webauthn_credentials WHERE user_id = \$1 ORDER BY created_at;`).
+				WithArgs("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b").
+				WillReturnError(tt.dbResponseErr)
+			if tt.dbResponseRows != nil {
+				expectedQuery.WillReturnRows(tt.dbResponseRows)
+			}
+
+			s := Storage{db: db}
+
+			credentials, err := s.WebAuthnCredentials("c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b")
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedErr) {
+				t.Errorf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedErr, err)
+			}
+			if !reflect.DeepEqual(credentials, tt.expectedCredentials) {
+				t.Errorf("Returned credentials are not as expected. Expected:\n%#v\nGiven:\n%#v", tt.expectedCredentials, credentials)
+			}
+		})
+	}
+}
+
+func TestStorage_CreateWebAuthnCredential(t *testing.T) {
+	createdAt := time.Date(2020, 2, 1, 4, 46, 45, 2, time.UTC)
+
+	tests := []struct {
+		name          string
+		dbResponseErr error
+		expectedError error
+	}{
+		{
+			name: "Happycase",
+		},
+		{
+			name:          "Credential already exists",
+			dbResponseErr: &pq.Error{Constraint: "webauthn_credentials_id_unique"},
+			expectedError: ErrWebAuthnCredentialAlreadyExists,
+		},
+		{
+			name:          "Unexpected db error",
+			dbResponseErr: errors.New("nope"),
+			expectedError: errors.New("failed to exec create webauthn credential stmt: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			mock.
+				ExpectExec(`INSERT INTO webauthn_credentials \(credential_id, user_id, public_key, sign_count, created_at\) VALUES\(\$1, \$2, \$3, \$4, \$5\);`).
+				WithArgs([]byte("id"), "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", []byte("key"), int64(3), createdAt).
+				WillReturnResult(sqlmock.NewResult(0, 1)).
+				WillReturnError(tt.dbResponseErr)
+
+			s := Storage{db: db}
+
+			err = s.CreateWebAuthnCredential(WebAuthnCredential{
+				ID:        []byte("id"),
+				UserID:    "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				PublicKey: []byte("key"),
+				SignCount: 3,
+				CreatedAt: createdAt,
+			})
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Errorf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestStorage_UpdateWebAuthnCredentialUsage(t *testing.T) {
+	lastUsedAt := time.Date(2020, 3, 1, 4, 46, 45, 2, time.UTC)
+
+	tests := []struct {
+		name          string
+		dbResponseErr error
+		rowsAffected  int64
+		expectedError error
+	}{
+		{
+			name:         "Happycase",
+			rowsAffected: 1,
+		},
+		{
+			name:          "Credential not found",
+			rowsAffected:  0,
+			expectedError: ErrWebAuthnCredentialNotFound,
+		},
+		{
+			name:          "Unexpected db error",
+			dbResponseErr: errors.New("nope"),
+			expectedError: errors.New("failed to exec update webauthn credential stmt: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal("Failed to create sql mock", err)
+			}
+
+			mock.
+				ExpectExec(`UPDATE webauthn_credentials SET sign_count = \$2, last_used_at = \$3 WHERE credential_id = \$1;`).
+				WithArgs([]byte("id"), int64(5), lastUsedAt).
+				WillReturnResult(sqlmock.NewResult(0, tt.rowsAffected)).
+				WillReturnError(tt.dbResponseErr)
+
+			s := Storage{db: db}
+
+			err = s.UpdateWebAuthnCredentialUsage([]byte("id"), 5, lastUsedAt)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Errorf("Returned error is not as expected. Expected:\n%q\nGiven:\n%q", tt.expectedError, err)
+			}
+		})
+	}
+}
@@ -6,16 +6,44 @@ package internal
 import (
 	"github.com/leberKleber/simple-jwt-provider/internal/storage"
 	"sync"
+	"time"
 )
 
 var (
-	lockStorageMockCreateToken           sync.RWMutex
-	lockStorageMockCreateUser            sync.RWMutex
-	lockStorageMockDeleteToken           sync.RWMutex
-	lockStorageMockDeleteUser            sync.RWMutex
-	lockStorageMockTokensByEMailAndToken sync.RWMutex
-	lockStorageMockUpdateUser            sync.RWMutex
-	lockStorageMockUser                  sync.RWMutex
+	lockStorageMockAddLogin                      sync.RWMutex
+	lockStorageMockAddPasswordHistory            sync.RWMutex
+	lockStorageMockCreateToken                   sync.RWMutex
+	lockStorageMockCreateUser                    sync.RWMutex
+	lockStorageMockCreateWebAuthnCredential      sync.RWMutex
+	lockStorageMockDeleteLoginsCreatedBefore     sync.RWMutex
+	lockStorageMockDeleteMFA                     sync.RWMutex
+	lockStorageMockDeleteToken                   sync.RWMutex
+	lockStorageMockDeleteTokensCreatedBefore     sync.RWMutex
+	lockStorageMockDeleteUnverifiedUsers         sync.RWMutex
+	lockStorageMockDeleteUser                    sync.RWMutex
+	lockStorageMockEraseUser                     sync.RWMutex
+	lockStorageMockIncrementTokenAttempts        sync.RWMutex
+	lockStorageMockLogins                        sync.RWMutex
+	lockStorageMockMarkPasswordExpiryReminded    sync.RWMutex
+	lockStorageMockMarkUserInvited               sync.RWMutex
+	lockStorageMockPasswordHistory               sync.RWMutex
+	lockStorageMockPatchUser                     sync.RWMutex
+	lockStorageMockReplaceRecoveryCodes          sync.RWMutex
+	lockStorageMockSaveTOTP                      sync.RWMutex
+	lockStorageMockTOTP                          sync.RWMutex
+	lockStorageMockTokensByUserIDAndType         sync.RWMutex
+	lockStorageMockUnusedRecoveryCodeCount       sync.RWMutex
+	lockStorageMockUpdateUser                    sync.RWMutex
+	lockStorageMockUpdateWebAuthnCredentialUsage sync.RWMutex
+	lockStorageMockUseRecoveryCode               sync.RWMutex
+	lockStorageMockUser                          sync.RWMutex
+	lockStorageMockUserByID                      sync.RWMutex
+	lockStorageMockUserByPhone                   sync.RWMutex
+	lockStorageMockUserByUsername                sync.RWMutex
+	lockStorageMockUserData                      sync.RWMutex
+	lockStorageMockUsersByClaims                 sync.RWMutex
+	lockStorageMockUsersToRemindOfPasswordExpiry sync.RWMutex
+	lockStorageMockWebAuthnCredentials           sync.RWMutex
 )
 
 // Ensure, that StorageMock does implement Storage.
@@ -28,27 +56,108 @@ var _ Storage = &StorageMock{}
 //
 //         // make and configure a mocked Storage
 //         mockedStorage := &StorageMock{
+//             AddLoginFunc: func(l storage.Login) error {
+// 	               panic("mock out the AddLogin method")
+//             },
+//             AddPasswordHistoryFunc: func(userID string, password []byte, createdAt time.Time, keep int) error {
+// 	               panic("mock out the AddPasswordHistory method")
+//             },
 //             CreateTokenFunc: func(t storage.Token) (int64, error) {
 // 	               panic("mock out the CreateToken method")
 //             },
 //             CreateUserFunc: func(user storage.User) error {
 // 	               panic("mock out the CreateUser method")
 //             },
+//             CreateWebAuthnCredentialFunc: func(c storage.WebAuthnCredential) error {
+// 	               panic("mock out the CreateWebAuthnCredential method")
+//             },
+//             DeleteLoginsCreatedBeforeFunc: func(createdBefore time.Time) (int64, error) {
+// 	               panic("mock out the DeleteLoginsCreatedBefore method")
+//             },
+//             DeleteMFAFunc: func(userID string) error {
+// 	               panic("mock out the DeleteMFA method")
+//             },
 //             DeleteTokenFunc: func(id int64) error {
 // 	               panic("mock out the DeleteToken method")
 //             },
-//             DeleteUserFunc: func(email string) error {
+//             DeleteTokensCreatedBeforeFunc: func(tokenType string, createdBefore time.Time) (int64, error) {
+// 	               panic("mock out the DeleteTokensCreatedBefore method")
+//             },
+//             DeleteUnverifiedUsersFunc: func(invitedBefore time.Time) (int64, error) {
+// 	               panic("mock out the DeleteUnverifiedUsers method")
+//             },
+//             DeleteUserFunc: func(id string) error {
 // 	               panic("mock out the DeleteUser method")
 //             },
-//             TokensByEMailAndTokenFunc: func(email string, token string) ([]storage.Token, error) {
-// 	               panic("mock out the TokensByEMailAndToken method")
+//             EraseUserFunc: func(id string) (map[string]int64, error) {
+// 	               panic("mock out the EraseUser method")
+//             },
+//             IncrementTokenAttemptsFunc: func(id int64) (int, error) {
+// 	               panic("mock out the IncrementTokenAttempts method")
+//             },
+//             LoginsFunc: func(userID string, limit int, offset int) ([]storage.Login, int, error) {
+// 	               panic("mock out the Logins method")
+//             },
+//             MarkPasswordExpiryRemindedFunc: func(userID string, remindedAt time.Time) error {
+// 	               panic("mock out the MarkPasswordExpiryReminded method")
+//             },
+//             MarkUserInvitedFunc: func(id string, invitedAt time.Time) error {
+// 	               panic("mock out the MarkUserInvited method")
+//             },
+//             PasswordHistoryFunc: func(userID string, limit int) ([][]byte, error) {
+// 	               panic("mock out the PasswordHistory method")
+//             },
+//             PatchUserFunc: func(id string, patch func(user *storage.User) error) error {
+// 	               panic("mock out the PatchUser method")
+//             },
+//             ReplaceRecoveryCodesFunc: func(userID string, codeHashes [][]byte, createdAt time.Time) error {
+// 	               panic("mock out the ReplaceRecoveryCodes method")
+//             },
+//             SaveTOTPFunc: func(t storage.TOTP) error {
+// 	               panic("mock out the SaveTOTP method")
+//             },
+//             TOTPFunc: func(userID string) (storage.TOTP, error) {
+// 	               panic("mock out the TOTP method")
+//             },
+//             TokensByUserIDAndTypeFunc: func(userID string, tokenType string) ([]storage.Token, error) {
+// 	               panic("mock out the TokensByUserIDAndType method")
+//             },
+//             UnusedRecoveryCodeCountFunc: func(userID string) (int, error) {
+// 	               panic("mock out the UnusedRecoveryCodeCount method")
 //             },
 //             UpdateUserFunc: func(user storage.User) error {
 // 	               panic("mock out the UpdateUser method")
 //             },
+//             UpdateWebAuthnCredentialUsageFunc: func(id []byte, signCount uint32, lastUsedAt time.Time) error {
+// 	               panic("mock out the UpdateWebAuthnCredentialUsage method")
+//             },
+//             UseRecoveryCodeFunc: func(userID string, codeHash []byte, usedAt time.Time) error {
+// 	               panic("mock out the UseRecoveryCode method")
+//             },
 //             UserFunc: func(email string) (storage.User, error) {
 // 	               panic("mock out the User method")
 //             },
+//             UserByIDFunc: func(id string) (storage.User, error) {
+// 	               panic("mock out the UserByID method")
+//             },
+//             UserByPhoneFunc: func(phone string) (storage.User, error) {
+// 	               panic("mock out the UserByPhone method")
+//             },
+//             UserByUsernameFunc: func(username string) (storage.User, error) {
+// 	               panic("mock out the UserByUsername method")
+//             },
+//             UserDataFunc: func(id string) (storage.UserData, error) {
+// 	               panic("mock out the UserData method")
+//             },
+//             UsersByClaimsFunc: func(predicates []storage.ClaimPredicate, limit int, offset int) ([]storage.User, int, error) {
+// 	               panic("mock out the UsersByClaims method")
+//             },
+//             UsersToRemindOfPasswordExpiryFunc: func(defaultMaxAgeDays int, reminderDays int, now time.Time) ([]storage.User, error) {
+// 	               panic("mock out the UsersToRemindOfPasswordExpiry method")
+//             },
+//             WebAuthnCredentialsFunc: func(userID string) ([]storage.WebAuthnCredential, error) {
+// 	               panic("mock out the WebAuthnCredentials method")
+//             },
 //         }
 //
 //         // use mockedStorage in code that requires Storage
@@ -56,29 +165,126 @@ var _ Storage = &StorageMock{}
 //
 //     }
 type StorageMock struct {
+	// AddLoginFunc mocks the AddLogin method.
+	AddLoginFunc func(l storage.Login) error
+
+	// AddPasswordHistoryFunc mocks the AddPasswordHistory method.
+	AddPasswordHistoryFunc func(userID string, password []byte, createdAt time.Time, keep int) error
+
 	// CreateTokenFunc mocks the CreateToken method.
 	CreateTokenFunc func(t storage.Token) (int64, error)
 
 	// CreateUserFunc mocks the CreateUser method.
 	CreateUserFunc func(user storage.User) error
 
+	// CreateWebAuthnCredentialFunc mocks the CreateWebAuthnCredential method.
+	CreateWebAuthnCredentialFunc func(c storage.WebAuthnCredential) error
+
+	// DeleteLoginsCreatedBeforeFunc mocks the DeleteLoginsCreatedBefore method.
+	DeleteLoginsCreatedBeforeFunc func(createdBefore time.Time) (int64, error)
+
+	// DeleteMFAFunc mocks the DeleteMFA method.
+	DeleteMFAFunc func(userID string) error
+
 	// DeleteTokenFunc mocks the DeleteToken method.
 	DeleteTokenFunc func(id int64) error
 
+	// DeleteTokensCreatedBeforeFunc mocks the DeleteTokensCreatedBefore method.
+	DeleteTokensCreatedBeforeFunc func(tokenType string, createdBefore time.Time) (int64, error)
+
+	// DeleteUnverifiedUsersFunc mocks the DeleteUnverifiedUsers method.
+	DeleteUnverifiedUsersFunc func(invitedBefore time.Time) (int64, error)
+
 	// DeleteUserFunc mocks the DeleteUser method.
-	DeleteUserFunc func(email string) error
+	DeleteUserFunc func(id string) error
+
+	// EraseUserFunc mocks the EraseUser method.
+	EraseUserFunc func(id string) (map[string]int64, error)
+
+	// IncrementTokenAttemptsFunc mocks the IncrementTokenAttempts method.
+	IncrementTokenAttemptsFunc func(id int64) (int, error)
+
+	// LoginsFunc mocks the Logins method.
+	LoginsFunc func(userID string, limit int, offset int) ([]storage.Login, int, error)
+
+	// MarkPasswordExpiryRemindedFunc mocks the MarkPasswordExpiryReminded method.
+	MarkPasswordExpiryRemindedFunc func(userID string, remindedAt time.Time) error
+
+	// MarkUserInvitedFunc mocks the MarkUserInvited method.
+	MarkUserInvitedFunc func(id string, invitedAt time.Time) error
+
+	// PasswordHistoryFunc mocks the PasswordHistory method.
+	PasswordHistoryFunc func(userID string, limit int) ([][]byte, error)
+
+	// PatchUserFunc mocks the PatchUser method.
+	PatchUserFunc func(id string, patch func(user *storage.User) error) error
+
+	// ReplaceRecoveryCodesFunc mocks the ReplaceRecoveryCodes method.
+	ReplaceRecoveryCodesFunc func(userID string, codeHashes [][]byte, createdAt time.Time) error
+
+	// SaveTOTPFunc mocks the SaveTOTP method.
+	SaveTOTPFunc func(t storage.TOTP) error
+
+	// TOTPFunc mocks the TOTP method.
+	TOTPFunc func(userID string) (storage.TOTP, error)
 
-	// TokensByEMailAndTokenFunc mocks the TokensByEMailAndToken method.
-	TokensByEMailAndTokenFunc func(email string, token string) ([]storage.Token, error)
+	// TokensByUserIDAndTypeFunc mocks the TokensByUserIDAndType method.
+	TokensByUserIDAndTypeFunc func(userID string, tokenType string) ([]storage.Token, error)
+
+	// UnusedRecoveryCodeCountFunc mocks the UnusedRecoveryCodeCount method.
+	UnusedRecoveryCodeCountFunc func(userID string) (int, error)
 
 	// UpdateUserFunc mocks the UpdateUser method.
 	UpdateUserFunc func(user storage.User) error
 
+	// UpdateWebAuthnCredentialUsageFunc mocks the UpdateWebAuthnCredentialUsage method.
+	UpdateWebAuthnCredentialUsageFunc func(id []byte, signCount uint32, lastUsedAt time.Time) error
+
+	// UseRecoveryCodeFunc mocks the UseRecoveryCode method.
+	UseRecoveryCodeFunc func(userID string, codeHash []byte, usedAt time.Time) error
+
 	// UserFunc mocks the User method.
 	UserFunc func(email string) (storage.User, error)
 
+	// UserByIDFunc mocks the UserByID method.
+	UserByIDFunc func(id string) (storage.User, error)
+
+	// UserByPhoneFunc mocks the UserByPhone method.
+	UserByPhoneFunc func(phone string) (storage.User, error)
+
+	// UserByUsernameFunc mocks the UserByUsername method.
+	UserByUsernameFunc func(username string) (storage.User, error)
+
+	// UserDataFunc mocks the UserData method.
+	UserDataFunc func(id string) (storage.UserData, error)
+
+	// UsersByClaimsFunc mocks the UsersByClaims method.
+	UsersByClaimsFunc func(predicates []storage.ClaimPredicate, limit int, offset int) ([]storage.User, int, error)
+
+	// UsersToRemindOfPasswordExpiryFunc mocks the UsersToRemindOfPasswordExpiry method.
+	UsersToRemindOfPasswordExpiryFunc func(defaultMaxAgeDays int, reminderDays int, now time.Time) ([]storage.User, error)
+
+	// WebAuthnCredentialsFunc mocks the WebAuthnCredentials method.
+	WebAuthnCredentialsFunc func(userID string) ([]storage.WebAuthnCredential, error)
+
 	// calls tracks calls to the methods.
 	calls struct {
+		// AddLogin holds details about calls to the AddLogin method.
+		AddLogin []struct {
+			// L is the l argument value.
+			L storage.Login
+		}
+		// AddPasswordHistory holds details about calls to the AddPasswordHistory method.
+		AddPasswordHistory []struct {
+			// UserID is the userID argument value.
+			UserID string
+			// Password is the password argument value.
+			Password []byte
+			// CreatedAt is the createdAt argument value.
+			CreatedAt time.Time
+			// Keep is the keep argument value.
+			Keep int
+		}
 		// CreateToken holds details about calls to the CreateToken method.
 		CreateToken []struct {
 			// T is the t argument value.
@@ -89,34 +295,267 @@ type StorageMock struct {
 			// User is the user argument value.
 			User storage.User
 		}
+		// CreateWebAuthnCredential holds details about calls to the CreateWebAuthnCredential method.
+		CreateWebAuthnCredential []struct {
+			// C is the c argument value.
+			C storage.WebAuthnCredential
+		}
+		// DeleteLoginsCreatedBefore holds details about calls to the DeleteLoginsCreatedBefore method.
+		DeleteLoginsCreatedBefore []struct {
+			// CreatedBefore is the createdBefore argument value.
+			CreatedBefore time.Time
+		}
+		// DeleteMFA holds details about calls to the DeleteMFA method.
+		DeleteMFA []struct {
+			// UserID is the userID argument value.
+			UserID string
+		}
 		// DeleteToken holds details about calls to the DeleteToken method.
 		DeleteToken []struct {
 			// ID is the id argument value.
 			ID int64
 		}
+		// DeleteTokensCreatedBefore holds details about calls to the DeleteTokensCreatedBefore method.
+		DeleteTokensCreatedBefore []struct {
+			// TokenType is the tokenType argument value.
+			TokenType string
+			// CreatedBefore is the createdBefore argument value.
+			CreatedBefore time.Time
+		}
+		// DeleteUnverifiedUsers holds details about calls to the DeleteUnverifiedUsers method.
+		DeleteUnverifiedUsers []struct {
+			// InvitedBefore is the invitedBefore argument value.
+			InvitedBefore time.Time
+		}
 		// DeleteUser holds details about calls to the DeleteUser method.
 		DeleteUser []struct {
-			// Email is the email argument value.
-			Email string
+			// ID is the id argument value.
+			ID string
 		}
-		// TokensByEMailAndToken holds details about calls to the TokensByEMailAndToken method.
-		TokensByEMailAndToken []struct {
-			// Email is the email argument value.
-			Email string
-			// Token is the token argument value.
-			Token string
+		// EraseUser holds details about calls to the EraseUser method.
+		EraseUser []struct {
+			// ID is the id argument value.
+			ID string
+		}
+		// IncrementTokenAttempts holds details about calls to the IncrementTokenAttempts method.
+		IncrementTokenAttempts []struct {
+			// ID is the id argument value.
+			ID int64
+		}
+		// Logins holds details about calls to the Logins method.
+		Logins []struct {
+			// UserID is the userID argument value.
+			UserID string
+			// Limit is the limit argument value.
+			Limit int
+			// Offset is the offset argument value.
+			Offset int
+		}
+		// MarkPasswordExpiryReminded holds details about calls to the MarkPasswordExpiryReminded method.
+		MarkPasswordExpiryReminded []struct {
+			// UserID is the userID argument value.
+			UserID string
+			// RemindedAt is the remindedAt argument value.
+			RemindedAt time.Time
+		}
+		// MarkUserInvited holds details about calls to the MarkUserInvited method.
+		MarkUserInvited []struct {
+			// ID is the id argument value.
+			ID string
+			// InvitedAt is the invitedAt argument value.
+			InvitedAt time.Time
+		}
+		// PasswordHistory holds details about calls to the PasswordHistory method.
+		PasswordHistory []struct {
+			// UserID is the userID argument value.
+			UserID string
+			// Limit is the limit argument value.
+			Limit int
+		}
+		// PatchUser holds details about calls to the PatchUser method.
+		PatchUser []struct {
+			// ID is the id argument value.
+			ID string
+			// Patch is the patch argument value.
+			Patch func(user *storage.User) error
+		}
+		// ReplaceRecoveryCodes holds details about calls to the ReplaceRecoveryCodes method.
+		ReplaceRecoveryCodes []struct {
+			// UserID is the userID argument value.
+			UserID string
+			// CodeHashes is the codeHashes argument value.
+			CodeHashes [][]byte
+			// CreatedAt is the createdAt argument value.
+			CreatedAt time.Time
+		}
+		// SaveTOTP holds details about calls to the SaveTOTP method.
+		SaveTOTP []struct {
+			// T is the t argument value.
+			T storage.TOTP
+		}
+		// TOTP holds details about calls to the TOTP method.
+		TOTP []struct {
+			// UserID is the userID argument value.
+			UserID string
+		}
+		// TokensByUserIDAndType holds details about calls to the TokensByUserIDAndType method.
+		TokensByUserIDAndType []struct {
+			// UserID is the userID argument value.
+			UserID string
+			// TokenType is the tokenType argument value.
+			TokenType string
+		}
+		// UnusedRecoveryCodeCount holds details about calls to the UnusedRecoveryCodeCount method.
+		UnusedRecoveryCodeCount []struct {
+			// UserID is the userID argument value.
+			UserID string
 		}
 		// UpdateUser holds details about calls to the UpdateUser method.
 		UpdateUser []struct {
 			// User is the user argument value.
 			User storage.User
 		}
+		// UpdateWebAuthnCredentialUsage holds details about calls to the UpdateWebAuthnCredentialUsage method.
+		UpdateWebAuthnCredentialUsage []struct {
+			// ID is the id argument value.
+			ID []byte
+			// SignCount is the signCount argument value.
+			SignCount uint32
+			// LastUsedAt is the lastUsedAt argument value.
+			LastUsedAt time.Time
+		}
+		// UseRecoveryCode holds details about calls to the UseRecoveryCode method.
+		UseRecoveryCode []struct {
+			// UserID is the userID argument value.
+			UserID string
+			// CodeHash is the codeHash argument value.
+			CodeHash []byte
+			// UsedAt is the usedAt argument value.
+			UsedAt time.Time
+		}
 		// User holds details about calls to the User method.
 		User []struct {
 			// Email is the email argument value.
 			Email string
 		}
+		// UserByID holds details about calls to the UserByID method.
+		UserByID []struct {
+			// ID is the id argument value.
+			ID string
+		}
+		// UserByPhone holds details about calls to the UserByPhone method.
+		UserByPhone []struct {
+			// Phone is the phone argument value.
+			Phone string
+		}
+		// UserByUsername holds details about calls to the UserByUsername method.
+		UserByUsername []struct {
+			// Username is the username argument value.
+			Username string
+		}
+		// UserData holds details about calls to the UserData method.
+		UserData []struct {
+			// ID is the id argument value.
+			ID string
+		}
+		// UsersByClaims holds details about calls to the UsersByClaims method.
+		UsersByClaims []struct {
+			// Predicates is the predicates argument value.
+			Predicates []storage.ClaimPredicate
+			// Limit is the limit argument value.
+			Limit int
+			// Offset is the offset argument value.
+			Offset int
+		}
+		// UsersToRemindOfPasswordExpiry holds details about calls to the UsersToRemindOfPasswordExpiry method.
+		UsersToRemindOfPasswordExpiry []struct {
+			// DefaultMaxAgeDays is the defaultMaxAgeDays argument value.
+			DefaultMaxAgeDays int
+			// ReminderDays is the reminderDays argument value.
+			ReminderDays int
+			// Now is the now argument value.
+			Now time.Time
+		}
+		// WebAuthnCredentials holds details about calls to the WebAuthnCredentials method.
+		WebAuthnCredentials []struct {
+			// UserID is the userID argument value.
+			UserID string
+		}
+	}
+}
+
+// AddLogin calls AddLoginFunc.
+func (mock *StorageMock) AddLogin(l storage.Login) error {
+	if mock.AddLoginFunc == nil {
+		panic("StorageMock.AddLoginFunc: method is nil but Storage.AddLogin was just called")
+	}
+	callInfo := struct {
+		L storage.Login
+	}{
+		L: l,
+	}
+	lockStorageMockAddLogin.Lock()
+	mock.calls.AddLogin = append(mock.calls.AddLogin, callInfo)
+	lockStorageMockAddLogin.Unlock()
+	return mock.AddLoginFunc(l)
+}
+
+// AddLoginCalls gets all the calls that were made to AddLogin.
+// Check the length with:
+//     len(mockedStorage.AddLoginCalls())
+func (mock *StorageMock) AddLoginCalls() []struct {
+	L storage.Login
+} {
+	var calls []struct {
+		L storage.Login
+	}
+	lockStorageMockAddLogin.RLock()
+	calls = mock.calls.AddLogin
+	lockStorageMockAddLogin.RUnlock()
+	return calls
+}
+
+// AddPasswordHistory calls AddPasswordHistoryFunc.
+func (mock *StorageMock) AddPasswordHistory(userID string, password []byte, createdAt time.Time, keep int) error {
+	if mock.AddPasswordHistoryFunc == nil {
+		panic("StorageMock.AddPasswordHistoryFunc: method is nil but Storage.AddPasswordHistory was just called")
+	}
+	callInfo := struct {
+		UserID    string
+		Password  []byte
+		CreatedAt time.Time
+		Keep      int
+	}{
+		UserID:    userID,
+		Password:  password,
+		CreatedAt: createdAt,
+		Keep:      keep,
+	}
+	lockStorageMockAddPasswordHistory.Lock()
+	mock.calls.AddPasswordHistory = append(mock.calls.AddPasswordHistory, callInfo)
+	lockStorageMockAddPasswordHistory.Unlock()
+	return mock.AddPasswordHistoryFunc(userID, password, createdAt, keep)
+}
+
+// AddPasswordHistoryCalls gets all the calls that were made to AddPasswordHistory.
+// Check the length with:
+//     len(mockedStorage.AddPasswordHistoryCalls())
+func (mock *StorageMock) AddPasswordHistoryCalls() []struct {
+	UserID    string
+	Password  []byte
+	CreatedAt time.Time
+	Keep      int
+} {
+	var calls []struct {
+		UserID    string
+		Password  []byte
+		CreatedAt time.Time
+		Keep      int
 	}
+	lockStorageMockAddPasswordHistory.RLock()
+	calls = mock.calls.AddPasswordHistory
+	lockStorageMockAddPasswordHistory.RUnlock()
+	return calls
 }
 
 // CreateToken calls CreateTokenFunc.
@@ -181,6 +620,99 @@ func (mock *StorageMock) CreateUserCalls() []struct {
 	return calls
 }
 
+// CreateWebAuthnCredential calls CreateWebAuthnCredentialFunc.
+func (mock *StorageMock) CreateWebAuthnCredential(c storage.WebAuthnCredential) error {
+	if mock.CreateWebAuthnCredentialFunc == nil {
+		panic("StorageMock.CreateWebAuthnCredentialFunc: method is nil but Storage.CreateWebAuthnCredential was just called")
+	}
+	callInfo := struct {
+		C storage.WebAuthnCredential
+	}{
+		C: c,
+	}
+	lockStorageMockCreateWebAuthnCredential.Lock()
+	mock.calls.CreateWebAuthnCredential = append(mock.calls.CreateWebAuthnCredential, callInfo)
+	lockStorageMockCreateWebAuthnCredential.Unlock()
+	return mock.CreateWebAuthnCredentialFunc(c)
+}
+
+// CreateWebAuthnCredentialCalls gets all the calls that were made to CreateWebAuthnCredential.
+// Check the length with:
+//     len(mockedStorage.CreateWebAuthnCredentialCalls())
+func (mock *StorageMock) CreateWebAuthnCredentialCalls() []struct {
+	C storage.WebAuthnCredential
+} {
+	var calls []struct {
+		C storage.WebAuthnCredential
+	}
+	lockStorageMockCreateWebAuthnCredential.RLock()
+	calls = mock.calls.CreateWebAuthnCredential
+	lockStorageMockCreateWebAuthnCredential.RUnlock()
+	return calls
+}
+
+// DeleteLoginsCreatedBefore calls DeleteLoginsCreatedBeforeFunc.
+func (mock *StorageMock) DeleteLoginsCreatedBefore(createdBefore time.Time) (int64, error) {
+	if mock.DeleteLoginsCreatedBeforeFunc == nil {
+		panic("StorageMock.DeleteLoginsCreatedBeforeFunc: method is nil but Storage.DeleteLoginsCreatedBefore was just called")
+	}
+	callInfo := struct {
+		CreatedBefore time.Time
+	}{
+		CreatedBefore: createdBefore,
+	}
+	lockStorageMockDeleteLoginsCreatedBefore.Lock()
+	mock.calls.DeleteLoginsCreatedBefore = append(mock.calls.DeleteLoginsCreatedBefore, callInfo)
+	lockStorageMockDeleteLoginsCreatedBefore.Unlock()
+	return mock.DeleteLoginsCreatedBeforeFunc(createdBefore)
+}
+
+// DeleteLoginsCreatedBeforeCalls gets all the calls that were made to DeleteLoginsCreatedBefore.
+// Check the length with:
+//     len(mockedStorage.DeleteLoginsCreatedBeforeCalls())
+func (mock *StorageMock) DeleteLoginsCreatedBeforeCalls() []struct {
+	CreatedBefore time.Time
+} {
+	var calls []struct {
+		CreatedBefore time.Time
+	}
+	lockStorageMockDeleteLoginsCreatedBefore.RLock()
+	calls = mock.calls.DeleteLoginsCreatedBefore
+	lockStorageMockDeleteLoginsCreatedBefore.RUnlock()
+	return calls
+}
+
+// DeleteMFA calls DeleteMFAFunc.
+func (mock *StorageMock) DeleteMFA(userID string) error {
+	if mock.DeleteMFAFunc == nil {
+		panic("StorageMock.DeleteMFAFunc: method is nil but Storage.DeleteMFA was just called")
+	}
+	callInfo := struct {
+		UserID string
+	}{
+		UserID: userID,
+	}
+	lockStorageMockDeleteMFA.Lock()
+	mock.calls.DeleteMFA = append(mock.calls.DeleteMFA, callInfo)
+	lockStorageMockDeleteMFA.Unlock()
+	return mock.DeleteMFAFunc(userID)
+}
+
+// DeleteMFACalls gets all the calls that were made to DeleteMFA.
+// Check the length with:
+//     len(mockedStorage.DeleteMFACalls())
+func (mock *StorageMock) DeleteMFACalls() []struct {
+	UserID string
+} {
+	var calls []struct {
+		UserID string
+	}
+	lockStorageMockDeleteMFA.RLock()
+	calls = mock.calls.DeleteMFA
+	lockStorageMockDeleteMFA.RUnlock()
+	return calls
+}
+
 // DeleteToken calls DeleteTokenFunc.
 func (mock *StorageMock) DeleteToken(id int64) error {
 	if mock.DeleteTokenFunc == nil {
@@ -212,30 +744,96 @@ func (mock *StorageMock) DeleteTokenCalls() []struct {
 	return calls
 }
 
+// DeleteTokensCreatedBefore calls DeleteTokensCreatedBeforeFunc.
+func (mock *StorageMock) DeleteTokensCreatedBefore(tokenType string, createdBefore time.Time) (int64, error) {
+	if mock.DeleteTokensCreatedBeforeFunc == nil {
+		panic("StorageMock.DeleteTokensCreatedBeforeFunc: method is nil but Storage.DeleteTokensCreatedBefore was just called")
+	}
+	callInfo := struct {
+		TokenType     string
+		CreatedBefore time.Time
+	}{
+		TokenType:     tokenType,
+		CreatedBefore: createdBefore,
+	}
+	lockStorageMockDeleteTokensCreatedBefore.Lock()
+	mock.calls.DeleteTokensCreatedBefore = append(mock.calls.DeleteTokensCreatedBefore, callInfo)
+	lockStorageMockDeleteTokensCreatedBefore.Unlock()
+	return mock.DeleteTokensCreatedBeforeFunc(tokenType, createdBefore)
+}
+
+// DeleteTokensCreatedBeforeCalls gets all the calls that were made to DeleteTokensCreatedBefore.
+// Check the length with:
+//     len(mockedStorage.DeleteTokensCreatedBeforeCalls())
+func (mock *StorageMock) DeleteTokensCreatedBeforeCalls() []struct {
+	TokenType     string
+	CreatedBefore time.Time
+} {
+	var calls []struct {
+		TokenType     string
+		CreatedBefore time.Time
+	}
+	lockStorageMockDeleteTokensCreatedBefore.RLock()
+	calls = mock.calls.DeleteTokensCreatedBefore
+	lockStorageMockDeleteTokensCreatedBefore.RUnlock()
+	return calls
+}
+
+// DeleteUnverifiedUsers calls DeleteUnverifiedUsersFunc.
+func (mock *StorageMock) DeleteUnverifiedUsers(invitedBefore time.Time) (int64, error) {
+	if mock.DeleteUnverifiedUsersFunc == nil {
+		panic("StorageMock.DeleteUnverifiedUsersFunc: method is nil but Storage.DeleteUnverifiedUsers was just called")
+	}
+	callInfo := struct {
+		InvitedBefore time.Time
+	}{
+		InvitedBefore: invitedBefore,
+	}
+	lockStorageMockDeleteUnverifiedUsers.Lock()
+	mock.calls.DeleteUnverifiedUsers = append(mock.calls.DeleteUnverifiedUsers, callInfo)
+	lockStorageMockDeleteUnverifiedUsers.Unlock()
+	return mock.DeleteUnverifiedUsersFunc(invitedBefore)
+}
+
+// DeleteUnverifiedUsersCalls gets all the calls that were made to DeleteUnverifiedUsers.
+// Check the length with:
+//     len(mockedStorage.DeleteUnverifiedUsersCalls())
+func (mock *StorageMock) DeleteUnverifiedUsersCalls() []struct {
+	InvitedBefore time.Time
+} {
+	var calls []struct {
+		InvitedBefore time.Time
+	}
+	lockStorageMockDeleteUnverifiedUsers.RLock()
+	calls = mock.calls.DeleteUnverifiedUsers
+	lockStorageMockDeleteUnverifiedUsers.RUnlock()
+	return calls
+}
+
 // DeleteUser calls DeleteUserFunc.
-func (mock *StorageMock) DeleteUser(email string) error {
+func (mock *StorageMock) DeleteUser(id string) error {
 	if mock.DeleteUserFunc == nil {
 		panic("StorageMock.DeleteUserFunc: method is nil but Storage.DeleteUser was just called")
 	}
 	callInfo := struct {
-		Email string
+		ID string
 	}{
-		Email: email,
+		ID: id,
 	}
 	lockStorageMockDeleteUser.Lock()
 	mock.calls.DeleteUser = append(mock.calls.DeleteUser, callInfo)
 	lockStorageMockDeleteUser.Unlock()
-	return mock.DeleteUserFunc(email)
+	return mock.DeleteUserFunc(id)
 }
 
 // DeleteUserCalls gets all the calls that were made to DeleteUser.
 // Check the length with:
 //     len(mockedStorage.DeleteUserCalls())
 func (mock *StorageMock) DeleteUserCalls() []struct {
-	Email string
+	ID string
 } {
 	var calls []struct {
-		Email string
+		ID string
 	}
 	lockStorageMockDeleteUser.RLock()
 	calls = mock.calls.DeleteUser
@@ -243,38 +841,411 @@ func (mock *StorageMock) DeleteUserCalls() []struct {
 	return calls
 }
 
-// TokensByEMailAndToken calls TokensByEMailAndTokenFunc.
-func (mock *StorageMock) TokensByEMailAndToken(email string, token string) ([]storage.Token, error) {
-	if mock.TokensByEMailAndTokenFunc == nil {
-		panic("StorageMock.TokensByEMailAndTokenFunc: method is nil but Storage.TokensByEMailAndToken was just called")
+// EraseUser calls EraseUserFunc.
+func (mock *StorageMock) EraseUser(id string) (map[string]int64, error) {
+	if mock.EraseUserFunc == nil {
+		panic("StorageMock.EraseUserFunc: method is nil but Storage.EraseUser was just called")
 	}
 	callInfo := struct {
-		Email string
-		Token string
+		ID string
 	}{
-		Email: email,
-		Token: token,
+		ID: id,
 	}
-	lockStorageMockTokensByEMailAndToken.Lock()
-	mock.calls.TokensByEMailAndToken = append(mock.calls.TokensByEMailAndToken, callInfo)
-	lockStorageMockTokensByEMailAndToken.Unlock()
-	return mock.TokensByEMailAndTokenFunc(email, token)
+	lockStorageMockEraseUser.Lock()
+	mock.calls.EraseUser = append(mock.calls.EraseUser, callInfo)
+	lockStorageMockEraseUser.Unlock()
+	return mock.EraseUserFunc(id)
 }
 
-// TokensByEMailAndTokenCalls gets all the calls that were made to TokensByEMailAndToken.
+// EraseUserCalls gets all the calls that were made to EraseUser.
 // Check the length with:
-//     len(mockedStorage.TokensByEMailAndTokenCalls())
-func (mock *StorageMock) TokensByEMailAndTokenCalls() []struct {
-	Email string
-	Token string
+//     len(mockedStorage.EraseUserCalls())
+func (mock *StorageMock) EraseUserCalls() []struct {
+	ID string
 } {
 	var calls []struct {
-		Email string
-		Token string
+		ID string
+	}
+	lockStorageMockEraseUser.RLock()
+	calls = mock.calls.EraseUser
+	lockStorageMockEraseUser.RUnlock()
+	return calls
+}
+
+// IncrementTokenAttempts calls IncrementTokenAttemptsFunc.
+func (mock *StorageMock) IncrementTokenAttempts(id int64) (int, error) {
+	if mock.IncrementTokenAttemptsFunc == nil {
+		panic("StorageMock.IncrementTokenAttemptsFunc: method is nil but Storage.IncrementTokenAttempts was just called")
+	}
+	callInfo := struct {
+		ID int64
+	}{
+		ID: id,
 	}
-	lockStorageMockTokensByEMailAndToken.RLock()
-	calls = mock.calls.TokensByEMailAndToken
-	lockStorageMockTokensByEMailAndToken.RUnlock()
+	lockStorageMockIncrementTokenAttempts.Lock()
+	mock.calls.IncrementTokenAttempts = append(mock.calls.IncrementTokenAttempts, callInfo)
+	lockStorageMockIncrementTokenAttempts.Unlock()
+	return mock.IncrementTokenAttemptsFunc(id)
+}
+
+// IncrementTokenAttemptsCalls gets all the calls that were made to IncrementTokenAttempts.
+// Check the length with:
+//     len(mockedStorage.IncrementTokenAttemptsCalls())
+func (mock *StorageMock) IncrementTokenAttemptsCalls() []struct {
+	ID int64
+} {
+	var calls []struct {
+		ID int64
+	}
+	lockStorageMockIncrementTokenAttempts.RLock()
+	calls = mock.calls.IncrementTokenAttempts
+	lockStorageMockIncrementTokenAttempts.RUnlock()
+	return calls
+}
+
+// Logins calls LoginsFunc.
+func (mock *StorageMock) Logins(userID string, limit int, offset int) ([]storage.Login, int, error) {
+	if mock.LoginsFunc == nil {
+		panic("StorageMock.LoginsFunc: method is nil but Storage.Logins was just called")
+	}
+	callInfo := struct {
+		UserID string
+		Limit  int
+		Offset int
+	}{
+		UserID: userID,
+		Limit:  limit,
+		Offset: offset,
+	}
+	lockStorageMockLogins.Lock()
+	mock.calls.Logins = append(mock.calls.Logins, callInfo)
+	lockStorageMockLogins.Unlock()
+	return mock.LoginsFunc(userID, limit, offset)
+}
+
+// LoginsCalls gets all the calls that were made to Logins.
+// Check the length with:
+//     len(mockedStorage.LoginsCalls())
+func (mock *StorageMock) LoginsCalls() []struct {
+	UserID string
+	Limit  int
+	Offset int
+} {
+	var calls []struct {
+		UserID string
+		Limit  int
+		Offset int
+	}
+	lockStorageMockLogins.RLock()
+	calls = mock.calls.Logins
+	lockStorageMockLogins.RUnlock()
+	return calls
+}
+
+// MarkPasswordExpiryReminded calls MarkPasswordExpiryRemindedFunc.
+func (mock *StorageMock) MarkPasswordExpiryReminded(userID string, remindedAt time.Time) error {
+	if mock.MarkPasswordExpiryRemindedFunc == nil {
+		panic("StorageMock.MarkPasswordExpiryRemindedFunc: method is nil but Storage.MarkPasswordExpiryReminded was just called")
+	}
+	callInfo := struct {
+		UserID     string
+		RemindedAt time.Time
+	}{
+		UserID:     userID,
+		RemindedAt: remindedAt,
+	}
+	lockStorageMockMarkPasswordExpiryReminded.Lock()
+	mock.calls.MarkPasswordExpiryReminded = append(mock.calls.MarkPasswordExpiryReminded, callInfo)
+	lockStorageMockMarkPasswordExpiryReminded.Unlock()
+	return mock.MarkPasswordExpiryRemindedFunc(userID, remindedAt)
+}
+
+// MarkPasswordExpiryRemindedCalls gets all the calls that were made to MarkPasswordExpiryReminded.
+// Check the length with:
+//     len(mockedStorage.MarkPasswordExpiryRemindedCalls())
+func (mock *StorageMock) MarkPasswordExpiryRemindedCalls() []struct {
+	UserID     string
+	RemindedAt time.Time
+} {
+	var calls []struct {
+		UserID     string
+		RemindedAt time.Time
+	}
+	lockStorageMockMarkPasswordExpiryReminded.RLock()
+	calls = mock.calls.MarkPasswordExpiryReminded
+	lockStorageMockMarkPasswordExpiryReminded.RUnlock()
+	return calls
+}
+
+// MarkUserInvited calls MarkUserInvitedFunc.
+func (mock *StorageMock) MarkUserInvited(id string, invitedAt time.Time) error {
+	if mock.MarkUserInvitedFunc == nil {
+		panic("StorageMock.MarkUserInvitedFunc: method is nil but Storage.MarkUserInvited was just called")
+	}
+	callInfo := struct {
+		ID        string
+		InvitedAt time.Time
+	}{
+		ID:        id,
+		InvitedAt: invitedAt,
+	}
+	lockStorageMockMarkUserInvited.Lock()
+	mock.calls.MarkUserInvited = append(mock.calls.MarkUserInvited, callInfo)
+	lockStorageMockMarkUserInvited.Unlock()
+	return mock.MarkUserInvitedFunc(id, invitedAt)
+}
+
+// MarkUserInvitedCalls gets all the calls that were made to MarkUserInvited.
+// Check the length with:
+//     len(mockedStorage.MarkUserInvitedCalls())
+func (mock *StorageMock) MarkUserInvitedCalls() []struct {
+	ID        string
+	InvitedAt time.Time
+} {
+	var calls []struct {
+		ID        string
+		InvitedAt time.Time
+	}
+	lockStorageMockMarkUserInvited.RLock()
+	calls = mock.calls.MarkUserInvited
+	lockStorageMockMarkUserInvited.RUnlock()
+	return calls
+}
+
+// PasswordHistory calls PasswordHistoryFunc.
+func (mock *StorageMock) PasswordHistory(userID string, limit int) ([][]byte, error) {
+	if mock.PasswordHistoryFunc == nil {
+		panic("StorageMock.PasswordHistoryFunc: method is nil but Storage.PasswordHistory was just called")
+	}
+	callInfo := struct {
+		UserID string
+		Limit  int
+	}{
+		UserID: userID,
+		Limit:  limit,
+	}
+	lockStorageMockPasswordHistory.Lock()
+	mock.calls.PasswordHistory = append(mock.calls.PasswordHistory, callInfo)
+	lockStorageMockPasswordHistory.Unlock()
+	return mock.PasswordHistoryFunc(userID, limit)
+}
+
+// PasswordHistoryCalls gets all the calls that were made to PasswordHistory.
+// Check the length with:
+//     len(mockedStorage.PasswordHistoryCalls())
+func (mock *StorageMock) PasswordHistoryCalls() []struct {
+	UserID string
+	Limit  int
+} {
+	var calls []struct {
+		UserID string
+		Limit  int
+	}
+	lockStorageMockPasswordHistory.RLock()
+	calls = mock.calls.PasswordHistory
+	lockStorageMockPasswordHistory.RUnlock()
+	return calls
+}
+
+// PatchUser calls PatchUserFunc.
+func (mock *StorageMock) PatchUser(id string, patch func(user *storage.User) error) error {
+	if mock.PatchUserFunc == nil {
+		panic("StorageMock.PatchUserFunc: method is nil but Storage.PatchUser was just called")
+	}
+	callInfo := struct {
+		ID    string
+		Patch func(user *storage.User) error
+	}{
+		ID:    id,
+		Patch: patch,
+	}
+	lockStorageMockPatchUser.Lock()
+	mock.calls.PatchUser = append(mock.calls.PatchUser, callInfo)
+	lockStorageMockPatchUser.Unlock()
+	return mock.PatchUserFunc(id, patch)
+}
+
+// PatchUserCalls gets all the calls that were made to PatchUser.
+// Check the length with:
+//     len(mockedStorage.PatchUserCalls())
+func (mock *StorageMock) PatchUserCalls() []struct {
+	ID    string
+	Patch func(user *storage.User) error
+} {
+	var calls []struct {
+		ID    string
+		Patch func(user *storage.User) error
+	}
+	lockStorageMockPatchUser.RLock()
+	calls = mock.calls.PatchUser
+	lockStorageMockPatchUser.RUnlock()
+	return calls
+}
+
+// ReplaceRecoveryCodes calls ReplaceRecoveryCodesFunc.
+func (mock *StorageMock) ReplaceRecoveryCodes(userID string, codeHashes [][]byte, createdAt time.Time) error {
+	if mock.ReplaceRecoveryCodesFunc == nil {
+		panic("StorageMock.ReplaceRecoveryCodesFunc: method is nil but Storage.ReplaceRecoveryCodes was just called")
+	}
+	callInfo := struct {
+		UserID     string
+		CodeHashes [][]byte
+		CreatedAt  time.Time
+	}{
+		UserID:     userID,
+		CodeHashes: codeHashes,
+		CreatedAt:  createdAt,
+	}
+	lockStorageMockReplaceRecoveryCodes.Lock()
+	mock.calls.ReplaceRecoveryCodes = append(mock.calls.ReplaceRecoveryCodes, callInfo)
+	lockStorageMockReplaceRecoveryCodes.Unlock()
+	return mock.ReplaceRecoveryCodesFunc(userID, codeHashes, createdAt)
+}
+
+// ReplaceRecoveryCodesCalls gets all the calls that were made to ReplaceRecoveryCodes.
+// Check the length with:
+//     len(mockedStorage.ReplaceRecoveryCodesCalls())
+func (mock *StorageMock) ReplaceRecoveryCodesCalls() []struct {
+	UserID     string
+	CodeHashes [][]byte
+	CreatedAt  time.Time
+} {
+	var calls []struct {
+		UserID     string
+		CodeHashes [][]byte
+		CreatedAt  time.Time
+	}
+	lockStorageMockReplaceRecoveryCodes.RLock()
+	calls = mock.calls.ReplaceRecoveryCodes
+	lockStorageMockReplaceRecoveryCodes.RUnlock()
+	return calls
+}
+
+// SaveTOTP calls SaveTOTPFunc.
+func (mock *StorageMock) SaveTOTP(t storage.TOTP) error {
+	if mock.SaveTOTPFunc == nil {
+		panic("StorageMock.SaveTOTPFunc: method is nil but Storage.SaveTOTP was just called")
+	}
+	callInfo := struct {
+		T storage.TOTP
+	}{
+		T: t,
+	}
+	lockStorageMockSaveTOTP.Lock()
+	mock.calls.SaveTOTP = append(mock.calls.SaveTOTP, callInfo)
+	lockStorageMockSaveTOTP.Unlock()
+	return mock.SaveTOTPFunc(t)
+}
+
+// SaveTOTPCalls gets all the calls that were made to SaveTOTP.
+// Check the length with:
+//     len(mockedStorage.SaveTOTPCalls())
+func (mock *StorageMock) SaveTOTPCalls() []struct {
+	T storage.TOTP
+} {
+	var calls []struct {
+		T storage.TOTP
+	}
+	lockStorageMockSaveTOTP.RLock()
+	calls = mock.calls.SaveTOTP
+	lockStorageMockSaveTOTP.RUnlock()
+	return calls
+}
+
+// TOTP calls TOTPFunc.
+func (mock *StorageMock) TOTP(userID string) (storage.TOTP, error) {
+	if mock.TOTPFunc == nil {
+		panic("StorageMock.TOTPFunc: method is nil but Storage.TOTP was just called")
+	}
+	callInfo := struct {
+		UserID string
+	}{
+		UserID: userID,
+	}
+	lockStorageMockTOTP.Lock()
+	mock.calls.TOTP = append(mock.calls.TOTP, callInfo)
+	lockStorageMockTOTP.Unlock()
+	return mock.TOTPFunc(userID)
+}
+
+// TOTPCalls gets all the calls that were made to TOTP.
+// Check the length with:
+//     len(mockedStorage.TOTPCalls())
+func (mock *StorageMock) TOTPCalls() []struct {
+	UserID string
+} {
+	var calls []struct {
+		UserID string
+	}
+	lockStorageMockTOTP.RLock()
+	calls = mock.calls.TOTP
+	lockStorageMockTOTP.RUnlock()
+	return calls
+}
+
+// TokensByUserIDAndType calls TokensByUserIDAndTypeFunc.
+func (mock *StorageMock) TokensByUserIDAndType(userID string, tokenType string) ([]storage.Token, error) {
+	if mock.TokensByUserIDAndTypeFunc == nil {
+		panic("StorageMock.TokensByUserIDAndTypeFunc: method is nil but Storage.TokensByUserIDAndType was just called")
+	}
+	callInfo := struct {
+		UserID    string
+		TokenType string
+	}{
+		UserID:    userID,
+		TokenType: tokenType,
+	}
+	lockStorageMockTokensByUserIDAndType.Lock()
+	mock.calls.TokensByUserIDAndType = append(mock.calls.TokensByUserIDAndType, callInfo)
+	lockStorageMockTokensByUserIDAndType.Unlock()
+	return mock.TokensByUserIDAndTypeFunc(userID, tokenType)
+}
+
+// TokensByUserIDAndTypeCalls gets all the calls that were made to TokensByUserIDAndType.
+// Check the length with:
+//     len(mockedStorage.TokensByUserIDAndTypeCalls())
+func (mock *StorageMock) TokensByUserIDAndTypeCalls() []struct {
+	UserID    string
+	TokenType string
+} {
+	var calls []struct {
+		UserID    string
+		TokenType string
+	}
+	lockStorageMockTokensByUserIDAndType.RLock()
+	calls = mock.calls.TokensByUserIDAndType
+	lockStorageMockTokensByUserIDAndType.RUnlock()
+	return calls
+}
+
+// UnusedRecoveryCodeCount calls UnusedRecoveryCodeCountFunc.
+func (mock *StorageMock) UnusedRecoveryCodeCount(userID string) (int, error) {
+	if mock.UnusedRecoveryCodeCountFunc == nil {
+		panic("StorageMock.UnusedRecoveryCodeCountFunc: method is nil but Storage.UnusedRecoveryCodeCount was just called")
+	}
+	callInfo := struct {
+		UserID string
+	}{
+		UserID: userID,
+	}
+	lockStorageMockUnusedRecoveryCodeCount.Lock()
+	mock.calls.UnusedRecoveryCodeCount = append(mock.calls.UnusedRecoveryCodeCount, callInfo)
+	lockStorageMockUnusedRecoveryCodeCount.Unlock()
+	return mock.UnusedRecoveryCodeCountFunc(userID)
+}
+
+// UnusedRecoveryCodeCountCalls gets all the calls that were made to UnusedRecoveryCodeCount.
+// Check the length with:
+//     len(mockedStorage.UnusedRecoveryCodeCountCalls())
+func (mock *StorageMock) UnusedRecoveryCodeCountCalls() []struct {
+	UserID string
+} {
+	var calls []struct {
+		UserID string
+	}
+	lockStorageMockUnusedRecoveryCodeCount.RLock()
+	calls = mock.calls.UnusedRecoveryCodeCount
+	lockStorageMockUnusedRecoveryCodeCount.RUnlock()
 	return calls
 }
 
@@ -309,6 +1280,84 @@ func (mock *StorageMock) UpdateUserCalls() []struct {
 	return calls
 }
 
+// UpdateWebAuthnCredentialUsage calls UpdateWebAuthnCredentialUsageFunc.
+func (mock *StorageMock) UpdateWebAuthnCredentialUsage(id []byte, signCount uint32, lastUsedAt time.Time) error {
+	if mock.UpdateWebAuthnCredentialUsageFunc == nil {
+		panic("StorageMock.UpdateWebAuthnCredentialUsageFunc: method is nil but Storage.UpdateWebAuthnCredentialUsage was just called")
+	}
+	callInfo := struct {
+		ID         []byte
+		SignCount  uint32
+		LastUsedAt time.Time
+	}{
+		ID:         id,
+		SignCount:  signCount,
+		LastUsedAt: lastUsedAt,
+	}
+	lockStorageMockUpdateWebAuthnCredentialUsage.Lock()
+	mock.calls.UpdateWebAuthnCredentialUsage = append(mock.calls.UpdateWebAuthnCredentialUsage, callInfo)
+	lockStorageMockUpdateWebAuthnCredentialUsage.Unlock()
+	return mock.UpdateWebAuthnCredentialUsageFunc(id, signCount, lastUsedAt)
+}
+
+// UpdateWebAuthnCredentialUsageCalls gets all the calls that were made to UpdateWebAuthnCredentialUsage.
+// Check the length with:
+//     len(mockedStorage.UpdateWebAuthnCredentialUsageCalls())
+func (mock *StorageMock) UpdateWebAuthnCredentialUsageCalls() []struct {
+	ID         []byte
+	SignCount  uint32
+	LastUsedAt time.Time
+} {
+	var calls []struct {
+		ID         []byte
+		SignCount  uint32
+		LastUsedAt time.Time
+	}
+	lockStorageMockUpdateWebAuthnCredentialUsage.RLock()
+	calls = mock.calls.UpdateWebAuthnCredentialUsage
+	lockStorageMockUpdateWebAuthnCredentialUsage.RUnlock()
+	return calls
+}
+
+// UseRecoveryCode calls UseRecoveryCodeFunc.
+func (mock *StorageMock) UseRecoveryCode(userID string, codeHash []byte, usedAt time.Time) error {
+	if mock.UseRecoveryCodeFunc == nil {
+		panic("StorageMock.UseRecoveryCodeFunc: method is nil but Storage.UseRecoveryCode was just called")
+	}
+	callInfo := struct {
+		UserID   string
+		CodeHash []byte
+		UsedAt   time.Time
+	}{
+		UserID:   userID,
+		CodeHash: codeHash,
+		UsedAt:   usedAt,
+	}
+	lockStorageMockUseRecoveryCode.Lock()
+	mock.calls.UseRecoveryCode = append(mock.calls.UseRecoveryCode, callInfo)
+	lockStorageMockUseRecoveryCode.Unlock()
+	return mock.UseRecoveryCodeFunc(userID, codeHash, usedAt)
+}
+
+// UseRecoveryCodeCalls gets all the calls that were made to UseRecoveryCode.
+// Check the length with:
+//     len(mockedStorage.UseRecoveryCodeCalls())
+func (mock *StorageMock) UseRecoveryCodeCalls() []struct {
+	UserID   string
+	CodeHash []byte
+	UsedAt   time.Time
+} {
+	var calls []struct {
+		UserID   string
+		CodeHash []byte
+		UsedAt   time.Time
+	}
+	lockStorageMockUseRecoveryCode.RLock()
+	calls = mock.calls.UseRecoveryCode
+	lockStorageMockUseRecoveryCode.RUnlock()
+	return calls
+}
+
 // User calls UserFunc.
 func (mock *StorageMock) User(email string) (storage.User, error) {
 	if mock.UserFunc == nil {
@@ -339,3 +1388,236 @@ func (mock *StorageMock) UserCalls() []struct {
 	lockStorageMockUser.RUnlock()
 	return calls
 }
+
+// UserByID calls UserByIDFunc.
+func (mock *StorageMock) UserByID(id string) (storage.User, error) {
+	if mock.UserByIDFunc == nil {
+		panic("StorageMock.UserByIDFunc: method is nil but Storage.UserByID was just called")
+	}
+	callInfo := struct {
+		ID string
+	}{
+		ID: id,
+	}
+	lockStorageMockUserByID.Lock()
+	mock.calls.UserByID = append(mock.calls.UserByID, callInfo)
+	lockStorageMockUserByID.Unlock()
+	return mock.UserByIDFunc(id)
+}
+
+// UserByIDCalls gets all the calls that were made to UserByID.
+// Check the length with:
+//     len(mockedStorage.UserByIDCalls())
+func (mock *StorageMock) UserByIDCalls() []struct {
+	ID string
+} {
+	var calls []struct {
+		ID string
+	}
+	lockStorageMockUserByID.RLock()
+	calls = mock.calls.UserByID
+	lockStorageMockUserByID.RUnlock()
+	return calls
+}
+
+// UserByPhone calls UserByPhoneFunc.
+func (mock *StorageMock) UserByPhone(phone string) (storage.User, error) {
+	if mock.UserByPhoneFunc == nil {
+		panic("StorageMock.UserByPhoneFunc: method is nil but Storage.UserByPhone was just called")
+	}
+	callInfo := struct {
+		Phone string
+	}{
+		Phone: phone,
+	}
+	lockStorageMockUserByPhone.Lock()
+	mock.calls.UserByPhone = append(mock.calls.UserByPhone, callInfo)
+	lockStorageMockUserByPhone.Unlock()
+	return mock.UserByPhoneFunc(phone)
+}
+
+// UserByPhoneCalls gets all the calls that were made to UserByPhone.
+// Check the length with:
+//     len(mockedStorage.UserByPhoneCalls())
+func (mock *StorageMock) UserByPhoneCalls() []struct {
+	Phone string
+} {
+	var calls []struct {
+		Phone string
+	}
+	lockStorageMockUserByPhone.RLock()
+	calls = mock.calls.UserByPhone
+	lockStorageMockUserByPhone.RUnlock()
+	return calls
+}
+
+// UserByUsername calls UserByUsernameFunc.
+func (mock *StorageMock) UserByUsername(username string) (storage.User, error) {
+	if mock.UserByUsernameFunc == nil {
+		panic("StorageMock.UserByUsernameFunc: method is nil but Storage.UserByUsername was just called")
+	}
+	callInfo := struct {
+		Username string
+	}{
+		Username: username,
+	}
+	lockStorageMockUserByUsername.Lock()
+	mock.calls.UserByUsername = append(mock.calls.UserByUsername, callInfo)
+	lockStorageMockUserByUsername.Unlock()
+	return mock.UserByUsernameFunc(username)
+}
+
+// UserByUsernameCalls gets all the calls that were made to UserByUsername.
+// Check the length with:
+//     len(mockedStorage.UserByUsernameCalls())
+func (mock *StorageMock) UserByUsernameCalls() []struct {
+	Username string
+} {
+	var calls []struct {
+		Username string
+	}
+	lockStorageMockUserByUsername.RLock()
+	calls = mock.calls.UserByUsername
+	lockStorageMockUserByUsername.RUnlock()
+	return calls
+}
+
+// UserData calls UserDataFunc.
+func (mock *StorageMock) UserData(id string) (storage.UserData, error) {
+	if mock.UserDataFunc == nil {
+		panic("StorageMock.UserDataFunc: method is nil but Storage.UserData was just called")
+	}
+	callInfo := struct {
+		ID string
+	}{
+		ID: id,
+	}
+	lockStorageMockUserData.Lock()
+	mock.calls.UserData = append(mock.calls.UserData, callInfo)
+	lockStorageMockUserData.Unlock()
+	return mock.UserDataFunc(id)
+}
+
+// UserDataCalls gets all the calls that were made to UserData.
+// Check the length with:
+//     len(mockedStorage.UserDataCalls())
+func (mock *StorageMock) UserDataCalls() []struct {
+	ID string
+} {
+	var calls []struct {
+		ID string
+	}
+	lockStorageMockUserData.RLock()
+	calls = mock.calls.UserData
+	lockStorageMockUserData.RUnlock()
+	return calls
+}
+
+// UsersByClaims calls UsersByClaimsFunc.
+func (mock *StorageMock) UsersByClaims(predicates []storage.ClaimPredicate, limit int, offset int) ([]storage.User, int, error) {
+	if mock.UsersByClaimsFunc == nil {
+		panic("StorageMock.UsersByClaimsFunc: method is nil but Storage.UsersByClaims was just called")
+	}
+	callInfo := struct {
+		Predicates []storage.ClaimPredicate
+		Limit      int
+		Offset     int
+	}{
+		Predicates: predicates,
+		Limit:      limit,
+		Offset:     offset,
+	}
+	lockStorageMockUsersByClaims.Lock()
+	mock.calls.UsersByClaims = append(mock.calls.UsersByClaims, callInfo)
+	lockStorageMockUsersByClaims.Unlock()
+	return mock.UsersByClaimsFunc(predicates, limit, offset)
+}
+
+// UsersByClaimsCalls gets all the calls that were made to UsersByClaims.
+// Check the length with:
+//     len(mockedStorage.UsersByClaimsCalls())
+func (mock *StorageMock) UsersByClaimsCalls() []struct {
+	Predicates []storage.ClaimPredicate
+	Limit      int
+	Offset     int
+} {
+	var calls []struct {
+		Predicates []storage.ClaimPredicate
+		Limit      int
+		Offset     int
+	}
+	lockStorageMockUsersByClaims.RLock()
+	calls = mock.calls.UsersByClaims
+	lockStorageMockUsersByClaims.RUnlock()
+	return calls
+}
+
+// UsersToRemindOfPasswordExpiry calls UsersToRemindOfPasswordExpiryFunc.
+func (mock *StorageMock) UsersToRemindOfPasswordExpiry(defaultMaxAgeDays int, reminderDays int, now time.Time) ([]storage.User, error) {
+	if mock.UsersToRemindOfPasswordExpiryFunc == nil {
+		panic("StorageMock.UsersToRemindOfPasswordExpiryFunc: method is nil but Storage.UsersToRemindOfPasswordExpiry was just called")
+	}
+	callInfo := struct {
+		DefaultMaxAgeDays int
+		ReminderDays      int
+		Now               time.Time
+	}{
+		DefaultMaxAgeDays: defaultMaxAgeDays,
+		ReminderDays:      reminderDays,
+		Now:               now,
+	}
+	lockStorageMockUsersToRemindOfPasswordExpiry.Lock()
+	mock.calls.UsersToRemindOfPasswordExpiry = append(mock.calls.UsersToRemindOfPasswordExpiry, callInfo)
+	lockStorageMockUsersToRemindOfPasswordExpiry.Unlock()
+	return mock.UsersToRemindOfPasswordExpiryFunc(defaultMaxAgeDays, reminderDays, now)
+}
+
+// UsersToRemindOfPasswordExpiryCalls gets all the calls that were made to UsersToRemindOfPasswordExpiry.
+// Check the length with:
+//     len(mockedStorage.UsersToRemindOfPasswordExpiryCalls())
+func (mock *StorageMock) UsersToRemindOfPasswordExpiryCalls() []struct {
+	DefaultMaxAgeDays int
+	ReminderDays      int
+	Now               time.Time
+} {
+	var calls []struct {
+		DefaultMaxAgeDays int
+		ReminderDays      int
+		Now               time.Time
+	}
+	lockStorageMockUsersToRemindOfPasswordExpiry.RLock()
+	calls = mock.calls.UsersToRemindOfPasswordExpiry
+	lockStorageMockUsersToRemindOfPasswordExpiry.RUnlock()
+	return calls
+}
+
+// WebAuthnCredentials calls WebAuthnCredentialsFunc.
+func (mock *StorageMock) WebAuthnCredentials(userID string) ([]storage.WebAuthnCredential, error) {
+	if mock.WebAuthnCredentialsFunc == nil {
+		panic("StorageMock.WebAuthnCredentialsFunc: method is nil but Storage.WebAuthnCredentials was just called")
+	}
+	callInfo := struct {
+		UserID string
+	}{
+		UserID: userID,
+	}
+	lockStorageMockWebAuthnCredentials.Lock()
+	mock.calls.WebAuthnCredentials = append(mock.calls.WebAuthnCredentials, callInfo)
+	lockStorageMockWebAuthnCredentials.Unlock()
+	return mock.WebAuthnCredentialsFunc(userID)
+}
+
+// WebAuthnCredentialsCalls gets all the calls that were made to WebAuthnCredentials.
+// Check the length with:
+//     len(mockedStorage.WebAuthnCredentialsCalls())
+func (mock *StorageMock) WebAuthnCredentialsCalls() []struct {
+	UserID string
+} {
+	var calls []struct {
+		UserID string
+	}
+	lockStorageMockWebAuthnCredentials.RLock()
+	calls = mock.calls.WebAuthnCredentials
+	lockStorageMockWebAuthnCredentials.RUnlock()
+	return calls
+}
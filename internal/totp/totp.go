@@ -0,0 +1,106 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const (
+	// Period is the lifetime of a single code
+	Period = 30 * time.Second
+	// Digits is the length of a code
+	Digits = 6
+	// secretSize is the size of generated secrets in bytes (160 bits as recommended by RFC 4226)
+	secretSize = 20
+	// skewSteps is the count of steps before and after the current one in which codes will be accepted
+	skewSteps = 1
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret generates a new random secret
+func GenerateSecret() ([]byte, error) {
+	secret := make([]byte, secretSize)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read random bytes: %w", err)
+	}
+
+	return secret, nil
+}
+
+// EncodeSecret encodes the given secret as base32 string (without padding) as expected by authenticator apps
+func EncodeSecret(secret []byte) string {
+	return encoding.EncodeToString(secret)
+}
+
+// URI builds the otpauth:// key-uri (https://github.com/google/google-authenticator/wiki/Key-Uri-Format) for the
+// given secret which can be rendered as qr-code for authenticator apps
+func URI(issuer, account string, secret []byte) string {
+	query := url.Values{}
+	query.Set("secret", EncodeSecret(secret))
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(Digits))
+	query.Set("period", fmt.Sprint(int(Period.Seconds())))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: query.Encode(),
+	}
+
+	return u.String()
+}
+
+// Step returns the time step of the given time
+func Step(t time.Time) int64 {
+	return t.Unix() / int64(Period.Seconds())
+}
+
+// Code calculates the code of the given secret for the given time step (RFC 6238 with HMAC-SHA1)
+func Code(secret []byte, step int64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(step))
+
+	mac := hmac.New(sha1.New, secret)
+	_, _ = mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	// dynamic truncation by https://tools.ietf.org/html/rfc4226#section-5.4
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < Digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", Digits, value%mod)
+}
+
+// Validate checks the given code against the codes of the given secret around the given time. Codes of steps lower or
+// equal to lastUsedStep will not be accepted to prevent replays. The matching step will be returned when the code is
+// valid.
+func Validate(secret []byte, code string, now time.Time, lastUsedStep int64) (int64, bool) {
+	current := Step(now)
+	for step := current - skewSteps; step <= current+skewSteps; step++ {
+		if step <= lastUsedStep {
+			continue
+		}
+
+		if subtle.ConstantTimeCompare([]byte(Code(secret, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,105 @@
+package totp
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// secret of the RFC 6238 SHA1 test vectors (https://tools.ietf.org/html/rfc6238#appendix-B)
+var rfcSecret = []byte("12345678901234567890")
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		givenTime    int64
+		expectedCode string
+	}{
+		{givenTime: 59, expectedCode: "287082"},
+		{givenTime: 1111111109, expectedCode: "081804"},
+		{givenTime: 1111111111, expectedCode: "050471"},
+		{givenTime: 1234567890, expectedCode: "005924"},
+		{givenTime: 2000000000, expectedCode: "279037"},
+		{givenTime: 20000000000, expectedCode: "353130"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.givenTime), func(t *testing.T) {
+			code := Code(rfcSecret, Step(time.Unix(tt.givenTime, 0)))
+			if code != tt.expectedCode {
+				t.Errorf("Code is not as expected. Expected: %q, Given: %q", tt.expectedCode, code)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	currentStep := Step(now)
+
+	tests := []struct {
+		name              string
+		givenCode         string
+		givenLastUsedStep int64
+		expectedStep      int64
+		expectedValid     bool
+	}{
+		{
+			name:          "Current code",
+			givenCode:     Code(rfcSecret, currentStep),
+			expectedStep:  currentStep,
+			expectedValid: true,
+		}, {
+			name:          "Previous code",
+			givenCode:     Code(rfcSecret, currentStep-1),
+			expectedStep:  currentStep - 1,
+			expectedValid: true,
+		}, {
+			name:          "Next code",
+			givenCode:     Code(rfcSecret, currentStep+1),
+			expectedStep:  currentStep + 1,
+			expectedValid: true,
+		}, {
+			name:      "Code out of skew",
+			givenCode: Code(rfcSecret, currentStep-2),
+		}, {
+			name:              "Code already used",
+			givenCode:         Code(rfcSecret, currentStep),
+			givenLastUsedStep: currentStep,
+		}, {
+			name:      "Invalid code",
+			givenCode: "123",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, valid := Validate(rfcSecret, tt.givenCode, now, tt.givenLastUsedStep)
+			if valid != tt.expectedValid {
+				t.Fatalf("Valid is not as expected. Expected: %t, Given: %t", tt.expectedValid, valid)
+			}
+			if step != tt.expectedStep {
+				t.Errorf("Step is not as expected. Expected: %d, Given: %d", tt.expectedStep, step)
+			}
+		})
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("simple-jwt-provider", "info@leberkleber.io", rfcSecret)
+
+	expectedURI := "otpauth://totp/simple-jwt-provider:info@leberkleber.io?algorithm=SHA1&digits=6&issuer=simple-jwt-provider&period=30&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	if uri != expectedURI {
+		t.Errorf("URI is not as expected. Expected:\n%q\nGiven:\n%q", expectedURI, uri)
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(secret) != secretSize {
+		t.Errorf("Secret size is not as expected. Expected: %d, Given: %d", secretSize, len(secret))
+	}
+}
@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/jwt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+var ErrInvalidAccessToken = errors.New("invalid access token")
+
+// UserExport is everything stored about a user for data subject access requests. Secrets (password hashes, token
+// values, totp secrets, webauthn public keys and recovery code hashes) are never part of it.
+type UserExport struct {
+	User                User
+	Tokens              []storage.TokenData
+	PasswordHistory     []time.Time
+	TOTP                *storage.TOTPData
+	WebAuthnCredentials []storage.WebAuthnCredentialData
+	RecoveryCodes       []storage.RecoveryCodeData
+	Logins              []storage.Login
+	ExportedAt          time.Time
+}
+
+// UserErasure is the proof of the erasure of a user. It contains no personal data besides the id of the erased user.
+type UserErasure struct {
+	UserID string
+	// DeletedRows is the count of deleted rows per table
+	DeletedRows map[string]int64
+	ErasedAt    time.Time
+}
+
+// ExportUser returns everything stored about the user with the given id or login identifier
+// return ErrUserNotFound when user does not exist
+func (p Provider) ExportUser(idOrIdentifier string) (UserExport, error) {
+	dbUser, err := p.findUser(idOrIdentifier)
+	if err != nil {
+		return UserExport{}, err
+	}
+
+	data, err := p.Storage.UserData(dbUser.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return UserExport{}, ErrUserNotFound
+		}
+
+		return UserExport{}, fmt.Errorf("failed to query data of user %q: %w", dbUser.ID, err)
+	}
+
+	return UserExport{
+		User:                toUser(data.User),
+		Tokens:              data.Tokens,
+		PasswordHistory:     data.PasswordHistory,
+		TOTP:                data.TOTP,
+		WebAuthnCredentials: data.WebAuthnCredentials,
+		RecoveryCodes:       data.RecoveryCodes,
+		Logins:              data.Logins,
+		ExportedAt:          nowFunc(),
+	}, nil
+}
+
+// EraseUser irreversibly deletes the user with the given id or login identifier and all of its rows in all other
+// tables in one transaction. Issued jwts stay valid until they expire.
+// return ErrUserNotFound when user does not exist
+func (p Provider) EraseUser(idOrIdentifier string) (UserErasure, error) {
+	dbUser, err := p.findUser(idOrIdentifier)
+	if err != nil {
+		return UserErasure{}, err
+	}
+
+	deletedRows, err := p.Storage.EraseUser(dbUser.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return UserErasure{}, ErrUserNotFound
+		}
+
+		return UserErasure{}, fmt.Errorf("failed to erase user %q: %w", dbUser.ID, err)
+	}
+
+	logrus.WithField("userID", dbUser.ID).Info("User has been erased")
+
+	return UserErasure{
+		UserID:      dbUser.ID,
+		DeletedRows: deletedRows,
+		ErasedAt:    nowFunc(),
+	}, nil
+}
+
+// VerifyAccessToken verifies the given jwt issued by this provider and returns the id of the user it has been issued
+// to
+// return ErrInvalidAccessToken when the jwt is malformed, expired or has not been issued by this provider
+func (p Provider) VerifyAccessToken(accessToken string) (string, error) {
+	userID, err := p.JWTGenerator.Verify(accessToken)
+	if err != nil {
+		if errors.Is(err, jwt.ErrInvalidToken) {
+			return "", ErrInvalidAccessToken
+		}
+
+		return "", fmt.Errorf("failed to verify access token: %w", err)
+	}
+
+	return userID, nil
+}
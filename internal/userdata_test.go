@@ -0,0 +1,186 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/jwt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProvider_ExportUser(t *testing.T) {
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	nowFunc = func() time.Time { return now }
+
+	tests := []struct {
+		name           string
+		givenUser      string
+		dbReturnError  error
+		expectedExport UserExport
+		expectedError  error
+	}{
+		{
+			name:      "Happycase",
+			givenUser: "test@test.test",
+			expectedExport: UserExport{
+				User: User{
+					ID:       "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+					EMail:    "test@test.test",
+					Password: blankedPassword,
+				},
+				Tokens:     []storage.TokenData{{Type: storage.TokenTypeReset, CreatedAt: now}},
+				TOTP:       &storage.TOTPData{Confirmed: true, CreatedAt: now},
+				ExportedAt: now,
+			},
+		}, {
+			name:          "User not found",
+			givenUser:     "test@test.test",
+			dbReturnError: storage.ErrUserNotFound,
+			expectedError: ErrUserNotFound,
+		}, {
+			name:          "Some db error",
+			givenUser:     "test@test.test",
+			dbReturnError: errors.New("nope"),
+			expectedError: errors.New(`failed to query data of user "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b": nope`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toTest := Provider{
+				Storage: &StorageMock{
+					UserFunc: func(email string) (storage.User, error) {
+						return storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", EMail: email}, nil
+					},
+					UserDataFunc: func(id string) (storage.UserData, error) {
+						return storage.UserData{
+							User:   storage.User{ID: id, EMail: "test@test.test", Password: []byte("secret")},
+							Tokens: []storage.TokenData{{Type: storage.TokenTypeReset, CreatedAt: now}},
+							TOTP:   &storage.TOTPData{Confirmed: true, CreatedAt: now},
+						}, tt.dbReturnError
+					},
+				},
+			}
+
+			export, err := toTest.ExportUser(tt.givenUser)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:%s\nGiven:%s", tt.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(export, tt.expectedExport) {
+				t.Errorf("Export is not as expected: \nExpected:%#v\nGiven:%#v", tt.expectedExport, export)
+			}
+		})
+	}
+}
+
+func TestProvider_EraseUser(t *testing.T) {
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	nowFunc = func() time.Time { return now }
+
+	tests := []struct {
+		name            string
+		givenUser       string
+		dbReturnError   error
+		expectedErasure UserErasure
+		expectedError   error
+	}{
+		{
+			name:      "Happycase",
+			givenUser: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			expectedErasure: UserErasure{
+				UserID:      "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				DeletedRows: map[string]int64{"users": 1, "tokens": 2},
+				ErasedAt:    now,
+			},
+		}, {
+			name:          "User not found",
+			givenUser:     "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			dbReturnError: storage.ErrUserNotFound,
+			expectedError: ErrUserNotFound,
+		}, {
+			name:          "Some db error",
+			givenUser:     "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			dbReturnError: errors.New("nope"),
+			expectedError: errors.New(`failed to erase user "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b": nope`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toTest := Provider{
+				Storage: &StorageMock{
+					UserByIDFunc: func(id string) (storage.User, error) {
+						return storage.User{ID: id}, nil
+					},
+					EraseUserFunc: func(id string) (map[string]int64, error) {
+						if tt.dbReturnError != nil {
+							return nil, tt.dbReturnError
+						}
+						return map[string]int64{"users": 1, "tokens": 2}, nil
+					},
+				},
+			}
+
+			erasure, err := toTest.EraseUser(tt.givenUser)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:%s\nGiven:%s", tt.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(erasure, tt.expectedErasure) {
+				t.Errorf("Erasure is not as expected: \nExpected:%#v\nGiven:%#v", tt.expectedErasure, erasure)
+			}
+		})
+	}
+}
+
+func TestProvider_VerifyAccessToken(t *testing.T) {
+	tests := []struct {
+		name           string
+		verifyUserID   string
+		verifyError    error
+		expectedUserID string
+		expectedError  error
+	}{
+		{
+			name:           "Happycase",
+			verifyUserID:   "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			expectedUserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+		}, {
+			name:          "Invalid token",
+			verifyError:   fmt.Errorf("%w: expired", jwt.ErrInvalidToken),
+			expectedError: ErrInvalidAccessToken,
+		}, {
+			name:          "Unexpected error",
+			verifyError:   errors.New("nope"),
+			expectedError: errors.New("failed to verify access token: nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toTest := Provider{
+				JWTGenerator: &JWTGeneratorMock{
+					VerifyFunc: func(token string) (string, error) {
+						return tt.verifyUserID, tt.verifyError
+					},
+				},
+			}
+
+			userID, err := toTest.VerifyAccessToken("myJWT")
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:%s\nGiven:%s", tt.expectedError, err)
+			}
+
+			if userID != tt.expectedUserID {
+				t.Errorf("Unexpected user id. Expected: %q, Given: %q", tt.expectedUserID, userID)
+			}
+		})
+	}
+}
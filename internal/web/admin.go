@@ -6,15 +6,76 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/leberKleber/simple-jwt-provider/internal"
 	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"mime"
 	"net/http"
 	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultUsersLimit = 50
+	maxUsersLimit     = 500
 )
 
 // User is the representation of a user for use in web
 type User struct {
-	EMail    string                 `json:"email"`
-	Password string                 `json:"password"`
-	Claims   map[string]interface{} `json:"claims"`
+	ID                 string                 `json:"id,omitempty"`
+	EMail              string                 `json:"email"`
+	DisplayEMail       string                 `json:"display_email,omitempty"`
+	Username           *string                `json:"username,omitempty"`
+	Phone              *string                `json:"phone,omitempty"`
+	Password           string                 `json:"password"`
+	Claims             map[string]interface{} `json:"claims"`
+	Metadata           map[string]interface{} `json:"metadata,omitempty"`
+	PasswordChangedAt  *time.Time             `json:"password_changed_at,omitempty"`
+	PasswordMaxAgeDays *int                   `json:"password_max_age_days,omitempty"`
+	LastLoginAt        *time.Time             `json:"last_login_at,omitempty"`
+	InvitationPending  bool                   `json:"invitation_pending,omitempty"`
+}
+
+// ClaimPredicate is the representation of a condition on a top level claim of users for use in web
+type ClaimPredicate struct {
+	Claim    string      `json:"claim"`
+	Operator string      `json:"operator"`
+	Value    interface{} `json:"value"`
+}
+
+// UserSearch is the representation of a search for users for use in web
+type UserSearch struct {
+	Claims []ClaimPredicate `json:"claims"`
+}
+
+// UserPage is a page of users
+type UserPage struct {
+	Users  []User `json:"users"`
+	Total  int    `json:"total"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+}
+
+// toWebUser converts the given internal.User to a User
+func toWebUser(u internal.User) User {
+	user := User{
+		ID:                 u.ID,
+		EMail:              u.EMail,
+		DisplayEMail:       u.DisplayEMail,
+		Username:           u.Username,
+		Phone:              u.Phone,
+		Password:           u.Password,
+		Claims:             u.Claims,
+		Metadata:           u.Metadata,
+		PasswordMaxAgeDays: u.PasswordMaxAgeDays,
+		LastLoginAt:        u.LastLoginAt,
+		InvitationPending:  u.InvitationPending,
+	}
+	if !u.PasswordChangedAt.IsZero() {
+		passwordChangedAt := u.PasswordChangedAt
+		user.PasswordChangedAt = &passwordChangedAt
+	}
+
+	return user
 }
 
 func (s *Server) createUserHandler(w http.ResponseWriter, r *http.Request) {
@@ -26,24 +87,44 @@ func (s *Server) createUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if user.EMail == "" {
-		writeError(w, http.StatusBadRequest, "email must be set")
-		return
-	}
-
-	if user.Password == "" {
-		writeError(w, http.StatusBadRequest, "password must be set")
+	if user.EMail == "" && isEmpty(user.Username) && isEmpty(user.Phone) {
+		writeError(w, http.StatusBadRequest, "email, username or phone must be set")
 		return
 	}
 
 	err = s.p.CreateUser(internal.User{
-		EMail:    user.EMail,
-		Password: user.Password,
-		Claims:   user.Claims,
+		EMail:              user.EMail,
+		Username:           user.Username,
+		Phone:              user.Phone,
+		Password:           user.Password,
+		Claims:             user.Claims,
+		Metadata:           user.Metadata,
+		PasswordMaxAgeDays: user.PasswordMaxAgeDays,
 	})
 	if err != nil {
+		if errors.Is(err, internal.ErrInvalidEMail) {
+			writeError(w, http.StatusBadRequest, "invalid email")
+			return
+		}
+		if errors.Is(err, internal.ErrInvalidUsername) || errors.Is(err, internal.ErrInvalidPhone) ||
+			errors.Is(err, internal.ErrNoLoginIdentifier) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		if errors.Is(err, internal.ErrUserAlreadyExists) {
-			writeError(w, http.StatusConflict, "User with given email already exists")
+			writeError(w, http.StatusConflict, "User with given email, username or phone already exists")
+			return
+		}
+		if errors.Is(err, internal.ErrPasswordBreached) {
+			writeError(w, http.StatusBadRequest, "password has been found in a data breach")
+			return
+		}
+		if errors.Is(err, internal.ErrInvitationNotConfigured) {
+			writeError(w, http.StatusBadRequest, "password must be set")
+			return
+		}
+		if errors.Is(err, internal.ErrInvitationRequiresEMail) {
+			writeError(w, http.StatusBadRequest, "email must be set to invite a user")
 			return
 		}
 
@@ -56,16 +137,20 @@ func (s *Server) createUserHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) getUserHandler(w http.ResponseWriter, r *http.Request) {
-	email, err := url.PathUnescape(mux.Vars(r)["email"])
+	idOrIdentifier, err := url.PathUnescape(mux.Vars(r)["user"])
 	if err != nil {
 		writeError(w, http.StatusBadRequest, "could not unescape email")
 		return
 	}
 
-	//when email has not been set 'notFoundHandler' handler will be used
+	//when user has not been set 'notFoundHandler' handler will be used
 
-	user, err := s.p.GetUser(email)
+	user, err := s.p.GetUser(idOrIdentifier)
 	if err != nil {
+		if errors.Is(err, internal.ErrInvalidEMail) {
+			writeError(w, http.StatusBadRequest, "invalid email")
+			return
+		}
 		if errors.Is(err, internal.ErrUserNotFound) {
 			writeError(w, http.StatusNotFound, "User with given email doesn't exists")
 			return
@@ -76,11 +161,7 @@ func (s *Server) getUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = json.NewEncoder(w).Encode(User{
-		EMail:    user.EMail,
-		Password: user.Password,
-		Claims:   user.Claims,
-	})
+	err = json.NewEncoder(w).Encode(toWebUser(user))
 	if err != nil {
 		logrus.WithError(err).Error("Failed to encode User")
 		writeInternalServerError(w)
@@ -89,13 +170,13 @@ func (s *Server) getUserHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) updateUserHandler(w http.ResponseWriter, r *http.Request) {
-	email, err := url.PathUnescape(mux.Vars(r)["email"])
+	idOrIdentifier, err := url.PathUnescape(mux.Vars(r)["user"])
 	if err != nil {
 		writeError(w, http.StatusBadRequest, "could not unescape email")
 		return
 	}
 
-	//when email has not been set 'notFoundHandler' handler will be used
+	//when user has not been set 'notFoundHandler' handler will be used
 
 	var user User
 	err = json.NewDecoder(r.Body).Decode(&user)
@@ -109,26 +190,124 @@ func (s *Server) updateUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updatedUser, err := s.p.UpdateUser(email, internal.User{
-		Password: user.Password,
-		Claims:   user.Claims,
+	updatedUser, err := s.p.UpdateUser(idOrIdentifier, internal.User{
+		Username:           user.Username,
+		Phone:              user.Phone,
+		Password:           user.Password,
+		Claims:             user.Claims,
+		Metadata:           user.Metadata,
+		PasswordMaxAgeDays: user.PasswordMaxAgeDays,
 	})
 	if err != nil {
+		if errors.Is(err, internal.ErrInvalidEMail) {
+			writeError(w, http.StatusBadRequest, "invalid email")
+			return
+		}
 		if errors.Is(err, internal.ErrUserNotFound) {
 			writeError(w, http.StatusNotFound, "User with given email doesn't exists")
 			return
 		}
+		if errors.Is(err, internal.ErrInvalidUsername) || errors.Is(err, internal.ErrInvalidPhone) ||
+			errors.Is(err, internal.ErrNoLoginIdentifier) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, internal.ErrUserAlreadyExists) {
+			writeError(w, http.StatusConflict, "User with given username or phone already exists")
+			return
+		}
+		if errors.Is(err, internal.ErrPasswordBreached) {
+			writeError(w, http.StatusBadRequest, "password has been found in a data breach")
+			return
+		}
+		if errors.Is(err, internal.ErrPasswordReused) {
+			writeError(w, http.StatusBadRequest, "password has been used recently")
+			return
+		}
 
 		logrus.WithError(err).Error("Failed to update User")
 		writeInternalServerError(w)
 		return
 	}
 
-	err = json.NewEncoder(w).Encode(User{
-		EMail:    updatedUser.EMail,
-		Password: updatedUser.Password,
-		Claims:   updatedUser.Claims,
-	})
+	err = json.NewEncoder(w).Encode(toWebUser(updatedUser))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to encode User")
+		writeInternalServerError(w)
+		return
+	}
+}
+
+// patchFormats are the supported content types of user patches
+var patchFormats = map[string]internal.PatchFormat{
+	"application/merge-patch+json": internal.MergePatch,
+	"application/json-patch+json":  internal.JSONPatch,
+}
+
+func (s *Server) patchUserHandler(w http.ResponseWriter, r *http.Request) {
+	idOrIdentifier, err := url.PathUnescape(mux.Vars(r)["user"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "could not unescape email")
+		return
+	}
+
+	//when user has not been set 'notFoundHandler' handler will be used
+
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	format, ok := patchFormats[contentType]
+	if !ok {
+		writeError(w, http.StatusUnsupportedMediaType, "content type must be application/merge-patch+json or application/json-patch+json")
+		return
+	}
+
+	patch, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "could not read body")
+		return
+	}
+
+	patchedUser, err := s.p.PatchUser(idOrIdentifier, format, patch)
+	if err != nil {
+		if errors.Is(err, internal.ErrInvalidEMail) {
+			writeError(w, http.StatusBadRequest, "invalid email")
+			return
+		}
+		if errors.Is(err, internal.ErrUserNotFound) {
+			writeError(w, http.StatusNotFound, "User with given email doesn't exists")
+			return
+		}
+		if errors.Is(err, internal.ErrInvalidPatch) || errors.Is(err, internal.ErrInvalidUsername) ||
+			errors.Is(err, internal.ErrInvalidPhone) || errors.Is(err, internal.ErrNoLoginIdentifier) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, internal.ErrPatchNotApplicable) {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if errors.Is(err, internal.ErrInvalidPatchedUser) {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		if errors.Is(err, internal.ErrUserAlreadyExists) {
+			writeError(w, http.StatusConflict, "User with given username or phone already exists")
+			return
+		}
+		if errors.Is(err, internal.ErrPasswordBreached) {
+			writeError(w, http.StatusBadRequest, "password has been found in a data breach")
+			return
+		}
+		if errors.Is(err, internal.ErrPasswordReused) {
+			writeError(w, http.StatusBadRequest, "password has been used recently")
+			return
+		}
+
+		logrus.WithError(err).Error("Failed to patch User")
+		writeInternalServerError(w)
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(toWebUser(patchedUser))
 	if err != nil {
 		logrus.WithError(err).Error("Failed to encode User")
 		writeInternalServerError(w)
@@ -137,16 +316,20 @@ func (s *Server) updateUserHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) deleteUserHandler(w http.ResponseWriter, r *http.Request) {
-	email, err := url.PathUnescape(mux.Vars(r)["email"])
+	idOrIdentifier, err := url.PathUnescape(mux.Vars(r)["user"])
 	if err != nil {
 		writeError(w, http.StatusBadRequest, "could not unescape email")
 		return
 	}
 
-	//when email has not been set 'notFoundHandler' handler will be used
+	//when user has not been set 'notFoundHandler' handler will be used
 
-	err = s.p.DeleteUser(email)
+	err = s.p.DeleteUser(idOrIdentifier)
 	if err != nil {
+		if errors.Is(err, internal.ErrInvalidEMail) {
+			writeError(w, http.StatusBadRequest, "invalid email")
+			return
+		}
 		if errors.Is(err, internal.ErrUserNotFound) {
 			writeError(w, http.StatusNotFound, "User with given email doesnt already exists")
 			return
@@ -159,3 +342,84 @@ func (s *Server) deleteUserHandler(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func (s *Server) resetMFAHandler(w http.ResponseWriter, r *http.Request) {
+	idOrIdentifier, err := url.PathUnescape(mux.Vars(r)["user"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "could not unescape email")
+		return
+	}
+
+	err = s.p.ResetMFA(idOrIdentifier)
+	if err != nil {
+		if errors.Is(err, internal.ErrInvalidEMail) {
+			writeError(w, http.StatusBadRequest, "invalid email")
+			return
+		}
+		if errors.Is(err, internal.ErrUserNotFound) {
+			writeError(w, http.StatusNotFound, "User with given email doesnt already exists")
+			return
+		}
+
+		logrus.WithError(err).Error("Failed to reset mfa of User")
+		writeInternalServerError(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) searchUsersHandler(w http.ResponseWriter, r *http.Request) {
+	limit, ok := queryInt(r, "limit", defaultUsersLimit)
+	if !ok || limit < 1 || limit > maxUsersLimit {
+		writeError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxUsersLimit))
+		return
+	}
+
+	offset, ok := queryInt(r, "offset", 0)
+	if !ok || offset < 0 {
+		writeError(w, http.StatusBadRequest, "offset must not be negative")
+		return
+	}
+
+	var search UserSearch
+	err := json.NewDecoder(r.Body).Decode(&search)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	var predicates []internal.ClaimPredicate
+	for _, c := range search.Claims {
+		predicates = append(predicates, internal.ClaimPredicate{Claim: c.Claim, Operator: c.Operator, Value: c.Value})
+	}
+
+	page, err := s.p.FindUsers(predicates, limit, offset)
+	if err != nil {
+		if errors.Is(err, internal.ErrInvalidClaimPredicate) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		logrus.WithError(err).Error("Failed to find Users")
+		writeInternalServerError(w)
+		return
+	}
+
+	response := UserPage{Users: []User{}, Total: page.Total, Limit: limit, Offset: offset}
+	for _, u := range page.Users {
+		response.Users = append(response.Users, toWebUser(u))
+	}
+
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to encode Users")
+		writeInternalServerError(w)
+		return
+	}
+}
+
+// isEmpty returns true when the given optional string is nil or empty
+func isEmpty(s *string) bool {
+	return s == nil || *s == ""
+}
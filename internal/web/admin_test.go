@@ -11,6 +11,7 @@ import (
 	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestCreateUserHandler(t *testing.T) {
@@ -36,10 +37,31 @@ func TestCreateUserHandler(t *testing.T) {
 			expectedResponseCode: http.StatusCreated,
 		},
 		{
-			name:                 "Missing email",
-			requestBody:          `{"password": "s3cr3t"}`,
+			name:        "Happycase with username and phone",
+			requestBody: `{"username": "alice", "phone": "+491701234567", "password": "s3cr3t"}`,
+			expectedUser: User{
+				Username: stringPtr("alice"),
+				Phone:    stringPtr("+491701234567"),
+				Password: "s3cr3t",
+			},
+			expectedResponseCode: http.StatusCreated,
+		},
+		{
+			name:                 "Missing login identifier",
+			requestBody:          `{"username": "", "password": "s3cr3t"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"email, username or phone must be set"}`,
+		},
+		{
+			name:          "Invalid username",
+			requestBody:   `{"username": "a", "password": "s3cr3t"}`,
+			providerError: internal.ErrInvalidUsername,
+			expectedUser: User{
+				Username: stringPtr("a"),
+				Password: "s3cr3t",
+			},
 			expectedResponseCode: http.StatusBadRequest,
-			expectedResponseBody: `{"message":"email must be set"}`,
+			expectedResponseBody: `{"message":"invalid username"}`,
 		},
 		{
 			name:                 "Invalid JSON",
@@ -48,11 +70,44 @@ func TestCreateUserHandler(t *testing.T) {
 			expectedResponseBody: `{"message":"invalid JSON"}`,
 		},
 		{
-			name:                 "Missing password",
-			requestBody:          `{"email": "test.test@test.test"}`,
+			name:        "Invitation without password",
+			requestBody: `{"email": "test.test@test.test"}`,
+			expectedUser: User{
+				EMail: "test.test@test.test",
+			},
+			expectedResponseCode: http.StatusCreated,
+		},
+		{
+			name:          "Missing password without invitations",
+			requestBody:   `{"email": "test.test@test.test"}`,
+			providerError: internal.ErrInvitationNotConfigured,
+			expectedUser: User{
+				EMail: "test.test@test.test",
+			},
 			expectedResponseCode: http.StatusBadRequest,
 			expectedResponseBody: `{"message":"password must be set"}`,
 		},
+		{
+			name:          "Invitation without email",
+			requestBody:   `{"username": "alice"}`,
+			providerError: internal.ErrInvitationRequiresEMail,
+			expectedUser: User{
+				Username: stringPtr("alice"),
+			},
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"email must be set to invite a user"}`,
+		},
+		{
+			name:          "Invalid email",
+			requestBody:   `{"email": "test.test", "password": "s3cr3t"}`,
+			providerError: internal.ErrInvalidEMail,
+			expectedUser: User{
+				EMail:    "test.test",
+				Password: "s3cr3t",
+			},
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid email"}`,
+		},
 		{
 			name:          "User already exists",
 			requestBody:   `{"email": "test.test@test.test", "password": "s3cr3t", "claims": {"hello": "world", "c": 42}}`,
@@ -66,7 +121,18 @@ func TestCreateUserHandler(t *testing.T) {
 				},
 			},
 			expectedResponseCode: http.StatusConflict,
-			expectedResponseBody: `{"message":"User with given email already exists"}`,
+			expectedResponseBody: `{"message":"User with given email, username or phone already exists"}`,
+		},
+		{
+			name:          "Breached password",
+			requestBody:   `{"email": "test.test@test.test", "password": "password"}`,
+			providerError: internal.ErrPasswordBreached,
+			expectedUser: User{
+				EMail:    "test.test@test.test",
+				Password: "password",
+			},
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"password has been found in a data breach"}`,
 		},
 		{
 			name:          "Unexpected error",
@@ -143,6 +209,8 @@ func TestCreateUserHandler(t *testing.T) {
 }
 
 func TestGetUserHandler(t *testing.T) {
+	lastLoginAt := time.Date(2020, 2, 2, 4, 46, 45, 0, time.UTC)
+
 	tests := []struct {
 		name                 string
 		providerError        error
@@ -166,6 +234,67 @@ func TestGetUserHandler(t *testing.T) {
 			expectedResponseCode: http.StatusOK,
 			expectedResponseBody: `{"email":"test.test@test.test","password":"myPassword","claims":{"test":"claim"}}`,
 		},
+		{
+			name:         "By id",
+			requestEmail: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			providerUser: internal.User{
+				ID:       "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+				EMail:    "test.test@test.test",
+				Password: "myPassword",
+			},
+			expectedEncodedEmail: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"id":"c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b","email":"test.test@test.test","password":"myPassword","claims":null}`,
+		},
+		{
+			name:         "With display email",
+			requestEmail: "Info%40LeberKleber.io",
+			providerUser: internal.User{
+				EMail:        "Info@leberkleber.io",
+				DisplayEMail: "Info@LeberKleber.io",
+				Password:     "myPassword",
+			},
+			expectedEncodedEmail: "Info@LeberKleber.io",
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"email":"Info@leberkleber.io","display_email":"Info@LeberKleber.io","password":"myPassword","claims":null}`,
+		},
+		{
+			name:         "With password expiry",
+			requestEmail: "info%40leberkleber.io",
+			providerUser: internal.User{
+				EMail:              "test.test@test.test",
+				Password:           "myPassword",
+				PasswordChangedAt:  time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC),
+				PasswordMaxAgeDays: intPtr(90),
+			},
+			expectedEncodedEmail: "info@leberkleber.io",
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"email":"test.test@test.test","password":"myPassword","claims":null,"password_changed_at":"2020-02-01T04:46:45Z","password_max_age_days":90}`,
+		},
+		{
+			name:         "With last login",
+			requestEmail: "info%40leberkleber.io",
+			providerUser: internal.User{
+				EMail:       "test.test@test.test",
+				Password:    "myPassword",
+				LastLoginAt: &lastLoginAt,
+			},
+			expectedEncodedEmail: "info@leberkleber.io",
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"email":"test.test@test.test","password":"myPassword","claims":null,"last_login_at":"2020-02-02T04:46:45Z"}`,
+		},
+		{
+			name:         "With pending invitation",
+			requestEmail: "info%40leberkleber.io",
+			providerUser: internal.User{
+				EMail:             "test.test@test.test",
+				Password:          "myPassword",
+				InvitationPending: true,
+			},
+			expectedEncodedEmail: "info@leberkleber.io",
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"email":"test.test@test.test","password":"myPassword","claims":null,"invitation_pending":true}`,
+		},
 		{
 			name:                 "User not found",
 			requestEmail:         "info%40leberkleber.io",
@@ -271,6 +400,22 @@ func TestUpdateUserHandler(t *testing.T) {
 			expectedResponseCode: http.StatusOK,
 			expectedResponseBody: `{"email":"test.test@test.test","password":"**********","claims":{"c":42,"hello":"world"}}`,
 		},
+		{
+			name:         "Happycase metadata only",
+			requestBody:  `{"metadata": {"firstName": "Alice"}}`,
+			requestEmail: `test.test@test.test`,
+			providerUser: internal.User{
+				EMail:    "test.test@test.test",
+				Password: "**********",
+				Claims:   map[string]interface{}{"hello": "world"},
+				Metadata: map[string]interface{}{"firstName": "Alice"},
+			},
+			expectedUser: User{
+				Metadata: map[string]interface{}{"firstName": "Alice"},
+			},
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"email":"test.test@test.test","password":"**********","claims":{"hello":"world"},"metadata":{"firstName":"Alice"}}`,
+		},
 		{
 			name:                 "Missing in body has been set",
 			requestBody:          `{"email": "test1.test1@test1.test1", "password": "s3cr3t"}`,
@@ -300,6 +445,50 @@ func TestUpdateUserHandler(t *testing.T) {
 			expectedResponseCode: http.StatusNotFound,
 			expectedResponseBody: `{"message":"User with given email doesn't exists"}`,
 		},
+		{
+			name:          "Username already taken",
+			requestBody:   `{"username": "alice"}`,
+			requestEmail:  `test.test@test.test`,
+			providerError: internal.ErrUserAlreadyExists,
+			expectedUser: User{
+				Username: stringPtr("alice"),
+			},
+			expectedResponseCode: http.StatusConflict,
+			expectedResponseBody: `{"message":"User with given username or phone already exists"}`,
+		},
+		{
+			name:          "Last login identifier removed",
+			requestBody:   `{"phone": ""}`,
+			requestEmail:  `+491701234567`,
+			providerError: internal.ErrNoLoginIdentifier,
+			expectedUser: User{
+				Phone: stringPtr(""),
+			},
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"user has neither email nor username nor phone"}`,
+		},
+		{
+			name:          "Breached password",
+			requestBody:   `{"password": "password"}`,
+			requestEmail:  `test.test@test.test`,
+			providerError: internal.ErrPasswordBreached,
+			expectedUser: User{
+				Password: "password",
+			},
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"password has been found in a data breach"}`,
+		},
+		{
+			name:          "Reused password",
+			requestBody:   `{"password": "s3cr3t"}`,
+			requestEmail:  `test.test@test.test`,
+			providerError: internal.ErrPasswordReused,
+			expectedUser: User{
+				Password: "s3cr3t",
+			},
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"password has been used recently"}`,
+		},
 		{
 			name:          "Unexpected error",
 			requestBody:   `{"password": "s3cr3t", "claims": {"hello": "world", "c": 42}}`,
@@ -348,6 +537,10 @@ func TestUpdateUserHandler(t *testing.T) {
 				t.Errorf("Provider called with unexpected User. Given: \n%#v \nExpected: \n%#v", givenUser, tt.expectedUser)
 			}
 
+			if !reflect.DeepEqual(givenUser.Metadata, tt.expectedUser.Metadata) {
+				t.Errorf("Provider called with unexpected metadata. Given: \n%#v \nExpected: \n%#v", givenUser.Metadata, tt.expectedUser.Metadata)
+			}
+
 			if resp.StatusCode != tt.expectedResponseCode {
 				t.Errorf("Request respond with unexpected status code. Expected: %d, Given: %d", tt.expectedResponseCode, resp.StatusCode)
 			}
@@ -375,6 +568,166 @@ func TestUpdateUserHandler(t *testing.T) {
 	}
 }
 
+func TestPatchUserHandler(t *testing.T) {
+	tests := []struct {
+		name                 string
+		requestContentType   string
+		requestBody          string
+		providerUser         internal.User
+		providerError        error
+		expectProviderCall   bool
+		expectedFormat       internal.PatchFormat
+		expectedResponseCode int
+		expectedResponseBody string
+	}{
+		{
+			name:               "Happycase merge patch",
+			requestContentType: "application/merge-patch+json",
+			requestBody:        `{"claims": {"role": "admin"}}`,
+			providerUser: internal.User{
+				EMail:    "test.test@test.test",
+				Password: "**********",
+				Claims:   map[string]interface{}{"role": "admin"},
+			},
+			expectProviderCall:   true,
+			expectedFormat:       internal.MergePatch,
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"email":"test.test@test.test","password":"**********","claims":{"role":"admin"}}`,
+		},
+		{
+			name:               "Happycase json patch",
+			requestContentType: "application/json-patch+json; charset=utf-8",
+			requestBody:        `[{"op": "add", "path": "/claims/role", "value": "admin"}]`,
+			providerUser: internal.User{
+				EMail:    "test.test@test.test",
+				Password: "**********",
+				Claims:   map[string]interface{}{"role": "admin"},
+			},
+			expectProviderCall:   true,
+			expectedFormat:       internal.JSONPatch,
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"email":"test.test@test.test","password":"**********","claims":{"role":"admin"}}`,
+		},
+		{
+			name:                 "Unsupported content type",
+			requestContentType:   "application/json",
+			requestBody:          `{"claims": {"role": "admin"}}`,
+			expectedResponseCode: http.StatusUnsupportedMediaType,
+			expectedResponseBody: `{"message":"content type must be application/merge-patch+json or application/json-patch+json"}`,
+		},
+		{
+			name:                 "Invalid patch",
+			requestContentType:   "application/json-patch+json",
+			requestBody:          `[{"op": "delete", "path": "/claims"}]`,
+			providerError:        fmt.Errorf("operation 0: %w: unknown op \"delete\"", internal.ErrInvalidPatch),
+			expectProviderCall:   true,
+			expectedFormat:       internal.JSONPatch,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"operation 0: invalid patch: unknown op \"delete\""}`,
+		},
+		{
+			name:                 "Patch not applicable",
+			requestContentType:   "application/json-patch+json",
+			requestBody:          `[{"op": "test", "path": "/claims/role", "value": "admin"}]`,
+			providerError:        fmt.Errorf("operation 0 (test): %w: test failed", internal.ErrPatchNotApplicable),
+			expectProviderCall:   true,
+			expectedFormat:       internal.JSONPatch,
+			expectedResponseCode: http.StatusConflict,
+			expectedResponseBody: `{"message":"operation 0 (test): patch not applicable: test failed"}`,
+		},
+		{
+			name:                 "Invalid patched user",
+			requestContentType:   "application/merge-patch+json",
+			requestBody:          `{"email": "new@test.test"}`,
+			providerError:        fmt.Errorf("%w: json: unknown field \"email\"", internal.ErrInvalidPatchedUser),
+			expectProviderCall:   true,
+			expectedFormat:       internal.MergePatch,
+			expectedResponseCode: http.StatusUnprocessableEntity,
+			expectedResponseBody: `{"message":"patched user is invalid: json: unknown field \"email\""}`,
+		},
+		{
+			name:                 "User not found",
+			requestContentType:   "application/merge-patch+json",
+			requestBody:          `{}`,
+			providerError:        internal.ErrUserNotFound,
+			expectProviderCall:   true,
+			expectedFormat:       internal.MergePatch,
+			expectedResponseCode: http.StatusNotFound,
+			expectedResponseBody: `{"message":"User with given email doesn't exists"}`,
+		},
+		{
+			name:                 "Unexpected error",
+			requestContentType:   "application/merge-patch+json",
+			requestBody:          `{}`,
+			providerError:        errors.New("nope"),
+			expectProviderCall:   true,
+			expectedFormat:       internal.MergePatch,
+			expectedResponseCode: http.StatusInternalServerError,
+			expectedResponseBody: `{"message":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var providerCalled bool
+			var givenFormat internal.PatchFormat
+			var givenPatch []byte
+
+			toTest := NewServer(&ProviderMock{
+				PatchUserFunc: func(idOrIdentifier string, format internal.PatchFormat, patch []byte) (internal.User, error) {
+					providerCalled = true
+					givenFormat = format
+					givenPatch = patch
+
+					return tt.providerUser, tt.providerError
+				},
+			}, true, "username", "password")
+			testServer := httptest.NewServer(toTest.h)
+
+			bb := bytes.NewReader([]byte(tt.requestBody))
+			req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("%s/v1/admin/users/test.test@test.test", testServer.URL), bb)
+			if err != nil {
+				t.Fatalf("Failed to build http request: %s", err)
+			}
+			req.SetBasicAuth("username", "password")
+			req.Header.Set("Content-Type", tt.requestContentType)
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to call server cause: %s", err)
+			}
+			defer resp.Body.Close()
+
+			if providerCalled != tt.expectProviderCall {
+				t.Fatalf("Provider call is not as expected. Expected: %t, Given: %t", tt.expectProviderCall, providerCalled)
+			}
+
+			if providerCalled && (givenFormat != tt.expectedFormat || string(givenPatch) != tt.requestBody) {
+				t.Errorf("Provider called with unexpected patch. Given: %d %q", givenFormat, givenPatch)
+			}
+
+			if resp.StatusCode != tt.expectedResponseCode {
+				t.Errorf("Request respond with unexpected status code. Expected: %d, Given: %d", tt.expectedResponseCode, resp.StatusCode)
+			}
+
+			respBody, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("Failed to read response body: %s", err)
+			}
+
+			compactedRespBody := &bytes.Buffer{}
+			err = json.Compact(compactedRespBody, respBody)
+			if err != nil {
+				t.Fatalf("Failed to compact json: %s", err)
+			}
+
+			if compactedRespBody.String() != tt.expectedResponseBody {
+				t.Errorf("Request response body is not as expected. Expected: \n%q\nGiven:\n%q", tt.expectedResponseBody, compactedRespBody.String())
+			}
+		})
+	}
+}
+
 func TestDeleteUserHandler(t *testing.T) {
 	tests := []struct {
 		name                 string
@@ -461,3 +814,229 @@ func TestDeleteUserHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestResetMFAHandler(t *testing.T) {
+	tests := []struct {
+		name                 string
+		providerError        error
+		requestEmail         string
+		expectedEncodedEmail string
+		expectedResponseBody string
+		expectedResponseCode int
+	}{
+		{
+			name:                 "Happycase",
+			requestEmail:         "info%40leberkleber.io",
+			expectedEncodedEmail: "info@leberkleber.io",
+			expectedResponseCode: http.StatusNoContent,
+		},
+		{
+			name:                 "User not found",
+			requestEmail:         "info%40leberkleber.io",
+			providerError:        internal.ErrUserNotFound,
+			expectedEncodedEmail: "info@leberkleber.io",
+			expectedResponseCode: http.StatusNotFound,
+			expectedResponseBody: `{"message":"User with given email doesnt already exists"}`,
+		},
+		{
+			name:                 "Error while reset",
+			requestEmail:         "info%40leberkleber.io",
+			providerError:        errors.New("nope"),
+			expectedEncodedEmail: "info@leberkleber.io",
+			expectedResponseCode: http.StatusInternalServerError,
+			expectedResponseBody: `{"message":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenEMail string
+
+			toTest := NewServer(&ProviderMock{
+				ResetMFAFunc: func(email string) error {
+					givenEMail = email
+					return tt.providerError
+				},
+			}, true, "username", "password")
+			testServer := httptest.NewServer(toTest.h)
+
+			req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/v1/admin/users/%s/mfa", testServer.URL, tt.requestEmail), nil)
+			if err != nil {
+				t.Fatalf("Failed to build http request: %s", err)
+			}
+			req.SetBasicAuth("username", "password")
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to call server cause: %s", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.expectedResponseCode {
+				t.Errorf("Request respond with unexpected status code. Expected: %d, Given: %d", tt.expectedResponseCode, resp.StatusCode)
+			}
+
+			respBody, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("Failed to read response body: %s", err)
+			}
+			var compactedRespBodyAsBytes []byte
+			if resp.ContentLength > 0 {
+				compactedRespBody := &bytes.Buffer{}
+				err = json.Compact(compactedRespBody, respBody)
+				if err != nil {
+					t.Fatalf("Failed to compact json: %s", err)
+				}
+
+				compactedRespBodyAsBytes = compactedRespBody.Bytes()
+			}
+
+			if tt.expectedEncodedEmail != givenEMail {
+				t.Errorf("Unexpected reset email. Expected: %q, Given: %q", tt.expectedEncodedEmail, givenEMail)
+			}
+
+			if !bytes.Equal(compactedRespBodyAsBytes, []byte(tt.expectedResponseBody)) {
+				t.Errorf("Request response body is not as expected. Expected: %q, Given: %q", tt.expectedResponseBody, string(compactedRespBodyAsBytes))
+			}
+		})
+	}
+}
+
+func TestSearchUsersHandler(t *testing.T) {
+	tests := []struct {
+		name                 string
+		requestQuery         string
+		requestBody          string
+		providerError        error
+		expectedPredicates   []internal.ClaimPredicate
+		expectedLimit        int
+		expectedOffset       int
+		expectedResponseCode int
+		expectedResponseBody string
+	}{
+		{
+			name:         "Happycase",
+			requestQuery: "?limit=10&offset=20",
+			requestBody:  `{"claims":[{"claim":"roles","operator":"contains","value":["admin"]},{"claim":"level","operator":"eq","value":4711},{"claim":"beta","operator":"exists"}]}`,
+			expectedPredicates: []internal.ClaimPredicate{
+				{Claim: "roles", Operator: internal.ClaimOperatorContains, Value: []interface{}{"admin"}},
+				{Claim: "level", Operator: internal.ClaimOperatorEquals, Value: float64(4711)},
+				{Claim: "beta", Operator: internal.ClaimOperatorExists},
+			},
+			expectedLimit:        10,
+			expectedOffset:       20,
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"users":[{"id":"c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b","email":"info@leberkleber.io","password":"**********","claims":{"roles":["admin"]}}],"total":21,"limit":10,"offset":20}`,
+		},
+		{
+			name:                 "Default paging without predicates",
+			requestBody:          `{}`,
+			expectedLimit:        50,
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"users":[{"id":"c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b","email":"info@leberkleber.io","password":"**********","claims":{"roles":["admin"]}}],"total":21,"limit":50,"offset":0}`,
+		},
+		{
+			name:                 "Invalid limit",
+			requestQuery:         "?limit=0",
+			requestBody:          `{}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"limit must be between 1 and 500"}`,
+		},
+		{
+			name:                 "Negative offset",
+			requestQuery:         "?offset=-1",
+			requestBody:          `{}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"offset must not be negative"}`,
+		},
+		{
+			name:                 "Invalid json",
+			requestBody:          `{"claims": 1}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid JSON"}`,
+		},
+		{
+			name:                 "Invalid predicate",
+			requestBody:          `{"claims":[{"claim":"roles","operator":"like","value":"admin"}]}`,
+			providerError:        fmt.Errorf(`%w: unknown claim operator "like"`, internal.ErrInvalidClaimPredicate),
+			expectedPredicates:   []internal.ClaimPredicate{{Claim: "roles", Operator: "like", Value: "admin"}},
+			expectedLimit:        50,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid claim predicate: unknown claim operator \"like\""}`,
+		},
+		{
+			name:                 "Unexpected error",
+			requestBody:          `{}`,
+			providerError:        errors.New("nope"),
+			expectedLimit:        50,
+			expectedResponseCode: http.StatusInternalServerError,
+			expectedResponseBody: `{"message":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenPredicates []internal.ClaimPredicate
+			var givenLimit, givenOffset int
+
+			toTest := NewServer(&ProviderMock{
+				FindUsersFunc: func(predicates []internal.ClaimPredicate, limit int, offset int) (internal.UserPage, error) {
+					givenPredicates, givenLimit, givenOffset = predicates, limit, offset
+					return internal.UserPage{
+						Users: []internal.User{{
+							ID:       "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+							EMail:    "info@leberkleber.io",
+							Password: "**********",
+							Claims:   map[string]interface{}{"roles": []interface{}{"admin"}},
+						}},
+						Total: 21,
+					}, tt.providerError
+				},
+			}, true, "username", "password")
+			testServer := httptest.NewServer(toTest.h)
+
+			req, err := http.NewRequest(http.MethodPost, testServer.URL+"/v1/admin/users/search"+tt.requestQuery, bytes.NewBufferString(tt.requestBody))
+			if err != nil {
+				t.Fatalf("Failed to build http request: %s", err)
+			}
+			req.SetBasicAuth("username", "password")
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to call server cause: %s", err)
+			}
+			defer resp.Body.Close()
+
+			if !reflect.DeepEqual(givenPredicates, tt.expectedPredicates) || givenLimit != tt.expectedLimit || givenOffset != tt.expectedOffset {
+				t.Errorf("Provider called with unexpected arguments. Predicates: %#v, Limit: %d, Offset: %d", givenPredicates, givenLimit, givenOffset)
+			}
+
+			if resp.StatusCode != tt.expectedResponseCode {
+				t.Errorf("Request respond with unexpected status code. Expected: %d, Given: %d", tt.expectedResponseCode, resp.StatusCode)
+			}
+
+			respBody, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("Failed to read response body: %s", err)
+			}
+
+			compactedRespBody := &bytes.Buffer{}
+			err = json.Compact(compactedRespBody, respBody)
+			if err != nil {
+				t.Fatalf("Failed to compact json: %s", err)
+			}
+
+			if compactedRespBody.String() != tt.expectedResponseBody {
+				t.Errorf("Request response body is not as expected. Expected: %q, Given: %q", tt.expectedResponseBody, compactedRespBody.String())
+			}
+		})
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
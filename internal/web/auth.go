@@ -10,7 +10,7 @@ import (
 
 func (s *Server) loginHandler(w http.ResponseWriter, r *http.Request) {
 	requestBody := struct {
-		EMail    string `json:"email"`
+		loginIdentifier
 		Password string `json:"password"`
 	}{}
 
@@ -20,8 +20,8 @@ func (s *Server) loginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if requestBody.EMail == "" {
-		writeError(w, http.StatusBadRequest, "email must be set")
+	if requestBody.identifier() == "" {
+		writeError(w, http.StatusBadRequest, "identifier must be set")
 		return
 	}
 
@@ -30,13 +30,21 @@ func (s *Server) loginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	jwt, err := s.p.Login(requestBody.EMail, requestBody.Password)
+	result, err := s.p.Login(requestBody.identifier(), requestBody.Password, client(r))
 	if err != nil {
+		if errors.Is(err, internal.ErrInvalidEMail) {
+			writeError(w, http.StatusBadRequest, "invalid email")
+			return
+		}
 		if errors.Is(err, internal.ErrIncorrectPassword) || errors.Is(err, internal.ErrUserNotFound) {
-			logrus.WithField("email", requestBody.EMail).Warn("somebody tried to login with invalid credentials")
+			logrus.WithField("identifier", requestBody.identifier()).Warn("somebody tried to login with invalid credentials")
 			writeError(w, http.StatusUnauthorized, "invalid credentials")
 			return
 		}
+		if errors.Is(err, internal.ErrPasswordExpired) {
+			writeError(w, http.StatusForbidden, "password expired")
+			return
+		}
 
 		logrus.WithError(err).Error("Failed to login User")
 		writeInternalServerError(w)
@@ -44,9 +52,13 @@ func (s *Server) loginHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	err = json.NewEncoder(w).Encode(struct {
-		AccessToken string `json:"access_token"`
+		AccessToken string   `json:"access_token,omitempty"`
+		MFAToken    string   `json:"mfa_token,omitempty"`
+		MFAMethods  []string `json:"mfa_methods,omitempty"`
 	}{
-		AccessToken: jwt,
+		AccessToken: result.AccessToken,
+		MFAToken:    result.MFAToken,
+		MFAMethods:  result.MFAMethods,
 	})
 	if err != nil {
 		logrus.WithError(err).Error("Failed marshal request response")
@@ -73,6 +85,10 @@ func (s *Server) passwordResetRequestHandler(w http.ResponseWriter, r *http.Requ
 
 	err = s.p.CreatePasswordResetRequest(requestBody.EMail)
 	if err != nil {
+		if errors.Is(err, internal.ErrInvalidEMail) {
+			writeError(w, http.StatusBadRequest, "invalid email")
+			return
+		}
 		if errors.Is(err, internal.ErrUserNotFound) {
 			logrus.WithField("email", requestBody.EMail).Warn("somebody tried to create a reset-password-request for non existing User")
 			w.WriteHeader(http.StatusCreated)
@@ -118,10 +134,26 @@ func (s *Server) passwordResetHandler(w http.ResponseWriter, r *http.Request) {
 
 	err = s.p.ResetPassword(requestBody.EMail, requestBody.ResetToken, requestBody.Password)
 	if err != nil {
+		if errors.Is(err, internal.ErrInvalidEMail) {
+			writeError(w, http.StatusBadRequest, "invalid email")
+			return
+		}
+		if errors.Is(err, internal.ErrTokenExpired) {
+			writeError(w, http.StatusGone, "reset-token has expired")
+			return
+		}
 		if errors.Is(err, internal.ErrNoValidTokenFound) {
 			writeError(w, http.StatusBadRequest, "reset-token is invalid or token email combination is not correct")
 			return
 		}
+		if errors.Is(err, internal.ErrPasswordBreached) {
+			writeError(w, http.StatusBadRequest, "password has been found in a data breach")
+			return
+		}
+		if errors.Is(err, internal.ErrPasswordReused) {
+			writeError(w, http.StatusBadRequest, "password has been used recently")
+			return
+		}
 		logrus.WithError(err).Error("Failed to create password-reset-request")
 		writeInternalServerError(w)
 		return
@@ -129,3 +161,59 @@ func (s *Server) passwordResetHandler(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func (s *Server) passwordChangeHandler(w http.ResponseWriter, r *http.Request) {
+	requestBody := struct {
+		loginIdentifier
+		Password    string `json:"password"`
+		NewPassword string `json:"new_password"`
+	}{}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	if requestBody.identifier() == "" {
+		writeError(w, http.StatusBadRequest, "identifier must be set")
+		return
+	}
+
+	if requestBody.Password == "" {
+		writeError(w, http.StatusBadRequest, "password must be set")
+		return
+	}
+
+	if requestBody.NewPassword == "" {
+		writeError(w, http.StatusBadRequest, "new-password must be set")
+		return
+	}
+
+	err = s.p.ChangePassword(requestBody.identifier(), requestBody.Password, requestBody.NewPassword)
+	if err != nil {
+		if errors.Is(err, internal.ErrInvalidEMail) {
+			writeError(w, http.StatusBadRequest, "invalid email")
+			return
+		}
+		if errors.Is(err, internal.ErrIncorrectPassword) || errors.Is(err, internal.ErrUserNotFound) {
+			logrus.WithField("identifier", requestBody.identifier()).Warn("somebody tried to change password with invalid credentials")
+			writeError(w, http.StatusUnauthorized, "invalid credentials")
+			return
+		}
+		if errors.Is(err, internal.ErrPasswordBreached) {
+			writeError(w, http.StatusBadRequest, "password has been found in a data breach")
+			return
+		}
+		if errors.Is(err, internal.ErrPasswordReused) {
+			writeError(w, http.StatusBadRequest, "password has been used recently")
+			return
+		}
+
+		logrus.WithError(err).Error("Failed to change password")
+		writeInternalServerError(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -16,6 +16,8 @@ func TestLoginHandler(t *testing.T) {
 		name                 string
 		requestBody          string
 		providerToken        string
+		providerMFAToken     string
+		providerMFAMethods   []string
 		providerError        error
 		expectedEMail        string
 		expectedPassword     string
@@ -31,6 +33,34 @@ func TestLoginHandler(t *testing.T) {
 			expectedResponseCode: http.StatusOK,
 			expectedResponseBody: `{"access_token":"myNewJWT"}`,
 		},
+		{
+			name:                 "Happycase with username",
+			requestBody:          `{"identifier": "alice", "password": "s3cr3t"}`,
+			expectedEMail:        "alice",
+			expectedPassword:     "s3cr3t",
+			providerToken:        "myNewJWT",
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"access_token":"myNewJWT"}`,
+		},
+		{
+			name:                 "Identifier takes precedence over email",
+			requestBody:          `{"email": "test.test@test.test", "identifier": "+491701234567", "password": "s3cr3t"}`,
+			expectedEMail:        "+491701234567",
+			expectedPassword:     "s3cr3t",
+			providerToken:        "myNewJWT",
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"access_token":"myNewJWT"}`,
+		},
+		{
+			name:                 "MFA required",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t"}`,
+			expectedEMail:        "test.test@test.test",
+			expectedPassword:     "s3cr3t",
+			providerMFAToken:     "myMFAToken",
+			providerMFAMethods:   []string{"totp", "webauthn"},
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"mfa_token":"myMFAToken","mfa_methods":["totp","webauthn"]}`,
+		},
 		{
 			name:                 "Invalid JSON",
 			requestBody:          `{"password s3cr3t"}`,
@@ -39,11 +69,11 @@ func TestLoginHandler(t *testing.T) {
 			expectedResponseBody: `{"message":"invalid JSON"}`,
 		},
 		{
-			name:                 "Missing Recipient",
+			name:                 "Missing identifier",
 			requestBody:          `{"password": "s3cr3t"}`,
 			providerToken:        "myNewJWT",
 			expectedResponseCode: http.StatusBadRequest,
-			expectedResponseBody: `{"message":"email must be set"}`,
+			expectedResponseBody: `{"message":"identifier must be set"}`,
 		},
 		{
 			name:                 "Missing Password",
@@ -52,6 +82,15 @@ func TestLoginHandler(t *testing.T) {
 			expectedResponseCode: http.StatusBadRequest,
 			expectedResponseBody: `{"message":"password must be set"}`,
 		},
+		{
+			name:                 "Invalid email",
+			requestBody:          `{"email": "test.test", "password": "s3cr3t"}`,
+			providerError:        internal.ErrInvalidEMail,
+			expectedEMail:        "test.test",
+			expectedPassword:     "s3cr3t",
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid email"}`,
+		},
 		{
 			name:                 "Incorrect Password",
 			requestBody:          `{"email": "test.test@test.test", "password": "n0p3"}`,
@@ -71,6 +110,15 @@ func TestLoginHandler(t *testing.T) {
 			expectedResponseCode: http.StatusUnauthorized,
 			expectedResponseBody: `{"message":"invalid credentials"}`,
 		},
+		{
+			name:                 "Password expired",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t"}`,
+			providerError:        internal.ErrPasswordExpired,
+			expectedEMail:        "test.test@test.test",
+			expectedPassword:     "s3cr3t",
+			expectedResponseCode: http.StatusForbidden,
+			expectedResponseBody: `{"message":"password expired"}`,
+		},
 		{
 			name:                 "Unexpected error",
 			requestBody:          `{"email": "not.found@test.test", "password": "s3cr3t"}`,
@@ -85,13 +133,15 @@ func TestLoginHandler(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			var givenEMail, givenPassword string
+			var givenClient internal.Client
 
 			toTest := NewServer(&ProviderMock{
-				LoginFunc: func(email string, password string) (string, error) {
+				LoginFunc: func(email string, password string, client internal.Client) (internal.LoginResult, error) {
 					givenEMail = email
 					givenPassword = password
+					givenClient = client
 
-					return tt.providerToken, tt.providerError
+					return internal.LoginResult{AccessToken: tt.providerToken, MFAToken: tt.providerMFAToken, MFAMethods: tt.providerMFAMethods}, tt.providerError
 				},
 			}, false, "", "")
 			testServer := httptest.NewServer(toTest.h)
@@ -101,6 +151,7 @@ func TestLoginHandler(t *testing.T) {
 			if err != nil {
 				t.Fatalf("Failed to build http request: %s", err)
 			}
+			req.Header.Set("User-Agent", "curl/7.68.0")
 
 			resp, err := http.DefaultClient.Do(req)
 			if err != nil {
@@ -125,6 +176,10 @@ func TestLoginHandler(t *testing.T) {
 				t.Errorf("Provider called with unexpected password. Given: %q, Expected: %q", givenPassword, tt.expectedPassword)
 			}
 
+			if givenEMail != "" && givenClient != (internal.Client{IP: "127.0.0.1", UserAgent: "curl/7.68.0"}) {
+				t.Errorf("Provider called with unexpected client. Given: %#v", givenClient)
+			}
+
 			var compactedRespBodyAsBytes []byte
 			if resp.ContentLength > 0 {
 				compactedRespBody := &bytes.Buffer{}
@@ -295,6 +350,36 @@ func TestPasswordResetHandler(t *testing.T) {
 			expectedResponseCode: http.StatusBadRequest,
 			expectedResponseBody: `{"message":"reset-token is invalid or token email combination is not correct"}`,
 		},
+		{
+			name:                 "Expired token",
+			requestBody:          `{"email":"test.test@test.test","password": "new_s3cr3t","reset_token": "expiredResetToken"}`,
+			providerError:        internal.ErrTokenExpired,
+			expectedEMail:        "test.test@test.test",
+			expectedPassword:     "new_s3cr3t",
+			expectedResetToken:   "expiredResetToken",
+			expectedResponseCode: http.StatusGone,
+			expectedResponseBody: `{"message":"reset-token has expired"}`,
+		},
+		{
+			name:                 "Breached password",
+			requestBody:          `{"email":"test.test@test.test","password": "password","reset_token": "myResetToken"}`,
+			providerError:        internal.ErrPasswordBreached,
+			expectedEMail:        "test.test@test.test",
+			expectedPassword:     "password",
+			expectedResetToken:   "myResetToken",
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"password has been found in a data breach"}`,
+		},
+		{
+			name:                 "Reused password",
+			requestBody:          `{"email":"test.test@test.test","password": "old_s3cr3t","reset_token": "myResetToken"}`,
+			providerError:        internal.ErrPasswordReused,
+			expectedEMail:        "test.test@test.test",
+			expectedPassword:     "old_s3cr3t",
+			expectedResetToken:   "myResetToken",
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"password has been used recently"}`,
+		},
 		{
 			name:                 "Unexpected error",
 			requestBody:          `{"email":"test.test@test.test","password": "new_s3cr3t","reset_token": "myResetToken"}`,
@@ -371,3 +456,153 @@ func TestPasswordResetHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestPasswordChangeHandler(t *testing.T) {
+	tests := []struct {
+		name                 string
+		requestBody          string
+		providerError        error
+		expectedEMail        string
+		expectedPassword     string
+		expectedNewPassword  string
+		expectedResponseCode int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "Happycase",
+			requestBody:          `{"email":"test.test@test.test","password": "s3cr3t","new_password": "new_s3cr3t"}`,
+			expectedEMail:        "test.test@test.test",
+			expectedPassword:     "s3cr3t",
+			expectedNewPassword:  "new_s3cr3t",
+			expectedResponseCode: http.StatusNoContent,
+		},
+		{
+			name:                 "Invalid JSON",
+			requestBody:          `{"email test.test@test.test}"`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid JSON"}`,
+		},
+		{
+			name:                 "Missing identifier",
+			requestBody:          `{"password": "s3cr3t","new_password": "new_s3cr3t"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"identifier must be set"}`,
+		},
+		{
+			name:                 "Missing password",
+			requestBody:          `{"email":"test.test@test.test","new_password": "new_s3cr3t"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"password must be set"}`,
+		},
+		{
+			name:                 "Missing new-password",
+			requestBody:          `{"email":"test.test@test.test","password": "s3cr3t"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"new-password must be set"}`,
+		},
+		{
+			name:                 "Incorrect password",
+			requestBody:          `{"email":"test.test@test.test","password": "n0p3","new_password": "new_s3cr3t"}`,
+			providerError:        internal.ErrIncorrectPassword,
+			expectedEMail:        "test.test@test.test",
+			expectedPassword:     "n0p3",
+			expectedNewPassword:  "new_s3cr3t",
+			expectedResponseCode: http.StatusUnauthorized,
+			expectedResponseBody: `{"message":"invalid credentials"}`,
+		},
+		{
+			name:                 "Breached password",
+			requestBody:          `{"email":"test.test@test.test","password": "s3cr3t","new_password": "password"}`,
+			providerError:        internal.ErrPasswordBreached,
+			expectedEMail:        "test.test@test.test",
+			expectedPassword:     "s3cr3t",
+			expectedNewPassword:  "password",
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"password has been found in a data breach"}`,
+		},
+		{
+			name:                 "Reused password",
+			requestBody:          `{"email":"test.test@test.test","password": "s3cr3t","new_password": "s3cr3t"}`,
+			providerError:        internal.ErrPasswordReused,
+			expectedEMail:        "test.test@test.test",
+			expectedPassword:     "s3cr3t",
+			expectedNewPassword:  "s3cr3t",
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"password has been used recently"}`,
+		},
+		{
+			name:                 "Unexpected error",
+			requestBody:          `{"email":"test.test@test.test","password": "s3cr3t","new_password": "new_s3cr3t"}`,
+			providerError:        errors.New("computer says nooooo"),
+			expectedEMail:        "test.test@test.test",
+			expectedPassword:     "s3cr3t",
+			expectedNewPassword:  "new_s3cr3t",
+			expectedResponseCode: http.StatusInternalServerError,
+			expectedResponseBody: `{"message":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenEMail, givenPassword, givenNewPassword string
+
+			toTest := NewServer(&ProviderMock{
+				ChangePasswordFunc: func(email string, password string, newPassword string) error {
+					givenEMail = email
+					givenPassword = password
+					givenNewPassword = newPassword
+					return tt.providerError
+				},
+			}, false, "", "")
+			testServer := httptest.NewServer(toTest.h)
+
+			bb := bytes.NewReader([]byte(tt.requestBody))
+			req, err := http.NewRequest(http.MethodPost, testServer.URL+"/v1/auth/password-change", bb)
+			if err != nil {
+				t.Fatalf("Failed to build http request: %s", err)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to call server cause: %s", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.expectedResponseCode {
+				t.Errorf("Request respond with unexpected status code. Expected: %d, Given: %d", tt.expectedResponseCode, resp.StatusCode)
+			}
+
+			respBody, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("Failed to read response body: %s", err)
+			}
+
+			if givenEMail != tt.expectedEMail {
+				t.Errorf("Provider called with unexpected email. Given: %q, Expected: %q", givenEMail, tt.expectedEMail)
+			}
+
+			if givenPassword != tt.expectedPassword {
+				t.Errorf("Provider called with unexpected password. Given: %q, Expected: %q", givenPassword, tt.expectedPassword)
+			}
+
+			if givenNewPassword != tt.expectedNewPassword {
+				t.Errorf("Provider called with unexpected new password. Given: %q, Expected: %q", givenNewPassword, tt.expectedNewPassword)
+			}
+
+			var compactedRespBodyAsBytes []byte
+			if resp.ContentLength > 0 {
+				compactedRespBody := &bytes.Buffer{}
+				err = json.Compact(compactedRespBody, respBody)
+				if err != nil {
+					t.Fatalf("Failed to compact json: %s", err)
+				}
+
+				compactedRespBodyAsBytes = compactedRespBody.Bytes()
+			}
+
+			if !bytes.Equal(compactedRespBodyAsBytes, []byte(tt.expectedResponseBody)) {
+				t.Errorf("Request response body is not as expected. Expected: %q, Given: %q", tt.expectedResponseBody, string(compactedRespBodyAsBytes))
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package web
+
+// loginIdentifier is embedded in the request bodies of all endpoints which identify the user by any login identifier
+// (email, username or phone). 'identifier' takes precedence, 'email' is accepted for compatibility.
+type loginIdentifier struct {
+	EMail      string `json:"email"`
+	Identifier string `json:"identifier"`
+}
+
+// identifier returns the given login identifier
+func (l loginIdentifier) identifier() string {
+	if l.Identifier != "" {
+		return l.Identifier
+	}
+
+	return l.EMail
+}
@@ -0,0 +1,105 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/leberKleber/simple-jwt-provider/internal"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+)
+
+func (s *Server) acceptInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	requestBody := struct {
+		EMail    string `json:"email"`
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}{}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	if requestBody.EMail == "" {
+		writeError(w, http.StatusBadRequest, "email must be set")
+		return
+	}
+
+	if requestBody.Token == "" {
+		writeError(w, http.StatusBadRequest, "token must be set")
+		return
+	}
+
+	if requestBody.Password == "" {
+		writeError(w, http.StatusBadRequest, "password must be set")
+		return
+	}
+
+	err = s.p.AcceptInvitation(requestBody.EMail, requestBody.Token, requestBody.Password)
+	if err != nil {
+		if errors.Is(err, internal.ErrInvalidEMail) {
+			writeError(w, http.StatusBadRequest, "invalid email")
+			return
+		}
+		if errors.Is(err, internal.ErrNoValidTokenFound) {
+			logrus.WithField("email", requestBody.EMail).Warn("somebody tried to accept an invalid invitation")
+			writeError(w, http.StatusBadRequest, "token is invalid or token email combination is not correct")
+			return
+		}
+		if errors.Is(err, internal.ErrPasswordBreached) {
+			writeError(w, http.StatusBadRequest, "password has been found in a data breach")
+			return
+		}
+		if errors.Is(err, internal.ErrInvitationNotConfigured) {
+			writeError(w, http.StatusNotFound, "invitations are not configured")
+			return
+		}
+
+		logrus.WithError(err).Error("Failed to accept invitation")
+		writeInternalServerError(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) resendInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	idOrIdentifier, err := url.PathUnescape(mux.Vars(r)["user"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "could not unescape email")
+		return
+	}
+
+	err = s.p.ResendInvitation(idOrIdentifier)
+	if err != nil {
+		if errors.Is(err, internal.ErrInvalidEMail) {
+			writeError(w, http.StatusBadRequest, "invalid email")
+			return
+		}
+		if errors.Is(err, internal.ErrUserNotFound) {
+			writeError(w, http.StatusNotFound, "User with given email doesn't exists")
+			return
+		}
+		if errors.Is(err, internal.ErrInvitationNotConfigured) {
+			writeError(w, http.StatusNotFound, "invitations are not configured")
+			return
+		}
+		if errors.Is(err, internal.ErrInvitationAccepted) {
+			writeError(w, http.StatusConflict, "invitation has already been accepted")
+			return
+		}
+		if errors.Is(err, internal.ErrInvitationRequiresEMail) {
+			writeError(w, http.StatusBadRequest, "user has no email")
+			return
+		}
+
+		logrus.WithError(err).Error("Failed to resend invitation")
+		writeInternalServerError(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
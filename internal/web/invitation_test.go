@@ -0,0 +1,195 @@
+package web
+
+import (
+	"errors"
+	"github.com/leberKleber/simple-jwt-provider/internal"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAcceptInvitationHandler(t *testing.T) {
+	tests := []struct {
+		name                 string
+		requestBody          string
+		providerError        error
+		expectedEMail        string
+		expectedToken        string
+		expectedPassword     string
+		expectedResponseCode int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "Happycase",
+			requestBody:          `{"email": "test.test@test.test", "token": "abc", "password": "s3cr3t"}`,
+			expectedEMail:        "test.test@test.test",
+			expectedToken:        "abc",
+			expectedPassword:     "s3cr3t",
+			expectedResponseCode: http.StatusNoContent,
+		},
+		{
+			name:                 "Invalid JSON",
+			requestBody:          `{"email"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid JSON"}`,
+		},
+		{
+			name:                 "Missing email",
+			requestBody:          `{"token": "abc", "password": "s3cr3t"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"email must be set"}`,
+		},
+		{
+			name:                 "Missing token",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"token must be set"}`,
+		},
+		{
+			name:                 "Missing password",
+			requestBody:          `{"email": "test.test@test.test", "token": "abc"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"password must be set"}`,
+		},
+		{
+			name:                 "Invalid token",
+			requestBody:          `{"email": "test.test@test.test", "token": "abc", "password": "s3cr3t"}`,
+			providerError:        internal.ErrNoValidTokenFound,
+			expectedEMail:        "test.test@test.test",
+			expectedToken:        "abc",
+			expectedPassword:     "s3cr3t",
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"token is invalid or token email combination is not correct"}`,
+		},
+		{
+			name:                 "Breached password",
+			requestBody:          `{"email": "test.test@test.test", "token": "abc", "password": "password"}`,
+			providerError:        internal.ErrPasswordBreached,
+			expectedEMail:        "test.test@test.test",
+			expectedToken:        "abc",
+			expectedPassword:     "password",
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"password has been found in a data breach"}`,
+		},
+		{
+			name:                 "Not configured",
+			requestBody:          `{"email": "test.test@test.test", "token": "abc", "password": "s3cr3t"}`,
+			providerError:        internal.ErrInvitationNotConfigured,
+			expectedEMail:        "test.test@test.test",
+			expectedToken:        "abc",
+			expectedPassword:     "s3cr3t",
+			expectedResponseCode: http.StatusNotFound,
+			expectedResponseBody: `{"message":"invitations are not configured"}`,
+		},
+		{
+			name:                 "Unexpected error",
+			requestBody:          `{"email": "test.test@test.test", "token": "abc", "password": "s3cr3t"}`,
+			providerError:        errors.New("nope"),
+			expectedEMail:        "test.test@test.test",
+			expectedToken:        "abc",
+			expectedPassword:     "s3cr3t",
+			expectedResponseCode: http.StatusInternalServerError,
+			expectedResponseBody: `{"message":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenEMail, givenToken, givenPassword string
+			toTest := NewServer(&ProviderMock{
+				AcceptInvitationFunc: func(email, invitationToken, password string) error {
+					givenEMail, givenToken, givenPassword = email, invitationToken, password
+					return tt.providerError
+				},
+			}, false, "", "")
+
+			callMFAEndpoint(t, toTest, "/v1/auth/invitation/accept", tt.requestBody, tt.expectedResponseCode, tt.expectedResponseBody)
+
+			if givenEMail != tt.expectedEMail || givenToken != tt.expectedToken || givenPassword != tt.expectedPassword {
+				t.Errorf("Provider called with unexpected arguments. EMail: %q, Token: %q, Password: %q", givenEMail, givenToken, givenPassword)
+			}
+		})
+	}
+}
+
+func TestResendInvitationHandler(t *testing.T) {
+	tests := []struct {
+		name                 string
+		providerError        error
+		expectedResponseCode int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "Happycase",
+			expectedResponseCode: http.StatusCreated,
+		},
+		{
+			name:                 "User not found",
+			providerError:        internal.ErrUserNotFound,
+			expectedResponseCode: http.StatusNotFound,
+			expectedResponseBody: `{"message":"User with given email doesn't exists"}`,
+		},
+		{
+			name:                 "Not configured",
+			providerError:        internal.ErrInvitationNotConfigured,
+			expectedResponseCode: http.StatusNotFound,
+			expectedResponseBody: `{"message":"invitations are not configured"}`,
+		},
+		{
+			name:                 "Already accepted",
+			providerError:        internal.ErrInvitationAccepted,
+			expectedResponseCode: http.StatusConflict,
+			expectedResponseBody: `{"message":"invitation has already been accepted"}`,
+		},
+		{
+			name:                 "Unexpected error",
+			providerError:        errors.New("nope"),
+			expectedResponseCode: http.StatusInternalServerError,
+			expectedResponseBody: `{"message":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenUser string
+			toTest := NewServer(&ProviderMock{
+				ResendInvitationFunc: func(idOrIdentifier string) error {
+					givenUser = idOrIdentifier
+					return tt.providerError
+				},
+			}, true, "username", "password")
+			testServer := httptest.NewServer(toTest.h)
+			defer testServer.Close()
+
+			req, err := http.NewRequest(http.MethodPost, testServer.URL+"/v1/admin/users/info%40leberkleber.io/invitation", nil)
+			if err != nil {
+				t.Fatalf("Failed to build http request: %s", err)
+			}
+			req.SetBasicAuth("username", "password")
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to call server cause: %s", err)
+			}
+			defer resp.Body.Close()
+
+			if givenUser != "info@leberkleber.io" {
+				t.Errorf("Provider called with unexpected user. Expected: %q, Given: %q", "info@leberkleber.io", givenUser)
+			}
+
+			if resp.StatusCode != tt.expectedResponseCode {
+				t.Errorf("Request respond with unexpected status code. Expected: %d, Given: %d", tt.expectedResponseCode, resp.StatusCode)
+			}
+
+			respBody, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("Failed to read response body: %s", err)
+			}
+
+			if string(respBody) != tt.expectedResponseBody {
+				t.Errorf("Request response body is not as expected. Expected: %q, Given: %q", tt.expectedResponseBody, string(respBody))
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/leberKleber/simple-jwt-provider/internal/jobs"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+//go:generate moq -out jobs_moq_test.go . Jobs
+type Jobs interface {
+	Stats() []jobs.Stats
+	Run(name string) (jobs.Stats, error)
+}
+
+// Job is the representation of the metrics of a background job for use in web
+type Job struct {
+	Name           string     `json:"name"`
+	Runs           int        `json:"runs"`
+	Failures       int        `json:"failures"`
+	Skipped        int        `json:"skipped"`
+	LastRunAt      *time.Time `json:"last_run_at,omitempty"`
+	LastDurationMS int64      `json:"last_duration_ms"`
+	LastAffected   int64      `json:"last_affected"`
+	LastError      string     `json:"last_error,omitempty"`
+}
+
+// toWebJob converts the given jobs.Stats to a Job
+func toWebJob(s jobs.Stats) Job {
+	job := Job{
+		Name:           s.Name,
+		Runs:           s.Runs,
+		Failures:       s.Failures,
+		Skipped:        s.Skipped,
+		LastDurationMS: s.LastDuration.Milliseconds(),
+		LastAffected:   s.LastAffected,
+		LastError:      s.LastError,
+	}
+	if !s.LastRunAt.IsZero() {
+		lastRunAt := s.LastRunAt
+		job.LastRunAt = &lastRunAt
+	}
+
+	return job
+}
+
+func (s *Server) listJobsHandler(w http.ResponseWriter, _ *http.Request) {
+	stats := s.jobs.Stats()
+
+	webJobs := make([]Job, 0, len(stats))
+	for _, s := range stats {
+		webJobs = append(webJobs, toWebJob(s))
+	}
+
+	err := json.NewEncoder(w).Encode(webJobs)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to encode Jobs")
+		writeInternalServerError(w)
+		return
+	}
+}
+
+func (s *Server) runJobHandler(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.jobs.Run(mux.Vars(r)["job"])
+	if err != nil {
+		if errors.Is(err, jobs.ErrJobNotFound) {
+			writeError(w, http.StatusNotFound, "job not found")
+			return
+		}
+		if errors.Is(err, jobs.ErrJobLocked) {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+
+		// the failure is part of the job metrics
+		logrus.WithError(err).Error("Failed to run Job")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	err = json.NewEncoder(w).Encode(toWebJob(stats))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to encode Job")
+		writeInternalServerError(w)
+		return
+	}
+}
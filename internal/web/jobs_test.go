@@ -0,0 +1,148 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/jobs"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestListJobsHandler(t *testing.T) {
+	lastRunAt := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+
+	toTest := NewRealmServer(&ProviderMock{}, nil, &JobsMock{
+		StatsFunc: func() []jobs.Stats {
+			return []jobs.Stats{
+				{Name: "purge-tokens", Runs: 2, Failures: 1, Skipped: 3, LastRunAt: lastRunAt, LastDuration: 1500 * time.Millisecond, LastAffected: 4, LastError: "nope"},
+				{Name: "purge-login-history"},
+			}
+		},
+	}, true, "username", "password")
+	testServer := httptest.NewServer(toTest.h)
+
+	req, err := http.NewRequest(http.MethodGet, testServer.URL+"/v1/admin/jobs", nil)
+	if err != nil {
+		t.Fatalf("Failed to build http request: %s", err)
+	}
+	req.SetBasicAuth("username", "password")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to call server cause: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Request respond with unexpected status code. Expected: %d, Given: %d", http.StatusOK, resp.StatusCode)
+	}
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response body: %s", err)
+	}
+
+	compactedRespBody := &bytes.Buffer{}
+	err = json.Compact(compactedRespBody, respBody)
+	if err != nil {
+		t.Fatalf("Failed to compact json: %s", err)
+	}
+
+	expectedResponseBody := `[{"name":"purge-tokens","runs":2,"failures":1,"skipped":3,"last_run_at":"2020-02-01T04:46:45Z","last_duration_ms":1500,"last_affected":4,"last_error":"nope"},` +
+		`{"name":"purge-login-history","runs":0,"failures":0,"skipped":0,"last_duration_ms":0,"last_affected":0}]`
+	if compactedRespBody.String() != expectedResponseBody {
+		t.Errorf("Request response body is not as expected. Expected: %q, Given: %q", expectedResponseBody, compactedRespBody.String())
+	}
+}
+
+func TestRunJobHandler(t *testing.T) {
+	lastRunAt := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+
+	tests := []struct {
+		name                 string
+		jobsStats            jobs.Stats
+		jobsError            error
+		expectedResponseCode int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "Happycase",
+			jobsStats:            jobs.Stats{Name: "purge-tokens", Runs: 1, LastRunAt: lastRunAt, LastDuration: 20 * time.Millisecond, LastAffected: 4},
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"name":"purge-tokens","runs":1,"failures":0,"skipped":0,"last_run_at":"2020-02-01T04:46:45Z","last_duration_ms":20,"last_affected":4}`,
+		},
+		{
+			name:                 "Job not found",
+			jobsError:            jobs.ErrJobNotFound,
+			expectedResponseCode: http.StatusNotFound,
+			expectedResponseBody: `{"message":"job not found"}`,
+		},
+		{
+			name:                 "Job locked",
+			jobsStats:            jobs.Stats{Name: "purge-tokens", Skipped: 1},
+			jobsError:            jobs.ErrJobLocked,
+			expectedResponseCode: http.StatusConflict,
+			expectedResponseBody: `{"message":"job is running on another instance"}`,
+		},
+		{
+			name:                 "Job failed",
+			jobsStats:            jobs.Stats{Name: "purge-tokens", Runs: 1, Failures: 1, LastRunAt: lastRunAt, LastError: "nope"},
+			jobsError:            fmt.Errorf("job \"purge-tokens\" failed: %w", errors.New("nope")),
+			expectedResponseCode: http.StatusInternalServerError,
+			expectedResponseBody: `{"name":"purge-tokens","runs":1,"failures":1,"skipped":0,"last_run_at":"2020-02-01T04:46:45Z","last_duration_ms":0,"last_affected":0,"last_error":"nope"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenName string
+			toTest := NewRealmServer(&ProviderMock{}, nil, &JobsMock{
+				RunFunc: func(name string) (jobs.Stats, error) {
+					givenName = name
+					return tt.jobsStats, tt.jobsError
+				},
+			}, true, "username", "password")
+			testServer := httptest.NewServer(toTest.h)
+
+			req, err := http.NewRequest(http.MethodPost, testServer.URL+"/v1/admin/jobs/purge-tokens/run", nil)
+			if err != nil {
+				t.Fatalf("Failed to build http request: %s", err)
+			}
+			req.SetBasicAuth("username", "password")
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to call server cause: %s", err)
+			}
+			defer resp.Body.Close()
+
+			if givenName != "purge-tokens" {
+				t.Errorf("Jobs called with unexpected name. Expected: %q, Given: %q", "purge-tokens", givenName)
+			}
+
+			if resp.StatusCode != tt.expectedResponseCode {
+				t.Errorf("Request respond with unexpected status code. Expected: %d, Given: %d", tt.expectedResponseCode, resp.StatusCode)
+			}
+
+			respBody, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("Failed to read response body: %s", err)
+			}
+
+			compactedRespBody := &bytes.Buffer{}
+			err = json.Compact(compactedRespBody, respBody)
+			if err != nil {
+				t.Fatalf("Failed to compact json: %s", err)
+			}
+
+			if compactedRespBody.String() != tt.expectedResponseBody {
+				t.Errorf("Request response body is not as expected. Expected: %q, Given: %q", tt.expectedResponseBody, compactedRespBody.String())
+			}
+		})
+	}
+}
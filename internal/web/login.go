@@ -0,0 +1,113 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/leberKleber/simple-jwt-provider/internal"
+	"github.com/sirupsen/logrus"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultLoginsLimit = 50
+	maxLoginsLimit     = 500
+)
+
+// Login is the representation of a recorded login attempt for use in web
+type Login struct {
+	CreatedAt time.Time `json:"created_at"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Outcome   string    `json:"outcome"`
+	Factor    string    `json:"factor"`
+}
+
+// LoginHistory is a page of the login history of a user
+type LoginHistory struct {
+	Logins []Login `json:"logins"`
+	Total  int     `json:"total"`
+	Limit  int     `json:"limit"`
+	Offset int     `json:"offset"`
+}
+
+// client returns the client of the given request which will be recorded on login attempts. The ip is the one of the
+// direct peer, forwarded-for headers will not be trusted.
+func client(r *http.Request) internal.Client {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	return internal.Client{IP: ip, UserAgent: r.UserAgent()}
+}
+
+func (s *Server) loginsHandler(w http.ResponseWriter, r *http.Request) {
+	idOrIdentifier, err := url.PathUnescape(mux.Vars(r)["user"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "could not unescape email")
+		return
+	}
+
+	limit, ok := queryInt(r, "limit", defaultLoginsLimit)
+	if !ok || limit < 1 || limit > maxLoginsLimit {
+		writeError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxLoginsLimit))
+		return
+	}
+
+	offset, ok := queryInt(r, "offset", 0)
+	if !ok || offset < 0 {
+		writeError(w, http.StatusBadRequest, "offset must not be negative")
+		return
+	}
+
+	history, err := s.p.Logins(idOrIdentifier, limit, offset)
+	if err != nil {
+		if errors.Is(err, internal.ErrInvalidEMail) {
+			writeError(w, http.StatusBadRequest, "invalid email")
+			return
+		}
+		if errors.Is(err, internal.ErrUserNotFound) {
+			writeError(w, http.StatusNotFound, "User with given email doesn't exists")
+			return
+		}
+
+		logrus.WithError(err).Error("Failed to get logins of User")
+		writeInternalServerError(w)
+		return
+	}
+
+	response := LoginHistory{Logins: []Login{}, Total: history.Total, Limit: limit, Offset: offset}
+	for _, l := range history.Logins {
+		response.Logins = append(response.Logins, Login{
+			CreatedAt: l.CreatedAt,
+			IP:        l.IP,
+			UserAgent: l.UserAgent,
+			Outcome:   l.Outcome,
+			Factor:    l.Factor,
+		})
+	}
+
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to encode logins of User")
+		writeInternalServerError(w)
+		return
+	}
+}
+
+// queryInt returns the int value of the query parameter with the given name or the given default value when it is
+// not set. ok is false when the value is not an int.
+func queryInt(r *http.Request, name string, defaultValue int) (int, bool) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return defaultValue, true
+	}
+
+	i, err := strconv.Atoi(raw)
+	return i, err == nil
+}
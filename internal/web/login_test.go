@@ -0,0 +1,138 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/leberKleber/simple-jwt-provider/internal"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLoginsHandler(t *testing.T) {
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+
+	tests := []struct {
+		name                 string
+		requestQuery         string
+		providerError        error
+		expectedUser         string
+		expectedLimit        int
+		expectedOffset       int
+		expectedResponseCode int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "Happycase",
+			requestQuery:         "?limit=10&offset=20",
+			expectedUser:         "info@leberkleber.io",
+			expectedLimit:        10,
+			expectedOffset:       20,
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"logins":[{"created_at":"2020-02-01T04:46:45Z","ip":"127.0.0.1","user_agent":"curl/7.68.0","outcome":"failed","factor":"password"}],"total":21,"limit":10,"offset":20}`,
+		},
+		{
+			name:                 "Default paging",
+			expectedUser:         "info@leberkleber.io",
+			expectedLimit:        50,
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"logins":[{"created_at":"2020-02-01T04:46:45Z","ip":"127.0.0.1","user_agent":"curl/7.68.0","outcome":"failed","factor":"password"}],"total":21,"limit":50,"offset":0}`,
+		},
+		{
+			name:                 "Invalid limit",
+			requestQuery:         "?limit=abc",
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"limit must be between 1 and 500"}`,
+		},
+		{
+			name:                 "Limit too high",
+			requestQuery:         "?limit=501",
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"limit must be between 1 and 500"}`,
+		},
+		{
+			name:                 "Negative offset",
+			requestQuery:         "?offset=-1",
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"offset must not be negative"}`,
+		},
+		{
+			name:                 "User not found",
+			providerError:        internal.ErrUserNotFound,
+			expectedUser:         "info@leberkleber.io",
+			expectedLimit:        50,
+			expectedResponseCode: http.StatusNotFound,
+			expectedResponseBody: `{"message":"User with given email doesn't exists"}`,
+		},
+		{
+			name:                 "Unexpected error",
+			providerError:        errors.New("nope"),
+			expectedUser:         "info@leberkleber.io",
+			expectedLimit:        50,
+			expectedResponseCode: http.StatusInternalServerError,
+			expectedResponseBody: `{"message":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenUser string
+			var givenLimit, givenOffset int
+
+			toTest := NewServer(&ProviderMock{
+				LoginsFunc: func(idOrIdentifier string, limit int, offset int) (internal.LoginHistory, error) {
+					givenUser, givenLimit, givenOffset = idOrIdentifier, limit, offset
+					return internal.LoginHistory{
+						Logins: []internal.Login{{
+							CreatedAt: now,
+							IP:        "127.0.0.1",
+							UserAgent: "curl/7.68.0",
+							Outcome:   "failed",
+							Factor:    internal.LoginFactorPassword,
+						}},
+						Total: 21,
+					}, tt.providerError
+				},
+			}, true, "username", "password")
+			testServer := httptest.NewServer(toTest.h)
+
+			req, err := http.NewRequest(http.MethodGet, testServer.URL+"/v1/admin/users/info%40leberkleber.io/logins"+tt.requestQuery, nil)
+			if err != nil {
+				t.Fatalf("Failed to build http request: %s", err)
+			}
+			req.SetBasicAuth("username", "password")
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to call server cause: %s", err)
+			}
+			defer resp.Body.Close()
+
+			if givenUser != tt.expectedUser || givenLimit != tt.expectedLimit || givenOffset != tt.expectedOffset {
+				t.Errorf("Provider called with unexpected arguments. User: %q, Limit: %d, Offset: %d", givenUser, givenLimit, givenOffset)
+			}
+
+			if resp.StatusCode != tt.expectedResponseCode {
+				t.Errorf("Request respond with unexpected status code. Expected: %d, Given: %d", tt.expectedResponseCode, resp.StatusCode)
+			}
+
+			respBody, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("Failed to read response body: %s", err)
+			}
+
+			compactedRespBody := &bytes.Buffer{}
+			err = json.Compact(compactedRespBody, respBody)
+			if err != nil {
+				t.Fatalf("Failed to compact json: %s", err)
+			}
+
+			if compactedRespBody.String() != tt.expectedResponseBody {
+				t.Errorf("Request response body is not as expected. Expected: %q, Given: %q", tt.expectedResponseBody, compactedRespBody.String())
+			}
+		})
+	}
+}
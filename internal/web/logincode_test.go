@@ -0,0 +1,179 @@
+package web
+
+import (
+	"errors"
+	"github.com/leberKleber/simple-jwt-provider/internal"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestLoginCodeHandler(t *testing.T) {
+	tests := []struct {
+		name                 string
+		requestBody          string
+		providerError        error
+		expectedEMail        string
+		expectedResponseCode int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "Happycase",
+			requestBody:          `{"email": "test.test@test.test"}`,
+			expectedEMail:        "test.test@test.test",
+			expectedResponseCode: http.StatusCreated,
+		},
+		{
+			name:                 "Invalid JSON",
+			requestBody:          `{"email test.test@test.test}"`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid JSON"}`,
+		},
+		{
+			name:                 "Missing email",
+			requestBody:          `{}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"email must be set"}`,
+		},
+		{
+			name:                 "User not found",
+			requestBody:          `{"email": "test.test@test.test"}`,
+			providerError:        internal.ErrUserNotFound,
+			expectedEMail:        "test.test@test.test",
+			expectedResponseCode: http.StatusCreated,
+		},
+		{
+			name:                 "Login code not configured",
+			requestBody:          `{"email": "test.test@test.test"}`,
+			providerError:        internal.ErrLoginCodeNotConfigured,
+			expectedEMail:        "test.test@test.test",
+			expectedResponseCode: http.StatusNotFound,
+			expectedResponseBody: `{"message":"login code login is not configured"}`,
+		},
+		{
+			name:                 "Unexpected error",
+			requestBody:          `{"email": "test.test@test.test"}`,
+			providerError:        errors.New("nope"),
+			expectedEMail:        "test.test@test.test",
+			expectedResponseCode: http.StatusInternalServerError,
+			expectedResponseBody: `{"message":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenEMail string
+
+			toTest := NewServer(&ProviderMock{
+				CreateLoginCodeFunc: func(email string) error {
+					givenEMail = email
+					return tt.providerError
+				},
+			}, false, "", "")
+
+			callMFAEndpoint(t, toTest, "/v1/auth/login-code", tt.requestBody, tt.expectedResponseCode, tt.expectedResponseBody)
+
+			if givenEMail != tt.expectedEMail {
+				t.Errorf("Provider called with unexpected email. Given: %q, Expected: %q", givenEMail, tt.expectedEMail)
+			}
+		})
+	}
+}
+
+func TestRedeemLoginCodeHandler(t *testing.T) {
+	tests := []struct {
+		name                 string
+		requestBody          string
+		providerResult       internal.LoginResult
+		providerError        error
+		expectedArgs         []string
+		expectedResponseCode int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "Happycase",
+			requestBody:          `{"email": "test.test@test.test", "code": "123456"}`,
+			providerResult:       internal.LoginResult{AccessToken: "myNewJWT"},
+			expectedArgs:         []string{"test.test@test.test", "123456"},
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"access_token":"myNewJWT"}`,
+		},
+		{
+			name:                 "Happycase with mfa",
+			requestBody:          `{"email": "test.test@test.test", "code": "123456"}`,
+			providerResult:       internal.LoginResult{MFAToken: "myMFAToken", MFAMethods: []string{"totp"}},
+			expectedArgs:         []string{"test.test@test.test", "123456"},
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"mfa_token":"myMFAToken","mfa_methods":["totp"]}`,
+		},
+		{
+			name:                 "Invalid JSON",
+			requestBody:          `{"email test.test@test.test}"`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid JSON"}`,
+		},
+		{
+			name:                 "Missing email",
+			requestBody:          `{"code": "123456"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"email must be set"}`,
+		},
+		{
+			name:                 "Missing code",
+			requestBody:          `{"email": "test.test@test.test"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"code must be set"}`,
+		},
+		{
+			name:                 "No valid code",
+			requestBody:          `{"email": "test.test@test.test", "code": "123456"}`,
+			providerError:        internal.ErrNoValidTokenFound,
+			expectedArgs:         []string{"test.test@test.test", "123456"},
+			expectedResponseCode: http.StatusUnauthorized,
+			expectedResponseBody: `{"message":"invalid credentials"}`,
+		},
+		{
+			name:                 "Invalid code",
+			requestBody:          `{"email": "test.test@test.test", "code": "123456"}`,
+			providerError:        internal.ErrInvalidLoginCode,
+			expectedArgs:         []string{"test.test@test.test", "123456"},
+			expectedResponseCode: http.StatusUnauthorized,
+			expectedResponseBody: `{"message":"invalid credentials"}`,
+		},
+		{
+			name:                 "Login code not configured",
+			requestBody:          `{"email": "test.test@test.test", "code": "123456"}`,
+			providerError:        internal.ErrLoginCodeNotConfigured,
+			expectedArgs:         []string{"test.test@test.test", "123456"},
+			expectedResponseCode: http.StatusNotFound,
+			expectedResponseBody: `{"message":"login code login is not configured"}`,
+		},
+		{
+			name:                 "Unexpected error",
+			requestBody:          `{"email": "test.test@test.test", "code": "123456"}`,
+			providerError:        errors.New("nope"),
+			expectedArgs:         []string{"test.test@test.test", "123456"},
+			expectedResponseCode: http.StatusInternalServerError,
+			expectedResponseBody: `{"message":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenArgs []string
+
+			toTest := NewServer(&ProviderMock{
+				LoginWithCodeFunc: func(email string, code string, client internal.Client) (internal.LoginResult, error) {
+					givenArgs = []string{email, code}
+					return tt.providerResult, tt.providerError
+				},
+			}, false, "", "")
+
+			callMFAEndpoint(t, toTest, "/v1/auth/login-code/redeem", tt.requestBody, tt.expectedResponseCode, tt.expectedResponseBody)
+
+			if !reflect.DeepEqual(givenArgs, tt.expectedArgs) {
+				t.Errorf("Provider called with unexpected args. Given: %q, Expected: %q", givenArgs, tt.expectedArgs)
+			}
+		})
+	}
+}
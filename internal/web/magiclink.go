@@ -0,0 +1,108 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/leberKleber/simple-jwt-provider/internal"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+func (s *Server) magicLinkHandler(w http.ResponseWriter, r *http.Request) {
+	requestBody := struct {
+		EMail string `json:"email"`
+	}{}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	if requestBody.EMail == "" {
+		writeError(w, http.StatusBadRequest, "email must be set")
+		return
+	}
+
+	err = s.p.CreateMagicLink(requestBody.EMail)
+	if err != nil {
+		if errors.Is(err, internal.ErrInvalidEMail) {
+			writeError(w, http.StatusBadRequest, "invalid email")
+			return
+		}
+		if errors.Is(err, internal.ErrUserNotFound) {
+			logrus.WithField("email", requestBody.EMail).Warn("somebody tried to create a magic link for non existing User")
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		if errors.Is(err, internal.ErrMagicLinkNotConfigured) {
+			writeError(w, http.StatusNotFound, "magic link login is not configured")
+			return
+		}
+
+		logrus.WithError(err).Error("Failed to create magic link")
+		writeInternalServerError(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) redeemMagicLinkHandler(w http.ResponseWriter, r *http.Request) {
+	requestBody := struct {
+		EMail string `json:"email"`
+		Token string `json:"token"`
+	}{}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	if requestBody.EMail == "" {
+		writeError(w, http.StatusBadRequest, "email must be set")
+		return
+	}
+
+	if requestBody.Token == "" {
+		writeError(w, http.StatusBadRequest, "token must be set")
+		return
+	}
+
+	result, err := s.p.LoginMagicLink(requestBody.EMail, requestBody.Token, client(r))
+	if err != nil {
+		if errors.Is(err, internal.ErrInvalidEMail) {
+			writeError(w, http.StatusBadRequest, "invalid email")
+			return
+		}
+		if errors.Is(err, internal.ErrNoValidTokenFound) {
+			logrus.WithField("email", requestBody.EMail).Warn("somebody tried to login with an invalid magic link")
+			writeError(w, http.StatusUnauthorized, "invalid credentials")
+			return
+		}
+		if errors.Is(err, internal.ErrMagicLinkNotConfigured) {
+			writeError(w, http.StatusNotFound, "magic link login is not configured")
+			return
+		}
+
+		logrus.WithError(err).Error("Failed to login User with magic link")
+		writeInternalServerError(w)
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(struct {
+		AccessToken string   `json:"access_token,omitempty"`
+		MFAToken    string   `json:"mfa_token,omitempty"`
+		MFAMethods  []string `json:"mfa_methods,omitempty"`
+	}{
+		AccessToken: result.AccessToken,
+		MFAToken:    result.MFAToken,
+		MFAMethods:  result.MFAMethods,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("Failed marshal request response")
+		writeInternalServerError(w)
+		return
+	}
+}
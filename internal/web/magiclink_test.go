@@ -0,0 +1,179 @@
+package web
+
+import (
+	"errors"
+	"github.com/leberKleber/simple-jwt-provider/internal"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestMagicLinkHandler(t *testing.T) {
+	tests := []struct {
+		name                 string
+		requestBody          string
+		providerError        error
+		expectedEMail        string
+		expectedResponseCode int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "Happycase",
+			requestBody:          `{"email": "test.test@test.test"}`,
+			expectedEMail:        "test.test@test.test",
+			expectedResponseCode: http.StatusCreated,
+		},
+		{
+			name:                 "Invalid JSON",
+			requestBody:          `{"email test.test@test.test}"`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid JSON"}`,
+		},
+		{
+			name:                 "Missing email",
+			requestBody:          `{}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"email must be set"}`,
+		},
+		{
+			name:                 "Invalid email",
+			requestBody:          `{"email": "test.test"}`,
+			providerError:        internal.ErrInvalidEMail,
+			expectedEMail:        "test.test",
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid email"}`,
+		},
+		{
+			name:                 "User not found",
+			requestBody:          `{"email": "test.test@test.test"}`,
+			providerError:        internal.ErrUserNotFound,
+			expectedEMail:        "test.test@test.test",
+			expectedResponseCode: http.StatusCreated,
+		},
+		{
+			name:                 "Magic link not configured",
+			requestBody:          `{"email": "test.test@test.test"}`,
+			providerError:        internal.ErrMagicLinkNotConfigured,
+			expectedEMail:        "test.test@test.test",
+			expectedResponseCode: http.StatusNotFound,
+			expectedResponseBody: `{"message":"magic link login is not configured"}`,
+		},
+		{
+			name:                 "Unexpected error",
+			requestBody:          `{"email": "test.test@test.test"}`,
+			providerError:        errors.New("nope"),
+			expectedEMail:        "test.test@test.test",
+			expectedResponseCode: http.StatusInternalServerError,
+			expectedResponseBody: `{"message":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenEMail string
+
+			toTest := NewServer(&ProviderMock{
+				CreateMagicLinkFunc: func(email string) error {
+					givenEMail = email
+					return tt.providerError
+				},
+			}, false, "", "")
+
+			callMFAEndpoint(t, toTest, "/v1/auth/magic-link", tt.requestBody, tt.expectedResponseCode, tt.expectedResponseBody)
+
+			if givenEMail != tt.expectedEMail {
+				t.Errorf("Provider called with unexpected email. Given: %q, Expected: %q", givenEMail, tt.expectedEMail)
+			}
+		})
+	}
+}
+
+func TestRedeemMagicLinkHandler(t *testing.T) {
+	tests := []struct {
+		name                 string
+		requestBody          string
+		providerResult       internal.LoginResult
+		providerError        error
+		expectedArgs         []string
+		expectedResponseCode int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "Happycase",
+			requestBody:          `{"email": "test.test@test.test", "token": "myToken"}`,
+			providerResult:       internal.LoginResult{AccessToken: "myNewJWT"},
+			expectedArgs:         []string{"test.test@test.test", "myToken"},
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"access_token":"myNewJWT"}`,
+		},
+		{
+			name:                 "Happycase with mfa",
+			requestBody:          `{"email": "test.test@test.test", "token": "myToken"}`,
+			providerResult:       internal.LoginResult{MFAToken: "myMFAToken", MFAMethods: []string{"totp"}},
+			expectedArgs:         []string{"test.test@test.test", "myToken"},
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"mfa_token":"myMFAToken","mfa_methods":["totp"]}`,
+		},
+		{
+			name:                 "Invalid JSON",
+			requestBody:          `{"email test.test@test.test}"`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid JSON"}`,
+		},
+		{
+			name:                 "Missing email",
+			requestBody:          `{"token": "myToken"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"email must be set"}`,
+		},
+		{
+			name:                 "Missing token",
+			requestBody:          `{"email": "test.test@test.test"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"token must be set"}`,
+		},
+		{
+			name:                 "Invalid token",
+			requestBody:          `{"email": "test.test@test.test", "token": "myToken"}`,
+			providerError:        internal.ErrNoValidTokenFound,
+			expectedArgs:         []string{"test.test@test.test", "myToken"},
+			expectedResponseCode: http.StatusUnauthorized,
+			expectedResponseBody: `{"message":"invalid credentials"}`,
+		},
+		{
+			name:                 "Magic link not configured",
+			requestBody:          `{"email": "test.test@test.test", "token": "myToken"}`,
+			providerError:        internal.ErrMagicLinkNotConfigured,
+			expectedArgs:         []string{"test.test@test.test", "myToken"},
+			expectedResponseCode: http.StatusNotFound,
+			expectedResponseBody: `{"message":"magic link login is not configured"}`,
+		},
+		{
+			name:                 "Unexpected error",
+			requestBody:          `{"email": "test.test@test.test", "token": "myToken"}`,
+			providerError:        errors.New("nope"),
+			expectedArgs:         []string{"test.test@test.test", "myToken"},
+			expectedResponseCode: http.StatusInternalServerError,
+			expectedResponseBody: `{"message":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenArgs []string
+
+			toTest := NewServer(&ProviderMock{
+				LoginMagicLinkFunc: func(email string, magicLinkToken string, client internal.Client) (internal.LoginResult, error) {
+					givenArgs = []string{email, magicLinkToken}
+					return tt.providerResult, tt.providerError
+				},
+			}, false, "", "")
+
+			callMFAEndpoint(t, toTest, "/v1/auth/magic-link/redeem", tt.requestBody, tt.expectedResponseCode, tt.expectedResponseBody)
+
+			if !reflect.DeepEqual(givenArgs, tt.expectedArgs) {
+				t.Errorf("Provider called with unexpected args. Given: %q, Expected: %q", givenArgs, tt.expectedArgs)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/leberKleber/simple-jwt-provider/internal"
+	"github.com/leberKleber/simple-jwt-provider/internal/webauthn"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+// secondFactor is the proof of an enabled second factor which is required to change the second factors of users who
+// have enabled one
+type secondFactor struct {
+	Code       string                      `json:"code"`
+	Credential *webauthn.AssertionResponse `json:"credential"`
+}
+
+func (f secondFactor) internal() internal.SecondFactor {
+	return internal.SecondFactor{Code: f.Code, WebAuthn: f.Credential}
+}
+
+// writeSecondFactorError writes the response of errors of second factors which are required besides the password and
+// returns true when the given error is one of them
+func writeSecondFactorError(w http.ResponseWriter, err error, identifier string) bool {
+	if errors.Is(err, internal.ErrSecondFactorRequired) {
+		writeError(w, http.StatusForbidden, "second factor required")
+		return true
+	}
+	if errors.Is(err, internal.ErrInvalidSecondFactor) {
+		logrus.WithField("identifier", identifier).Warn("somebody tried to change the second factors with an invalid second factor")
+		writeError(w, http.StatusUnauthorized, "invalid credentials")
+		return true
+	}
+
+	return false
+}
+
+func (s *Server) loginMFAHandler(w http.ResponseWriter, r *http.Request) {
+	requestBody := struct {
+		loginIdentifier
+		MFAToken string `json:"mfa_token"`
+		Code     string `json:"code"`
+	}{}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	if requestBody.identifier() == "" {
+		writeError(w, http.StatusBadRequest, "identifier must be set")
+		return
+	}
+
+	if requestBody.MFAToken == "" {
+		writeError(w, http.StatusBadRequest, "mfa-token must be set")
+		return
+	}
+
+	if requestBody.Code == "" {
+		writeError(w, http.StatusBadRequest, "code must be set")
+		return
+	}
+
+	jwt, err := s.p.LoginMFA(requestBody.identifier(), requestBody.MFAToken, requestBody.Code, client(r))
+	if err != nil {
+		if errors.Is(err, internal.ErrInvalidEMail) {
+			writeError(w, http.StatusBadRequest, "invalid email")
+			return
+		}
+		if errors.Is(err, internal.ErrNoValidTokenFound) || errors.Is(err, internal.ErrInvalidMFACode) ||
+			errors.Is(err, internal.ErrTOTPNotEnrolled) {
+			logrus.WithField("identifier", requestBody.identifier()).Warn("somebody tried to login with invalid mfa credentials")
+			writeError(w, http.StatusUnauthorized, "invalid credentials")
+			return
+		}
+		if errors.Is(err, internal.ErrTOTPNotConfigured) {
+			writeError(w, http.StatusNotFound, "totp is not configured")
+			return
+		}
+
+		logrus.WithError(err).Error("Failed to login User with mfa")
+		writeInternalServerError(w)
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(struct {
+		AccessToken string `json:"access_token"`
+	}{
+		AccessToken: jwt,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("Failed marshal request response")
+		writeInternalServerError(w)
+		return
+	}
+}
+
+func (s *Server) enrolTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	requestBody := struct {
+		loginIdentifier
+		Password     string       `json:"password"`
+		SecondFactor secondFactor `json:"second_factor"`
+	}{}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	if requestBody.identifier() == "" {
+		writeError(w, http.StatusBadRequest, "identifier must be set")
+		return
+	}
+
+	if requestBody.Password == "" {
+		writeError(w, http.StatusBadRequest, "password must be set")
+		return
+	}
+
+	enrolment, err := s.p.EnrolTOTP(requestBody.identifier(), requestBody.Password, requestBody.SecondFactor.internal())
+	if err != nil {
+		if errors.Is(err, internal.ErrInvalidEMail) {
+			writeError(w, http.StatusBadRequest, "invalid email")
+			return
+		}
+		if errors.Is(err, internal.ErrIncorrectPassword) || errors.Is(err, internal.ErrUserNotFound) {
+			logrus.WithField("identifier", requestBody.identifier()).Warn("somebody tried to enrol totp with invalid credentials")
+			writeError(w, http.StatusUnauthorized, "invalid credentials")
+			return
+		}
+		if writeSecondFactorError(w, err, requestBody.identifier()) {
+			return
+		}
+		if errors.Is(err, internal.ErrTOTPNotConfigured) {
+			writeError(w, http.StatusNotFound, "totp is not configured")
+			return
+		}
+		if errors.Is(err, internal.ErrTOTPAlreadyEnabled) {
+			writeError(w, http.StatusConflict, "totp is already enabled")
+			return
+		}
+
+		logrus.WithError(err).Error("Failed to enrol totp")
+		writeInternalServerError(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(struct {
+		Secret string `json:"secret"`
+		URI    string `json:"uri"`
+	}{
+		Secret: enrolment.Secret,
+		URI:    enrolment.URI,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("Failed marshal request response")
+		return
+	}
+}
+
+func (s *Server) confirmTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	requestBody := struct {
+		loginIdentifier
+		Password     string       `json:"password"`
+		Code         string       `json:"code"`
+		SecondFactor secondFactor `json:"second_factor"`
+	}{}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	if requestBody.identifier() == "" {
+		writeError(w, http.StatusBadRequest, "identifier must be set")
+		return
+	}
+
+	if requestBody.Password == "" {
+		writeError(w, http.StatusBadRequest, "password must be set")
+		return
+	}
+
+	if requestBody.Code == "" {
+		writeError(w, http.StatusBadRequest, "code must be set")
+		return
+	}
+
+	recoveryCodes, err := s.p.ConfirmTOTP(requestBody.identifier(), requestBody.Password, requestBody.Code, requestBody.SecondFactor.internal())
+	if err != nil {
+		if errors.Is(err, internal.ErrInvalidEMail) {
+			writeError(w, http.StatusBadRequest, "invalid email")
+			return
+		}
+		if errors.Is(err, internal.ErrIncorrectPassword) || errors.Is(err, internal.ErrUserNotFound) {
+			logrus.WithField("identifier", requestBody.identifier()).Warn("somebody tried to confirm totp with invalid credentials")
+			writeError(w, http.StatusUnauthorized, "invalid credentials")
+			return
+		}
+		if writeSecondFactorError(w, err, requestBody.identifier()) {
+			return
+		}
+		if errors.Is(err, internal.ErrTOTPNotConfigured) {
+			writeError(w, http.StatusNotFound, "totp is not configured")
+			return
+		}
+		if errors.Is(err, internal.ErrTOTPNotEnrolled) {
+			writeError(w, http.StatusBadRequest, "totp is not enrolled")
+			return
+		}
+		if errors.Is(err, internal.ErrTOTPAlreadyEnabled) {
+			writeError(w, http.StatusConflict, "totp is already enabled")
+			return
+		}
+		if errors.Is(err, internal.ErrInvalidMFACode) {
+			writeError(w, http.StatusBadRequest, "invalid code")
+			return
+		}
+
+		logrus.WithError(err).Error("Failed to confirm totp")
+		writeInternalServerError(w)
+		return
+	}
+
+	if len(recoveryCodes) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}{
+		RecoveryCodes: recoveryCodes,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("Failed marshal request response")
+		writeInternalServerError(w)
+		return
+	}
+}
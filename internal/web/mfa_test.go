@@ -0,0 +1,399 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/leberKleber/simple-jwt-provider/internal"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestLoginMFAHandler(t *testing.T) {
+	tests := []struct {
+		name                 string
+		requestBody          string
+		providerToken        string
+		providerError        error
+		expectedArgs         []string
+		expectedResponseCode int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "Happycase",
+			requestBody:          `{"email": "test.test@test.test", "mfa_token": "myMFAToken", "code": "123456"}`,
+			providerToken:        "myNewJWT",
+			expectedArgs:         []string{"test.test@test.test", "myMFAToken", "123456"},
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"access_token":"myNewJWT"}`,
+		},
+		{
+			name:                 "Invalid JSON",
+			requestBody:          `{"email test.test@test.test}"`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid JSON"}`,
+		},
+		{
+			name:                 "Missing identifier",
+			requestBody:          `{"mfa_token": "myMFAToken", "code": "123456"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"identifier must be set"}`,
+		},
+		{
+			name:                 "Missing mfa token",
+			requestBody:          `{"email": "test.test@test.test", "code": "123456"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"mfa-token must be set"}`,
+		},
+		{
+			name:                 "Missing code",
+			requestBody:          `{"email": "test.test@test.test", "mfa_token": "myMFAToken"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"code must be set"}`,
+		},
+		{
+			name:                 "Invalid mfa token",
+			requestBody:          `{"email": "test.test@test.test", "mfa_token": "myMFAToken", "code": "123456"}`,
+			providerError:        internal.ErrNoValidTokenFound,
+			expectedArgs:         []string{"test.test@test.test", "myMFAToken", "123456"},
+			expectedResponseCode: http.StatusUnauthorized,
+			expectedResponseBody: `{"message":"invalid credentials"}`,
+		},
+		{
+			name:                 "Invalid code",
+			requestBody:          `{"email": "test.test@test.test", "mfa_token": "myMFAToken", "code": "123456"}`,
+			providerError:        internal.ErrInvalidMFACode,
+			expectedArgs:         []string{"test.test@test.test", "myMFAToken", "123456"},
+			expectedResponseCode: http.StatusUnauthorized,
+			expectedResponseBody: `{"message":"invalid credentials"}`,
+		},
+		{
+			name:                 "TOTP not configured",
+			requestBody:          `{"email": "test.test@test.test", "mfa_token": "myMFAToken", "code": "123456"}`,
+			providerError:        internal.ErrTOTPNotConfigured,
+			expectedArgs:         []string{"test.test@test.test", "myMFAToken", "123456"},
+			expectedResponseCode: http.StatusNotFound,
+			expectedResponseBody: `{"message":"totp is not configured"}`,
+		},
+		{
+			name:                 "Unexpected error",
+			requestBody:          `{"email": "test.test@test.test", "mfa_token": "myMFAToken", "code": "123456"}`,
+			providerError:        errors.New("nope"),
+			expectedArgs:         []string{"test.test@test.test", "myMFAToken", "123456"},
+			expectedResponseCode: http.StatusInternalServerError,
+			expectedResponseBody: `{"message":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenArgs []string
+
+			toTest := NewServer(&ProviderMock{
+				LoginMFAFunc: func(email string, mfaToken string, code string, client internal.Client) (string, error) {
+					givenArgs = []string{email, mfaToken, code}
+					return tt.providerToken, tt.providerError
+				},
+			}, false, "", "")
+
+			callMFAEndpoint(t, toTest, "/v1/auth/login/mfa", tt.requestBody, tt.expectedResponseCode, tt.expectedResponseBody)
+
+			if !reflect.DeepEqual(givenArgs, tt.expectedArgs) {
+				t.Errorf("Provider called with unexpected args. Given: %q, Expected: %q", givenArgs, tt.expectedArgs)
+			}
+		})
+	}
+}
+
+func TestEnrolTOTPHandler(t *testing.T) {
+	tests := []struct {
+		name                 string
+		requestBody          string
+		providerEnrolment    internal.TOTPEnrolment
+		providerError        error
+		expectedArgs         []string
+		expectedResponseCode int
+		expectedResponseBody string
+	}{
+		{
+			name:        "Happycase",
+			requestBody: `{"email": "test.test@test.test", "password": "s3cr3t"}`,
+			providerEnrolment: internal.TOTPEnrolment{
+				Secret: "GEZDGNBVGY3TQOJQ",
+				URI:    "otpauth://totp/issuer:test.test@test.test?secret=GEZDGNBVGY3TQOJQ",
+			},
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", ""},
+			expectedResponseCode: http.StatusCreated,
+			expectedResponseBody: `{"secret":"GEZDGNBVGY3TQOJQ","uri":"otpauth://totp/issuer:test.test@test.test?secret=GEZDGNBVGY3TQOJQ"}`,
+		},
+		{
+			name:        "Happycase with second factor",
+			requestBody: `{"email": "test.test@test.test", "password": "s3cr3t", "second_factor": {"code": "abcde-fghjk"}}`,
+			providerEnrolment: internal.TOTPEnrolment{
+				Secret: "GEZDGNBVGY3TQOJQ",
+				URI:    "otpauth://totp/issuer:test.test@test.test?secret=GEZDGNBVGY3TQOJQ",
+			},
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", "abcde-fghjk"},
+			expectedResponseCode: http.StatusCreated,
+			expectedResponseBody: `{"secret":"GEZDGNBVGY3TQOJQ","uri":"otpauth://totp/issuer:test.test@test.test?secret=GEZDGNBVGY3TQOJQ"}`,
+		},
+		{
+			name:                 "Invalid JSON",
+			requestBody:          `{"email test.test@test.test}"`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid JSON"}`,
+		},
+		{
+			name:                 "Missing identifier",
+			requestBody:          `{"password": "s3cr3t"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"identifier must be set"}`,
+		},
+		{
+			name:                 "Missing password",
+			requestBody:          `{"email": "test.test@test.test"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"password must be set"}`,
+		},
+		{
+			name:                 "Invalid credentials",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t"}`,
+			providerError:        internal.ErrIncorrectPassword,
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", ""},
+			expectedResponseCode: http.StatusUnauthorized,
+			expectedResponseBody: `{"message":"invalid credentials"}`,
+		},
+		{
+			name:                 "Second factor required",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t"}`,
+			providerError:        internal.ErrSecondFactorRequired,
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", ""},
+			expectedResponseCode: http.StatusForbidden,
+			expectedResponseBody: `{"message":"second factor required"}`,
+		},
+		{
+			name:                 "Invalid second factor",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t", "second_factor": {"code": "abcde-fghjk"}}`,
+			providerError:        internal.ErrInvalidSecondFactor,
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", "abcde-fghjk"},
+			expectedResponseCode: http.StatusUnauthorized,
+			expectedResponseBody: `{"message":"invalid credentials"}`,
+		},
+		{
+			name:                 "TOTP not configured",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t"}`,
+			providerError:        internal.ErrTOTPNotConfigured,
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", ""},
+			expectedResponseCode: http.StatusNotFound,
+			expectedResponseBody: `{"message":"totp is not configured"}`,
+		},
+		{
+			name:                 "TOTP already enabled",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t"}`,
+			providerError:        internal.ErrTOTPAlreadyEnabled,
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", ""},
+			expectedResponseCode: http.StatusConflict,
+			expectedResponseBody: `{"message":"totp is already enabled"}`,
+		},
+		{
+			name:                 "Unexpected error",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t"}`,
+			providerError:        errors.New("nope"),
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", ""},
+			expectedResponseCode: http.StatusInternalServerError,
+			expectedResponseBody: `{"message":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenArgs []string
+
+			toTest := NewServer(&ProviderMock{
+				EnrolTOTPFunc: func(email string, password string, secondFactor internal.SecondFactor) (internal.TOTPEnrolment, error) {
+					givenArgs = []string{email, password, secondFactor.Code}
+					return tt.providerEnrolment, tt.providerError
+				},
+			}, false, "", "")
+
+			callMFAEndpoint(t, toTest, "/v1/auth/totp", tt.requestBody, tt.expectedResponseCode, tt.expectedResponseBody)
+
+			if !reflect.DeepEqual(givenArgs, tt.expectedArgs) {
+				t.Errorf("Provider called with unexpected args. Given: %q, Expected: %q", givenArgs, tt.expectedArgs)
+			}
+		})
+	}
+}
+
+func TestConfirmTOTPHandler(t *testing.T) {
+	tests := []struct {
+		name                 string
+		requestBody          string
+		providerCodes        []string
+		providerError        error
+		expectedArgs         []string
+		expectedResponseCode int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "Happycase",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t", "code": "123456"}`,
+			providerCodes:        []string{"abcde-fghjk", "01234-56789"},
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", "123456", ""},
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"recovery_codes":["abcde-fghjk","01234-56789"]}`,
+		},
+		{
+			name:                 "Happycase without new recovery codes",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t", "code": "123456"}`,
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", "123456", ""},
+			expectedResponseCode: http.StatusNoContent,
+		},
+		{
+			name:                 "Invalid JSON",
+			requestBody:          `{"email test.test@test.test}"`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid JSON"}`,
+		},
+		{
+			name:                 "Missing identifier",
+			requestBody:          `{"password": "s3cr3t", "code": "123456"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"identifier must be set"}`,
+		},
+		{
+			name:                 "Missing password",
+			requestBody:          `{"email": "test.test@test.test", "code": "123456"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"password must be set"}`,
+		},
+		{
+			name:                 "Missing code",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"code must be set"}`,
+		},
+		{
+			name:                 "Invalid credentials",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t", "code": "123456"}`,
+			providerError:        internal.ErrUserNotFound,
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", "123456", ""},
+			expectedResponseCode: http.StatusUnauthorized,
+			expectedResponseBody: `{"message":"invalid credentials"}`,
+		},
+		{
+			name:                 "Second factor required",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t", "code": "123456"}`,
+			providerError:        internal.ErrSecondFactorRequired,
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", "123456", ""},
+			expectedResponseCode: http.StatusForbidden,
+			expectedResponseBody: `{"message":"second factor required"}`,
+		},
+		{
+			name:                 "Invalid second factor",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t", "code": "123456", "second_factor": {"code": "abcde-fghjk"}}`,
+			providerError:        internal.ErrInvalidSecondFactor,
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", "123456", "abcde-fghjk"},
+			expectedResponseCode: http.StatusUnauthorized,
+			expectedResponseBody: `{"message":"invalid credentials"}`,
+		},
+		{
+			name:                 "TOTP not configured",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t", "code": "123456"}`,
+			providerError:        internal.ErrTOTPNotConfigured,
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", "123456", ""},
+			expectedResponseCode: http.StatusNotFound,
+			expectedResponseBody: `{"message":"totp is not configured"}`,
+		},
+		{
+			name:                 "TOTP not enrolled",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t", "code": "123456"}`,
+			providerError:        internal.ErrTOTPNotEnrolled,
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", "123456", ""},
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"totp is not enrolled"}`,
+		},
+		{
+			name:                 "TOTP already enabled",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t", "code": "123456"}`,
+			providerError:        internal.ErrTOTPAlreadyEnabled,
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", "123456", ""},
+			expectedResponseCode: http.StatusConflict,
+			expectedResponseBody: `{"message":"totp is already enabled"}`,
+		},
+		{
+			name:                 "Invalid code",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t", "code": "123456"}`,
+			providerError:        internal.ErrInvalidMFACode,
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", "123456", ""},
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid code"}`,
+		},
+		{
+			name:                 "Unexpected error",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t", "code": "123456"}`,
+			providerError:        errors.New("nope"),
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", "123456", ""},
+			expectedResponseCode: http.StatusInternalServerError,
+			expectedResponseBody: `{"message":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenArgs []string
+
+			toTest := NewServer(&ProviderMock{
+				ConfirmTOTPFunc: func(email string, password string, code string, secondFactor internal.SecondFactor) ([]string, error) {
+					givenArgs = []string{email, password, code, secondFactor.Code}
+					return tt.providerCodes, tt.providerError
+				},
+			}, false, "", "")
+
+			callMFAEndpoint(t, toTest, "/v1/auth/totp/confirm", tt.requestBody, tt.expectedResponseCode, tt.expectedResponseBody)
+
+			if !reflect.DeepEqual(givenArgs, tt.expectedArgs) {
+				t.Errorf("Provider called with unexpected args. Given: %q, Expected: %q", givenArgs, tt.expectedArgs)
+			}
+		})
+	}
+}
+
+func callMFAEndpoint(t *testing.T, s *Server, path, requestBody string, expectedResponseCode int, expectedResponseBody string) {
+	t.Helper()
+	testServer := httptest.NewServer(s.h)
+	defer testServer.Close()
+
+	resp, err := http.Post(testServer.URL+path, "application/json", bytes.NewReader([]byte(requestBody)))
+	if err != nil {
+		t.Fatalf("Failed to call server cause: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != expectedResponseCode {
+		t.Errorf("Request respond with unexpected status code. Expected: %d, Given: %d", expectedResponseCode, resp.StatusCode)
+	}
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response body: %s", err)
+	}
+
+	var compactedRespBodyAsBytes []byte
+	if len(respBody) > 0 {
+		compactedRespBody := &bytes.Buffer{}
+		err = json.Compact(compactedRespBody, respBody)
+		if err != nil {
+			t.Fatalf("Failed to compact json: %s", err)
+		}
+
+		compactedRespBodyAsBytes = compactedRespBody.Bytes()
+	}
+
+	if !bytes.Equal(compactedRespBodyAsBytes, []byte(expectedResponseBody)) {
+		t.Errorf("Request response body is not as expected. Expected: %q, Given: %q", expectedResponseBody, string(compactedRespBodyAsBytes))
+	}
+}
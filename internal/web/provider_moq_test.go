@@ -5,17 +5,41 @@ package web
 
 import (
 	"github.com/leberKleber/simple-jwt-provider/internal"
+	"github.com/leberKleber/simple-jwt-provider/internal/webauthn"
 	"sync"
 )
 
 var (
+	lockProviderMockAcceptInvitation           sync.RWMutex
+	lockProviderMockBeginWebAuthnLogin         sync.RWMutex
+	lockProviderMockBeginWebAuthnRegistration  sync.RWMutex
+	lockProviderMockChangePassword             sync.RWMutex
+	lockProviderMockConfirmTOTP                sync.RWMutex
+	lockProviderMockCreateLoginCode            sync.RWMutex
+	lockProviderMockCreateMagicLink            sync.RWMutex
 	lockProviderMockCreatePasswordResetRequest sync.RWMutex
 	lockProviderMockCreateUser                 sync.RWMutex
 	lockProviderMockDeleteUser                 sync.RWMutex
+	lockProviderMockEnrolTOTP                  sync.RWMutex
+	lockProviderMockEraseUser                  sync.RWMutex
+	lockProviderMockExportUser                 sync.RWMutex
+	lockProviderMockFindUsers                  sync.RWMutex
+	lockProviderMockFinishWebAuthnLogin        sync.RWMutex
+	lockProviderMockFinishWebAuthnRegistration sync.RWMutex
 	lockProviderMockGetUser                    sync.RWMutex
 	lockProviderMockLogin                      sync.RWMutex
+	lockProviderMockLoginMFA                   sync.RWMutex
+	lockProviderMockLoginMagicLink             sync.RWMutex
+	lockProviderMockLoginRecoveryCode          sync.RWMutex
+	lockProviderMockLoginWithCode              sync.RWMutex
+	lockProviderMockLogins                     sync.RWMutex
+	lockProviderMockPatchUser                  sync.RWMutex
+	lockProviderMockRegenerateRecoveryCodes    sync.RWMutex
+	lockProviderMockResendInvitation           sync.RWMutex
+	lockProviderMockResetMFA                   sync.RWMutex
 	lockProviderMockResetPassword              sync.RWMutex
 	lockProviderMockUpdateUser                 sync.RWMutex
+	lockProviderMockVerifyAccessToken          sync.RWMutex
 )
 
 // Ensure, that ProviderMock does implement Provider.
@@ -28,27 +52,96 @@ var _ Provider = &ProviderMock{}
 //
 //         // make and configure a mocked Provider
 //         mockedProvider := &ProviderMock{
+//             AcceptInvitationFunc: func(email string, invitationToken string, password string) error {
+// 	               panic("mock out the AcceptInvitation method")
+//             },
+//             BeginWebAuthnLoginFunc: func(identifier string, mfaToken string) (webauthn.CredentialRequestOptions, error) {
+// 	               panic("mock out the BeginWebAuthnLogin method")
+//             },
+//             BeginWebAuthnRegistrationFunc: func(identifier string, password string, secondFactor internal.SecondFactor) (webauthn.CredentialCreationOptions, error) {
+// 	               panic("mock out the BeginWebAuthnRegistration method")
+//             },
+//             ChangePasswordFunc: func(identifier string, password string, newPassword string) error {
+// 	               panic("mock out the ChangePassword method")
+//             },
+//             ConfirmTOTPFunc: func(identifier string, password string, code string, secondFactor internal.SecondFactor) ([]string, error) {
+// 	               panic("mock out the ConfirmTOTP method")
+//             },
+//             CreateLoginCodeFunc: func(email string) error {
+// 	               panic("mock out the CreateLoginCode method")
+//             },
+//             CreateMagicLinkFunc: func(email string) error {
+// 	               panic("mock out the CreateMagicLink method")
+//             },
 //             CreatePasswordResetRequestFunc: func(email string) error {
 // 	               panic("mock out the CreatePasswordResetRequest method")
 //             },
 //             CreateUserFunc: func(user internal.User) error {
 // 	               panic("mock out the CreateUser method")
 //             },
-//             DeleteUserFunc: func(email string) error {
+//             DeleteUserFunc: func(idOrIdentifier string) error {
 // 	               panic("mock out the DeleteUser method")
 //             },
-//             GetUserFunc: func(email string) (internal.User, error) {
+//             EnrolTOTPFunc: func(identifier string, password string, secondFactor internal.SecondFactor) (internal.TOTPEnrolment, error) {
+// 	               panic("mock out the EnrolTOTP method")
+//             },
+//             EraseUserFunc: func(idOrIdentifier string) (internal.UserErasure, error) {
+// 	               panic("mock out the EraseUser method")
+//             },
+//             ExportUserFunc: func(idOrIdentifier string) (internal.UserExport, error) {
+// 	               panic("mock out the ExportUser method")
+//             },
+//             FindUsersFunc: func(predicates []internal.ClaimPredicate, limit int, offset int) (internal.UserPage, error) {
+// 	               panic("mock out the FindUsers method")
+//             },
+//             FinishWebAuthnLoginFunc: func(identifier string, mfaToken string, r webauthn.AssertionResponse, client internal.Client) (string, error) {
+// 	               panic("mock out the FinishWebAuthnLogin method")
+//             },
+//             FinishWebAuthnRegistrationFunc: func(identifier string, r webauthn.AttestationResponse) ([]string, error) {
+// 	               panic("mock out the FinishWebAuthnRegistration method")
+//             },
+//             GetUserFunc: func(idOrIdentifier string) (internal.User, error) {
 // 	               panic("mock out the GetUser method")
 //             },
-//             LoginFunc: func(email string, password string) (string, error) {
+//             LoginFunc: func(identifier string, password string, client internal.Client) (internal.LoginResult, error) {
 // 	               panic("mock out the Login method")
 //             },
+//             LoginMFAFunc: func(identifier string, mfaToken string, code string, client internal.Client) (string, error) {
+// 	               panic("mock out the LoginMFA method")
+//             },
+//             LoginMagicLinkFunc: func(email string, magicLinkToken string, client internal.Client) (internal.LoginResult, error) {
+// 	               panic("mock out the LoginMagicLink method")
+//             },
+//             LoginRecoveryCodeFunc: func(identifier string, mfaToken string, recoveryCode string, client internal.Client) (string, error) {
+// 	               panic("mock out the LoginRecoveryCode method")
+//             },
+//             LoginWithCodeFunc: func(email string, code string, client internal.Client) (internal.LoginResult, error) {
+// 	               panic("mock out the LoginWithCode method")
+//             },
+//             LoginsFunc: func(idOrIdentifier string, limit int, offset int) (internal.LoginHistory, error) {
+// 	               panic("mock out the Logins method")
+//             },
+//             PatchUserFunc: func(idOrIdentifier string, format internal.PatchFormat, patch []byte) (internal.User, error) {
+// 	               panic("mock out the PatchUser method")
+//             },
+//             RegenerateRecoveryCodesFunc: func(identifier string, password string, code string) ([]string, error) {
+// 	               panic("mock out the RegenerateRecoveryCodes method")
+//             },
+//             ResendInvitationFunc: func(idOrIdentifier string) error {
+// 	               panic("mock out the ResendInvitation method")
+//             },
+//             ResetMFAFunc: func(idOrIdentifier string) error {
+// 	               panic("mock out the ResetMFA method")
+//             },
 //             ResetPasswordFunc: func(email string, resetToken string, password string) error {
 // 	               panic("mock out the ResetPassword method")
 //             },
-//             UpdateUserFunc: func(email string, user internal.User) (internal.User, error) {
+//             UpdateUserFunc: func(idOrIdentifier string, user internal.User) (internal.User, error) {
 // 	               panic("mock out the UpdateUser method")
 //             },
+//             VerifyAccessTokenFunc: func(accessToken string) (string, error) {
+// 	               panic("mock out the VerifyAccessToken method")
+//             },
 //         }
 //
 //         // use mockedProvider in code that requires Provider
@@ -56,6 +149,27 @@ var _ Provider = &ProviderMock{}
 //
 //     }
 type ProviderMock struct {
+	// AcceptInvitationFunc mocks the AcceptInvitation method.
+	AcceptInvitationFunc func(email string, invitationToken string, password string) error
+
+	// BeginWebAuthnLoginFunc mocks the BeginWebAuthnLogin method.
+	BeginWebAuthnLoginFunc func(identifier string, mfaToken string) (webauthn.CredentialRequestOptions, error)
+
+	// BeginWebAuthnRegistrationFunc mocks the BeginWebAuthnRegistration method.
+	BeginWebAuthnRegistrationFunc func(identifier string, password string, secondFactor internal.SecondFactor) (webauthn.CredentialCreationOptions, error)
+
+	// ChangePasswordFunc mocks the ChangePassword method.
+	ChangePasswordFunc func(identifier string, password string, newPassword string) error
+
+	// ConfirmTOTPFunc mocks the ConfirmTOTP method.
+	ConfirmTOTPFunc func(identifier string, password string, code string, secondFactor internal.SecondFactor) ([]string, error)
+
+	// CreateLoginCodeFunc mocks the CreateLoginCode method.
+	CreateLoginCodeFunc func(email string) error
+
+	// CreateMagicLinkFunc mocks the CreateMagicLink method.
+	CreateMagicLinkFunc func(email string) error
+
 	// CreatePasswordResetRequestFunc mocks the CreatePasswordResetRequest method.
 	CreatePasswordResetRequestFunc func(email string) error
 
@@ -63,22 +177,125 @@ type ProviderMock struct {
 	CreateUserFunc func(user internal.User) error
 
 	// DeleteUserFunc mocks the DeleteUser method.
-	DeleteUserFunc func(email string) error
+	DeleteUserFunc func(idOrIdentifier string) error
+
+	// EnrolTOTPFunc mocks the EnrolTOTP method.
+	EnrolTOTPFunc func(identifier string, password string, secondFactor internal.SecondFactor) (internal.TOTPEnrolment, error)
+
+	// EraseUserFunc mocks the EraseUser method.
+	EraseUserFunc func(idOrIdentifier string) (internal.UserErasure, error)
+
+	// ExportUserFunc mocks the ExportUser method.
+	ExportUserFunc func(idOrIdentifier string) (internal.UserExport, error)
+
+	// FindUsersFunc mocks the FindUsers method.
+	FindUsersFunc func(predicates []internal.ClaimPredicate, limit int, offset int) (internal.UserPage, error)
+
+	// FinishWebAuthnLoginFunc mocks the FinishWebAuthnLogin method.
+	FinishWebAuthnLoginFunc func(identifier string, mfaToken string, r webauthn.AssertionResponse, client internal.Client) (string, error)
+
+	// FinishWebAuthnRegistrationFunc mocks the FinishWebAuthnRegistration method.
+	FinishWebAuthnRegistrationFunc func(identifier string, r webauthn.AttestationResponse) ([]string, error)
 
 	// GetUserFunc mocks the GetUser method.
-	GetUserFunc func(email string) (internal.User, error)
+	GetUserFunc func(idOrIdentifier string) (internal.User, error)
 
 	// LoginFunc mocks the Login method.
-	LoginFunc func(email string, password string) (string, error)
+	LoginFunc func(identifier string, password string, client internal.Client) (internal.LoginResult, error)
+
+	// LoginMFAFunc mocks the LoginMFA method.
+	LoginMFAFunc func(identifier string, mfaToken string, code string, client internal.Client) (string, error)
+
+	// LoginMagicLinkFunc mocks the LoginMagicLink method.
+	LoginMagicLinkFunc func(email string, magicLinkToken string, client internal.Client) (internal.LoginResult, error)
+
+	// LoginRecoveryCodeFunc mocks the LoginRecoveryCode method.
+	LoginRecoveryCodeFunc func(identifier string, mfaToken string, recoveryCode string, client internal.Client) (string, error)
+
+	// LoginWithCodeFunc mocks the LoginWithCode method.
+	LoginWithCodeFunc func(email string, code string, client internal.Client) (internal.LoginResult, error)
+
+	// LoginsFunc mocks the Logins method.
+	LoginsFunc func(idOrIdentifier string, limit int, offset int) (internal.LoginHistory, error)
+
+	// PatchUserFunc mocks the PatchUser method.
+	PatchUserFunc func(idOrIdentifier string, format internal.PatchFormat, patch []byte) (internal.User, error)
+
+	// RegenerateRecoveryCodesFunc mocks the RegenerateRecoveryCodes method.
+	RegenerateRecoveryCodesFunc func(identifier string, password string, code string) ([]string, error)
+
+	// ResendInvitationFunc mocks the ResendInvitation method.
+	ResendInvitationFunc func(idOrIdentifier string) error
+
+	// ResetMFAFunc mocks the ResetMFA method.
+	ResetMFAFunc func(idOrIdentifier string) error
 
 	// ResetPasswordFunc mocks the ResetPassword method.
 	ResetPasswordFunc func(email string, resetToken string, password string) error
 
 	// UpdateUserFunc mocks the UpdateUser method.
-	UpdateUserFunc func(email string, user internal.User) (internal.User, error)
+	UpdateUserFunc func(idOrIdentifier string, user internal.User) (internal.User, error)
+
+	// VerifyAccessTokenFunc mocks the VerifyAccessToken method.
+	VerifyAccessTokenFunc func(accessToken string) (string, error)
 
 	// calls tracks calls to the methods.
 	calls struct {
+		// AcceptInvitation holds details about calls to the AcceptInvitation method.
+		AcceptInvitation []struct {
+			// Email is the email argument value.
+			Email string
+			// InvitationToken is the invitationToken argument value.
+			InvitationToken string
+			// Password is the password argument value.
+			Password string
+		}
+		// BeginWebAuthnLogin holds details about calls to the BeginWebAuthnLogin method.
+		BeginWebAuthnLogin []struct {
+			// Identifier is the identifier argument value.
+			Identifier string
+			// MfaToken is the mfaToken argument value.
+			MfaToken string
+		}
+		// BeginWebAuthnRegistration holds details about calls to the BeginWebAuthnRegistration method.
+		BeginWebAuthnRegistration []struct {
+			// Identifier is the identifier argument value.
+			Identifier string
+			// Password is the password argument value.
+			Password string
+			// SecondFactor is the secondFactor argument value.
+			SecondFactor internal.SecondFactor
+		}
+		// ChangePassword holds details about calls to the ChangePassword method.
+		ChangePassword []struct {
+			// Identifier is the identifier argument value.
+			Identifier string
+			// Password is the password argument value.
+			Password string
+			// NewPassword is the newPassword argument value.
+			NewPassword string
+		}
+		// ConfirmTOTP holds details about calls to the ConfirmTOTP method.
+		ConfirmTOTP []struct {
+			// Identifier is the identifier argument value.
+			Identifier string
+			// Password is the password argument value.
+			Password string
+			// Code is the code argument value.
+			Code string
+			// SecondFactor is the secondFactor argument value.
+			SecondFactor internal.SecondFactor
+		}
+		// CreateLoginCode holds details about calls to the CreateLoginCode method.
+		CreateLoginCode []struct {
+			// Email is the email argument value.
+			Email string
+		}
+		// CreateMagicLink holds details about calls to the CreateMagicLink method.
+		CreateMagicLink []struct {
+			// Email is the email argument value.
+			Email string
+		}
 		// CreatePasswordResetRequest holds details about calls to the CreatePasswordResetRequest method.
 		CreatePasswordResetRequest []struct {
 			// Email is the email argument value.
@@ -91,20 +308,145 @@ type ProviderMock struct {
 		}
 		// DeleteUser holds details about calls to the DeleteUser method.
 		DeleteUser []struct {
-			// Email is the email argument value.
-			Email string
+			// IdOrIdentifier is the idOrIdentifier argument value.
+			IdOrIdentifier string
+		}
+		// EnrolTOTP holds details about calls to the EnrolTOTP method.
+		EnrolTOTP []struct {
+			// Identifier is the identifier argument value.
+			Identifier string
+			// Password is the password argument value.
+			Password string
+			// SecondFactor is the secondFactor argument value.
+			SecondFactor internal.SecondFactor
+		}
+		// EraseUser holds details about calls to the EraseUser method.
+		EraseUser []struct {
+			// IdOrIdentifier is the idOrIdentifier argument value.
+			IdOrIdentifier string
+		}
+		// ExportUser holds details about calls to the ExportUser method.
+		ExportUser []struct {
+			// IdOrIdentifier is the idOrIdentifier argument value.
+			IdOrIdentifier string
+		}
+		// FindUsers holds details about calls to the FindUsers method.
+		FindUsers []struct {
+			// Predicates is the predicates argument value.
+			Predicates []internal.ClaimPredicate
+			// Limit is the limit argument value.
+			Limit int
+			// Offset is the offset argument value.
+			Offset int
+		}
+		// FinishWebAuthnLogin holds details about calls to the FinishWebAuthnLogin method.
+		FinishWebAuthnLogin []struct {
+			// Identifier is the identifier argument value.
+			Identifier string
+			// MfaToken is the mfaToken argument value.
+			MfaToken string
+			// R is the r argument value.
+			R webauthn.AssertionResponse
+			// Client is the client argument value.
+			Client internal.Client
+		}
+		// FinishWebAuthnRegistration holds details about calls to the FinishWebAuthnRegistration method.
+		FinishWebAuthnRegistration []struct {
+			// Identifier is the identifier argument value.
+			Identifier string
+			// R is the r argument value.
+			R webauthn.AttestationResponse
 		}
 		// GetUser holds details about calls to the GetUser method.
 		GetUser []struct {
-			// Email is the email argument value.
-			Email string
+			// IdOrIdentifier is the idOrIdentifier argument value.
+			IdOrIdentifier string
 		}
 		// Login holds details about calls to the Login method.
 		Login []struct {
+			// Identifier is the identifier argument value.
+			Identifier string
+			// Password is the password argument value.
+			Password string
+			// Client is the client argument value.
+			Client internal.Client
+		}
+		// LoginMFA holds details about calls to the LoginMFA method.
+		LoginMFA []struct {
+			// Identifier is the identifier argument value.
+			Identifier string
+			// MfaToken is the mfaToken argument value.
+			MfaToken string
+			// Code is the code argument value.
+			Code string
+			// Client is the client argument value.
+			Client internal.Client
+		}
+		// LoginMagicLink holds details about calls to the LoginMagicLink method.
+		LoginMagicLink []struct {
+			// Email is the email argument value.
+			Email string
+			// MagicLinkToken is the magicLinkToken argument value.
+			MagicLinkToken string
+			// Client is the client argument value.
+			Client internal.Client
+		}
+		// LoginRecoveryCode holds details about calls to the LoginRecoveryCode method.
+		LoginRecoveryCode []struct {
+			// Identifier is the identifier argument value.
+			Identifier string
+			// MfaToken is the mfaToken argument value.
+			MfaToken string
+			// RecoveryCode is the recoveryCode argument value.
+			RecoveryCode string
+			// Client is the client argument value.
+			Client internal.Client
+		}
+		// LoginWithCode holds details about calls to the LoginWithCode method.
+		LoginWithCode []struct {
 			// Email is the email argument value.
 			Email string
+			// Code is the code argument value.
+			Code string
+			// Client is the client argument value.
+			Client internal.Client
+		}
+		// Logins holds details about calls to the Logins method.
+		Logins []struct {
+			// IdOrIdentifier is the idOrIdentifier argument value.
+			IdOrIdentifier string
+			// Limit is the limit argument value.
+			Limit int
+			// Offset is the offset argument value.
+			Offset int
+		}
+		// PatchUser holds details about calls to the PatchUser method.
+		PatchUser []struct {
+			// IdOrIdentifier is the idOrIdentifier argument value.
+			IdOrIdentifier string
+			// Format is the format argument value.
+			Format internal.PatchFormat
+			// Patch is the patch argument value.
+			Patch []byte
+		}
+		// RegenerateRecoveryCodes holds details about calls to the RegenerateRecoveryCodes method.
+		RegenerateRecoveryCodes []struct {
+			// Identifier is the identifier argument value.
+			Identifier string
 			// Password is the password argument value.
 			Password string
+			// Code is the code argument value.
+			Code string
+		}
+		// ResendInvitation holds details about calls to the ResendInvitation method.
+		ResendInvitation []struct {
+			// IdOrIdentifier is the idOrIdentifier argument value.
+			IdOrIdentifier string
+		}
+		// ResetMFA holds details about calls to the ResetMFA method.
+		ResetMFA []struct {
+			// IdOrIdentifier is the idOrIdentifier argument value.
+			IdOrIdentifier string
 		}
 		// ResetPassword holds details about calls to the ResetPassword method.
 		ResetPassword []struct {
@@ -117,14 +459,276 @@ type ProviderMock struct {
 		}
 		// UpdateUser holds details about calls to the UpdateUser method.
 		UpdateUser []struct {
-			// Email is the email argument value.
-			Email string
+			// IdOrIdentifier is the idOrIdentifier argument value.
+			IdOrIdentifier string
 			// User is the user argument value.
 			User internal.User
 		}
+		// VerifyAccessToken holds details about calls to the VerifyAccessToken method.
+		VerifyAccessToken []struct {
+			// AccessToken is the accessToken argument value.
+			AccessToken string
+		}
 	}
 }
 
+// AcceptInvitation calls AcceptInvitationFunc.
+func (mock *ProviderMock) AcceptInvitation(email string, invitationToken string, password string) error {
+	if mock.AcceptInvitationFunc == nil {
+		panic("ProviderMock.AcceptInvitationFunc: method is nil but Provider.AcceptInvitation was just called")
+	}
+	callInfo := struct {
+		Email           string
+		InvitationToken string
+		Password        string
+	}{
+		Email:           email,
+		InvitationToken: invitationToken,
+		Password:        password,
+	}
+	lockProviderMockAcceptInvitation.Lock()
+	mock.calls.AcceptInvitation = append(mock.calls.AcceptInvitation, callInfo)
+	lockProviderMockAcceptInvitation.Unlock()
+	return mock.AcceptInvitationFunc(email, invitationToken, password)
+}
+
+// AcceptInvitationCalls gets all the calls that were made to AcceptInvitation.
+// Check the length with:
+//     len(mockedProvider.AcceptInvitationCalls())
+func (mock *ProviderMock) AcceptInvitationCalls() []struct {
+	Email           string
+	InvitationToken string
+	Password        string
+} {
+	var calls []struct {
+		Email           string
+		InvitationToken string
+		Password        string
+	}
+	lockProviderMockAcceptInvitation.RLock()
+	calls = mock.calls.AcceptInvitation
+	lockProviderMockAcceptInvitation.RUnlock()
+	return calls
+}
+
+// BeginWebAuthnLogin calls BeginWebAuthnLoginFunc.
+func (mock *ProviderMock) BeginWebAuthnLogin(identifier string, mfaToken string) (webauthn.CredentialRequestOptions, error) {
+	if mock.BeginWebAuthnLoginFunc == nil {
+		panic("ProviderMock.BeginWebAuthnLoginFunc: method is nil but Provider.BeginWebAuthnLogin was just called")
+	}
+	callInfo := struct {
+		Identifier string
+		MfaToken   string
+	}{
+		Identifier: identifier,
+		MfaToken:   mfaToken,
+	}
+	lockProviderMockBeginWebAuthnLogin.Lock()
+	mock.calls.BeginWebAuthnLogin = append(mock.calls.BeginWebAuthnLogin, callInfo)
+	lockProviderMockBeginWebAuthnLogin.Unlock()
+	return mock.BeginWebAuthnLoginFunc(identifier, mfaToken)
+}
+
+// BeginWebAuthnLoginCalls gets all the calls that were made to BeginWebAuthnLogin.
+// Check the length with:
+//     len(mockedProvider.BeginWebAuthnLoginCalls())
+func (mock *ProviderMock) BeginWebAuthnLoginCalls() []struct {
+	Identifier string
+	MfaToken   string
+} {
+	var calls []struct {
+		Identifier string
+		MfaToken   string
+	}
+	lockProviderMockBeginWebAuthnLogin.RLock()
+	calls = mock.calls.BeginWebAuthnLogin
+	lockProviderMockBeginWebAuthnLogin.RUnlock()
+	return calls
+}
+
+// BeginWebAuthnRegistration calls BeginWebAuthnRegistrationFunc.
+func (mock *ProviderMock) BeginWebAuthnRegistration(identifier string, password string, secondFactor internal.SecondFactor) (webauthn.CredentialCreationOptions, error) {
+	if mock.BeginWebAuthnRegistrationFunc == nil {
+		panic("ProviderMock.BeginWebAuthnRegistrationFunc: method is nil but Provider.BeginWebAuthnRegistration was just called")
+	}
+	callInfo := struct {
+		Identifier   string
+		Password     string
+		SecondFactor internal.SecondFactor
+	}{
+		Identifier:   identifier,
+		Password:     password,
+		SecondFactor: secondFactor,
+	}
+	lockProviderMockBeginWebAuthnRegistration.Lock()
+	mock.calls.BeginWebAuthnRegistration = append(mock.calls.BeginWebAuthnRegistration, callInfo)
+	lockProviderMockBeginWebAuthnRegistration.Unlock()
+	return mock.BeginWebAuthnRegistrationFunc(identifier, password, secondFactor)
+}
+
+// BeginWebAuthnRegistrationCalls gets all the calls that were made to BeginWebAuthnRegistration.
+// Check the length with:
+//     len(mockedProvider.BeginWebAuthnRegistrationCalls())
+func (mock *ProviderMock) BeginWebAuthnRegistrationCalls() []struct {
+	Identifier   string
+	Password     string
+	SecondFactor internal.SecondFactor
+} {
+	var calls []struct {
+		Identifier   string
+		Password     string
+		SecondFactor internal.SecondFactor
+	}
+	lockProviderMockBeginWebAuthnRegistration.RLock()
+	calls = mock.calls.BeginWebAuthnRegistration
+	lockProviderMockBeginWebAuthnRegistration.RUnlock()
+	return calls
+}
+
+// ChangePassword calls ChangePasswordFunc.
+func (mock *ProviderMock) ChangePassword(identifier string, password string, newPassword string) error {
+	if mock.ChangePasswordFunc == nil {
+		panic("ProviderMock.ChangePasswordFunc: method is nil but Provider.ChangePassword was just called")
+	}
+	callInfo := struct {
+		Identifier  string
+		Password    string
+		NewPassword string
+	}{
+		Identifier:  identifier,
+		Password:    password,
+		NewPassword: newPassword,
+	}
+	lockProviderMockChangePassword.Lock()
+	mock.calls.ChangePassword = append(mock.calls.ChangePassword, callInfo)
+	lockProviderMockChangePassword.Unlock()
+	return mock.ChangePasswordFunc(identifier, password, newPassword)
+}
+
+// ChangePasswordCalls gets all the calls that were made to ChangePassword.
+// Check the length with:
+//     len(mockedProvider.ChangePasswordCalls())
+func (mock *ProviderMock) ChangePasswordCalls() []struct {
+	Identifier  string
+	Password    string
+	NewPassword string
+} {
+	var calls []struct {
+		Identifier  string
+		Password    string
+		NewPassword string
+	}
+	lockProviderMockChangePassword.RLock()
+	calls = mock.calls.ChangePassword
+	lockProviderMockChangePassword.RUnlock()
+	return calls
+}
+
+// ConfirmTOTP calls ConfirmTOTPFunc.
+func (mock *ProviderMock) ConfirmTOTP(identifier string, password string, code string, secondFactor internal.SecondFactor) ([]string, error) {
+	if mock.ConfirmTOTPFunc == nil {
+		panic("ProviderMock.ConfirmTOTPFunc: method is nil but Provider.ConfirmTOTP was just called")
+	}
+	callInfo := struct {
+		Identifier   string
+		Password     string
+		Code         string
+		SecondFactor internal.SecondFactor
+	}{
+		Identifier:   identifier,
+		Password:     password,
+		Code:         code,
+		SecondFactor: secondFactor,
+	}
+	lockProviderMockConfirmTOTP.Lock()
+	mock.calls.ConfirmTOTP = append(mock.calls.ConfirmTOTP, callInfo)
+	lockProviderMockConfirmTOTP.Unlock()
+	return mock.ConfirmTOTPFunc(identifier, password, code, secondFactor)
+}
+
+// ConfirmTOTPCalls gets all the calls that were made to ConfirmTOTP.
+// Check the length with:
+//     len(mockedProvider.ConfirmTOTPCalls())
+func (mock *ProviderMock) ConfirmTOTPCalls() []struct {
+	Identifier   string
+	Password     string
+	Code         string
+	SecondFactor internal.SecondFactor
+} {
+	var calls []struct {
+		Identifier   string
+		Password     string
+		Code         string
+		SecondFactor internal.SecondFactor
+	}
+	lockProviderMockConfirmTOTP.RLock()
+	calls = mock.calls.ConfirmTOTP
+	lockProviderMockConfirmTOTP.RUnlock()
+	return calls
+}
+
+// CreateLoginCode calls CreateLoginCodeFunc.
+func (mock *ProviderMock) CreateLoginCode(email string) error {
+	if mock.CreateLoginCodeFunc == nil {
+		panic("ProviderMock.CreateLoginCodeFunc: method is nil but Provider.CreateLoginCode was just called")
+	}
+	callInfo := struct {
+		Email string
+	}{
+		Email: email,
+	}
+	lockProviderMockCreateLoginCode.Lock()
+	mock.calls.CreateLoginCode = append(mock.calls.CreateLoginCode, callInfo)
+	lockProviderMockCreateLoginCode.Unlock()
+	return mock.CreateLoginCodeFunc(email)
+}
+
+// CreateLoginCodeCalls gets all the calls that were made to CreateLoginCode.
+// Check the length with:
+//     len(mockedProvider.CreateLoginCodeCalls())
+func (mock *ProviderMock) CreateLoginCodeCalls() []struct {
+	Email string
+} {
+	var calls []struct {
+		Email string
+	}
+	lockProviderMockCreateLoginCode.RLock()
+	calls = mock.calls.CreateLoginCode
+	lockProviderMockCreateLoginCode.RUnlock()
+	return calls
+}
+
+// CreateMagicLink calls CreateMagicLinkFunc.
+func (mock *ProviderMock) CreateMagicLink(email string) error {
+	if mock.CreateMagicLinkFunc == nil {
+		panic("ProviderMock.CreateMagicLinkFunc: method is nil but Provider.CreateMagicLink was just called")
+	}
+	callInfo := struct {
+		Email string
+	}{
+		Email: email,
+	}
+	lockProviderMockCreateMagicLink.Lock()
+	mock.calls.CreateMagicLink = append(mock.calls.CreateMagicLink, callInfo)
+	lockProviderMockCreateMagicLink.Unlock()
+	return mock.CreateMagicLinkFunc(email)
+}
+
+// CreateMagicLinkCalls gets all the calls that were made to CreateMagicLink.
+// Check the length with:
+//     len(mockedProvider.CreateMagicLinkCalls())
+func (mock *ProviderMock) CreateMagicLinkCalls() []struct {
+	Email string
+} {
+	var calls []struct {
+		Email string
+	}
+	lockProviderMockCreateMagicLink.RLock()
+	calls = mock.calls.CreateMagicLink
+	lockProviderMockCreateMagicLink.RUnlock()
+	return calls
+}
+
 // CreatePasswordResetRequest calls CreatePasswordResetRequestFunc.
 func (mock *ProviderMock) CreatePasswordResetRequest(email string) error {
 	if mock.CreatePasswordResetRequestFunc == nil {
@@ -188,29 +792,29 @@ func (mock *ProviderMock) CreateUserCalls() []struct {
 }
 
 // DeleteUser calls DeleteUserFunc.
-func (mock *ProviderMock) DeleteUser(email string) error {
+func (mock *ProviderMock) DeleteUser(idOrIdentifier string) error {
 	if mock.DeleteUserFunc == nil {
 		panic("ProviderMock.DeleteUserFunc: method is nil but Provider.DeleteUser was just called")
 	}
 	callInfo := struct {
-		Email string
+		IdOrIdentifier string
 	}{
-		Email: email,
+		IdOrIdentifier: idOrIdentifier,
 	}
 	lockProviderMockDeleteUser.Lock()
 	mock.calls.DeleteUser = append(mock.calls.DeleteUser, callInfo)
 	lockProviderMockDeleteUser.Unlock()
-	return mock.DeleteUserFunc(email)
+	return mock.DeleteUserFunc(idOrIdentifier)
 }
 
 // DeleteUserCalls gets all the calls that were made to DeleteUser.
 // Check the length with:
 //     len(mockedProvider.DeleteUserCalls())
 func (mock *ProviderMock) DeleteUserCalls() []struct {
-	Email string
+	IdOrIdentifier string
 } {
 	var calls []struct {
-		Email string
+		IdOrIdentifier string
 	}
 	lockProviderMockDeleteUser.RLock()
 	calls = mock.calls.DeleteUser
@@ -218,30 +822,248 @@ func (mock *ProviderMock) DeleteUserCalls() []struct {
 	return calls
 }
 
+// EnrolTOTP calls EnrolTOTPFunc.
+func (mock *ProviderMock) EnrolTOTP(identifier string, password string, secondFactor internal.SecondFactor) (internal.TOTPEnrolment, error) {
+	if mock.EnrolTOTPFunc == nil {
+		panic("ProviderMock.EnrolTOTPFunc: method is nil but Provider.EnrolTOTP was just called")
+	}
+	callInfo := struct {
+		Identifier   string
+		Password     string
+		SecondFactor internal.SecondFactor
+	}{
+		Identifier:   identifier,
+		Password:     password,
+		SecondFactor: secondFactor,
+	}
+	lockProviderMockEnrolTOTP.Lock()
+	mock.calls.EnrolTOTP = append(mock.calls.EnrolTOTP, callInfo)
+	lockProviderMockEnrolTOTP.Unlock()
+	return mock.EnrolTOTPFunc(identifier, password, secondFactor)
+}
+
+// EnrolTOTPCalls gets all the calls that were made to EnrolTOTP.
+// Check the length with:
+//     len(mockedProvider.EnrolTOTPCalls())
+func (mock *ProviderMock) EnrolTOTPCalls() []struct {
+	Identifier   string
+	Password     string
+	SecondFactor internal.SecondFactor
+} {
+	var calls []struct {
+		Identifier   string
+		Password     string
+		SecondFactor internal.SecondFactor
+	}
+	lockProviderMockEnrolTOTP.RLock()
+	calls = mock.calls.EnrolTOTP
+	lockProviderMockEnrolTOTP.RUnlock()
+	return calls
+}
+
+// EraseUser calls EraseUserFunc.
+func (mock *ProviderMock) EraseUser(idOrIdentifier string) (internal.UserErasure, error) {
+	if mock.EraseUserFunc == nil {
+		panic("ProviderMock.EraseUserFunc: method is nil but Provider.EraseUser was just called")
+	}
+	callInfo := struct {
+		IdOrIdentifier string
+	}{
+		IdOrIdentifier: idOrIdentifier,
+	}
+	lockProviderMockEraseUser.Lock()
+	mock.calls.EraseUser = append(mock.calls.EraseUser, callInfo)
+	lockProviderMockEraseUser.Unlock()
+	return mock.EraseUserFunc(idOrIdentifier)
+}
+
+// EraseUserCalls gets all the calls that were made to EraseUser.
+// Check the length with:
+//     len(mockedProvider.EraseUserCalls())
+func (mock *ProviderMock) EraseUserCalls() []struct {
+	IdOrIdentifier string
+} {
+	var calls []struct {
+		IdOrIdentifier string
+	}
+	lockProviderMockEraseUser.RLock()
+	calls = mock.calls.EraseUser
+	lockProviderMockEraseUser.RUnlock()
+	return calls
+}
+
+// ExportUser calls ExportUserFunc.
+func (mock *ProviderMock) ExportUser(idOrIdentifier string) (internal.UserExport, error) {
+	if mock.ExportUserFunc == nil {
+		panic("ProviderMock.ExportUserFunc: method is nil but Provider.ExportUser was just called")
+	}
+	callInfo := struct {
+		IdOrIdentifier string
+	}{
+		IdOrIdentifier: idOrIdentifier,
+	}
+	lockProviderMockExportUser.Lock()
+	mock.calls.ExportUser = append(mock.calls.ExportUser, callInfo)
+	lockProviderMockExportUser.Unlock()
+	return mock.ExportUserFunc(idOrIdentifier)
+}
+
+// ExportUserCalls gets all the calls that were made to ExportUser.
+// Check the length with:
+//     len(mockedProvider.ExportUserCalls())
+func (mock *ProviderMock) ExportUserCalls() []struct {
+	IdOrIdentifier string
+} {
+	var calls []struct {
+		IdOrIdentifier string
+	}
+	lockProviderMockExportUser.RLock()
+	calls = mock.calls.ExportUser
+	lockProviderMockExportUser.RUnlock()
+	return calls
+}
+
+// FindUsers calls FindUsersFunc.
+func (mock *ProviderMock) FindUsers(predicates []internal.ClaimPredicate, limit int, offset int) (internal.UserPage, error) {
+	if mock.FindUsersFunc == nil {
+		panic("ProviderMock.FindUsersFunc: method is nil but Provider.FindUsers was just called")
+	}
+	callInfo := struct {
+		Predicates []internal.ClaimPredicate
+		Limit      int
+		Offset     int
+	}{
+		Predicates: predicates,
+		Limit:      limit,
+		Offset:     offset,
+	}
+	lockProviderMockFindUsers.Lock()
+	mock.calls.FindUsers = append(mock.calls.FindUsers, callInfo)
+	lockProviderMockFindUsers.Unlock()
+	return mock.FindUsersFunc(predicates, limit, offset)
+}
+
+// FindUsersCalls gets all the calls that were made to FindUsers.
+// Check the length with:
+//     len(mockedProvider.FindUsersCalls())
+func (mock *ProviderMock) FindUsersCalls() []struct {
+	Predicates []internal.ClaimPredicate
+	Limit      int
+	Offset     int
+} {
+	var calls []struct {
+		Predicates []internal.ClaimPredicate
+		Limit      int
+		Offset     int
+	}
+	lockProviderMockFindUsers.RLock()
+	calls = mock.calls.FindUsers
+	lockProviderMockFindUsers.RUnlock()
+	return calls
+}
+
+// FinishWebAuthnLogin calls FinishWebAuthnLoginFunc.
+func (mock *ProviderMock) FinishWebAuthnLogin(identifier string, mfaToken string, r webauthn.AssertionResponse, client internal.Client) (string, error) {
+	if mock.FinishWebAuthnLoginFunc == nil {
+		panic("ProviderMock.FinishWebAuthnLoginFunc: method is nil but Provider.FinishWebAuthnLogin was just called")
+	}
+	callInfo := struct {
+		Identifier string
+		MfaToken   string
+		R          webauthn.AssertionResponse
+		Client     internal.Client
+	}{
+		Identifier: identifier,
+		MfaToken:   mfaToken,
+		R:          r,
+		Client:     client,
+	}
+	lockProviderMockFinishWebAuthnLogin.Lock()
+	mock.calls.FinishWebAuthnLogin = append(mock.calls.FinishWebAuthnLogin, callInfo)
+	lockProviderMockFinishWebAuthnLogin.Unlock()
+	return mock.FinishWebAuthnLoginFunc(identifier, mfaToken, r, client)
+}
+
+// FinishWebAuthnLoginCalls gets all the calls that were made to FinishWebAuthnLogin.
+// Check the length with:
+//     len(mockedProvider.FinishWebAuthnLoginCalls())
+func (mock *ProviderMock) FinishWebAuthnLoginCalls() []struct {
+	Identifier string
+	MfaToken   string
+	R          webauthn.AssertionResponse
+	Client     internal.Client
+} {
+	var calls []struct {
+		Identifier string
+		MfaToken   string
+		R          webauthn.AssertionResponse
+		Client     internal.Client
+	}
+	lockProviderMockFinishWebAuthnLogin.RLock()
+	calls = mock.calls.FinishWebAuthnLogin
+	lockProviderMockFinishWebAuthnLogin.RUnlock()
+	return calls
+}
+
+// FinishWebAuthnRegistration calls FinishWebAuthnRegistrationFunc.
+func (mock *ProviderMock) FinishWebAuthnRegistration(identifier string, r webauthn.AttestationResponse) ([]string, error) {
+	if mock.FinishWebAuthnRegistrationFunc == nil {
+		panic("ProviderMock.FinishWebAuthnRegistrationFunc: method is nil but Provider.FinishWebAuthnRegistration was just called")
+	}
+	callInfo := struct {
+		Identifier string
+		R          webauthn.AttestationResponse
+	}{
+		Identifier: identifier,
+		R:          r,
+	}
+	lockProviderMockFinishWebAuthnRegistration.Lock()
+	mock.calls.FinishWebAuthnRegistration = append(mock.calls.FinishWebAuthnRegistration, callInfo)
+	lockProviderMockFinishWebAuthnRegistration.Unlock()
+	return mock.FinishWebAuthnRegistrationFunc(identifier, r)
+}
+
+// FinishWebAuthnRegistrationCalls gets all the calls that were made to FinishWebAuthnRegistration.
+// Check the length with:
+//     len(mockedProvider.FinishWebAuthnRegistrationCalls())
+func (mock *ProviderMock) FinishWebAuthnRegistrationCalls() []struct {
+	Identifier string
+	R          webauthn.AttestationResponse
+} {
+	var calls []struct {
+		Identifier string
+		R          webauthn.AttestationResponse
+	}
+	lockProviderMockFinishWebAuthnRegistration.RLock()
+	calls = mock.calls.FinishWebAuthnRegistration
+	lockProviderMockFinishWebAuthnRegistration.RUnlock()
+	return calls
+}
+
 // GetUser calls GetUserFunc.
-func (mock *ProviderMock) GetUser(email string) (internal.User, error) {
+func (mock *ProviderMock) GetUser(idOrIdentifier string) (internal.User, error) {
 	if mock.GetUserFunc == nil {
 		panic("ProviderMock.GetUserFunc: method is nil but Provider.GetUser was just called")
 	}
 	callInfo := struct {
-		Email string
+		IdOrIdentifier string
 	}{
-		Email: email,
+		IdOrIdentifier: idOrIdentifier,
 	}
 	lockProviderMockGetUser.Lock()
 	mock.calls.GetUser = append(mock.calls.GetUser, callInfo)
 	lockProviderMockGetUser.Unlock()
-	return mock.GetUserFunc(email)
+	return mock.GetUserFunc(idOrIdentifier)
 }
 
 // GetUserCalls gets all the calls that were made to GetUser.
 // Check the length with:
 //     len(mockedProvider.GetUserCalls())
 func (mock *ProviderMock) GetUserCalls() []struct {
-	Email string
+	IdOrIdentifier string
 } {
 	var calls []struct {
-		Email string
+		IdOrIdentifier string
 	}
 	lockProviderMockGetUser.RLock()
 	calls = mock.calls.GetUser
@@ -250,33 +1072,37 @@ func (mock *ProviderMock) GetUserCalls() []struct {
 }
 
 // Login calls LoginFunc.
-func (mock *ProviderMock) Login(email string, password string) (string, error) {
+func (mock *ProviderMock) Login(identifier string, password string, client internal.Client) (internal.LoginResult, error) {
 	if mock.LoginFunc == nil {
 		panic("ProviderMock.LoginFunc: method is nil but Provider.Login was just called")
 	}
 	callInfo := struct {
-		Email    string
-		Password string
+		Identifier string
+		Password   string
+		Client     internal.Client
 	}{
-		Email:    email,
-		Password: password,
+		Identifier: identifier,
+		Password:   password,
+		Client:     client,
 	}
 	lockProviderMockLogin.Lock()
 	mock.calls.Login = append(mock.calls.Login, callInfo)
 	lockProviderMockLogin.Unlock()
-	return mock.LoginFunc(email, password)
+	return mock.LoginFunc(identifier, password, client)
 }
 
 // LoginCalls gets all the calls that were made to Login.
 // Check the length with:
 //     len(mockedProvider.LoginCalls())
 func (mock *ProviderMock) LoginCalls() []struct {
-	Email    string
-	Password string
+	Identifier string
+	Password   string
+	Client     internal.Client
 } {
 	var calls []struct {
-		Email    string
-		Password string
+		Identifier string
+		Password   string
+		Client     internal.Client
 	}
 	lockProviderMockLogin.RLock()
 	calls = mock.calls.Login
@@ -284,6 +1110,349 @@ func (mock *ProviderMock) LoginCalls() []struct {
 	return calls
 }
 
+// LoginMFA calls LoginMFAFunc.
+func (mock *ProviderMock) LoginMFA(identifier string, mfaToken string, code string, client internal.Client) (string, error) {
+	if mock.LoginMFAFunc == nil {
+		panic("ProviderMock.LoginMFAFunc: method is nil but Provider.LoginMFA was just called")
+	}
+	callInfo := struct {
+		Identifier string
+		MfaToken   string
+		Code       string
+		Client     internal.Client
+	}{
+		Identifier: identifier,
+		MfaToken:   mfaToken,
+		Code:       code,
+		Client:     client,
+	}
+	lockProviderMockLoginMFA.Lock()
+	mock.calls.LoginMFA = append(mock.calls.LoginMFA, callInfo)
+	lockProviderMockLoginMFA.Unlock()
+	return mock.LoginMFAFunc(identifier, mfaToken, code, client)
+}
+
+// LoginMFACalls gets all the calls that were made to LoginMFA.
+// Check the length with:
+//     len(mockedProvider.LoginMFACalls())
+func (mock *ProviderMock) LoginMFACalls() []struct {
+	Identifier string
+	MfaToken   string
+	Code       string
+	Client     internal.Client
+} {
+	var calls []struct {
+		Identifier string
+		MfaToken   string
+		Code       string
+		Client     internal.Client
+	}
+	lockProviderMockLoginMFA.RLock()
+	calls = mock.calls.LoginMFA
+	lockProviderMockLoginMFA.RUnlock()
+	return calls
+}
+
+// LoginMagicLink calls LoginMagicLinkFunc.
+func (mock *ProviderMock) LoginMagicLink(email string, magicLinkToken string, client internal.Client) (internal.LoginResult, error) {
+	if mock.LoginMagicLinkFunc == nil {
+		panic("ProviderMock.LoginMagicLinkFunc: method is nil but Provider.LoginMagicLink was just called")
+	}
+	callInfo := struct {
+		Email          string
+		MagicLinkToken string
+		Client         internal.Client
+	}{
+		Email:          email,
+		MagicLinkToken: magicLinkToken,
+		Client:         client,
+	}
+	lockProviderMockLoginMagicLink.Lock()
+	mock.calls.LoginMagicLink = append(mock.calls.LoginMagicLink, callInfo)
+	lockProviderMockLoginMagicLink.Unlock()
+	return mock.LoginMagicLinkFunc(email, magicLinkToken, client)
+}
+
+// LoginMagicLinkCalls gets all the calls that were made to LoginMagicLink.
+// Check the length with:
+//     len(mockedProvider.LoginMagicLinkCalls())
+func (mock *ProviderMock) LoginMagicLinkCalls() []struct {
+	Email          string
+	MagicLinkToken string
+	Client         internal.Client
+} {
+	var calls []struct {
+		Email          string
+		MagicLinkToken string
+		Client         internal.Client
+	}
+	lockProviderMockLoginMagicLink.RLock()
+	calls = mock.calls.LoginMagicLink
+	lockProviderMockLoginMagicLink.RUnlock()
+	return calls
+}
+
+// LoginRecoveryCode calls LoginRecoveryCodeFunc.
+func (mock *ProviderMock) LoginRecoveryCode(identifier string, mfaToken string, recoveryCode string, client internal.Client) (string, error) {
+	if mock.LoginRecoveryCodeFunc == nil {
+		panic("ProviderMock.LoginRecoveryCodeFunc: method is nil but Provider.LoginRecoveryCode was just called")
+	}
+	callInfo := struct {
+		Identifier   string
+		MfaToken     string
+		RecoveryCode string
+		Client       internal.Client
+	}{
+		Identifier:   identifier,
+		MfaToken:     mfaToken,
+		RecoveryCode: recoveryCode,
+		Client:       client,
+	}
+	lockProviderMockLoginRecoveryCode.Lock()
+	mock.calls.LoginRecoveryCode = append(mock.calls.LoginRecoveryCode, callInfo)
+	lockProviderMockLoginRecoveryCode.Unlock()
+	return mock.LoginRecoveryCodeFunc(identifier, mfaToken, recoveryCode, client)
+}
+
+// LoginRecoveryCodeCalls gets all the calls that were made to LoginRecoveryCode.
+// Check the length with:
+//     len(mockedProvider.LoginRecoveryCodeCalls())
+func (mock *ProviderMock) LoginRecoveryCodeCalls() []struct {
+	Identifier   string
+	MfaToken     string
+	RecoveryCode string
+	Client       internal.Client
+} {
+	var calls []struct {
+		Identifier   string
+		MfaToken     string
+		RecoveryCode string
+		Client       internal.Client
+	}
+	lockProviderMockLoginRecoveryCode.RLock()
+	calls = mock.calls.LoginRecoveryCode
+	lockProviderMockLoginRecoveryCode.RUnlock()
+	return calls
+}
+
+// LoginWithCode calls LoginWithCodeFunc.
+func (mock *ProviderMock) LoginWithCode(email string, code string, client internal.Client) (internal.LoginResult, error) {
+	if mock.LoginWithCodeFunc == nil {
+		panic("ProviderMock.LoginWithCodeFunc: method is nil but Provider.LoginWithCode was just called")
+	}
+	callInfo := struct {
+		Email  string
+		Code   string
+		Client internal.Client
+	}{
+		Email:  email,
+		Code:   code,
+		Client: client,
+	}
+	lockProviderMockLoginWithCode.Lock()
+	mock.calls.LoginWithCode = append(mock.calls.LoginWithCode, callInfo)
+	lockProviderMockLoginWithCode.Unlock()
+	return mock.LoginWithCodeFunc(email, code, client)
+}
+
+// LoginWithCodeCalls gets all the calls that were made to LoginWithCode.
+// Check the length with:
+//     len(mockedProvider.LoginWithCodeCalls())
+func (mock *ProviderMock) LoginWithCodeCalls() []struct {
+	Email  string
+	Code   string
+	Client internal.Client
+} {
+	var calls []struct {
+		Email  string
+		Code   string
+		Client internal.Client
+	}
+	lockProviderMockLoginWithCode.RLock()
+	calls = mock.calls.LoginWithCode
+	lockProviderMockLoginWithCode.RUnlock()
+	return calls
+}
+
+// Logins calls LoginsFunc.
+func (mock *ProviderMock) Logins(idOrIdentifier string, limit int, offset int) (internal.LoginHistory, error) {
+	if mock.LoginsFunc == nil {
+		panic("ProviderMock.LoginsFunc: method is nil but Provider.Logins was just called")
+	}
+	callInfo := struct {
+		IdOrIdentifier string
+		Limit          int
+		Offset         int
+	}{
+		IdOrIdentifier: idOrIdentifier,
+		Limit:          limit,
+		Offset:         offset,
+	}
+	lockProviderMockLogins.Lock()
+	mock.calls.Logins = append(mock.calls.Logins, callInfo)
+	lockProviderMockLogins.Unlock()
+	return mock.LoginsFunc(idOrIdentifier, limit, offset)
+}
+
+// LoginsCalls gets all the calls that were made to Logins.
+// Check the length with:
+//     len(mockedProvider.LoginsCalls())
+func (mock *ProviderMock) LoginsCalls() []struct {
+	IdOrIdentifier string
+	Limit          int
+	Offset         int
+} {
+	var calls []struct {
+		IdOrIdentifier string
+		Limit          int
+		Offset         int
+	}
+	lockProviderMockLogins.RLock()
+	calls = mock.calls.Logins
+	lockProviderMockLogins.RUnlock()
+	return calls
+}
+
+// PatchUser calls PatchUserFunc.
+func (mock *ProviderMock) PatchUser(idOrIdentifier string, format internal.PatchFormat, patch []byte) (internal.User, error) {
+	if mock.PatchUserFunc == nil {
+		panic("ProviderMock.PatchUserFunc: method is nil but Provider.PatchUser was just called")
+	}
+	callInfo := struct {
+		IdOrIdentifier string
+		Format         internal.PatchFormat
+		Patch          []byte
+	}{
+		IdOrIdentifier: idOrIdentifier,
+		Format:         format,
+		Patch:          patch,
+	}
+	lockProviderMockPatchUser.Lock()
+	mock.calls.PatchUser = append(mock.calls.PatchUser, callInfo)
+	lockProviderMockPatchUser.Unlock()
+	return mock.PatchUserFunc(idOrIdentifier, format, patch)
+}
+
+// PatchUserCalls gets all the calls that were made to PatchUser.
+// Check the length with:
+//     len(mockedProvider.PatchUserCalls())
+func (mock *ProviderMock) PatchUserCalls() []struct {
+	IdOrIdentifier string
+	Format         internal.PatchFormat
+	Patch          []byte
+} {
+	var calls []struct {
+		IdOrIdentifier string
+		Format         internal.PatchFormat
+		Patch          []byte
+	}
+	lockProviderMockPatchUser.RLock()
+	calls = mock.calls.PatchUser
+	lockProviderMockPatchUser.RUnlock()
+	return calls
+}
+
+// RegenerateRecoveryCodes calls RegenerateRecoveryCodesFunc.
+func (mock *ProviderMock) RegenerateRecoveryCodes(identifier string, password string, code string) ([]string, error) {
+	if mock.RegenerateRecoveryCodesFunc == nil {
+		panic("ProviderMock.RegenerateRecoveryCodesFunc: method is nil but Provider.RegenerateRecoveryCodes was just called")
+	}
+	callInfo := struct {
+		Identifier string
+		Password   string
+		Code       string
+	}{
+		Identifier: identifier,
+		Password:   password,
+		Code:       code,
+	}
+	lockProviderMockRegenerateRecoveryCodes.Lock()
+	mock.calls.RegenerateRecoveryCodes = append(mock.calls.RegenerateRecoveryCodes, callInfo)
+	lockProviderMockRegenerateRecoveryCodes.Unlock()
+	return mock.RegenerateRecoveryCodesFunc(identifier, password, code)
+}
+
+// RegenerateRecoveryCodesCalls gets all the calls that were made to RegenerateRecoveryCodes.
+// Check the length with:
+//     len(mockedProvider.RegenerateRecoveryCodesCalls())
+func (mock *ProviderMock) RegenerateRecoveryCodesCalls() []struct {
+	Identifier string
+	Password   string
+	Code       string
+} {
+	var calls []struct {
+		Identifier string
+		Password   string
+		Code       string
+	}
+	lockProviderMockRegenerateRecoveryCodes.RLock()
+	calls = mock.calls.RegenerateRecoveryCodes
+	lockProviderMockRegenerateRecoveryCodes.RUnlock()
+	return calls
+}
+
+// ResendInvitation calls ResendInvitationFunc.
+func (mock *ProviderMock) ResendInvitation(idOrIdentifier string) error {
+	if mock.ResendInvitationFunc == nil {
+		panic("ProviderMock.ResendInvitationFunc: method is nil but Provider.ResendInvitation was just called")
+	}
+	callInfo := struct {
+		IdOrIdentifier string
+	}{
+		IdOrIdentifier: idOrIdentifier,
+	}
+	lockProviderMockResendInvitation.Lock()
+	mock.calls.ResendInvitation = append(mock.calls.ResendInvitation, callInfo)
+	lockProviderMockResendInvitation.Unlock()
+	return mock.ResendInvitationFunc(idOrIdentifier)
+}
+
+// ResendInvitationCalls gets all the calls that were made to ResendInvitation.
+// Check the length with:
+//     len(mockedProvider.ResendInvitationCalls())
+func (mock *ProviderMock) ResendInvitationCalls() []struct {
+	IdOrIdentifier string
+} {
+	var calls []struct {
+		IdOrIdentifier string
+	}
+	lockProviderMockResendInvitation.RLock()
+	calls = mock.calls.ResendInvitation
+	lockProviderMockResendInvitation.RUnlock()
+	return calls
+}
+
+// ResetMFA calls ResetMFAFunc.
+func (mock *ProviderMock) ResetMFA(idOrIdentifier string) error {
+	if mock.ResetMFAFunc == nil {
+		panic("ProviderMock.ResetMFAFunc: method is nil but Provider.ResetMFA was just called")
+	}
+	callInfo := struct {
+		IdOrIdentifier string
+	}{
+		IdOrIdentifier: idOrIdentifier,
+	}
+	lockProviderMockResetMFA.Lock()
+	mock.calls.ResetMFA = append(mock.calls.ResetMFA, callInfo)
+	lockProviderMockResetMFA.Unlock()
+	return mock.ResetMFAFunc(idOrIdentifier)
+}
+
+// ResetMFACalls gets all the calls that were made to ResetMFA.
+// Check the length with:
+//     len(mockedProvider.ResetMFACalls())
+func (mock *ProviderMock) ResetMFACalls() []struct {
+	IdOrIdentifier string
+} {
+	var calls []struct {
+		IdOrIdentifier string
+	}
+	lockProviderMockResetMFA.RLock()
+	calls = mock.calls.ResetMFA
+	lockProviderMockResetMFA.RUnlock()
+	return calls
+}
+
 // ResetPassword calls ResetPasswordFunc.
 func (mock *ProviderMock) ResetPassword(email string, resetToken string, password string) error {
 	if mock.ResetPasswordFunc == nil {
@@ -324,36 +1493,67 @@ func (mock *ProviderMock) ResetPasswordCalls() []struct {
 }
 
 // UpdateUser calls UpdateUserFunc.
-func (mock *ProviderMock) UpdateUser(email string, user internal.User) (internal.User, error) {
+func (mock *ProviderMock) UpdateUser(idOrIdentifier string, user internal.User) (internal.User, error) {
 	if mock.UpdateUserFunc == nil {
 		panic("ProviderMock.UpdateUserFunc: method is nil but Provider.UpdateUser was just called")
 	}
 	callInfo := struct {
-		Email string
-		User  internal.User
+		IdOrIdentifier string
+		User           internal.User
 	}{
-		Email: email,
-		User:  user,
+		IdOrIdentifier: idOrIdentifier,
+		User:           user,
 	}
 	lockProviderMockUpdateUser.Lock()
 	mock.calls.UpdateUser = append(mock.calls.UpdateUser, callInfo)
 	lockProviderMockUpdateUser.Unlock()
-	return mock.UpdateUserFunc(email, user)
+	return mock.UpdateUserFunc(idOrIdentifier, user)
 }
 
 // UpdateUserCalls gets all the calls that were made to UpdateUser.
 // Check the length with:
 //     len(mockedProvider.UpdateUserCalls())
 func (mock *ProviderMock) UpdateUserCalls() []struct {
-	Email string
-	User  internal.User
+	IdOrIdentifier string
+	User           internal.User
 } {
 	var calls []struct {
-		Email string
-		User  internal.User
+		IdOrIdentifier string
+		User           internal.User
 	}
 	lockProviderMockUpdateUser.RLock()
 	calls = mock.calls.UpdateUser
 	lockProviderMockUpdateUser.RUnlock()
 	return calls
 }
+
+// VerifyAccessToken calls VerifyAccessTokenFunc.
+func (mock *ProviderMock) VerifyAccessToken(accessToken string) (string, error) {
+	if mock.VerifyAccessTokenFunc == nil {
+		panic("ProviderMock.VerifyAccessTokenFunc: method is nil but Provider.VerifyAccessToken was just called")
+	}
+	callInfo := struct {
+		AccessToken string
+	}{
+		AccessToken: accessToken,
+	}
+	lockProviderMockVerifyAccessToken.Lock()
+	mock.calls.VerifyAccessToken = append(mock.calls.VerifyAccessToken, callInfo)
+	lockProviderMockVerifyAccessToken.Unlock()
+	return mock.VerifyAccessTokenFunc(accessToken)
+}
+
+// VerifyAccessTokenCalls gets all the calls that were made to VerifyAccessToken.
+// Check the length with:
+//     len(mockedProvider.VerifyAccessTokenCalls())
+func (mock *ProviderMock) VerifyAccessTokenCalls() []struct {
+	AccessToken string
+} {
+	var calls []struct {
+		AccessToken string
+	}
+	lockProviderMockVerifyAccessToken.RLock()
+	calls = mock.calls.VerifyAccessToken
+	lockProviderMockVerifyAccessToken.RUnlock()
+	return calls
+}
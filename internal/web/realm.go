@@ -0,0 +1,254 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/leberKleber/simple-jwt-provider/internal"
+	"github.com/sirupsen/logrus"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const realmPathPrefix = "/v1/realms/"
+
+//go:generate moq -out realms_moq_test.go . Realms
+type Realms interface {
+	Provider(realm string) (Provider, error)
+	RealmByHost(host string) (string, bool)
+	List() ([]internal.Realm, error)
+	Realm(name string) (internal.Realm, error)
+	CreateRealm(realm internal.Realm) (internal.Realm, error)
+	UpdateRealm(name string, realm internal.Realm) (internal.Realm, error)
+}
+
+// Realm is the representation of a realm for use in web
+type Realm struct {
+	Name          string     `json:"name"`
+	Hosts         []string   `json:"hosts"`
+	JWTIssuer     *string    `json:"jwt_issuer,omitempty"`
+	JWTAudience   *string    `json:"jwt_audience,omitempty"`
+	JWTPrivateKey string     `json:"jwt_private_key,omitempty"`
+	JWTPublicKey  string     `json:"jwt_public_key,omitempty"`
+	CreatedAt     *time.Time `json:"created_at,omitempty"`
+}
+
+// toWebRealm converts the given internal.Realm to a Realm
+func toWebRealm(r internal.Realm) Realm {
+	realm := Realm{
+		Name:         r.Name,
+		Hosts:        r.Hosts,
+		JWTIssuer:    r.JWTIssuer,
+		JWTAudience:  r.JWTAudience,
+		JWTPublicKey: r.JWTPublicKey,
+	}
+	if realm.Hosts == nil {
+		realm.Hosts = []string{}
+	}
+	if !r.CreatedAt.IsZero() {
+		createdAt := r.CreatedAt
+		realm.CreatedAt = &createdAt
+	}
+
+	return realm
+}
+
+// realmHandler serves all requests with the url prefix '/v1/realms/{realm}' or with the host of a realm by the Server
+// of the realm and all other requests by the given default handler
+func (s *Server) realmHandler(defaultHandler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, realmPathPrefix) {
+			path := strings.TrimPrefix(r.URL.Path, realmPathPrefix)
+			i := strings.Index(path, "/")
+			if i < 0 {
+				notFoundHandler(w, r)
+				return
+			}
+
+			s.serveRealm(w, r, path[:i], "/v1"+path[i:])
+			return
+		}
+
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+
+		realm, ok := s.realms.RealmByHost(host)
+		if ok {
+			s.serveRealm(w, r, realm, r.URL.Path)
+			return
+		}
+
+		defaultHandler.ServeHTTP(w, r)
+	})
+}
+
+// serveRealm serves the given request with the given path by the Server of the given realm
+func (s *Server) serveRealm(w http.ResponseWriter, r *http.Request, realm, path string) {
+	realmServer, err := s.realmServer(realm)
+	if err != nil {
+		if errors.Is(err, internal.ErrRealmNotFound) {
+			writeError(w, http.StatusNotFound, "realm not found")
+			return
+		}
+
+		logrus.WithError(err).WithField("realm", realm).Error("Failed to get provider of realm")
+		writeInternalServerError(w)
+		return
+	}
+
+	u := *r.URL
+	u.Path = path
+	u.RawPath = ""
+	realmRequest := r.WithContext(r.Context())
+	realmRequest.URL = &u
+
+	realmServer.h.ServeHTTP(w, realmRequest)
+}
+
+// realmServer returns the Server of the given realm. It will be created once per provider, so realms with replaced
+// providers (e.g. after an update) get a new Server.
+// return internal.ErrRealmNotFound when realm does not exist
+func (s *Server) realmServer(realm string) (*Server, error) {
+	p, err := s.realms.Provider(realm)
+	if err != nil {
+		return nil, err
+	}
+
+	s.realmServersMu.Lock()
+	defer s.realmServersMu.Unlock()
+
+	realmServer, ok := s.realmServers[realm]
+	if !ok || realmServer.p != p {
+		realmServer = NewServer(p, s.enableAdminAPI, s.adminAPIUsername, s.adminAPIPassword)
+		s.realmServers[realm] = realmServer
+	}
+
+	return realmServer, nil
+}
+
+func (s *Server) createRealmHandler(w http.ResponseWriter, r *http.Request) {
+	var realm Realm
+	err := json.NewDecoder(r.Body).Decode(&realm)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	createdRealm, err := s.realms.CreateRealm(internal.Realm{
+		Name:          realm.Name,
+		Hosts:         realm.Hosts,
+		JWTIssuer:     realm.JWTIssuer,
+		JWTAudience:   realm.JWTAudience,
+		JWTPrivateKey: realm.JWTPrivateKey,
+	})
+	if err != nil {
+		if errors.Is(err, internal.ErrInvalidRealmName) || errors.Is(err, internal.ErrInvalidRealmHost) ||
+			errors.Is(err, internal.ErrInvalidJWTPrivateKey) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, internal.ErrRealmAlreadyExists) || errors.Is(err, internal.ErrRealmHostAlreadyUsed) {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+
+		logrus.WithError(err).Error("Failed to create Realm")
+		writeInternalServerError(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeRealm(w, createdRealm)
+}
+
+func (s *Server) listRealmsHandler(w http.ResponseWriter, _ *http.Request) {
+	realms, err := s.realms.List()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list Realms")
+		writeInternalServerError(w)
+		return
+	}
+
+	webRealms := make([]Realm, 0, len(realms))
+	for _, realm := range realms {
+		webRealms = append(webRealms, toWebRealm(realm))
+	}
+
+	err = json.NewEncoder(w).Encode(webRealms)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to encode Realms")
+		writeInternalServerError(w)
+		return
+	}
+}
+
+func (s *Server) getRealmHandler(w http.ResponseWriter, r *http.Request) {
+	realm, err := s.realms.Realm(mux.Vars(r)["realm"])
+	if err != nil {
+		if errors.Is(err, internal.ErrRealmNotFound) {
+			writeError(w, http.StatusNotFound, "realm not found")
+			return
+		}
+
+		logrus.WithError(err).Error("Failed to get Realm")
+		writeInternalServerError(w)
+		return
+	}
+
+	writeRealm(w, realm)
+}
+
+func (s *Server) updateRealmHandler(w http.ResponseWriter, r *http.Request) {
+	var realm Realm
+	err := json.NewDecoder(r.Body).Decode(&realm)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	name := mux.Vars(r)["realm"]
+	if realm.Name != "" && realm.Name != name {
+		writeError(w, http.StatusBadRequest, "name can not be changed")
+		return
+	}
+
+	updatedRealm, err := s.realms.UpdateRealm(name, internal.Realm{
+		Hosts:         realm.Hosts,
+		JWTIssuer:     realm.JWTIssuer,
+		JWTAudience:   realm.JWTAudience,
+		JWTPrivateKey: realm.JWTPrivateKey,
+	})
+	if err != nil {
+		if errors.Is(err, internal.ErrRealmNotFound) {
+			writeError(w, http.StatusNotFound, "realm not found")
+			return
+		}
+		if errors.Is(err, internal.ErrInvalidRealmHost) || errors.Is(err, internal.ErrInvalidJWTPrivateKey) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, internal.ErrRealmHostAlreadyUsed) {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+
+		logrus.WithError(err).Error("Failed to update Realm")
+		writeInternalServerError(w)
+		return
+	}
+
+	writeRealm(w, updatedRealm)
+}
+
+func writeRealm(w http.ResponseWriter, realm internal.Realm) {
+	err := json.NewEncoder(w).Encode(toWebRealm(realm))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to encode Realm")
+		writeInternalServerError(w)
+		return
+	}
+}
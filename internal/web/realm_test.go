@@ -0,0 +1,463 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRealmHandler(t *testing.T) {
+	tests := []struct {
+		name                 string
+		requestPath          string
+		requestHost          string
+		realmError           error
+		expectedRealm        string
+		expectedResponseCode int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "Default realm",
+			requestPath:          "/v1/auth/login",
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"access_token":"default"}`,
+		},
+		{
+			name:                 "Realm by url prefix",
+			requestPath:          "/v1/realms/my-realm/auth/login",
+			expectedRealm:        "my-realm",
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"access_token":"my-realm"}`,
+		},
+		{
+			name:                 "Realm by host",
+			requestPath:          "/v1/auth/login",
+			requestHost:          "login.my-realm.test:8080",
+			expectedRealm:        "my-realm",
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"access_token":"my-realm"}`,
+		},
+		{
+			name:                 "Unknown host",
+			requestPath:          "/v1/auth/login",
+			requestHost:          "login.unknown.test",
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"access_token":"default"}`,
+		},
+		{
+			name:                 "Realm not found",
+			requestPath:          "/v1/realms/unknown/auth/login",
+			realmError:           internal.ErrRealmNotFound,
+			expectedRealm:        "unknown",
+			expectedResponseCode: http.StatusNotFound,
+			expectedResponseBody: `{"message":"realm not found"}`,
+		},
+		{
+			name:                 "Missing path after realm",
+			requestPath:          "/v1/realms/my-realm",
+			expectedResponseCode: http.StatusNotFound,
+			expectedResponseBody: `{"message":"endpoint not found"}`,
+		},
+		{
+			name:                 "Unexpected error",
+			requestPath:          "/v1/realms/my-realm/auth/login",
+			realmError:           errors.New("nope"),
+			expectedRealm:        "my-realm",
+			expectedResponseCode: http.StatusInternalServerError,
+			expectedResponseBody: `{"message":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenRealm string
+
+			toTest := NewRealmServer(&ProviderMock{
+				LoginFunc: func(identifier, password string, client internal.Client) (internal.LoginResult, error) {
+					return internal.LoginResult{AccessToken: "default"}, nil
+				},
+			}, &RealmsMock{
+				ProviderFunc: func(realm string) (Provider, error) {
+					givenRealm = realm
+					return &ProviderMock{
+						LoginFunc: func(identifier, password string, client internal.Client) (internal.LoginResult, error) {
+							return internal.LoginResult{AccessToken: realm}, nil
+						},
+					}, tt.realmError
+				},
+				RealmByHostFunc: func(host string) (string, bool) {
+					return "my-realm", host == "login.my-realm.test"
+				},
+			}, nil, false, "", "")
+			testServer := httptest.NewServer(toTest.h)
+
+			bb := bytes.NewReader([]byte(`{"email": "test.test@test.test", "password": "s3cr3t"}`))
+			req, err := http.NewRequest(http.MethodPost, testServer.URL+tt.requestPath, bb)
+			if err != nil {
+				t.Fatalf("Failed to build http request: %s", err)
+			}
+			if tt.requestHost != "" {
+				req.Host = tt.requestHost
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to call server cause: %s", err)
+			}
+			defer resp.Body.Close()
+
+			if givenRealm != tt.expectedRealm {
+				t.Errorf("Unexpected realm. Expected: %q, Given: %q", tt.expectedRealm, givenRealm)
+			}
+
+			if resp.StatusCode != tt.expectedResponseCode {
+				t.Errorf("Request respond with unexpected status code. Expected: %d, Given: %d", tt.expectedResponseCode, resp.StatusCode)
+			}
+
+			respBody, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("Failed to read response body: %s", err)
+			}
+
+			compactedRespBody := &bytes.Buffer{}
+			err = json.Compact(compactedRespBody, respBody)
+			if err != nil {
+				t.Fatalf("Failed to compact json: %s", err)
+			}
+
+			if compactedRespBody.String() != tt.expectedResponseBody {
+				t.Errorf("Request response body is not as expected. Expected: %q, Given: %q", tt.expectedResponseBody, compactedRespBody.String())
+			}
+		})
+	}
+}
+
+func TestRealmServer(t *testing.T) {
+	p1, p2 := &ProviderMock{}, &ProviderMock{}
+	p := p1
+	toTest := NewRealmServer(&ProviderMock{}, &RealmsMock{
+		ProviderFunc: func(realm string) (Provider, error) {
+			return p, nil
+		},
+	}, nil, false, "", "")
+
+	s1, err := toTest.realmServer("my-realm")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	cached, err := toTest.realmServer("my-realm")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if s1 != cached {
+		t.Error("Server of unchanged realm has not been reused")
+	}
+
+	p = p2
+	s2, err := toTest.realmServer("my-realm")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if s1 == s2 || s2.p != p2 {
+		t.Error("Server of realm with replaced provider has not been recreated")
+	}
+}
+
+func TestCreateRealmHandler(t *testing.T) {
+	createdAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name                 string
+		requestBody          string
+		realmsError          error
+		expectedRealm        internal.Realm
+		expectedResponseCode int
+		expectedResponseBody string
+	}{
+		{
+			name:        "Happycase",
+			requestBody: `{"name": "my-realm", "hosts": ["login.my-realm.test"], "jwt_issuer": "iss", "jwt_audience": "aud"}`,
+			expectedRealm: internal.Realm{
+				Name:        "my-realm",
+				Hosts:       []string{"login.my-realm.test"},
+				JWTIssuer:   stringPtr("iss"),
+				JWTAudience: stringPtr("aud"),
+			},
+			expectedResponseCode: http.StatusCreated,
+			expectedResponseBody: `{"name":"my-realm","hosts":["login.my-realm.test"],"jwt_issuer":"iss","jwt_audience":"aud","jwt_public_key":"public","created_at":"2020-01-02T03:04:05Z"}`,
+		},
+		{
+			name:        "Happycase with private key",
+			requestBody: `{"name": "my-realm", "jwt_private_key": "private"}`,
+			expectedRealm: internal.Realm{
+				Name:          "my-realm",
+				JWTPrivateKey: "private",
+			},
+			expectedResponseCode: http.StatusCreated,
+			expectedResponseBody: `{"name":"my-realm","hosts":[],"jwt_public_key":"public","created_at":"2020-01-02T03:04:05Z"}`,
+		},
+		{
+			name:                 "Invalid JSON",
+			requestBody:          `{"name"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid JSON"}`,
+		},
+		{
+			name:                 "Invalid name",
+			requestBody:          `{"name": "My Realm"}`,
+			realmsError:          internal.ErrInvalidRealmName,
+			expectedRealm:        internal.Realm{Name: "My Realm"},
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid realm name"}`,
+		},
+		{
+			name:                 "Invalid private key",
+			requestBody:          `{"name": "my-realm", "jwt_private_key": "private"}`,
+			realmsError:          fmt.Errorf("%w: nope", internal.ErrInvalidJWTPrivateKey),
+			expectedRealm:        internal.Realm{Name: "my-realm", JWTPrivateKey: "private"},
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid jwt private key: nope"}`,
+		},
+		{
+			name:                 "Realm already exists",
+			requestBody:          `{"name": "my-realm"}`,
+			realmsError:          internal.ErrRealmAlreadyExists,
+			expectedRealm:        internal.Realm{Name: "my-realm"},
+			expectedResponseCode: http.StatusConflict,
+			expectedResponseBody: `{"message":"realm already exists"}`,
+		},
+		{
+			name:                 "Host already used",
+			requestBody:          `{"name": "my-realm", "hosts": ["login.other.test"]}`,
+			realmsError:          internal.ErrRealmHostAlreadyUsed,
+			expectedRealm:        internal.Realm{Name: "my-realm", Hosts: []string{"login.other.test"}},
+			expectedResponseCode: http.StatusConflict,
+			expectedResponseBody: `{"message":"realm host is already used by another realm"}`,
+		},
+		{
+			name:                 "Unexpected error",
+			requestBody:          `{"name": "my-realm"}`,
+			realmsError:          errors.New("nope"),
+			expectedRealm:        internal.Realm{Name: "my-realm"},
+			expectedResponseCode: http.StatusInternalServerError,
+			expectedResponseBody: `{"message":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenRealm internal.Realm
+
+			toTest := NewRealmServer(&ProviderMock{}, &RealmsMock{
+				RealmByHostFunc: func(host string) (string, bool) {
+					return "", false
+				},
+				CreateRealmFunc: func(realm internal.Realm) (internal.Realm, error) {
+					givenRealm = realm
+					if tt.realmsError != nil {
+						return internal.Realm{}, tt.realmsError
+					}
+
+					realm.JWTPrivateKey = ""
+					realm.JWTPublicKey = "public"
+					realm.CreatedAt = createdAt
+					return realm, nil
+				},
+			}, nil, true, "username", "password")
+			testServer := httptest.NewServer(toTest.h)
+
+			bb := bytes.NewReader([]byte(tt.requestBody))
+			req, err := http.NewRequest(http.MethodPost, testServer.URL+"/v1/admin/realms", bb)
+			if err != nil {
+				t.Fatalf("Failed to build http request: %s", err)
+			}
+			req.SetBasicAuth("username", "password")
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to call server cause: %s", err)
+			}
+			defer resp.Body.Close()
+
+			if !reflect.DeepEqual(givenRealm, tt.expectedRealm) {
+				t.Errorf("Realms called with unexpected Realm. Given: \n%#v \nExpected: \n%#v", givenRealm, tt.expectedRealm)
+			}
+
+			if resp.StatusCode != tt.expectedResponseCode {
+				t.Errorf("Request respond with unexpected status code. Expected: %d, Given: %d", tt.expectedResponseCode, resp.StatusCode)
+			}
+
+			respBody, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("Failed to read response body: %s", err)
+			}
+
+			compactedRespBody := &bytes.Buffer{}
+			err = json.Compact(compactedRespBody, respBody)
+			if err != nil {
+				t.Fatalf("Failed to compact json: %s", err)
+			}
+
+			if compactedRespBody.String() != tt.expectedResponseBody {
+				t.Errorf("Request response body is not as expected. Expected: %q, Given: %q", tt.expectedResponseBody, compactedRespBody.String())
+			}
+		})
+	}
+}
+
+func TestUpdateRealmHandler(t *testing.T) {
+	tests := []struct {
+		name                 string
+		requestRealm         string
+		requestBody          string
+		realmsError          error
+		expectedName         string
+		expectedRealm        internal.Realm
+		expectedResponseCode int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "Happycase",
+			requestRealm:         "my-realm",
+			requestBody:          `{"hosts": ["login.my-realm.test"], "jwt_issuer": "iss"}`,
+			expectedName:         "my-realm",
+			expectedRealm:        internal.Realm{Hosts: []string{"login.my-realm.test"}, JWTIssuer: stringPtr("iss")},
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"name":"my-realm","hosts":["login.my-realm.test"],"jwt_issuer":"iss","jwt_public_key":"public"}`,
+		},
+		{
+			name:                 "Happycase with unchanged name",
+			requestRealm:         "my-realm",
+			requestBody:          `{"name": "my-realm"}`,
+			expectedName:         "my-realm",
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"name":"my-realm","hosts":[],"jwt_public_key":"public"}`,
+		},
+		{
+			name:                 "Invalid JSON",
+			requestRealm:         "my-realm",
+			requestBody:          `{"hosts"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid JSON"}`,
+		},
+		{
+			name:                 "Changed name",
+			requestRealm:         "my-realm",
+			requestBody:          `{"name": "other"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"name can not be changed"}`,
+		},
+		{
+			name:                 "Realm not found",
+			requestRealm:         "my-realm",
+			requestBody:          `{}`,
+			realmsError:          internal.ErrRealmNotFound,
+			expectedName:         "my-realm",
+			expectedResponseCode: http.StatusNotFound,
+			expectedResponseBody: `{"message":"realm not found"}`,
+		},
+		{
+			name:                 "Invalid host",
+			requestRealm:         "my-realm",
+			requestBody:          `{"hosts": ["a b"]}`,
+			realmsError:          internal.ErrInvalidRealmHost,
+			expectedName:         "my-realm",
+			expectedRealm:        internal.Realm{Hosts: []string{"a b"}},
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid realm host"}`,
+		},
+		{
+			name:                 "Host already used",
+			requestRealm:         "my-realm",
+			requestBody:          `{"hosts": ["login.other.test"]}`,
+			realmsError:          internal.ErrRealmHostAlreadyUsed,
+			expectedName:         "my-realm",
+			expectedRealm:        internal.Realm{Hosts: []string{"login.other.test"}},
+			expectedResponseCode: http.StatusConflict,
+			expectedResponseBody: `{"message":"realm host is already used by another realm"}`,
+		},
+		{
+			name:                 "Unexpected error",
+			requestRealm:         "my-realm",
+			requestBody:          `{}`,
+			realmsError:          errors.New("nope"),
+			expectedName:         "my-realm",
+			expectedResponseCode: http.StatusInternalServerError,
+			expectedResponseBody: `{"message":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenName string
+			var givenRealm internal.Realm
+
+			toTest := NewRealmServer(&ProviderMock{}, &RealmsMock{
+				RealmByHostFunc: func(host string) (string, bool) {
+					return "", false
+				},
+				UpdateRealmFunc: func(name string, realm internal.Realm) (internal.Realm, error) {
+					givenName = name
+					givenRealm = realm
+					if tt.realmsError != nil {
+						return internal.Realm{}, tt.realmsError
+					}
+
+					realm.Name = name
+					realm.JWTPublicKey = "public"
+					return realm, nil
+				},
+			}, nil, true, "username", "password")
+			testServer := httptest.NewServer(toTest.h)
+
+			bb := bytes.NewReader([]byte(tt.requestBody))
+			req, err := http.NewRequest(http.MethodPut, testServer.URL+"/v1/admin/realms/"+tt.requestRealm, bb)
+			if err != nil {
+				t.Fatalf("Failed to build http request: %s", err)
+			}
+			req.SetBasicAuth("username", "password")
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to call server cause: %s", err)
+			}
+			defer resp.Body.Close()
+
+			if givenName != tt.expectedName {
+				t.Errorf("Unexpected realm name. Expected: %q, Given: %q", tt.expectedName, givenName)
+			}
+
+			if !reflect.DeepEqual(givenRealm, tt.expectedRealm) {
+				t.Errorf("Realms called with unexpected Realm. Given: \n%#v \nExpected: \n%#v", givenRealm, tt.expectedRealm)
+			}
+
+			if resp.StatusCode != tt.expectedResponseCode {
+				t.Errorf("Request respond with unexpected status code. Expected: %d, Given: %d", tt.expectedResponseCode, resp.StatusCode)
+			}
+
+			respBody, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("Failed to read response body: %s", err)
+			}
+
+			compactedRespBody := &bytes.Buffer{}
+			err = json.Compact(compactedRespBody, respBody)
+			if err != nil {
+				t.Fatalf("Failed to compact json: %s", err)
+			}
+
+			if compactedRespBody.String() != tt.expectedResponseBody {
+				t.Errorf("Request response body is not as expected. Expected: %q, Given: %q", tt.expectedResponseBody, compactedRespBody.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/leberKleber/simple-jwt-provider/internal"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+func (s *Server) loginRecoveryCodeHandler(w http.ResponseWriter, r *http.Request) {
+	requestBody := struct {
+		loginIdentifier
+		MFAToken     string `json:"mfa_token"`
+		RecoveryCode string `json:"recovery_code"`
+	}{}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	if requestBody.identifier() == "" {
+		writeError(w, http.StatusBadRequest, "identifier must be set")
+		return
+	}
+
+	if requestBody.MFAToken == "" {
+		writeError(w, http.StatusBadRequest, "mfa-token must be set")
+		return
+	}
+
+	if requestBody.RecoveryCode == "" {
+		writeError(w, http.StatusBadRequest, "recovery-code must be set")
+		return
+	}
+
+	jwt, err := s.p.LoginRecoveryCode(requestBody.identifier(), requestBody.MFAToken, requestBody.RecoveryCode, client(r))
+	if err != nil {
+		if errors.Is(err, internal.ErrInvalidEMail) {
+			writeError(w, http.StatusBadRequest, "invalid email")
+			return
+		}
+		if errors.Is(err, internal.ErrNoValidTokenFound) || errors.Is(err, internal.ErrInvalidRecoveryCode) {
+			logrus.WithField("identifier", requestBody.identifier()).Warn("somebody tried to login with an invalid recovery code")
+			writeError(w, http.StatusUnauthorized, "invalid credentials")
+			return
+		}
+
+		logrus.WithError(err).Error("Failed to login User with recovery code")
+		writeInternalServerError(w)
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(struct {
+		AccessToken string `json:"access_token"`
+	}{
+		AccessToken: jwt,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("Failed marshal request response")
+		writeInternalServerError(w)
+		return
+	}
+}
+
+func (s *Server) regenerateRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	requestBody := struct {
+		loginIdentifier
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}{}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	if requestBody.identifier() == "" {
+		writeError(w, http.StatusBadRequest, "identifier must be set")
+		return
+	}
+
+	if requestBody.Password == "" {
+		writeError(w, http.StatusBadRequest, "password must be set")
+		return
+	}
+
+	if requestBody.Code == "" {
+		writeError(w, http.StatusBadRequest, "code must be set")
+		return
+	}
+
+	recoveryCodes, err := s.p.RegenerateRecoveryCodes(requestBody.identifier(), requestBody.Password, requestBody.Code)
+	if err != nil {
+		if errors.Is(err, internal.ErrInvalidEMail) {
+			writeError(w, http.StatusBadRequest, "invalid email")
+			return
+		}
+		if errors.Is(err, internal.ErrIncorrectPassword) || errors.Is(err, internal.ErrUserNotFound) ||
+			errors.Is(err, internal.ErrInvalidMFACode) {
+			logrus.WithField("identifier", requestBody.identifier()).Warn("somebody tried to regenerate recovery codes with invalid credentials")
+			writeError(w, http.StatusUnauthorized, "invalid credentials")
+			return
+		}
+		if errors.Is(err, internal.ErrMFANotEnabled) {
+			writeError(w, http.StatusBadRequest, "mfa is not enabled")
+			return
+		}
+
+		logrus.WithError(err).Error("Failed to regenerate recovery codes")
+		writeInternalServerError(w)
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}{
+		RecoveryCodes: recoveryCodes,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("Failed marshal request response")
+		writeInternalServerError(w)
+		return
+	}
+}
@@ -0,0 +1,193 @@
+package web
+
+import (
+	"errors"
+	"github.com/leberKleber/simple-jwt-provider/internal"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestLoginRecoveryCodeHandler(t *testing.T) {
+	tests := []struct {
+		name                 string
+		requestBody          string
+		providerToken        string
+		providerError        error
+		expectedArgs         []string
+		expectedResponseCode int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "Happycase",
+			requestBody:          `{"email": "test.test@test.test", "mfa_token": "myMFAToken", "recovery_code": "abcde-fghjk"}`,
+			providerToken:        "myNewJWT",
+			expectedArgs:         []string{"test.test@test.test", "myMFAToken", "abcde-fghjk"},
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"access_token":"myNewJWT"}`,
+		},
+		{
+			name:                 "Invalid JSON",
+			requestBody:          `{"email test.test@test.test}"`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid JSON"}`,
+		},
+		{
+			name:                 "Missing identifier",
+			requestBody:          `{"mfa_token": "myMFAToken", "recovery_code": "abcde-fghjk"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"identifier must be set"}`,
+		},
+		{
+			name:                 "Missing mfa token",
+			requestBody:          `{"email": "test.test@test.test", "recovery_code": "abcde-fghjk"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"mfa-token must be set"}`,
+		},
+		{
+			name:                 "Missing recovery code",
+			requestBody:          `{"email": "test.test@test.test", "mfa_token": "myMFAToken"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"recovery-code must be set"}`,
+		},
+		{
+			name:                 "Invalid mfa token",
+			requestBody:          `{"email": "test.test@test.test", "mfa_token": "myMFAToken", "recovery_code": "abcde-fghjk"}`,
+			providerError:        internal.ErrNoValidTokenFound,
+			expectedArgs:         []string{"test.test@test.test", "myMFAToken", "abcde-fghjk"},
+			expectedResponseCode: http.StatusUnauthorized,
+			expectedResponseBody: `{"message":"invalid credentials"}`,
+		},
+		{
+			name:                 "Invalid recovery code",
+			requestBody:          `{"email": "test.test@test.test", "mfa_token": "myMFAToken", "recovery_code": "abcde-fghjk"}`,
+			providerError:        internal.ErrInvalidRecoveryCode,
+			expectedArgs:         []string{"test.test@test.test", "myMFAToken", "abcde-fghjk"},
+			expectedResponseCode: http.StatusUnauthorized,
+			expectedResponseBody: `{"message":"invalid credentials"}`,
+		},
+		{
+			name:                 "Unexpected error",
+			requestBody:          `{"email": "test.test@test.test", "mfa_token": "myMFAToken", "recovery_code": "abcde-fghjk"}`,
+			providerError:        errors.New("nope"),
+			expectedArgs:         []string{"test.test@test.test", "myMFAToken", "abcde-fghjk"},
+			expectedResponseCode: http.StatusInternalServerError,
+			expectedResponseBody: `{"message":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenArgs []string
+
+			toTest := NewServer(&ProviderMock{
+				LoginRecoveryCodeFunc: func(email string, mfaToken string, recoveryCode string, client internal.Client) (string, error) {
+					givenArgs = []string{email, mfaToken, recoveryCode}
+					return tt.providerToken, tt.providerError
+				},
+			}, false, "", "")
+
+			callMFAEndpoint(t, toTest, "/v1/auth/login/recovery", tt.requestBody, tt.expectedResponseCode, tt.expectedResponseBody)
+
+			if !reflect.DeepEqual(givenArgs, tt.expectedArgs) {
+				t.Errorf("Provider called with unexpected args. Given: %q, Expected: %q", givenArgs, tt.expectedArgs)
+			}
+		})
+	}
+}
+
+func TestRegenerateRecoveryCodesHandler(t *testing.T) {
+	tests := []struct {
+		name                 string
+		requestBody          string
+		providerCodes        []string
+		providerError        error
+		expectedArgs         []string
+		expectedResponseCode int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "Happycase",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t", "code": "123456"}`,
+			providerCodes:        []string{"abcde-fghjk", "01234-56789"},
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", "123456"},
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"recovery_codes":["abcde-fghjk","01234-56789"]}`,
+		},
+		{
+			name:                 "Invalid JSON",
+			requestBody:          `{"email test.test@test.test}"`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid JSON"}`,
+		},
+		{
+			name:                 "Missing identifier",
+			requestBody:          `{"password": "s3cr3t", "code": "123456"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"identifier must be set"}`,
+		},
+		{
+			name:                 "Missing password",
+			requestBody:          `{"email": "test.test@test.test", "code": "123456"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"password must be set"}`,
+		},
+		{
+			name:                 "Missing code",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"code must be set"}`,
+		},
+		{
+			name:                 "Invalid credentials",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t", "code": "123456"}`,
+			providerError:        internal.ErrIncorrectPassword,
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", "123456"},
+			expectedResponseCode: http.StatusUnauthorized,
+			expectedResponseBody: `{"message":"invalid credentials"}`,
+		},
+		{
+			name:                 "Invalid code",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t", "code": "123456"}`,
+			providerError:        internal.ErrInvalidMFACode,
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", "123456"},
+			expectedResponseCode: http.StatusUnauthorized,
+			expectedResponseBody: `{"message":"invalid credentials"}`,
+		},
+		{
+			name:                 "MFA not enabled",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t", "code": "123456"}`,
+			providerError:        internal.ErrMFANotEnabled,
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", "123456"},
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"mfa is not enabled"}`,
+		},
+		{
+			name:                 "Unexpected error",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t", "code": "123456"}`,
+			providerError:        errors.New("nope"),
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", "123456"},
+			expectedResponseCode: http.StatusInternalServerError,
+			expectedResponseBody: `{"message":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenArgs []string
+
+			toTest := NewServer(&ProviderMock{
+				RegenerateRecoveryCodesFunc: func(email string, password string, code string) ([]string, error) {
+					givenArgs = []string{email, password, code}
+					return tt.providerCodes, tt.providerError
+				},
+			}, false, "", "")
+
+			callMFAEndpoint(t, toTest, "/v1/auth/mfa/recovery-codes", tt.requestBody, tt.expectedResponseCode, tt.expectedResponseBody)
+
+			if !reflect.DeepEqual(givenArgs, tt.expectedArgs) {
+				t.Errorf("Provider called with unexpected args. Given: %q, Expected: %q", givenArgs, tt.expectedArgs)
+			}
+		})
+	}
+}
@@ -5,35 +5,101 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/leberKleber/simple-jwt-provider/internal"
 	"github.com/leberKleber/simple-jwt-provider/internal/web/middleware"
+	"github.com/leberKleber/simple-jwt-provider/internal/webauthn"
 	"github.com/sirupsen/logrus"
 	"net/http"
+	"sync"
 )
 
 //go:generate moq -out provider_moq_test.go . Provider
 type Provider interface {
-	Login(email, password string) (string, error)
+	Login(identifier, password string, client internal.Client) (internal.LoginResult, error)
+	LoginMFA(identifier, mfaToken, code string, client internal.Client) (string, error)
+	CreateMagicLink(email string) error
+	LoginMagicLink(email, magicLinkToken string, client internal.Client) (internal.LoginResult, error)
+	CreateLoginCode(email string) error
+	LoginWithCode(email, code string, client internal.Client) (internal.LoginResult, error)
+	EnrolTOTP(identifier, password string, secondFactor internal.SecondFactor) (internal.TOTPEnrolment, error)
+	ConfirmTOTP(identifier, password, code string, secondFactor internal.SecondFactor) ([]string, error)
+	LoginRecoveryCode(identifier, mfaToken, recoveryCode string, client internal.Client) (string, error)
+	RegenerateRecoveryCodes(identifier, password, code string) ([]string, error)
+	BeginWebAuthnRegistration(identifier, password string, secondFactor internal.SecondFactor) (webauthn.CredentialCreationOptions, error)
+	FinishWebAuthnRegistration(identifier string, r webauthn.AttestationResponse) ([]string, error)
+	BeginWebAuthnLogin(identifier, mfaToken string) (webauthn.CredentialRequestOptions, error)
+	FinishWebAuthnLogin(identifier, mfaToken string, r webauthn.AssertionResponse, client internal.Client) (string, error)
 	CreatePasswordResetRequest(email string) error
 	ResetPassword(email, resetToken, password string) error
+	ChangePassword(identifier, password, newPassword string) error
 	CreateUser(user internal.User) error
-	UpdateUser(email string, user internal.User) (internal.User, error)
-	GetUser(email string) (internal.User, error)
-	DeleteUser(email string) error
+	UpdateUser(idOrIdentifier string, user internal.User) (internal.User, error)
+	PatchUser(idOrIdentifier string, format internal.PatchFormat, patch []byte) (internal.User, error)
+	GetUser(idOrIdentifier string) (internal.User, error)
+	FindUsers(predicates []internal.ClaimPredicate, limit, offset int) (internal.UserPage, error)
+	DeleteUser(idOrIdentifier string) error
+	ResetMFA(idOrIdentifier string) error
+	ExportUser(idOrIdentifier string) (internal.UserExport, error)
+	EraseUser(idOrIdentifier string) (internal.UserErasure, error)
+	VerifyAccessToken(accessToken string) (string, error)
+	Logins(idOrIdentifier string, limit, offset int) (internal.LoginHistory, error)
+	ResendInvitation(idOrIdentifier string) error
+	AcceptInvitation(email, invitationToken, password string) error
 }
 
 type Server struct {
 	h http.Handler
 	p Provider
+
+	realms           Realms
+	jobs             Jobs
+	enableAdminAPI   bool
+	adminAPIUsername string
+	adminAPIPassword string
+	realmServersMu   sync.Mutex
+	realmServers     map[string]*Server
 }
 
 // NewServer returns a Server instance with configure http routs
 func NewServer(p Provider, enableAdminAPI bool, adminAPIUsername, adminAPIPassword string) *Server {
-	s := &Server{}
+	return NewRealmServer(p, nil, nil, enableAdminAPI, adminAPIUsername, adminAPIPassword)
+}
+
+// NewRealmServer returns a Server instance like NewServer which serves the given provider as default realm and
+// additionally all given realms. Realms will be selected by the url prefix '/v1/realms/{realm}' or by host header,
+// all other requests will be served by the default realm. Realms will be managed via the admin api of the default
+// realm. Without realms (nil) only the default realm will be served. The given background jobs (may be nil) will be
+// monitored and triggered via the admin api of the default realm.
+func NewRealmServer(p Provider, realms Realms, jobs Jobs, enableAdminAPI bool, adminAPIUsername, adminAPIPassword string) *Server {
+	s := &Server{
+		realms:           realms,
+		jobs:             jobs,
+		enableAdminAPI:   enableAdminAPI,
+		adminAPIUsername: adminAPIUsername,
+		adminAPIPassword: adminAPIPassword,
+		realmServers:     map[string]*Server{},
+	}
 	r := mux.NewRouter()
 	v1 := r.PathPrefix("/v1").Subrouter()
 	v1.Path("/internal/alive").Methods(http.MethodGet).HandlerFunc(s.aliveHandler)
 	v1.Path("/auth/login").Methods(http.MethodPost).HandlerFunc(s.loginHandler)
+	v1.Path("/auth/login/mfa").Methods(http.MethodPost).HandlerFunc(s.loginMFAHandler)
+	v1.Path("/auth/login/recovery").Methods(http.MethodPost).HandlerFunc(s.loginRecoveryCodeHandler)
+	v1.Path("/auth/magic-link").Methods(http.MethodPost).HandlerFunc(s.magicLinkHandler)
+	v1.Path("/auth/magic-link/redeem").Methods(http.MethodPost).HandlerFunc(s.redeemMagicLinkHandler)
+	v1.Path("/auth/login-code").Methods(http.MethodPost).HandlerFunc(s.loginCodeHandler)
+	v1.Path("/auth/login-code/redeem").Methods(http.MethodPost).HandlerFunc(s.redeemLoginCodeHandler)
+	v1.Path("/auth/totp").Methods(http.MethodPost).HandlerFunc(s.enrolTOTPHandler)
+	v1.Path("/auth/totp/confirm").Methods(http.MethodPost).HandlerFunc(s.confirmTOTPHandler)
+	v1.Path("/auth/mfa/recovery-codes").Methods(http.MethodPost).HandlerFunc(s.regenerateRecoveryCodesHandler)
+	v1.Path("/auth/webauthn/register/begin").Methods(http.MethodPost).HandlerFunc(s.beginWebAuthnRegistrationHandler)
+	v1.Path("/auth/webauthn/register/finish").Methods(http.MethodPost).HandlerFunc(s.finishWebAuthnRegistrationHandler)
+	v1.Path("/auth/webauthn/login/begin").Methods(http.MethodPost).HandlerFunc(s.beginWebAuthnLoginHandler)
+	v1.Path("/auth/webauthn/login/finish").Methods(http.MethodPost).HandlerFunc(s.finishWebAuthnLoginHandler)
 	v1.Path("/auth/password-reset-request").Methods(http.MethodPost).HandlerFunc(s.passwordResetRequestHandler)
 	v1.Path("/auth/password-reset").Methods(http.MethodPost).HandlerFunc(s.passwordResetHandler)
+	v1.Path("/auth/invitation/accept").Methods(http.MethodPost).HandlerFunc(s.acceptInvitationHandler)
+	v1.Path("/auth/password-change").Methods(http.MethodPost).HandlerFunc(s.passwordChangeHandler)
+	v1.Path("/auth/data-export").Methods(http.MethodGet).HandlerFunc(s.exportOwnDataHandler)
+	v1.Path("/auth/data-erasure").Methods(http.MethodPost).HandlerFunc(s.eraseOwnDataHandler)
 
 	r.NotFoundHandler = http.HandlerFunc(notFoundHandler)
 	r.MethodNotAllowedHandler = http.HandlerFunc(methodNotAllowedHandler)
@@ -43,13 +109,35 @@ func NewServer(p Provider, enableAdminAPI bool, adminAPIUsername, adminAPIPasswo
 		adminAPI.Use(middleware.BasicAuth(adminAPIUsername, adminAPIPassword))
 
 		adminAPI.Path("/users").Methods(http.MethodPost).HandlerFunc(s.createUserHandler)
-		adminAPI.Path("/users/{email}").Methods(http.MethodGet).HandlerFunc(s.getUserHandler)
-		adminAPI.Path("/users/{email}").Methods(http.MethodPut).HandlerFunc(s.updateUserHandler)
-		adminAPI.Path("/users/{email}").Methods(http.MethodDelete).HandlerFunc(s.deleteUserHandler)
+		adminAPI.Path("/users/search").Methods(http.MethodPost).HandlerFunc(s.searchUsersHandler)
+		adminAPI.Path("/users/{user}").Methods(http.MethodGet).HandlerFunc(s.getUserHandler)
+		adminAPI.Path("/users/{user}").Methods(http.MethodPut).HandlerFunc(s.updateUserHandler)
+		adminAPI.Path("/users/{user}").Methods(http.MethodPatch).HandlerFunc(s.patchUserHandler)
+		adminAPI.Path("/users/{user}").Methods(http.MethodDelete).HandlerFunc(s.deleteUserHandler)
+		adminAPI.Path("/users/{user}/mfa").Methods(http.MethodDelete).HandlerFunc(s.resetMFAHandler)
+		adminAPI.Path("/users/{user}/data-export").Methods(http.MethodGet).HandlerFunc(s.exportUserHandler)
+		adminAPI.Path("/users/{user}/data-erasure").Methods(http.MethodPost).HandlerFunc(s.eraseUserHandler)
+		adminAPI.Path("/users/{user}/logins").Methods(http.MethodGet).HandlerFunc(s.loginsHandler)
+		adminAPI.Path("/users/{user}/invitation").Methods(http.MethodPost).HandlerFunc(s.resendInvitationHandler)
+
+		if realms != nil {
+			adminAPI.Path("/realms").Methods(http.MethodPost).HandlerFunc(s.createRealmHandler)
+			adminAPI.Path("/realms").Methods(http.MethodGet).HandlerFunc(s.listRealmsHandler)
+			adminAPI.Path("/realms/{realm}").Methods(http.MethodGet).HandlerFunc(s.getRealmHandler)
+			adminAPI.Path("/realms/{realm}").Methods(http.MethodPut).HandlerFunc(s.updateRealmHandler)
+		}
+
+		if jobs != nil {
+			adminAPI.Path("/jobs").Methods(http.MethodGet).HandlerFunc(s.listJobsHandler)
+			adminAPI.Path("/jobs/{job}/run").Methods(http.MethodPost).HandlerFunc(s.runJobHandler)
+		}
 	}
 
 	s.h = r
 	s.p = p
+	if realms != nil {
+		s.h = s.realmHandler(r)
+	}
 	return s
 }
 
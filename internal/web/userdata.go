@@ -0,0 +1,218 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/leberKleber/simple-jwt-provider/internal"
+	"github.com/leberKleber/simple-jwt-provider/internal/webauthn"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// UserExport is the json archive of everything stored about a user
+type UserExport struct {
+	User                User                 `json:"user"`
+	Tokens              []exportedToken      `json:"tokens"`
+	PasswordHistory     []time.Time          `json:"password_history"`
+	TOTP                *exportedTOTP        `json:"totp,omitempty"`
+	WebAuthnCredentials []exportedCredential `json:"webauthn_credentials"`
+	RecoveryCodes       []exportedCode       `json:"recovery_codes"`
+	Logins              []Login              `json:"logins"`
+	ExportedAt          time.Time            `json:"exported_at"`
+}
+
+type exportedToken struct {
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Attempts  int       `json:"attempts"`
+}
+
+type exportedTOTP struct {
+	Confirmed bool      `json:"confirmed"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type exportedCredential struct {
+	ID         webauthn.URLEncodedBase64 `json:"id"`
+	SignCount  uint32                    `json:"sign_count"`
+	CreatedAt  time.Time                 `json:"created_at"`
+	LastUsedAt *time.Time                `json:"last_used_at,omitempty"`
+}
+
+type exportedCode struct {
+	CreatedAt time.Time  `json:"created_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
+
+// UserErasure is the proof of the erasure of a user
+type UserErasure struct {
+	UserID      string           `json:"user_id"`
+	DeletedRows map[string]int64 `json:"deleted_rows"`
+	ErasedAt    time.Time        `json:"erased_at"`
+}
+
+// toWebUserExport converts the given internal.UserExport to a UserExport
+func toWebUserExport(e internal.UserExport) UserExport {
+	export := UserExport{
+		User:                toWebUser(e.User),
+		Tokens:              []exportedToken{},
+		PasswordHistory:     e.PasswordHistory,
+		WebAuthnCredentials: []exportedCredential{},
+		RecoveryCodes:       []exportedCode{},
+		Logins:              []Login{},
+		ExportedAt:          e.ExportedAt,
+	}
+	if export.PasswordHistory == nil {
+		export.PasswordHistory = []time.Time{}
+	}
+	for _, t := range e.Tokens {
+		export.Tokens = append(export.Tokens, exportedToken{Type: t.Type, CreatedAt: t.CreatedAt, Attempts: t.Attempts})
+	}
+	if e.TOTP != nil {
+		export.TOTP = &exportedTOTP{Confirmed: e.TOTP.Confirmed, CreatedAt: e.TOTP.CreatedAt}
+	}
+	for _, c := range e.WebAuthnCredentials {
+		export.WebAuthnCredentials = append(export.WebAuthnCredentials, exportedCredential{
+			ID:         c.ID,
+			SignCount:  c.SignCount,
+			CreatedAt:  c.CreatedAt,
+			LastUsedAt: c.LastUsedAt,
+		})
+	}
+	for _, c := range e.RecoveryCodes {
+		export.RecoveryCodes = append(export.RecoveryCodes, exportedCode{CreatedAt: c.CreatedAt, UsedAt: c.UsedAt})
+	}
+	for _, l := range e.Logins {
+		export.Logins = append(export.Logins, Login{
+			CreatedAt: l.CreatedAt,
+			IP:        l.IP,
+			UserAgent: l.UserAgent,
+			Outcome:   l.Outcome,
+			Factor:    l.Factor,
+		})
+	}
+
+	return export
+}
+
+func (s *Server) exportUserHandler(w http.ResponseWriter, r *http.Request) {
+	idOrIdentifier, err := url.PathUnescape(mux.Vars(r)["user"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "could not unescape email")
+		return
+	}
+
+	s.exportUser(w, idOrIdentifier)
+}
+
+func (s *Server) eraseUserHandler(w http.ResponseWriter, r *http.Request) {
+	idOrIdentifier, err := url.PathUnescape(mux.Vars(r)["user"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "could not unescape email")
+		return
+	}
+
+	s.eraseUser(w, idOrIdentifier)
+}
+
+func (s *Server) exportOwnDataHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	s.exportUser(w, userID)
+}
+
+func (s *Server) eraseOwnDataHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	s.eraseUser(w, userID)
+}
+
+// authenticate returns the id of the user the bearer token of the given request has been issued to. The response will
+// be written when the request could not be authenticated.
+func (s *Server) authenticate(w http.ResponseWriter, r *http.Request) (string, bool) {
+	accessToken := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if accessToken == "" || accessToken == r.Header.Get("Authorization") {
+		writeError(w, http.StatusUnauthorized, "invalid access token")
+		return "", false
+	}
+
+	userID, err := s.p.VerifyAccessToken(accessToken)
+	if err != nil {
+		if errors.Is(err, internal.ErrInvalidAccessToken) {
+			writeError(w, http.StatusUnauthorized, "invalid access token")
+			return "", false
+		}
+
+		logrus.WithError(err).Error("Failed to verify access token")
+		writeInternalServerError(w)
+		return "", false
+	}
+
+	return userID, true
+}
+
+func (s *Server) exportUser(w http.ResponseWriter, idOrIdentifier string) {
+	export, err := s.p.ExportUser(idOrIdentifier)
+	if err != nil {
+		if errors.Is(err, internal.ErrInvalidEMail) {
+			writeError(w, http.StatusBadRequest, "invalid email")
+			return
+		}
+		if errors.Is(err, internal.ErrUserNotFound) {
+			writeError(w, http.StatusNotFound, "User with given email doesn't exists")
+			return
+		}
+
+		logrus.WithError(err).Error("Failed to export User")
+		writeInternalServerError(w)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"user-%s.json\"", export.User.ID))
+	err = json.NewEncoder(w).Encode(toWebUserExport(export))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to encode User export")
+		writeInternalServerError(w)
+		return
+	}
+}
+
+func (s *Server) eraseUser(w http.ResponseWriter, idOrIdentifier string) {
+	erasure, err := s.p.EraseUser(idOrIdentifier)
+	if err != nil {
+		if errors.Is(err, internal.ErrInvalidEMail) {
+			writeError(w, http.StatusBadRequest, "invalid email")
+			return
+		}
+		if errors.Is(err, internal.ErrUserNotFound) {
+			writeError(w, http.StatusNotFound, "User with given email doesn't exists")
+			return
+		}
+
+		logrus.WithError(err).Error("Failed to erase User")
+		writeInternalServerError(w)
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(UserErasure{
+		UserID:      erasure.UserID,
+		DeletedRows: erasure.DeletedRows,
+		ErasedAt:    erasure.ErasedAt,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("Failed to encode User erasure")
+		writeInternalServerError(w)
+		return
+	}
+}
@@ -0,0 +1,250 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/leberKleber/simple-jwt-provider/internal"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestExportUserHandler(t *testing.T) {
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+
+	tests := []struct {
+		name                       string
+		requestUser                string
+		providerError              error
+		expectedUser               string
+		expectedContentDisposition string
+		expectedResponseCode       int
+		expectedResponseBody       string
+	}{
+		{
+			name:                       "Happycase",
+			requestUser:                "info%40leberkleber.io",
+			expectedUser:               "info@leberkleber.io",
+			expectedContentDisposition: `attachment; filename="user-c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b.json"`,
+			expectedResponseCode:       http.StatusOK,
+			expectedResponseBody: `{"user":{"id":"c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b","email":"info@leberkleber.io","password":"**********","claims":null,"password_changed_at":"2020-02-01T04:46:45Z"},` +
+				`"tokens":[{"type":"reset","created_at":"2020-02-01T04:46:45Z","attempts":0}],"password_history":[],` +
+				`"totp":{"confirmed":true,"created_at":"2020-02-01T04:46:45Z"},` +
+				`"webauthn_credentials":[{"id":"Y3JlZA","sign_count":3,"created_at":"2020-02-01T04:46:45Z"}],` +
+				`"recovery_codes":[{"created_at":"2020-02-01T04:46:45Z","used_at":"2020-02-01T04:46:45Z"}],` +
+				`"logins":[{"created_at":"2020-02-01T04:46:45Z","ip":"127.0.0.1","user_agent":"curl/7.68.0","outcome":"success","factor":"password"}],"exported_at":"2020-02-01T04:46:45Z"}`,
+		},
+		{
+			name:                 "User not found",
+			requestUser:          "info%40leberkleber.io",
+			providerError:        internal.ErrUserNotFound,
+			expectedUser:         "info@leberkleber.io",
+			expectedResponseCode: http.StatusNotFound,
+			expectedResponseBody: `{"message":"User with given email doesn't exists"}`,
+		},
+		{
+			name:                 "Unexpected error",
+			requestUser:          "info%40leberkleber.io",
+			providerError:        errors.New("nope"),
+			expectedUser:         "info@leberkleber.io",
+			expectedResponseCode: http.StatusInternalServerError,
+			expectedResponseBody: `{"message":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenUser string
+
+			toTest := NewServer(&ProviderMock{
+				ExportUserFunc: func(idOrIdentifier string) (internal.UserExport, error) {
+					givenUser = idOrIdentifier
+					return internal.UserExport{
+						User: internal.User{
+							ID:                "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+							EMail:             "info@leberkleber.io",
+							Password:          "**********",
+							PasswordChangedAt: now,
+						},
+						Tokens:              []storage.TokenData{{Type: storage.TokenTypeReset, CreatedAt: now}},
+						TOTP:                &storage.TOTPData{Confirmed: true, CreatedAt: now},
+						WebAuthnCredentials: []storage.WebAuthnCredentialData{{ID: []byte("cred"), SignCount: 3, CreatedAt: now}},
+						RecoveryCodes:       []storage.RecoveryCodeData{{CreatedAt: now, UsedAt: &now}},
+						Logins: []storage.Login{
+							{ID: 1, CreatedAt: now, IP: "127.0.0.1", UserAgent: "curl/7.68.0", Outcome: storage.LoginOutcomeSuccess, Factor: "password"},
+						},
+						ExportedAt: now,
+					}, tt.providerError
+				},
+			}, true, "username", "password")
+			testServer := httptest.NewServer(toTest.h)
+
+			req, err := http.NewRequest(http.MethodGet, testServer.URL+"/v1/admin/users/"+tt.requestUser+"/data-export", nil)
+			if err != nil {
+				t.Fatalf("Failed to build http request: %s", err)
+			}
+			req.SetBasicAuth("username", "password")
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to call server cause: %s", err)
+			}
+			defer resp.Body.Close()
+
+			if givenUser != tt.expectedUser {
+				t.Errorf("Unexpected user. Expected: %q, Given: %q", tt.expectedUser, givenUser)
+			}
+
+			if resp.StatusCode != tt.expectedResponseCode {
+				t.Errorf("Request respond with unexpected status code. Expected: %d, Given: %d", tt.expectedResponseCode, resp.StatusCode)
+			}
+
+			if contentDisposition := resp.Header.Get("Content-Disposition"); contentDisposition != tt.expectedContentDisposition {
+				t.Errorf("Unexpected content disposition. Expected: %q, Given: %q", tt.expectedContentDisposition, contentDisposition)
+			}
+
+			respBody, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("Failed to read response body: %s", err)
+			}
+
+			compactedRespBody := &bytes.Buffer{}
+			err = json.Compact(compactedRespBody, respBody)
+			if err != nil {
+				t.Fatalf("Failed to compact json: %s", err)
+			}
+
+			if compactedRespBody.String() != tt.expectedResponseBody {
+				t.Errorf("Request response body is not as expected. Expected: %q, Given: %q", tt.expectedResponseBody, compactedRespBody.String())
+			}
+		})
+	}
+}
+
+func TestEraseOwnDataHandler(t *testing.T) {
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+
+	tests := []struct {
+		name                 string
+		authorization        string
+		verifyError          error
+		eraseError           error
+		expectedAccessToken  string
+		expectedUser         string
+		expectedResponseCode int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "Happycase",
+			authorization:        "Bearer myJWT",
+			expectedAccessToken:  "myJWT",
+			expectedUser:         "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"user_id":"c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b","deleted_rows":{"tokens":2,"users":1},"erased_at":"2020-02-01T04:46:45Z"}`,
+		},
+		{
+			name:                 "Missing access token",
+			expectedResponseCode: http.StatusUnauthorized,
+			expectedResponseBody: `{"message":"invalid access token"}`,
+		},
+		{
+			name:                 "Basic auth",
+			authorization:        "Basic dXNlcm5hbWU6cGFzc3dvcmQ=",
+			expectedResponseCode: http.StatusUnauthorized,
+			expectedResponseBody: `{"message":"invalid access token"}`,
+		},
+		{
+			name:                 "Invalid access token",
+			authorization:        "Bearer myJWT",
+			verifyError:          internal.ErrInvalidAccessToken,
+			expectedAccessToken:  "myJWT",
+			expectedResponseCode: http.StatusUnauthorized,
+			expectedResponseBody: `{"message":"invalid access token"}`,
+		},
+		{
+			name:                 "User already erased",
+			authorization:        "Bearer myJWT",
+			eraseError:           internal.ErrUserNotFound,
+			expectedAccessToken:  "myJWT",
+			expectedUser:         "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			expectedResponseCode: http.StatusNotFound,
+			expectedResponseBody: `{"message":"User with given email doesn't exists"}`,
+		},
+		{
+			name:                 "Unexpected error",
+			authorization:        "Bearer myJWT",
+			eraseError:           errors.New("nope"),
+			expectedAccessToken:  "myJWT",
+			expectedUser:         "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b",
+			expectedResponseCode: http.StatusInternalServerError,
+			expectedResponseBody: `{"message":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenAccessToken, givenUser string
+
+			toTest := NewServer(&ProviderMock{
+				VerifyAccessTokenFunc: func(accessToken string) (string, error) {
+					givenAccessToken = accessToken
+					return "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", tt.verifyError
+				},
+				EraseUserFunc: func(idOrIdentifier string) (internal.UserErasure, error) {
+					givenUser = idOrIdentifier
+					return internal.UserErasure{
+						UserID:      idOrIdentifier,
+						DeletedRows: map[string]int64{"users": 1, "tokens": 2},
+						ErasedAt:    now,
+					}, tt.eraseError
+				},
+			}, false, "", "")
+			testServer := httptest.NewServer(toTest.h)
+
+			req, err := http.NewRequest(http.MethodPost, testServer.URL+"/v1/auth/data-erasure", nil)
+			if err != nil {
+				t.Fatalf("Failed to build http request: %s", err)
+			}
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to call server cause: %s", err)
+			}
+			defer resp.Body.Close()
+
+			if givenAccessToken != tt.expectedAccessToken {
+				t.Errorf("Unexpected access token. Expected: %q, Given: %q", tt.expectedAccessToken, givenAccessToken)
+			}
+
+			if givenUser != tt.expectedUser {
+				t.Errorf("Unexpected user. Expected: %q, Given: %q", tt.expectedUser, givenUser)
+			}
+
+			if resp.StatusCode != tt.expectedResponseCode {
+				t.Errorf("Request respond with unexpected status code. Expected: %d, Given: %d", tt.expectedResponseCode, resp.StatusCode)
+			}
+
+			respBody, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("Failed to read response body: %s", err)
+			}
+
+			compactedRespBody := &bytes.Buffer{}
+			err = json.Compact(compactedRespBody, respBody)
+			if err != nil {
+				t.Fatalf("Failed to compact json: %s", err)
+			}
+
+			if compactedRespBody.String() != tt.expectedResponseBody {
+				t.Errorf("Request response body is not as expected. Expected: %q, Given: %q", tt.expectedResponseBody, compactedRespBody.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/leberKleber/simple-jwt-provider/internal"
+	"github.com/leberKleber/simple-jwt-provider/internal/webauthn"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+func (s *Server) beginWebAuthnRegistrationHandler(w http.ResponseWriter, r *http.Request) {
+	requestBody := struct {
+		loginIdentifier
+		Password     string       `json:"password"`
+		SecondFactor secondFactor `json:"second_factor"`
+	}{}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	if requestBody.identifier() == "" {
+		writeError(w, http.StatusBadRequest, "identifier must be set")
+		return
+	}
+
+	if requestBody.Password == "" {
+		writeError(w, http.StatusBadRequest, "password must be set")
+		return
+	}
+
+	options, err := s.p.BeginWebAuthnRegistration(requestBody.identifier(), requestBody.Password, requestBody.SecondFactor.internal())
+	if err != nil {
+		if errors.Is(err, internal.ErrInvalidEMail) {
+			writeError(w, http.StatusBadRequest, "invalid email")
+			return
+		}
+		if errors.Is(err, internal.ErrIncorrectPassword) || errors.Is(err, internal.ErrUserNotFound) {
+			logrus.WithField("identifier", requestBody.identifier()).Warn("somebody tried to register a webauthn credential with invalid credentials")
+			writeError(w, http.StatusUnauthorized, "invalid credentials")
+			return
+		}
+		if writeSecondFactorError(w, err, requestBody.identifier()) {
+			return
+		}
+		if errors.Is(err, internal.ErrWebAuthnNotConfigured) {
+			writeError(w, http.StatusNotFound, "webauthn is not configured")
+			return
+		}
+
+		logrus.WithError(err).Error("Failed to begin webauthn registration")
+		writeInternalServerError(w)
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(struct {
+		PublicKey webauthn.CredentialCreationOptions `json:"publicKey"`
+	}{
+		PublicKey: options,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("Failed marshal request response")
+		writeInternalServerError(w)
+		return
+	}
+}
+
+func (s *Server) finishWebAuthnRegistrationHandler(w http.ResponseWriter, r *http.Request) {
+	requestBody := struct {
+		loginIdentifier
+		Credential webauthn.AttestationResponse `json:"credential"`
+	}{}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	if requestBody.identifier() == "" {
+		writeError(w, http.StatusBadRequest, "identifier must be set")
+		return
+	}
+
+	recoveryCodes, err := s.p.FinishWebAuthnRegistration(requestBody.identifier(), requestBody.Credential)
+	if err != nil {
+		if errors.Is(err, internal.ErrInvalidEMail) {
+			writeError(w, http.StatusBadRequest, "invalid email")
+			return
+		}
+		if errors.Is(err, internal.ErrNoValidTokenFound) {
+			writeError(w, http.StatusBadRequest, "invalid or expired challenge")
+			return
+		}
+		if errors.Is(err, internal.ErrInvalidWebAuthnResponse) {
+			logrus.WithError(err).WithField("identifier", requestBody.identifier()).Warn("invalid webauthn registration")
+			writeError(w, http.StatusBadRequest, "invalid credential")
+			return
+		}
+		if errors.Is(err, internal.ErrWebAuthnNotConfigured) {
+			writeError(w, http.StatusNotFound, "webauthn is not configured")
+			return
+		}
+		if errors.Is(err, internal.ErrWebAuthnCredentialAlreadyExists) {
+			writeError(w, http.StatusConflict, "credential already registered")
+			return
+		}
+
+		logrus.WithError(err).Error("Failed to finish webauthn registration")
+		writeInternalServerError(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if len(recoveryCodes) == 0 {
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}{
+		RecoveryCodes: recoveryCodes,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("Failed marshal request response")
+		return
+	}
+}
+
+func (s *Server) beginWebAuthnLoginHandler(w http.ResponseWriter, r *http.Request) {
+	requestBody := struct {
+		loginIdentifier
+		MFAToken string `json:"mfa_token"`
+	}{}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	if requestBody.identifier() == "" {
+		writeError(w, http.StatusBadRequest, "identifier must be set")
+		return
+	}
+
+	options, err := s.p.BeginWebAuthnLogin(requestBody.identifier(), requestBody.MFAToken)
+	if err != nil {
+		if errors.Is(err, internal.ErrInvalidEMail) {
+			writeError(w, http.StatusBadRequest, "invalid email")
+			return
+		}
+		if errors.Is(err, internal.ErrNoValidTokenFound) || errors.Is(err, internal.ErrNoWebAuthnCredentials) {
+			logrus.WithField("identifier", requestBody.identifier()).Warn("somebody tried to begin a webauthn login with invalid credentials")
+			writeError(w, http.StatusUnauthorized, "invalid credentials")
+			return
+		}
+		if errors.Is(err, internal.ErrWebAuthnNotConfigured) {
+			writeError(w, http.StatusNotFound, "webauthn is not configured")
+			return
+		}
+
+		logrus.WithError(err).Error("Failed to begin webauthn login")
+		writeInternalServerError(w)
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(struct {
+		PublicKey webauthn.CredentialRequestOptions `json:"publicKey"`
+	}{
+		PublicKey: options,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("Failed marshal request response")
+		writeInternalServerError(w)
+		return
+	}
+}
+
+func (s *Server) finishWebAuthnLoginHandler(w http.ResponseWriter, r *http.Request) {
+	requestBody := struct {
+		loginIdentifier
+		MFAToken   string                     `json:"mfa_token"`
+		Credential webauthn.AssertionResponse `json:"credential"`
+	}{}
+
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+
+	if requestBody.identifier() == "" {
+		writeError(w, http.StatusBadRequest, "identifier must be set")
+		return
+	}
+
+	jwt, err := s.p.FinishWebAuthnLogin(requestBody.identifier(), requestBody.MFAToken, requestBody.Credential, client(r))
+	if err != nil {
+		if errors.Is(err, internal.ErrInvalidEMail) {
+			writeError(w, http.StatusBadRequest, "invalid email")
+			return
+		}
+		if errors.Is(err, internal.ErrNoValidTokenFound) || errors.Is(err, internal.ErrInvalidWebAuthnResponse) {
+			logrus.WithError(err).WithField("identifier", requestBody.identifier()).Warn("somebody tried to login with invalid webauthn credentials")
+			writeError(w, http.StatusUnauthorized, "invalid credentials")
+			return
+		}
+		if errors.Is(err, internal.ErrWebAuthnNotConfigured) {
+			writeError(w, http.StatusNotFound, "webauthn is not configured")
+			return
+		}
+
+		logrus.WithError(err).Error("Failed to login User with webauthn")
+		writeInternalServerError(w)
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(struct {
+		AccessToken string `json:"access_token"`
+	}{
+		AccessToken: jwt,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("Failed marshal request response")
+		writeInternalServerError(w)
+		return
+	}
+}
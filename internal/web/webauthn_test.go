@@ -0,0 +1,426 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal"
+	"github.com/leberKleber/simple-jwt-provider/internal/webauthn"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestBeginWebAuthnRegistrationHandler(t *testing.T) {
+	tests := []struct {
+		name                 string
+		requestBody          string
+		providerError        error
+		expectedArgs         []string
+		expectedResponseCode int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "Happycase",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t"}`,
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", "", ""},
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"publicKey":{"challenge":"AQI","rp":{"id":"example.com","name":"example"},"user":{"id":"AwQ","name":"test.test@test.test","displayName":"test.test@test.test"},"pubKeyCredParams":null,"authenticatorSelection":{"residentKey":"","userVerification":""},"attestation":"none"}}`,
+		},
+		{
+			name:                 "Happycase with totp code as second factor",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t", "second_factor": {"code": "123456"}}`,
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", "123456", ""},
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"publicKey":{"challenge":"AQI","rp":{"id":"example.com","name":"example"},"user":{"id":"AwQ","name":"test.test@test.test","displayName":"test.test@test.test"},"pubKeyCredParams":null,"authenticatorSelection":{"residentKey":"","userVerification":""},"attestation":"none"}}`,
+		},
+		{
+			name:                 "Happycase with webauthn as second factor",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t", "second_factor": {"credential": {"id": "AQI", "rawId": "AQI", "type": "public-key", "response": {"clientDataJSON": "e30", "authenticatorData": "AA", "signature": "AA"}}}}`,
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", "", "0102"},
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"publicKey":{"challenge":"AQI","rp":{"id":"example.com","name":"example"},"user":{"id":"AwQ","name":"test.test@test.test","displayName":"test.test@test.test"},"pubKeyCredParams":null,"authenticatorSelection":{"residentKey":"","userVerification":""},"attestation":"none"}}`,
+		},
+		{
+			name:                 "Invalid JSON",
+			requestBody:          `{"email test.test@test.test}"`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid JSON"}`,
+		},
+		{
+			name:                 "Missing identifier",
+			requestBody:          `{"password": "s3cr3t"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"identifier must be set"}`,
+		},
+		{
+			name:                 "Missing password",
+			requestBody:          `{"email": "test.test@test.test"}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"password must be set"}`,
+		},
+		{
+			name:                 "Invalid credentials",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t"}`,
+			providerError:        internal.ErrIncorrectPassword,
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", "", ""},
+			expectedResponseCode: http.StatusUnauthorized,
+			expectedResponseBody: `{"message":"invalid credentials"}`,
+		},
+		{
+			name:                 "Second factor required",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t"}`,
+			providerError:        internal.ErrSecondFactorRequired,
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", "", ""},
+			expectedResponseCode: http.StatusForbidden,
+			expectedResponseBody: `{"message":"second factor required"}`,
+		},
+		{
+			name:                 "Invalid second factor",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t", "second_factor": {"code": "123456"}}`,
+			providerError:        internal.ErrInvalidSecondFactor,
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", "123456", ""},
+			expectedResponseCode: http.StatusUnauthorized,
+			expectedResponseBody: `{"message":"invalid credentials"}`,
+		},
+		{
+			name:                 "WebAuthn not configured",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t"}`,
+			providerError:        internal.ErrWebAuthnNotConfigured,
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", "", ""},
+			expectedResponseCode: http.StatusNotFound,
+			expectedResponseBody: `{"message":"webauthn is not configured"}`,
+		},
+		{
+			name:                 "Unexpected error",
+			requestBody:          `{"email": "test.test@test.test", "password": "s3cr3t"}`,
+			providerError:        errors.New("nope"),
+			expectedArgs:         []string{"test.test@test.test", "s3cr3t", "", ""},
+			expectedResponseCode: http.StatusInternalServerError,
+			expectedResponseBody: `{"message":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenArgs []string
+
+			toTest := NewServer(&ProviderMock{
+				BeginWebAuthnRegistrationFunc: func(email string, password string, secondFactor internal.SecondFactor) (webauthn.CredentialCreationOptions, error) {
+					var credentialID string
+					if secondFactor.WebAuthn != nil {
+						credentialID = fmt.Sprintf("%x", secondFactor.WebAuthn.RawID)
+					}
+					givenArgs = []string{email, password, secondFactor.Code, credentialID}
+					if tt.providerError != nil {
+						return webauthn.CredentialCreationOptions{}, tt.providerError
+					}
+					return webauthn.CredentialCreationOptions{
+						Challenge:   []byte{1, 2},
+						RP:          webauthn.RelyingPartyEntity{ID: "example.com", Name: "example"},
+						User:        webauthn.UserEntity{ID: []byte{3, 4}, Name: email, DisplayName: email},
+						Attestation: "none",
+					}, nil
+				},
+			}, false, "", "")
+
+			callMFAEndpoint(t, toTest, "/v1/auth/webauthn/register/begin", tt.requestBody, tt.expectedResponseCode, tt.expectedResponseBody)
+
+			if !reflect.DeepEqual(givenArgs, tt.expectedArgs) {
+				t.Errorf("Provider called with unexpected args. Given: %q, Expected: %q", givenArgs, tt.expectedArgs)
+			}
+		})
+	}
+}
+
+func TestFinishWebAuthnRegistrationHandler(t *testing.T) {
+	tests := []struct {
+		name                 string
+		requestBody          string
+		providerCodes        []string
+		providerError        error
+		expectedEMail        string
+		expectedResponseCode int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "Happycase",
+			requestBody:          `{"email": "test.test@test.test", "credential": {"id": "AQI", "rawId": "AQI", "type": "public-key", "response": {"clientDataJSON": "e30", "attestationObject": "oA"}}}`,
+			providerCodes:        []string{"abcde-fghjk"},
+			expectedEMail:        "test.test@test.test",
+			expectedResponseCode: http.StatusCreated,
+			expectedResponseBody: `{"recovery_codes":["abcde-fghjk"]}`,
+		},
+		{
+			name:                 "Happycase without new recovery codes",
+			requestBody:          `{"email": "test.test@test.test", "credential": {}}`,
+			expectedEMail:        "test.test@test.test",
+			expectedResponseCode: http.StatusCreated,
+		},
+		{
+			name:                 "Invalid JSON",
+			requestBody:          `{"email test.test@test.test}"`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid JSON"}`,
+		},
+		{
+			name:                 "Invalid base64",
+			requestBody:          `{"email": "test.test@test.test", "credential": {"rawId": "*"}}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid JSON"}`,
+		},
+		{
+			name:                 "Missing identifier",
+			requestBody:          `{"credential": {}}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"identifier must be set"}`,
+		},
+		{
+			name:                 "Invalid challenge",
+			requestBody:          `{"email": "test.test@test.test", "credential": {}}`,
+			providerError:        internal.ErrNoValidTokenFound,
+			expectedEMail:        "test.test@test.test",
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid or expired challenge"}`,
+		},
+		{
+			name:                 "Invalid credential",
+			requestBody:          `{"email": "test.test@test.test", "credential": {}}`,
+			providerError:        fmt.Errorf("%w: nope", internal.ErrInvalidWebAuthnResponse),
+			expectedEMail:        "test.test@test.test",
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid credential"}`,
+		},
+		{
+			name:                 "Credential already registered",
+			requestBody:          `{"email": "test.test@test.test", "credential": {}}`,
+			providerError:        internal.ErrWebAuthnCredentialAlreadyExists,
+			expectedEMail:        "test.test@test.test",
+			expectedResponseCode: http.StatusConflict,
+			expectedResponseBody: `{"message":"credential already registered"}`,
+		},
+		{
+			name:                 "WebAuthn not configured",
+			requestBody:          `{"email": "test.test@test.test", "credential": {}}`,
+			providerError:        internal.ErrWebAuthnNotConfigured,
+			expectedEMail:        "test.test@test.test",
+			expectedResponseCode: http.StatusNotFound,
+			expectedResponseBody: `{"message":"webauthn is not configured"}`,
+		},
+		{
+			name:                 "Unexpected error",
+			requestBody:          `{"email": "test.test@test.test", "credential": {}}`,
+			providerError:        errors.New("nope"),
+			expectedEMail:        "test.test@test.test",
+			expectedResponseCode: http.StatusInternalServerError,
+			expectedResponseBody: `{"message":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenEMail string
+			var givenCredential webauthn.AttestationResponse
+
+			toTest := NewServer(&ProviderMock{
+				FinishWebAuthnRegistrationFunc: func(email string, r webauthn.AttestationResponse) ([]string, error) {
+					givenEMail = email
+					givenCredential = r
+					return tt.providerCodes, tt.providerError
+				},
+			}, false, "", "")
+
+			callMFAEndpoint(t, toTest, "/v1/auth/webauthn/register/finish", tt.requestBody, tt.expectedResponseCode, tt.expectedResponseBody)
+
+			if givenEMail != tt.expectedEMail {
+				t.Errorf("Provider called with unexpected email. Given: %q, Expected: %q", givenEMail, tt.expectedEMail)
+			}
+
+			if tt.name == "Happycase" && (string(givenCredential.RawID) != "\x01\x02" ||
+				string(givenCredential.Response.ClientDataJSON) != "{}" || string(givenCredential.Response.AttestationObject) != "\xa0") {
+				t.Errorf("Provider called with unexpected credential: %#v", givenCredential)
+			}
+		})
+	}
+}
+
+func TestBeginWebAuthnLoginHandler(t *testing.T) {
+	tests := []struct {
+		name                 string
+		requestBody          string
+		providerError        error
+		expectedArgs         []string
+		expectedResponseCode int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "Happycase passwordless",
+			requestBody:          `{"email": "test.test@test.test"}`,
+			expectedArgs:         []string{"test.test@test.test", ""},
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"publicKey":{"challenge":"AQI","rpId":"example.com","allowCredentials":[{"type":"public-key","id":"AwQ"}],"userVerification":"required"}}`,
+		},
+		{
+			name:                 "Happycase second factor",
+			requestBody:          `{"email": "test.test@test.test", "mfa_token": "myMFAToken"}`,
+			expectedArgs:         []string{"test.test@test.test", "myMFAToken"},
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"publicKey":{"challenge":"AQI","rpId":"example.com","allowCredentials":[{"type":"public-key","id":"AwQ"}],"userVerification":"required"}}`,
+		},
+		{
+			name:                 "Invalid JSON",
+			requestBody:          `{"email test.test@test.test}"`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid JSON"}`,
+		},
+		{
+			name:                 "Missing identifier",
+			requestBody:          `{}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"identifier must be set"}`,
+		},
+		{
+			name:                 "Invalid mfa token",
+			requestBody:          `{"email": "test.test@test.test", "mfa_token": "myMFAToken"}`,
+			providerError:        internal.ErrNoValidTokenFound,
+			expectedArgs:         []string{"test.test@test.test", "myMFAToken"},
+			expectedResponseCode: http.StatusUnauthorized,
+			expectedResponseBody: `{"message":"invalid credentials"}`,
+		},
+		{
+			name:                 "No credentials",
+			requestBody:          `{"email": "test.test@test.test"}`,
+			providerError:        internal.ErrNoWebAuthnCredentials,
+			expectedArgs:         []string{"test.test@test.test", ""},
+			expectedResponseCode: http.StatusUnauthorized,
+			expectedResponseBody: `{"message":"invalid credentials"}`,
+		},
+		{
+			name:                 "WebAuthn not configured",
+			requestBody:          `{"email": "test.test@test.test"}`,
+			providerError:        internal.ErrWebAuthnNotConfigured,
+			expectedArgs:         []string{"test.test@test.test", ""},
+			expectedResponseCode: http.StatusNotFound,
+			expectedResponseBody: `{"message":"webauthn is not configured"}`,
+		},
+		{
+			name:                 "Unexpected error",
+			requestBody:          `{"email": "test.test@test.test"}`,
+			providerError:        errors.New("nope"),
+			expectedArgs:         []string{"test.test@test.test", ""},
+			expectedResponseCode: http.StatusInternalServerError,
+			expectedResponseBody: `{"message":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenArgs []string
+
+			toTest := NewServer(&ProviderMock{
+				BeginWebAuthnLoginFunc: func(email string, mfaToken string) (webauthn.CredentialRequestOptions, error) {
+					givenArgs = []string{email, mfaToken}
+					if tt.providerError != nil {
+						return webauthn.CredentialRequestOptions{}, tt.providerError
+					}
+					return webauthn.CredentialRequestOptions{
+						Challenge:        []byte{1, 2},
+						RPID:             "example.com",
+						AllowCredentials: []webauthn.CredentialDescriptor{{Type: "public-key", ID: []byte{3, 4}}},
+						UserVerification: webauthn.UserVerificationRequired,
+					}, nil
+				},
+			}, false, "", "")
+
+			callMFAEndpoint(t, toTest, "/v1/auth/webauthn/login/begin", tt.requestBody, tt.expectedResponseCode, tt.expectedResponseBody)
+
+			if !reflect.DeepEqual(givenArgs, tt.expectedArgs) {
+				t.Errorf("Provider called with unexpected args. Given: %q, Expected: %q", givenArgs, tt.expectedArgs)
+			}
+		})
+	}
+}
+
+func TestFinishWebAuthnLoginHandler(t *testing.T) {
+	tests := []struct {
+		name                 string
+		requestBody          string
+		providerToken        string
+		providerError        error
+		expectedArgs         []string
+		expectedResponseCode int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "Happycase",
+			requestBody:          `{"email": "test.test@test.test", "mfa_token": "myMFAToken", "credential": {"rawId": "AQI", "response": {"clientDataJSON": "e30", "authenticatorData": "AwQ", "signature": "BQY"}}}`,
+			providerToken:        "myNewJWT",
+			expectedArgs:         []string{"test.test@test.test", "myMFAToken", "\x01\x02", "{}", "\x03\x04", "\x05\x06"},
+			expectedResponseCode: http.StatusOK,
+			expectedResponseBody: `{"access_token":"myNewJWT"}`,
+		},
+		{
+			name:                 "Invalid JSON",
+			requestBody:          `{"email test.test@test.test}"`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"invalid JSON"}`,
+		},
+		{
+			name:                 "Missing identifier",
+			requestBody:          `{"credential": {}}`,
+			expectedResponseCode: http.StatusBadRequest,
+			expectedResponseBody: `{"message":"identifier must be set"}`,
+		},
+		{
+			name:                 "Invalid challenge",
+			requestBody:          `{"email": "test.test@test.test", "credential": {}}`,
+			providerError:        internal.ErrNoValidTokenFound,
+			expectedArgs:         []string{"test.test@test.test", "", "", "", "", ""},
+			expectedResponseCode: http.StatusUnauthorized,
+			expectedResponseBody: `{"message":"invalid credentials"}`,
+		},
+		{
+			name:                 "Invalid credential",
+			requestBody:          `{"email": "test.test@test.test", "credential": {}}`,
+			providerError:        fmt.Errorf("%w: nope", internal.ErrInvalidWebAuthnResponse),
+			expectedArgs:         []string{"test.test@test.test", "", "", "", "", ""},
+			expectedResponseCode: http.StatusUnauthorized,
+			expectedResponseBody: `{"message":"invalid credentials"}`,
+		},
+		{
+			name:                 "WebAuthn not configured",
+			requestBody:          `{"email": "test.test@test.test", "credential": {}}`,
+			providerError:        internal.ErrWebAuthnNotConfigured,
+			expectedArgs:         []string{"test.test@test.test", "", "", "", "", ""},
+			expectedResponseCode: http.StatusNotFound,
+			expectedResponseBody: `{"message":"webauthn is not configured"}`,
+		},
+		{
+			name:                 "Unexpected error",
+			requestBody:          `{"email": "test.test@test.test", "credential": {}}`,
+			providerError:        errors.New("nope"),
+			expectedArgs:         []string{"test.test@test.test", "", "", "", "", ""},
+			expectedResponseCode: http.StatusInternalServerError,
+			expectedResponseBody: `{"message":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenArgs []string
+
+			toTest := NewServer(&ProviderMock{
+				FinishWebAuthnLoginFunc: func(email string, mfaToken string, r webauthn.AssertionResponse, client internal.Client) (string, error) {
+					givenArgs = []string{email, mfaToken, string(r.RawID), string(r.Response.ClientDataJSON),
+						string(r.Response.AuthenticatorData), string(r.Response.Signature)}
+					return tt.providerToken, tt.providerError
+				},
+			}, false, "", "")
+
+			callMFAEndpoint(t, toTest, "/v1/auth/webauthn/login/finish", tt.requestBody, tt.expectedResponseCode, tt.expectedResponseBody)
+
+			if !reflect.DeepEqual(givenArgs, tt.expectedArgs) {
+				t.Errorf("Provider called with unexpected args. Given: %q, Expected: %q", givenArgs, tt.expectedArgs)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"github.com/leberKleber/simple-jwt-provider/internal/webauthn"
+)
+
+var ErrWebAuthnNotConfigured = errors.New("webauthn is not configured")
+var ErrWebAuthnCredentialAlreadyExists = errors.New("webauthn credential already exists")
+var ErrNoWebAuthnCredentials = errors.New("user has no webauthn credentials")
+var ErrInvalidWebAuthnResponse = errors.New("invalid webauthn response")
+
+// BeginWebAuthnRegistration starts the registration of a new webauthn credential (security key, passkey) for the given
+// user. Users with an enabled second factor or unused recovery codes have to prove it besides the password. The
+// returned options have to be passed to navigator.credentials.create() and the result to FinishWebAuthnRegistration.
+// return ErrWebAuthnNotConfigured when webauthn has not been configured
+// return ErrIncorrectPassword when password is incorrect
+// return ErrUserNotFound when user not found
+// return ErrSecondFactorRequired when the user has a second factor but none has been given
+// return ErrInvalidSecondFactor when the given second factor is invalid
+func (p Provider) BeginWebAuthnRegistration(identifier, password string, secondFactor SecondFactor) (webauthn.CredentialCreationOptions, error) {
+	if p.WebAuthn == nil {
+		return webauthn.CredentialCreationOptions{}, ErrWebAuthnNotConfigured
+	}
+
+	u, err := p.authenticate(identifier, password)
+	if err != nil {
+		return webauthn.CredentialCreationOptions{}, err
+	}
+
+	err = p.requireSecondFactor(u.ID, secondFactor)
+	if err != nil {
+		return webauthn.CredentialCreationOptions{}, err
+	}
+
+	credentialIDs, err := p.webAuthnCredentialIDs(u.ID)
+	if err != nil {
+		return webauthn.CredentialCreationOptions{}, err
+	}
+
+	challenge, err := p.createWebAuthnChallenge(u.ID, storage.TokenTypeWebAuthnRegistration)
+	if err != nil {
+		return webauthn.CredentialCreationOptions{}, err
+	}
+
+	return p.WebAuthn.CreationOptions(challenge, webAuthnUserID(u.ID), loginName(u), credentialIDs), nil
+}
+
+// FinishWebAuthnRegistration verifies the response of navigator.credentials.create() to the challenge issued by
+// BeginWebAuthnRegistration and stores the new credential. From now on the user needs the credential (or another
+// enabled second factor) to login. Returns new recovery codes when the user had no unused ones before. The challenge
+// can be used once, also when the response is invalid.
+// return ErrWebAuthnNotConfigured when webauthn has not been configured
+// return ErrNoValidTokenFound when the challenge is unknown or expired
+// return ErrInvalidWebAuthnResponse when the response could not be verified
+// return ErrWebAuthnCredentialAlreadyExists when the credential has already been registered
+func (p Provider) FinishWebAuthnRegistration(identifier string, r webauthn.AttestationResponse) ([]string, error) {
+	if p.WebAuthn == nil {
+		return nil, ErrWebAuthnNotConfigured
+	}
+
+	u, err := p.challengedUser(identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := p.redeemWebAuthnChallenge(u.ID, r.Response.ClientDataJSON, storage.TokenTypeWebAuthnRegistration)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := p.WebAuthn.VerifyRegistration(challenge, r, false)
+	if err != nil {
+		return nil, webAuthnError(err)
+	}
+
+	err = p.Storage.CreateWebAuthnCredential(storage.WebAuthnCredential{
+		ID:        c.ID,
+		UserID:    u.ID,
+		PublicKey: c.PublicKey,
+		SignCount: c.SignCount,
+		CreatedAt: nowFunc(),
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrWebAuthnCredentialAlreadyExists) {
+			return nil, ErrWebAuthnCredentialAlreadyExists
+		}
+		return nil, fmt.Errorf("failed to create webauthn credential: %w", err)
+	}
+
+	return p.issueRecoveryCodes(u.ID)
+}
+
+// BeginWebAuthnLogin starts a webauthn login of the given user. With a mfa challenge token (issued by Login,
+// LoginMagicLink or LoginWithCode) the credential will be used as second factor, otherwise as passwordless login which
+// requires user verification. The returned options have to be passed to navigator.credentials.get() and the result to
+// FinishWebAuthnLogin.
+// return ErrWebAuthnNotConfigured when webauthn has not been configured
+// return ErrNoValidTokenFound when the mfa token is unknown or expired
+// return ErrNoWebAuthnCredentials when the user has no webauthn credentials
+func (p Provider) BeginWebAuthnLogin(identifier, mfaToken string) (webauthn.CredentialRequestOptions, error) {
+	if p.WebAuthn == nil {
+		return webauthn.CredentialRequestOptions{}, ErrWebAuthnNotConfigured
+	}
+
+	u, err := p.userByLoginIdentifier(identifier)
+	if err != nil {
+		if !errors.Is(err, ErrUserNotFound) {
+			return webauthn.CredentialRequestOptions{}, err
+		}
+		// unknown users neither have a mfa token nor credentials
+		if mfaToken != "" {
+			return webauthn.CredentialRequestOptions{}, ErrNoValidTokenFound
+		}
+		return webauthn.CredentialRequestOptions{}, ErrNoWebAuthnCredentials
+	}
+
+	userVerification := webauthn.UserVerificationRequired
+	if mfaToken != "" {
+		_, err := p.findToken(u.ID, mfaToken, storage.TokenTypeMFA)
+		if err != nil {
+			return webauthn.CredentialRequestOptions{}, err
+		}
+		userVerification = webauthn.UserVerificationPreferred
+	}
+
+	credentialIDs, err := p.webAuthnCredentialIDs(u.ID)
+	if err != nil {
+		return webauthn.CredentialRequestOptions{}, err
+	}
+	if len(credentialIDs) == 0 {
+		return webauthn.CredentialRequestOptions{}, ErrNoWebAuthnCredentials
+	}
+
+	challenge, err := p.createWebAuthnChallenge(u.ID, storage.TokenTypeWebAuthnLogin)
+	if err != nil {
+		return webauthn.CredentialRequestOptions{}, err
+	}
+
+	return p.WebAuthn.RequestOptions(challenge, credentialIDs, userVerification), nil
+}
+
+// FinishWebAuthnLogin verifies the response of navigator.credentials.get() and returns a new jwt. The 'amr' claim is
+// the first factor of the mfa challenge token and "hwk" (e.g. ["pwd", "hwk"]) when a mfa challenge token is given and
+// ["hwk"] for passwordless logins. The challenge and the mfa token can be used once, also when the response is invalid.
+// The attempt will be recorded in the login history of the user.
+// return ErrWebAuthnNotConfigured when webauthn has not been configured
+// return ErrNoValidTokenFound when the mfa token or the challenge is unknown or expired
+// return ErrInvalidWebAuthnResponse when the response could not be verified
+func (p Provider) FinishWebAuthnLogin(identifier, mfaToken string, r webauthn.AssertionResponse, client Client) (accessToken string, err error) {
+	if p.WebAuthn == nil {
+		return "", ErrWebAuthnNotConfigured
+	}
+
+	u, err := p.challengedUser(identifier)
+	if err != nil {
+		return "", err
+	}
+	defer func() { p.recordLogin(u.ID, client, LoginFactorWebAuthn, false, err) }()
+
+	amr := []string{amrHardwareKey}
+	if mfaToken != "" {
+		firstFactor, err := p.redeemMFAToken(u.ID, mfaToken)
+		if err != nil {
+			return "", err
+		}
+		amr = []string{firstFactor, amrHardwareKey}
+	}
+
+	err = p.verifyWebAuthnAssertion(u.ID, r, mfaToken == "")
+	if err != nil {
+		return "", err
+	}
+
+	return p.JWTGenerator.Generate(u.ID, u.EMail, withClaim(u.Claims, amrClaim, amr))
+}
+
+// verifyWebAuthnAssertion redeems the login challenge referenced by the given response of navigator.credentials.get(),
+// verifies the response against the stored credential of the user with the given id and updates its usage.
+// return ErrNoValidTokenFound when the challenge is unknown or expired
+// return ErrInvalidWebAuthnResponse when the response could not be verified
+func (p Provider) verifyWebAuthnAssertion(userID string, r webauthn.AssertionResponse, userVerification bool) error {
+	challenge, err := p.redeemWebAuthnChallenge(userID, r.Response.ClientDataJSON, storage.TokenTypeWebAuthnLogin)
+	if err != nil {
+		return err
+	}
+
+	credentials, err := p.Storage.WebAuthnCredentials(userID)
+	if err != nil {
+		return fmt.Errorf("failed to query webauthn credentials: %w", err)
+	}
+
+	var credential *storage.WebAuthnCredential
+	for _, c := range credentials {
+		if bytes.Equal(c.ID, r.RawID) {
+			credential = &c
+			break
+		}
+	}
+	if credential == nil {
+		return fmt.Errorf("%w: unknown credential", ErrInvalidWebAuthnResponse)
+	}
+
+	signCount, err := p.WebAuthn.VerifyAssertion(challenge, r, webauthn.Credential{
+		ID:        credential.ID,
+		PublicKey: credential.PublicKey,
+		SignCount: credential.SignCount,
+	}, userVerification)
+	if err != nil {
+		return webAuthnError(err)
+	}
+
+	err = p.Storage.UpdateWebAuthnCredentialUsage(credential.ID, signCount, nowFunc())
+	if err != nil {
+		return fmt.Errorf("failed to update webauthn credential: %w", err)
+	}
+
+	return nil
+}
+
+// webAuthnCredentialIDs returns the ids of all webauthn credentials of the user with the given id
+func (p Provider) webAuthnCredentialIDs(userID string) ([][]byte, error) {
+	credentials, err := p.Storage.WebAuthnCredentials(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webauthn credentials: %w", err)
+	}
+
+	var ids [][]byte
+	for _, c := range credentials {
+		ids = append(ids, c.ID)
+	}
+
+	return ids, nil
+}
+
+// createWebAuthnChallenge generates a new challenge and stores it as token of the given type
+func (p Provider) createWebAuthnChallenge(userID, tokenType string) ([]byte, error) {
+	challenge, err := webauthn.GenerateChallenge()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate webauthn challenge: %w", err)
+	}
+
+	err = p.createToken(userID, tokenType, webauthn.EncodeChallenge(challenge), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+// redeemWebAuthnChallenge redeems the challenge token of the given type which is referenced by the given client data.
+// return ErrNoValidTokenFound when the challenge is unknown or expired
+func (p Provider) redeemWebAuthnChallenge(userID string, clientDataJSON []byte, tokenType string) ([]byte, error) {
+	cd, err := webauthn.ParseClientData(clientDataJSON)
+	if err != nil {
+		return nil, webAuthnError(err)
+	}
+
+	_, err = p.redeemToken(userID, cd.Challenge, tokenType)
+	if err != nil {
+		return nil, err
+	}
+
+	// the stored token is the encoded challenge, the webauthn verification compares the decoded one
+	return webauthn.DecodeChallenge(cd.Challenge)
+}
+
+// webAuthnError maps verification errors to ErrInvalidWebAuthnResponse
+func webAuthnError(err error) error {
+	if errors.Is(err, webauthn.ErrVerification) {
+		return fmt.Errorf("%w: %s", ErrInvalidWebAuthnResponse, err)
+	}
+
+	return fmt.Errorf("failed to verify webauthn response: %w", err)
+}
+
+// webAuthnUserID is the webauthn user handle. It must not contain personal data, so the hashed user id will be used.
+func webAuthnUserID(userID string) []byte {
+	h := sha256.Sum256([]byte(userID))
+	return h[:]
+}
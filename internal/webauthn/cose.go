@@ -0,0 +1,108 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"github.com/fxamacker/cbor/v2"
+	"math/big"
+)
+
+// COSE algorithms and key types by https://www.iana.org/assignments/cose/cose.xhtml
+const (
+	algES256 int64 = -7
+	algRS256 int64 = -257
+
+	keyTypeEC2 int64 = 2
+	keyTypeRSA int64 = 3
+
+	curveP256 int64 = 1
+)
+
+// coseKey is a COSE_Key (https://tools.ietf.org/html/rfc8152#section-7). The labels -1, -2 and -3 have different
+// meanings per key type.
+type coseKey struct {
+	KeyType   int64           `cbor:"1,keyasint"`
+	Algorithm int64           `cbor:"3,keyasint"`
+	CurveOrN  cbor.RawMessage `cbor:"-1,keyasint,omitempty"`
+	XOrE      cbor.RawMessage `cbor:"-2,keyasint,omitempty"`
+	Y         cbor.RawMessage `cbor:"-3,keyasint,omitempty"`
+}
+
+type publicKey struct {
+	algorithm int64
+	ecdsa     *ecdsa.PublicKey
+	rsa       *rsa.PublicKey
+}
+
+// parsePublicKey parses the given COSE encoded ES256 or RS256 public key
+func parsePublicKey(raw []byte) (publicKey, error) {
+	var k coseKey
+	err := cbor.Unmarshal(raw, &k)
+	if err != nil {
+		return publicKey{}, fmt.Errorf("invalid cose key: %s", err)
+	}
+
+	switch {
+	case k.KeyType == keyTypeEC2 && k.Algorithm == algES256:
+		var curve int64
+		var x, y []byte
+		if cbor.Unmarshal(k.CurveOrN, &curve) != nil || cbor.Unmarshal(k.XOrE, &x) != nil || cbor.Unmarshal(k.Y, &y) != nil {
+			return publicKey{}, errors.New("invalid ec2 cose key")
+		}
+		if curve != curveP256 {
+			return publicKey{}, fmt.Errorf("unsupported curve %d", curve)
+		}
+
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return publicKey{}, errors.New("ec2 public key is not on curve")
+		}
+
+		return publicKey{algorithm: k.Algorithm, ecdsa: pub}, nil
+	case k.KeyType == keyTypeRSA && k.Algorithm == algRS256:
+		var n, e []byte
+		if cbor.Unmarshal(k.CurveOrN, &n) != nil || cbor.Unmarshal(k.XOrE, &e) != nil {
+			return publicKey{}, errors.New("invalid rsa cose key")
+		}
+
+		return publicKey{
+			algorithm: k.Algorithm,
+			rsa:       &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())},
+		}, nil
+	default:
+		return publicKey{}, fmt.Errorf("unsupported key type %d with algorithm %d", k.KeyType, k.Algorithm)
+	}
+}
+
+// verify verifies the given signature of the given data
+func (p publicKey) verify(data, signature []byte) error {
+	hash := sha256.Sum256(data)
+
+	if p.algorithm == algRS256 {
+		err := rsa.VerifyPKCS1v15(p.rsa, crypto.SHA256, hash[:], signature)
+		if err != nil {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+
+	var sig struct {
+		R, S *big.Int
+	}
+	rest, err := asn1.Unmarshal(signature, &sig)
+	if err != nil || len(rest) != 0 {
+		return errors.New("invalid signature encoding")
+	}
+
+	if !ecdsa.Verify(p.ecdsa, hash[:], sig.R, sig.S) {
+		return errors.New("invalid signature")
+	}
+
+	return nil
+}
@@ -0,0 +1,193 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"github.com/fxamacker/cbor/v2"
+)
+
+const (
+	flagUserPresent            = 0x01
+	flagUserVerified           = 0x04
+	flagAttestedCredentialData = 0x40
+
+	// rpIDHash (32) + flags (1) + signCount (4)
+	authenticatorDataMinLength = 37
+	// aaguid (16) + credentialIdLength (2)
+	attestedCredentialDataMinLength = 18
+)
+
+type attestationObject struct {
+	Fmt      string          `cbor:"fmt"`
+	AttStmt  cbor.RawMessage `cbor:"attStmt"`
+	AuthData []byte          `cbor:"authData"`
+}
+
+type authenticatorData struct {
+	rpIDHash            []byte
+	flags               byte
+	signCount           uint32
+	credentialID        []byte
+	credentialPublicKey []byte
+}
+
+// VerifyRegistration verifies the response of a registration ceremony started with the given challenge and returns the
+// new credential. Attestation statements will not be verified (attestation conveyance 'none').
+func (rp RelyingParty) VerifyRegistration(challenge []byte, r AttestationResponse, requireUserVerification bool) (Credential, error) {
+	err := rp.verifyClientData(r.Response.ClientDataJSON, "webauthn.create", challenge)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	var ao attestationObject
+	err = cbor.Unmarshal(r.Response.AttestationObject, &ao)
+	if err != nil {
+		return Credential{}, fmt.Errorf("%w: invalid attestation object: %s", ErrVerification, err)
+	}
+
+	ad, err := rp.verifyAuthenticatorData(ao.AuthData, requireUserVerification)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	if ad.flags&flagAttestedCredentialData == 0 {
+		return Credential{}, fmt.Errorf("%w: attested credential data missing", ErrVerification)
+	}
+
+	if len(r.RawID) > 0 && !bytes.Equal(r.RawID, ad.credentialID) {
+		return Credential{}, fmt.Errorf("%w: credential id does not match", ErrVerification)
+	}
+
+	_, err = parsePublicKey(ad.credentialPublicKey)
+	if err != nil {
+		return Credential{}, fmt.Errorf("%w: %s", ErrVerification, err)
+	}
+
+	return Credential{
+		ID:        ad.credentialID,
+		PublicKey: ad.credentialPublicKey,
+		SignCount: ad.signCount,
+	}, nil
+}
+
+// VerifyAssertion verifies the response of an authentication ceremony started with the given challenge against the
+// given credential and returns the new sign count of the credential.
+func (rp RelyingParty) VerifyAssertion(challenge []byte, r AssertionResponse, c Credential, requireUserVerification bool) (uint32, error) {
+	err := rp.verifyClientData(r.Response.ClientDataJSON, "webauthn.get", challenge)
+	if err != nil {
+		return 0, err
+	}
+
+	ad, err := rp.verifyAuthenticatorData(r.Response.AuthenticatorData, requireUserVerification)
+	if err != nil {
+		return 0, err
+	}
+
+	publicKey, err := parsePublicKey(c.PublicKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse stored public key: %w", err)
+	}
+
+	clientDataHash := sha256.Sum256(r.Response.ClientDataJSON)
+	signedData := append(append([]byte{}, r.Response.AuthenticatorData...), clientDataHash[:]...)
+	err = publicKey.verify(signedData, r.Response.Signature)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrVerification, err)
+	}
+
+	// authenticators without counter always send 0
+	if (ad.signCount != 0 || c.SignCount != 0) && ad.signCount <= c.SignCount {
+		return 0, fmt.Errorf("%w: sign count did not increase, the authenticator may be cloned", ErrVerification)
+	}
+
+	return ad.signCount, nil
+}
+
+func (rp RelyingParty) verifyClientData(clientDataJSON []byte, expectedType string, expectedChallenge []byte) error {
+	cd, err := ParseClientData(clientDataJSON)
+	if err != nil {
+		return err
+	}
+
+	if cd.Type != expectedType {
+		return fmt.Errorf("%w: unexpected client data type %q", ErrVerification, cd.Type)
+	}
+
+	challenge, err := DecodeChallenge(cd.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(challenge, expectedChallenge) != 1 {
+		return fmt.Errorf("%w: challenge does not match", ErrVerification)
+	}
+
+	for _, origin := range rp.Origins {
+		if cd.Origin == origin {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: origin %q is not allowed", ErrVerification, cd.Origin)
+}
+
+func (rp RelyingParty) verifyAuthenticatorData(raw []byte, requireUserVerification bool) (authenticatorData, error) {
+	ad, err := parseAuthenticatorData(raw)
+	if err != nil {
+		return authenticatorData{}, fmt.Errorf("%w: %s", ErrVerification, err)
+	}
+
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(ad.rpIDHash, rpIDHash[:]) {
+		return authenticatorData{}, fmt.Errorf("%w: rp id hash does not match", ErrVerification)
+	}
+
+	if ad.flags&flagUserPresent == 0 {
+		return authenticatorData{}, fmt.Errorf("%w: user not present", ErrVerification)
+	}
+
+	if requireUserVerification && ad.flags&flagUserVerified == 0 {
+		return authenticatorData{}, fmt.Errorf("%w: user not verified", ErrVerification)
+	}
+
+	return ad, nil
+}
+
+// parseAuthenticatorData parses authenticator data by https://www.w3.org/TR/webauthn-2/#sctn-authenticator-data
+func parseAuthenticatorData(raw []byte) (authenticatorData, error) {
+	if len(raw) < authenticatorDataMinLength {
+		return authenticatorData{}, fmt.Errorf("authenticator data too short")
+	}
+
+	ad := authenticatorData{
+		rpIDHash:  raw[:32],
+		flags:     raw[32],
+		signCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+
+	if ad.flags&flagAttestedCredentialData == 0 {
+		return ad, nil
+	}
+
+	rest := raw[authenticatorDataMinLength:]
+	if len(rest) < attestedCredentialDataMinLength {
+		return authenticatorData{}, fmt.Errorf("attested credential data too short")
+	}
+
+	credentialIDLength := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[attestedCredentialDataMinLength:]
+	if len(rest) < credentialIDLength {
+		return authenticatorData{}, fmt.Errorf("credential id too short")
+	}
+	ad.credentialID = rest[:credentialIDLength]
+	rest = rest[credentialIDLength:]
+
+	// the public key is followed by optional extensions, so only the first cbor item will be read
+	var publicKey cbor.RawMessage
+	err := cbor.NewDecoder(bytes.NewReader(rest)).Decode(&publicKey)
+	if err != nil {
+		return authenticatorData{}, fmt.Errorf("invalid credential public key: %s", err)
+	}
+	ad.credentialPublicKey = publicKey
+
+	return ad, nil
+}
@@ -0,0 +1,231 @@
+package webauthn
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// UserVerificationRequired lets the authenticator verify the user (pin, biometrics) and fails when it can not
+	UserVerificationRequired = "required"
+	// UserVerificationPreferred lets the authenticator verify the user when possible
+	UserVerificationPreferred = "preferred"
+
+	publicKeyCredentialType = "public-key"
+	challengeSize           = 32
+)
+
+// ErrVerification will be wrapped by all errors caused by invalid client responses
+var ErrVerification = errors.New("webauthn verification failed")
+
+// URLEncodedBase64 is a byte slice which will be (un)marshalled from / to unpadded base64url json strings as used by
+// the WebAuthn api
+type URLEncodedBase64 []byte
+
+// MarshalJSON encodes the bytes as unpadded base64url string
+func (u URLEncodedBase64) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(u))
+}
+
+// UnmarshalJSON decodes a (padded or unpadded) base64url string
+func (u *URLEncodedBase64) UnmarshalJSON(data []byte) error {
+	var s string
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return err
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return err
+	}
+
+	*u = b
+	return nil
+}
+
+// RelyingParty verifies WebAuthn ceremonies for the given relying party id (effective domain e.g.: 'example.com') and
+// the allowed origins (e.g.: 'https://login.example.com')
+type RelyingParty struct {
+	ID      string
+	Name    string
+	Origins []string
+	Timeout time.Duration
+}
+
+// Credential is a registered public key credential
+type Credential struct {
+	ID []byte
+	// PublicKey is the COSE encoded public key
+	PublicKey []byte
+	SignCount uint32
+}
+
+// CredentialCreationOptions has to be passed as 'publicKey' to navigator.credentials.create()
+type CredentialCreationOptions struct {
+	Challenge              URLEncodedBase64       `json:"challenge"`
+	RP                     RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout,omitempty"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials,omitempty"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// CredentialRequestOptions has to be passed as 'publicKey' to navigator.credentials.get()
+type CredentialRequestOptions struct {
+	Challenge        URLEncodedBase64       `json:"challenge"`
+	Timeout          int64                  `json:"timeout,omitempty"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials,omitempty"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	ID          URLEncodedBase64 `json:"id"`
+	Name        string           `json:"name"`
+	DisplayName string           `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type string           `json:"type"`
+	ID   URLEncodedBase64 `json:"id"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// AttestationResponse is the PublicKeyCredential returned by navigator.credentials.create()
+type AttestationResponse struct {
+	ID       string           `json:"id"`
+	RawID    URLEncodedBase64 `json:"rawId"`
+	Type     string           `json:"type"`
+	Response struct {
+		ClientDataJSON    URLEncodedBase64 `json:"clientDataJSON"`
+		AttestationObject URLEncodedBase64 `json:"attestationObject"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the PublicKeyCredential returned by navigator.credentials.get()
+type AssertionResponse struct {
+	ID       string           `json:"id"`
+	RawID    URLEncodedBase64 `json:"rawId"`
+	Type     string           `json:"type"`
+	Response struct {
+		ClientDataJSON    URLEncodedBase64 `json:"clientDataJSON"`
+		AuthenticatorData URLEncodedBase64 `json:"authenticatorData"`
+		Signature         URLEncodedBase64 `json:"signature"`
+		UserHandle        URLEncodedBase64 `json:"userHandle,omitempty"`
+	} `json:"response"`
+}
+
+// ClientData is the parsed clientDataJSON (https://www.w3.org/TR/webauthn-2/#dictionary-client-data)
+type ClientData struct {
+	Type string `json:"type"`
+	// Challenge is the unpadded base64url encoded challenge
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+// GenerateChallenge generates a new random challenge
+func GenerateChallenge() ([]byte, error) {
+	challenge := make([]byte, challengeSize)
+	_, err := rand.Read(challenge)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read random bytes: %w", err)
+	}
+
+	return challenge, nil
+}
+
+// EncodeChallenge encodes the given challenge like it will be contained in ClientData
+func EncodeChallenge(challenge []byte) string {
+	return base64.RawURLEncoding.EncodeToString(challenge)
+}
+
+// DecodeChallenge decodes a challenge encoded by EncodeChallenge
+func DecodeChallenge(challenge string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(challenge)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid challenge encoding", ErrVerification)
+	}
+
+	return b, nil
+}
+
+// ParseClientData parses the given clientDataJSON
+func ParseClientData(clientDataJSON []byte) (ClientData, error) {
+	var cd ClientData
+	err := json.Unmarshal(clientDataJSON, &cd)
+	if err != nil {
+		return ClientData{}, fmt.Errorf("%w: invalid client data: %s", ErrVerification, err)
+	}
+
+	return cd, nil
+}
+
+// CreationOptions builds the options for a registration ceremony of a new credential for the given user. Already
+// registered credentials of the user should be excluded.
+func (rp RelyingParty) CreationOptions(challenge, userID []byte, userName string, excludeCredentialIDs [][]byte) CredentialCreationOptions {
+	return CredentialCreationOptions{
+		Challenge: challenge,
+		RP: RelyingPartyEntity{
+			ID:   rp.ID,
+			Name: rp.Name,
+		},
+		User: UserEntity{
+			ID:          userID,
+			Name:        userName,
+			DisplayName: userName,
+		},
+		PubKeyCredParams: []CredentialParameter{
+			{Type: publicKeyCredentialType, Alg: algES256},
+			{Type: publicKeyCredentialType, Alg: algRS256},
+		},
+		Timeout:            rp.Timeout.Milliseconds(),
+		ExcludeCredentials: credentialDescriptors(excludeCredentialIDs),
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: UserVerificationPreferred,
+		},
+		Attestation: "none",
+	}
+}
+
+// RequestOptions builds the options for an authentication ceremony with one of the given credentials
+func (rp RelyingParty) RequestOptions(challenge []byte, allowCredentialIDs [][]byte, userVerification string) CredentialRequestOptions {
+	return CredentialRequestOptions{
+		Challenge:        challenge,
+		Timeout:          rp.Timeout.Milliseconds(),
+		RPID:             rp.ID,
+		AllowCredentials: credentialDescriptors(allowCredentialIDs),
+		UserVerification: userVerification,
+	}
+}
+
+func credentialDescriptors(ids [][]byte) []CredentialDescriptor {
+	var descriptors []CredentialDescriptor
+	for _, id := range ids {
+		descriptors = append(descriptors, CredentialDescriptor{Type: publicKeyCredentialType, ID: id})
+	}
+
+	return descriptors
+}
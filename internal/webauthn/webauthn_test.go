@@ -0,0 +1,342 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/fxamacker/cbor/v2"
+	"math/big"
+	"testing"
+)
+
+var testRP = RelyingParty{ID: "example.com", Name: "example", Origins: []string{"https://login.example.com"}}
+
+// testAuthenticator is a software authenticator with a single ES256 credential
+type testAuthenticator struct {
+	credentialID []byte
+	key          *ecdsa.PrivateKey
+	signCount    uint32
+	flags        byte
+	rpID         string
+	origin       string
+}
+
+func newTestAuthenticator(t *testing.T) *testAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+
+	return &testAuthenticator{
+		credentialID: []byte("credential-id"),
+		key:          key,
+		flags:        flagUserPresent | flagUserVerified,
+		rpID:         testRP.ID,
+		origin:       testRP.Origins[0],
+	}
+}
+
+func (a *testAuthenticator) coseKey(t *testing.T) []byte {
+	coordinate := func(i *big.Int) []byte {
+		b := i.Bytes()
+		return append(make([]byte, 32-len(b)), b...)
+	}
+
+	k, err := ctap2Marshal(map[int]interface{}{
+		1:  keyTypeEC2,
+		3:  algES256,
+		-1: curveP256,
+		-2: coordinate(a.key.X),
+		-3: coordinate(a.key.Y),
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal cose key: %s", err)
+	}
+
+	return k
+}
+
+func ctap2Marshal(v interface{}) ([]byte, error) {
+	em, err := cbor.CTAP2EncOptions().EncMode()
+	if err != nil {
+		return nil, err
+	}
+
+	return em.Marshal(v)
+}
+
+func (a *testAuthenticator) authenticatorData(t *testing.T, attestedCredentialData bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	data := append([]byte{}, rpIDHash[:]...)
+
+	flags := a.flags
+	if attestedCredentialData {
+		flags |= flagAttestedCredentialData
+	}
+	data = append(data, flags)
+	data = append(data, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(data[33:], a.signCount)
+
+	if attestedCredentialData {
+		data = append(data, make([]byte, 16)...)
+		data = append(data, 0, 0)
+		binary.BigEndian.PutUint16(data[len(data)-2:], uint16(len(a.credentialID)))
+		data = append(data, a.credentialID...)
+		data = append(data, a.coseKey(t)...)
+	}
+
+	return data
+}
+
+func (a *testAuthenticator) clientData(t *testing.T, typ string, challenge []byte) []byte {
+	cd, err := json.Marshal(ClientData{Type: typ, Challenge: EncodeChallenge(challenge), Origin: a.origin})
+	if err != nil {
+		t.Fatalf("failed to marshal client data: %s", err)
+	}
+
+	return cd
+}
+
+func (a *testAuthenticator) create(t *testing.T, challenge []byte) AttestationResponse {
+	ao, err := ctap2Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": a.authenticatorData(t, true),
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal attestation object: %s", err)
+	}
+
+	var r AttestationResponse
+	r.RawID = a.credentialID
+	r.Type = publicKeyCredentialType
+	r.Response.ClientDataJSON = a.clientData(t, "webauthn.create", challenge)
+	r.Response.AttestationObject = ao
+
+	return r
+}
+
+func (a *testAuthenticator) get(t *testing.T, challenge []byte) AssertionResponse {
+	var r AssertionResponse
+	r.RawID = a.credentialID
+	r.Type = publicKeyCredentialType
+	r.Response.ClientDataJSON = a.clientData(t, "webauthn.get", challenge)
+	r.Response.AuthenticatorData = a.authenticatorData(t, false)
+
+	clientDataHash := sha256.Sum256(r.Response.ClientDataJSON)
+	hash := sha256.Sum256(append(append([]byte{}, r.Response.AuthenticatorData...), clientDataHash[:]...))
+	sigR, sigS, err := ecdsa.Sign(rand.Reader, a.key, hash[:])
+	if err != nil {
+		t.Fatalf("failed to sign: %s", err)
+	}
+	r.Response.Signature, err = asn1.Marshal(struct{ R, S *big.Int }{sigR, sigS})
+	if err != nil {
+		t.Fatalf("failed to marshal signature: %s", err)
+	}
+
+	return r
+}
+
+func TestRelyingParty_VerifyRegistration(t *testing.T) {
+	challenge := []byte("challenge")
+
+	tests := []struct {
+		name                    string
+		modifyAuthenticator     func(a *testAuthenticator)
+		modifyResponse          func(r *AttestationResponse)
+		challenge               []byte
+		requireUserVerification bool
+		expectedErr             error
+	}{
+		{
+			name:                    "Happy case",
+			challenge:               challenge,
+			requireUserVerification: true,
+		},
+		{
+			name:        "Wrong challenge",
+			challenge:   []byte("other"),
+			expectedErr: fmt.Errorf("%w: challenge does not match", ErrVerification),
+		},
+		{
+			name:                "Wrong origin",
+			challenge:           challenge,
+			modifyAuthenticator: func(a *testAuthenticator) { a.origin = "https://evil.example.com" },
+			expectedErr:         fmt.Errorf("%w: origin %q is not allowed", ErrVerification, "https://evil.example.com"),
+		},
+		{
+			name:                "Wrong rp id",
+			challenge:           challenge,
+			modifyAuthenticator: func(a *testAuthenticator) { a.rpID = "evil.com" },
+			expectedErr:         fmt.Errorf("%w: rp id hash does not match", ErrVerification),
+		},
+		{
+			name:                    "User not verified",
+			challenge:               challenge,
+			requireUserVerification: true,
+			modifyAuthenticator:     func(a *testAuthenticator) { a.flags = flagUserPresent },
+			expectedErr:             fmt.Errorf("%w: user not verified", ErrVerification),
+		},
+		{
+			name:                "User not verified but not required",
+			challenge:           challenge,
+			modifyAuthenticator: func(a *testAuthenticator) { a.flags = flagUserPresent },
+		},
+		{
+			name:                "User not present",
+			challenge:           challenge,
+			modifyAuthenticator: func(a *testAuthenticator) { a.flags = 0 },
+			expectedErr:         fmt.Errorf("%w: user not present", ErrVerification),
+		},
+		{
+			name:           "Wrong client data type",
+			challenge:      challenge,
+			modifyResponse: func(r *AttestationResponse) { r.Response.ClientDataJSON = []byte(`{"type":"webauthn.get"}`) },
+			expectedErr:    fmt.Errorf("%w: unexpected client data type %q", ErrVerification, "webauthn.get"),
+		},
+		{
+			name:           "Credential id mismatch",
+			challenge:      challenge,
+			modifyResponse: func(r *AttestationResponse) { r.RawID = []byte("other") },
+			expectedErr:    fmt.Errorf("%w: credential id does not match", ErrVerification),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAuthenticator(t)
+			if tt.modifyAuthenticator != nil {
+				tt.modifyAuthenticator(a)
+			}
+			r := a.create(t, challenge)
+			if tt.modifyResponse != nil {
+				tt.modifyResponse(&r)
+			}
+
+			c, err := testRP.VerifyRegistration(tt.challenge, r, tt.requireUserVerification)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedErr) {
+				t.Fatalf("Unexpected error. Expected: %q. Given: %q", tt.expectedErr, err)
+			}
+			if tt.expectedErr != nil {
+				if !errors.Is(err, ErrVerification) {
+					t.Errorf("Error should wrap ErrVerification")
+				}
+				return
+			}
+
+			if string(c.ID) != string(a.credentialID) {
+				t.Errorf("Unexpected credential id. Expected: %q. Given: %q", a.credentialID, c.ID)
+			}
+			if string(c.PublicKey) != string(a.coseKey(t)) {
+				t.Errorf("Unexpected public key")
+			}
+		})
+	}
+}
+
+func TestRelyingParty_VerifyAssertion(t *testing.T) {
+	challenge := []byte("challenge")
+
+	tests := []struct {
+		name                    string
+		modifyAuthenticator     func(a *testAuthenticator)
+		modifyResponse          func(r *AssertionResponse)
+		storedSignCount         uint32
+		requireUserVerification bool
+		expectedSignCount       uint32
+		expectedErr             error
+	}{
+		{
+			name:                    "Happy case",
+			modifyAuthenticator:     func(a *testAuthenticator) { a.signCount = 5 },
+			storedSignCount:         4,
+			requireUserVerification: true,
+			expectedSignCount:       5,
+		},
+		{
+			name: "Happy case without counter",
+		},
+		{
+			name:                "Sign count not increased",
+			modifyAuthenticator: func(a *testAuthenticator) { a.signCount = 4 },
+			storedSignCount:     4,
+			expectedErr:         fmt.Errorf("%w: sign count did not increase, the authenticator may be cloned", ErrVerification),
+		},
+		{
+			name:           "Invalid signature",
+			modifyResponse: func(r *AssertionResponse) { r.Response.Signature[len(r.Response.Signature)-1]++ },
+			expectedErr:    fmt.Errorf("%w: invalid signature", ErrVerification),
+		},
+		{
+			name:           "Invalid signature encoding",
+			modifyResponse: func(r *AssertionResponse) { r.Response.Signature = []byte("sig") },
+			expectedErr:    fmt.Errorf("%w: invalid signature encoding", ErrVerification),
+		},
+		{
+			name:                    "User not verified",
+			modifyAuthenticator:     func(a *testAuthenticator) { a.flags = flagUserPresent },
+			requireUserVerification: true,
+			expectedErr:             fmt.Errorf("%w: user not verified", ErrVerification),
+		},
+		{
+			name:           "Authenticator data too short",
+			modifyResponse: func(r *AssertionResponse) { r.Response.AuthenticatorData = []byte("short") },
+			expectedErr:    fmt.Errorf("%w: authenticator data too short", ErrVerification),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAuthenticator(t)
+			c, err := testRP.VerifyRegistration(challenge, a.create(t, challenge), false)
+			if err != nil {
+				t.Fatalf("failed to register credential: %s", err)
+			}
+			c.SignCount = tt.storedSignCount
+
+			if tt.modifyAuthenticator != nil {
+				tt.modifyAuthenticator(a)
+			}
+			r := a.get(t, challenge)
+			if tt.modifyResponse != nil {
+				tt.modifyResponse(&r)
+			}
+
+			signCount, err := testRP.VerifyAssertion(challenge, r, c, tt.requireUserVerification)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedErr) {
+				t.Fatalf("Unexpected error. Expected: %q. Given: %q", tt.expectedErr, err)
+			}
+
+			if signCount != tt.expectedSignCount {
+				t.Errorf("Unexpected sign count. Expected: %d. Given: %d", tt.expectedSignCount, signCount)
+			}
+		})
+	}
+}
+
+func TestURLEncodedBase64(t *testing.T) {
+	var s struct {
+		V URLEncodedBase64 `json:"v"`
+	}
+	err := json.Unmarshal([]byte(`{"v":"_-8="}`), &s)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if string(s.V) != "\xff\xef" {
+		t.Errorf("Unexpected value: %x", s.V)
+	}
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if string(b) != `{"v":"_-8"}` {
+		t.Errorf("Unexpected json: %s", b)
+	}
+}
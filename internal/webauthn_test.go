@@ -0,0 +1,543 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/leberKleber/simple-jwt-provider/internal/storage"
+	"github.com/leberKleber/simple-jwt-provider/internal/webauthn"
+	"golang.org/x/crypto/bcrypt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProvider_BeginWebAuthnRegistration(t *testing.T) {
+	bcryptCost = bcrypt.MinCost
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+
+	assertionChallenge := []byte("assertion-challenge")
+	assertionToken := storage.Token{ID: 1, UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Token: "hashed-" + webauthn.EncodeChallenge(assertionChallenge), Type: storage.TokenTypeWebAuthnLogin, CreatedAt: now.Add(-time.Minute)}
+	assertion := &webauthn.AssertionResponse{RawID: []byte("id1")}
+	assertion.Response.ClientDataJSON = []byte(fmt.Sprintf(`{"type":"webauthn.get","challenge":%q}`, webauthn.EncodeChallenge(assertionChallenge)))
+
+	tests := []struct {
+		name                string
+		webAuthnDisabled    bool
+		givenPassword       string
+		givenSecondFactor   SecondFactor
+		dbTokens            []storage.Token
+		dbCredentials       []storage.WebAuthnCredential
+		dbCredentialsError  error
+		dbCodeCount         int
+		dbUseCodeError      error
+		dbCreateTokenError  error
+		verifyError         error
+		expectedExcludedIDs [][]byte
+		expectedError       error
+	}{
+		{
+			name:          "Happycase",
+			givenPassword: "password",
+		}, {
+			name:                "Happycase with webauthn as second factor",
+			givenPassword:       "password",
+			givenSecondFactor:   SecondFactor{WebAuthn: assertion},
+			dbTokens:            []storage.Token{assertionToken},
+			dbCredentials:       []storage.WebAuthnCredential{{ID: []byte("id1")}, {ID: []byte("id2")}},
+			expectedExcludedIDs: [][]byte{[]byte("id1"), []byte("id2")},
+		}, {
+			name:                "Happycase with recovery code as second factor",
+			givenPassword:       "password",
+			givenSecondFactor:   SecondFactor{Code: "abcde-fghjk"},
+			dbCredentials:       []storage.WebAuthnCredential{{ID: []byte("id1")}},
+			expectedExcludedIDs: [][]byte{[]byte("id1")},
+		}, {
+			name:             "WebAuthn not configured",
+			webAuthnDisabled: true,
+			givenPassword:    "password",
+			expectedError:    ErrWebAuthnNotConfigured,
+		}, {
+			name:          "Incorrect password",
+			givenPassword: "wrong",
+			expectedError: ErrIncorrectPassword,
+		}, {
+			name:          "Password only with enabled webauthn",
+			givenPassword: "password",
+			dbCredentials: []storage.WebAuthnCredential{{ID: []byte("id1")}},
+			expectedError: ErrSecondFactorRequired,
+		}, {
+			name:          "Password only with unused recovery codes",
+			givenPassword: "password",
+			dbCodeCount:   3,
+			expectedError: ErrSecondFactorRequired,
+		}, {
+			name:              "Invalid webauthn as second factor",
+			givenPassword:     "password",
+			givenSecondFactor: SecondFactor{WebAuthn: assertion},
+			dbTokens:          []storage.Token{assertionToken},
+			dbCredentials:     []storage.WebAuthnCredential{{ID: []byte("id1")}},
+			verifyError:       fmt.Errorf("%w: invalid signature", webauthn.ErrVerification),
+			expectedError:     errors.New("invalid second factor: invalid webauthn response: webauthn verification failed: invalid signature"),
+		}, {
+			name:              "Unknown challenge of webauthn as second factor",
+			givenPassword:     "password",
+			givenSecondFactor: SecondFactor{WebAuthn: assertion},
+			dbCredentials:     []storage.WebAuthnCredential{{ID: []byte("id1")}},
+			expectedError:     errors.New("invalid second factor: no valid token found"),
+		}, {
+			name:              "Invalid recovery code as second factor",
+			givenPassword:     "password",
+			givenSecondFactor: SecondFactor{Code: "abcde-fghjk"},
+			dbCredentials:     []storage.WebAuthnCredential{{ID: []byte("id1")}},
+			dbUseCodeError:    storage.ErrRecoveryCodeNotFound,
+			expectedError:     ErrInvalidSecondFactor,
+		}, {
+			name:               "Unexpected credentials db error",
+			givenPassword:      "password",
+			dbCredentialsError: errors.New("nope"),
+			expectedError:      errors.New("failed to query webauthn credentials: nope"),
+		}, {
+			name:               "Unexpected token db error",
+			givenPassword:      "password",
+			dbCreateTokenError: errors.New("nope"),
+			expectedError:      errors.New("failed to create webauthn-registration-token for user \"c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b\": nope"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenToken storage.Token
+			storageMock := &StorageMock{
+				UserFunc: func(email string) (storage.User, error) {
+					return storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", EMail: email, Password: testPasswordHash}, nil
+				},
+				TOTPFunc: func(userID string) (storage.TOTP, error) {
+					return storage.TOTP{}, storage.ErrTOTPNotFound
+				},
+				WebAuthnCredentialsFunc: func(userID string) ([]storage.WebAuthnCredential, error) {
+					return tt.dbCredentials, tt.dbCredentialsError
+				},
+				UnusedRecoveryCodeCountFunc: func(userID string) (int, error) {
+					return tt.dbCodeCount, nil
+				},
+				UseRecoveryCodeFunc: func(userID string, codeHash []byte, usedAt time.Time) error {
+					return tt.dbUseCodeError
+				},
+				TokensByUserIDAndTypeFunc: func(userID string, tokenType string) ([]storage.Token, error) {
+					return tokensOfType(tt.dbTokens, tokenType), nil
+				},
+				DeleteTokenFunc: func(id int64) error {
+					return nil
+				},
+				UpdateWebAuthnCredentialUsageFunc: func(id []byte, signCount uint32, lastUsedAt time.Time) error {
+					return nil
+				},
+				CreateTokenFunc: func(t storage.Token) (int64, error) {
+					givenToken = t
+					return 1, tt.dbCreateTokenError
+				},
+			}
+			rp := &WebAuthnRelyingPartyMock{
+				CreationOptionsFunc: func(challenge []byte, userID []byte, userName string, excludeCredentialIDs [][]byte) webauthn.CredentialCreationOptions {
+					return webauthn.CredentialCreationOptions{Challenge: challenge}
+				},
+				VerifyAssertionFunc: func(c []byte, r webauthn.AssertionResponse, credential webauthn.Credential, requireUserVerification bool) (uint32, error) {
+					if string(c) != string(assertionChallenge) || requireUserVerification {
+						return 0, fmt.Errorf("unexpected challenge %q or user verification requirement %t", c, requireUserVerification)
+					}
+					return 1, tt.verifyError
+				},
+			}
+
+			toTest := Provider{Storage: storageMock, TokenHasher: testTokenHasher, MFATokenLifetime: 5 * time.Minute}
+			if !tt.webAuthnDisabled {
+				toTest.WebAuthn = rp
+			}
+
+			options, err := toTest.BeginWebAuthnRegistration("test@test.test", tt.givenPassword, tt.givenSecondFactor)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+			if tt.expectedError != nil {
+				if len(rp.CreationOptionsCalls()) != 0 {
+					t.Errorf("Registration must not be started")
+				}
+				return
+			}
+
+			calls := rp.CreationOptionsCalls()
+			if len(calls) != 1 {
+				t.Fatalf("Unexpected count of CreationOptions calls: %d", len(calls))
+			}
+			if !reflect.DeepEqual(calls[0].ExcludeCredentialIDs, tt.expectedExcludedIDs) {
+				t.Errorf("Unexpected excluded credentials. Expected: %q. Given: %q", tt.expectedExcludedIDs, calls[0].ExcludeCredentialIDs)
+			}
+			if calls[0].UserName != "test@test.test" || len(calls[0].UserID) != 32 {
+				t.Errorf("Unexpected user. Name: %q, ID: %x", calls[0].UserName, calls[0].UserID)
+			}
+			if givenToken.Type != storage.TokenTypeWebAuthnRegistration || givenToken.Token != "hashed-"+webauthn.EncodeChallenge(options.Challenge) {
+				t.Errorf("Challenge token is not as expected: %#v", givenToken)
+			}
+		})
+	}
+}
+
+func TestProvider_FinishWebAuthnRegistration(t *testing.T) {
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+
+	challenge := []byte("challenge")
+	validToken := storage.Token{ID: 42, UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Token: "hashed-" + webauthn.EncodeChallenge(challenge), Type: storage.TokenTypeWebAuthnRegistration, CreatedAt: now.Add(-time.Minute)}
+	credential := webauthn.Credential{ID: []byte("id"), PublicKey: []byte("key"), SignCount: 1}
+
+	tests := []struct {
+		name                string
+		webAuthnDisabled    bool
+		dbTokens            []storage.Token
+		verifyError         error
+		dbCreateError       error
+		expectedCredential  *storage.WebAuthnCredential
+		expectedTokenDelete bool
+		expectedCodes       int
+		expectedError       error
+	}{
+		{
+			name:                "Happycase",
+			dbTokens:            []storage.Token{validToken},
+			expectedCredential:  &storage.WebAuthnCredential{ID: []byte("id"), UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", PublicKey: []byte("key"), SignCount: 1, CreatedAt: now},
+			expectedTokenDelete: true,
+			expectedCodes:       recoveryCodeCount,
+		}, {
+			name:             "WebAuthn not configured",
+			webAuthnDisabled: true,
+			expectedError:    ErrWebAuthnNotConfigured,
+		}, {
+			name: "Challenge not found",
+			dbTokens: []storage.Token{
+				{ID: 42, UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Token: "hashed-" + webauthn.EncodeChallenge(challenge), Type: storage.TokenTypeWebAuthnLogin, CreatedAt: now},
+			},
+			expectedError: ErrNoValidTokenFound,
+		}, {
+			name:                "Invalid response",
+			dbTokens:            []storage.Token{validToken},
+			verifyError:         fmt.Errorf("%w: user not present", webauthn.ErrVerification),
+			expectedTokenDelete: true,
+			expectedError:       errors.New("invalid webauthn response: webauthn verification failed: user not present"),
+		}, {
+			name:                "Credential already exists",
+			dbTokens:            []storage.Token{validToken},
+			dbCreateError:       storage.ErrWebAuthnCredentialAlreadyExists,
+			expectedCredential:  &storage.WebAuthnCredential{ID: []byte("id"), UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", PublicKey: []byte("key"), SignCount: 1, CreatedAt: now},
+			expectedTokenDelete: true,
+			expectedError:       ErrWebAuthnCredentialAlreadyExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenCredential *storage.WebAuthnCredential
+			storageMock := &StorageMock{
+				UserFunc: func(email string) (storage.User, error) {
+					return storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", EMail: email}, nil
+				},
+				TokensByUserIDAndTypeFunc: func(userID string, tokenType string) ([]storage.Token, error) {
+					return tokensOfType(tt.dbTokens, tokenType), nil
+				},
+				DeleteTokenFunc: func(id int64) error {
+					return nil
+				},
+				CreateWebAuthnCredentialFunc: func(c storage.WebAuthnCredential) error {
+					givenCredential = &c
+					return tt.dbCreateError
+				},
+				UnusedRecoveryCodeCountFunc: func(userID string) (int, error) {
+					return 0, nil
+				},
+				ReplaceRecoveryCodesFunc: func(userID string, codeHashes [][]byte, createdAt time.Time) error {
+					return nil
+				},
+			}
+
+			rp := &WebAuthnRelyingPartyMock{
+				VerifyRegistrationFunc: func(c []byte, r webauthn.AttestationResponse, requireUserVerification bool) (webauthn.Credential, error) {
+					if string(c) != string(challenge) {
+						return webauthn.Credential{}, fmt.Errorf("unexpected challenge %q", c)
+					}
+					return credential, tt.verifyError
+				},
+			}
+
+			toTest := Provider{Storage: storageMock, TokenHasher: testTokenHasher, MFATokenLifetime: 5 * time.Minute}
+			if !tt.webAuthnDisabled {
+				toTest.WebAuthn = rp
+			}
+
+			var r webauthn.AttestationResponse
+			r.Response.ClientDataJSON = []byte(fmt.Sprintf(`{"type":"webauthn.create","challenge":%q}`, webauthn.EncodeChallenge(challenge)))
+
+			codes, err := toTest.FinishWebAuthnRegistration("test@test.test", r)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			if len(codes) != tt.expectedCodes {
+				t.Errorf("Count of returned recovery codes is not as expected. Expected: %d. Given: %d", tt.expectedCodes, len(codes))
+			}
+
+			if !reflect.DeepEqual(givenCredential, tt.expectedCredential) {
+				t.Errorf("Stored credential is not as expected. Expected:\n%#v\nGiven:\n%#v", tt.expectedCredential, givenCredential)
+			}
+
+			deleteCalls := storageMock.DeleteTokenCalls()
+			if (len(deleteCalls) == 1) != tt.expectedTokenDelete {
+				t.Errorf("Unexpected count of DeleteToken calls: %d", len(deleteCalls))
+			}
+		})
+	}
+}
+
+func TestProvider_BeginWebAuthnLogin(t *testing.T) {
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+
+	mfaToken := storage.Token{ID: 42, UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Token: "hashed-myMFAToken", Type: storage.TokenTypeMFA, CreatedAt: now.Add(-time.Minute)}
+
+	tests := []struct {
+		name                     string
+		givenMFAToken            string
+		dbTokens                 []storage.Token
+		dbCredentials            []storage.WebAuthnCredential
+		dbUserError              error
+		expectedUserVerification string
+		expectedError            error
+	}{
+		{
+			name:                     "Happycase passwordless",
+			dbCredentials:            []storage.WebAuthnCredential{{ID: []byte("id1")}},
+			expectedUserVerification: webauthn.UserVerificationRequired,
+		}, {
+			name:                     "Happycase second factor",
+			givenMFAToken:            "myMFAToken",
+			dbTokens:                 []storage.Token{mfaToken},
+			dbCredentials:            []storage.WebAuthnCredential{{ID: []byte("id1")}},
+			expectedUserVerification: webauthn.UserVerificationPreferred,
+		}, {
+			name:          "Invalid mfa token",
+			givenMFAToken: "myMFAToken",
+			dbCredentials: []storage.WebAuthnCredential{{ID: []byte("id1")}},
+			expectedError: ErrNoValidTokenFound,
+		}, {
+			name:          "No credentials",
+			expectedError: ErrNoWebAuthnCredentials,
+		}, {
+			name:          "User not found",
+			dbUserError:   storage.ErrUserNotFound,
+			expectedError: ErrNoWebAuthnCredentials,
+		}, {
+			name:          "User not found with mfa token",
+			givenMFAToken: "myMFAToken",
+			dbUserError:   storage.ErrUserNotFound,
+			expectedError: ErrNoValidTokenFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenToken storage.Token
+			storageMock := &StorageMock{
+				UserFunc: func(email string) (storage.User, error) {
+					return storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", EMail: email}, tt.dbUserError
+				},
+				TokensByUserIDAndTypeFunc: func(userID string, tokenType string) ([]storage.Token, error) {
+					return tokensOfType(tt.dbTokens, tokenType), nil
+				},
+				WebAuthnCredentialsFunc: func(userID string) ([]storage.WebAuthnCredential, error) {
+					return tt.dbCredentials, nil
+				},
+				CreateTokenFunc: func(t storage.Token) (int64, error) {
+					givenToken = t
+					return 1, nil
+				},
+			}
+			rp := &WebAuthnRelyingPartyMock{
+				RequestOptionsFunc: func(challenge []byte, allowCredentialIDs [][]byte, userVerification string) webauthn.CredentialRequestOptions {
+					return webauthn.CredentialRequestOptions{Challenge: challenge, UserVerification: userVerification}
+				},
+			}
+
+			toTest := Provider{Storage: storageMock, TokenHasher: testTokenHasher, WebAuthn: rp, MFATokenLifetime: 5 * time.Minute}
+
+			options, err := toTest.BeginWebAuthnLogin("test@test.test", tt.givenMFAToken)
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			if options.UserVerification != tt.expectedUserVerification {
+				t.Errorf("Unexpected user verification. Expected: %q. Given: %q", tt.expectedUserVerification, options.UserVerification)
+			}
+
+			if tt.expectedError == nil && (givenToken.Type != storage.TokenTypeWebAuthnLogin || givenToken.Token != "hashed-"+webauthn.EncodeChallenge(options.Challenge)) {
+				t.Errorf("Challenge token is not as expected: %#v", givenToken)
+			}
+
+			if len(storageMock.DeleteTokenCalls()) != 0 {
+				t.Errorf("mfa token must not be redeemed")
+			}
+		})
+	}
+}
+
+func TestProvider_FinishWebAuthnLogin(t *testing.T) {
+	oldNowFunc := nowFunc
+	defer func() { nowFunc = oldNowFunc }()
+	now := time.Date(2020, 2, 1, 4, 46, 45, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+
+	challenge := []byte("challenge")
+	challengeToken := storage.Token{ID: 1, UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Token: "hashed-" + webauthn.EncodeChallenge(challenge), Type: storage.TokenTypeWebAuthnLogin, CreatedAt: now.Add(-time.Minute)}
+	mfaToken := storage.Token{ID: 2, UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", Token: "hashed-myMFAToken", Type: storage.TokenTypeMFA, CreatedAt: now.Add(-time.Minute)}
+	dbCredential := storage.WebAuthnCredential{ID: []byte("id"), UserID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", PublicKey: []byte("key"), SignCount: 4}
+
+	tests := []struct {
+		name                            string
+		givenMFAToken                   string
+		givenCredentialID               string
+		dbTokens                        []storage.Token
+		verifyError                     error
+		expectedRequireUserVerification bool
+		expectedDeletedTokens           []int64
+		expectedJWT                     string
+		expectedClaims                  map[string]interface{}
+		expectedError                   error
+	}{
+		{
+			name:                            "Happycase passwordless",
+			givenCredentialID:               "id",
+			dbTokens:                        []storage.Token{challengeToken},
+			expectedRequireUserVerification: true,
+			expectedDeletedTokens:           []int64{1},
+			expectedJWT:                     "myJWT",
+			expectedClaims:                  map[string]interface{}{"myCustomClaim": "value", "amr": []string{"hwk"}},
+		}, {
+			name:                  "Happycase second factor",
+			givenMFAToken:         "myMFAToken",
+			givenCredentialID:     "id",
+			dbTokens:              []storage.Token{challengeToken, mfaToken},
+			expectedDeletedTokens: []int64{2, 1},
+			expectedJWT:           "myJWT",
+			expectedClaims:        map[string]interface{}{"myCustomClaim": "value", "amr": []string{"pwd", "hwk"}},
+		}, {
+			name:              "Invalid mfa token",
+			givenMFAToken:     "myMFAToken",
+			givenCredentialID: "id",
+			dbTokens:          []storage.Token{challengeToken},
+			expectedError:     ErrNoValidTokenFound,
+		}, {
+			name:              "Challenge not found",
+			givenCredentialID: "id",
+			expectedError:     ErrNoValidTokenFound,
+		}, {
+			name:                  "Unknown credential",
+			givenCredentialID:     "other",
+			dbTokens:              []storage.Token{challengeToken},
+			expectedDeletedTokens: []int64{1},
+			expectedError:         errors.New("invalid webauthn response: unknown credential"),
+		}, {
+			name:                            "Invalid response",
+			givenCredentialID:               "id",
+			dbTokens:                        []storage.Token{challengeToken},
+			verifyError:                     fmt.Errorf("%w: invalid signature", webauthn.ErrVerification),
+			expectedRequireUserVerification: true,
+			expectedDeletedTokens:           []int64{1},
+			expectedError:                   errors.New("invalid webauthn response: webauthn verification failed: invalid signature"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var givenClaims map[string]interface{}
+			var deletedTokens []int64
+			storageMock := &StorageMock{
+				TokensByUserIDAndTypeFunc: func(userID string, tokenType string) ([]storage.Token, error) {
+					return tokensOfType(tt.dbTokens, tokenType), nil
+				},
+				DeleteTokenFunc: func(id int64) error {
+					deletedTokens = append(deletedTokens, id)
+					return nil
+				},
+				WebAuthnCredentialsFunc: func(userID string) ([]storage.WebAuthnCredential, error) {
+					return []storage.WebAuthnCredential{dbCredential}, nil
+				},
+				UpdateWebAuthnCredentialUsageFunc: func(id []byte, signCount uint32, lastUsedAt time.Time) error {
+					return nil
+				},
+				UserFunc: func(email string) (storage.User, error) {
+					return storage.User{ID: "c6a1e4a8-1a5e-4b8e-9d6b-5f1f6d0e2a7b", EMail: email, Claims: map[string]interface{}{"myCustomClaim": "value"}}, nil
+				},
+				AddLoginFunc: func(l storage.Login) error {
+					if l.Factor != LoginFactorWebAuthn {
+						t.Errorf("Unexpected login factor %q", l.Factor)
+					}
+					return nil
+				},
+			}
+			rp := &WebAuthnRelyingPartyMock{
+				VerifyAssertionFunc: func(c []byte, r webauthn.AssertionResponse, credential webauthn.Credential, requireUserVerification bool) (uint32, error) {
+					if requireUserVerification != tt.expectedRequireUserVerification {
+						return 0, fmt.Errorf("unexpected user verification requirement %t", requireUserVerification)
+					}
+					if string(c) != string(challenge) || credential.SignCount != 4 {
+						return 0, fmt.Errorf("unexpected challenge %q or credential %#v", c, credential)
+					}
+					return 5, tt.verifyError
+				},
+			}
+
+			toTest := Provider{
+				Storage:          storageMock,
+				TokenHasher:      testTokenHasher,
+				WebAuthn:         rp,
+				MFATokenLifetime: 5 * time.Minute,
+				JWTGenerator: &JWTGeneratorMock{
+					GenerateFunc: func(userID string, email string, userClaims map[string]interface{}) (string, error) {
+						givenClaims = userClaims
+						return "myJWT", nil
+					},
+				},
+			}
+
+			var r webauthn.AssertionResponse
+			r.RawID = []byte(tt.givenCredentialID)
+			r.Response.ClientDataJSON = []byte(fmt.Sprintf(`{"type":"webauthn.get","challenge":%q}`, webauthn.EncodeChallenge(challenge)))
+
+			jwt, err := toTest.FinishWebAuthnLogin("test@test.test", tt.givenMFAToken, r, Client{})
+			if fmt.Sprint(err) != fmt.Sprint(tt.expectedError) {
+				t.Fatalf("Processing error is not as expected: \nExpected:\n%s\nGiven:\n%s", tt.expectedError, err)
+			}
+
+			if jwt != tt.expectedJWT {
+				t.Errorf("Given jwt is not as expected. Expected: %q, Given: %q", tt.expectedJWT, jwt)
+			}
+
+			if !reflect.DeepEqual(givenClaims, tt.expectedClaims) {
+				t.Errorf("Generator claims are not as expected. Expected:\n%#v\nGiven:\n%#v", tt.expectedClaims, givenClaims)
+			}
+
+			if !reflect.DeepEqual(deletedTokens, tt.expectedDeletedTokens) {
+				t.Errorf("Deleted tokens are not as expected. Expected: %v. Given: %v", tt.expectedDeletedTokens, deletedTokens)
+			}
+
+			updateCalls := storageMock.UpdateWebAuthnCredentialUsageCalls()
+			if tt.expectedError == nil && (len(updateCalls) != 1 || updateCalls[0].SignCount != 5 || !updateCalls[0].LastUsedAt.Equal(now)) {
+				t.Errorf("Credential usage has not been updated as expected: %#v", updateCalls)
+			}
+		})
+	}
+}